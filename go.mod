@@ -50,6 +50,7 @@ require (
 	github.com/spf13/viper v1.21.0
 	github.com/stretchr/testify v1.11.1
 	github.com/ziflex/lecho/v3 v3.9.0
+	go.etcd.io/bbolt v1.3.6
 	golang.org/x/crypto v0.52.0
 	golang.org/x/image v0.40.0
 	golang.org/x/net v0.54.0
@@ -186,7 +187,6 @@ require (
 	github.com/vektah/gqlparser/v2 v2.5.26 // indirect
 	github.com/wlynxg/anet v0.0.3 // indirect
 	github.com/xo/terminfo v0.0.0-20220910002029-abceb7e1c41e // indirect
-	go.etcd.io/bbolt v1.3.6 // indirect
 	go.opentelemetry.io/auto/sdk v1.2.1 // indirect
 	go.opentelemetry.io/otel v1.38.0 // indirect
 	go.opentelemetry.io/otel/metric v1.38.0 // indirect
@@ -0,0 +1,269 @@
+package access
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Role determines which features an API token can use.
+// The features disabled for each role are defined in core.
+type Role string
+
+const (
+	// RoleAdmin has access to everything, like the server password.
+	RoleAdmin Role = "admin"
+	// RoleViewer can watch, read and manage their lists but cannot change the server.
+	RoleViewer Role = "viewer"
+	// RoleGuest can only watch and read.
+	RoleGuest Role = "guest"
+)
+
+var (
+	ErrInvalidRole  = errors.New("access: Invalid role")
+	ErrInvalidName  = errors.New("access: Name is required")
+	ErrTokenMissing = errors.New("access: Token not found")
+
+	// FlushInterval is the interval at which audit entries are saved
+	FlushInterval = 10 * time.Second
+)
+
+const tokenPrefix = "sea_"
+
+func (r Role) IsValid() bool {
+	switch r {
+	case RoleAdmin, RoleViewer, RoleGuest:
+		return true
+	}
+	return false
+}
+
+func Roles() []Role {
+	return []Role{RoleAdmin, RoleViewer, RoleGuest}
+}
+
+type (
+	// Manager creates and resolves API tokens and keeps an audit of the requests made with them.
+	// Tokens are cached in memory since they are resolved on every request.
+	Manager struct {
+		logger *zerolog.Logger
+		db     *db.Database
+
+		mu     sync.RWMutex
+		tokens map[string]*models.ApiToken // key: token hash
+
+		auditMu      sync.Mutex
+		pendingAudit []*models.ApiTokenAuditEntry
+		lastUsed     map[uint]time.Time
+	}
+
+	NewManagerOptions struct {
+		Logger   *zerolog.Logger
+		Database *db.Database
+	}
+)
+
+func NewManager(opts *NewManagerOptions) *Manager {
+	ret := &Manager{
+		logger:   opts.Logger,
+		db:       opts.Database,
+		tokens:   make(map[string]*models.ApiToken),
+		lastUsed: make(map[uint]time.Time),
+	}
+
+	ret.reload()
+
+	return ret
+}
+
+// Start saves the audit entries periodically until the context is canceled.
+func (m *Manager) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(FlushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				m.Flush()
+				return
+			case <-ticker.C:
+				m.Flush()
+			}
+		}
+	}()
+}
+
+func (m *Manager) reload() {
+	tokens, err := m.db.GetApiTokens()
+	if err != nil {
+		m.logger.Error().Err(err).Msg("access: Failed to load API tokens")
+		return
+	}
+
+	cache := make(map[string]*models.ApiToken, len(tokens))
+	for _, token := range tokens {
+		cache[token.TokenHash] = token
+	}
+
+	m.mu.Lock()
+	m.tokens = cache
+	m.mu.Unlock()
+}
+
+// HasTokens returns true if at least one API token exists.
+func (m *Manager) HasTokens() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.tokens) > 0
+}
+
+// Resolve returns the token matching the hash sent by the client.
+func (m *Manager) Resolve(tokenHash string) (*models.ApiToken, bool) {
+	if tokenHash == "" {
+		return nil, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	token, found := m.tokens[strings.ToLower(tokenHash)]
+	return token, found
+}
+
+// GetTokens returns all API tokens.
+func (m *Manager) GetTokens() ([]*models.ApiToken, error) {
+	return m.db.GetApiTokens()
+}
+
+// CreateToken creates an API token and returns its value.
+// The value is only returned once, only its hash is stored.
+func (m *Manager) CreateToken(name string, role Role) (string, *models.ApiToken, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, ErrInvalidName
+	}
+	if !role.IsValid() {
+		return "", nil, ErrInvalidRole
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", nil, fmt.Errorf("access: Failed to generate token: %w", err)
+	}
+	value := tokenPrefix + hex.EncodeToString(b)
+
+	token := &models.ApiToken{
+		Name:      name,
+		Role:      string(role),
+		TokenHash: HashToken(value),
+	}
+	if err := m.db.InsertApiToken(token); err != nil {
+		return "", nil, err
+	}
+
+	m.reload()
+
+	m.logger.Info().Str("name", name).Str("role", string(role)).Msg("access: API token created")
+
+	return value, token, nil
+}
+
+// DeleteToken revokes the token.
+func (m *Manager) DeleteToken(id uint) error {
+	m.mu.RLock()
+	found := false
+	for _, token := range m.tokens {
+		if token.ID == id {
+			found = true
+			break
+		}
+	}
+	m.mu.RUnlock()
+
+	if !found {
+		return ErrTokenMissing
+	}
+
+	if err := m.db.DeleteApiToken(id); err != nil {
+		return err
+	}
+
+	m.reload()
+
+	m.logger.Info().Uint("id", id).Msg("access: API token revoked")
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RecordRequest adds an audit entry for a request made with the token.
+// Entries are saved in batches by Flush.
+func (m *Manager) RecordRequest(token *models.ApiToken, method string, path string, status int) {
+	if token == nil {
+		return
+	}
+
+	m.auditMu.Lock()
+	defer m.auditMu.Unlock()
+
+	m.pendingAudit = append(m.pendingAudit, &models.ApiTokenAuditEntry{
+		TokenID: token.ID,
+		Method:  method,
+		Path:    path,
+		Status:  status,
+	})
+	m.lastUsed[token.ID] = time.Now()
+}
+
+// Flush saves the pending audit entries and updates the last use of the tokens.
+func (m *Manager) Flush() {
+	defer util.HandlePanicInModuleThen("access/Flush", func() {})
+
+	m.auditMu.Lock()
+	entries := m.pendingAudit
+	lastUsed := m.lastUsed
+	m.pendingAudit = nil
+	m.lastUsed = make(map[uint]time.Time)
+	m.auditMu.Unlock()
+
+	if len(entries) == 0 {
+		return
+	}
+
+	if err := m.db.InsertApiTokenAuditEntries(entries); err != nil {
+		m.logger.Error().Err(err).Msg("access: Failed to save audit entries")
+	}
+
+	for id, t := range lastUsed {
+		if err := m.db.UpdateApiTokenLastUsedAt(id, t); err != nil {
+			m.logger.Error().Err(err).Msg("access: Failed to update token")
+		}
+	}
+}
+
+// GetAuditEntries returns the most recent requests made with the token.
+func (m *Manager) GetAuditEntries(tokenId uint, limit int) ([]*models.ApiTokenAuditEntry, error) {
+	// Save the pending entries so that the audit is up-to-date
+	m.Flush()
+
+	if limit <= 0 || limit > 1000 {
+		limit = 1000
+	}
+
+	return m.db.GetApiTokenAuditEntries(tokenId, limit)
+}
+
+// HashToken returns the hash of the token value, which is what clients send in the X-Seanime-Token header.
+func HashToken(value string) string {
+	return util.HashSHA256Hex(value)
+}
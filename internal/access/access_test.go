@@ -0,0 +1,80 @@
+package access
+
+import (
+	"seanime/internal/testutil"
+	"seanime/internal/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateAndResolveToken(t *testing.T) {
+	logger := util.NewLogger()
+	database := testutil.NewTestEnv(t).MustNewDatabase(logger)
+
+	m := NewManager(&NewManagerOptions{Logger: logger, Database: database})
+	require.False(t, m.HasTokens())
+
+	_, _, err := m.CreateToken("Kid", Role("owner"))
+	require.ErrorIs(t, err, ErrInvalidRole)
+	_, _, err = m.CreateToken(" ", RoleGuest)
+	require.ErrorIs(t, err, ErrInvalidName)
+
+	value, token, err := m.CreateToken("Kid", RoleGuest)
+	require.NoError(t, err)
+	require.NotEqual(t, value, token.TokenHash)
+
+	// The client sends the hash of the token
+	resolved, found := m.Resolve(HashToken(value))
+	require.True(t, found)
+	require.Equal(t, token.ID, resolved.ID)
+	require.Equal(t, string(RoleGuest), resolved.Role)
+
+	_, found = m.Resolve(value)
+	require.False(t, found)
+
+	// A new manager loads the tokens from the database
+	resolved, found = NewManager(&NewManagerOptions{Logger: logger, Database: database}).Resolve(HashToken(value))
+	require.True(t, found)
+	require.Equal(t, "Kid", resolved.Name)
+
+	require.NoError(t, m.DeleteToken(token.ID))
+	_, found = m.Resolve(HashToken(value))
+	require.False(t, found)
+	require.ErrorIs(t, m.DeleteToken(token.ID), ErrTokenMissing)
+}
+
+func TestAuditEntries(t *testing.T) {
+	logger := util.NewLogger()
+	database := testutil.NewTestEnv(t).MustNewDatabase(logger)
+
+	m := NewManager(&NewManagerOptions{Logger: logger, Database: database})
+
+	_, kid, err := m.CreateToken("Kid", RoleGuest)
+	require.NoError(t, err)
+	_, partner, err := m.CreateToken("Partner", RoleViewer)
+	require.NoError(t, err)
+
+	m.RecordRequest(kid, "GET", "/api/v1/library/collection", 200)
+	m.RecordRequest(kid, "DELETE", "/api/v1/library/local-files", 500)
+	m.RecordRequest(partner, "POST", "/api/v1/anilist/list-entry", 200)
+
+	entries, err := m.GetAuditEntries(kid.ID, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	// Most recent first
+	require.Equal(t, "DELETE", entries[0].Method)
+	require.Equal(t, "/api/v1/library/local-files", entries[0].Path)
+	require.Equal(t, 500, entries[0].Status)
+
+	tokens, err := m.GetTokens()
+	require.NoError(t, err)
+	require.Len(t, tokens, 2)
+	require.NotNil(t, tokens[0].LastUsedAt)
+
+	// Revoking a token deletes its audit entries
+	require.NoError(t, m.DeleteToken(kid.ID))
+	entries, err = m.GetAuditEntries(kid.ID, 0)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
@@ -6,7 +6,8 @@ import (
 )
 
 const (
-	BaseAnimeFields string = "id,title,main_picture,alternative_titles,start_date,end_date,start_season,nsfw,synopsis,num_episodes,mean,rank,popularity,media_type,status"
+	BaseAnimeFields       string = "id,title,main_picture,alternative_titles,start_date,end_date,start_season,nsfw,synopsis,num_episodes,mean,rank,popularity,media_type,status"
+	AnimeListStatusFields string = "list_status{status,score,num_episodes_watched,is_rewatching,num_times_rewatched,start_date,finish_date,updated_at}"
 )
 
 type (
@@ -50,7 +51,10 @@ type (
 			Status             MediaListStatus `json:"status"`
 			IsRewatching       bool            `json:"is_rewatching"`
 			NumEpisodesWatched int             `json:"num_episodes_watched"`
+			NumTimesRewatched  int             `json:"num_times_rewatched"`
 			Score              int             `json:"score"`
+			StartDate          string          `json:"start_date"`
+			FinishDate         string          `json:"finish_date"`
 			UpdatedAt          string          `json:"updated_at"`
 		} `json:"list_status"`
 	}
@@ -80,22 +84,28 @@ func (w *Wrapper) GetAnimeDetails(mId int) (*BasicAnime, error) {
 func (w *Wrapper) GetAnimeCollection() ([]*AnimeListEntry, error) {
 	w.logger.Debug().Msg("mal: Getting anime collection")
 
-	reqUrl := fmt.Sprintf("%s/users/@me/animelist?fields=list_status&limit=1000", ApiBaseURL)
+	reqUrl := fmt.Sprintf("%s/users/@me/animelist?fields=%s&limit=1000&nsfw=true", ApiBaseURL, AnimeListStatusFields)
 
 	type response struct {
-		Data []*AnimeListEntry `json:"data"`
+		Data   []*AnimeListEntry `json:"data"`
+		Paging Paging            `json:"paging"`
 	}
 
-	var data response
-	err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
-	if err != nil {
-		w.logger.Error().Err(err).Msg("mal: Failed to get anime collection")
-		return nil, err
+	ret := make([]*AnimeListEntry, 0)
+	for reqUrl != "" {
+		var data response
+		err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("mal: Failed to get anime collection")
+			return nil, err
+		}
+		ret = append(ret, data.Data...)
+		reqUrl = data.Paging.Next
 	}
 
-	w.logger.Info().Msg("mal: Fetched anime collection")
+	w.logger.Info().Int("count", len(ret)).Msg("mal: Fetched anime collection")
 
-	return data.Data, nil
+	return ret, nil
 }
 
 type AnimeListProgressParams struct {
@@ -137,7 +147,10 @@ type AnimeListStatusParams struct {
 	Status             *MediaListStatus
 	IsRewatching       *bool
 	NumEpisodesWatched *int
+	NumTimesRewatched  *int
 	Score              *int
+	StartDate          *string // YYYY-MM-DD, empty to clear
+	FinishDate         *string // YYYY-MM-DD, empty to clear
 }
 
 func (w *Wrapper) UpdateAnimeListStatus(opts *AnimeListStatusParams, mId int) error {
@@ -156,9 +169,18 @@ func (w *Wrapper) UpdateAnimeListStatus(opts *AnimeListStatusParams, mId int) er
 	if opts.NumEpisodesWatched != nil {
 		urlData.Set("num_watched_episodes", fmt.Sprintf("%d", *opts.NumEpisodesWatched))
 	}
+	if opts.NumTimesRewatched != nil {
+		urlData.Set("num_times_rewatched", fmt.Sprintf("%d", *opts.NumTimesRewatched))
+	}
 	if opts.Score != nil {
 		urlData.Set("score", fmt.Sprintf("%d", *opts.Score))
 	}
+	if opts.StartDate != nil {
+		urlData.Set("start_date", *opts.StartDate)
+	}
+	if opts.FinishDate != nil {
+		urlData.Set("finish_date", *opts.FinishDate)
+	}
 	encodedData := urlData.Encode()
 
 	err := w.doMutation("PATCH", reqUrl, encodedData)
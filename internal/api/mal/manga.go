@@ -6,7 +6,8 @@ import (
 )
 
 const (
-	BaseMangaFields string = "id,title,main_picture,alternative_titles,start_date,end_date,nsfw,synopsis,num_volumes,num_chapters,mean,rank,popularity,media_type,status"
+	BaseMangaFields       string = "id,title,main_picture,alternative_titles,start_date,end_date,nsfw,synopsis,num_volumes,num_chapters,mean,rank,popularity,media_type,status"
+	MangaListStatusFields string = "list_status{status,score,num_volumes_read,num_chapters_read,is_rereading,num_times_reread,start_date,finish_date,updated_at}"
 )
 
 type (
@@ -49,7 +50,10 @@ type (
 			IsRereading     bool            `json:"is_rereading"`
 			NumVolumesRead  int             `json:"num_volumes_read"`
 			NumChaptersRead int             `json:"num_chapters_read"`
+			NumTimesReread  int             `json:"num_times_reread"`
 			Score           int             `json:"score"`
+			StartDate       string          `json:"start_date"`
+			FinishDate      string          `json:"finish_date"`
 			UpdatedAt       string          `json:"updated_at"`
 		} `json:"list_status"`
 	}
@@ -79,22 +83,28 @@ func (w *Wrapper) GetMangaDetails(mId int) (*BasicManga, error) {
 func (w *Wrapper) GetMangaCollection() ([]*MangaListEntry, error) {
 	w.logger.Debug().Msg("mal: Getting manga collection")
 
-	reqUrl := fmt.Sprintf("%s/users/@me/mangalist?fields=list_status&limit=1000", ApiBaseURL)
+	reqUrl := fmt.Sprintf("%s/users/@me/mangalist?fields=%s&limit=1000&nsfw=true", ApiBaseURL, MangaListStatusFields)
 
 	type response struct {
-		Data []*MangaListEntry `json:"data"`
+		Data   []*MangaListEntry `json:"data"`
+		Paging Paging            `json:"paging"`
 	}
 
-	var data response
-	err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
-	if err != nil {
-		w.logger.Error().Err(err).Msg("mal: Failed to get manga collection")
-		return nil, err
+	ret := make([]*MangaListEntry, 0)
+	for reqUrl != "" {
+		var data response
+		err := w.doQuery("GET", reqUrl, nil, "application/json", &data)
+		if err != nil {
+			w.logger.Error().Err(err).Msg("mal: Failed to get manga collection")
+			return nil, err
+		}
+		ret = append(ret, data.Data...)
+		reqUrl = data.Paging.Next
 	}
 
-	w.logger.Info().Msg("mal: Fetched manga collection")
+	w.logger.Info().Int("count", len(ret)).Msg("mal: Fetched manga collection")
 
-	return data.Data, nil
+	return ret, nil
 }
 
 type MangaListProgressParams struct {
@@ -136,7 +146,10 @@ type MangaListStatusParams struct {
 	Status          *MediaListStatus
 	IsRereading     *bool
 	NumChaptersRead *int
+	NumTimesReread  *int
 	Score           *int
+	StartDate       *string // YYYY-MM-DD, empty to clear
+	FinishDate      *string // YYYY-MM-DD, empty to clear
 }
 
 func (w *Wrapper) UpdateMangaListStatus(opts *MangaListStatusParams, mId int) error {
@@ -155,9 +168,18 @@ func (w *Wrapper) UpdateMangaListStatus(opts *MangaListStatusParams, mId int) er
 	if opts.NumChaptersRead != nil {
 		urlData.Set("num_chapters_read", fmt.Sprintf("%d", *opts.NumChaptersRead))
 	}
+	if opts.NumTimesReread != nil {
+		urlData.Set("num_times_reread", fmt.Sprintf("%d", *opts.NumTimesReread))
+	}
 	if opts.Score != nil {
 		urlData.Set("score", fmt.Sprintf("%d", *opts.Score))
 	}
+	if opts.StartDate != nil {
+		urlData.Set("start_date", *opts.StartDate)
+	}
+	if opts.FinishDate != nil {
+		urlData.Set("finish_date", *opts.FinishDate)
+	}
 	encodedData := urlData.Encode()
 
 	err := w.doMutation("PATCH", reqUrl, encodedData)
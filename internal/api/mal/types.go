@@ -9,6 +9,12 @@ type (
 		ExpiresAt    time.Time
 	}
 
+	// Paging is returned by list endpoints, Next is empty on the last page
+	Paging struct {
+		Previous string `json:"previous"`
+		Next     string `json:"next"`
+	}
+
 	MediaType       string
 	MediaStatus     string
 	MediaListStatus string
@@ -0,0 +1,515 @@
+package backup
+
+import (
+	"archive/zip"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"seanime/internal/constants"
+	"seanime/internal/continuity"
+	"seanime/internal/database/db"
+	"seanime/internal/util/filecache"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrBackupNotFound   = errors.New("backup: Backup not found")
+	ErrBackupInProgress = errors.New("backup: A backup is already in progress")
+	ErrInvalidArchive   = errors.New("backup: Not a Seanime backup")
+	ErrNewerVersion     = errors.New("backup: The backup was created by a newer version of Seanime")
+	ErrDataDirNotFresh  = errors.New("backup: The data directory already contains a database")
+)
+
+const (
+	DefaultIntervalHours = 24
+	DefaultRetention     = 7
+	// DirName is the name of the default backup directory in the data directory
+	DirName = "backups"
+)
+
+const (
+	manifestFilename     = "manifest.json"
+	databaseFilename     = "database.db"
+	archiveCacheDir      = "cache"
+	archiveExtensionsDir = "extensions"
+	filenamePrefix       = "seanime-backup-"
+	filenameExt          = ".zip"
+	filenameTimeFormat   = "20060102-150405"
+	// checkInterval is how often the scheduler checks if a backup is due
+	checkInterval = 10 * time.Minute
+)
+
+type (
+	// Manifest describes the content of an archive
+	Manifest struct {
+		Version      string    `json:"version"`
+		CreatedAt    time.Time `json:"createdAt"`
+		DatabaseName string    `json:"databaseName"`
+		Scheduled    bool      `json:"scheduled"`
+	}
+
+	// Backup is an archive in the backup directory
+	Backup struct {
+		Filename  string    `json:"filename"`
+		Size      int64     `json:"size"`
+		Version   string    `json:"version"`
+		CreatedAt time.Time `json:"createdAt"`
+		Scheduled bool      `json:"scheduled"`
+	}
+
+	Settings struct {
+		// Enabled creates backups on a schedule
+		Enabled  bool
+		Interval time.Duration
+		// Retention is the number of scheduled backups to keep, 0 keeps all of them
+		Retention int
+		Dir       string
+	}
+
+	// Manager creates archives of the database, the watch history and manga buckets of the file cache,
+	// the installed extensions and the config file.
+	Manager struct {
+		logger        *zerolog.Logger
+		database      *db.Database
+		fileCacher    *filecache.Cacher
+		databaseName  string
+		configPath    string
+		extensionsDir string
+		defaultDir    string
+
+		mu       sync.Mutex
+		settings Settings
+		// createMu is held while an archive is written
+		createMu sync.Mutex
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+
+	NewManagerOptions struct {
+		Logger        *zerolog.Logger
+		Database      *db.Database
+		FileCacher    *filecache.Cacher
+		DatabaseName  string
+		ConfigPath    string
+		ExtensionsDir string
+		// DefaultDir is used when the backup directory is not set
+		DefaultDir string
+	}
+)
+
+func NewManager(opts *NewManagerOptions) *Manager {
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Manager{
+		logger:        opts.Logger,
+		database:      opts.Database,
+		fileCacher:    opts.FileCacher,
+		databaseName:  opts.DatabaseName,
+		configPath:    opts.ConfigPath,
+		extensionsDir: opts.ExtensionsDir,
+		defaultDir:    opts.DefaultDir,
+		ctx:           ctx,
+		cancel:        cancel,
+	}
+	m.wg.Add(1)
+	go m.run()
+	return m
+}
+
+func (m *Manager) SetSettings(settings Settings) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.settings = settings
+}
+
+// Stop stops the scheduler, it waits for a running scheduled backup to finish.
+func (m *Manager) Stop() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// GetDir returns the directory where backups are written.
+func (m *Manager) GetDir() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.settings.Dir != "" {
+		return m.settings.Dir
+	}
+	return m.defaultDir
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// isBackedUpBucket returns true for the file cache buckets that cannot be fetched again.
+func isBackedUpBucket(name string) bool {
+	switch {
+	case strings.HasPrefix(name, continuity.WatchHistoryBucketName), strings.HasPrefix(name, continuity.ReadingHistoryBucketName):
+		return true
+	// Chapter containers, e.g. "manga_<provider>_chapters_<mediaId>"
+	case strings.HasPrefix(name, "manga_") && strings.Contains(name, "_chapters_"):
+		return true
+	case strings.HasPrefix(name, "manga_downloaded_"), name == "manga-preferences":
+		return true
+	case name == "pending-media-list-updates":
+		return true
+	}
+	return false
+}
+
+// CreateBackup writes an archive to the backup directory.
+func (m *Manager) CreateBackup(scheduled bool) (*Backup, error) {
+	if !m.createMu.TryLock() {
+		return nil, ErrBackupInProgress
+	}
+	defer m.createMu.Unlock()
+
+	dir := m.GetDir()
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Version:      constants.Version,
+		CreatedAt:    time.Now(),
+		DatabaseName: m.databaseName,
+		Scheduled:    scheduled,
+	}
+
+	filename := filenamePrefix + manifest.CreatedAt.Format(filenameTimeFormat) + filenameExt
+	for i := 1; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, filename)); os.IsNotExist(err) {
+			break
+		}
+		filename = filenamePrefix + manifest.CreatedAt.Format(filenameTimeFormat) + "-" + strconv.Itoa(i) + filenameExt
+	}
+	dest := filepath.Join(dir, filename)
+
+	// The archive is renamed once it is complete
+	partial := dest + ".partial"
+	if err := m.writeArchive(partial, manifest); err != nil {
+		_ = os.Remove(partial)
+		return nil, err
+	}
+	if err := os.Rename(partial, dest); err != nil {
+		_ = os.Remove(partial)
+		return nil, err
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info().Str("filename", filename).Int64("size", info.Size()).Bool("scheduled", scheduled).Msg("backup: Backup created")
+
+	return &Backup{
+		Filename:  filename,
+		Size:      info.Size(),
+		Version:   manifest.Version,
+		CreatedAt: manifest.CreatedAt,
+		Scheduled: scheduled,
+	}, nil
+}
+
+func (m *Manager) writeArchive(dest string, manifest *Manifest) (err error) {
+	tmpDir, err := os.MkdirTemp("", "seanime-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Snapshot the database first, the rest of the data is small
+	dbSnapshot := filepath.Join(tmpDir, databaseFilename)
+	if err := m.database.SnapshotTo(dbSnapshot); err != nil {
+		return fmt.Errorf("backup: Failed to snapshot the database: %w", err)
+	}
+
+	file, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	zw := zip.NewWriter(file)
+	defer func() {
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+
+	manifestData, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	if err := writeArchiveFile(zw, manifestFilename, manifestData); err != nil {
+		return err
+	}
+
+	if err := copyFileToArchive(zw, databaseFilename, dbSnapshot); err != nil {
+		return err
+	}
+
+	if m.configPath != "" {
+		if err := copyFileToArchive(zw, constants.ConfigFileName, m.configPath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	if m.fileCacher != nil {
+		err := m.fileCacher.Export(isBackedUpBucket, func(bucketName string, data []byte) error {
+			return writeArchiveFile(zw, path.Join(archiveCacheDir, bucketName+".cache"), data)
+		})
+		if err != nil {
+			return fmt.Errorf("backup: Failed to export the file cache: %w", err)
+		}
+	}
+
+	if m.extensionsDir != "" {
+		err := filepath.WalkDir(m.extensionsDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			rel, err := filepath.Rel(m.extensionsDir, p)
+			if err != nil {
+				return err
+			}
+			return copyFileToArchive(zw, path.Join(archiveExtensionsDir, filepath.ToSlash(rel)), p)
+		})
+		if err != nil {
+			return fmt.Errorf("backup: Failed to copy the extensions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func writeArchiveFile(zw *zip.Writer, name string, data []byte) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: time.Now()})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func copyFileToArchive(zw *zip.Writer, name string, src string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: info.ModTime()})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, file)
+	return err
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// ListBackups returns the backups of the backup directory, newest first.
+func (m *Manager) ListBackups() ([]*Backup, error) {
+	dir := m.GetDir()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Backup{}, nil
+		}
+		return nil, err
+	}
+
+	ret := make([]*Backup, 0, len(entries))
+	for _, e := range entries {
+		if e.IsDir() || !isBackupFilename(e.Name()) {
+			continue
+		}
+		b, err := readBackup(filepath.Join(dir, e.Name()))
+		if err != nil {
+			m.logger.Warn().Err(err).Str("filename", e.Name()).Msg("backup: Failed to read backup")
+			continue
+		}
+		ret = append(ret, b)
+	}
+
+	slices.SortFunc(ret, func(a, b *Backup) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.Filename, a.Filename))
+	})
+
+	return ret, nil
+}
+
+// GetBackupPath returns the path of a backup of the backup directory.
+func (m *Manager) GetBackupPath(filename string) (string, error) {
+	if !isBackupFilename(filename) {
+		return "", ErrBackupNotFound
+	}
+	p := filepath.Join(m.GetDir(), filename)
+	if _, err := os.Stat(p); err != nil {
+		return "", ErrBackupNotFound
+	}
+	return p, nil
+}
+
+func (m *Manager) DeleteBackup(filename string) error {
+	p, err := m.GetBackupPath(filename)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(p); err != nil {
+		return err
+	}
+	m.logger.Info().Str("filename", filename).Msg("backup: Backup deleted")
+	return nil
+}
+
+func isBackupFilename(filename string) bool {
+	return filepath.Base(filename) == filename &&
+		strings.HasPrefix(filename, filenamePrefix) &&
+		strings.HasSuffix(filename, filenameExt)
+}
+
+func readBackup(p string) (*Backup, error) {
+	r, err := zip.OpenReader(p)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	manifest, err := readManifest(&r.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Backup{
+		Filename:  filepath.Base(p),
+		Size:      info.Size(),
+		Version:   manifest.Version,
+		CreatedAt: manifest.CreatedAt,
+		Scheduled: manifest.Scheduled,
+	}, nil
+}
+
+func readManifest(r *zip.Reader) (*Manifest, error) {
+	file, err := r.Open(manifestFilename)
+	if err != nil {
+		return nil, ErrInvalidArchive
+	}
+	defer file.Close()
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(file).Decode(manifest); err != nil || manifest.Version == "" {
+		return nil, ErrInvalidArchive
+	}
+	return manifest, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (m *Manager) run() {
+	defer m.wg.Done()
+
+	ticker := time.NewTicker(checkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			m.runScheduledBackup()
+		}
+	}
+}
+
+// runScheduledBackup creates a backup if the last scheduled backup is older than the interval, and deletes the old ones.
+func (m *Manager) runScheduledBackup() {
+	m.mu.Lock()
+	settings := m.settings
+	m.mu.Unlock()
+
+	if !settings.Enabled || settings.Interval <= 0 {
+		return
+	}
+
+	backups, err := m.ListBackups()
+	if err != nil {
+		m.logger.Error().Err(err).Msg("backup: Failed to list backups")
+		return
+	}
+
+	for _, b := range backups {
+		if b.Scheduled {
+			if time.Since(b.CreatedAt) < settings.Interval {
+				return
+			}
+			break
+		}
+	}
+
+	if _, err := m.CreateBackup(true); err != nil {
+		m.logger.Error().Err(err).Msg("backup: Scheduled backup failed")
+		return
+	}
+
+	m.pruneBackups(settings.Retention)
+}
+
+// pruneBackups deletes the oldest scheduled backups, keeping the given number of them.
+func (m *Manager) pruneBackups(retention int) {
+	if retention <= 0 {
+		return
+	}
+
+	backups, err := m.ListBackups()
+	if err != nil {
+		m.logger.Error().Err(err).Msg("backup: Failed to list backups")
+		return
+	}
+
+	kept := 0
+	for _, b := range backups {
+		if !b.Scheduled {
+			continue
+		}
+		if kept < retention {
+			kept++
+			continue
+		}
+		if err := m.DeleteBackup(b.Filename); err != nil {
+			m.logger.Error().Err(err).Str("filename", b.Filename).Msg("backup: Failed to delete old backup")
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package backup
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"seanime/internal/constants"
+	"seanime/internal/continuity"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"seanime/internal/util/filecache"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestManager(t *testing.T) (*Manager, string) {
+	t.Helper()
+	t.Setenv("TEST_ENV", "false")
+
+	dataDir := t.TempDir()
+	logger := util.NewLogger()
+
+	database, err := db.NewDatabase(dataDir, "seanime", logger)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := database.Gorm().DB()
+		_ = sqlDB.Close()
+	})
+	require.NoError(t, database.Gorm().Create(&models.Profile{Name: "Backed up"}).Error)
+
+	fileCacher, err := filecache.NewCacher(filepath.Join(dataDir, "cache"))
+	require.NoError(t, err)
+	require.NoError(t, fileCacher.Set(filecache.NewBucket(continuity.WatchHistoryBucketName, time.Hour), "1", 42))
+	require.NoError(t, fileCacher.Set(filecache.NewBucket("manga_provider_pages_1", time.Hour), "1", "pages"))
+
+	extensionsDir := filepath.Join(dataDir, "extensions")
+	require.NoError(t, os.MkdirAll(filepath.Join(extensionsDir, "plugins"), 0700))
+	require.NoError(t, os.WriteFile(filepath.Join(extensionsDir, "plugins", "plugin.json"), []byte(`{"id":"plugin"}`), 0600))
+
+	configPath := filepath.Join(dataDir, constants.ConfigFileName)
+	require.NoError(t, os.WriteFile(configPath, []byte("version = '3.0.0'\n"), 0600))
+
+	m := NewManager(&NewManagerOptions{
+		Logger:        logger,
+		Database:      database,
+		FileCacher:    fileCacher,
+		DatabaseName:  "seanime",
+		ConfigPath:    configPath,
+		ExtensionsDir: extensionsDir,
+		DefaultDir:    filepath.Join(dataDir, DirName),
+	})
+	t.Cleanup(m.Stop)
+
+	return m, dataDir
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	b, err := m.CreateBackup(false)
+	require.NoError(t, err)
+	require.Equal(t, constants.Version, b.Version)
+	require.False(t, b.Scheduled)
+
+	backups, err := m.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 1)
+	require.Equal(t, b.Filename, backups[0].Filename)
+
+	archivePath, err := m.GetBackupPath(b.Filename)
+	require.NoError(t, err)
+	_, err = m.GetBackupPath("../" + b.Filename)
+	require.ErrorIs(t, err, ErrBackupNotFound)
+
+	// Restore into a fresh data directory
+	restoreDir := t.TempDir()
+	opts := &RestoreOptions{
+		DataDir:       restoreDir,
+		DatabaseName:  "seanime",
+		ConfigPath:    filepath.Join(restoreDir, constants.ConfigFileName),
+		CacheDir:      filepath.Join(restoreDir, "cache"),
+		ExtensionsDir: filepath.Join(restoreDir, "extensions"),
+	}
+	manifest, err := Restore(archivePath, opts)
+	require.NoError(t, err)
+	require.Equal(t, constants.Version, manifest.Version)
+
+	config, err := os.ReadFile(opts.ConfigPath)
+	require.NoError(t, err)
+	require.Contains(t, string(config), "3.0.0")
+	require.FileExists(t, filepath.Join(opts.ExtensionsDir, "plugins", "plugin.json"))
+	require.FileExists(t, filepath.Join(opts.CacheDir, continuity.WatchHistoryBucketName+".cache"))
+	require.NoFileExists(t, filepath.Join(opts.CacheDir, "manga_provider_pages_1.cache"))
+
+	restoredCacher, err := filecache.NewCacher(opts.CacheDir)
+	require.NoError(t, err)
+	var progress int
+	found, err := restoredCacher.Get(filecache.NewBucket(continuity.WatchHistoryBucketName, time.Hour), "1", &progress)
+	require.NoError(t, err)
+	require.True(t, found)
+	require.Equal(t, 42, progress)
+
+	// The restored database is migrated when it is opened
+	restoredDb, err := db.NewDatabase(restoreDir, "seanime", util.NewLogger())
+	require.NoError(t, err)
+	var profile models.Profile
+	require.NoError(t, restoredDb.Gorm().Where("name = ?", "Backed up").First(&profile).Error)
+	sqlDB, _ := restoredDb.Gorm().DB()
+	_ = sqlDB.Close()
+
+	// The data directory is no longer fresh
+	_, err = Restore(archivePath, opts)
+	require.ErrorIs(t, err, ErrDataDirNotFresh)
+}
+
+func TestRestore_NewerVersion(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "seanime-backup-test.zip")
+	file, err := os.Create(archivePath)
+	require.NoError(t, err)
+	zw := zip.NewWriter(file)
+	require.NoError(t, writeArchiveFile(zw, manifestFilename, []byte(`{"version":"99.0.0"}`)))
+	require.NoError(t, writeArchiveFile(zw, databaseFilename, []byte{}))
+	require.NoError(t, zw.Close())
+	require.NoError(t, file.Close())
+
+	restoreDir := t.TempDir()
+	_, err = Restore(archivePath, &RestoreOptions{DataDir: restoreDir, DatabaseName: "seanime"})
+	require.ErrorIs(t, err, ErrNewerVersion)
+	require.NoFileExists(t, filepath.Join(restoreDir, "seanime.db"))
+}
+
+func TestScheduledBackups(t *testing.T) {
+	m, _ := newTestManager(t)
+
+	_, err := m.CreateBackup(false)
+	require.NoError(t, err)
+
+	m.SetSettings(Settings{Enabled: true, Interval: time.Hour, Retention: 2})
+
+	m.runScheduledBackup()
+	backups, err := m.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+
+	// The last scheduled backup is recent
+	m.runScheduledBackup()
+	backups, err = m.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+
+	m.SetSettings(Settings{Enabled: true, Interval: time.Nanosecond, Retention: 2})
+	m.runScheduledBackup()
+	m.runScheduledBackup()
+
+	// Only the newest scheduled backups are kept, manual backups are not deleted
+	backups, err = m.ListBackups()
+	require.NoError(t, err)
+	require.Len(t, backups, 3)
+	scheduled := 0
+	for _, b := range backups {
+		if b.Scheduled {
+			scheduled++
+		}
+	}
+	require.Equal(t, 2, scheduled)
+}
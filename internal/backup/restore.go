@@ -0,0 +1,120 @@
+package backup
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"seanime/internal/constants"
+	"seanime/internal/util"
+	"strings"
+)
+
+type RestoreOptions struct {
+	DataDir      string
+	DatabaseName string
+	ConfigPath   string
+	CacheDir     string
+	// ExtensionsDir receives the installed extensions of the backup
+	ExtensionsDir string
+}
+
+// Restore extracts an archive into a data directory that does not have a database yet.
+// Archives created by a newer version are rejected.
+//
+// The restored database is migrated when it is opened, and the restored config keeps the version of the archive
+// so that the version migrations run on the next startup.
+func Restore(archivePath string, opts *RestoreOptions) (*Manifest, error) {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return nil, fmt.Errorf("backup: Failed to open archive: %w", err)
+	}
+	defer r.Close()
+
+	manifest, err := readManifest(&r.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if util.VersionIsOlderThan(constants.Version, manifest.Version) {
+		return nil, fmt.Errorf("%w (%s)", ErrNewerVersion, manifest.Version)
+	}
+
+	dbPath := filepath.Join(opts.DataDir, opts.DatabaseName+".db")
+	if _, err := os.Stat(dbPath); err == nil {
+		return nil, ErrDataDirNotFresh
+	}
+
+	hasDatabase := false
+	for _, file := range r.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		var dest string
+		switch {
+		case file.Name == databaseFilename:
+			// The database is moved in place once everything else is restored
+			dest = dbPath + ".partial"
+			hasDatabase = true
+		case file.Name == constants.ConfigFileName:
+			dest = opts.ConfigPath
+		case strings.HasPrefix(file.Name, archiveCacheDir+"/"):
+			name := strings.TrimPrefix(file.Name, archiveCacheDir+"/")
+			if !isBackedUpBucket(strings.TrimSuffix(name, ".cache")) || path.Base(name) != name {
+				continue
+			}
+			dest = filepath.Join(opts.CacheDir, name)
+		case strings.HasPrefix(file.Name, archiveExtensionsDir+"/"):
+			rel := filepath.FromSlash(strings.TrimPrefix(file.Name, archiveExtensionsDir+"/"))
+			if !filepath.IsLocal(rel) {
+				return nil, fmt.Errorf("%w: invalid path %q", ErrInvalidArchive, file.Name)
+			}
+			dest = filepath.Join(opts.ExtensionsDir, rel)
+		default:
+			continue
+		}
+
+		if dest == "" {
+			continue
+		}
+		if err := extractFile(file, dest); err != nil {
+			return nil, fmt.Errorf("backup: Failed to restore %s: %w", file.Name, err)
+		}
+	}
+
+	if !hasDatabase {
+		return nil, fmt.Errorf("%w: missing database", ErrInvalidArchive)
+	}
+
+	if err := os.Rename(dbPath+".partial", dbPath); err != nil {
+		return nil, err
+	}
+
+	return manifest, nil
+}
+
+func extractFile(file *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0700); err != nil {
+		return err
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	if _, err := io.Copy(out, src); err != nil {
+		return err
+	}
+	return out.Close()
+}
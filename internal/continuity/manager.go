@@ -1,6 +1,7 @@
 package continuity
 
 import (
+	"fmt"
 	"github.com/rs/zerolog"
 	"github.com/samber/mo"
 	"seanime/internal/database/db"
@@ -18,9 +19,10 @@ const (
 type (
 	// Manager is used to manage the user's viewing history across different media types.
 	Manager struct {
-		fileCacher                  *filecache.Cacher
-		db                          *db.Database
-		watchHistoryFileCacheBucket *filecache.Bucket
+		fileCacher                    *filecache.Cacher
+		db                            *db.Database
+		watchHistoryFileCacheBucket   *filecache.Bucket
+		readingHistoryFileCacheBucket *filecache.Bucket
 
 		externalPlayerEpisodeDetails mo.Option[*ExternalPlayerEpisodeDetails]
 
@@ -55,12 +57,14 @@ type (
 // NewManager creates a new Manager, it should be initialized once.
 func NewManager(opts *NewManagerOptions) *Manager {
 	watchHistoryFileCacheBucket := filecache.NewBucket(WatchHistoryBucketName, time.Hour*24*99999)
+	readingHistoryFileCacheBucket := filecache.NewBucket(ReadingHistoryBucketName, time.Hour*24*99999)
 
 	ret := &Manager{
-		fileCacher:                  opts.FileCacher,
-		logger:                      opts.Logger,
-		db:                          opts.Database,
-		watchHistoryFileCacheBucket: &watchHistoryFileCacheBucket,
+		fileCacher:                    opts.FileCacher,
+		logger:                        opts.Logger,
+		db:                            opts.Database,
+		watchHistoryFileCacheBucket:   &watchHistoryFileCacheBucket,
+		readingHistoryFileCacheBucket: &readingHistoryFileCacheBucket,
 		settings: &Settings{
 			WatchContinuityEnabled: false,
 		},
@@ -94,6 +98,46 @@ func (m *Manager) GetSettings() *Settings {
 	return m.settings
 }
 
+// SetProfileScope switches the watch and reading history to the ones of the profile.
+// See db.GetProfileScope.
+func (m *Manager) SetProfileScope(scope uint) {
+	if m == nil {
+		return
+	}
+
+	bucket := filecache.NewBucket(watchHistoryBucketName(scope), time.Hour*24*99999)
+	readingBucket := filecache.NewBucket(readingHistoryBucketName(scope), time.Hour*24*99999)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.watchHistoryFileCacheBucket = &bucket
+	m.readingHistoryFileCacheBucket = &readingBucket
+	m.externalPlayerEpisodeDetails = mo.None[*ExternalPlayerEpisodeDetails]()
+}
+
+// RemoveProfileWatchHistory deletes the watch and reading history of a profile.
+func (m *Manager) RemoveProfileWatchHistory(scope uint) error {
+	if m == nil || scope == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err := m.fileCacher.Remove(watchHistoryBucketName(scope)); err != nil {
+		return err
+	}
+	return m.fileCacher.Remove(readingHistoryBucketName(scope))
+}
+
+// watchHistoryBucketName returns the name of the watch history bucket of the profile.
+// The default profile (scope 0) uses the bucket created before profiles existed.
+func watchHistoryBucketName(scope uint) string {
+	if scope == 0 {
+		return WatchHistoryBucketName
+	}
+	return fmt.Sprintf("%s_profile_%d", WatchHistoryBucketName, scope)
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 func (m *Manager) SetExternalPlayerEpisodeDetails(details *ExternalPlayerEpisodeDetails) {
@@ -258,6 +258,41 @@ func TestGetExternalPlayerEpisodeWatchHistoryItemLocalFile(t *testing.T) {
 	require.NotNil(t, byFilename.Item)
 }
 
+func TestWatchHistoryIsScopedByProfile(t *testing.T) {
+	manager, _ := newHistoryTestManager(t)
+	manager.SetSettings(&Settings{WatchContinuityEnabled: true})
+
+	require.NoError(t, manager.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{
+		CurrentTime:   600,
+		Duration:      1440,
+		MediaId:       1,
+		EpisodeNumber: 1,
+		Kind:          MediastreamKind,
+	}))
+	require.True(t, manager.GetWatchHistoryItem(1).Found)
+
+	// Another profile has its own history
+	manager.SetProfileScope(2)
+	require.False(t, manager.GetWatchHistoryItem(1).Found)
+	require.NoError(t, manager.UpdateWatchHistoryItem(&UpdateWatchHistoryItemOptions{
+		CurrentTime:   700,
+		Duration:      1440,
+		MediaId:       2,
+		EpisodeNumber: 4,
+		Kind:          MediastreamKind,
+	}))
+	require.Len(t, manager.GetWatchHistory(), 1)
+
+	// The default profile keeps its history
+	manager.SetProfileScope(0)
+	require.True(t, manager.GetWatchHistoryItem(1).Found)
+	require.False(t, manager.GetWatchHistoryItem(2).Found)
+
+	require.NoError(t, manager.RemoveProfileWatchHistory(2))
+	manager.SetProfileScope(2)
+	require.Empty(t, manager.GetWatchHistory())
+}
+
 func newHistoryTestManager(t *testing.T) (*Manager, *filecache.Cacher) {
 	t.Helper()
 
@@ -0,0 +1,224 @@
+package continuity
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"seanime/internal/util"
+	"seanime/internal/util/filecache"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	MangaKind Kind = "manga"
+
+	MaxReadingHistoryItems   = 100
+	ReadingHistoryBucketName = "reading_history"
+)
+
+type (
+	// ReadingHistory is a map of ReadingHistoryItem.
+	// The key is the ReadingHistoryItem.MediaId.
+	ReadingHistory map[int]*ReadingHistoryItem
+
+	// ReadingHistoryItem records the exact page a manga was left at.
+	// It is stored in the file cache so that reading can resume on any device.
+	// Only one item per MediaId exists in the history.
+	ReadingHistoryItem struct {
+		Kind          Kind   `json:"kind"`
+		MediaId       int    `json:"mediaId"`
+		Provider      string `json:"provider"`
+		ChapterId     string `json:"chapterId"`
+		ChapterNumber string `json:"chapterNumber"`
+		// The index of the current page, from 0.
+		PageIndex int `json:"pageIndex"`
+		// The number of pages in the chapter, 0 if unknown.
+		PageCount int `json:"pageCount"`
+		// The client that last updated the item.
+		// Used by clients to tell whether reading started on another device.
+		ClientId    string    `json:"clientId,omitempty"`
+		TimeAdded   time.Time `json:"timeAdded"`
+		TimeUpdated time.Time `json:"timeUpdated"`
+	}
+
+	ReadingHistoryItemResponse struct {
+		Item  *ReadingHistoryItem `json:"item"`
+		Found bool                `json:"found"`
+	}
+
+	UpdateReadingHistoryItemOptions struct {
+		MediaId       int    `json:"mediaId"`
+		Provider      string `json:"provider"`
+		ChapterId     string `json:"chapterId"`
+		ChapterNumber string `json:"chapterNumber"`
+		PageIndex     int    `json:"pageIndex"`
+		PageCount     int    `json:"pageCount"`
+		ClientId      string `json:"-"`
+	}
+)
+
+// IsChapterFinished returns true if the last page of the chapter was reached.
+func (i *ReadingHistoryItem) IsChapterFinished() bool {
+	return i.PageCount > 0 && i.PageIndex >= i.PageCount-1
+}
+
+func (opts *UpdateReadingHistoryItemOptions) validate() error {
+	if opts.MediaId <= 0 {
+		return errors.New("invalid media id")
+	}
+	if strings.TrimSpace(opts.Provider) == "" || strings.TrimSpace(opts.ChapterId) == "" {
+		return errors.New("provider and chapter id are required")
+	}
+	if opts.PageIndex < 0 || opts.PageCount < 0 {
+		return errors.New("invalid page")
+	}
+	if opts.PageCount > 0 && opts.PageIndex >= opts.PageCount {
+		return errors.New("page index is out of range")
+	}
+	return nil
+}
+
+// readingHistoryBucketName returns the name of the reading history bucket of the profile.
+func readingHistoryBucketName(scope uint) string {
+	if scope == 0 {
+		return ReadingHistoryBucketName
+	}
+	return fmt.Sprintf("%s_profile_%d", ReadingHistoryBucketName, scope)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (m *Manager) GetReadingHistory() ReadingHistory {
+	defer util.HandlePanicInModuleThen("continuity/GetReadingHistory", func() {})
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	items, err := filecache.GetAll[*ReadingHistoryItem](m.fileCacher, *m.readingHistoryFileCacheBucket)
+	if err != nil {
+		m.logger.Error().Err(err).Msg("continuity: Failed to get reading history")
+		return nil
+	}
+
+	ret := make(ReadingHistory)
+	for _, item := range items {
+		ret[item.MediaId] = item
+	}
+
+	return ret
+}
+
+// GetContinueReadingItems returns the reading history items, most recently read first.
+func (m *Manager) GetContinueReadingItems() []*ReadingHistoryItem {
+	history := m.GetReadingHistory()
+
+	ret := make([]*ReadingHistoryItem, 0, len(history))
+	for _, item := range history {
+		ret = append(ret, item)
+	}
+	slices.SortFunc(ret, func(a, b *ReadingHistoryItem) int {
+		return cmp.Or(b.TimeUpdated.Compare(a.TimeUpdated), cmp.Compare(a.MediaId, b.MediaId))
+	})
+
+	return ret
+}
+
+func (m *Manager) GetReadingHistoryItem(mediaId int) *ReadingHistoryItemResponse {
+	defer util.HandlePanicInModuleThen("continuity/GetReadingHistoryItem", func() {})
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	i, found := m.getReadingHistory(mediaId)
+	return &ReadingHistoryItemResponse{
+		Item:  i,
+		Found: found,
+	}
+}
+
+// UpdateReadingHistoryItem records the current page of a manga.
+func (m *Manager) UpdateReadingHistoryItem(opts *UpdateReadingHistoryItemOptions) (ret *ReadingHistoryItem, err error) {
+	defer util.HandlePanicInModuleWithError("continuity/UpdateReadingHistoryItem", &err)
+
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	i, found := m.getReadingHistory(opts.MediaId)
+	if !found {
+		i = &ReadingHistoryItem{
+			Kind:      MangaKind,
+			MediaId:   opts.MediaId,
+			TimeAdded: now,
+		}
+	}
+	i.Provider = opts.Provider
+	i.ChapterId = opts.ChapterId
+	i.ChapterNumber = opts.ChapterNumber
+	i.PageIndex = opts.PageIndex
+	i.PageCount = opts.PageCount
+	i.ClientId = opts.ClientId
+	i.TimeUpdated = now
+
+	err = m.fileCacher.Set(*m.readingHistoryFileCacheBucket, strconv.Itoa(opts.MediaId), i)
+	if err != nil {
+		return nil, fmt.Errorf("continuity: Failed to save reading history item: %w", err)
+	}
+
+	if !found {
+		_ = m.trimReadingHistoryItems()
+	}
+
+	return i, nil
+}
+
+func (m *Manager) DeleteReadingHistoryItem(mediaId int) (err error) {
+	defer util.HandlePanicInModuleWithError("continuity/DeleteReadingHistoryItem", &err)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	err = m.fileCacher.Delete(*m.readingHistoryFileCacheBucket, strconv.Itoa(mediaId))
+	if err != nil {
+		return fmt.Errorf("continuity: Failed to delete reading history item: %w", err)
+	}
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (m *Manager) getReadingHistory(mediaId int) (ret *ReadingHistoryItem, exists bool) {
+	exists, _ = m.fileCacher.Get(*m.readingHistoryFileCacheBucket, strconv.Itoa(mediaId), &ret)
+	return ret, exists && ret != nil
+}
+
+// removes the least recently read items from the file cache.
+func (m *Manager) trimReadingHistoryItems() error {
+	items, err := filecache.GetAll[*ReadingHistoryItem](m.fileCacher, *m.readingHistoryFileCacheBucket)
+	if err != nil {
+		return fmt.Errorf("continuity: Failed to get reading history items: %w", err)
+	}
+
+	for len(items) > MaxReadingHistoryItems {
+		var oldestKey string
+		for key := range items {
+			if oldestKey == "" || items[key].TimeUpdated.Before(items[oldestKey].TimeUpdated) {
+				oldestKey = key
+			}
+		}
+		if err := m.fileCacher.Delete(*m.readingHistoryFileCacheBucket, oldestKey); err != nil {
+			return fmt.Errorf("continuity: Failed to remove oldest reading history item: %w", err)
+		}
+		delete(items, oldestKey)
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package continuity
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateReadingHistoryItemResumesOnAnotherClient(t *testing.T) {
+	manager, _ := newHistoryTestManager(t)
+
+	item, err := manager.UpdateReadingHistoryItem(&UpdateReadingHistoryItemOptions{
+		MediaId:       30013,
+		Provider:      "comick",
+		ChapterId:     "ch_1",
+		ChapterNumber: "1",
+		PageIndex:     4,
+		PageCount:     20,
+		ClientId:      "desktop",
+	})
+	require.NoError(t, err)
+	require.Equal(t, MangaKind, item.Kind)
+	require.False(t, item.IsChapterFinished())
+	timeAdded := item.TimeAdded
+
+	// The item is returned to the other client at the same page
+	response := manager.GetReadingHistoryItem(30013)
+	require.True(t, response.Found)
+	require.Equal(t, "ch_1", response.Item.ChapterId)
+	require.Equal(t, 4, response.Item.PageIndex)
+	require.Equal(t, "desktop", response.Item.ClientId)
+
+	item, err = manager.UpdateReadingHistoryItem(&UpdateReadingHistoryItemOptions{
+		MediaId:       30013,
+		Provider:      "comick",
+		ChapterId:     "ch_2",
+		ChapterNumber: "2",
+		PageIndex:     17,
+		PageCount:     18,
+		ClientId:      "phone",
+	})
+	require.NoError(t, err)
+	require.True(t, item.IsChapterFinished())
+	require.True(t, item.TimeAdded.Equal(timeAdded))
+
+	response = manager.GetReadingHistoryItem(30013)
+	require.Equal(t, "ch_2", response.Item.ChapterId)
+	require.Equal(t, "phone", response.Item.ClientId)
+
+	// The watch history is not affected
+	require.Empty(t, manager.GetWatchHistory())
+
+	require.NoError(t, manager.DeleteReadingHistoryItem(30013))
+	require.False(t, manager.GetReadingHistoryItem(30013).Found)
+}
+
+func TestUpdateReadingHistoryItemValidation(t *testing.T) {
+	manager, _ := newHistoryTestManager(t)
+
+	for _, opts := range []*UpdateReadingHistoryItemOptions{
+		{MediaId: 0, Provider: "comick", ChapterId: "ch_1"},
+		{MediaId: 1, Provider: "", ChapterId: "ch_1"},
+		{MediaId: 1, Provider: "comick", ChapterId: "ch_1", PageIndex: -1},
+		{MediaId: 1, Provider: "comick", ChapterId: "ch_1", PageIndex: 10, PageCount: 10},
+	} {
+		_, err := manager.UpdateReadingHistoryItem(opts)
+		require.Error(t, err)
+	}
+}
+
+func TestContinueReadingItemsAreSortedAndTrimmed(t *testing.T) {
+	manager, cacher := newHistoryTestManager(t)
+	baseTime := time.Now().Add(-time.Hour)
+
+	for mediaId := 1; mediaId <= MaxReadingHistoryItems; mediaId++ {
+		require.NoError(t, cacher.Set(*manager.readingHistoryFileCacheBucket, strconv.Itoa(mediaId), &ReadingHistoryItem{
+			Kind:        MangaKind,
+			MediaId:     mediaId,
+			Provider:    "comick",
+			ChapterId:   "ch_1",
+			TimeAdded:   baseTime.Add(time.Duration(mediaId) * time.Second),
+			TimeUpdated: baseTime.Add(time.Duration(mediaId) * time.Second),
+		}))
+	}
+
+	_, err := manager.UpdateReadingHistoryItem(&UpdateReadingHistoryItemOptions{
+		MediaId: 1000, Provider: "comick", ChapterId: "ch_1",
+	})
+	require.NoError(t, err)
+
+	items := manager.GetContinueReadingItems()
+	require.Len(t, items, MaxReadingHistoryItems)
+	require.Equal(t, 1000, items[0].MediaId)
+	require.Equal(t, MaxReadingHistoryItems, items[1].MediaId)
+	// The least recently read item was removed
+	require.False(t, manager.GetReadingHistoryItem(1).Found)
+}
+
+func TestReadingHistoryIsScopedByProfile(t *testing.T) {
+	manager, _ := newHistoryTestManager(t)
+
+	_, err := manager.UpdateReadingHistoryItem(&UpdateReadingHistoryItemOptions{MediaId: 1, Provider: "comick", ChapterId: "ch_1"})
+	require.NoError(t, err)
+
+	manager.SetProfileScope(2)
+	require.False(t, manager.GetReadingHistoryItem(1).Found)
+	_, err = manager.UpdateReadingHistoryItem(&UpdateReadingHistoryItemOptions{MediaId: 2, Provider: "comick", ChapterId: "ch_1"})
+	require.NoError(t, err)
+
+	manager.SetProfileScope(0)
+	require.True(t, manager.GetReadingHistoryItem(1).Found)
+	require.False(t, manager.GetReadingHistoryItem(2).Found)
+
+	require.NoError(t, manager.RemoveProfileWatchHistory(2))
+	manager.SetProfileScope(2)
+	require.Empty(t, manager.GetReadingHistory())
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"seanime/internal/api/anilist"
+	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	"seanime/internal/events"
 	"seanime/internal/platforms/anilist_platform"
+	"seanime/internal/platforms/mal_platform"
 	"seanime/internal/platforms/platform"
 	"seanime/internal/platforms/simulated_platform"
 	"seanime/internal/user"
@@ -56,6 +58,56 @@ func (a *App) UpdatePlatform(platform platform.Platform) {
 	})
 }
 
+// newUnauthenticatedPlatform returns the platform used when the user is not logged in to AniList.
+func (a *App) newUnauthenticatedPlatform() (platform.Platform, error) {
+	if useMalPlatform(a.Database) {
+		return mal_platform.NewMalPlatform(a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database), nil
+	}
+	return simulated_platform.NewSimulatedPlatform(a.LocalManager, a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database)
+}
+
+// useMalPlatform returns true if the MyAnimeList list should replace the simulated collections.
+func useMalPlatform(database *db.Database) bool {
+	settings, err := database.GetSettings()
+	if err != nil || settings.GetAnilist() == nil || !settings.GetAnilist().UseMalList {
+		return false
+	}
+	malInfo, err := database.GetMalInfo()
+	return err == nil && malInfo.AccessToken != ""
+}
+
+// RefreshUnauthenticatedPlatform switches between the simulated and MyAnimeList platforms
+// when the user is not logged in to AniList and the MyAnimeList preference or connection changed.
+func (a *App) RefreshUnauthenticatedPlatform() {
+	if a.IsOffline() || !a.AnilistPlatformRef.IsPresent() || a.AnilistClientRef.Get().IsAuthenticated() {
+		return
+	}
+
+	_, isMal := a.AnilistPlatformRef.Get().(*mal_platform.MalPlatform)
+	if isMal == useMalPlatform(a.Database) {
+		return
+	}
+
+	nextPlatform, err := a.newUnauthenticatedPlatform()
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to create platform")
+		return
+	}
+	a.UpdatePlatform(nextPlatform)
+
+	a.Logger.Info().Bool("mal", !isMal).Msg("app: Switched list platform")
+
+	go func() {
+		defer util.HandlePanicThen(func() {})
+		if _, err := a.RefreshAnimeCollection(); err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to fetch anime collection")
+		}
+		if _, err := a.RefreshMangaCollection(); err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to fetch manga collection")
+		}
+	}()
+}
+
 // UpdateAnilistClientToken will update the Anilist Client Wrapper token.
 // This function should be called when a user logs in
 func (a *App) UpdateAnilistClientToken(token string) {
@@ -123,7 +175,7 @@ func (a *App) applyRuntimeAnilistClient(client anilist.AnilistClient) error {
 	if client.IsAuthenticated() {
 		nextPlatform = anilist_platform.NewAnilistPlatform(a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database, a.LogoutFromAnilist)
 	} else {
-		nextPlatform, err = simulated_platform.NewSimulatedPlatform(a.LocalManager, a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database)
+		nextPlatform, err = a.newUnauthenticatedPlatform()
 		if err != nil {
 			return err
 		}
@@ -195,7 +247,7 @@ func (a *App) LoginToAnilist(token string) error {
 		a.Logger.Err(err).Msg("scan: could not save local files")
 	}
 
-	_, err = a.Database.UpsertAccount(&models.Account{
+	acc, err := a.Database.UpsertAccount(&models.Account{
 		BaseModel: models.BaseModel{
 			ID:        1,
 			UpdatedAt: time.Now(),
@@ -207,6 +259,7 @@ func (a *App) LoginToAnilist(token string) error {
 	if err != nil {
 		return err
 	}
+	a.updateActiveProfileAccount(acc)
 
 	a.Logger.Info().Msg("app: Authenticated to AniList")
 
@@ -221,12 +274,13 @@ func (a *App) LoginToAnilist(token string) error {
 		a.InitOrRefreshTorrentstreamSettings()
 		a.InitOrRefreshMediastreamSettings()
 		a.InitOrRefreshDebridSettings()
+		a.InitOrRefreshUsenetSettings()
 	}()
 
 	return nil
 }
 
-// LogoutFromAnilist clears the AniList token and switches to the simulated platform.
+// LogoutFromAnilist clears the AniList token and switches to the simulated or MyAnimeList platform.
 // This is called internally when the token is detected as invalid.
 func (a *App) LogoutFromAnilist() {
 	// prevent multiple concurrent calls (e.g. from parallel failing requests)
@@ -237,14 +291,14 @@ func (a *App) LogoutFromAnilist() {
 
 	a.UpdateAnilistClientToken("")
 
-	simulatedPlatform, err := simulated_platform.NewSimulatedPlatform(a.LocalManager, a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database)
+	nextPlatform, err := a.newUnauthenticatedPlatform()
 	if err != nil {
-		a.Logger.Error().Err(err).Msg("app: Failed to create simulated platform during auto-logout")
+		a.Logger.Error().Err(err).Msg("app: Failed to create platform during auto-logout")
 	} else {
-		a.UpdatePlatform(simulatedPlatform)
+		a.UpdatePlatform(nextPlatform)
 	}
 
-	_, _ = a.Database.UpsertAccount(&models.Account{
+	acc, err := a.Database.UpsertAccount(&models.Account{
 		BaseModel: models.BaseModel{
 			ID:        1,
 			UpdatedAt: time.Now(),
@@ -253,8 +307,11 @@ func (a *App) LogoutFromAnilist() {
 		Token:    "",
 		Viewer:   nil,
 	})
+	if err == nil {
+		a.updateActiveProfileAccount(acc)
+	}
 
-	a.Logger.Debug().Msg("app: Logged out from AniList, switched to simulated platform")
+	a.Logger.Debug().Msg("app: Logged out from AniList, switched to unauthenticated platform")
 
 	a.InitOrRefreshModules()
 	a.InitOrRefreshAnilistData()
@@ -342,6 +399,8 @@ func (a *App) RefreshMangaCollection() (*anilist.MangaCollection, error) {
 
 	a.LocalManager.SetMangaCollection(mc)
 
+	a.OpdsCatalog.RefreshLibrary()
+
 	a.WSEventManager.SendEvent(events.RefreshedAnilistMangaCollection, nil)
 
 	return mc, nil
@@ -1,18 +1,23 @@
 package core
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"runtime"
+	"seanime/internal/access"
 	"seanime/internal/api/anilist"
 	"seanime/internal/api/metadata_provider"
+	"seanime/internal/backup"
 	"seanime/internal/constants"
 	"seanime/internal/continuity"
+	"seanime/internal/customsource/nfo_source"
 	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	debrid_client "seanime/internal/debrid/client"
 	"seanime/internal/directstream"
 	discordrpc_presence "seanime/internal/discordrpc/presence"
+	"seanime/internal/dlna"
 	"seanime/internal/doh"
 	"seanime/internal/events"
 	"seanime/internal/extension"
@@ -30,16 +35,20 @@ import (
 	"seanime/internal/manga"
 	"seanime/internal/mediacore"
 	"seanime/internal/mediaplayers/iina"
+	"seanime/internal/mediaplayers/kodi"
 	"seanime/internal/mediaplayers/mediaplayer"
 	"seanime/internal/mediaplayers/mpchc"
 	"seanime/internal/mediaplayers/mpv"
+	"seanime/internal/mediaplayers/upnp"
 	"seanime/internal/mediaplayers/vlc"
 	"seanime/internal/mediastream"
 	"seanime/internal/mpvcore"
 	"seanime/internal/nakama"
 	"seanime/internal/nativeplayer"
 	"seanime/internal/onlinestream"
+	"seanime/internal/opds"
 	"seanime/internal/platforms/anilist_platform"
+	"seanime/internal/platforms/mal_platform"
 	"seanime/internal/platforms/offline_platform"
 	"seanime/internal/platforms/platform"
 	"seanime/internal/platforms/simulated_platform"
@@ -50,11 +59,13 @@ import (
 	"seanime/internal/torrents/torrent"
 	"seanime/internal/torrentstream"
 	"seanime/internal/updater"
+	usenet_client "seanime/internal/usenet/client"
 	"seanime/internal/user"
 	"seanime/internal/util"
 	"seanime/internal/util/filecache"
 	"seanime/internal/util/result"
 	"seanime/internal/videocore"
+	"seanime/internal/watchjournal"
 	"sync"
 	"sync/atomic"
 
@@ -76,6 +87,7 @@ type (
 		TorrentClientRepository *torrent_client.Repository
 		TorrentRepository       *torrent.Repository
 		DebridClientRepository  *debrid_client.Repository
+		UsenetClientRepository  *usenet_client.Repository
 
 		// File system monitoring
 		Watcher *scanner.Watcher
@@ -91,6 +103,8 @@ type (
 		AutoDownloader      *autodownloader.AutoDownloader
 		AutoScanner         *autoscanner.AutoScanner
 		PlaybackManager     *playbackmanager.PlaybackManager
+		DlnaServer          *dlna.Server  // Publishes the library to DLNA renderers
+		OpdsCatalog         *opds.Catalog // Publishes the downloaded and local manga to e-readers
 		episodeAvailability episodeAvailability
 
 		// Real-time communication
@@ -100,6 +114,7 @@ type (
 		ExtensionRepository           *extension_repo.Repository
 		ExtensionBankRef              *util.Ref[*extension.UnifiedBank]
 		ExtensionPlaygroundRepository *extension_playground.PlaygroundRepository
+		NfoCustomSource               *nfo_source.Provider // Built-in custom source reading NFO files from the library folders
 
 		// Streaming
 		DirectStreamManager     *directstream.Manager
@@ -116,6 +131,8 @@ type (
 			MpcHc *mpchc.MpcHc
 			Mpv   *mpv.Mpv
 			Iina  *iina.Iina
+			Kodi  *kodi.Kodi
+			Upnp  *upnp.Upnp
 		}
 		MediaPlayerRepository *mediaplayer.Repository
 		MpvCore               *mpvcore.MpvCore
@@ -129,6 +146,7 @@ type (
 
 		// Utilities
 		FileCacher       *filecache.Cacher
+		BackupManager    *backup.Manager // Archives of the app data
 		Updater          *updater.Updater
 		SelfUpdater      *updater.SelfUpdater
 		ReportRepository *report.Repository
@@ -138,6 +156,7 @@ type (
 
 		// Continuity and sync
 		ContinuityManager *continuity.Manager
+		WatchJournal      *watchjournal.Journal // Viewing sessions and statistics
 
 		// Lifecycle management
 		Cleanups                        []func()
@@ -147,12 +166,16 @@ type (
 		// Configuration and feature flags
 		FeatureFlags      FeatureFlags
 		FeatureManager    *FeatureManager
+		AccessManager     *access.Manager // API tokens and their roles
 		Settings          *models.Settings
 		SecondarySettings struct {
 			Mediastream   *models.MediastreamSettings
 			Torrentstream *models.TorrentstreamSettings
 			Debrid        *models.DebridSettings
 			DummyDebrid   *models.DummyDebridSettings
+			Usenet        *models.UsenetSettings
+			Dlna          *models.DlnaSettings
+			Backup        *models.BackupSettings
 		}
 
 		// Metadata
@@ -167,6 +190,7 @@ type (
 		user                 *user.User
 		previousVersion      string
 		moduleMu             sync.Mutex
+		profileMu            sync.Mutex
 		ServerReady          bool
 		isOfflineRef         *util.Ref[bool]
 		ServerPasswordHash   string
@@ -246,6 +270,17 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		logger.Info().Msg("app: Desktop sidecar mode enabled")
 	}
 
+	// Restore a backup into the fresh data directory before the database is opened
+	if configOpts.Flags.RestoreBackup != "" {
+		restoreBackup(configOpts.Flags.RestoreBackup, cfg, logger)
+
+		// Reload the restored config, the version change of the backup triggers the migrations
+		cfg, err = NewConfig(configOpts, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msgf("app: Failed to load the restored config")
+		}
+	}
+
 	// Initialize database connection
 	database, err := db.NewDatabase(cfg.Data.AppDataDir, cfg.Database.Name, logger)
 	if err != nil {
@@ -290,6 +325,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	if err != nil {
 		logger.Fatal().Err(err).Msgf("app: Failed to initialize file cacher")
 	}
+	fileCacher.SetMaxSize(int64(cfg.Cache.MaxSize) << 20)
 
 	// Initialize the extension bank that will be shared across modules
 	extensionBankRef := util.NewRef(extension.NewUnifiedBank())
@@ -326,6 +362,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		Database:         database,
 		ExtensionBankRef: extensionBankRef,
 	})
+	mangaRepository.SetPageCacheOptions(int64(cfg.Manga.PageCacheMaxSize)<<20, cfg.Manga.PrefetchPages)
 
 	// Initialize Anilist platform
 	anilistPlatform := anilist_platform.NewAnilistPlatform(anilistCWRef, extensionBankRef, logger, database, func() {
@@ -375,8 +412,13 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		logger.Warn().Msg("app: Offline mode is active, using offline platform")
 		activePlatformRef.Set(offlinePlatform)
 	} else if !anilistCWRef.Get().IsAuthenticated() {
-		logger.Warn().Msg("app: Anilist client is not authenticated, using simulated platform")
-		activePlatformRef.Set(simulatedPlatform)
+		if useMalPlatform(database) {
+			logger.Warn().Msg("app: Anilist client is not authenticated, using MyAnimeList platform")
+			activePlatformRef.Set(mal_platform.NewMalPlatform(anilistCWRef, extensionBankRef, logger, database))
+		} else {
+			logger.Warn().Msg("app: Anilist client is not authenticated, using simulated platform")
+			activePlatformRef.Set(simulatedPlatform)
+		}
 	}
 
 	isOfflineRef := util.NewRef(cfg.Server.Offline)
@@ -408,6 +450,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		Config:                        cfg,
 		Flags:                         configOpts.Flags,
 		FeatureManager:                NewFeatureManager(logger, configOpts.Flags),
+		AccessManager:                 access.NewManager(&access.NewManagerOptions{Logger: logger, Database: database}),
 		Database:                      database,
 		AnilistClientRef:              anilistCWRef,
 		AnilistPlatformRef:            activePlatformRef,
@@ -425,6 +468,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		ExtensionRepository:           extensionRepository,
 		ExtensionBankRef:              extensionBankRef,
 		ExtensionPlaygroundRepository: extensionPlaygroundRepository,
+		NfoCustomSource:               nfo_source.NewProvider(logger),
 		ReportRepository:              report.NewRepository(logger),
 		TorrentRepository:             nil, // Initialized in App.initModulesOnce
 		FillerManager:                 nil, // Initialized in App.initModulesOnce
@@ -436,7 +480,12 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 		MediastreamRepository:         nil, // Initialized in App.initModulesOnce
 		TorrentstreamRepository:       nil, // Initialized in App.initModulesOnce
 		ContinuityManager:             nil, // Initialized in App.initModulesOnce
+		WatchJournal:                  nil, // Initialized in App.initModulesOnce
 		DebridClientRepository:        nil, // Initialized in App.initModulesOnce
+		UsenetClientRepository:        nil, // Initialized in App.initModulesOnce
+		DlnaServer:                    nil, // Initialized in App.initModulesOnce
+		OpdsCatalog:                   nil, // Initialized in App.initModulesOnce
+		BackupManager:                 nil, // Initialized in App.initModulesOnce
 		DirectStreamManager:           nil, // Initialized in App.initModulesOnce
 		NativePlayer:                  nil, // Initialized in App.initModulesOnce
 		VideoCore:                     nil, // Initialized in App.initModulesOnce
@@ -455,7 +504,10 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 			Torrentstream *models.TorrentstreamSettings
 			Debrid        *models.DebridSettings
 			DummyDebrid   *models.DummyDebridSettings
-		}{Mediastream: nil, Torrentstream: nil, Debrid: nil, DummyDebrid: nil},
+			Usenet        *models.UsenetSettings
+			Dlna          *models.DlnaSettings
+			Backup        *models.BackupSettings
+		}{Mediastream: nil, Torrentstream: nil, Debrid: nil, DummyDebrid: nil, Usenet: nil, Dlna: nil, Backup: nil},
 		SelfUpdater:                     selfupdater,
 		moduleMu:                        sync.Mutex{},
 		OnRefreshAnilistCollectionFuncs: result.NewMap[string, func()](),
@@ -516,6 +568,9 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	// Initialize modules that only need to be initialized once
 	app.initModulesOnce()
 
+	// Scope the watch history, playlists and simulated collections to the active profile
+	app.initProfiles()
+
 	plugin.GlobalAppContext.SetModulesPartial(plugin.AppContextModules{
 		ContinuityManager:       app.ContinuityManager,
 		AutoScanner:             app.AutoScanner,
@@ -536,7 +591,7 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	app.InitOrRefreshModules()
 
 	// Load custom source extensions before fetching AniList data
-	LoadCustomSourceExtensions(extensionRepository)
+	LoadCustomSourceExtensions(extensionRepository, app.NfoCustomSource)
 
 	// Initialize Anilist data if not in offline mode
 	if !app.IsOffline() {
@@ -560,9 +615,31 @@ func NewApp(configOpts *ConfigOptions, selfupdater *updater.SelfUpdater) *App {
 	// Initialize debrid settings (for debrid services)
 	app.InitOrRefreshDebridSettings()
 
+	// Initialize usenet settings (for usenet download clients)
+	app.InitOrRefreshUsenetSettings()
+
+	// Initialize DLNA settings (starts the media server if enabled)
+	app.InitOrRefreshDlnaSettings()
+	app.AddCleanupFunction(app.DlnaServer.Stop)
+
+	// Initialize backup settings (schedules the backups if enabled)
+	app.InitOrRefreshBackupSettings()
+	app.AddCleanupFunction(app.BackupManager.Stop)
+
 	// Register Nakama manager cleanup
 	app.AddCleanupFunction(app.NakamaManager.Cleanup)
 
+	// Save the API token audit entries periodically and on shutdown
+	accessCtx, accessCancel := context.WithCancel(context.Background())
+	app.AccessManager.Start(accessCtx)
+	if app.AccessManager.HasTokens() && cfg.Server.Password == "" {
+		logger.Warn().Msg("app: API tokens only restrict access when a server password is set")
+	}
+	app.AddCleanupFunction(func() {
+		accessCancel()
+		app.AccessManager.Flush()
+	})
+
 	// Run one-time initialization actions
 	app.performActionsOnce()
 
@@ -0,0 +1,28 @@
+package core
+
+import (
+	"path/filepath"
+	"seanime/internal/backup"
+	"seanime/internal/constants"
+
+	"github.com/rs/zerolog"
+)
+
+// restoreBackup restores a backup archive into the data directory of the config.
+// The data directory must not have a database yet, the app exits if the restore fails.
+func restoreBackup(archivePath string, cfg *Config, logger *zerolog.Logger) {
+	logger.Info().Str("path", archivePath).Msg("app: Restoring backup")
+
+	manifest, err := backup.Restore(archivePath, &backup.RestoreOptions{
+		DataDir:       cfg.Data.AppDataDir,
+		DatabaseName:  cfg.Database.Name,
+		ConfigPath:    filepath.Join(cfg.Data.AppDataDir, constants.ConfigFileName),
+		CacheDir:      cfg.Cache.Dir,
+		ExtensionsDir: cfg.Extensions.Dir,
+	})
+	if err != nil {
+		logger.Fatal().Err(err).Msg("app: Failed to restore backup")
+	}
+
+	logger.Info().Str("version", manifest.Version).Time("createdAt", manifest.CreatedAt).Msg("app: Backup restored")
+}
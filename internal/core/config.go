@@ -46,14 +46,17 @@ type Config struct {
 	Cache struct {
 		Dir          string
 		TranscodeDir string
+		MaxSize      int // Size budget of the file cache in MB, 0 disables eviction
 	}
 	Offline struct {
 		Dir      string
 		AssetDir string
 	}
 	Manga struct {
-		DownloadDir string
-		LocalDir    string
+		DownloadDir      string
+		LocalDir         string
+		PageCacheMaxSize int // Size budget of the page cache in MB, 0 disables eviction
+		PrefetchPages    int // Number of pages fetched ahead of the page being read, 0 disables prefetching
 	}
 	Data struct { // Hydrated after config is loaded
 		AppDataDir string
@@ -160,8 +163,11 @@ func NewConfig(options *ConfigOptions, logger *zerolog.Logger) (*Config, error)
 	viper.SetDefault("web.assetDir", "$SEANIME_DATA_DIR/assets")
 	viper.SetDefault("cache.dir", "$SEANIME_DATA_DIR/cache")
 	viper.SetDefault("cache.transcodeDir", "$SEANIME_DATA_DIR/cache/transcode")
+	viper.SetDefault("cache.maxSize", 1024)
 	viper.SetDefault("manga.downloadDir", "$SEANIME_DATA_DIR/manga")
 	viper.SetDefault("manga.localDir", "$SEANIME_DATA_DIR/manga-local")
+	viper.SetDefault("manga.pageCacheMaxSize", 512)
+	viper.SetDefault("manga.prefetchPages", 5)
 	viper.SetDefault("logs.dir", "$SEANIME_DATA_DIR/logs")
 	viper.SetDefault("offline.dir", "$SEANIME_DATA_DIR/offline")
 	viper.SetDefault("offline.assetDir", "$SEANIME_DATA_DIR/offline/assets")
@@ -389,6 +395,9 @@ func validateConfig(cfg *Config, logger *zerolog.Logger) error {
 	if err := checkIsValidPath(cfg.Cache.TranscodeDir); err != nil {
 		return wrapInvalidConfigValue("cache.transcodeDir", err)
 	}
+	if cfg.Cache.MaxSize < 0 {
+		return errInvalidConfigValue("cache.maxSize", "cannot be negative")
+	}
 
 	if cfg.Logs.Dir == "" {
 		return errInvalidConfigValue("logs.dir", "cannot be empty")
@@ -410,6 +419,12 @@ func validateConfig(cfg *Config, logger *zerolog.Logger) error {
 	if err := checkIsValidPath(cfg.Manga.LocalDir); err != nil {
 		return wrapInvalidConfigValue("manga.localDir", err)
 	}
+	if cfg.Manga.PageCacheMaxSize < 0 {
+		return errInvalidConfigValue("manga.pageCacheMaxSize", "cannot be negative")
+	}
+	if cfg.Manga.PrefetchPages < 0 {
+		return errInvalidConfigValue("manga.prefetchPages", "cannot be negative")
+	}
 
 	if cfg.Extensions.Dir == "" {
 		return errInvalidConfigValue("extensions.dir", "cannot be empty")
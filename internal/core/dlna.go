@@ -0,0 +1,69 @@
+package core
+
+import (
+	"context"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/library/anime"
+)
+
+// getDlnaContent returns the library published by the DLNA server.
+func (a *App) getDlnaContent(ctx context.Context) (*anime.LibraryCollection, []*anime.LocalFile, error) {
+	animeCollection, err := a.GetAnimeCollection(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(a.Database)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	libraryCollection, err := anime.NewLibraryCollection(ctx, &anime.NewLibraryCollectionOptions{
+		AnimeCollection:     animeCollection,
+		PlatformRef:         a.AnilistPlatformRef,
+		LocalFiles:          lfs,
+		MetadataProviderRef: a.MetadataProviderRef,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return libraryCollection, lfs, nil
+}
+
+// onDlnaFilePlayed updates the progress when a DLNA renderer has played an episode.
+// The progress is only updated if the episode is the next one or further.
+func (a *App) onDlnaFilePlayed(lf *anime.LocalFile) {
+	if a.SecondarySettings.Dlna == nil || !a.SecondarySettings.Dlna.AutoUpdateProgress {
+		return
+	}
+	if !lf.IsMain() || lf.GetEpisodeNumber() <= 0 {
+		return
+	}
+
+	animeCollection, err := a.GetAnimeCollection(false)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("dlna: Failed to get anime collection")
+		return
+	}
+
+	entry, found := animeCollection.GetListEntryFromAnimeId(lf.MediaId)
+	if !found {
+		return
+	}
+
+	episodeNumber := lf.GetEpisodeNumber()
+	if entry.GetProgressSafe() >= episodeNumber {
+		return
+	}
+
+	err = a.AnilistPlatformRef.Get().UpdateEntryProgress(context.Background(), lf.MediaId, episodeNumber, entry.GetMedia().GetEpisodes())
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("dlna: Failed to update progress")
+		return
+	}
+
+	a.Logger.Info().Int("mediaId", lf.MediaId).Int("episode", episodeNumber).Msg("dlna: Updated progress")
+
+	_, _ = a.RefreshAnimeCollection()
+}
@@ -1,14 +1,29 @@
 package core
 
 import (
+	"seanime/internal/customsource/nfo_source"
 	"seanime/internal/extension"
 	"seanime/internal/extension_repo"
 	manga_providers "seanime/internal/manga/providers"
+	"seanime/internal/torrents/torznab"
 
 	"github.com/rs/zerolog"
 )
 
-func LoadCustomSourceExtensions(extensionRepository *extension_repo.Repository) {
+func LoadCustomSourceExtensions(extensionRepository *extension_repo.Repository, nfoCustomSource *nfo_source.Provider) {
+	// Load built-in custom sources
+	extensionRepository.ReloadBuiltInExtension(extension.Extension{
+		ID:          nfo_source.ExtensionID,
+		Name:        "Local NFO",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeCustomSource,
+		Description: "Media described by Kodi/Jellyfin tvshow.nfo files in the library folders.",
+		Author:      "Seanime",
+		Lang:        "multi",
+	}, nfoCustomSource)
+
 	extensionRepository.LoadOnlyWrapper([]extension.Type{extension.TypeCustomSource}, func() {
 		extensionRepository.ReloadExternalExtensions()
 	})
@@ -28,6 +43,58 @@ func LoadExtensions(extensionRepository *extension_repo.Repository, logger *zero
 		Icon:        "https://raw.githubusercontent.com/5rahim/hibike/main/icons/local-manga.png",
 	}, manga_providers.NewLocal(config.Manga.LocalDir, logger))
 
+	extensionRepository.ReloadBuiltInExtension(extension.Extension{
+		ID:          manga_providers.KomgaProvider,
+		Name:        "Komga",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeMangaProvider,
+		Description: "Read the series of a Komga server. Read progress can be pushed back to the server.",
+		Author:      "Seanime",
+		Lang:        "multi",
+		UserConfig:  manga_providers.MediaServerUserConfig(true),
+	}, manga_providers.NewKomga(logger))
+
+	extensionRepository.ReloadBuiltInExtension(extension.Extension{
+		ID:          manga_providers.KavitaProvider,
+		Name:        "Kavita",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeMangaProvider,
+		Description: "Read the series of a Kavita server. Read progress can be pushed back to the server.",
+		Author:      "Seanime",
+		Lang:        "multi",
+		UserConfig:  manga_providers.MediaServerUserConfig(false),
+	}, manga_providers.NewKavita(logger))
+
+	extensionRepository.ReloadBuiltInExtension(extension.Extension{
+		ID:          torznab.ProviderID,
+		Name:        "Torznab",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeAnimeTorrentProvider,
+		Description: "Search Torznab-compatible indexers (Jackett, Prowlarr, etc.).",
+		Author:      "Seanime",
+		Lang:        "multi",
+		UserConfig:  torznab.UserConfig(),
+	}, torznab.NewProvider(logger))
+
+	extensionRepository.ReloadBuiltInExtension(extension.Extension{
+		ID:          torznab.NewznabProviderID,
+		Name:        "Newznab",
+		Version:     "",
+		ManifestURI: "builtin",
+		Language:    extension.LanguageGo,
+		Type:        extension.TypeAnimeTorrentProvider,
+		Description: "Search Newznab-compatible Usenet indexers (NZBHydra2, Prowlarr, etc.). Releases are sent to the Usenet download client.",
+		Author:      "Seanime",
+		Lang:        "multi",
+		UserConfig:  torznab.UserConfig(),
+	}, torznab.NewNewznabProvider(logger))
+
 	// Load external extensions
 	//extensionRepository.ReloadExternalExtensions()
 	extensionRepository.LoadOnlyWrapper([]extension.Type{extension.TypeMangaProvider, extension.TypeOnlinestreamProvider, extension.TypeAnimeTorrentProvider, extension.TypePlugin}, func() {
@@ -1,6 +1,9 @@
 package core
 
 import (
+	"seanime/internal/access"
+	"slices"
+
 	"github.com/rs/zerolog"
 )
 
@@ -58,6 +61,8 @@ const (
 	Proxy             FeatureKey = "Proxy"
 	ManageMangaSource FeatureKey = "ManageMangaSource"
 	PushRequests      FeatureKey = "PushRequests"
+	// ManageAccessTokens allows creating and revoking API tokens.
+	ManageAccessTokens FeatureKey = "ManageAccessTokens"
 )
 
 func NewFeatureManager(logger *zerolog.Logger, flags SeanimeFlags) *FeatureManager {
@@ -99,6 +104,7 @@ func NewFeatureManager(logger *zerolog.Logger, flags SeanimeFlags) *FeatureManag
 			Transcode,
 			ManageMangaSource,
 			PushRequests,
+			ManageAccessTokens,
 		}
 	}
 
@@ -131,6 +137,84 @@ func (m *FeatureManager) GetDisabledFeatureMap() map[FeatureKey]bool {
 	return m.disabledFeatures
 }
 
+// IsDisabledForRole returns true if the feature is disabled globally or for the role of the API token.
+func (m *FeatureManager) IsDisabledForRole(role access.Role, key FeatureKey) bool {
+	return m.IsDisabled(key) || slices.Contains(disabledFeaturesForRole(role), key)
+}
+
+// HasDisabledFeaturesForRole returns true if features are disabled globally or for the role.
+func (m *FeatureManager) HasDisabledFeaturesForRole(role access.Role) bool {
+	return m.HasDisabledFeatures() || len(disabledFeaturesForRole(role)) > 0
+}
+
+// GetDisabledFeaturesForRole returns the features disabled globally and for the role.
+func (m *FeatureManager) GetDisabledFeaturesForRole(role access.Role) []FeatureKey {
+	ret := slices.Clone(m.DisabledFeatures)
+	for _, key := range disabledFeaturesForRole(role) {
+		if !slices.Contains(ret, key) {
+			ret = append(ret, key)
+		}
+	}
+	return ret
+}
+
+// GetRoleDisabledFeatures returns the features disabled for each role.
+func GetRoleDisabledFeatures() map[access.Role][]FeatureKey {
+	ret := make(map[access.Role][]FeatureKey, len(roleDisabledFeatures))
+	for _, role := range access.Roles() {
+		ret[role] = slices.Clone(disabledFeaturesForRole(role))
+	}
+	return ret
+}
+
+// disabledFeaturesForRole returns the features disabled for the role.
+// Unknown roles are treated as guests.
+func disabledFeaturesForRole(role access.Role) []FeatureKey {
+	if keys, found := roleDisabledFeatures[role]; found {
+		return keys
+	}
+	return roleDisabledFeatures[access.RoleGuest]
+}
+
+var (
+	// viewerDisabledFeatures are the features a viewer cannot use.
+	// Viewers can watch, read and update their lists but cannot change the server or the library.
+	viewerDisabledFeatures = []FeatureKey{
+		ManageOfflineMode,
+		ViewLogs,
+		UpdateSettings,
+		ManageLocalAnimeLibrary,
+		ManageAccount,
+		RefreshMetadata,
+		ManageMangaDownloads,
+		ManageAutoDownloader,
+		ManageExtensions,
+		ManageHomeScreen,
+		OpenInExplorer,
+		PluginTray,
+		ManageNakama,
+		ManageDebrid,
+		ManageMangaSource,
+		ManageAccessTokens,
+	}
+
+	// roleDisabledFeatures maps the API token roles to the features they cannot use.
+	// Admins have access to everything.
+	roleDisabledFeatures = map[access.Role][]FeatureKey{
+		access.RoleAdmin:  {},
+		access.RoleViewer: viewerDisabledFeatures,
+		access.RoleGuest: append(slices.Clone(viewerDisabledFeatures),
+			ViewSettings,
+			ViewAccount,
+			ManageLists,
+			ManagePlaylist,
+			ViewAutoDownloader,
+			ViewScanSummaries,
+			ViewExtensions,
+		),
+	}
+)
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 type (
@@ -18,6 +18,7 @@ type (
 		Password         string
 		DisablePassword  bool
 		LockDown         bool
+		RestoreBackup    string
 	}
 )
 
@@ -42,6 +43,7 @@ func GetSeanimeFlags() SeanimeFlags {
 		fmt.Printf("  --disable-all-features        disable all features that can be disabled\n")
 		fmt.Printf("  --password string             password to use for the instance\n")
 		fmt.Printf("  --disable-password            disable password protection\n")
+		fmt.Printf("  --restore string              restore a backup archive into a new data directory\n")
 		fmt.Printf("  -h                           show this help message\n")
 	}
 
@@ -54,11 +56,13 @@ func GetSeanimeFlags() SeanimeFlags {
 	flag.BoolVar(&flags.LockDown, "disable-all-features", false, "Disables all features that can be disabled")
 	flag.StringVar(&flags.Password, "password", "", "Password to use for the instance")
 	flag.BoolVar(&flags.DisablePassword, "disable-password", false, "Disable password protection")
+	flag.StringVar(&flags.RestoreBackup, "restore", "", "Restore a backup archive into a new data directory")
 
 	flag.Parse()
 
 	flags.DataDir = strings.TrimSpace(flags.DataDir)
 	flags.Host = strings.TrimSpace(flags.Host)
+	flags.RestoreBackup = strings.TrimSpace(flags.RestoreBackup)
 
 	if disableFeaturesStr != "" {
 		features := strings.Split(disableFeaturesStr, ",")
@@ -3,7 +3,10 @@ package core
 import (
 	"context"
 	"errors"
+	"path/filepath"
 	"seanime/internal/api/anilist"
+	"seanime/internal/backup"
+	"seanime/internal/constants"
 	"seanime/internal/continuity"
 	"seanime/internal/database/db"
 	"seanime/internal/database/db_bridge"
@@ -11,6 +14,7 @@ import (
 	debrid_client "seanime/internal/debrid/client"
 	"seanime/internal/directstream"
 	discordrpc_presence "seanime/internal/discordrpc/presence"
+	"seanime/internal/dlna"
 	"seanime/internal/events"
 	"seanime/internal/library/anime"
 	"seanime/internal/library/autodownloader"
@@ -21,15 +25,18 @@ import (
 	"seanime/internal/manga"
 	"seanime/internal/mediacore"
 	"seanime/internal/mediaplayers/iina"
+	"seanime/internal/mediaplayers/kodi"
 	"seanime/internal/mediaplayers/mediaplayer"
 	"seanime/internal/mediaplayers/mpchc"
 	"seanime/internal/mediaplayers/mpv"
+	"seanime/internal/mediaplayers/upnp"
 	"seanime/internal/mediaplayers/vlc"
 	"seanime/internal/mediastream"
 	"seanime/internal/mpvcore"
 	"seanime/internal/nakama"
 	"seanime/internal/nativeplayer"
 	"seanime/internal/notifier"
+	"seanime/internal/opds"
 	"seanime/internal/platforms/shared_platform"
 	"seanime/internal/player"
 	"seanime/internal/playlist"
@@ -42,9 +49,12 @@ import (
 	torrent_availability "seanime/internal/torrents/availability"
 	"seanime/internal/torrents/torrent"
 	"seanime/internal/torrentstream"
+	usenet_client "seanime/internal/usenet/client"
 	"seanime/internal/user"
 	"seanime/internal/util"
 	"seanime/internal/videocore"
+	"seanime/internal/watchjournal"
+	"time"
 
 	"github.com/cli/browser"
 	"github.com/rs/zerolog"
@@ -263,6 +273,19 @@ func (a *App) initModulesOnce() {
 
 	a.MediacoreCoordinator.SetupSharedEffects()
 
+	// +---------------------+
+	// |    Watch Journal    |
+	// +---------------------+
+
+	a.WatchJournal = watchjournal.NewJournal(&watchjournal.NewJournalOptions{
+		Database: a.Database,
+		Logger:   a.Logger,
+	})
+	a.WatchJournal.ListenToMediacore(a.MediacoreCoordinator)
+	a.WatchJournal.ListenToPlaybackManager(a.PlaybackManager)
+
+	a.AddCleanupFunction(a.WatchJournal.Close)
+
 	// +---------------------+
 	// |    Media Stream     |
 	// +---------------------+
@@ -339,6 +362,51 @@ func (a *App) initModulesOnce() {
 		DummyDebridEnabled:  a.FeatureFlags.DummyDebrid,
 	})
 
+	// +---------------------+
+	// | Usenet Client Repo  |
+	// +---------------------+
+
+	a.UsenetClientRepository = usenet_client.NewRepository(&usenet_client.NewRepositoryOptions{
+		Logger:         a.Logger,
+		WSEventManager: a.WSEventManager,
+		Database:       a.Database,
+	})
+
+	// +---------------------+
+	// |     DLNA Server     |
+	// +---------------------+
+
+	a.DlnaServer = dlna.NewServer(&dlna.NewServerOptions{
+		Logger:     a.Logger,
+		GetContent: a.getDlnaContent,
+		OnPlayed:   a.onDlnaFilePlayed,
+	})
+	a.AddOnRefreshAnilistCollectionFunc("DlnaServer", a.DlnaServer.RefreshLibrary)
+
+	// +---------------------+
+	// |    OPDS Catalog     |
+	// +---------------------+
+
+	a.OpdsCatalog = opds.NewCatalog(&opds.NewCatalogOptions{
+		Logger:          a.Logger,
+		GetContent:      a.getOpdsContent,
+		GetChapterFiles: a.MangaRepository.GetChapterFiles,
+	})
+
+	// +---------------------+
+	// |       Backups       |
+	// +---------------------+
+
+	a.BackupManager = backup.NewManager(&backup.NewManagerOptions{
+		Logger:        a.Logger,
+		Database:      a.Database,
+		FileCacher:    a.FileCacher,
+		DatabaseName:  a.Config.Database.Name,
+		ConfigPath:    filepath.Join(a.Config.Data.AppDataDir, constants.ConfigFileName),
+		ExtensionsDir: a.Config.Extensions.Dir,
+		DefaultDir:    filepath.Join(a.Config.Data.AppDataDir, backup.DirName),
+	})
+
 	plugin.GlobalAppContext.SetModulesPartial(plugin.AppContextModules{
 		PlaybackManager:      a.PlaybackManager,
 		MangaRepository:      a.MangaRepository,
@@ -359,11 +427,13 @@ func (a *App) initModulesOnce() {
 		WSEventManager:          a.WSEventManager,
 		MetadataProviderRef:     a.MetadataProviderRef,
 		DebridClientRepository:  a.DebridClientRepository,
+		UsenetClientRepository:  a.UsenetClientRepository,
 		IsOfflineRef:            a.IsOfflineRef(),
 	})
 
 	// This is run in a goroutine
 	a.AutoDownloader.Start()
+	a.AddCleanupFunction(a.AutoDownloader.StopFeedPoller)
 
 	// +---------------------+
 	// |    Auto Scanner     |
@@ -383,11 +453,16 @@ func (a *App) initModulesOnce() {
 				_, _ = a.RefreshAnimeCollection()
 			}()
 		},
+		OnScanCompleted:   a.OnLibraryScanned,
+		FolderMediaSource: a.NfoCustomSource,
 	})
 
 	// This is run in a goroutine
 	a.AutoScanner.Start()
 
+	// Scan the library when Usenet downloads complete
+	a.UsenetClientRepository.SetOnJobCompleted(a.AutoScanner.Notify)
+
 	// +---------------------+
 	// |       Nakama        |
 	// +---------------------+
@@ -419,6 +494,9 @@ func (a *App) initModulesOnce() {
 		WSEventManager:          a.WSEventManager,
 		NakamaManager:           a.NakamaManager,
 		MediacoreCoordinator:    a.MediacoreCoordinator,
+		MetadataProviderRef:     a.MetadataProviderRef,
+		FillerManager:           a.FillerManager,
+		ContinuityManager:       a.ContinuityManager,
 		Database:                a.Database,
 		Logger:                  a.Logger,
 	})
@@ -478,6 +556,7 @@ func (a *App) InitOrRefreshModules() {
 	}
 
 	a.Settings = settings // Store settings instance in app
+	a.RefreshUnauthenticatedPlatform()
 	if settings.Library != nil {
 		a.LibraryDir = settings.GetLibrary().LibraryPath
 
@@ -527,6 +606,9 @@ func (a *App) InitOrRefreshModules() {
 			// Update the library paths for the library explorer (thread safe)
 			go a.LibraryExplorer.SetLibraryPaths(settings.GetLibrary().GetLibraryPaths())
 		}
+
+		// Update the library paths walked by the NFO custom source (thread safe)
+		a.NfoCustomSource.SetLibraryPaths(settings.GetLibrary().GetLibraryPaths())
 	}
 
 	if settings.MediaPlayer != nil {
@@ -545,6 +627,34 @@ func (a *App) InitOrRefreshModules() {
 		}
 		a.MediaPlayer.Mpv = mpv.New(a.Logger, settings.MediaPlayer.MpvSocket, settings.MediaPlayer.MpvPath, settings.MediaPlayer.MpvArgs)
 		a.MediaPlayer.Iina = iina.New(a.Logger, settings.MediaPlayer.IinaSocket, settings.MediaPlayer.IinaPath, settings.MediaPlayer.IinaArgs)
+		kodiHost := settings.MediaPlayer.KodiHost
+		if kodiHost == "" {
+			kodiHost = settings.MediaPlayer.Host
+		}
+		a.MediaPlayer.Kodi = &kodi.Kodi{
+			Host:     kodiHost,
+			Port:     settings.MediaPlayer.KodiPort,
+			Username: settings.MediaPlayer.KodiUsername,
+			Password: settings.MediaPlayer.KodiPassword,
+			Logger:   a.Logger,
+		}
+		// Keep the UPnP player across refreshes so that saving settings does not interrupt a cast
+		if a.MediaPlayer.Upnp == nil || a.MediaPlayer.Upnp.RendererLocation != settings.MediaPlayer.UpnpRendererLocation {
+			if a.MediaPlayer.Upnp != nil {
+				go a.MediaPlayer.Upnp.Stop()
+			}
+			a.MediaPlayer.Upnp = &upnp.Upnp{
+				RendererLocation: settings.MediaPlayer.UpnpRendererLocation,
+				Logger:           a.Logger,
+				HMACTokenFunc: func(endpoint string, symbol string) string {
+					qp, err := a.GetServerPasswordHMACAuth().GenerateQueryParam(endpoint, symbol)
+					if err != nil {
+						return ""
+					}
+					return qp
+				},
+			}
+		}
 
 		// Set media player repository
 		a.MediaPlayerRepository = mediaplayer.NewRepository(&mediaplayer.NewRepositoryOptions{
@@ -554,6 +664,8 @@ func (a *App) InitOrRefreshModules() {
 			MpcHc:             a.MediaPlayer.MpcHc,
 			Mpv:               a.MediaPlayer.Mpv, // Socket
 			Iina:              a.MediaPlayer.Iina,
+			Kodi:              a.MediaPlayer.Kodi,
+			Upnp:              a.MediaPlayer.Upnp,
 			WSEventManager:    a.WSEventManager,
 			ContinuityManager: a.ContinuityManager,
 		})
@@ -718,6 +830,7 @@ func (a *App) InitOrRefreshModules() {
 
 	if settings.Manga != nil {
 		go a.MangaRepository.SetSettings(settings)
+		a.MangaDownloader.SetPageProcessingOptions(settings.Manga)
 	}
 
 	// +---------------------+
@@ -861,6 +974,98 @@ func (a *App) InitOrRefreshDebridSettings() {
 	}
 }
 
+func (a *App) InitOrRefreshUsenetSettings() {
+
+	settings, found := a.Database.GetUsenetSettings()
+	if !found {
+
+		var err error
+		settings, err = a.Database.UpsertUsenetSettings(&models.UsenetSettings{
+			BaseModel: models.BaseModel{
+				ID: 1,
+			},
+			Enabled:  false,
+			Provider: "",
+		})
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to initialize usenet module")
+			return
+		}
+	}
+
+	a.SecondarySettings.Usenet = settings
+
+	err := a.UsenetClientRepository.InitializeProvider(settings)
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to initialize usenet provider")
+		return
+	}
+}
+
+func (a *App) InitOrRefreshDlnaSettings() {
+
+	settings, found := a.Database.GetDlnaSettings()
+	if !found {
+
+		var err error
+		settings, err = a.Database.UpsertDlnaSettings(&models.DlnaSettings{
+			BaseModel: models.BaseModel{
+				ID: 1,
+			},
+			Enabled:            false,
+			FriendlyName:       dlna.DefaultFriendlyName,
+			Port:               dlna.DefaultPort,
+			AutoUpdateProgress: true,
+		})
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to initialize DLNA module")
+			return
+		}
+	}
+
+	a.SecondarySettings.Dlna = settings
+
+	err := a.DlnaServer.SetSettings(dlna.Settings{
+		Enabled:      settings.Enabled,
+		FriendlyName: settings.FriendlyName,
+		Port:         settings.Port,
+	})
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to start DLNA server")
+		return
+	}
+}
+
+func (a *App) InitOrRefreshBackupSettings() {
+
+	settings, found := a.Database.GetBackupSettings()
+	if !found {
+
+		var err error
+		settings, err = a.Database.UpsertBackupSettings(&models.BackupSettings{
+			BaseModel: models.BaseModel{
+				ID: 1,
+			},
+			Enabled:       false,
+			IntervalHours: backup.DefaultIntervalHours,
+			Retention:     backup.DefaultRetention,
+		})
+		if err != nil {
+			a.Logger.Error().Err(err).Msg("app: Failed to initialize backup module")
+			return
+		}
+	}
+
+	a.SecondarySettings.Backup = settings
+
+	a.BackupManager.SetSettings(backup.Settings{
+		Enabled:   settings.Enabled,
+		Interval:  time.Duration(settings.IntervalHours) * time.Hour,
+		Retention: settings.Retention,
+		Dir:       settings.Dir,
+	})
+}
+
 func (a *App) InitOrRefreshDummyDebridSettings() {
 	settings, found := a.Database.GetDummyDebridSettings()
 	if !found {
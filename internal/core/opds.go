@@ -0,0 +1,22 @@
+package core
+
+import (
+	"context"
+	"seanime/internal/api/anilist"
+	"seanime/internal/manga"
+)
+
+// getOpdsContent returns the manga collection and the chapters published by the OPDS catalog.
+func (a *App) getOpdsContent(_ context.Context) (*anilist.MangaCollection, []*manga.ChapterContainer, error) {
+	mangaCollection, err := a.GetMangaCollection(false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	containers, err := a.MangaRepository.GetDownloadedChapterContainers(mangaCollection)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mangaCollection, containers, nil
+}
@@ -0,0 +1,241 @@
+package core
+
+import (
+	"errors"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/platforms/anilist_platform"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+var (
+	ErrProfileNameRequired = errors.New("profile name is required")
+	ErrCannotDeleteProfile = errors.New("cannot delete the default or active profile")
+)
+
+// initProfiles creates the default profile on first run and scopes the profile data to the active profile.
+// It should be called after the modules are initialized.
+func (a *App) initProfiles() {
+	profile, err := a.Database.EnsureDefaultProfile()
+	if err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to initialize profiles")
+		return
+	}
+
+	a.setProfileScope(profile)
+}
+
+// setProfileScope makes the modules use the data of the profile.
+func (a *App) setProfileScope(profile *models.Profile) {
+	scope := db.GetProfileScope(profile)
+	a.Database.SetProfileScope(scope)
+	a.ContinuityManager.SetProfileScope(scope)
+	a.LocalManager.SetProfileScope(scope)
+}
+
+func (a *App) GetProfiles() ([]*models.Profile, error) {
+	return a.Database.GetProfiles()
+}
+
+func (a *App) GetActiveProfile() (*models.Profile, error) {
+	return a.Database.GetActiveProfile()
+}
+
+// CreateProfile creates a profile without an AniList account.
+// The profile uses the simulated platform until an AniList account is linked to it.
+func (a *App) CreateProfile(name string) (*models.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrProfileNameRequired
+	}
+
+	profile := &models.Profile{Name: name}
+	if err := a.Database.InsertProfile(profile); err != nil {
+		return nil, err
+	}
+
+	a.Logger.Info().Str("name", name).Msg("app: Profile created")
+
+	return profile, nil
+}
+
+func (a *App) RenameProfile(id uint, name string) (*models.Profile, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, ErrProfileNameRequired
+	}
+
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	profile, err := a.Database.GetProfile(id)
+	if err != nil {
+		return nil, err
+	}
+
+	profile.Name = name
+	if err := a.Database.UpdateProfile(profile); err != nil {
+		return nil, err
+	}
+
+	return profile, nil
+}
+
+// DeleteProfile deletes a profile and its watch history, playlists and simulated collections.
+func (a *App) DeleteProfile(id uint) error {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	profile, err := a.Database.GetProfile(id)
+	if err != nil {
+		return err
+	}
+
+	if profile.IsDefault || profile.IsActive {
+		return ErrCannotDeleteProfile
+	}
+
+	if err := a.Database.DeleteProfile(profile.ID); err != nil {
+		return err
+	}
+
+	scope := db.GetProfileScope(profile)
+	if err := a.ContinuityManager.RemoveProfileWatchHistory(scope); err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to remove the watch history of the profile")
+	}
+	if err := a.LocalManager.DeleteProfileData(scope); err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to remove the simulated collections of the profile")
+	}
+
+	a.Logger.Info().Str("name", profile.Name).Msg("app: Profile deleted")
+
+	return nil
+}
+
+// SwitchProfile activates a profile without logging out.
+// The AniList account and Discord settings of the active profile are saved in it, and the ones of the new profile are restored.
+// Profiles are server-wide, every client uses the active profile.
+// They are meant for a server used by one person at a time, switching changes the profile of every connected client.
+func (a *App) SwitchProfile(id uint) (*models.Profile, error) {
+	a.profileMu.Lock()
+	defer a.profileMu.Unlock()
+
+	target, err := a.Database.GetProfile(id)
+	if err != nil {
+		return nil, err
+	}
+
+	current, err := a.Database.GetActiveProfile()
+	if err != nil {
+		return nil, err
+	}
+
+	if current.ID == target.ID {
+		return target, nil
+	}
+
+	// Save the account and settings of the current profile
+	a.snapshotProfile(current)
+	if err := a.Database.UpdateProfile(current); err != nil {
+		return nil, err
+	}
+
+	if err := a.Database.SetActiveProfile(target.ID); err != nil {
+		return nil, err
+	}
+	target.IsActive = true
+
+	a.setProfileScope(target)
+
+	if err := a.restoreProfile(target); err != nil {
+		return nil, err
+	}
+
+	a.Logger.Info().Str("name", target.Name).Msg("app: Switched profile")
+
+	a.WSEventManager.SendEvent(events.SettingsChanged, nil)
+
+	return target, nil
+}
+
+// snapshotProfile copies the current account and Discord settings into the profile.
+func (a *App) snapshotProfile(profile *models.Profile) {
+	profile.Username = ""
+	profile.Token = ""
+	profile.Viewer = nil
+	if acc, err := a.Database.GetAccount(); err == nil {
+		profile.Username = acc.Username
+		profile.Token = acc.Token
+		profile.Viewer = acc.Viewer
+	}
+
+	if a.Settings != nil && a.Settings.Discord != nil {
+		if data, err := json.Marshal(a.Settings.Discord); err == nil {
+			profile.Discord = data
+		}
+	}
+}
+
+// updateActiveProfileAccount saves the account in the active profile when the user logs in or out,
+// so that switching back to the profile restores the current account instead of the one saved at the last switch.
+func (a *App) updateActiveProfileAccount(acc *models.Account) {
+	profile, err := a.Database.GetActiveProfile()
+	if err != nil {
+		return
+	}
+
+	profile.Username = acc.Username
+	profile.Token = acc.Token
+	profile.Viewer = acc.Viewer
+	if err := a.Database.UpdateProfile(profile); err != nil {
+		a.Logger.Error().Err(err).Msg("app: Failed to update the account of the active profile")
+	}
+}
+
+// restoreProfile restores the account and Discord settings of the profile and refreshes the modules.
+func (a *App) restoreProfile(profile *models.Profile) error {
+	_, err := a.Database.UpsertAccount(&models.Account{
+		BaseModel: models.BaseModel{
+			ID:        1,
+			UpdatedAt: time.Now(),
+		},
+		Username: profile.Username,
+		Token:    profile.Token,
+		Viewer:   profile.Viewer,
+	})
+	if err != nil {
+		return err
+	}
+
+	if settings, err := a.Database.GetSettings(); err == nil {
+		// New profiles start without Discord presence
+		discord := &models.DiscordSettings{RichPresenceUseMediaTitleStatus: true}
+		if len(profile.Discord) > 0 {
+			_ = json.Unmarshal(profile.Discord, discord)
+		}
+		settings.Discord = discord
+		if _, err := a.Database.UpsertSettings(settings); err != nil {
+			return err
+		}
+	}
+
+	a.UpdateAnilistClientToken(profile.Token)
+	if profile.Token != "" {
+		a.UpdatePlatform(anilist_platform.NewAnilistPlatform(a.AnilistClientRef, a.ExtensionBankRef, a.Logger, a.Database, a.LogoutFromAnilist))
+	} else {
+		nextPlatform, err := a.newUnauthenticatedPlatform()
+		if err != nil {
+			return err
+		}
+		a.UpdatePlatform(nextPlatform)
+	}
+
+	a.InitOrRefreshModules()
+	a.InitOrRefreshAnilistData()
+
+	return nil
+}
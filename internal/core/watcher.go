@@ -2,11 +2,27 @@ package core
 
 import (
 	"seanime/internal/events"
+	"seanime/internal/library/anime"
 	"seanime/internal/library/scanner"
 	"seanime/internal/util"
 	"sync"
 )
 
+// OnLibraryScanned is called with the local files after a manual or automatic scan.
+func (a *App) OnLibraryScanned(lfs []*anime.LocalFile) {
+	if a.MediastreamRepository == nil {
+		return
+	}
+
+	paths := make([]string, 0, len(lfs))
+	for _, lf := range lfs {
+		if !lf.IsIgnored() {
+			paths = append(paths, lf.GetPath())
+		}
+	}
+	a.MediastreamRepository.OnLibraryScanned(paths)
+}
+
 func (a *App) UpdateLibrarySize(refreshAC bool) {
 	if a.Settings == nil || a.Settings.Library == nil {
 		return
@@ -0,0 +1,247 @@
+package nfo_source
+
+import (
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"strconv"
+	"strings"
+)
+
+// imageURL returns the URL of a local image of the show, or the remote image of the NFO file.
+func (s *series) imageURL(name string) *string {
+	if _, ok := s.images[name]; ok {
+		return new(ImageRoute + "/" + strconv.Itoa(s.localId) + "/" + name)
+	}
+
+	var remote string
+	switch name {
+	case ImagePoster:
+		remote = remoteThumb(s.show.Thumbs, "poster")
+	case ImageFanart:
+		remote = remoteThumb(s.show.Fanart, "")
+	}
+	if remote == "" {
+		return nil
+	}
+	return new(remote)
+}
+
+func (s *series) status() anilist.MediaStatus {
+	switch strings.ToLower(strings.TrimSpace(s.show.Status)) {
+	case "continuing", "returning series", "in production":
+		return anilist.MediaStatusReleasing
+	case "upcoming", "planned":
+		return anilist.MediaStatusNotYetReleased
+	default:
+		return anilist.MediaStatusFinished
+	}
+}
+
+func (s *series) description() *string {
+	description := strings.TrimSpace(s.show.Plot)
+	if description == "" {
+		description = strings.TrimSpace(s.show.Outline)
+	}
+	if description == "" {
+		return nil
+	}
+	return new(description)
+}
+
+func (s *series) synonyms() []*string {
+	ret := make([]*string, 0)
+	seen := map[string]struct{}{strings.ToLower(s.show.Title): {}}
+	candidates := []string{s.show.OriginalTitle, s.show.SortTitle}
+	for _, dir := range s.dirs {
+		candidates = append(candidates, filepath.Base(dir))
+	}
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(candidate)]; ok {
+			continue
+		}
+		seen[strings.ToLower(candidate)] = struct{}{}
+		ret = append(ret, new(candidate))
+	}
+	return ret
+}
+
+func (s *series) genres() []*string {
+	ret := make([]*string, 0, len(s.show.Genres))
+	for _, genre := range s.show.Genres {
+		if genre = strings.TrimSpace(genre); genre != "" {
+			ret = append(ret, new(genre))
+		}
+	}
+	return ret
+}
+
+// episodesTotal returns the number of episodes, it is unknown while the show is airing.
+func (s *series) episodesTotal() *int {
+	if s.episodeCount == 0 || s.status() != anilist.MediaStatusFinished {
+		return nil
+	}
+	return new(s.episodeCount)
+}
+
+func optionalInt(value int) *int {
+	if value <= 0 {
+		return nil
+	}
+	return new(value)
+}
+
+func (s *series) toBaseAnime() *anilist.BaseAnime {
+	title := s.show.Title
+	startYear, startMonth, startDay := parseNfoDate(s.show.Premiered)
+	if startYear == 0 {
+		startYear = s.show.year()
+	}
+	endYear, endMonth, endDay := parseNfoDate(s.show.EndDate)
+
+	ret := &anilist.BaseAnime{
+		ID:          s.localId,
+		Status:      new(s.status()),
+		Type:        new(anilist.MediaTypeAnime),
+		Format:      new(anilist.MediaFormatTv),
+		SeasonYear:  optionalInt(startYear),
+		BannerImage: s.imageURL(ImageFanart),
+		Episodes:    s.episodesTotal(),
+		Synonyms:    s.synonyms(),
+		IsAdult:     new(false),
+		MeanScore:   optionalInt(s.show.score()),
+		Description: s.description(),
+		Genres:      s.genres(),
+		Duration:    optionalInt(s.show.Runtime),
+		Title: &anilist.BaseAnime_Title{
+			English:       new(title),
+			Romaji:        new(title),
+			UserPreferred: new(title),
+		},
+		CoverImage: &anilist.BaseAnime_CoverImage{},
+		StartDate: &anilist.BaseAnime_StartDate{
+			Year:  optionalInt(startYear),
+			Month: optionalInt(startMonth),
+			Day:   optionalInt(startDay),
+		},
+		EndDate: &anilist.BaseAnime_EndDate{
+			Year:  optionalInt(endYear),
+			Month: optionalInt(endMonth),
+			Day:   optionalInt(endDay),
+		},
+	}
+
+	if s.show.OriginalTitle != "" {
+		ret.Title.Native = new(s.show.OriginalTitle)
+	}
+
+	if poster := s.imageURL(ImagePoster); poster != nil {
+		ret.CoverImage.ExtraLarge = poster
+		ret.CoverImage.Large = poster
+		ret.CoverImage.Medium = poster
+	}
+
+	return ret
+}
+
+func (s *series) toAnimeDetails() *anilist.AnimeDetailsById_Media {
+	base := s.toBaseAnime()
+
+	ret := &anilist.AnimeDetailsById_Media{
+		ID:           s.localId,
+		Description:  base.Description,
+		Genres:       base.Genres,
+		Duration:     base.Duration,
+		MeanScore:    base.MeanScore,
+		AverageScore: base.MeanScore,
+		StartDate: &anilist.AnimeDetailsById_Media_StartDate{
+			Year:  base.StartDate.Year,
+			Month: base.StartDate.Month,
+			Day:   base.StartDate.Day,
+		},
+		EndDate: &anilist.AnimeDetailsById_Media_EndDate{
+			Year:  base.EndDate.Year,
+			Month: base.EndDate.Month,
+			Day:   base.EndDate.Day,
+		},
+		Studios: &anilist.AnimeDetailsById_Media_Studios{
+			Nodes: []*anilist.AnimeDetailsById_Media_Studios_Nodes{},
+		},
+	}
+
+	for _, studio := range s.show.Studios {
+		if studio = strings.TrimSpace(studio); studio != "" {
+			ret.Studios.Nodes = append(ret.Studios.Nodes, &anilist.AnimeDetailsById_Media_Studios_Nodes{Name: studio})
+		}
+	}
+
+	return ret
+}
+
+func (s *series) toAnimeMetadata() *metadata.AnimeMetadata {
+	ret := &metadata.AnimeMetadata{
+		Titles:       map[string]string{"en": s.show.Title},
+		Episodes:     make(map[string]*metadata.EpisodeMetadata, len(s.episodes)),
+		EpisodeCount: s.episodeCount,
+		SpecialCount: s.specialCount,
+		Mappings: &metadata.AnimeMappings{
+			ImdbId:       s.show.uniqueIdOfType("imdb"),
+			ThemoviedbId: s.show.uniqueIdOfType("tmdb"),
+		},
+	}
+	if tvdbId, err := strconv.Atoi(s.show.uniqueIdOfType("tvdb")); err == nil {
+		ret.Mappings.ThetvdbId = tvdbId
+	}
+
+	fallbackImage := ""
+	if image := s.imageURL(ImageFanart); image != nil {
+		fallbackImage = *image
+	} else if image := s.imageURL(ImagePoster); image != nil {
+		fallbackImage = *image
+	}
+
+	for key, ep := range s.episodes {
+		number, _ := strconv.Atoi(strings.TrimPrefix(key, "S"))
+
+		image := remoteThumb(ep.Thumbs, "")
+		hasImage := image != ""
+		if !hasImage {
+			image = fallbackImage
+		}
+
+		runtime := ep.Runtime
+		if runtime == 0 {
+			runtime = s.show.Runtime
+		}
+
+		episodeMetadata := &metadata.EpisodeMetadata{
+			Title:                 ep.Title,
+			Image:                 image,
+			AirDate:               strings.TrimSpace(ep.Aired),
+			Length:                runtime,
+			Summary:               strings.TrimSpace(ep.Plot),
+			Overview:              strings.TrimSpace(ep.Plot),
+			EpisodeNumber:         number,
+			Episode:               key,
+			SeasonNumber:          ep.Season,
+			AbsoluteEpisodeNumber: number,
+			HasImage:              hasImage,
+		}
+		if episodeMetadata.Title == "" {
+			episodeMetadata.Title = "Episode " + key
+		}
+		for _, id := range ep.UniqueIds {
+			if strings.EqualFold(id.Type, "tvdb") {
+				episodeMetadata.TvdbId, _ = strconv.Atoi(strings.TrimSpace(id.Value))
+			}
+		}
+
+		ret.Episodes[key] = episodeMetadata
+	}
+
+	return ret
+}
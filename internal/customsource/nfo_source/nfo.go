@@ -0,0 +1,226 @@
+package nfo_source
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html/charset"
+)
+
+// Kodi/Jellyfin NFO files
+//	https://kodi.wiki/view/NFO_files/TV_shows
+//	https://kodi.wiki/view/NFO_files/Episodes
+
+const (
+	TvShowFilename = "tvshow.nfo"
+)
+
+var (
+	ErrNotAnNfo = errors.New("nfo source: not a supported nfo file")
+)
+
+type (
+	tvShowNfo struct {
+		XMLName       xml.Name      `xml:"tvshow"`
+		Title         string        `xml:"title"`
+		OriginalTitle string        `xml:"originaltitle"`
+		SortTitle     string        `xml:"sorttitle"`
+		Plot          string        `xml:"plot"`
+		Outline       string        `xml:"outline"`
+		Year          int           `xml:"year"`
+		Premiered     string        `xml:"premiered"`
+		EndDate       string        `xml:"enddate"`
+		Status        string        `xml:"status"`
+		Runtime       int           `xml:"runtime"`
+		Genres        []string      `xml:"genre"`
+		Tags          []string      `xml:"tag"`
+		Studios       []string      `xml:"studio"`
+		Country       string        `xml:"country"`
+		Mpaa          string        `xml:"mpaa"`
+		Rating        float64       `xml:"rating"`
+		Ratings       []nfoRating   `xml:"ratings>rating"`
+		UniqueIds     []nfoUniqueId `xml:"uniqueid"`
+		// Legacy <id> element, usually the TVDB ID
+		Id     string     `xml:"id"`
+		Thumbs []nfoThumb `xml:"thumb"`
+		Fanart []nfoThumb `xml:"fanart>thumb"`
+	}
+
+	episodeNfo struct {
+		XMLName   xml.Name      `xml:"episodedetails"`
+		Title     string        `xml:"title"`
+		Season    int           `xml:"season"`
+		Episode   int           `xml:"episode"`
+		Plot      string        `xml:"plot"`
+		Aired     string        `xml:"aired"`
+		Runtime   int           `xml:"runtime"`
+		UniqueIds []nfoUniqueId `xml:"uniqueid"`
+		Thumbs    []nfoThumb    `xml:"thumb"`
+	}
+
+	nfoRating struct {
+		Name    string  `xml:"name,attr"`
+		Max     float64 `xml:"max,attr"`
+		Default bool    `xml:"default,attr"`
+		Value   float64 `xml:"value"`
+	}
+
+	nfoUniqueId struct {
+		Type    string `xml:"type,attr"`
+		Default bool   `xml:"default,attr"`
+		Value   string `xml:",chardata"`
+	}
+
+	nfoThumb struct {
+		Aspect string `xml:"aspect,attr"`
+		Value  string `xml:",chardata"`
+	}
+)
+
+// parseTvShowNfo parses a tvshow.nfo file.
+func parseTvShowNfo(path string) (*tvShowNfo, error) {
+	ret := &tvShowNfo{}
+	if err := decodeNfo(path, ret); err != nil {
+		return nil, err
+	}
+	ret.Title = strings.TrimSpace(ret.Title)
+	ret.OriginalTitle = strings.TrimSpace(ret.OriginalTitle)
+	return ret, nil
+}
+
+// parseEpisodeNfo parses an episode NFO file.
+// Multi-episode files contain several <episodedetails> elements, only the first one is used.
+func parseEpisodeNfo(path string) (*episodeNfo, error) {
+	ret := &episodeNfo{}
+	if err := decodeNfo(path, ret); err != nil {
+		return nil, err
+	}
+	ret.Title = strings.TrimSpace(ret.Title)
+	return ret, nil
+}
+
+func decodeNfo(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	// NFO files can start with a BOM and end with a scraper URL after the XML document
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	if !bytes.Contains(data, []byte("<")) {
+		return ErrNotAnNfo
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = false
+	decoder.CharsetReader = charset.NewReaderLabel
+	if err := decoder.Decode(v); err != nil {
+		var unexpected xml.UnmarshalError
+		if errors.As(err, &unexpected) {
+			return ErrNotAnNfo
+		}
+		return err
+	}
+	return nil
+}
+
+// uniqueId returns the default unique ID of the show, e.g. "tvdb:12345".
+func (n *tvShowNfo) uniqueId() (string, bool) {
+	var ret *nfoUniqueId
+	for i, id := range n.UniqueIds {
+		if strings.TrimSpace(id.Value) == "" {
+			continue
+		}
+		if ret == nil || id.Default {
+			ret = &n.UniqueIds[i]
+		}
+	}
+	if ret != nil {
+		idType := strings.ToLower(strings.TrimSpace(ret.Type))
+		if idType == "" {
+			idType = "unknown"
+		}
+		return idType + ":" + strings.TrimSpace(ret.Value), true
+	}
+	if id := strings.TrimSpace(n.Id); id != "" {
+		return "id:" + id, true
+	}
+	return "", false
+}
+
+// uniqueIdOfType returns the unique ID of the given type, e.g. "tvdb".
+func (n *tvShowNfo) uniqueIdOfType(idType string) string {
+	for _, id := range n.UniqueIds {
+		if strings.EqualFold(id.Type, idType) {
+			return strings.TrimSpace(id.Value)
+		}
+	}
+	return ""
+}
+
+// score returns the rating of the show out of 100.
+func (n *tvShowNfo) score() int {
+	var ret *nfoRating
+	for i, r := range n.Ratings {
+		if ret == nil || r.Default {
+			ret = &n.Ratings[i]
+		}
+	}
+	if ret != nil && ret.Value > 0 {
+		ratingMax := ret.Max
+		if ratingMax <= 0 {
+			ratingMax = 10
+		}
+		return int(ret.Value / ratingMax * 100)
+	}
+	if n.Rating > 0 {
+		return int(n.Rating * 10)
+	}
+	return 0
+}
+
+// year returns the premiere year of the show.
+func (n *tvShowNfo) year() int {
+	if n.Year > 0 {
+		return n.Year
+	}
+	if year, _, _ := parseNfoDate(n.Premiered); year > 0 {
+		return year
+	}
+	return 0
+}
+
+// remoteThumb returns the URL of the first remote image with the given aspect, e.g. "poster".
+func remoteThumb(thumbs []nfoThumb, aspect string) string {
+	for _, thumb := range thumbs {
+		value := strings.TrimSpace(thumb.Value)
+		if aspect != "" && !strings.EqualFold(thumb.Aspect, aspect) {
+			continue
+		}
+		if strings.HasPrefix(value, "http://") || strings.HasPrefix(value, "https://") {
+			return value
+		}
+	}
+	return ""
+}
+
+// parseNfoDate parses "YYYY-MM-DD" dates, missing parts are 0.
+func parseNfoDate(value string) (year int, month int, day int) {
+	parts := strings.Split(strings.TrimSpace(value), "-")
+	fields := []*int{&year, &month, &day}
+	for i, part := range parts {
+		if i >= len(fields) {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return
+		}
+		*fields[i] = n
+	}
+	return
+}
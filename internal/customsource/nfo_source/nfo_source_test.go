@@ -0,0 +1,134 @@
+package nfo_source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"seanime/internal/customsource"
+	"seanime/internal/util"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testTvShowNfo = "\xef\xbb\xbf" + `<?xml version="1.0" encoding="UTF-8" standalone="yes" ?>
+<tvshow>
+    <title>Kino no Tabi</title>
+    <originaltitle>キノの旅</originaltitle>
+    <plot>A traveler and a talking motorcycle.</plot>
+    <year>2003</year>
+    <premiered>2003-04-08</premiered>
+    <status>Ended</status>
+    <runtime>24</runtime>
+    <genre>Adventure</genre>
+    <studio>A.C.G.T</studio>
+    <ratings>
+        <rating name="tvdb" max="10" default="true">
+            <value>8.1</value>
+        </rating>
+    </ratings>
+    <uniqueid type="tvdb" default="true">79099</uniqueid>
+    <uniqueid type="imdb">tt0421357</uniqueid>
+</tvshow>
+https://thetvdb.com/?tab=series&id=79099`
+
+func writeTestFile(t *testing.T, path string, content string) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+}
+
+func writeTestEpisode(t *testing.T, path string, title string, season int, episode int) {
+	writeTestFile(t, path, `<?xml version="1.0" encoding="UTF-8"?>
+<episodedetails>
+    <title>`+title+`</title>
+    <season>`+strconv.Itoa(season)+`</season>
+    <episode>`+strconv.Itoa(episode)+`</episode>
+</episodedetails>`)
+}
+
+func TestProvider(t *testing.T) {
+	library := t.TempDir()
+	showDir := filepath.Join(library, "Kino's Journey")
+
+	writeTestFile(t, filepath.Join(showDir, TvShowFilename), testTvShowNfo)
+	writeTestFile(t, filepath.Join(showDir, "Poster.JPG"), "poster")
+	writeTestEpisode(t, filepath.Join(showDir, "Season 1", "Kino - S01E01.nfo"), "Land of Visible Pain", 1, 1)
+	writeTestEpisode(t, filepath.Join(showDir, "Season 1", "Kino - S01E02.nfo"), "Three Men Along the Rails", 1, 2)
+	writeTestEpisode(t, filepath.Join(showDir, "Season 2", "Kino - S02E01.nfo"), "Land of Books", 2, 1)
+	writeTestEpisode(t, filepath.Join(showDir, "Specials", "Kino - S00E01.nfo"), "Life Goes On", 0, 1)
+	// Folders without a tvshow.nfo file are ignored
+	writeTestEpisode(t, filepath.Join(library, "Other", "Other - S01E01.nfo"), "Other", 1, 1)
+
+	provider := NewProvider(util.NewLogger())
+	provider.SetLibraryPaths([]string{library})
+
+	// The extension is not loaded
+	require.Empty(t, provider.GetFolderMedia(context.Background(), []string{library}))
+
+	provider.SetExtensionIdentifier(5)
+
+	folderMedia := provider.GetFolderMedia(context.Background(), []string{library})
+	require.Len(t, folderMedia, 1)
+	media, ok := folderMedia[util.NormalizePath(showDir)]
+	require.True(t, ok)
+	require.True(t, customsource.IsExtensionId(media.ID))
+	extensionIdentifier, localId := customsource.ExtractExtensionData(media.ID)
+	require.Equal(t, 5, extensionIdentifier)
+	require.Equal(t, "Kino no Tabi", media.GetTitleSafe())
+	require.Equal(t, 81, *media.GetMeanScore())
+	require.Equal(t, 3, *media.GetEpisodes())
+
+	anime, err := provider.GetAnime(context.Background(), []int{localId})
+	require.NoError(t, err)
+	require.Len(t, anime, 1)
+	require.NotNil(t, anime[0].GetCoverImage().GetLarge())
+	require.Nil(t, anime[0].GetBannerImage())
+
+	path, ok := provider.GetImagePath(localId, ImagePoster)
+	require.True(t, ok)
+	require.Equal(t, filepath.Join(showDir, "Poster.JPG"), path)
+	_, ok = provider.GetImagePath(localId, ImageFanart)
+	require.False(t, ok)
+
+	// Episodes are numbered sequentially across seasons
+	animeMetadata, err := provider.GetAnimeMetadata(context.Background(), localId)
+	require.NoError(t, err)
+	require.Equal(t, 3, animeMetadata.EpisodeCount)
+	require.Equal(t, 1, animeMetadata.SpecialCount)
+	require.Equal(t, "Land of Visible Pain", animeMetadata.Episodes["1"].Title)
+	require.Equal(t, "Land of Books", animeMetadata.Episodes["3"].Title)
+	require.Equal(t, 2, animeMetadata.Episodes["3"].SeasonNumber)
+	require.Equal(t, "Life Goes On", animeMetadata.Episodes["S1"].Title)
+	require.Equal(t, 79099, animeMetadata.Mappings.ThetvdbId)
+	require.Equal(t, "tt0421357", animeMetadata.Mappings.ImdbId)
+
+	list, err := provider.ListAnime(context.Background(), "キノ", 1, 10)
+	require.NoError(t, err)
+	require.Equal(t, 1, list.Total)
+	list, err = provider.ListAnime(context.Background(), "unknown", 1, 10)
+	require.NoError(t, err)
+	require.Zero(t, list.Total)
+
+	// The ID does not depend on the folder
+	renamedDir := filepath.Join(library, "Kino no Tabi (2003)")
+	require.NoError(t, os.Rename(showDir, renamedDir))
+
+	folderMedia = provider.GetFolderMedia(context.Background(), []string{library})
+	require.Len(t, folderMedia, 1)
+	require.Equal(t, media.ID, folderMedia[util.NormalizePath(renamedDir)].ID)
+}
+
+func TestDecodeNfo_NotAnNfo(t *testing.T) {
+	dir := t.TempDir()
+
+	// Kodi also accepts NFO files that only contain a URL
+	writeTestFile(t, filepath.Join(dir, TvShowFilename), "https://thetvdb.com/?tab=series&id=79099")
+	_, err := parseTvShowNfo(filepath.Join(dir, TvShowFilename))
+	require.ErrorIs(t, err, ErrNotAnNfo)
+
+	writeTestFile(t, filepath.Join(dir, "movie.nfo"), "<movie><title>Movie</title></movie>")
+	_, err = parseEpisodeNfo(filepath.Join(dir, "movie.nfo"))
+	require.ErrorIs(t, err, ErrNotAnNfo)
+}
@@ -0,0 +1,512 @@
+package nfo_source
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"hash/fnv"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/metadata"
+	"seanime/internal/customsource"
+	hibikecustomsource "seanime/internal/extension/hibike/customsource"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	ExtensionID = "local-nfo"
+
+	// ImageRoute serves the poster and fanart files of the library folders
+	ImageRoute = "/api/v1/custom-source/local-nfo/image"
+
+	// How long the library folders are cached before being walked again
+	refreshInterval = 5 * time.Minute
+)
+
+const (
+	ImagePoster = "poster"
+	ImageFanart = "fanart"
+)
+
+var (
+	ErrMediaNotFound = errors.New("nfo source: media not found")
+	ErrNoManga       = errors.New("nfo source: manga are not supported")
+
+	imageFilenames = map[string][]string{
+		ImagePoster: {"poster.jpg", "poster.png", "poster.webp", "folder.jpg", "folder.png", "cover.jpg", "cover.png"},
+		ImageFanart: {"fanart.jpg", "fanart.png", "fanart.webp", "backdrop.jpg", "backdrop.png", "background.jpg"},
+	}
+)
+
+type (
+	// Provider is a built-in custom source that reads Kodi/Jellyfin-style NFO files from the library folders.
+	// Each folder containing a tvshow.nfo file is a media, its episodes are described by the episode NFO files in its subtree.
+	// Media IDs are derived from the unique ID (or title) of the show so that they don't change when the folder is moved.
+	Provider struct {
+		logger              *zerolog.Logger
+		extensionIdentifier int
+
+		// Prevents concurrent walks of the library folders
+		refreshMu sync.Mutex
+
+		mu           sync.RWMutex
+		libraryPaths []string
+		series       map[int]*series
+		refreshedAt  time.Time
+	}
+
+	series struct {
+		localId int
+		// Folders containing a tvshow.nfo file of the show
+		dirs     []string
+		show     *tvShowNfo
+		episodes map[string]*episodeNfo // key is the episode number, e.g. "1" or "S1"
+		// Number of main episodes and specials
+		episodeCount int
+		specialCount int
+		// Local image files
+		images map[string]string
+	}
+)
+
+func NewProvider(logger *zerolog.Logger) *Provider {
+	return &Provider{
+		logger: logger,
+		series: make(map[int]*series),
+	}
+}
+
+// SetExtensionIdentifier is called when the built-in extension is loaded.
+func (p *Provider) SetExtensionIdentifier(identifier int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.extensionIdentifier = identifier
+}
+
+func (p *Provider) GetExtensionIdentifier() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.extensionIdentifier
+}
+
+// SetLibraryPaths sets the folders that are walked for NFO files.
+func (p *Provider) SetLibraryPaths(paths []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.libraryPaths = paths
+	p.refreshedAt = time.Time{}
+}
+
+func (p *Provider) GetSettings() hibikecustomsource.Settings {
+	return hibikecustomsource.Settings{
+		SupportsAnime: true,
+		SupportsManga: false,
+	}
+}
+
+// GetFolderMedia walks the library paths and returns the media of each folder containing a tvshow.nfo file.
+// The keys are normalized folder paths and the media IDs are the runtime custom source IDs.
+func (p *Provider) GetFolderMedia(ctx context.Context, libraryPaths []string) map[string]*anilist.BaseAnime {
+	ret := make(map[string]*anilist.BaseAnime)
+
+	identifier := p.GetExtensionIdentifier()
+	if identifier == 0 {
+		// The extension is not loaded
+		return ret
+	}
+
+	p.refreshMu.Lock()
+	all, err := p.refresh(ctx, libraryPaths)
+	p.refreshMu.Unlock()
+	if err != nil {
+		return ret
+	}
+
+	for _, s := range all {
+		for _, dir := range s.dirs {
+			media := s.toBaseAnime()
+			customsource.NormalizeMedia(identifier, ExtensionID, media)
+			ret[util.NormalizePath(dir)] = media
+		}
+	}
+
+	return ret
+}
+
+// GetImagePath returns the path of a local image of the media, name is either ImagePoster or ImageFanart.
+func (p *Provider) GetImagePath(localId int, name string) (string, bool) {
+	s, ok := p.getSeries(context.Background())[localId]
+	if !ok {
+		return "", false
+	}
+	path, ok := s.images[name]
+	return path, ok
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Anime
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (p *Provider) GetAnime(ctx context.Context, ids []int) ([]*anilist.BaseAnime, error) {
+	all := p.getSeries(ctx)
+
+	ret := make([]*anilist.BaseAnime, 0, len(ids))
+	for _, id := range ids {
+		if s, ok := all[id]; ok {
+			ret = append(ret, s.toBaseAnime())
+		}
+	}
+	return ret, nil
+}
+
+func (p *Provider) ListAnime(ctx context.Context, search string, page int, perPage int) (*hibikecustomsource.ListAnimeResponse, error) {
+	all := p.getSeries(ctx)
+
+	search = strings.ToLower(strings.TrimSpace(search))
+	media := make([]*anilist.BaseAnime, 0, len(all))
+	for _, s := range all {
+		if search != "" && !s.matchesSearch(search) {
+			continue
+		}
+		media = append(media, s.toBaseAnime())
+	}
+	slices.SortFunc(media, func(a, b *anilist.BaseAnime) int {
+		return cmp.Compare(strings.ToLower(a.GetTitleSafe()), strings.ToLower(b.GetTitleSafe()))
+	})
+
+	if perPage <= 0 {
+		perPage = 20
+	}
+	page = max(page, 1)
+	start := min((page-1)*perPage, len(media))
+	end := min(start+perPage, len(media))
+
+	return &hibikecustomsource.ListAnimeResponse{
+		Media:      media[start:end],
+		Page:       page,
+		TotalPages: (len(media) + perPage - 1) / perPage,
+		Total:      len(media),
+	}, nil
+}
+
+func (p *Provider) GetAnimeWithRelations(ctx context.Context, id int) (*anilist.CompleteAnime, error) {
+	s, ok := p.getSeries(ctx)[id]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	return s.toBaseAnime().ToCompleteAnime(), nil
+}
+
+func (p *Provider) GetAnimeMetadata(ctx context.Context, id int) (*metadata.AnimeMetadata, error) {
+	s, ok := p.getSeries(ctx)[id]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	return s.toAnimeMetadata(), nil
+}
+
+func (p *Provider) GetAnimeDetails(ctx context.Context, id int) (*anilist.AnimeDetailsById_Media, error) {
+	s, ok := p.getSeries(ctx)[id]
+	if !ok {
+		return nil, ErrMediaNotFound
+	}
+	return s.toAnimeDetails(), nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Manga
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (p *Provider) GetManga(ctx context.Context, ids []int) ([]*anilist.BaseManga, error) {
+	return []*anilist.BaseManga{}, nil
+}
+
+func (p *Provider) ListManga(ctx context.Context, search string, page int, perPage int) (*hibikecustomsource.ListMangaResponse, error) {
+	return &hibikecustomsource.ListMangaResponse{
+		Media: []*anilist.BaseManga{},
+		Page:  max(page, 1),
+	}, nil
+}
+
+func (p *Provider) GetMangaDetails(ctx context.Context, id int) (*anilist.MangaDetailsById_Media, error) {
+	return nil, ErrNoManga
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Library folders
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// getSeries returns the cached series, the library paths are walked again when the cache is stale.
+func (p *Provider) getSeries(ctx context.Context) map[int]*series {
+	if all, ok := p.getCachedSeries(); ok {
+		return all
+	}
+
+	p.refreshMu.Lock()
+	defer p.refreshMu.Unlock()
+
+	// Another caller might have refreshed the cache
+	if all, ok := p.getCachedSeries(); ok {
+		return all
+	}
+
+	p.mu.RLock()
+	libraryPaths := p.libraryPaths
+	p.mu.RUnlock()
+
+	all, err := p.refresh(ctx, libraryPaths)
+	if err != nil {
+		p.mu.RLock()
+		defer p.mu.RUnlock()
+		return p.series
+	}
+	return all
+}
+
+func (p *Provider) getCachedSeries() (map[int]*series, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.refreshedAt.IsZero() || time.Since(p.refreshedAt) >= refreshInterval {
+		return nil, false
+	}
+	return p.series, true
+}
+
+// refresh walks the library paths and replaces the cached series.
+// refreshMu should be locked.
+func (p *Provider) refresh(ctx context.Context, libraryPaths []string) (map[int]*series, error) {
+	start := time.Now()
+	all, err := walkLibraryPaths(ctx, libraryPaths, p.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	p.series = all
+	p.refreshedAt = time.Now()
+	p.mu.Unlock()
+
+	p.logger.Debug().Int("count", len(all)).Str("duration", time.Since(start).String()).Msg("nfo source: Refreshed library folders")
+
+	return all, nil
+}
+
+// walkLibraryPaths finds the tvshow.nfo files in the library paths and assigns the episode NFO files to the closest show folder.
+func walkLibraryPaths(ctx context.Context, libraryPaths []string, logger *zerolog.Logger) (map[int]*series, error) {
+	shows := make(map[string]*tvShowNfo) // key is the folder path
+	nfoFiles := make(map[string]struct{})
+
+	for _, root := range libraryPaths {
+		if root == "" {
+			continue
+		}
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				return ctxErr
+			}
+			if err != nil {
+				// Skip unreadable folders
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && strings.HasPrefix(d.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if !strings.EqualFold(filepath.Ext(d.Name()), ".nfo") {
+				return nil
+			}
+
+			if strings.EqualFold(d.Name(), TvShowFilename) {
+				show, err := parseTvShowNfo(path)
+				if err != nil {
+					logger.Warn().Err(err).Str("path", path).Msg("nfo source: Failed to parse show nfo")
+					return nil
+				}
+				shows[filepath.Dir(path)] = show
+				return nil
+			}
+
+			nfoFiles[path] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ret := make(map[int]*series)
+	seriesByDir := make(map[string]*series, len(shows))
+
+	// Sort the folders so that IDs are assigned in the same order on every walk
+	dirs := make([]string, 0, len(shows))
+	for dir := range shows {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+
+	for _, dir := range dirs {
+		show := shows[dir]
+		if show.Title == "" {
+			show.Title = filepath.Base(dir)
+		}
+
+		localId := generateLocalId(seriesKey(show, dir))
+		s, ok := ret[localId]
+		if !ok {
+			// Folders of the same show, e.g. in different library paths, share the media
+			s = &series{
+				localId:  localId,
+				show:     show,
+				episodes: make(map[string]*episodeNfo),
+				images:   make(map[string]string),
+			}
+			ret[localId] = s
+		}
+		s.dirs = append(s.dirs, dir)
+		s.findImages(dir)
+		seriesByDir[dir] = s
+	}
+
+	// Assign the episode NFO files to the closest show folder
+	episodes := make(map[*series][]*episodeNfo)
+	for path := range nfoFiles {
+		s, ok := findParentSeries(seriesByDir, path)
+		if !ok {
+			continue
+		}
+		if strings.EqualFold(filepath.Base(path), "season.nfo") {
+			continue
+		}
+		ep, err := parseEpisodeNfo(path)
+		if err != nil {
+			// Not an episode NFO (e.g. release info)
+			continue
+		}
+		episodes[s] = append(episodes[s], ep)
+	}
+
+	for s, eps := range episodes {
+		s.setEpisodes(eps)
+	}
+
+	return ret, nil
+}
+
+func findParentSeries(seriesByDir map[string]*series, path string) (*series, bool) {
+	dir := filepath.Dir(path)
+	for {
+		if s, ok := seriesByDir[dir]; ok {
+			return s, true
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, false
+		}
+		dir = parent
+	}
+}
+
+// seriesKey returns the key used to generate the local ID of a show.
+func seriesKey(show *tvShowNfo, dir string) string {
+	if id, ok := show.uniqueId(); ok {
+		return id
+	}
+	if show.Title != "" {
+		return "title:" + strings.ToLower(show.Title) + ":" + strconv.Itoa(show.year())
+	}
+	return "path:" + util.NormalizePath(dir)
+}
+
+// generateLocalId hashes the key of a show into a local ID that fits in a custom source media ID.
+func generateLocalId(key string) int {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	id := int(h.Sum64() & customsource.MaxLocalId)
+	if id == 0 {
+		id = 1
+	}
+	return id
+}
+
+func (s *series) findImages(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	files := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			files[strings.ToLower(entry.Name())] = filepath.Join(dir, entry.Name())
+		}
+	}
+	for name, filenames := range imageFilenames {
+		if _, ok := s.images[name]; ok {
+			continue
+		}
+		for _, filename := range filenames {
+			if path, ok := files[filename]; ok {
+				s.images[name] = path
+				break
+			}
+		}
+	}
+}
+
+// setEpisodes numbers the episodes of the show.
+// Season 0 episodes are specials. When the main episodes span multiple seasons, they are numbered sequentially.
+func (s *series) setEpisodes(eps []*episodeNfo) {
+	main := make([]*episodeNfo, 0, len(eps))
+	seen := make(map[[2]int]struct{}, len(eps))
+	seasons := make(map[int]struct{})
+
+	for _, ep := range eps {
+		if ep.Episode <= 0 {
+			continue
+		}
+		// Multiple versions of the same episode
+		if _, ok := seen[[2]int{ep.Season, ep.Episode}]; ok {
+			continue
+		}
+		seen[[2]int{ep.Season, ep.Episode}] = struct{}{}
+
+		if ep.Season == 0 {
+			s.episodes["S"+strconv.Itoa(ep.Episode)] = ep
+			s.specialCount++
+			continue
+		}
+		main = append(main, ep)
+		seasons[ep.Season] = struct{}{}
+	}
+
+	slices.SortFunc(main, func(a, b *episodeNfo) int {
+		return cmp.Or(cmp.Compare(a.Season, b.Season), cmp.Compare(a.Episode, b.Episode))
+	})
+	for i, ep := range main {
+		number := ep.Episode
+		if len(seasons) > 1 {
+			number = i + 1
+		}
+		s.episodes[strconv.Itoa(number)] = ep
+	}
+	s.episodeCount = len(main)
+}
+
+func (s *series) matchesSearch(search string) bool {
+	for _, title := range []string{s.show.Title, s.show.OriginalTitle, s.show.SortTitle} {
+		if title != "" && strings.Contains(strings.ToLower(title), search) {
+			return true
+		}
+	}
+	return false
+}
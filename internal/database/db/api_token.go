@@ -0,0 +1,54 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+	"time"
+)
+
+func (db *Database) GetApiTokens() ([]*models.ApiToken, error) {
+	var res []*models.ApiToken
+	err := db.gormdb.Order("id ASC").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) InsertApiToken(token *models.ApiToken) error {
+	return db.gormdb.Create(token).Error
+}
+
+// DeleteApiToken deletes the token and its audit entries.
+func (db *Database) DeleteApiToken(id uint) error {
+	err := db.gormdb.Delete(&models.ApiToken{}, id).Error
+	if err != nil {
+		return err
+	}
+
+	return db.gormdb.Where("token_id = ?", id).Delete(&models.ApiTokenAuditEntry{}).Error
+}
+
+func (db *Database) UpdateApiTokenLastUsedAt(id uint, lastUsedAt time.Time) error {
+	return db.gormdb.Model(&models.ApiToken{}).Where("id = ?", id).UpdateColumn("last_used_at", lastUsedAt).Error
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (db *Database) InsertApiTokenAuditEntries(entries []*models.ApiTokenAuditEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	return db.gormdb.CreateInBatches(entries, 100).Error
+}
+
+// GetApiTokenAuditEntries returns the most recent audit entries of the token.
+func (db *Database) GetApiTokenAuditEntries(tokenId uint, limit int) ([]*models.ApiTokenAuditEntry, error) {
+	var res []*models.ApiTokenAuditEntry
+	err := db.gormdb.Where("token_id = ?", tokenId).Order("id DESC").Limit(limit).Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
@@ -0,0 +1,52 @@
+package db
+
+import (
+	"os"
+	"seanime/internal/database/models"
+
+	"gorm.io/gorm/clause"
+)
+
+var CurrentBackupSettings *models.BackupSettings
+
+func (db *Database) UpsertBackupSettings(settings *models.BackupSettings) (*models.BackupSettings, error) {
+	settings.ID = 1
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save backup settings in the database")
+		return nil, err
+	}
+
+	CurrentBackupSettings = settings
+
+	db.Logger.Debug().Msg("db: Backup settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetBackupSettings() (*models.BackupSettings, bool) {
+	if CurrentBackupSettings != nil {
+		return CurrentBackupSettings, true
+	}
+
+	var settings models.BackupSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	CurrentBackupSettings = &settings
+	return &settings, true
+}
+
+// SnapshotTo writes a consistent copy of the database to the given path while it stays usable.
+// The copy does not depend on the WAL file of the database.
+func (db *Database) SnapshotTo(path string) error {
+	// VACUUM INTO fails if the file exists
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return db.gormdb.Exec("VACUUM INTO ?", path).Error
+}
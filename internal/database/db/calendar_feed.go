@@ -0,0 +1,41 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+
+	"gorm.io/gorm/clause"
+)
+
+var CurrentCalendarFeedSettings *models.CalendarFeedSettings
+
+func (db *Database) UpsertCalendarFeedSettings(settings *models.CalendarFeedSettings) (*models.CalendarFeedSettings, error) {
+	settings.ID = 1
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save calendar feed settings in the database")
+		return nil, err
+	}
+
+	CurrentCalendarFeedSettings = settings
+
+	db.Logger.Debug().Msg("db: Calendar feed settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetCalendarFeedSettings() (*models.CalendarFeedSettings, bool) {
+	if CurrentCalendarFeedSettings != nil {
+		return CurrentCalendarFeedSettings, true
+	}
+
+	var settings models.CalendarFeedSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	CurrentCalendarFeedSettings = &settings
+	return &settings, true
+}
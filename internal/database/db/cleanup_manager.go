@@ -26,6 +26,7 @@ func (cm *CleanupManager) RunAllCleanupOperations() {
 	cm.trimScanSummaryEntries()
 	cm.trimLocalFileEntries()
 	cm.trimTorrentstreamHistory()
+	cm.trimApiTokenAuditEntries()
 
 	cm.logger.Debug().Msg("database: Cleanup operations completed")
 }
@@ -146,3 +147,42 @@ func (cm *CleanupManager) trimTorrentstreamHistory() {
 		}
 	}
 }
+
+// trimApiTokenAuditEntries trims API token audit entries
+func (cm *CleanupManager) trimApiTokenAuditEntries() {
+	var count int64
+	err := cm.gormdb.Model(&models.ApiTokenAuditEntry{}).Count(&count).Error
+	if err != nil {
+		cm.logger.Error().Err(err).Msg("database: Failed to count API token audit entries")
+		return
+	}
+	if count > 10000 {
+		var idsToDelete []uint
+		err = cm.gormdb.Model(&models.ApiTokenAuditEntry{}).
+			Select("id").
+			Order("id ASC").
+			Limit(int(count-5000)).
+			Pluck("id", &idsToDelete).Error
+		if err != nil {
+			cm.logger.Error().Err(err).Msg("database: Failed to get API token audit entry IDs to delete")
+			return
+		}
+
+		if len(idsToDelete) > 0 {
+			batchSize := 900
+			for i := 0; i < len(idsToDelete); i += batchSize {
+				end := i + batchSize
+				if end > len(idsToDelete) {
+					end = len(idsToDelete)
+				}
+				batch := idsToDelete[i:end]
+				err = cm.gormdb.Delete(&models.ApiTokenAuditEntry{}, batch).Error
+				if err != nil {
+					cm.logger.Error().Err(err).Msg("database: Failed to delete old API token audit entries")
+					return // Exit on first error
+				}
+			}
+			cm.logger.Debug().Int("deleted", len(idsToDelete)).Msg("database: Deleted old API token audit entries")
+		}
+	}
+}
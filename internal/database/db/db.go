@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"seanime/internal/database/models"
+	"sync/atomic"
 	"time"
 
 	"github.com/glebarez/sqlite"
@@ -19,6 +20,7 @@ type Database struct {
 	Logger           *zerolog.Logger
 	CurrMediaFillers mo.Option[map[int]*MediaFillerItem]
 	cleanupManager   *CleanupManager
+	profileScope     atomic.Uint64 // See ProfileScope
 }
 
 func (db *Database) Gorm() *gorm.DB {
@@ -95,6 +97,7 @@ func migrateTables(db *gorm.DB) error {
 		&models.AutoSelectProfile{},
 		&models.AutoDownloaderRule{},
 		&models.AutoDownloaderProfile{},
+		&models.AutoDownloaderFeed{},
 		&models.AutoDownloaderItem{},
 		&models.SilencedMediaEntry{},
 		&models.Theme{},
@@ -111,6 +114,15 @@ func migrateTables(db *gorm.DB) error {
 		&models.DummyDebridSettings{},
 		&models.DebridTorrentItem{},
 		&models.DebridTransferHash{},
+		&models.UsenetSettings{},
+		&models.UsenetJobItem{},
+		&models.DlnaSettings{},
+		&models.BackupSettings{},
+		&models.CalendarFeedSettings{},
+		&models.WatchSession{},
+		&models.ApiToken{},
+		&models.ApiTokenAuditEntry{},
+		&models.Profile{},
 		&models.PluginData{},
 		&models.CustomSourceCollection{},
 		&models.CustomSourceIdentifier{},
@@ -123,6 +135,12 @@ func migrateTables(db *gorm.DB) error {
 		return err
 	}
 
+	// Playlists created before profiles belong to the default profile
+	err = db.Model(&models.Playlist{}).Where("profile_id IS NULL").UpdateColumn("profile_id", 0).Error
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -0,0 +1,41 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+
+	"gorm.io/gorm/clause"
+)
+
+var CurrentDlnaSettings *models.DlnaSettings
+
+func (db *Database) UpsertDlnaSettings(settings *models.DlnaSettings) (*models.DlnaSettings, error) {
+	settings.ID = 1
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save DLNA settings in the database")
+		return nil, err
+	}
+
+	CurrentDlnaSettings = settings
+
+	db.Logger.Debug().Msg("db: DLNA settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetDlnaSettings() (*models.DlnaSettings, bool) {
+	if CurrentDlnaSettings != nil {
+		return CurrentDlnaSettings, true
+	}
+
+	var settings models.DlnaSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	CurrentDlnaSettings = &settings
+	return &settings, true
+}
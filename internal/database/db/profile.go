@@ -0,0 +1,131 @@
+package db
+
+import (
+	"errors"
+	"seanime/internal/database/models"
+
+	"gorm.io/gorm"
+)
+
+// ProfileScope returns the scope of the data owned by the active profile (playlists, etc.).
+// The default profile has the scope 0 so that the data created before profiles existed belongs to it.
+func (db *Database) ProfileScope() uint {
+	return uint(db.profileScope.Load())
+}
+
+func (db *Database) SetProfileScope(scope uint) {
+	db.profileScope.Store(uint64(scope))
+}
+
+// GetProfileScope returns the scope of the data owned by the profile.
+func GetProfileScope(profile *models.Profile) uint {
+	if profile == nil || profile.IsDefault {
+		return 0
+	}
+	return profile.ID
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// EnsureDefaultProfile creates the default profile if no profile exists and returns the active profile.
+func (db *Database) EnsureDefaultProfile() (*models.Profile, error) {
+	active, err := db.GetActiveProfile()
+	if err == nil {
+		return active, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var count int64
+	if err := db.gormdb.Model(&models.Profile{}).Count(&count).Error; err != nil {
+		return nil, err
+	}
+
+	// No active profile, activate the default one
+	if count > 0 {
+		var def models.Profile
+		if err := db.gormdb.Where("is_default = ?", true).First(&def).Error; err != nil {
+			return nil, err
+		}
+		if err := db.SetActiveProfile(def.ID); err != nil {
+			return nil, err
+		}
+		def.IsActive = true
+		return &def, nil
+	}
+
+	profile := &models.Profile{
+		Name:      "Default",
+		IsDefault: true,
+		IsActive:  true,
+	}
+	if err := db.gormdb.Create(profile).Error; err != nil {
+		return nil, err
+	}
+
+	db.Logger.Debug().Msg("db: Default profile created")
+
+	return profile, nil
+}
+
+func (db *Database) GetProfiles() ([]*models.Profile, error) {
+	var res []*models.Profile
+	err := db.gormdb.Order("id ASC").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) GetProfile(id uint) (*models.Profile, error) {
+	var res models.Profile
+	err := db.gormdb.First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *Database) GetActiveProfile() (*models.Profile, error) {
+	var res models.Profile
+	err := db.gormdb.Where("is_active = ?", true).First(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (db *Database) InsertProfile(profile *models.Profile) error {
+	return db.gormdb.Create(profile).Error
+}
+
+func (db *Database) UpdateProfile(profile *models.Profile) error {
+	return db.gormdb.Save(profile).Error
+}
+
+// SetActiveProfile marks the profile as active and the others as inactive.
+func (db *Database) SetActiveProfile(id uint) error {
+	return db.gormdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Profile{}).Where("id <> ?", id).UpdateColumn("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Profile{}).Where("id = ?", id).UpdateColumn("is_active", true).Error
+	})
+}
+
+// DeleteProfile deletes the profile and the data it owns in the database.
+func (db *Database) DeleteProfile(id uint) error {
+	return db.gormdb.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("profile_id = ?", id).Delete(&models.Playlist{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("profile_id = ?", id).Delete(&models.WatchSession{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Profile{}, id).Error
+	})
+}
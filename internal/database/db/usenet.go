@@ -0,0 +1,65 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+
+	"gorm.io/gorm/clause"
+)
+
+var CurrentUsenetSettings *models.UsenetSettings
+
+func (db *Database) UpsertUsenetSettings(settings *models.UsenetSettings) (*models.UsenetSettings, error) {
+	settings.ID = 1
+	err := db.gormdb.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+
+	if err != nil {
+		db.Logger.Error().Err(err).Msg("db: Failed to save usenet settings in the database")
+		return nil, err
+	}
+
+	CurrentUsenetSettings = settings
+
+	db.Logger.Debug().Msg("db: Usenet settings saved")
+	return settings, nil
+}
+
+func (db *Database) GetUsenetSettings() (*models.UsenetSettings, bool) {
+	if CurrentUsenetSettings != nil {
+		return CurrentUsenetSettings, true
+	}
+
+	var settings models.UsenetSettings
+	err := db.gormdb.Where("id = ?", 1).First(&settings).Error
+	if err != nil {
+		return nil, false
+	}
+	CurrentUsenetSettings = &settings
+	return &settings, true
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (db *Database) GetUsenetJobItems() ([]*models.UsenetJobItem, error) {
+	var res []*models.UsenetJobItem
+	err := db.gormdb.Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) InsertUsenetJobItem(item *models.UsenetJobItem) error {
+	return db.gormdb.Create(item).Error
+}
+
+func (db *Database) DeleteUsenetJobItemByDbId(dbId uint) error {
+	return db.gormdb.Delete(&models.UsenetJobItem{}, dbId).Error
+}
+
+func (db *Database) DeleteUsenetJobItemByJobId(jobId string) error {
+	return db.gormdb.Where("job_id = ?", jobId).Delete(&models.UsenetJobItem{}).Error
+}
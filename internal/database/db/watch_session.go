@@ -0,0 +1,34 @@
+package db
+
+import (
+	"seanime/internal/database/models"
+	"time"
+)
+
+func (db *Database) InsertWatchSession(session *models.WatchSession) error {
+	return db.gormdb.Create(session).Error
+}
+
+// GetWatchSessions returns the sessions of the active profile started in [from, to), oldest first.
+// A zero time leaves the range open on that side.
+func (db *Database) GetWatchSessions(from, to time.Time) ([]*models.WatchSession, error) {
+	query := db.gormdb.Where("profile_id = ?", db.ProfileScope())
+	if !from.IsZero() {
+		query = query.Where("started_at >= ?", from)
+	}
+	if !to.IsZero() {
+		query = query.Where("started_at < ?", to)
+	}
+
+	var res []*models.WatchSession
+	err := query.Order("started_at ASC").Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (db *Database) DeleteWatchSession(id uint) error {
+	return db.gormdb.Where("id = ? AND profile_id = ?", id, db.ProfileScope()).Delete(&models.WatchSession{}).Error
+}
@@ -0,0 +1,88 @@
+package db_bridge
+
+import (
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+
+	"github.com/goccy/go-json"
+)
+
+func GetAutoDownloaderFeeds(db *db.Database) ([]*anime.AutoDownloaderFeed, error) {
+
+	var res []*models.AutoDownloaderFeed
+	err := db.Gorm().Find(&res).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the data
+	var feeds []*anime.AutoDownloaderFeed
+	for _, r := range res {
+		smBytes := r.Value
+		var sm anime.AutoDownloaderFeed
+		if err := json.Unmarshal(smBytes, &sm); err != nil {
+			return nil, err
+		}
+		sm.DbID = r.ID
+		feeds = append(feeds, &sm)
+	}
+
+	return feeds, nil
+}
+
+func GetAutoDownloaderFeed(db *db.Database, id uint) (*anime.AutoDownloaderFeed, error) {
+	var res models.AutoDownloaderFeed
+	err := db.Gorm().First(&res, id).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal the data
+	smBytes := res.Value
+	var sm anime.AutoDownloaderFeed
+	if err := json.Unmarshal(smBytes, &sm); err != nil {
+		return nil, err
+	}
+	sm.DbID = res.ID
+
+	return &sm, nil
+}
+
+func InsertAutoDownloaderFeed(db *db.Database, sm *anime.AutoDownloaderFeed) error {
+
+	// Marshal the data
+	bytes, err := json.Marshal(sm)
+	if err != nil {
+		return err
+	}
+
+	// Save the data
+	model := &models.AutoDownloaderFeed{
+		Value: bytes,
+	}
+	if err := db.Gorm().Create(model).Error; err != nil {
+		return err
+	}
+
+	sm.DbID = model.ID
+
+	return nil
+}
+
+func DeleteAutoDownloaderFeed(db *db.Database, id uint) error {
+
+	return db.Gorm().Delete(&models.AutoDownloaderFeed{}, id).Error
+}
+
+func UpdateAutoDownloaderFeed(db *db.Database, id uint, sm *anime.AutoDownloaderFeed) error {
+
+	// Marshal the data
+	bytes, err := json.Marshal(sm)
+	if err != nil {
+		return err
+	}
+
+	// Save the data
+	return db.Gorm().Model(&models.AutoDownloaderFeed{}).Where("id = ?", id).Update("value", bytes).Error
+}
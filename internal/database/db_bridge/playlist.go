@@ -10,7 +10,7 @@ import (
 
 func GetPlaylists(db *db.Database) ([]*anime.Playlist, error) {
 	var res []*models.Playlist
-	err := db.Gorm().Find(&res).Error
+	err := db.Gorm().Where("profile_id = ?", db.ProfileScope()).Find(&res).Error
 	if err != nil {
 		return nil, err
 	}
@@ -22,6 +22,7 @@ func GetPlaylists(db *db.Database) ([]*anime.Playlist, error) {
 			playlist := anime.NewPlaylist(p.Name)
 			playlist.SetEpisodes(eps)
 			playlist.DbId = p.ID
+			playlist.Rules = unmarshalPlaylistRules(p.Rules)
 			playlists = append(playlists, playlist)
 		}
 	}
@@ -30,7 +31,7 @@ func GetPlaylists(db *db.Database) ([]*anime.Playlist, error) {
 
 func GetPlaylistsWithoutEpisodes(db *db.Database) ([]*anime.Playlist, error) {
 	var res []*models.Playlist
-	err := db.Gorm().Find(&res).Error
+	err := db.Gorm().Where("profile_id = ?", db.ProfileScope()).Find(&res).Error
 	if err != nil {
 		return nil, err
 	}
@@ -41,6 +42,7 @@ func GetPlaylistsWithoutEpisodes(db *db.Database) ([]*anime.Playlist, error) {
 		if err := json.Unmarshal(p.Value, &eps); err == nil {
 			playlist := anime.NewPlaylist(p.Name)
 			playlist.DbId = p.ID
+			playlist.Rules = unmarshalPlaylistRules(p.Rules)
 			playlists = append(playlists, playlist)
 		}
 	}
@@ -52,16 +54,22 @@ func SavePlaylist(db *db.Database, playlist *anime.Playlist) error {
 	if err != nil {
 		return err
 	}
+	rules, err := marshalPlaylistRules(playlist.Rules)
+	if err != nil {
+		return err
+	}
 	entry := &models.Playlist{
-		Name:  playlist.Name,
-		Value: data,
+		Name:      playlist.Name,
+		Value:     data,
+		Rules:     rules,
+		ProfileID: db.ProfileScope(),
 	}
 
 	return db.Gorm().Save(entry).Error
 }
 
 func DeletePlaylist(db *db.Database, id uint) error {
-	return db.Gorm().Where("id = ?", id).Delete(&models.Playlist{}).Error
+	return db.Gorm().Where("id = ? AND profile_id = ?", id, db.ProfileScope()).Delete(&models.Playlist{}).Error
 }
 
 func UpdatePlaylist(db *db.Database, playlist *anime.Playlist) error {
@@ -69,23 +77,28 @@ func UpdatePlaylist(db *db.Database, playlist *anime.Playlist) error {
 	if err != nil {
 		return err
 	}
+	rules, err := marshalPlaylistRules(playlist.Rules)
+	if err != nil {
+		return err
+	}
 
 	// Get the playlist entry
 	entry := &models.Playlist{}
-	if err := db.Gorm().Where("id = ?", playlist.DbId).First(entry).Error; err != nil {
+	if err := db.Gorm().Where("id = ? AND profile_id = ?", playlist.DbId, db.ProfileScope()).First(entry).Error; err != nil {
 		return err
 	}
 
 	// Update the playlist entry
 	entry.Name = playlist.Name
 	entry.Value = data
+	entry.Rules = rules
 
 	return db.Gorm().Save(entry).Error
 }
 
 func GetPlaylist(db *db.Database, id uint) (*anime.Playlist, error) {
 	entry := &models.Playlist{}
-	if err := db.Gorm().Where("id = ?", id).First(entry).Error; err != nil {
+	if err := db.Gorm().Where("id = ? AND profile_id = ?", id, db.ProfileScope()).First(entry).Error; err != nil {
 		return nil, err
 	}
 
@@ -97,6 +110,26 @@ func GetPlaylist(db *db.Database, id uint) (*anime.Playlist, error) {
 	playlist := anime.NewPlaylist(entry.Name)
 	playlist.SetEpisodes(eps)
 	playlist.DbId = entry.ID
+	playlist.Rules = unmarshalPlaylistRules(entry.Rules)
 
 	return playlist, nil
 }
+
+func marshalPlaylistRules(rules *anime.PlaylistRules) ([]byte, error) {
+	if rules == nil {
+		return nil, nil
+	}
+	return json.Marshal(rules)
+}
+
+// unmarshalPlaylistRules returns nil for static playlists.
+func unmarshalPlaylistRules(data []byte) *anime.PlaylistRules {
+	if len(data) == 0 {
+		return nil
+	}
+	var rules anime.PlaylistRules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil
+	}
+	return &rules
+}
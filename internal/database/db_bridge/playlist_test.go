@@ -0,0 +1,41 @@
+package db_bridge
+
+import (
+	"path/filepath"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/util"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// Playlists created before profiles existed belong to the default profile after the migration.
+func TestGetPlaylistsAfterProfileMigration(t *testing.T) {
+	t.Setenv("TEST_ENV", "")
+	dir := t.TempDir()
+
+	type playlist struct {
+		models.BaseModel
+		Name  string `gorm:"column:name"`
+		Value []byte `gorm:"column:value"`
+	}
+
+	gormdb, err := gorm.Open(sqlite.Open(filepath.Join(dir, "playlists.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, gormdb.Table("playlists").AutoMigrate(&playlist{}))
+	require.NoError(t, gormdb.Table("playlists").Create(&playlist{Name: "Weekend", Value: []byte("[]")}).Error)
+	sqlDB, err := gormdb.DB()
+	require.NoError(t, err)
+	require.NoError(t, sqlDB.Close())
+
+	database, err := db.NewDatabase(dir, "playlists", util.NewLogger())
+	require.NoError(t, err)
+
+	playlists, err := GetPlaylists(database)
+	require.NoError(t, err)
+	require.Len(t, playlists, 1)
+	require.Equal(t, "Weekend", playlists[0].Name)
+}
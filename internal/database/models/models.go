@@ -28,6 +28,24 @@ type Account struct {
 	Viewer   []byte `gorm:"column:viewer" json:"viewer"`
 }
 
+// +---------------------+
+// |       Profile       |
+// +---------------------+
+
+// Profile is a person using the server.
+// Each profile has its own AniList account, watch history, playlists and Discord settings, the rest is shared.
+// The Account of the active profile is the one stored in the Account table, the other profiles keep theirs here until they are activated.
+type Profile struct {
+	BaseModel
+	Name      string `gorm:"column:name" json:"name"`
+	IsDefault bool   `gorm:"column:is_default" json:"isDefault"` // The default profile owns the data created before profiles existed
+	IsActive  bool   `gorm:"column:is_active" json:"isActive"`
+	Username  string `gorm:"column:username" json:"username"`
+	Token     string `gorm:"column:token" json:"-"`
+	Viewer    []byte `gorm:"column:viewer" json:"-"`
+	Discord   []byte `gorm:"column:discord" json:"-"` // Marshaled DiscordSettings
+}
+
 // +---------------------+
 // |     LocalFiles      |
 // +---------------------+
@@ -66,6 +84,8 @@ type AnilistSettings struct {
 	EnableAdultContent bool `gorm:"column:enable_adult_content" json:"enableAdultContent"`
 	BlurAdultContent   bool `gorm:"column:blur_adult_content" json:"blurAdultContent"`
 	DisableCacheLayer  bool `gorm:"column:disable_cache_layer" json:"disableCacheLayer"`
+	// UseMalList uses the connected MyAnimeList account as the list when not logged in to AniList
+	UseMalList bool `gorm:"column:use_mal_list" json:"useMalList"`
 }
 
 type LibrarySettings struct {
@@ -182,10 +202,17 @@ type MangaSettings struct {
 	DefaultProvider      string `gorm:"column:default_manga_provider" json:"defaultMangaProvider"`
 	AutoUpdateProgress   bool   `gorm:"column:manga_auto_update_progress" json:"mangaAutoUpdateProgress"`
 	LocalSourceDirectory string `gorm:"column:manga_local_source_directory" json:"mangaLocalSourceDirectory"`
+	// Processing of the pages of downloaded chapters
+	PageSplitSpreads bool   `gorm:"column:manga_page_split_spreads" json:"mangaPageSplitSpreads"`
+	PageLeftToRight  bool   `gorm:"column:manga_page_left_to_right" json:"mangaPageLeftToRight"`
+	PageTrimBorders  bool   `gorm:"column:manga_page_trim_borders" json:"mangaPageTrimBorders"`
+	PageGrayscale    bool   `gorm:"column:manga_page_grayscale" json:"mangaPageGrayscale"`
+	PageFormat       string `gorm:"column:manga_page_format" json:"mangaPageFormat"` // "" or "jpeg"
+	PageQuality      int    `gorm:"column:manga_page_quality" json:"mangaPageQuality"`
 }
 
 type MediaPlayerSettings struct {
-	Default                   string `gorm:"column:default_player" json:"defaultPlayer"` // "vlc" or "mpc-hc"
+	Default                   string `gorm:"column:default_player" json:"defaultPlayer"` // "vlc", "mpc-hc", "mpv", "iina", "kodi" or "upnp"
 	Host                      string `gorm:"column:player_host" json:"host"`
 	VlcUsername               string `gorm:"column:vlc_username" json:"vlcUsername"`
 	VlcPassword               string `gorm:"column:vlc_password" json:"vlcPassword"`
@@ -199,6 +226,11 @@ type MediaPlayerSettings struct {
 	IinaSocket                string `gorm:"column:iina_socket" json:"iinaSocket"`
 	IinaPath                  string `gorm:"column:iina_path" json:"iinaPath"`
 	IinaArgs                  string `gorm:"column:iina_args" json:"iinaArgs"`
+	KodiHost                  string `gorm:"column:kodi_host" json:"kodiHost"`
+	KodiPort                  int    `gorm:"column:kodi_port" json:"kodiPort"`
+	KodiUsername              string `gorm:"column:kodi_username" json:"kodiUsername"`
+	KodiPassword              string `gorm:"column:kodi_password" json:"kodiPassword"`
+	UpnpRendererLocation      string `gorm:"column:upnp_renderer_location" json:"upnpRendererLocation"` // URL of the device description of the DLNA/UPnP renderer
 	VcTranslate               bool   `gorm:"column:vc_translate" json:"vcTranslate"`
 	VcTranslateTargetLanguage string `gorm:"column:vc_translate_target_language" json:"vcTranslateTargetLanguage"`
 	VcTranslateProvider       string `gorm:"column:vc_translate_provider" json:"vcTranslateProvider"`
@@ -307,6 +339,11 @@ type AutoDownloaderProfile struct {
 	Value []byte `gorm:"column:value" json:"value"`
 }
 
+type AutoDownloaderFeed struct {
+	BaseModel
+	Value []byte `gorm:"column:value" json:"value"`
+}
+
 // +---------------------+
 // |     Auto Select     |
 // +---------------------+
@@ -324,6 +361,7 @@ type AutoDownloaderItem struct {
 	Link        string    `gorm:"column:link" json:"link"`
 	Hash        string    `gorm:"column:hash" json:"hash"`
 	Magnet      string    `gorm:"column:magnet" json:"magnet"`
+	NzbUrl      string    `gorm:"column:nzb_url" json:"nzbUrl"` // Set for NZB releases, which are sent to the Usenet download client
 	TorrentName string    `gorm:"column:torrent_name" json:"torrentName"`
 	Downloaded  bool      `gorm:"column:downloaded" json:"downloaded"`
 	IsDelayed   bool      `gorm:"column:is_delayed" json:"isDelayed"`
@@ -341,6 +379,8 @@ type AutoDownloaderSettings struct {
 	EnableEnhancedQueries bool `gorm:"column:auto_downloader_enable_enhanced_queries" json:"enableEnhancedQueries"`
 	EnableSeasonCheck     bool `gorm:"column:auto_downloader_enable_season_check" json:"enableSeasonCheck"`
 	UseDebrid             bool `gorm:"column:auto_downloader_use_debrid" json:"useDebrid"`
+	// Interval in minutes at which RSS feeds are polled
+	FeedInterval int `gorm:"column:auto_downloader_feed_interval" json:"feedInterval"`
 }
 
 // +---------------------+
@@ -440,8 +480,10 @@ type PlaylistEntry struct {
 
 type Playlist struct {
 	BaseModel
-	Name  string `gorm:"column:name" json:"name"`
-	Value []byte `gorm:"column:value" json:"value"`
+	Name      string `gorm:"column:name" json:"name"`
+	Value     []byte `gorm:"column:value" json:"value"`
+	Rules     []byte `gorm:"column:rules" json:"rules"`                          // Rules of smart playlists, nil for static playlists
+	ProfileID uint   `gorm:"column:profile_id;index;default:0" json:"profileId"` // 0 for the default profile
 }
 
 // +------------------------+
@@ -477,6 +519,11 @@ type MediastreamSettings struct {
 	FfprobePath                   string `gorm:"column:ffprobe_path" json:"ffprobePath"`
 	// v2.2+
 	TranscodeHwAccelCustomSettings string `gorm:"column:transcode_hw_accel_custom_settings" json:"transcodeHwAccelCustomSettings"`
+	// Seek bar previews
+	TrickplayEnabled  bool   `gorm:"column:trickplay_enabled" json:"trickplayEnabled"`
+	TrickplayMode     string `gorm:"column:trickplay_mode" json:"trickplayMode"`         // "on-demand" or "on-scan"
+	TrickplayInterval int    `gorm:"column:trickplay_interval" json:"trickplayInterval"` // Seconds between two thumbnails
+	TrickplayWidth    int    `gorm:"column:trickplay_width" json:"trickplayWidth"`       // Width of the thumbnails in pixels
 
 	//TranscodeTempDir              string `gorm:"column:transcode_temp_dir" json:"transcodeTempDir"` // DEPRECATED
 }
@@ -653,6 +700,116 @@ type DebridTransferHash struct {
 	Hash       string `gorm:"column:hash;index" json:"hash"`
 }
 
+// +---------------------+
+// |       Usenet        |
+// +---------------------+
+
+type UsenetSettings struct {
+	BaseModel
+	Enabled  bool   `gorm:"column:enabled" json:"enabled"`
+	Provider string `gorm:"column:provider" json:"provider"` // "sabnzbd" or "nzbget"
+	Host     string `gorm:"column:host" json:"host"`         // URL of the web interface, e.g. "http://localhost:8080"
+	ApiKey   string `gorm:"column:api_key" json:"apiKey"`    // SABnzbd only
+	Username string `gorm:"column:username" json:"username"` // NZBGet only
+	Password string `gorm:"column:password" json:"password"` // NZBGet only
+	Category string `gorm:"column:category" json:"category"`
+}
+
+// UsenetJobItem is a job added by Seanime that is tracked until it completes.
+type UsenetJobItem struct {
+	BaseModel
+	JobID       string `gorm:"column:job_id;index" json:"jobId"`
+	Provider    string `gorm:"column:provider" json:"provider"`
+	Name        string `gorm:"column:name" json:"name"`
+	Hash        string `gorm:"column:hash" json:"hash"` // Release hash, see usenet.ReleaseHash
+	Destination string `gorm:"column:destination" json:"destination"`
+	MediaId     int    `gorm:"column:media_id" json:"mediaId"`
+}
+
+// +---------------------+
+// |        DLNA         |
+// +---------------------+
+
+type DlnaSettings struct {
+	BaseModel
+	Enabled            bool   `gorm:"column:enabled" json:"enabled"`
+	FriendlyName       string `gorm:"column:friendly_name" json:"friendlyName"` // Name displayed by renderers
+	Port               int    `gorm:"column:port" json:"port"`
+	AutoUpdateProgress bool   `gorm:"column:auto_update_progress" json:"autoUpdateProgress"` // Update progress when a renderer plays an episode
+}
+
+// +---------------------+
+// |       Backups       |
+// +---------------------+
+
+type BackupSettings struct {
+	BaseModel
+	// Enabled creates backups on a schedule
+	Enabled bool `gorm:"column:enabled" json:"enabled"`
+	// IntervalHours is the number of hours between two scheduled backups
+	IntervalHours int `gorm:"column:interval_hours" json:"intervalHours"`
+	// Retention is the number of scheduled backups to keep, manual backups are never deleted
+	Retention int `gorm:"column:retention" json:"retention"`
+	// Dir is where the backups are written, defaults to the backups folder of the data directory
+	Dir string `gorm:"column:dir" json:"dir"`
+}
+
+// +---------------------+
+// |    Calendar Feed    |
+// +---------------------+
+
+// CalendarFeedSettings configures the iCalendar feed of the airing schedule.
+type CalendarFeedSettings struct {
+	BaseModel
+	Enabled bool `gorm:"column:enabled" json:"enabled"`
+	// Token is sent by calendar apps in the feed URL since they cannot authenticate
+	Token string `gorm:"column:token" json:"token"`
+	// Delays is the expected release delay of shows in minutes, by media ID
+	Delays map[int]int `gorm:"column:delays;serializer:json" json:"delays"`
+}
+
+// +---------------------+
+// |     API Tokens      |
+// +---------------------+
+
+// ApiToken gives access to the server with a role (admin, viewer, guest).
+// Only the SHA-256 hash of the token is stored, the client sends the hash in the X-Seanime-Token header like the server password.
+type ApiToken struct {
+	BaseModel
+	Name       string     `gorm:"column:name" json:"name"`
+	Role       string     `gorm:"column:role" json:"role"`
+	TokenHash  string     `gorm:"column:token_hash;uniqueIndex" json:"-"`
+	LastUsedAt *time.Time `gorm:"column:last_used_at" json:"lastUsedAt"`
+}
+
+// ApiTokenAuditEntry is a request made with an API token.
+type ApiTokenAuditEntry struct {
+	BaseModel
+	TokenID uint   `gorm:"column:token_id;index" json:"tokenId"`
+	Method  string `gorm:"column:method" json:"method"`
+	Path    string `gorm:"column:path" json:"path"`
+	Status  int    `gorm:"column:status" json:"status"`
+}
+
+// +---------------------+
+// |    Watch Journal    |
+// +---------------------+
+
+// WatchSession is a viewing session of an episode, recorded when the playback ends.
+type WatchSession struct {
+	BaseModel
+	MediaId        int       `gorm:"column:media_id;index" json:"mediaId"`
+	EpisodeNumber  int       `gorm:"column:episode_number" json:"episodeNumber"`
+	Kind           string    `gorm:"column:kind" json:"kind"`     // Playback kind, e.g. "localfile", "torrent", "onlinestream"
+	Player         string    `gorm:"column:player" json:"player"` // "videocore", "mpvcore" or "external"
+	StartedAt      time.Time `gorm:"column:started_at;index" json:"startedAt"`
+	EndedAt        time.Time `gorm:"column:ended_at" json:"endedAt"`
+	WatchedSeconds float64   `gorm:"column:watched_seconds" json:"watchedSeconds"` // Time spent playing, seeks excluded
+	Duration       float64   `gorm:"column:duration" json:"duration"`              // Duration of the episode in seconds
+	Completed      bool      `gorm:"column:completed" json:"completed"`
+	ProfileID      uint      `gorm:"column:profile_id;index" json:"profileId"` // 0 for the default profile
+}
+
 // +---------------------+
 // |       Plugin        |
 // +---------------------+
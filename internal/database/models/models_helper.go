@@ -78,6 +78,7 @@ func (s *Settings) GetSensitiveValues() []string {
 	}
 	return []string{
 		s.GetMediaPlayer().VlcPassword,
+		s.GetMediaPlayer().KodiPassword,
 		s.GetTorrent().QBittorrentPassword,
 		s.GetTorrent().TransmissionPassword,
 		s.GetNakama().RemoteServerPassword,
@@ -95,3 +96,73 @@ func (s *DebridSettings) GetSensitiveValues() []string {
 		s.ApiKey,
 	}
 }
+
+func (s *UsenetSettings) GetSensitiveValues() []string {
+	if s == nil {
+		return []string{}
+	}
+	return []string{
+		s.ApiKey,
+		s.Password,
+	}
+}
+
+// Redacted returns a copy of the settings without the passwords and API keys.
+// It is sent to clients that are not allowed to change the settings.
+func (s *Settings) Redacted() *Settings {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	if s.MediaPlayer != nil {
+		mediaPlayer := *s.MediaPlayer
+		mediaPlayer.VlcPassword = ""
+		mediaPlayer.KodiPassword = ""
+		mediaPlayer.VcTranslateApiKey = ""
+		ret.MediaPlayer = &mediaPlayer
+	}
+	if s.Torrent != nil {
+		torrent := *s.Torrent
+		torrent.QBittorrentPassword = ""
+		torrent.TransmissionPassword = ""
+		ret.Torrent = &torrent
+	}
+	if s.Nakama != nil {
+		nakama := *s.Nakama
+		nakama.HostPassword = ""
+		nakama.RemoteServerPassword = ""
+		ret.Nakama = &nakama
+	}
+	return &ret
+}
+
+// Redacted returns a copy of the settings without the API key.
+func (s *DebridSettings) Redacted() *DebridSettings {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	ret.ApiKey = ""
+	return &ret
+}
+
+// Redacted returns a copy of the settings without the API key and the password.
+func (s *UsenetSettings) Redacted() *UsenetSettings {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	ret.ApiKey = ""
+	ret.Password = ""
+	return &ret
+}
+
+// Redacted returns a copy of the settings without the token.
+func (s *CalendarFeedSettings) Redacted() *CalendarFeedSettings {
+	if s == nil {
+		return nil
+	}
+	ret := *s
+	ret.Token = ""
+	return &ret
+}
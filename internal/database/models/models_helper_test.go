@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsRedacted(t *testing.T) {
+	settings := &Settings{
+		MediaPlayer: &MediaPlayerSettings{Host: "localhost", VlcPassword: "vlc", KodiPassword: "kodi", VcTranslateApiKey: "key"},
+		Torrent:     &TorrentSettings{QBittorrentUsername: "admin", QBittorrentPassword: "qbit", TransmissionPassword: "transmission"},
+		Nakama:      &NakamaSettings{Username: "host", HostPassword: "host", RemoteServerPassword: "remote"},
+	}
+
+	redacted := settings.Redacted()
+	require.Equal(t, "localhost", redacted.MediaPlayer.Host)
+	require.Equal(t, "admin", redacted.Torrent.QBittorrentUsername)
+	require.Equal(t, "host", redacted.Nakama.Username)
+	for _, v := range redacted.GetSensitiveValues() {
+		if v == "host" {
+			continue
+		}
+		require.Empty(t, v)
+	}
+	require.Empty(t, redacted.MediaPlayer.VcTranslateApiKey)
+
+	// The settings are not modified
+	require.Equal(t, "kodi", settings.MediaPlayer.KodiPassword)
+	require.Equal(t, "qbit", settings.Torrent.QBittorrentPassword)
+	require.Equal(t, "remote", settings.Nakama.RemoteServerPassword)
+
+	require.Nil(t, (*Settings)(nil).Redacted())
+	require.Empty(t, (&DebridSettings{ApiKey: "key"}).Redacted().ApiKey)
+	usenet := (&UsenetSettings{Host: "http://localhost", ApiKey: "key", Password: "password"}).Redacted()
+	require.Equal(t, "http://localhost", usenet.Host)
+	require.Empty(t, usenet.ApiKey)
+	require.Empty(t, usenet.Password)
+	calendar := (&CalendarFeedSettings{Enabled: true, Token: "token"}).Redacted()
+	require.True(t, calendar.Enabled)
+	require.Empty(t, calendar.Token)
+}
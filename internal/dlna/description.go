@@ -0,0 +1,175 @@
+package dlna
+
+import (
+	"encoding/xml"
+	"fmt"
+	"seanime/internal/constants"
+)
+
+const (
+	deviceType                   = "urn:schemas-upnp-org:device:MediaServer:1"
+	contentDirectoryServiceType  = "urn:schemas-upnp-org:service:ContentDirectory:1"
+	connectionManagerServiceType = "urn:schemas-upnp-org:service:ConnectionManager:1"
+
+	descriptionPath              = "/dlna/description.xml"
+	contentDirectorySCPDPath     = "/dlna/ContentDirectory.xml"
+	connectionManagerSCPDPath    = "/dlna/ConnectionManager.xml"
+	contentDirectoryControlPath  = "/dlna/control/ContentDirectory"
+	connectionManagerControlPath = "/dlna/control/ConnectionManager"
+	contentDirectoryEventPath    = "/dlna/event/ContentDirectory"
+	connectionManagerEventPath   = "/dlna/event/ConnectionManager"
+	mediaPathPrefix              = "/dlna/media/"
+)
+
+func serverHeader() string {
+	return fmt.Sprintf("Seanime/%s UPnP/1.0 DLNADOC/1.50", constants.Version)
+}
+
+type (
+	deviceDescription struct {
+		XMLName     xml.Name    `xml:"urn:schemas-upnp-org:device-1-0 root"`
+		SpecVersion specVersion `xml:"specVersion"`
+		Device      device      `xml:"device"`
+	}
+
+	specVersion struct {
+		Major int `xml:"major"`
+		Minor int `xml:"minor"`
+	}
+
+	device struct {
+		DeviceType   string    `xml:"deviceType"`
+		FriendlyName string    `xml:"friendlyName"`
+		Manufacturer string    `xml:"manufacturer"`
+		ModelName    string    `xml:"modelName"`
+		ModelNumber  string    `xml:"modelNumber"`
+		UDN          string    `xml:"UDN"`
+		DLNADoc      string    `xml:"urn:schemas-dlna-org:device-1-0 X_DLNADOC"`
+		ServiceList  []service `xml:"serviceList>service"`
+	}
+
+	service struct {
+		ServiceType string `xml:"serviceType"`
+		ServiceId   string `xml:"serviceId"`
+		SCPDURL     string `xml:"SCPDURL"`
+		ControlURL  string `xml:"controlURL"`
+		EventSubURL string `xml:"eventSubURL"`
+	}
+)
+
+func newDeviceDescription(friendlyName string, udn string) ([]byte, error) {
+	desc := &deviceDescription{
+		SpecVersion: specVersion{Major: 1, Minor: 0},
+		Device: device{
+			DeviceType:   deviceType,
+			FriendlyName: friendlyName,
+			Manufacturer: "Seanime",
+			ModelName:    "Seanime",
+			ModelNumber:  constants.Version,
+			UDN:          udn,
+			DLNADoc:      "DMS-1.50",
+			ServiceList: []service{
+				{
+					ServiceType: contentDirectoryServiceType,
+					ServiceId:   "urn:upnp-org:serviceId:ContentDirectory",
+					SCPDURL:     contentDirectorySCPDPath,
+					ControlURL:  contentDirectoryControlPath,
+					EventSubURL: contentDirectoryEventPath,
+				},
+				{
+					ServiceType: connectionManagerServiceType,
+					ServiceId:   "urn:upnp-org:serviceId:ConnectionManager",
+					SCPDURL:     connectionManagerSCPDPath,
+					ControlURL:  connectionManagerControlPath,
+					EventSubURL: connectionManagerEventPath,
+				},
+			},
+		},
+	}
+
+	data, err := xml.MarshalIndent(desc, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), data...), nil
+}
+
+// contentDirectorySCPD describes the actions of the ContentDirectory service supported by the server.
+const contentDirectorySCPD = `<?xml version="1.0" encoding="UTF-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>Browse</name>
+      <argumentList>
+        <argument><name>ObjectID</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_ObjectID</relatedStateVariable></argument>
+        <argument><name>BrowseFlag</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_BrowseFlag</relatedStateVariable></argument>
+        <argument><name>Filter</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Filter</relatedStateVariable></argument>
+        <argument><name>StartingIndex</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Index</relatedStateVariable></argument>
+        <argument><name>RequestedCount</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>SortCriteria</name><direction>in</direction><relatedStateVariable>A_ARG_TYPE_SortCriteria</relatedStateVariable></argument>
+        <argument><name>Result</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Result</relatedStateVariable></argument>
+        <argument><name>NumberReturned</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>TotalMatches</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_Count</relatedStateVariable></argument>
+        <argument><name>UpdateID</name><direction>out</direction><relatedStateVariable>A_ARG_TYPE_UpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSystemUpdateID</name>
+      <argumentList>
+        <argument><name>Id</name><direction>out</direction><relatedStateVariable>SystemUpdateID</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSearchCapabilities</name>
+      <argumentList>
+        <argument><name>SearchCaps</name><direction>out</direction><relatedStateVariable>SearchCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetSortCapabilities</name>
+      <argumentList>
+        <argument><name>SortCaps</name><direction>out</direction><relatedStateVariable>SortCapabilities</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_ObjectID</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Result</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_BrowseFlag</name><dataType>string</dataType><allowedValueList><allowedValue>BrowseMetadata</allowedValue><allowedValue>BrowseDirectChildren</allowedValue></allowedValueList></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Filter</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_SortCriteria</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Index</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_Count</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>A_ARG_TYPE_UpdateID</name><dataType>ui4</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SearchCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="no"><name>SortCapabilities</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SystemUpdateID</name><dataType>ui4</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
+
+// connectionManagerSCPD describes the actions of the ConnectionManager service supported by the server.
+const connectionManagerSCPD = `<?xml version="1.0" encoding="UTF-8"?>
+<scpd xmlns="urn:schemas-upnp-org:service-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <actionList>
+    <action>
+      <name>GetProtocolInfo</name>
+      <argumentList>
+        <argument><name>Source</name><direction>out</direction><relatedStateVariable>SourceProtocolInfo</relatedStateVariable></argument>
+        <argument><name>Sink</name><direction>out</direction><relatedStateVariable>SinkProtocolInfo</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+    <action>
+      <name>GetCurrentConnectionIDs</name>
+      <argumentList>
+        <argument><name>ConnectionIDs</name><direction>out</direction><relatedStateVariable>CurrentConnectionIDs</relatedStateVariable></argument>
+      </argumentList>
+    </action>
+  </actionList>
+  <serviceStateTable>
+    <stateVariable sendEvents="yes"><name>SourceProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>SinkProtocolInfo</name><dataType>string</dataType></stateVariable>
+    <stateVariable sendEvents="yes"><name>CurrentConnectionIDs</name><dataType>string</dataType></stateVariable>
+  </serviceStateTable>
+</scpd>`
@@ -0,0 +1,465 @@
+package dlna
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	httputil "seanime/internal/util/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+const (
+	DefaultPort         = 43215
+	DefaultFriendlyName = "Seanime"
+)
+
+var (
+	// LibraryCacheTTL is the duration for which the published library is cached
+	LibraryCacheTTL = 30 * time.Second
+	// PlayedThreshold is the portion of a file that has to be served for it to be considered played
+	PlayedThreshold = 0.8
+	// playbackSessionTimeout is the duration after which a file can be reported as played again
+	playbackSessionTimeout = 30 * time.Minute
+)
+
+type (
+	// Server is a UPnP MediaServer publishing the anime library to DLNA renderers (TVs, consoles) on the local network.
+	// It runs its own HTTP server since renderers cannot authenticate, it only exposes the local files of the library.
+	Server struct {
+		logger     *zerolog.Logger
+		getContent GetContentFunc
+		onPlayed   OnPlayedFunc
+		udn        string
+
+		mu         sync.Mutex
+		settings   Settings
+		httpServer *http.Server
+		cancel     context.CancelFunc
+
+		libraryMu      sync.Mutex
+		library        *Library
+		libraryExpires time.Time
+		systemUpdateId atomic.Uint32
+
+		sessionsMu sync.Mutex
+		sessions   map[string]*playbackSession // key: file key
+	}
+
+	Settings struct {
+		Enabled      bool
+		FriendlyName string
+		Port         int
+	}
+
+	// GetContentFunc returns the library collection and the local files to publish.
+	GetContentFunc func(ctx context.Context) (*anime.LibraryCollection, []*anime.LocalFile, error)
+
+	// OnPlayedFunc is called when a renderer has played most of a file.
+	OnPlayedFunc func(lf *anime.LocalFile)
+
+	NewServerOptions struct {
+		Logger     *zerolog.Logger
+		GetContent GetContentFunc
+		OnPlayed   OnPlayedFunc
+	}
+
+	playbackSession struct {
+		served     int64
+		size       int64
+		played     bool
+		lastAccess time.Time
+	}
+)
+
+func NewServer(opts *NewServerOptions) *Server {
+	hostname, _ := os.Hostname()
+
+	return &Server{
+		logger:     opts.Logger,
+		getContent: opts.GetContent,
+		onPlayed:   opts.OnPlayed,
+		// The UDN has to be stable so that renderers remember the server
+		udn:      "uuid:" + uuid.NewSHA1(uuid.NameSpaceOID, []byte("seanime-dlna:"+hostname)).String(),
+		sessions: make(map[string]*playbackSession),
+	}
+}
+
+// SetSettings starts, restarts or stops the server.
+func (s *Server) SetSettings(settings Settings) error {
+	if settings.FriendlyName == "" {
+		settings.FriendlyName = DefaultFriendlyName
+	}
+	if settings.Port == 0 {
+		settings.Port = DefaultPort
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.httpServer != nil && s.settings == settings {
+		return nil
+	}
+
+	s.stop()
+	s.settings = settings
+
+	if !settings.Enabled {
+		return nil
+	}
+
+	return s.start()
+}
+
+// Stop stops the server.
+func (s *Server) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stop()
+}
+
+// IsRunning returns true if the server is running.
+func (s *Server) IsRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.httpServer != nil
+}
+
+func (s *Server) start() error {
+	listener, err := net.Listen("tcp4", fmt.Sprintf(":%d", s.settings.Port))
+	if err != nil {
+		s.logger.Error().Err(err).Msg("dlna: Failed to start server")
+		return fmt.Errorf("dlna: Failed to start server: %w", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s.httpServer = &http.Server{Handler: s.Handler()}
+	s.cancel = cancel
+
+	go func(server *http.Server) {
+		defer util.HandlePanicInModuleThen("dlna/start", func() {})
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error().Err(err).Msg("dlna: Server stopped")
+		}
+	}(s.httpServer)
+
+	if err := s.runSSDP(ctx, s.settings.Port); err != nil {
+		// The content directory can still be reached if the renderer knows the address
+		s.logger.Error().Err(err).Msg("dlna: Discovery is unavailable")
+	}
+
+	s.logger.Info().Int("port", s.settings.Port).Str("name", s.settings.FriendlyName).Msg("dlna: Media server started")
+
+	return nil
+}
+
+func (s *Server) stop() {
+	if s.httpServer == nil {
+		return
+	}
+
+	s.cancel()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = s.httpServer.Shutdown(ctx)
+
+	s.httpServer = nil
+	s.cancel = nil
+
+	s.logger.Info().Msg("dlna: Media server stopped")
+}
+
+// location returns the URL of the device description.
+func location(ip net.IP, port int) string {
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(ip.String(), fmt.Sprintf("%d", port)), descriptionPath)
+}
+
+func (s *Server) friendlyName() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.settings.FriendlyName == "" {
+		return DefaultFriendlyName
+	}
+	return s.settings.FriendlyName
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// RefreshLibrary makes the server rebuild the published library on the next request.
+func (s *Server) RefreshLibrary() {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+	s.libraryExpires = time.Time{}
+}
+
+func (s *Server) getLibrary() (*Library, error) {
+	s.libraryMu.Lock()
+	defer s.libraryMu.Unlock()
+
+	if s.library != nil && time.Now().Before(s.libraryExpires) {
+		return s.library, nil
+	}
+
+	collection, localFiles, err := s.getContent(context.Background())
+	if err != nil {
+		if s.library != nil {
+			return s.library, nil
+		}
+		return nil, err
+	}
+
+	s.library = NewLibrary(collection, localFiles)
+	s.libraryExpires = time.Now().Add(LibraryCacheTTL)
+	s.systemUpdateId.Add(1)
+
+	return s.library, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Handler returns the HTTP handler serving the device description, the services and the files.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(descriptionPath, s.handleDescription)
+	mux.HandleFunc(contentDirectorySCPDPath, serveXML(contentDirectorySCPD))
+	mux.HandleFunc(connectionManagerSCPDPath, serveXML(connectionManagerSCPD))
+	mux.HandleFunc(contentDirectoryControlPath, s.handleContentDirectory)
+	mux.HandleFunc(connectionManagerControlPath, s.handleConnectionManager)
+	mux.HandleFunc(contentDirectoryEventPath, handleEventSubscription)
+	mux.HandleFunc(connectionManagerEventPath, handleEventSubscription)
+	mux.HandleFunc(mediaPathPrefix, s.handleMedia)
+	return mux
+}
+
+func serveXML(content string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+		_, _ = w.Write([]byte(content))
+	}
+}
+
+// handleEventSubscription accepts subscriptions without sending events, renderers poll the content directory instead.
+func handleEventSubscription(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "SUBSCRIBE":
+		w.Header().Set("SID", "uuid:"+uuid.NewString())
+		w.Header().Set("TIMEOUT", "Second-1800")
+	case "UNSUBSCRIBE":
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleDescription(w http.ResponseWriter, r *http.Request) {
+	data, err := newDeviceDescription(s.friendlyName(), s.udn)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Server", serverHeader())
+	_, _ = w.Write(data)
+}
+
+func (s *Server) handleContentDirectory(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	action, err := readSoapAction(r)
+	if err != nil {
+		writeSoapError(w, errInvalidAction)
+		return
+	}
+
+	s.logger.Trace().Str("action", action.XMLName.Local).Str("objectId", action.arg("ObjectID")).Msg("dlna: Content directory request")
+
+	var args []soapResponseArg
+	switch action.XMLName.Local {
+	case "Browse":
+		var upnpErr *upnpError
+		args, upnpErr = s.browse(action, "http://"+r.Host)
+		if upnpErr != nil {
+			writeSoapError(w, upnpErr)
+			return
+		}
+	case "GetSystemUpdateID":
+		args = []soapResponseArg{{Name: "Id", Value: fmt.Sprintf("%d", s.systemUpdateId.Load())}}
+	case "GetSearchCapabilities":
+		args = []soapResponseArg{{Name: "SearchCaps", Value: ""}}
+	case "GetSortCapabilities":
+		args = []soapResponseArg{{Name: "SortCaps", Value: ""}}
+	default:
+		writeSoapError(w, errInvalidAction)
+		return
+	}
+
+	writeSoapResponse(w, contentDirectoryServiceType, action.XMLName.Local, args)
+}
+
+func (s *Server) handleConnectionManager(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	action, err := readSoapAction(r)
+	if err != nil {
+		writeSoapError(w, errInvalidAction)
+		return
+	}
+
+	var args []soapResponseArg
+	switch action.XMLName.Local {
+	case "GetProtocolInfo":
+		source := make([]string, 0)
+		for _, ext := range []string{".mkv", ".mp4", ".avi", ".webm", ".ts"} {
+			source = append(source, protocolInfo(ext))
+		}
+		args = []soapResponseArg{{Name: "Source", Value: strings.Join(source, ",")}, {Name: "Sink", Value: ""}}
+	case "GetCurrentConnectionIDs":
+		args = []soapResponseArg{{Name: "ConnectionIDs", Value: "0"}}
+	default:
+		writeSoapError(w, errInvalidAction)
+		return
+	}
+
+	writeSoapResponse(w, connectionManagerServiceType, action.XMLName.Local, args)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func fileSize(path string) (int64, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	return info.Size(), true
+}
+
+// handleMedia serves a local file with support for range requests.
+func (s *Server) handleMedia(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, mediaPathPrefix)
+	key := strings.TrimSuffix(name, filepath.Ext(name))
+
+	library, err := s.getLibrary()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f, ok := library.GetFile(key)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := f.localFile.GetPath()
+	file, err := os.Open(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	size := info.Size()
+
+	w.Header().Set("Content-Type", mimeType(path))
+	w.Header().Set("Accept-Ranges", "bytes")
+	w.Header().Set("Server", serverHeader())
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", contentFeatures)
+
+	ranges, err := httputil.ParseRange(r.Header.Get("Range"), size)
+	if err != nil {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "Range Not Satisfiable", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	ra := httputil.Range{Start: 0, Length: size}
+	status := http.StatusOK
+	if len(ranges) > 0 {
+		// Renderers only request a single range
+		ra = ranges[0]
+		status = http.StatusPartialContent
+		w.Header().Set("Content-Range", ra.ContentRange(size))
+	}
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", ra.Length))
+	w.WriteHeader(status)
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := file.Seek(ra.Start, io.SeekStart); err != nil {
+		return
+	}
+
+	_, _ = io.CopyN(&playbackWriter{w: w, server: s, file: f, size: size}, file, ra.Length)
+}
+
+// playbackWriter counts the bytes served to the renderer.
+type playbackWriter struct {
+	w      io.Writer
+	server *Server
+	file   *libraryFile
+	size   int64
+}
+
+func (pw *playbackWriter) Write(p []byte) (int, error) {
+	n, err := pw.w.Write(p)
+	if n > 0 {
+		pw.server.recordServed(pw.file, pw.size, int64(n))
+	}
+	return n, err
+}
+
+// recordServed keeps track of the bytes served for a file and reports it as played once most of it has been served.
+// Seeking backwards makes renderers request the same bytes again, which is an acceptable approximation.
+func (s *Server) recordServed(f *libraryFile, size int64, n int64) {
+	s.sessionsMu.Lock()
+
+	session, ok := s.sessions[f.key]
+	if !ok || time.Since(session.lastAccess) > playbackSessionTimeout {
+		session = &playbackSession{size: size}
+		s.sessions[f.key] = session
+	}
+	session.served += n
+	session.lastAccess = time.Now()
+
+	reached := !session.played && session.size > 0 && float64(session.served)/float64(session.size) >= PlayedThreshold
+	if reached {
+		session.played = true
+	}
+
+	s.sessionsMu.Unlock()
+
+	if reached && s.onPlayed != nil {
+		s.logger.Debug().Str("file", f.localFile.Name).Msg("dlna: File played by renderer")
+		go s.onPlayed(f.localFile)
+	}
+}
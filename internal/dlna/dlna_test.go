@@ -0,0 +1,315 @@
+package dlna
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestContent(t *testing.T) (*anime.LibraryCollection, []*anime.LocalFile) {
+	dir := t.TempDir()
+
+	newFile := func(name string, mediaId int, episode int, fileType anime.LocalFileType, size int) *anime.LocalFile {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(strings.Repeat("x", size)), 0644))
+		return &anime.LocalFile{
+			Path:     path,
+			Name:     name,
+			MediaId:  mediaId,
+			Metadata: &anime.LocalFileMetadata{Episode: episode, AniDBEpisode: fmt.Sprint(episode), Type: fileType},
+		}
+	}
+
+	localFiles := []*anime.LocalFile{
+		newFile("Frieren - 02.mkv", 154587, 2, anime.LocalFileTypeMain, 1000),
+		newFile("Frieren - 01.mkv", 154587, 1, anime.LocalFileTypeMain, 1000),
+		newFile("Frieren - NCOP.mkv", 154587, 1, anime.LocalFileTypeNC, 100),
+		newFile("Mushishi - 01.mp4", 457, 1, anime.LocalFileTypeMain, 100),
+		newFile("Unmatched - 01.mkv", 0, 1, anime.LocalFileTypeMain, 100),
+	}
+
+	newMedia := func(id int, title string) *anilist.BaseAnime {
+		return &anilist.BaseAnime{
+			ID:         id,
+			Title:      &anilist.BaseAnime_Title{UserPreferred: lo.ToPtr(title)},
+			CoverImage: &anilist.BaseAnime_CoverImage{Large: lo.ToPtr(fmt.Sprintf("https://img.anili.st/%d.jpg", id))},
+		}
+	}
+
+	collection := &anime.LibraryCollection{
+		Lists: []*anime.LibraryCollectionList{
+			{
+				Type:   anilist.MediaListStatusCompleted,
+				Status: anilist.MediaListStatusCompleted,
+				Entries: []*anime.LibraryCollectionEntry{
+					{MediaId: 457, Media: newMedia(457, "Mushishi")},
+				},
+			},
+			{
+				Type:   anilist.MediaListStatusCurrent,
+				Status: anilist.MediaListStatusCurrent,
+				Entries: []*anime.LibraryCollectionEntry{
+					{MediaId: 154587, Media: newMedia(154587, "Sousou no Frieren")},
+					// No local files
+					{MediaId: 21, Media: newMedia(21, "One Piece")},
+				},
+			},
+		},
+	}
+
+	return collection, localFiles
+}
+
+func newTestServer(t *testing.T, onPlayed OnPlayedFunc) *Server {
+	collection, localFiles := newTestContent(t)
+	return NewServer(&NewServerOptions{
+		Logger: util.NewLogger(),
+		GetContent: func(ctx context.Context) (*anime.LibraryCollection, []*anime.LocalFile, error) {
+			return collection, localFiles, nil
+		},
+		OnPlayed: onPlayed,
+	})
+}
+
+type didlLite struct {
+	Containers []struct {
+		ID         string `xml:"id,attr"`
+		ParentID   string `xml:"parentID,attr"`
+		ChildCount int    `xml:"childCount,attr"`
+		Title      string `xml:"title"`
+	} `xml:"container"`
+	Items []struct {
+		ID    string `xml:"id,attr"`
+		Title string `xml:"title"`
+		Res   struct {
+			URL          string `xml:",chardata"`
+			Size         int64  `xml:"size,attr"`
+			ProtocolInfo string `xml:"protocolInfo,attr"`
+		} `xml:"res"`
+	} `xml:"item"`
+}
+
+type browseResponse struct {
+	Body struct {
+		Response struct {
+			Result         string `xml:"Result"`
+			NumberReturned int    `xml:"NumberReturned"`
+			TotalMatches   int    `xml:"TotalMatches"`
+		} `xml:"BrowseResponse"`
+		Fault *struct {
+			ErrorCode int `xml:"detail>UPnPError>errorCode"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+func browse(t *testing.T, baseUrl string, objectId string, flag string, start int, count int) (*browseResponse, *didlLite) {
+	body := fmt.Sprintf(`<?xml version="1.0"?>
+<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/">
+<s:Body><u:Browse xmlns:u="urn:schemas-upnp-org:service:ContentDirectory:1">
+<ObjectID>%s</ObjectID><BrowseFlag>%s</BrowseFlag><Filter>*</Filter>
+<StartingIndex>%d</StartingIndex><RequestedCount>%d</RequestedCount><SortCriteria></SortCriteria>
+</u:Browse></s:Body></s:Envelope>`, objectId, flag, start, count)
+
+	req, err := http.NewRequest(http.MethodPost, baseUrl+contentDirectoryControlPath, strings.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", `"urn:schemas-upnp-org:service:ContentDirectory:1#Browse"`)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var res browseResponse
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&res))
+	if res.Body.Fault != nil {
+		return &res, nil
+	}
+
+	var didl didlLite
+	require.NoError(t, xml.Unmarshal([]byte(res.Body.Response.Result), &didl))
+	return &res, &didl
+}
+
+func TestBrowse(t *testing.T) {
+	server := newTestServer(t, nil)
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	// Root: one container per list, in display order
+	res, didl := browse(t, ts.URL, "0", "BrowseDirectChildren", 0, 0)
+	require.Equal(t, 2, res.Body.Response.TotalMatches)
+	require.Len(t, didl.Containers, 2)
+	require.Equal(t, "list:CURRENT", didl.Containers[0].ID)
+	require.Equal(t, "Currently Watching", didl.Containers[0].Title)
+	// Media without local files are not published
+	require.Equal(t, 1, didl.Containers[0].ChildCount)
+	require.Equal(t, "list:COMPLETED", didl.Containers[1].ID)
+
+	_, didl = browse(t, ts.URL, "list:CURRENT", "BrowseDirectChildren", 0, 0)
+	require.Len(t, didl.Containers, 1)
+	require.Equal(t, "media:154587", didl.Containers[0].ID)
+	require.Equal(t, "list:CURRENT", didl.Containers[0].ParentID)
+	require.Equal(t, "Sousou no Frieren", didl.Containers[0].Title)
+
+	// Main episodes first, in order
+	res, didl = browse(t, ts.URL, "media:154587", "BrowseDirectChildren", 0, 0)
+	require.Equal(t, 3, res.Body.Response.TotalMatches)
+	require.Len(t, didl.Items, 3)
+	require.Equal(t, "Episode 1", didl.Items[0].Title)
+	require.Equal(t, "Episode 2", didl.Items[1].Title)
+	require.Equal(t, "Frieren - NCOP", didl.Items[2].Title)
+	require.Equal(t, int64(1000), didl.Items[0].Res.Size)
+	require.Contains(t, didl.Items[0].Res.ProtocolInfo, "video/x-matroska")
+	require.True(t, strings.HasPrefix(didl.Items[0].Res.URL, ts.URL+mediaPathPrefix))
+	require.True(t, strings.HasSuffix(didl.Items[0].Res.URL, ".mkv"))
+
+	// Paging
+	res, didl = browse(t, ts.URL, "media:154587", "BrowseDirectChildren", 1, 1)
+	require.Equal(t, 1, res.Body.Response.NumberReturned)
+	require.Equal(t, 3, res.Body.Response.TotalMatches)
+	require.Equal(t, "Episode 2", didl.Items[0].Title)
+
+	// Metadata
+	_, didl = browse(t, ts.URL, didl.Items[0].ID, "BrowseMetadata", 0, 0)
+	require.Len(t, didl.Items, 1)
+	require.Equal(t, "Episode 2", didl.Items[0].Title)
+
+	res, _ = browse(t, ts.URL, "media:21", "BrowseDirectChildren", 0, 0)
+	require.NotNil(t, res.Body.Fault)
+	require.Equal(t, 701, res.Body.Fault.ErrorCode)
+}
+
+func TestServeMediaAndReportPlayed(t *testing.T) {
+	played := make(chan *anime.LocalFile, 1)
+	server := newTestServer(t, func(lf *anime.LocalFile) {
+		played <- lf
+	})
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	_, didl := browse(t, ts.URL, "media:154587", "BrowseDirectChildren", 0, 0)
+	url := didl.Items[0].Res.URL
+
+	get := func(rangeHeader string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		require.NoError(t, err)
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_, _ = io.Copy(io.Discard, resp.Body)
+		_ = resp.Body.Close()
+		return resp
+	}
+
+	// Renderers probe the end of the file before playing
+	resp := get("bytes=900-")
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "bytes 900-999/1000", resp.Header.Get("Content-Range"))
+	require.Equal(t, "Streaming", resp.Header.Get("transferMode.dlna.org"))
+
+	resp = get("bytes=0-499")
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "500", resp.Header.Get("Content-Length"))
+
+	select {
+	case <-played:
+		t.Fatal("file reported as played too early")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	resp = get("bytes=500-")
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+
+	select {
+	case lf := <-played:
+		require.Equal(t, "Frieren - 01.mkv", lf.Name)
+	case <-time.After(2 * time.Second):
+		t.Fatal("file not reported as played")
+	}
+
+	// Reported once per session
+	resp = get("")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	select {
+	case <-played:
+		t.Fatal("file reported as played twice")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	resp = get("bytes=5000-")
+	require.Equal(t, http.StatusRequestedRangeNotSatisfiable, resp.StatusCode)
+
+	resp, err := http.Get(ts.URL + mediaPathPrefix + "unknown.mkv")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestDeviceDescription(t *testing.T) {
+	server := newTestServer(t, nil)
+	require.NoError(t, server.SetSettings(Settings{Enabled: false, FriendlyName: "Living Room Seanime"}))
+
+	ts := httptest.NewServer(server.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + descriptionPath)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var desc deviceDescription
+	require.NoError(t, xml.NewDecoder(resp.Body).Decode(&desc))
+	require.Equal(t, deviceType, desc.Device.DeviceType)
+	require.Equal(t, "Living Room Seanime", desc.Device.FriendlyName)
+	require.Equal(t, server.udn, desc.Device.UDN)
+	require.Len(t, desc.Device.ServiceList, 2)
+	require.Equal(t, contentDirectoryControlPath, desc.Device.ServiceList[0].ControlURL)
+
+	// The UDN is stable
+	require.Equal(t, server.udn, newTestServer(t, nil).udn)
+}
+
+func TestSSDPSearchResponses(t *testing.T) {
+	udn := "uuid:2f402f80-da50-11e1-9b23-001788255acc"
+	location := "http://192.168.1.20:43215/dlna/description.xml"
+
+	search := func(st string) []byte {
+		return []byte("M-SEARCH * HTTP/1.1\r\n" +
+			"HOST: 239.255.255.250:1900\r\n" +
+			"MAN: \"ssdp:discover\"\r\n" +
+			"MX: 2\r\n" +
+			"ST: " + st + "\r\n\r\n")
+	}
+
+	responses := searchResponses(search("urn:schemas-upnp-org:device:MediaServer:1"), udn, location)
+	require.Len(t, responses, 1)
+	res := string(responses[0])
+	require.True(t, strings.HasPrefix(res, "HTTP/1.1 200 OK\r\n"))
+	require.Contains(t, res, "LOCATION: "+location+"\r\n")
+	require.Contains(t, res, "USN: "+udn+"::urn:schemas-upnp-org:device:MediaServer:1\r\n")
+
+	require.Len(t, searchResponses(search("ssdp:all"), udn, location), 5)
+	require.Len(t, searchResponses(search(udn), udn, location), 1)
+	require.Empty(t, searchResponses(search("urn:schemas-upnp-org:device:MediaRenderer:1"), udn, location))
+
+	// Announcements of other devices are ignored
+	notify := notifyMessages("uuid:other", location, true)
+	require.Len(t, notify, 5)
+	require.Empty(t, searchResponses(notify[0], udn, location))
+	require.Contains(t, string(notifyMessages(udn, location, false)[0]), "NTS: ssdp:byebye")
+}
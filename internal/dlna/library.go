@@ -0,0 +1,314 @@
+package dlna
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	rootId         = "0"
+	listIdPrefix   = "list:"
+	mediaIdPrefix  = "media:"
+	fileIdPrefix   = "file:"
+	classFolder    = "object.container.storageFolder"
+	classVideoItem = "object.item.videoItem"
+)
+
+type (
+	// Library is the content published by the server.
+	// It is built from the library collection and is only refreshed when the cache expires.
+	Library struct {
+		lists []*libraryList
+		media map[int]*libraryMedia
+		files map[string]*libraryFile // key: file key
+	}
+
+	libraryList struct {
+		status anilist.MediaListStatus
+		media  []*libraryMedia
+	}
+
+	libraryMedia struct {
+		id     int
+		listId string
+		title  string
+		cover  string
+		files  []*libraryFile
+	}
+
+	libraryFile struct {
+		key       string
+		mediaId   int
+		title     string
+		localFile *anime.LocalFile
+	}
+
+	// object is a container or an item of the content directory
+	object struct {
+		ID         string
+		ParentID   string
+		Title      string
+		Class      string
+		ChildCount int
+		AlbumArt   string
+		File       *libraryFile
+	}
+)
+
+// listTitles are the titles of the list containers, in display order
+var listTitles = []struct {
+	status anilist.MediaListStatus
+	title  string
+}{
+	{anilist.MediaListStatusCurrent, "Currently Watching"},
+	{anilist.MediaListStatusRepeating, "Rewatching"},
+	{anilist.MediaListStatusPlanning, "Planning"},
+	{anilist.MediaListStatusPaused, "Paused"},
+	{anilist.MediaListStatusCompleted, "Completed"},
+	{anilist.MediaListStatusDropped, "Dropped"},
+}
+
+// fileKey returns a stable identifier for a file that does not leak its path.
+func fileKey(path string) string {
+	return util.HashSHA256Hex(util.NormalizePath(path))[:20]
+}
+
+// NewLibrary organizes the local files by list status and media.
+// Only media with local files are published.
+func NewLibrary(collection *anime.LibraryCollection, localFiles []*anime.LocalFile) *Library {
+	ret := &Library{
+		media: make(map[int]*libraryMedia),
+		files: make(map[string]*libraryFile),
+	}
+
+	filesByMedia := make(map[int][]*anime.LocalFile)
+	for _, lf := range localFiles {
+		if lf == nil || lf.MediaId == 0 || lf.IsIgnored() {
+			continue
+		}
+		filesByMedia[lf.MediaId] = append(filesByMedia[lf.MediaId], lf)
+	}
+
+	if collection == nil {
+		return ret
+	}
+
+	listsByStatus := make(map[anilist.MediaListStatus]*libraryList)
+	for _, list := range collection.Lists {
+		if list == nil {
+			continue
+		}
+		for _, entry := range list.Entries {
+			if entry == nil || entry.Media == nil {
+				continue
+			}
+			lfs, ok := filesByMedia[entry.MediaId]
+			if !ok {
+				continue
+			}
+			if _, added := ret.media[entry.MediaId]; added {
+				continue
+			}
+
+			l, ok := listsByStatus[list.Status]
+			if !ok {
+				l = &libraryList{status: list.Status}
+				listsByStatus[list.Status] = l
+			}
+
+			media := &libraryMedia{
+				id:     entry.MediaId,
+				listId: listIdPrefix + string(list.Status),
+				title:  entry.Media.GetPreferredTitle(),
+				cover:  entry.Media.GetCoverImageSafe(),
+			}
+			media.files = newLibraryFiles(media, lfs)
+			for _, f := range media.files {
+				ret.files[f.key] = f
+			}
+
+			l.media = append(l.media, media)
+			ret.media[media.id] = media
+		}
+	}
+
+	for _, lt := range listTitles {
+		l, ok := listsByStatus[lt.status]
+		if !ok {
+			continue
+		}
+		slices.SortFunc(l.media, func(a, b *libraryMedia) int {
+			return cmp.Compare(strings.ToLower(a.title), strings.ToLower(b.title))
+		})
+		ret.lists = append(ret.lists, l)
+	}
+
+	return ret
+}
+
+// newLibraryFiles returns the files of the media, main episodes first.
+func newLibraryFiles(media *libraryMedia, lfs []*anime.LocalFile) []*libraryFile {
+	sorted := slices.Clone(lfs)
+	slices.SortFunc(sorted, func(a, b *anime.LocalFile) int {
+		if a.IsMain() != b.IsMain() {
+			if a.IsMain() {
+				return -1
+			}
+			return 1
+		}
+		if c := cmp.Compare(a.GetEpisodeNumber(), b.GetEpisodeNumber()); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Name, b.Name)
+	})
+
+	ret := make([]*libraryFile, 0, len(sorted))
+	for _, lf := range sorted {
+		ret = append(ret, &libraryFile{
+			key:       fileKey(lf.GetPath()),
+			mediaId:   media.id,
+			title:     fileTitle(lf),
+			localFile: lf,
+		})
+	}
+	return ret
+}
+
+func fileTitle(lf *anime.LocalFile) string {
+	metadata := lf.GetMetadata()
+	if metadata == nil || metadata.Episode <= 0 {
+		return strings.TrimSuffix(lf.Name, filepath.Ext(lf.Name))
+	}
+
+	switch metadata.Type {
+	case anime.LocalFileTypeSpecial:
+		return fmt.Sprintf("Special %d", metadata.Episode)
+	case anime.LocalFileTypeNC:
+		return strings.TrimSuffix(lf.Name, filepath.Ext(lf.Name))
+	}
+
+	ret := fmt.Sprintf("Episode %d", metadata.Episode)
+	if title := lf.GetParsedEpisodeTitle(); title != "" {
+		ret += " - " + title
+	}
+	return ret
+}
+
+// GetFile returns the file matching the key.
+func (l *Library) GetFile(key string) (*libraryFile, bool) {
+	f, ok := l.files[key]
+	return f, ok
+}
+
+// getObject returns the object matching the id.
+func (l *Library) getObject(id string) (*object, bool) {
+	switch {
+	case id == rootId:
+		return &object{ID: rootId, ParentID: "-1", Title: "Seanime", Class: classFolder, ChildCount: len(l.lists)}, true
+	case strings.HasPrefix(id, listIdPrefix):
+		for _, list := range l.lists {
+			if listIdPrefix+string(list.status) == id {
+				return l.listObject(list), true
+			}
+		}
+	case strings.HasPrefix(id, mediaIdPrefix):
+		mId, err := strconv.Atoi(strings.TrimPrefix(id, mediaIdPrefix))
+		if err != nil {
+			return nil, false
+		}
+		if media, ok := l.media[mId]; ok {
+			return mediaObject(media), true
+		}
+	case strings.HasPrefix(id, fileIdPrefix):
+		if f, ok := l.files[strings.TrimPrefix(id, fileIdPrefix)]; ok {
+			return l.fileObject(f), true
+		}
+	}
+	return nil, false
+}
+
+// getChildren returns the children of the container matching the id.
+func (l *Library) getChildren(id string) ([]*object, bool) {
+	switch {
+	case id == rootId:
+		ret := make([]*object, 0, len(l.lists))
+		for _, list := range l.lists {
+			ret = append(ret, l.listObject(list))
+		}
+		return ret, true
+	case strings.HasPrefix(id, listIdPrefix):
+		for _, list := range l.lists {
+			if listIdPrefix+string(list.status) != id {
+				continue
+			}
+			ret := make([]*object, 0, len(list.media))
+			for _, media := range list.media {
+				ret = append(ret, mediaObject(media))
+			}
+			return ret, true
+		}
+	case strings.HasPrefix(id, mediaIdPrefix):
+		mId, err := strconv.Atoi(strings.TrimPrefix(id, mediaIdPrefix))
+		if err != nil {
+			return nil, false
+		}
+		media, ok := l.media[mId]
+		if !ok {
+			return nil, false
+		}
+		ret := make([]*object, 0, len(media.files))
+		for _, f := range media.files {
+			ret = append(ret, l.fileObject(f))
+		}
+		return ret, true
+	}
+	return nil, false
+}
+
+func (l *Library) listObject(list *libraryList) *object {
+	title := string(list.status)
+	for _, lt := range listTitles {
+		if lt.status == list.status {
+			title = lt.title
+		}
+	}
+	return &object{
+		ID:         listIdPrefix + string(list.status),
+		ParentID:   rootId,
+		Title:      title,
+		Class:      classFolder,
+		ChildCount: len(list.media),
+	}
+}
+
+func mediaObject(media *libraryMedia) *object {
+	return &object{
+		ID:         mediaIdPrefix + strconv.Itoa(media.id),
+		ParentID:   media.listId,
+		Title:      media.title,
+		Class:      classFolder,
+		ChildCount: len(media.files),
+		AlbumArt:   media.cover,
+	}
+}
+
+func (l *Library) fileObject(f *libraryFile) *object {
+	ret := &object{
+		ID:       fileIdPrefix + f.key,
+		ParentID: mediaIdPrefix + strconv.Itoa(f.mediaId),
+		Title:    f.title,
+		Class:    classVideoItem,
+		File:     f,
+	}
+	if media, ok := l.media[f.mediaId]; ok {
+		ret.AlbumArt = media.cover
+	}
+	return ret
+}
@@ -0,0 +1,237 @@
+package dlna
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Errors returned to control points, see the UPnP ContentDirectory specification
+var (
+	errInvalidAction = &upnpError{Code: 401, Description: "Invalid Action"}
+	errInvalidArgs   = &upnpError{Code: 402, Description: "Invalid Args"}
+	errNoSuchObject  = &upnpError{Code: 701, Description: "No such object"}
+)
+
+type upnpError struct {
+	Code        int
+	Description string
+}
+
+func (e *upnpError) Error() string {
+	return fmt.Sprintf("dlna: %s (%d)", e.Description, e.Code)
+}
+
+type (
+	soapEnvelope struct {
+		XMLName xml.Name `xml:"Envelope"`
+		Body    struct {
+			Action soapAction `xml:",any"`
+		} `xml:"Body"`
+	}
+
+	soapAction struct {
+		XMLName xml.Name
+		Args    []soapArg `xml:",any"`
+	}
+
+	soapArg struct {
+		XMLName xml.Name
+		Value   string `xml:",chardata"`
+	}
+
+	// soapResponseArg is an output argument of an action, in order
+	soapResponseArg struct {
+		Name  string
+		Value string
+	}
+)
+
+func (a *soapAction) arg(name string) string {
+	for _, arg := range a.Args {
+		if arg.XMLName.Local == name {
+			return arg.Value
+		}
+	}
+	return ""
+}
+
+func readSoapAction(r *http.Request) (*soapAction, error) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+
+	var env soapEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.Body.Action.XMLName.Local == "" {
+		return nil, errors.New("dlna: Missing action")
+	}
+
+	return &env.Body.Action, nil
+}
+
+func writeSoapResponse(w http.ResponseWriter, serviceType string, action string, args []soapResponseArg) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	fmt.Fprintf(&b, `<u:%sResponse xmlns:u="%s">`, action, serviceType)
+	for _, arg := range args {
+		fmt.Fprintf(&b, "<%s>", arg.Name)
+		_ = xml.EscapeText(&b, []byte(arg.Value))
+		fmt.Fprintf(&b, "</%s>", arg.Name)
+	}
+	fmt.Fprintf(&b, `</u:%sResponse>`, action)
+	b.WriteString(`</s:Body></s:Envelope>`)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.Header().Set("Ext", "")
+	w.Header().Set("Server", serverHeader())
+	_, _ = w.Write(b.Bytes())
+}
+
+func writeSoapError(w http.ResponseWriter, err *upnpError) {
+	var b bytes.Buffer
+	b.WriteString(xml.Header)
+	b.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body><s:Fault>`)
+	b.WriteString(`<faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0">`)
+	fmt.Fprintf(&b, "<errorCode>%d</errorCode><errorDescription>%s</errorDescription>", err.Code, err.Description)
+	b.WriteString(`</UPnPError></detail></s:Fault></s:Body></s:Envelope>`)
+
+	w.Header().Set("Content-Type", `text/xml; charset="utf-8"`)
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = w.Write(b.Bytes())
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// browse implements the Browse action of the ContentDirectory service.
+func (s *Server) browse(action *soapAction, baseUrl string) ([]soapResponseArg, *upnpError) {
+	library, err := s.getLibrary()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("dlna: Failed to get library")
+		return nil, errNoSuchObject
+	}
+
+	objectId := action.arg("ObjectID")
+	startingIndex, _ := strconv.Atoi(action.arg("StartingIndex"))
+	requestedCount, _ := strconv.Atoi(action.arg("RequestedCount"))
+	if startingIndex < 0 || requestedCount < 0 {
+		return nil, errInvalidArgs
+	}
+
+	var objects []*object
+	var total int
+
+	switch action.arg("BrowseFlag") {
+	case "BrowseMetadata":
+		obj, ok := library.getObject(objectId)
+		if !ok {
+			return nil, errNoSuchObject
+		}
+		objects = []*object{obj}
+		total = 1
+	case "BrowseDirectChildren":
+		children, ok := library.getChildren(objectId)
+		if !ok {
+			return nil, errNoSuchObject
+		}
+		total = len(children)
+		if startingIndex > len(children) {
+			startingIndex = len(children)
+		}
+		children = children[startingIndex:]
+		if requestedCount > 0 && requestedCount < len(children) {
+			children = children[:requestedCount]
+		}
+		objects = children
+	default:
+		return nil, errInvalidArgs
+	}
+
+	return []soapResponseArg{
+		{Name: "Result", Value: s.didlLite(objects, baseUrl)},
+		{Name: "NumberReturned", Value: strconv.Itoa(len(objects))},
+		{Name: "TotalMatches", Value: strconv.Itoa(total)},
+		{Name: "UpdateID", Value: strconv.FormatUint(uint64(s.systemUpdateId.Load()), 10)},
+	}, nil
+}
+
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// didlLite returns the DIDL-Lite document describing the objects.
+func (s *Server) didlLite(objects []*object, baseUrl string) string {
+	var b strings.Builder
+	b.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/" xmlns:dlna="urn:schemas-dlna-org:metadata-1-0/">`)
+
+	for _, obj := range objects {
+		if obj.File == nil {
+			fmt.Fprintf(&b, `<container id="%s" parentID="%s" childCount="%d" restricted="1" searchable="0">`, escapeXML(obj.ID), escapeXML(obj.ParentID), obj.ChildCount)
+			fmt.Fprintf(&b, `<dc:title>%s</dc:title><upnp:class>%s</upnp:class>`, escapeXML(obj.Title), obj.Class)
+			if obj.AlbumArt != "" {
+				fmt.Fprintf(&b, `<upnp:albumArtURI>%s</upnp:albumArtURI>`, escapeXML(obj.AlbumArt))
+			}
+			b.WriteString(`</container>`)
+			continue
+		}
+
+		path := obj.File.localFile.GetPath()
+		fmt.Fprintf(&b, `<item id="%s" parentID="%s" restricted="1">`, escapeXML(obj.ID), escapeXML(obj.ParentID))
+		fmt.Fprintf(&b, `<dc:title>%s</dc:title><upnp:class>%s</upnp:class>`, escapeXML(obj.Title), obj.Class)
+		if obj.AlbumArt != "" {
+			fmt.Fprintf(&b, `<upnp:albumArtURI>%s</upnp:albumArtURI>`, escapeXML(obj.AlbumArt))
+		}
+		resUrl := baseUrl + mediaPathPrefix + obj.File.key + strings.ToLower(filepath.Ext(path))
+		fmt.Fprintf(&b, `<res protocolInfo="%s"`, escapeXML(protocolInfo(path)))
+		if size, ok := fileSize(path); ok {
+			fmt.Fprintf(&b, ` size="%d"`, size)
+		}
+		fmt.Fprintf(&b, `>%s</res></item>`, escapeXML(resUrl))
+	}
+
+	b.WriteString(`</DIDL-Lite>`)
+	return b.String()
+}
+
+// contentFeatures is the DLNA.ORG_OP flag for range seeking and the flags for streaming transfer mode.
+const contentFeatures = "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000"
+
+func protocolInfo(path string) string {
+	return fmt.Sprintf("http-get:*:%s:%s", mimeType(path), contentFeatures)
+}
+
+func mimeType(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".mkv":
+		return "video/x-matroska"
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".avi":
+		return "video/x-msvideo"
+	case ".webm":
+		return "video/webm"
+	case ".ts", ".m2ts":
+		return "video/mp2t"
+	case ".mov":
+		return "video/quicktime"
+	case ".wmv":
+		return "video/x-ms-wmv"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,222 @@
+package dlna
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	ssdpAddr   = "239.255.255.250:1900"
+	ssdpMaxAge = 1800
+)
+
+// ssdpNotifyInterval is the interval at which the server announces itself
+var ssdpNotifyInterval = 5 * time.Minute
+
+// ssdpTargets returns the search targets the server responds to, with their USN.
+func ssdpTargets(udn string) [][2]string {
+	return [][2]string{
+		{"upnp:rootdevice", udn + "::upnp:rootdevice"},
+		{udn, udn},
+		{deviceType, udn + "::" + deviceType},
+		{contentDirectoryServiceType, udn + "::" + contentDirectoryServiceType},
+		{connectionManagerServiceType, udn + "::" + connectionManagerServiceType},
+	}
+}
+
+// parseSearchRequest returns the search target of an M-SEARCH request.
+func parseSearchRequest(data []byte) (string, bool) {
+	req, err := http.ReadRequest(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		return "", false
+	}
+	if req.Method != "M-SEARCH" || strings.Trim(req.Header.Get("Man"), `"`) != "ssdp:discover" {
+		return "", false
+	}
+	st := req.Header.Get("St")
+	return st, st != ""
+}
+
+// searchResponses returns the responses to an M-SEARCH request.
+// Nothing is returned if the request does not target the server.
+func searchResponses(data []byte, udn string, location string) [][]byte {
+	st, ok := parseSearchRequest(data)
+	if !ok {
+		return nil
+	}
+
+	var ret [][]byte
+	for _, target := range ssdpTargets(udn) {
+		if st != "ssdp:all" && st != target[0] {
+			continue
+		}
+		ret = append(ret, []byte(fmt.Sprintf("HTTP/1.1 200 OK\r\n"+
+			"CACHE-CONTROL: max-age=%d\r\n"+
+			"DATE: %s\r\n"+
+			"EXT:\r\n"+
+			"LOCATION: %s\r\n"+
+			"SERVER: %s\r\n"+
+			"ST: %s\r\n"+
+			"USN: %s\r\n"+
+			"\r\n", ssdpMaxAge, time.Now().UTC().Format(http.TimeFormat), location, serverHeader(), target[0], target[1])))
+	}
+	return ret
+}
+
+// notifyMessages returns the NOTIFY messages announcing the server, or its departure if alive is false.
+func notifyMessages(udn string, location string, alive bool) [][]byte {
+	var ret [][]byte
+	for _, target := range ssdpTargets(udn) {
+		if alive {
+			ret = append(ret, []byte(fmt.Sprintf("NOTIFY * HTTP/1.1\r\n"+
+				"HOST: %s\r\n"+
+				"CACHE-CONTROL: max-age=%d\r\n"+
+				"LOCATION: %s\r\n"+
+				"NT: %s\r\n"+
+				"NTS: ssdp:alive\r\n"+
+				"SERVER: %s\r\n"+
+				"USN: %s\r\n"+
+				"\r\n", ssdpAddr, ssdpMaxAge, location, target[0], serverHeader(), target[1])))
+		} else {
+			ret = append(ret, []byte(fmt.Sprintf("NOTIFY * HTTP/1.1\r\n"+
+				"HOST: %s\r\n"+
+				"NT: %s\r\n"+
+				"NTS: ssdp:byebye\r\n"+
+				"USN: %s\r\n"+
+				"\r\n", ssdpAddr, target[0], target[1])))
+		}
+	}
+	return ret
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// localIPFor returns the local IP address used to reach the address.
+func localIPFor(addr *net.UDPAddr) (net.IP, error) {
+	conn, err := net.DialUDP("udp4", nil, addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// multicastIPs returns the IPv4 addresses of the interfaces that can send multicast messages.
+func multicastIPs() []net.IP {
+	var ret []net.IP
+
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ret
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagMulticast == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.To4() != nil {
+				ret = append(ret, ipNet.IP.To4())
+			}
+		}
+	}
+	return ret
+}
+
+// runSSDP answers M-SEARCH requests and announces the server until the context is canceled.
+func (s *Server) runSSDP(ctx context.Context, port int) error {
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return err
+	}
+
+	conn, err := net.ListenMulticastUDP("udp4", nil, group)
+	if err != nil {
+		return fmt.Errorf("dlna: Failed to listen for SSDP requests: %w", err)
+	}
+
+	go func() {
+		<-ctx.Done()
+		s.notify(group, port, false)
+		_ = conn.Close()
+	}()
+
+	go func() {
+		s.notify(group, port, true)
+		ticker := time.NewTicker(ssdpNotifyInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.notify(group, port, true)
+			}
+		}
+	}()
+
+	go func() {
+		buf := make([]byte, 2048)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Error().Err(err).Msg("dlna: Failed to read SSDP request")
+				}
+				return
+			}
+
+			// Ignore the announcements of other devices
+			if _, ok := parseSearchRequest(buf[:n]); !ok {
+				continue
+			}
+
+			ip, err := localIPFor(from)
+			if err != nil {
+				continue
+			}
+
+			responses := searchResponses(buf[:n], s.udn, location(ip, port))
+			if len(responses) == 0 {
+				continue
+			}
+			s.logger.Trace().Str("from", from.String()).Msg("dlna: Answering SSDP search")
+
+			go func(from *net.UDPAddr, responses [][]byte) {
+				reply, err := net.DialUDP("udp4", nil, from)
+				if err != nil {
+					return
+				}
+				defer reply.Close()
+				for _, res := range responses {
+					_, _ = reply.Write(res)
+				}
+			}(from, responses)
+		}
+	}()
+
+	return nil
+}
+
+// notify sends the NOTIFY messages on every interface.
+func (s *Server) notify(group *net.UDPAddr, port int, alive bool) {
+	for _, ip := range multicastIPs() {
+		conn, err := net.DialUDP("udp4", &net.UDPAddr{IP: ip}, group)
+		if err != nil {
+			continue
+		}
+		for _, msg := range notifyMessages(s.udn, location(ip, port), alive) {
+			_, _ = conn.Write(msg)
+		}
+		_ = conn.Close()
+	}
+}
@@ -57,8 +57,11 @@ const (
 	OfflineSnapshotCreated      = "offline-snapshot-created"
 	MangaPreferencesUpdated     = "manga-preferences-updated"
 	MangaSourceRefreshUpdated   = "manga-source-refresh-job-updated"
+	ReadingHistoryItemUpdated   = "reading-history-item-updated"
 
-	MediastreamShutdownStream = "mediastream-shutdown-stream"
+	MediastreamShutdownStream         = "mediastream-shutdown-stream"
+	MediastreamPreTranscodeJobUpdated = "mediastream-pre-transcode-job-updated"
+	MediastreamTrickplayGenerated     = "mediastream-trickplay-generated"
 
 	ExtensionsReloaded      = "extensions-reloaded"
 	ExtensionUpdatesFound   = "extension-updates-found"
@@ -3,6 +3,7 @@ package extension_repo
 import (
 	"seanime/internal/events"
 	"seanime/internal/extension"
+	hibikecustomsource "seanime/internal/extension/hibike/customsource"
 	hibikemanga "seanime/internal/extension/hibike/manga"
 	hibikeonlinestream "seanime/internal/extension/hibike/onlinestream"
 	hibiketorrent "seanime/internal/extension/hibike/torrent"
@@ -115,6 +116,19 @@ func (r *Repository) loadBuiltInExtension(ext extension.Extension, provider inte
 		case extension.LanguageJavascript, extension.LanguageTypescript:
 			r.loadBuiltInOnlinestreamProviderExtensionJS(ext)
 		}
+	case extension.TypeCustomSource:
+		switch ext.Language {
+		// Go
+		case extension.LanguageGo:
+			if provider == nil {
+				r.logger.Error().Str("id", ext.ID).Msg("extensions: Built-in custom source extension requires a provider")
+				return
+			}
+			saveUserConfigInProvider(&ext, provider)
+			if customSourceProvider, ok := provider.(hibikecustomsource.Provider); ok {
+				r.loadBuiltInCustomSourceExtension(ext, customSourceProvider)
+			}
+		}
 	case extension.TypePlugin:
 		// TODO: Implement
 	}
@@ -138,6 +152,21 @@ func (r *Repository) loadBuiltInOnlinestreamProviderExtension(ext extension.Exte
 	r.logger.Debug().Str("id", ext.ID).Msg("extensions: Loaded built-in onlinestream provider extension")
 }
 
+// identifiableCustomSource is implemented by built-in custom sources that need their extension identifier to generate media IDs
+type identifiableCustomSource interface {
+	SetExtensionIdentifier(identifier int)
+}
+
+func (r *Repository) loadBuiltInCustomSourceExtension(ext extension.Extension, provider hibikecustomsource.Provider) {
+	retExt := extension.NewCustomSourceExtension(&ext, provider)
+	retExt.SetExtensionIdentifier(r.generateExtensionIdentifier(ext.ID))
+	if identifiable, ok := provider.(identifiableCustomSource); ok {
+		identifiable.SetExtensionIdentifier(retExt.GetExtensionIdentifier())
+	}
+	r.extensionBankRef.Get().Set(ext.ID, retExt)
+	r.logger.Debug().Str("id", ext.ID).Int("identifier", retExt.GetExtensionIdentifier()).Msg("extensions: Loaded built-in custom source extension")
+}
+
 func (r *Repository) loadBuiltInOnlinestreamProviderExtensionJS(ext extension.Extension) {
 	// Load the extension as if it was an external extension
 	err := r.loadExternalOnlinestreamExtensionJS(&ext, ext.Language)
@@ -86,6 +86,39 @@ type ExtensionUserConfig struct {
 	SavedUserConfig *extension.SavedUserConfig `json:"savedUserConfig"`
 }
 
+// Redacted returns a copy of the config without the values of the text fields, e.g. API keys and passwords.
+// Switch and select values are kept.
+func (c *ExtensionUserConfig) Redacted() *ExtensionUserConfig {
+	if c == nil || c.SavedUserConfig == nil {
+		return c
+	}
+
+	kept := make(map[string]bool)
+	if c.UserConfig != nil {
+		for _, field := range c.UserConfig.Fields {
+			if field.Type == extension.ConfigFieldTypeSwitch || field.Type == extension.ConfigFieldTypeSelect {
+				kept[field.Name] = true
+			}
+		}
+	}
+
+	saved := &extension.SavedUserConfig{
+		Version: c.SavedUserConfig.Version,
+		Values:  make(map[string]string, len(c.SavedUserConfig.Values)),
+	}
+	for name, value := range c.SavedUserConfig.Values {
+		if !kept[name] {
+			value = ""
+		}
+		saved.Values[name] = value
+	}
+
+	return &ExtensionUserConfig{
+		UserConfig:      c.UserConfig,
+		SavedUserConfig: saved,
+	}
+}
+
 func (r *Repository) GetExtensionUserConfig(id string) (ret *ExtensionUserConfig) {
 	ret = &ExtensionUserConfig{
 		UserConfig:      nil,
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"seanime/internal/access"
+	"seanime/internal/core"
+	"seanime/internal/database/models"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+)
+
+const apiTokenContextKey = "apiToken"
+
+// resolveApiToken returns the API token sent by the client.
+// Like the server password, the client sends the hash of the token in the X-Seanime-Token header or the token query parameter.
+func (h *Handler) resolveApiToken(c echo.Context) (*models.ApiToken, bool) {
+	if h.App.AccessManager == nil || !h.App.AccessManager.HasTokens() {
+		return nil, false
+	}
+
+	if token, found := h.App.AccessManager.Resolve(c.Request().Header.Get("X-Seanime-Token")); found {
+		return token, true
+	}
+
+	if passwordHash, ok := getOpdsPasswordHash(c.Request()); ok {
+		if token, found := h.App.AccessManager.Resolve(passwordHash); found {
+			return token, true
+		}
+	}
+
+	return h.App.AccessManager.Resolve(c.QueryParam("token"))
+}
+
+// getContextApiToken returns the API token set by OptionalAuthMiddleware.
+func getContextApiToken(c echo.Context) (*models.ApiToken, bool) {
+	token, ok := c.Get(apiTokenContextKey).(*models.ApiToken)
+	return token, ok && token != nil
+}
+
+// getContextAccessRole returns the role of the API token used for the request.
+// Requests made without an API token (server password or no password) have the admin role.
+func getContextAccessRole(c echo.Context) access.Role {
+	token, found := getContextApiToken(c)
+	if !found {
+		return access.RoleAdmin
+	}
+	return access.Role(token.Role)
+}
+
+// isAdminRequest returns true if the request is allowed to see secrets, e.g. passwords and API keys in the settings.
+func isAdminRequest(c echo.Context) bool {
+	return getContextAccessRole(c) == access.RoleAdmin
+}
+
+// AccessAuditMiddleware records the requests made with API tokens, including the ones rejected by FeaturesMiddleware.
+func (h *Handler) AccessAuditMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		token, found := getContextApiToken(c)
+		if !found {
+			return next(c)
+		}
+
+		err := next(c)
+
+		status := c.Response().Status
+		if err != nil && !c.Response().Committed {
+			status = http.StatusInternalServerError
+			var httpErr *echo.HTTPError
+			if errors.As(err, &httpErr) {
+				status = httpErr.Code
+			}
+		}
+
+		h.App.AccessManager.RecordRequest(token, c.Request().Method, c.Request().URL.Path, status)
+
+		return err
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type AccessRoleInfo struct {
+	Role             access.Role       `json:"role"`
+	DisabledFeatures []core.FeatureKey `json:"disabledFeatures"`
+}
+
+// HandleGetAccessRoles
+//
+//	@summary returns the API token roles.
+//	@desc Each role comes with the features it cannot use, in addition to the features disabled for everyone.
+//	@route /api/v1/access/roles [GET]
+//	@returns []handlers.AccessRoleInfo
+func (h *Handler) HandleGetAccessRoles(c echo.Context) error {
+	roleFeatures := core.GetRoleDisabledFeatures()
+
+	ret := make([]*AccessRoleInfo, 0, len(roleFeatures))
+	for _, role := range access.Roles() {
+		ret = append(ret, &AccessRoleInfo{
+			Role:             role,
+			DisabledFeatures: roleFeatures[role],
+		})
+	}
+
+	return h.RespondWithData(c, ret)
+}
+
+// HandleGetApiTokens
+//
+//	@summary returns the API tokens.
+//	@desc The token values are not returned, only their name and role.
+//	@route /api/v1/access/tokens [GET]
+//	@returns []models.ApiToken
+func (h *Handler) HandleGetApiTokens(c echo.Context) error {
+	tokens, err := h.App.AccessManager.GetTokens()
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, tokens)
+}
+
+type CreateApiTokenResponse struct {
+	Token    string           `json:"token"`
+	ApiToken *models.ApiToken `json:"apiToken"`
+}
+
+// HandleCreateApiToken
+//
+//	@summary creates an API token.
+//	@desc The token value is only returned once.
+//	@desc It is used in place of the server password, requests made with it are restricted by its role.
+//	@route /api/v1/access/tokens [POST]
+//	@returns handlers.CreateApiTokenResponse
+func (h *Handler) HandleCreateApiToken(c echo.Context) error {
+
+	type body struct {
+		Name string      `json:"name"`
+		Role access.Role `json:"role"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	value, token, err := h.App.AccessManager.CreateToken(b.Name, b.Role)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, &CreateApiTokenResponse{
+		Token:    value,
+		ApiToken: token,
+	})
+}
+
+// HandleDeleteApiToken
+//
+//	@summary revokes an API token.
+//	@desc The audit entries of the token are deleted.
+//	@route /api/v1/access/tokens [DELETE]
+//	@returns bool
+func (h *Handler) HandleDeleteApiToken(c echo.Context) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.AccessManager.DeleteToken(b.ID); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleGetApiTokenAudit
+//
+//	@summary returns the requests made with an API token.
+//	@desc The most recent requests are returned first.
+//	@route /api/v1/access/tokens/{id}/audit [GET]
+//	@param id - int - true - "The DB id of the token"
+//	@returns []models.ApiTokenAuditEntry
+func (h *Handler) HandleGetApiTokenAudit(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithError(c, errors.New("invalid id"))
+	}
+
+	entries, err := h.App.AccessManager.GetAuditEntries(uint(id), 0)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, entries)
+}
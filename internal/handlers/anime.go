@@ -1,8 +1,16 @@
 package handlers
 
 import (
+	"context"
+	"errors"
+	"net/http"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/db_bridge"
 	"seanime/internal/library/anime"
+	"seanime/internal/util/limiter"
+	"seanime/internal/util/result"
 	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 )
@@ -54,3 +62,92 @@ func (h *Handler) HandleGetAnimeEpisodeCollection(c echo.Context) error {
 
 	return h.RespondWithData(c, ec)
 }
+
+var franchiseTreeCache = result.NewCache[int, *anilist.CompleteAnimeRelationTree]()
+
+// HandleGetAnimeFranchiseGuide
+//
+//	@summary returns the watch order of the franchise of an anime.
+//	@desc The main story is made of the prequels and sequels of the anime. Side stories, spin-offs, alternative versions and recaps are labeled and optional.
+//	@desc The 'order' query parameter is "release" (default) or "chronological".
+//	@desc Entries are annotated with the local library, the list status and the watched state.
+//	@param id - int - true - "AniList anime media ID"
+//	@route /api/v1/anime/franchise-guide/{id} [GET]
+//	@returns anime.FranchiseGuide
+func (h *Handler) HandleGetAnimeFranchiseGuide(c echo.Context) error {
+	mId, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	order := anime.FranchiseGuideReleaseOrder
+	if c.QueryParam("order") != "" {
+		order = anime.FranchiseGuideOrder(c.QueryParam("order"))
+		if !order.IsValid() {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("order must be release or chronological"))
+		}
+	}
+
+	tree, ok := franchiseTreeCache.Get(mId)
+	if !ok {
+		tree, err = h.fetchFranchiseTree(c.Request().Context(), mId)
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
+		// The tree is shared by the whole franchise
+		franchiseTreeCache.SetT(mId, tree, 1*time.Hour)
+		tree.Range(func(id int, _ *anilist.CompleteAnime) bool {
+			franchiseTreeCache.SetT(id, tree, 1*time.Hour)
+			return true
+		})
+	}
+
+	animeCollection, err := h.App.GetAnimeCollection(false)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(h.App.Database)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, anime.NewFranchiseGuide(&anime.NewFranchiseGuideOptions{
+		Tree:            tree,
+		Order:           order,
+		AnimeCollection: animeCollection,
+		LocalFiles:      lfs,
+	}))
+}
+
+// fetchFranchiseTree fetches the prequels and sequels of the anime, or of its parent if it is a side story.
+func (h *Handler) fetchFranchiseTree(ctx context.Context, mId int) (*anilist.CompleteAnimeRelationTree, error) {
+	anilistClient := h.App.AnilistPlatformRef.Get().GetAnilistClient()
+
+	res, err := anilistClient.CompleteAnimeByID(ctx, &mId)
+	if err != nil {
+		return nil, err
+	}
+	media := res.GetMedia()
+	if media == nil {
+		return nil, errors.New("anime not found")
+	}
+
+	if rootId := anime.FranchiseGuideRoot(media); rootId != media.GetID() {
+		res, err = anilistClient.CompleteAnimeByID(ctx, &rootId)
+		if err != nil {
+			return nil, err
+		}
+		if res.GetMedia() != nil {
+			media = res.GetMedia()
+		}
+	}
+
+	tree := anilist.NewCompleteAnimeRelationTree()
+	err = media.FetchMediaTree(anilist.FetchMediaTreeAll, anilistClient, limiter.NewAnilistLimiter(), tree, anilist.NewCompleteAnimeCache())
+	if err != nil {
+		return nil, err
+	}
+
+	return tree, nil
+}
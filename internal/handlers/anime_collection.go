@@ -238,6 +238,16 @@ func (h *Handler) HandleGetLibraryCollection(c echo.Context) error {
 //	@route /api/v1/library/schedule [GET]
 //	@returns []anime.ScheduleItem
 func (h *Handler) HandleGetAnimeCollectionSchedule(c echo.Context) error {
+	ret, err := h.getAnimeCollectionSchedule(c)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, ret)
+}
+
+// getAnimeCollectionSchedule returns the schedule items of the anime collection, cached until the collection is refreshed.
+func (h *Handler) getAnimeCollectionSchedule(c echo.Context) ([]*anime.ScheduleItem, error) {
 
 	// Invalidate the cache when the Anilist collection is refreshed
 	h.App.AddOnRefreshAnilistCollectionFunc("HandleGetAnimeCollectionSchedule", func() {
@@ -245,24 +255,24 @@ func (h *Handler) HandleGetAnimeCollectionSchedule(c echo.Context) error {
 	})
 
 	if ret, ok := anime.GetScheduleCache(); ok {
-		return h.RespondWithData(c, ret)
+		return ret, nil
 	}
 
 	animeSchedule, err := h.App.AnilistPlatformRef.Get().GetAnimeAiringSchedule(c.Request().Context())
 	if err != nil {
-		return h.RespondWithError(c, err)
+		return nil, err
 	}
 
 	animeCollection, err := h.App.GetAnimeCollection(false)
 	if err != nil {
-		return h.RespondWithError(c, err)
+		return nil, err
 	}
 
 	ret := anime.GetScheduleItems(animeSchedule, animeCollection)
 
 	anime.SetScheduleCache(ret)
 
-	return h.RespondWithData(c, ret)
+	return ret, nil
 }
 
 // HandleAddUnknownMedia
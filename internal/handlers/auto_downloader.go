@@ -3,11 +3,13 @@ package handlers
 import (
 	"context"
 	"errors"
+	"net/url"
 	"path/filepath"
 	"seanime/internal/api/anilist"
 	"seanime/internal/database/db_bridge"
 	"seanime/internal/library/anime"
 	"strconv"
+	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -340,6 +342,131 @@ func (h *Handler) HandleDeleteAutoDownloaderProfile(c echo.Context) error {
 
 ////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
+// HandleGetAutoDownloaderFeeds
+//
+//	@summary returns all RSS feeds.
+//	@route /api/v1/auto-downloader/feeds [GET]
+//	@returns []anime.AutoDownloaderFeed
+func (h *Handler) HandleGetAutoDownloaderFeeds(c echo.Context) error {
+	feeds, err := db_bridge.GetAutoDownloaderFeeds(h.App.Database)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, feeds)
+}
+
+// HandleCreateAutoDownloaderFeed
+//
+//	@summary creates a new RSS feed.
+//	@route /api/v1/auto-downloader/feed [POST]
+//	@returns anime.AutoDownloaderFeed
+func (h *Handler) HandleCreateAutoDownloaderFeed(c echo.Context) error {
+	var feed anime.AutoDownloaderFeed
+	if err := c.Bind(&feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := validateAutoDownloaderFeed(&feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := db_bridge.InsertAutoDownloaderFeed(h.App.Database, &feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, feed)
+}
+
+// HandleUpdateAutoDownloaderFeed
+//
+//	@summary updates an RSS feed.
+//	@route /api/v1/auto-downloader/feed [PATCH]
+//	@returns anime.AutoDownloaderFeed
+func (h *Handler) HandleUpdateAutoDownloaderFeed(c echo.Context) error {
+	var feed anime.AutoDownloaderFeed
+	if err := c.Bind(&feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if feed.DbID == 0 {
+		return h.RespondWithError(c, errors.New("invalid feed id"))
+	}
+
+	if err := validateAutoDownloaderFeed(&feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := db_bridge.UpdateAutoDownloaderFeed(h.App.Database, feed.DbID, &feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, feed)
+}
+
+// HandleDeleteAutoDownloaderFeed
+//
+//	@summary deletes an RSS feed.
+//	@route /api/v1/auto-downloader/feed/{id} [DELETE]
+//	@param id - int - true - "The DB id of the feed"
+//	@returns bool
+func (h *Handler) HandleDeleteAutoDownloaderFeed(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithError(c, errors.New("invalid id"))
+	}
+
+	if err := db_bridge.DeleteAutoDownloaderFeed(h.App.Database, uint(id)); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandlePreviewAutoDownloaderFeed
+//
+//	@summary fetches an RSS feed and returns its items.
+//	@desc This is used to check that a feed can be parsed before saving it.
+//	@desc Items without an info hash are not returned since the AutoDownloader ignores them.
+//	@route /api/v1/auto-downloader/feed/preview [POST]
+//	@returns []autodownloader.NormalizedTorrent
+func (h *Handler) HandlePreviewAutoDownloaderFeed(c echo.Context) error {
+	type body struct {
+		URL string `json:"url"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	feed := &anime.AutoDownloaderFeed{Name: "preview", URL: b.URL, Enabled: true}
+	if err := validateAutoDownloaderFeed(feed); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	torrents, err := h.App.AutoDownloader.PreviewFeed(c.Request().Context(), feed)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, torrents)
+}
+
+func validateAutoDownloaderFeed(feed *anime.AutoDownloaderFeed) error {
+	feed.URL = strings.TrimSpace(feed.URL)
+	if feed.Name == "" {
+		return errors.New("feed name is required")
+	}
+	u, err := url.Parse(feed.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("feed url must be a valid http(s) url")
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
 // HandleGetAutoDownloaderItems
 //
 //	@summary returns all queued items.
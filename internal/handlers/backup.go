@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"seanime/internal/backup"
+	"seanime/internal/database/models"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetBackupSettings
+//
+//	@summary get backup settings.
+//	@desc This returns the schedule and retention of the backups.
+//	@returns models.BackupSettings
+//	@route /api/v1/backup/settings [GET]
+func (h *Handler) HandleGetBackupSettings(c echo.Context) error {
+	backupSettings, found := h.App.Database.GetBackupSettings()
+	if !found {
+		return h.RespondWithError(c, errors.New("backup settings not found"))
+	}
+
+	return h.RespondWithData(c, backupSettings)
+}
+
+// HandleSaveBackupSettings
+//
+//	@summary save backup settings.
+//	@desc This saves the schedule and retention of the backups.
+//	@desc If the directory is empty, backups are written to the backups folder of the data directory.
+//	@returns models.BackupSettings
+//	@route /api/v1/backup/settings [PATCH]
+func (h *Handler) HandleSaveBackupSettings(c echo.Context) error {
+
+	type body struct {
+		Settings models.BackupSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if b.Settings.IntervalHours <= 0 {
+		return h.RespondWithError(c, errors.New("invalid interval"))
+	}
+	if b.Settings.Retention < 0 {
+		return h.RespondWithError(c, errors.New("invalid retention"))
+	}
+	b.Settings.Dir = strings.TrimSpace(b.Settings.Dir)
+
+	// The directory is written to by the server
+	if prev, found := h.App.Database.GetBackupSettings(); !found || prev.Dir != b.Settings.Dir {
+		if err := h.guardStrictLocalOnlyAction(c); err != nil {
+			return err
+		}
+	}
+
+	settings, err := h.App.Database.UpsertBackupSettings(&b.Settings)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	h.App.InitOrRefreshBackupSettings()
+
+	return h.RespondWithData(c, settings)
+}
+
+// HandleGetBackups
+//
+//	@summary returns the backups.
+//	@desc This returns the archives of the backup directory, newest first.
+//	@returns []backup.Backup
+//	@route /api/v1/backup/list [GET]
+func (h *Handler) HandleGetBackups(c echo.Context) error {
+	backups, err := h.App.BackupManager.ListBackups()
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, backups)
+}
+
+// HandleCreateBackup
+//
+//	@summary creates a backup.
+//	@desc This writes an archive of the database, the watch history, the manga chapter containers, the extensions and the config.
+//	@desc The archive contains the account tokens and the server password, it should be stored safely.
+//	@returns backup.Backup
+//	@route /api/v1/backup/create [POST]
+func (h *Handler) HandleCreateBackup(c echo.Context) error {
+	b, err := h.App.BackupManager.CreateBackup(false)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, b)
+}
+
+// HandleDeleteBackup
+//
+//	@summary deletes a backup.
+//	@returns bool
+//	@route /api/v1/backup [DELETE]
+func (h *Handler) HandleDeleteBackup(c echo.Context) error {
+
+	type body struct {
+		Filename string `json:"filename"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.BackupManager.DeleteBackup(b.Filename); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleDownloadBackup
+//
+//	@summary downloads a backup.
+//	@desc The archive can be restored into a new data directory with the --restore flag.
+//	@route /api/v1/backup/download/{filename} [GET]
+func (h *Handler) HandleDownloadBackup(c echo.Context) error {
+	// The archive contains the account tokens
+	if err := h.guardStrictLocalOnlyAction(c); err != nil {
+		return err
+	}
+
+	p, err := h.App.BackupManager.GetBackupPath(c.Param("filename"))
+	if err != nil {
+		if errors.Is(err, backup.ErrBackupNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return h.RespondWithError(c, err)
+	}
+
+	return c.Attachment(p, c.Param("filename"))
+}
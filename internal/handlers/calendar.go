@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
+	"seanime/internal/util"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Calendar apps cannot authenticate, the feed is protected by the token of the calendar feed settings
+const calendarFeedPath = "/api/v1/calendar/schedule.ics"
+
+// Largest expected release delay of a show, in minutes
+const maxCalendarFeedDelay = 7 * 24 * 60
+
+// HandleGetScheduleCalendar
+//
+//	@summary returns the airing schedule as an iCalendar feed.
+//	@desc Calendar apps subscribe to /api/v1/calendar/schedule.ics?key={token}, where the token is the one of the calendar feed settings.
+//	@desc The 'status' query parameter filters the entries by list status, e.g. "current,planning". Current, repeating and planning entries are published by default.
+//	@desc Episodes are shifted by the expected release delay of their show.
+//	@route /api/v1/calendar/schedule.ics [GET]
+//	@returns string
+func (h *Handler) HandleGetScheduleCalendar(c echo.Context) error {
+	settings, found := h.App.Database.GetCalendarFeedSettings()
+	if !found || !settings.Enabled || settings.Token == "" {
+		return h.RespondWithStatusError(c, http.StatusNotFound, errors.New("calendar feed is disabled"))
+	}
+	if subtle.ConstantTimeCompare([]byte(c.QueryParam("key")), []byte(settings.Token)) != 1 {
+		return h.RespondWithStatusError(c, http.StatusUnauthorized, errors.New("invalid calendar feed key"))
+	}
+
+	statuses, err := anime.ParseScheduleCalendarStatuses(c.QueryParam("status"))
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+
+	items, err := h.getAnimeCollectionSchedule(c)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	animeCollection, err := h.App.GetAnimeCollection(false)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(h.App.Database)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	delays := make(map[int]time.Duration, len(settings.Delays))
+	for mediaId, minutes := range settings.Delays {
+		delays[mediaId] = time.Duration(minutes) * time.Minute
+	}
+
+	calendar := anime.NewScheduleCalendar(items, animeCollection, &anime.ScheduleCalendarOptions{
+		Statuses:   statuses,
+		Delays:     delays,
+		LocalFiles: lfs,
+		Stamp:      time.Now(),
+	})
+
+	var buf bytes.Buffer
+	if err := calendar.Write(&buf); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	c.Response().Header().Set("Content-Disposition", `inline; filename="seanime-schedule.ics"`)
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", buf.Bytes())
+}
+
+// HandleGetCalendarFeedSettings
+//
+//	@summary returns the settings of the airing schedule calendar feed.
+//	@desc The token is used to subscribe to /api/v1/calendar/schedule.ics?key={token}.
+//	@desc It is only returned to admin clients.
+//	@route /api/v1/calendar/settings [GET]
+//	@returns models.CalendarFeedSettings
+func (h *Handler) HandleGetCalendarFeedSettings(c echo.Context) error {
+	settings, found := h.App.Database.GetCalendarFeedSettings()
+	if !found {
+		settings = &models.CalendarFeedSettings{Delays: map[int]int{}}
+	}
+	if !isAdminRequest(c) {
+		settings = settings.Redacted()
+	}
+
+	return h.RespondWithData(c, settings)
+}
+
+// HandleSaveCalendarFeedSettings
+//
+//	@summary saves the settings of the airing schedule calendar feed.
+//	@desc A token is generated the first time the feed is enabled.
+//	@desc Delays are the expected release delays of shows in minutes, by media ID, up to a week.
+//	@route /api/v1/calendar/settings [PATCH]
+//	@returns models.CalendarFeedSettings
+func (h *Handler) HandleSaveCalendarFeedSettings(c echo.Context) error {
+
+	type body struct {
+		Enabled bool        `json:"enabled"`
+		Delays  map[int]int `json:"delays"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	delays := make(map[int]int, len(b.Delays))
+	for mediaId, minutes := range b.Delays {
+		if minutes < 0 || minutes > maxCalendarFeedDelay {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("release delays must be between 0 and 7 days"))
+		}
+		if minutes > 0 {
+			delays[mediaId] = minutes
+		}
+	}
+
+	token := ""
+	if prev, found := h.App.Database.GetCalendarFeedSettings(); found {
+		token = prev.Token
+	}
+	if token == "" && b.Enabled {
+		token = util.GenerateCryptoID()
+	}
+
+	settings, err := h.App.Database.UpsertCalendarFeedSettings(&models.CalendarFeedSettings{
+		Enabled: b.Enabled,
+		Token:   token,
+		Delays:  delays,
+	})
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+	if !isAdminRequest(c) {
+		settings = settings.Redacted()
+	}
+
+	return h.RespondWithData(c, settings)
+}
+
+// HandleRegenerateCalendarFeedToken
+//
+//	@summary generates a new token for the airing schedule calendar feed.
+//	@desc The subscriptions using the previous token stop working.
+//	@route /api/v1/calendar/token [POST]
+//	@returns models.CalendarFeedSettings
+func (h *Handler) HandleRegenerateCalendarFeedToken(c echo.Context) error {
+	settings := &models.CalendarFeedSettings{Delays: map[int]int{}}
+	if prev, found := h.App.Database.GetCalendarFeedSettings(); found {
+		settings = &models.CalendarFeedSettings{
+			Enabled: prev.Enabled,
+			Delays:  prev.Delays,
+		}
+	}
+	settings.Token = util.GenerateCryptoID()
+
+	settings, err := h.App.Database.UpsertCalendarFeedSettings(settings)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+	if !isAdminRequest(c) {
+		settings = settings.Redacted()
+	}
+
+	return h.RespondWithData(c, settings)
+}
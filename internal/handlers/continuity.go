@@ -1,7 +1,9 @@
 package handlers
 
 import (
+	"net/http"
 	"seanime/internal/continuity"
+	"seanime/internal/events"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
@@ -72,3 +74,80 @@ func (h *Handler) HandleGetContinuityWatchHistory(c echo.Context) error {
 	resp := h.App.ContinuityManager.GetWatchHistory()
 	return h.RespondWithData(c, resp)
 }
+
+// HandleUpdateContinuityReadingHistoryItem
+//
+//	@summary Updates the reading history item of a manga.
+//	@desc This endpoint is called by the reader when the current page changes.
+//	@desc The updated item is broadcast to the other clients so they can resume at the same page.
+//	@route /api/v1/continuity/reading/item [PATCH]
+//	@returns continuity.ReadingHistoryItem
+func (h *Handler) HandleUpdateContinuityReadingHistoryItem(c echo.Context) error {
+	type body struct {
+		Options continuity.UpdateReadingHistoryItemOptions `json:"options"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	b.Options.ClientId = getContextClientId(c)
+
+	item, err := h.App.ContinuityManager.UpdateReadingHistoryItem(&b.Options)
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+
+	h.App.WSEventManager.SendEvent(events.ReadingHistoryItemUpdated, item)
+
+	// Push the progress to the media server the chapter comes from, if any
+	go h.App.MangaRepository.SyncProviderReadProgress(item.Provider, item.ChapterId, item.PageIndex, item.IsChapterFinished())
+
+	return h.RespondWithData(c, item)
+}
+
+// HandleGetContinuityReadingHistoryItem
+//
+//	@summary Returns the reading history item of a manga.
+//	@desc This endpoint is used to resume reading at the last page read on any device.
+//	@route /api/v1/continuity/reading/item/{id} [GET]
+//	@param id - int - true - "AniList manga media ID"
+//	@returns continuity.ReadingHistoryItemResponse
+func (h *Handler) HandleGetContinuityReadingHistoryItem(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, h.App.ContinuityManager.GetReadingHistoryItem(id))
+}
+
+// HandleDeleteContinuityReadingHistoryItem
+//
+//	@summary Deletes the reading history item of a manga.
+//	@route /api/v1/continuity/reading/item/{id} [DELETE]
+//	@param id - int - true - "AniList manga media ID"
+//	@returns bool
+func (h *Handler) HandleDeleteContinuityReadingHistoryItem(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.ContinuityManager.DeleteReadingHistoryItem(id); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleGetContinuityContinueReading
+//
+//	@summary Returns the manga to continue reading.
+//	@desc Items are sorted by the time they were last read, most recent first.
+//	@route /api/v1/continuity/reading/history [GET]
+//	@returns []continuity.ReadingHistoryItem
+func (h *Handler) HandleGetContinuityContinueReading(c echo.Context) error {
+	return h.RespondWithData(c, h.App.ContinuityManager.GetContinueReadingItems())
+}
@@ -2,7 +2,9 @@ package handlers
 
 import (
 	"errors"
+	"net/http"
 	"seanime/internal/customsource"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
 )
@@ -80,3 +82,22 @@ func (h *Handler) HandleCustomSourceListManga(c echo.Context) error {
 
 	return h.RespondWithData(c, res)
 }
+
+// HandleGetNfoCustomSourceImage
+//
+//	@summary serves the poster or fanart of a library folder described by an NFO file.
+//	@desc The ID is the local ID of the media in the NFO custom source, the name is either "poster" or "fanart".
+//	@route /api/v1/custom-source/local-nfo/image/{id}/{name} [GET]
+func (h *Handler) HandleGetNfoCustomSourceImage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.NoContent(http.StatusBadRequest)
+	}
+
+	path, ok := h.App.NfoCustomSource.GetImagePath(id, c.Param("name"))
+	if !ok {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	return c.File(path)
+}
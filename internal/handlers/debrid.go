@@ -26,6 +26,9 @@ func (h *Handler) HandleGetDebridSettings(c echo.Context) error {
 	if !found {
 		return h.RespondWithError(c, errors.New("debrid settings not found"))
 	}
+	if !isAdminRequest(c) {
+		debridSettings = debridSettings.Redacted()
+	}
 
 	return h.RespondWithData(c, debridSettings)
 }
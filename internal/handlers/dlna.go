@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/database/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetDlnaSettings
+//
+//	@summary get DLNA settings.
+//	@desc This returns the settings of the DLNA media server.
+//	@returns models.DlnaSettings
+//	@route /api/v1/dlna/settings [GET]
+func (h *Handler) HandleGetDlnaSettings(c echo.Context) error {
+	dlnaSettings, found := h.App.Database.GetDlnaSettings()
+	if !found {
+		return h.RespondWithError(c, errors.New("dlna settings not found"))
+	}
+
+	return h.RespondWithData(c, dlnaSettings)
+}
+
+// HandleSaveDlnaSettings
+//
+//	@summary save DLNA settings.
+//	@desc This saves the settings of the DLNA media server and restarts it.
+//	@desc The server publishes the local files of the library to DLNA renderers on the local network, without authentication.
+//	@desc The client should refetch the server status.
+//	@returns models.DlnaSettings
+//	@route /api/v1/dlna/settings [PATCH]
+func (h *Handler) HandleSaveDlnaSettings(c echo.Context) error {
+
+	type body struct {
+		Settings models.DlnaSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if b.Settings.Port < 0 || b.Settings.Port > 65535 {
+		return h.RespondWithError(c, errors.New("invalid port"))
+	}
+
+	settings, err := h.App.Database.UpsertDlnaSettings(&b.Settings)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	h.App.InitOrRefreshDlnaSettings()
+
+	if settings.Enabled && !h.App.DlnaServer.IsRunning() {
+		return h.RespondWithError(c, errors.New("the DLNA server could not be started, check the logs"))
+	}
+
+	return h.RespondWithData(c, settings)
+}
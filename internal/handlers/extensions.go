@@ -499,6 +499,7 @@ func (h *Handler) HandleRunExtensionPlaygroundCode(c echo.Context) error {
 // HandleGetExtensionUserConfig
 //
 //	@summary returns the user config definition and current values for the extension with the given ID.
+//	@desc The values of text fields, e.g. API keys, are only returned to admin clients.
 //	@route /api/v1/extensions/user-config/{id} [GET]
 //	@returns extension_repo.ExtensionUserConfig
 func (h *Handler) HandleGetExtensionUserConfig(c echo.Context) error {
@@ -507,6 +508,9 @@ func (h *Handler) HandleGetExtensionUserConfig(c echo.Context) error {
 		return h.RespondWithError(c, fmt.Errorf("id is required"))
 	}
 	config := h.App.ExtensionRepository.GetExtensionUserConfig(id)
+	if !isAdminRequest(c) {
+		config = config.Redacted()
+	}
 	return h.RespondWithData(c, config)
 }
 
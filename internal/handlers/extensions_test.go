@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/access"
+	"seanime/internal/core"
+	"seanime/internal/database/models"
+	"seanime/internal/extension"
+	"seanime/internal/extension_repo"
+	"seanime/internal/util"
+	"seanime/internal/util/filecache"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/labstack/echo/v4"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetExtensionUserConfigRedactsValuesForViewers(t *testing.T) {
+	fileCacher, err := filecache.NewCacher(t.TempDir())
+	require.NoError(t, err)
+
+	repo := extension_repo.NewRepository(&extension_repo.NewRepositoryOptions{
+		Logger:           util.NewLogger(),
+		ExtensionDir:     t.TempDir(),
+		FileCacher:       fileCacher,
+		ExtensionBankRef: util.NewRef(extension.NewUnifiedBank()),
+	})
+	require.NoError(t, repo.SaveExtensionUserConfig("torznab-indexer", &extension.SavedUserConfig{
+		Version: 1,
+		Values:  map[string]string{"apiKey": "secret"},
+	}))
+
+	h := &Handler{App: &core.App{ExtensionRepository: repo}}
+
+	get := func(token *models.ApiToken) *extension_repo.ExtensionUserConfig {
+		e := echo.New()
+		rec := httptest.NewRecorder()
+		c := e.NewContext(httptest.NewRequest(http.MethodGet, "/api/v1/extensions/user-config/torznab-indexer", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("torznab-indexer")
+		if token != nil {
+			c.Set(apiTokenContextKey, token)
+		}
+		require.NoError(t, h.HandleGetExtensionUserConfig(c))
+		require.Equal(t, http.StatusOK, rec.Code)
+
+		var res struct {
+			Data *extension_repo.ExtensionUserConfig `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &res))
+		require.NotNil(t, res.Data.SavedUserConfig)
+		return res.Data
+	}
+
+	require.Equal(t, "secret", get(nil).SavedUserConfig.Values["apiKey"])
+	require.Equal(t, "secret", get(&models.ApiToken{Role: string(access.RoleAdmin)}).SavedUserConfig.Values["apiKey"])
+
+	viewer := get(&models.ApiToken{Role: string(access.RoleViewer)})
+	require.Equal(t, 1, viewer.SavedUserConfig.Version)
+	require.Contains(t, viewer.SavedUserConfig.Values, "apiKey")
+	require.Empty(t, viewer.SavedUserConfig.Values["apiKey"])
+}
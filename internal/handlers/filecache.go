@@ -24,6 +24,17 @@ func (h *Handler) HandleGetFileCacheTotalSize(c echo.Context) error {
 	return h.RespondWithData(c, util.Bytes(uint64(size)))
 }
 
+// HandleGetFileCacheStats
+//
+//	@summary returns the size and usage of the cache buckets.
+//	@desc Buckets are sorted by size, largest first.
+//	@desc Hits and misses are counted since the server started.
+//	@route /api/v1/filecache/stats [GET]
+//	@returns filecache.Stats
+func (h *Handler) HandleGetFileCacheStats(c echo.Context) error {
+	return h.RespondWithData(c, h.App.FileCacher.GetStats())
+}
+
 // HandleRemoveFileCacheBucket
 //
 //	@summary deletes all buckets with the given prefix.
@@ -104,3 +115,42 @@ func (h *Handler) HandleClearFileCacheMediastreamVideoFiles(c echo.Context) erro
 	// Return a success response
 	return h.RespondWithData(c, true)
 }
+
+// HandleGetFileCacheMangaPagesStats
+//
+//	@summary returns the size and usage of the manga page cache.
+//	@desc The page cache stores the images of chapters read from manga providers.
+//	@desc Media are sorted by size, largest first.
+//	@route /api/v1/filecache/manga-pages/stats [GET]
+//	@returns pagecache.Stats
+func (h *Handler) HandleGetFileCacheMangaPagesStats(c echo.Context) error {
+	return h.RespondWithData(c, h.App.MangaRepository.GetPageCacheStats())
+}
+
+// HandleClearFileCacheMangaPages
+//
+//	@summary deletes cached manga pages.
+//	@desc If a media ID is given, only the pages of that media are deleted.
+//	@desc Returns 'true' if the operation was successful.
+//	@route /api/v1/filecache/manga-pages [DELETE]
+//	@returns bool
+func (h *Handler) HandleClearFileCacheMangaPages(c echo.Context) error {
+	if err := h.guardStrictLocalOnlyAction(c); err != nil {
+		return err
+	}
+
+	type body struct {
+		MediaId int `json:"mediaId"` // 0 deletes all pages
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.MangaRepository.ClearPageCache(b.MediaId); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
@@ -89,6 +89,9 @@ func (h *Handler) HandleMALAuth(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
+	// Use the MyAnimeList list if it is the user's primary list
+	h.App.RefreshUnauthenticatedPlatform()
+
 	return h.RespondWithData(c, ret)
 }
 
@@ -145,6 +148,7 @@ func (h *Handler) HandleEditMALListEntryProgress(c echo.Context) error {
 //
 //	@summary logs the user out of MyAnimeList.
 //	@desc This will delete the MAL info from the database, effectively logging the user out.
+//	@desc If the MyAnimeList list was used as the primary list, the simulated list is used instead.
 //	@desc The client should re-fetch the server status after this.
 //	@route /api/v1/mal/logout [POST]
 //	@returns bool
@@ -155,5 +159,7 @@ func (h *Handler) HandleMALLogout(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
+	h.App.RefreshUnauthenticatedPlatform()
+
 	return h.RespondWithData(c, true)
 }
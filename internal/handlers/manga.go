@@ -429,6 +429,49 @@ func (h *Handler) HandleGetMangaEntryChapters(c echo.Context) error {
 	return h.RespondWithData(c, container)
 }
 
+// HandleGetMangaEntryMergedChapters
+//
+//	@summary returns the merged chapter list of a manga entry.
+//	@desc The chapter lists of several providers are combined and deduplicated by chapter number.
+//	@desc If no providers are given, the merged providers of the entry preference are used.
+//	@desc The scanlator and language filters of the entry preference are applied to each provider.
+//	@route /api/v1/manga/merged-chapters [POST]
+//	@returns manga.MergedChapterContainer
+func (h *Handler) HandleGetMangaEntryMergedChapters(c echo.Context) error {
+
+	type body struct {
+		MediaId   int      `json:"mediaId"`
+		Providers []string `json:"providers,omitempty"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	baseManga, found := baseMangaCache.Get(b.MediaId)
+	if !found {
+		var err error
+		baseManga, err = h.App.AnilistPlatformRef.Get().GetManga(c.Request().Context(), b.MediaId)
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
+		baseMangaCache.SetT(b.MediaId, baseManga, 24*time.Hour)
+	}
+
+	container, err := h.App.MangaRepository.GetMergedMangaChapterContainer(&manga.GetMergedMangaChapterContainerOptions{
+		MediaId:   b.MediaId,
+		Titles:    baseManga.GetAllTitles(),
+		Year:      baseManga.GetStartYearSafe(),
+		Providers: b.Providers,
+	})
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, container)
+}
+
 // HandleGetMangaEntryPages
 //
 //	@summary returns the pages for a manga entry based on the provider and chapter id.
@@ -437,6 +480,7 @@ func (h *Handler) HandleGetMangaEntryChapters(c echo.Context) error {
 //	@desc If the app is online and the chapter is not downloaded, it will return the pages from the provider.
 //	@desc If the chapter is downloaded, it will return the appropriate struct.
 //	@desc If 'double page' is requested, it will fetch image sizes and include the dimensions in the response.
+//	@desc If 'fallback' is requested, the same chapter is tried on the other merged providers of the entry when the pages cannot be fetched.
 //	@route /api/v1/manga/pages [POST]
 //	@returns manga.PageContainer
 func (h *Handler) HandleGetMangaEntryPages(c echo.Context) error {
@@ -446,6 +490,7 @@ func (h *Handler) HandleGetMangaEntryPages(c echo.Context) error {
 		Provider   string `json:"provider"`
 		ChapterId  string `json:"chapterId"`
 		DoublePage bool   `json:"doublePage"`
+		Fallback   bool   `json:"fallback"`
 	}
 
 	var b body
@@ -453,7 +498,13 @@ func (h *Handler) HandleGetMangaEntryPages(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
-	container, err := h.App.MangaRepository.GetMangaPageContainer(b.Provider, b.MediaId, b.ChapterId, b.DoublePage, h.App.IsOfflineRef())
+	var container *manga.PageContainer
+	var err error
+	if b.Fallback {
+		container, err = h.App.MangaRepository.GetMangaPageContainerWithFallback(b.Provider, b.MediaId, b.ChapterId, b.DoublePage, h.App.IsOfflineRef())
+	} else {
+		container, err = h.App.MangaRepository.GetMangaPageContainer(b.Provider, b.MediaId, b.ChapterId, b.DoublePage, h.App.IsOfflineRef())
+	}
 	if err != nil {
 		return h.RespondWithError(c, err)
 	}
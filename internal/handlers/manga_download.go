@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
 	"seanime/internal/events"
 	"seanime/internal/manga"
 	chapter_downloader "seanime/internal/manga/downloader"
@@ -185,6 +187,36 @@ func (h *Handler) HandleDeleteMangaDownloadedChapters(c echo.Context) error {
 	return h.RespondWithData(c, true)
 }
 
+// HandleProcessMangaDownloadedChapters
+//
+//	@summary applies the page processing settings to downloaded chapters.
+//	@desc Pages that have already been processed are left as they are.
+//	@desc Returns an error if page processing is disabled in the settings.
+//	@desc The client should refetch the chapter pages after this.
+//	@route /api/v1/manga/downloaded-chapters/process [POST]
+//	@returns bool
+func (h *Handler) HandleProcessMangaDownloadedChapters(c echo.Context) error {
+
+	type body struct {
+		DownloadIds []chapter_downloader.DownloadID `json:"downloadIds"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	err := h.App.MangaDownloader.ProcessChapters(b.DownloadIds)
+	if errors.Is(err, chapter_downloader.ErrPageProcessingDisabled) {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
 // HandleGetMangaDownloadsList
 //
 //	@summary displays the list of downloaded manga.
@@ -1,12 +1,16 @@
 package handlers
 
 import (
+	"seanime/internal/mediaplayers/upnp"
+	"time"
+
 	"github.com/labstack/echo/v4"
 )
 
 // HandleStartDefaultMediaPlayer
 //
 //	@summary launches the default media player (vlc or mpc-hc).
+//	@desc Kodi and UPnP renderers are not launched by Seanime, this only checks that they are reachable.
 //	@route /api/v1/media-player/start [POST]
 //	@returns bool
 func (h *Handler) HandleStartDefaultMediaPlayer(c echo.Context) error {
@@ -31,7 +35,33 @@ func (h *Handler) HandleStartDefaultMediaPlayer(c echo.Context) error {
 		if err != nil {
 			return h.RespondWithError(c, err)
 		}
+	case "kodi":
+		err = h.App.MediaPlayer.Kodi.Start()
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
+	case "upnp":
+		err = h.App.MediaPlayer.Upnp.Start()
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
 	}
 
 	return h.RespondWithData(c, true)
 }
+
+// HandleDiscoverUpnpRenderers
+//
+//	@summary returns the DLNA/UPnP media renderers found on the local network.
+//	@desc The search takes a few seconds.
+//	@desc The location of a renderer is saved as the media player setting "upnpRendererLocation".
+//	@route /api/v1/media-player/upnp/renderers [GET]
+//	@returns []upnp.Renderer
+func (h *Handler) HandleDiscoverUpnpRenderers(c echo.Context) error {
+	renderers, err := upnp.Discover(c.Request().Context(), 3*time.Second)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, renderers)
+}
@@ -5,9 +5,14 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"seanime/internal/database/db_bridge"
 	"seanime/internal/database/models"
+	"seanime/internal/library/anime"
 	"seanime/internal/mediastream"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/pretranscode"
 	"seanime/internal/util"
+	"slices"
 	"strings"
 
 	"github.com/labstack/echo/v4"
@@ -81,6 +86,8 @@ func (h *Handler) HandleRequestMediastreamMediaContainer(c echo.Context) error {
 		StreamType       mediastream.StreamType `json:"streamType"`       // The type of stream to request.
 		AudioStreamIndex int                    `json:"audioStreamIndex"` // The audio stream index to use. (unused)
 		ClientId         string                 `json:"clientId"`         // The session id
+		// The transcode profile selected by the client, e.g. to burn subtitles for clients that cannot render them.
+		TranscodeProfile cassette.TranscodeProfile `json:"transcodeProfile"`
 	}
 
 	var b body
@@ -107,7 +114,7 @@ func (h *Handler) HandleRequestMediastreamMediaContainer(c echo.Context) error {
 	case mediastream.StreamTypeDirect:
 		mediaContainer, err = h.App.MediastreamRepository.RequestDirectPlay(b.Path, b.ClientId)
 	case mediastream.StreamTypeTranscode:
-		mediaContainer, err = h.App.MediastreamRepository.RequestTranscodeStream(b.Path, b.ClientId)
+		mediaContainer, err = h.App.MediastreamRepository.RequestTranscodeStream(b.Path, b.ClientId, b.TranscodeProfile)
 	case mediastream.StreamTypeOptimized:
 		err = fmt.Errorf("stream type %s not implemented", b.StreamType)
 		//mediaContainer, err = h.App.MediastreamRepository.RequestOptimizedStream(b.Path)
@@ -185,6 +192,16 @@ func (h *Handler) HandleMediastreamGetAttachments(c echo.Context) error {
 	return h.App.MediastreamRepository.ServeEchoExtractedAttachments(c)
 }
 
+// HandleMediastreamGetTrickplay
+//
+//	@summary serves the seek bar previews of the current media.
+//	@desc This serves the WebVTT index and the sprite sheets it references.
+//	@desc It returns 404 until the thumbnails are generated.
+//	@route /api/v1/mediastream/trickplay/{name} [GET]
+func (h *Handler) HandleMediastreamGetTrickplay(c echo.Context) error {
+	return h.App.MediastreamRepository.ServeEchoTrickplay(c)
+}
+
 //
 // Direct
 //
@@ -274,3 +291,202 @@ func (h *Handler) HandleMediastreamLocalSubtitles(c echo.Context) error {
 
 	return h.RespondWithData(c, files)
 }
+
+//
+// Pre-transcode
+//
+
+// HandleGetMediastreamPreTranscodeJobs
+//
+//	@summary returns the pre-transcode jobs.
+//	@desc This returns the queued, running and finished offline copy jobs.
+//	@returns []pretranscode.Job
+//	@route /api/v1/mediastream/pre-transcode/jobs [GET]
+func (h *Handler) HandleGetMediastreamPreTranscodeJobs(c echo.Context) error {
+	return h.RespondWithData(c, h.App.MediastreamRepository.PreTranscodeQueue().List())
+}
+
+// HandleGetMediastreamPreTranscodeProfiles
+//
+//	@summary returns the pre-transcode profiles.
+//	@returns []pretranscode.Profile
+//	@route /api/v1/mediastream/pre-transcode/profiles [GET]
+func (h *Handler) HandleGetMediastreamPreTranscodeProfiles(c echo.Context) error {
+	return h.RespondWithData(c, pretranscode.Profiles)
+}
+
+// HandleAddMediastreamPreTranscodeJobs
+//
+//	@summary queues pre-transcode jobs.
+//	@desc This queues an offline copy of the given files, or of all the episodes of the media if no path is given.
+//	@desc The progress is sent with events.MediastreamPreTranscodeJobUpdated.
+//	@returns []pretranscode.Job
+//	@route /api/v1/mediastream/pre-transcode/jobs [POST]
+func (h *Handler) HandleAddMediastreamPreTranscodeJobs(c echo.Context) error {
+	type body struct {
+		Paths         []string                  `json:"paths"`
+		MediaId       int                       `json:"mediaId"`
+		Profile       string                    `json:"profile"`
+		Subtitles     pretranscode.SubtitleMode `json:"subtitles"`     // Overrides the subtitle mode of the profile.
+		AudioTrack    *int                      `json:"audioTrack"`    // Uses the default track if not set.
+		SubtitleTrack *int                      `json:"subtitleTrack"` // Uses the default track if not set.
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.guardPrivilegedMediastream(c, h.App.SecondarySettings.Mediastream); err != nil {
+		return err
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(h.App.Database)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	type target struct {
+		path          string
+		mediaId       int
+		episodeNumber int
+	}
+	var targets []target
+
+	if len(b.Paths) > 0 {
+		for _, p := range b.Paths {
+			p = util.ResolvePhysicalPath(p)
+			if err := h.guardStrictFilesystemPath(c, p); err != nil {
+				return err
+			}
+			t := target{path: p}
+			for _, lf := range lfs {
+				if lf.HasSamePath(p) {
+					t.mediaId = lf.MediaId
+					t.episodeNumber = lf.GetEpisodeNumber()
+					break
+				}
+			}
+			targets = append(targets, t)
+		}
+	} else if b.MediaId != 0 {
+		lfWrapper := anime.NewLocalFileWrapper(lfs)
+		entry, ok := lfWrapper.GetLocalEntryById(b.MediaId)
+		if !ok {
+			return h.RespondWithError(c, errors.New("no local files found for this media"))
+		}
+		mainFiles, _ := entry.GetMainLocalFiles()
+		for _, lf := range mainFiles {
+			if err := h.guardStrictFilesystemPath(c, lf.Path); err != nil {
+				return err
+			}
+			targets = append(targets, target{path: lf.Path, mediaId: b.MediaId, episodeNumber: lf.GetEpisodeNumber()})
+		}
+		slices.SortFunc(targets, func(a, b target) int { return a.episodeNumber - b.episodeNumber })
+	}
+
+	if len(targets) == 0 {
+		return h.RespondWithError(c, errors.New("no files to transcode"))
+	}
+
+	audioTrack, subtitleTrack := -1, -1
+	if b.AudioTrack != nil {
+		audioTrack = *b.AudioTrack
+	}
+	if b.SubtitleTrack != nil {
+		subtitleTrack = *b.SubtitleTrack
+	}
+
+	queue := h.App.MediastreamRepository.PreTranscodeQueue()
+	ret := make([]*pretranscode.Job, 0, len(targets))
+	for _, t := range targets {
+		job, err := queue.Add(&pretranscode.AddOptions{
+			Path:          t.path,
+			MediaId:       t.mediaId,
+			EpisodeNumber: t.episodeNumber,
+			Profile:       b.Profile,
+			Subtitles:     b.Subtitles,
+			AudioTrack:    audioTrack,
+			SubtitleTrack: subtitleTrack,
+		})
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
+		ret = append(ret, job)
+	}
+
+	return h.RespondWithData(c, ret)
+}
+
+// HandleCancelMediastreamPreTranscodeJob
+//
+//	@summary cancels a pre-transcode job.
+//	@returns bool
+//	@route /api/v1/mediastream/pre-transcode/jobs/cancel [POST]
+func (h *Handler) HandleCancelMediastreamPreTranscodeJob(c echo.Context) error {
+	type body struct {
+		ID string `json:"id"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.guardPrivilegedMediastream(c, h.App.SecondarySettings.Mediastream); err != nil {
+		return err
+	}
+
+	if err := h.App.MediastreamRepository.PreTranscodeQueue().Cancel(b.ID); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleDeleteMediastreamPreTranscodeJob
+//
+//	@summary deletes a pre-transcode job.
+//	@desc This cancels the job if it is running and deletes its output.
+//	@returns bool
+//	@route /api/v1/mediastream/pre-transcode/jobs [DELETE]
+func (h *Handler) HandleDeleteMediastreamPreTranscodeJob(c echo.Context) error {
+	type body struct {
+		ID string `json:"id"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.guardPrivilegedMediastream(c, h.App.SecondarySettings.Mediastream); err != nil {
+		return err
+	}
+
+	if err := h.App.MediastreamRepository.PreTranscodeQueue().Remove(b.ID); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleDownloadMediastreamPreTranscodeJob
+//
+//	@summary downloads the output of a completed pre-transcode job.
+//	@route /api/v1/mediastream/pre-transcode/download/{id} [GET]
+func (h *Handler) HandleDownloadMediastreamPreTranscodeJob(c echo.Context) error {
+	if err := h.guardMediaConsumption(c); err != nil {
+		return err
+	}
+
+	p, name, err := h.App.MediastreamRepository.PreTranscodeQueue().GetOutput(c.Param("id"))
+	if err != nil {
+		if errors.Is(err, pretranscode.ErrJobNotFound) {
+			return c.NoContent(http.StatusNotFound)
+		}
+		return h.RespondWithError(c, err)
+	}
+
+	return c.Attachment(p, name)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"net/http"
+	"seanime/internal/opds"
+	"seanime/internal/util"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleOpds
+//
+//	@summary serves the OPDS catalog of the downloaded and local manga.
+//	@desc The OPDS 1.2 catalog is served at /api/v1/opds/v1.2/catalog and the OPDS 2.0 catalog at /api/v1/opds/v2/catalog.
+//	@desc Chapters are served as CBZ archives and their pages can be streamed with OPDS-PSE.
+//	@desc Readers authenticate with HTTP basic authentication, the password being the server password or an API token.
+//	@route /api/v1/opds [GET]
+func (h *Handler) HandleOpds(c echo.Context) error {
+	h.App.OpdsCatalog.Handler().ServeHTTP(c.Response(), c.Request())
+	return nil
+}
+
+func isOpdsPath(path string) bool {
+	return path == opds.BasePath || strings.HasPrefix(path, opds.BasePath+"/")
+}
+
+// getOpdsPasswordHash returns the hash of the password sent by an OPDS reader.
+// Readers only support HTTP basic authentication, the user name is ignored.
+func getOpdsPasswordHash(req *http.Request) (string, bool) {
+	if !isOpdsPath(req.URL.Path) {
+		return "", false
+	}
+	_, password, ok := req.BasicAuth()
+	if !ok || password == "" {
+		return "", false
+	}
+	return util.HashSHA256Hex(password), true
+}
@@ -1,9 +1,12 @@
 package handlers
 
 import (
+	"errors"
+	"net/http"
 	"seanime/internal/customsource"
 	"seanime/internal/database/db_bridge"
 	"seanime/internal/library/anime"
+	"seanime/internal/playlist"
 	"strconv"
 
 	"github.com/labstack/echo/v4"
@@ -14,6 +17,7 @@ import (
 //
 //	@summary creates a new playlist.
 //	@desc This will create a new playlist with the given name and episodes.
+//	@desc Smart playlists are created with rules instead, their episodes are selected each time they are started.
 //	@desc The response is ignored, the client should re-fetch the playlists after this.
 //	@route /api/v1/playlist [POST]
 //	@returns anime.Playlist
@@ -22,6 +26,7 @@ func (h *Handler) HandleCreatePlaylist(c echo.Context) error {
 	type body struct {
 		Name     string                   `json:"name"`
 		Episodes []*anime.PlaylistEpisode `json:"episodes"`
+		Rules    *anime.PlaylistRules     `json:"rules"`
 	}
 
 	var b body
@@ -29,9 +34,17 @@ func (h *Handler) HandleCreatePlaylist(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
+	if b.Rules != nil {
+		b.Rules.Normalize()
+		if err := b.Rules.Validate(); err != nil {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+		}
+	}
+
 	// Create the playlist
 	playlist := anime.NewPlaylist(b.Name)
 	playlist.SetEpisodes(b.Episodes)
+	playlist.Rules = b.Rules
 
 	// Save the playlist
 	if err := db_bridge.SavePlaylist(h.App.Database, playlist); err != nil {
@@ -70,6 +83,7 @@ func (h *Handler) HandleUpdatePlaylist(c echo.Context) error {
 		DbId     uint                     `json:"dbId"`
 		Name     string                   `json:"name"`
 		Episodes []*anime.PlaylistEpisode `json:"episodes"`
+		Rules    *anime.PlaylistRules     `json:"rules"`
 	}
 
 	var b body
@@ -77,11 +91,19 @@ func (h *Handler) HandleUpdatePlaylist(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
+	if b.Rules != nil {
+		b.Rules.Normalize()
+		if err := b.Rules.Validate(); err != nil {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+		}
+	}
+
 	// Recreate playlist
 	playlist := anime.NewPlaylist(b.Name)
 	playlist.DbId = b.DbId
 	playlist.Name = b.Name
 	playlist.SetEpisodes(b.Episodes)
+	playlist.Rules = b.Rules
 
 	// Save the playlist
 	if err := db_bridge.UpdatePlaylist(h.App.Database, playlist); err != nil {
@@ -115,6 +137,42 @@ func (h *Handler) HandleDeletePlaylist(c echo.Context) error {
 	return h.RespondWithData(c, true)
 }
 
+// HandlePreviewSmartPlaylist
+//
+//	@summary returns the episodes selected by the rules of a smart playlist.
+//	@desc The episodes are selected against the anime collection, the library, the watch history and the filler data, like when a smart playlist is started.
+//	@route /api/v1/playlist/smart/preview [POST]
+//	@returns []anime.PlaylistEpisode
+func (h *Handler) HandlePreviewSmartPlaylist(c echo.Context) error {
+
+	type body struct {
+		Rules *anime.PlaylistRules `json:"rules"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if b.Rules == nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("rules are required"))
+	}
+	b.Rules.Normalize()
+	if err := b.Rules.Validate(); err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+
+	episodes, err := h.App.PlaylistManager.EvaluateRules(c.Request().Context(), b.Rules)
+	if errors.Is(err, playlist.ErrNoSmartPlaylistEpisodes) {
+		return h.RespondWithData(c, []*anime.PlaylistEpisode{})
+	}
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, episodes)
+}
+
 // HandleGetPlaylistEpisodes
 //
 //	@summary returns all the local files of a playlist media entry that have not been watched.
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetProfiles
+//
+//	@summary returns the profiles.
+//	@desc Each profile has its own AniList account, watch history, playlists and Discord settings.
+//	@route /api/v1/profiles [GET]
+//	@returns []models.Profile
+func (h *Handler) HandleGetProfiles(c echo.Context) error {
+	profiles, err := h.App.GetProfiles()
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, profiles)
+}
+
+// HandleCreateProfile
+//
+//	@summary creates a profile.
+//	@desc The profile has no AniList account until the user logs in after switching to it.
+//	@route /api/v1/profiles [POST]
+//	@returns models.Profile
+func (h *Handler) HandleCreateProfile(c echo.Context) error {
+
+	type body struct {
+		Name string `json:"name"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	profile, err := h.App.CreateProfile(b.Name)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, profile)
+}
+
+// HandleUpdateProfile
+//
+//	@summary renames a profile.
+//	@route /api/v1/profiles [PATCH]
+//	@returns models.Profile
+func (h *Handler) HandleUpdateProfile(c echo.Context) error {
+
+	type body struct {
+		ID   uint   `json:"id"`
+		Name string `json:"name"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	profile, err := h.App.RenameProfile(b.ID, b.Name)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, profile)
+}
+
+// HandleDeleteProfile
+//
+//	@summary deletes a profile.
+//	@desc The watch history, playlists and simulated collections of the profile are deleted.
+//	@desc The default and active profiles cannot be deleted.
+//	@route /api/v1/profiles [DELETE]
+//	@returns bool
+func (h *Handler) HandleDeleteProfile(c echo.Context) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.DeleteProfile(b.ID); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleSwitchProfile
+//
+//	@summary switches the active profile.
+//	@desc The AniList account, watch history, playlists and Discord settings of the profile are restored without logging out.
+//	@desc The active profile is server-wide: switching changes it for every client connected to the server.
+//	@desc It creates a new handlers.Status and refreshes App modules.
+//	@route /api/v1/profiles/switch [POST]
+//	@returns handlers.Status
+func (h *Handler) HandleSwitchProfile(c echo.Context) error {
+
+	type body struct {
+		ID uint `json:"id"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if _, err := h.App.SwitchProfile(b.ID); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	status := h.NewStatus(c)
+
+	return h.RespondWithData(c, status)
+}
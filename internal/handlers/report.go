@@ -75,6 +75,7 @@ func (h *Handler) HandleSaveIssueReport(c echo.Context) error {
 		RRWebEvents:         b.RRWebEvents,
 		Settings:            h.App.Settings,
 		DebridSettings:      h.App.SecondarySettings.Debrid,
+		UsenetSettings:      h.App.SecondarySettings.Usenet,
 		IsAnimeLibraryIssue: b.IsAnimeLibraryIssue,
 		LocalFiles:          localFiles,
 		ServerStatus:        status,
@@ -125,9 +125,10 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	// Auth middleware
 	//
 	v1.Use(h.OptionalAuthMiddleware)
+	v1.Use(h.AccessAuditMiddleware)
 	v1.Use(h.FeaturesMiddleware)
 
-	imageProxy := &util.ImageProxy{}
+	imageProxy := &util.ImageProxy{Cache: h.App.MangaRepository}
 	v1.GET("/image-proxy", imageProxy.ProxyImage)
 
 	v1.GET("/internal/docs", h.HandleGetDocs)
@@ -183,6 +184,12 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1.PATCH("/auto-downloader/profile", h.HandleUpdateAutoDownloaderProfile)
 	v1.DELETE("/auto-downloader/profile/:id", h.HandleDeleteAutoDownloaderProfile)
 
+	v1.GET("/auto-downloader/feeds", h.HandleGetAutoDownloaderFeeds)
+	v1.POST("/auto-downloader/feed", h.HandleCreateAutoDownloaderFeed)
+	v1.POST("/auto-downloader/feed/preview", h.HandlePreviewAutoDownloaderFeed)
+	v1.PATCH("/auto-downloader/feed", h.HandleUpdateAutoDownloaderFeed)
+	v1.DELETE("/auto-downloader/feed/:id", h.HandleDeleteAutoDownloaderFeed)
+
 	// Other
 	v1.POST("/test-dump", h.HandleTestDump)
 
@@ -191,6 +198,7 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1.POST("/open-in-explorer", h.HandleOpenInExplorer)
 
 	v1.POST("/media-player/start", h.HandleStartDefaultMediaPlayer)
+	v1.GET("/media-player/upnp/renderers", h.HandleDiscoverUpnpRenderers)
 
 	//
 	// AniList
@@ -285,6 +293,7 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	// Anime
 	//
 	v1.GET("/anime/episode-collection/:id", h.HandleGetAnimeEpisodeCollection)
+	v1.GET("/anime/franchise-guide/:id", h.HandleGetAnimeFranchiseGuide)
 
 	//
 	// Torrent / Torrent Client
@@ -356,6 +365,7 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1.PATCH("/playlist", h.HandleUpdatePlaylist)
 	v1.DELETE("/playlist", h.HandleDeletePlaylist)
 	v1.GET("/playlist/episodes/:id", h.HandleGetPlaylistEpisodes)
+	v1.POST("/playlist/smart/preview", h.HandlePreviewSmartPlaylist)
 
 	//
 	// Onlinestream
@@ -403,10 +413,12 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1Manga.GET("/entry/:id/details", h.HandleGetMangaEntryDetails)
 	v1Manga.DELETE("/entry/cache", h.HandleEmptyMangaEntryCache)
 	v1Manga.POST("/chapters", h.HandleGetMangaEntryChapters)
+	v1Manga.POST("/merged-chapters", h.HandleGetMangaEntryMergedChapters)
 	v1Manga.POST("/pages", h.HandleGetMangaEntryPages)
 	v1Manga.POST("/update-progress", h.HandleUpdateMangaProgress)
 
 	v1Manga.GET("/downloaded-chapters/:id", h.HandleGetMangaEntryDownloadedChapters)
+	v1Manga.POST("/downloaded-chapters/process", h.HandleProcessMangaDownloadedChapters)
 	v1Manga.GET("/downloads", h.HandleGetMangaDownloadsList)
 	v1Manga.POST("/download-chapters", h.HandleDownloadMangaChapters)
 	v1Manga.POST("/download-data", h.HandleGetMangaDownloadData)
@@ -431,9 +443,12 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 
 	v1FileCache := v1.Group("/filecache")
 	v1FileCache.GET("/total-size", h.HandleGetFileCacheTotalSize)
+	v1FileCache.GET("/stats", h.HandleGetFileCacheStats)
 	v1FileCache.DELETE("/bucket", h.HandleRemoveFileCacheBucket)
 	v1FileCache.GET("/mediastream/videofiles/total-size", h.HandleGetFileCacheMediastreamVideoFilesTotalSize)
 	v1FileCache.DELETE("/mediastream/videofiles", h.HandleClearFileCacheMediastreamVideoFiles)
+	v1FileCache.GET("/manga-pages/stats", h.HandleGetFileCacheMangaPagesStats)
+	v1FileCache.DELETE("/manga-pages", h.HandleClearFileCacheMangaPages)
 
 	//
 	// Discord
@@ -458,10 +473,17 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1.GET("/mediastream/transcode/*", h.HandleMediastreamTranscode)
 	v1.GET("/mediastream/subs/*", h.HandleMediastreamGetSubtitles)
 	v1.GET("/mediastream/att/*", h.HandleMediastreamGetAttachments)
+	v1.GET("/mediastream/trickplay/*", h.HandleMediastreamGetTrickplay)
 	v1.GET("/mediastream/direct", h.HandleMediastreamDirectPlay)
 	v1.HEAD("/mediastream/direct", h.HandleMediastreamDirectPlay)
 	v1.GET("/mediastream/file", h.HandleMediastreamFile)
 	v1.GET("/mediastream/local-subtitles", h.HandleMediastreamLocalSubtitles)
+	v1.GET("/mediastream/pre-transcode/jobs", h.HandleGetMediastreamPreTranscodeJobs)
+	v1.POST("/mediastream/pre-transcode/jobs", h.HandleAddMediastreamPreTranscodeJobs)
+	v1.DELETE("/mediastream/pre-transcode/jobs", h.HandleDeleteMediastreamPreTranscodeJob)
+	v1.POST("/mediastream/pre-transcode/jobs/cancel", h.HandleCancelMediastreamPreTranscodeJob)
+	v1.GET("/mediastream/pre-transcode/profiles", h.HandleGetMediastreamPreTranscodeProfiles)
+	v1.GET("/mediastream/pre-transcode/download/:id", h.HandleDownloadMediastreamPreTranscodeJob)
 
 	//
 	// Direct Stream
@@ -530,6 +552,10 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1Continuity.PATCH("/item", h.HandleUpdateContinuityWatchHistoryItem)
 	v1Continuity.GET("/item/:id", h.HandleGetContinuityWatchHistoryItem)
 	v1Continuity.GET("/history", h.HandleGetContinuityWatchHistory)
+	v1Continuity.PATCH("/reading/item", h.HandleUpdateContinuityReadingHistoryItem)
+	v1Continuity.GET("/reading/item/:id", h.HandleGetContinuityReadingHistoryItem)
+	v1Continuity.DELETE("/reading/item/:id", h.HandleDeleteContinuityReadingHistoryItem)
+	v1Continuity.GET("/reading/history", h.HandleGetContinuityContinueReading)
 
 	//
 	// Sync
@@ -567,6 +593,83 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1.POST("/debrid/stream/start", h.HandleDebridStartStream)
 	v1.POST("/debrid/stream/cancel", h.HandleDebridCancelStream)
 
+	//
+	// Profiles
+	//
+
+	v1.GET("/profiles", h.HandleGetProfiles)
+	v1.POST("/profiles", h.HandleCreateProfile)
+	v1.PATCH("/profiles", h.HandleUpdateProfile)
+	v1.DELETE("/profiles", h.HandleDeleteProfile)
+	v1.POST("/profiles/switch", h.HandleSwitchProfile)
+
+	//
+	// Access
+	//
+
+	v1.GET("/access/roles", h.HandleGetAccessRoles)
+	v1.GET("/access/tokens", h.HandleGetApiTokens)
+	v1.POST("/access/tokens", h.HandleCreateApiToken)
+	v1.DELETE("/access/tokens", h.HandleDeleteApiToken)
+	v1.GET("/access/tokens/:id/audit", h.HandleGetApiTokenAudit)
+
+	//
+	// Usenet
+	//
+
+	v1.GET("/usenet/settings", h.HandleGetUsenetSettings)
+	v1.PATCH("/usenet/settings", h.HandleSaveUsenetSettings)
+	v1.POST("/usenet/test", h.HandleTestUsenetConnection)
+	v1.GET("/usenet/jobs", h.HandleGetUsenetJobs)
+	v1.POST("/usenet/nzbs", h.HandleUsenetAddNzbs)
+	v1.DELETE("/usenet/job", h.HandleDeleteUsenetJob)
+
+	//
+	// DLNA
+	//
+
+	v1.GET("/dlna/settings", h.HandleGetDlnaSettings)
+	v1.PATCH("/dlna/settings", h.HandleSaveDlnaSettings)
+
+	//
+	// OPDS
+	//
+
+	v1.GET("/opds", h.HandleOpds)
+	v1.GET("/opds/*", h.HandleOpds)
+
+	//
+	// Calendar feed
+	//
+
+	v1.GET("/calendar/schedule.ics", h.HandleGetScheduleCalendar)
+	v1.GET("/calendar/settings", h.HandleGetCalendarFeedSettings)
+	v1.PATCH("/calendar/settings", h.HandleSaveCalendarFeedSettings)
+	v1.POST("/calendar/token", h.HandleRegenerateCalendarFeedToken)
+
+	//
+	// Watch Journal
+	//
+
+	v1.GET("/watch-journal/sessions", h.HandleGetWatchSessions)
+	v1.DELETE("/watch-journal/sessions/:id", h.HandleDeleteWatchSession)
+	v1.GET("/watch-journal/stats/time", h.HandleGetWatchTimeStats)
+	v1.GET("/watch-journal/stats/breakdown", h.HandleGetWatchBreakdownStats)
+	v1.GET("/watch-journal/stats/rewatches", h.HandleGetWatchRewatchStats)
+	v1.GET("/watch-journal/stats/streaks", h.HandleGetWatchStreakStats)
+	v1.GET("/watch-journal/stats/year/:year", h.HandleGetWatchYearSummary)
+
+	//
+	// Backups
+	//
+
+	v1.GET("/backup/settings", h.HandleGetBackupSettings)
+	v1.PATCH("/backup/settings", h.HandleSaveBackupSettings)
+	v1.GET("/backup/list", h.HandleGetBackups)
+	v1.POST("/backup/create", h.HandleCreateBackup)
+	v1.DELETE("/backup", h.HandleDeleteBackup)
+	v1.GET("/backup/download/:filename", h.HandleDownloadBackup)
+
 	//
 	// Report
 	//
@@ -612,6 +715,7 @@ func InitRoutes(app *core.App, e *echo.Echo) {
 	v1CustomSource := v1.Group("/custom-source")
 	v1CustomSource.POST("/provider/list/anime", h.HandleCustomSourceListAnime)
 	v1CustomSource.POST("/provider/list/manga", h.HandleCustomSourceListManga)
+	v1CustomSource.GET("/local-nfo/image/:id/:name", h.HandleGetNfoCustomSourceImage)
 
 }
 
@@ -93,6 +93,7 @@ func (h *Handler) HandleScanLocalFiles(c echo.Context) error {
 		ExistingShelvedFiles:       existingShelvedLfs,
 		ConfigAsString:             h.App.Settings.GetLibrary().ScannerConfig,
 		AnimeCollection:            ac,
+		FolderMediaSource:          h.App.NfoCustomSource,
 	}
 
 	// Scan the library
@@ -126,6 +127,8 @@ func (h *Handler) HandleScanLocalFiles(c echo.Context) error {
 
 	go h.App.UpdateLibrarySize(true)
 
+	go h.App.OnLibraryScanned(lfs)
+
 	return h.RespondWithData(c, lfs)
 
 }
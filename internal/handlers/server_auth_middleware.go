@@ -10,11 +10,21 @@ import (
 
 func (h *Handler) OptionalAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
+		req := c.Request()
+
+		// API tokens are accepted with or without a server password, their role is enforced by FeaturesMiddleware
+		if token, found := h.resolveApiToken(c); found {
+			c.Set(apiTokenContextKey, token)
+			if h.App.Config.Server.Password != "" {
+				authFailureRateLimits.reset(authFailureRateLimitKey(req))
+			}
+			return next(c)
+		}
+
 		if h.App.Config.Server.Password == "" {
 			return next(c)
 		}
 
-		req := c.Request()
 		authKey := authFailureRateLimitKey(req)
 
 		path := req.URL.Path
@@ -22,7 +32,8 @@ func (h *Handler) OptionalAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc
 
 		// Allow the following paths to be accessed by anyone
 		if path == "/api/v1/status" || // public but restricted
-			path == "/events" { // for server events (auth handled by websocket handler)
+			path == "/events" || // for server events (auth handled by websocket handler)
+			path == calendarFeedPath { // for calendar apps (auth handled by the feed key)
 
 			if path == "/api/v1/status" {
 				// allow status requests by all clients but mark as unauthenticated
@@ -39,6 +50,12 @@ func (h *Handler) OptionalAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc
 			return next(c)
 		}
 
+		// OPDS readers send the server password with HTTP basic authentication
+		if opdsPasswordHash, ok := getOpdsPasswordHash(req); ok && opdsPasswordHash == h.App.ServerPasswordHash {
+			authFailureRateLimits.reset(authKey)
+			return next(c)
+		}
+
 		// Check HMAC token in query parameter
 		token := req.URL.Query().Get("token")
 		if token != "" {
@@ -80,6 +97,11 @@ func (h *Handler) OptionalAuthMiddleware(next echo.HandlerFunc) echo.HandlerFunc
 			return h.RespondWithStatusError(c, http.StatusTooManyRequests, errTooManyAuthenticationAttempts)
 		}
 
+		// Make OPDS readers prompt for the password
+		if isOpdsPath(path) {
+			c.Response().Header().Set("WWW-Authenticate", `Basic realm="Seanime", charset="UTF-8"`)
+		}
+
 		return h.RespondWithStatusError(c, http.StatusUnauthorized, errors.New("UNAUTHENTICATED"))
 	}
 }
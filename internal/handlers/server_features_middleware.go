@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"errors"
+	"seanime/internal/access"
 	"seanime/internal/core"
 	"slices"
 	"strings"
@@ -11,10 +12,17 @@ import (
 
 func (h *Handler) FeaturesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
-		if !h.App.FeatureManager.HasDisabledFeatures() {
+		// Requests made with an API token are restricted by the role of the token
+		role := getContextAccessRole(c)
+
+		if !h.App.FeatureManager.HasDisabledFeaturesForRole(role) {
 			return next(c)
 		}
 
+		isDisabled := func(key core.FeatureKey) bool {
+			return h.App.FeatureManager.IsDisabledForRole(role, key)
+		}
+
 		var ErrFeatureDisabled = errors.New("feature disabled")
 
 		type pathFeatureConfig struct {
@@ -30,95 +38,124 @@ func (h *Handler) FeaturesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		path := c.Request().URL.Path
 		method := strings.ToUpper(c.Request().Method)
 
+		// API tokens without the admin role can only make the changes allowed by roleUpdateRoutes
+		if role != access.RoleAdmin && slices.Contains(UpdateMethods, method) && !isRoleUpdateAllowed(path, isDisabled) {
+			return h.RespondWithError(c, ErrFeatureDisabled)
+		}
+
 		var pathFeatureConfigs = []pathFeatureConfig{
 			// offline mode
-			{"/api/v1/local", h.App.FeatureManager.IsDisabled(core.ManageOfflineMode), UpdateMethods, Empty},
+			{"/api/v1/local", isDisabled(core.ManageOfflineMode), UpdateMethods, Empty},
 			// settings
-			{"/api/v1/start", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/settings", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/torrentstream/settings", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/debrid/settings", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/mediastream/settings", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/report", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/theme", h.App.FeatureManager.IsDisabled(core.UpdateSettings), UpdateMethods, Empty},
-			{"/api/v1/memory", h.App.FeatureManager.IsDisabled(core.UpdateSettings), Empty, Empty},
-			{"/api/v1/filecache", h.App.FeatureManager.IsDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/start", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/torrentstream/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/debrid/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/usenet/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/dlna/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/mediastream/settings", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/report", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/theme", isDisabled(core.UpdateSettings), UpdateMethods, Empty},
+			{"/api/v1/memory", isDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/filecache", isDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/backup", isDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/calendar/settings", isDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/calendar/token", isDisabled(core.UpdateSettings), Empty, Empty},
+			{"/api/v1/settings", isDisabled(core.ViewSettings), Empty, Empty},
+			{"/api/v1/torrentstream/settings", isDisabled(core.ViewSettings), Empty, Empty},
+			{"/api/v1/debrid/settings", isDisabled(core.ViewSettings), Empty, Empty},
+			{"/api/v1/usenet/settings", isDisabled(core.ViewSettings), Empty, Empty},
+			{"/api/v1/dlna/settings", isDisabled(core.ViewSettings), Empty, Empty},
+			{"/api/v1/mediastream/settings", isDisabled(core.ViewSettings), Empty, Empty},
 			// account
-			{"/api/v1/auth", h.App.FeatureManager.IsDisabled(core.ManageAccount), UpdateMethods, Empty},
-			{"/api/v1/mal/auth", h.App.FeatureManager.IsDisabled(core.ManageAccount), UpdateMethods, Empty},
-			{"/api/v1/mal/logout", h.App.FeatureManager.IsDisabled(core.ManageAccount), UpdateMethods, Empty},
+			{"/api/v1/auth", isDisabled(core.ManageAccount), UpdateMethods, Empty},
+			{"/api/v1/mal/auth", isDisabled(core.ManageAccount), UpdateMethods, Empty},
+			{"/api/v1/mal/logout", isDisabled(core.ManageAccount), UpdateMethods, Empty},
+			{"/api/v1/profiles", isDisabled(core.ManageAccount), UpdateMethods, Empty},
+			{"/api/v1/profiles", isDisabled(core.ViewAccount), Empty, Empty},
+			{"/api/v1/anilist/stats", isDisabled(core.ViewAccount), Empty, Empty},
 			// lists
-			{"/api/v1/anilist/list-entry", h.App.FeatureManager.IsDisabled(core.ManageLists), UpdateMethods, Empty},
-			{"/api/v1/library/anime-entry/update-progress", h.App.FeatureManager.IsDisabled(core.ManageLists), UpdateMethods, Empty},
-			{"/api/v1/library/anime-entry/update-repeat", h.App.FeatureManager.IsDisabled(core.ManageLists), UpdateMethods, Empty},
-			{"/api/v1/manga/update-progress", h.App.FeatureManager.IsDisabled(core.ManageLists), UpdateMethods, Empty},
+			{"/api/v1/anilist/list-entry", isDisabled(core.ManageLists), UpdateMethods, Empty},
+			{"/api/v1/library/anime-entry/update-progress", isDisabled(core.ManageLists), UpdateMethods, Empty},
+			{"/api/v1/library/anime-entry/update-repeat", isDisabled(core.ManageLists), UpdateMethods, Empty},
+			{"/api/v1/manga/update-progress", isDisabled(core.ManageLists), UpdateMethods, Empty},
 			// refresh metadata
-			{"/api/v1/anilist/cache-layer/status", h.App.FeatureManager.IsDisabled(core.RefreshMetadata), UpdateMethods, Empty},
-			{"/api/v1/library/scan", h.App.FeatureManager.IsDisabled(core.RefreshMetadata), UpdateMethods, Empty},
-			{"/api/v1/manga/refetch-chapter-containers", h.App.FeatureManager.IsDisabled(core.RefreshMetadata), UpdateMethods, Empty},
+			{"/api/v1/anilist/cache-layer/status", isDisabled(core.RefreshMetadata), UpdateMethods, Empty},
+			{"/api/v1/library/scan", isDisabled(core.RefreshMetadata), UpdateMethods, Empty},
+			{"/api/v1/manga/refetch-chapter-containers", isDisabled(core.RefreshMetadata), UpdateMethods, Empty},
 			// playlists
-			{"/api/v1/playlist", h.App.FeatureManager.IsDisabled(core.ManagePlaylist), UpdateMethods, Empty},
-			{"/api/v1/playback-manager/start-playlist", h.App.FeatureManager.IsDisabled(core.ManagePlaylist), UpdateMethods, Empty},
-			{"/api/v1/playback-manager/playlist-next", h.App.FeatureManager.IsDisabled(core.ManagePlaylist), UpdateMethods, Empty},
-			{"/api/v1/playback-manager/cancel-playlist", h.App.FeatureManager.IsDisabled(core.ManagePlaylist), UpdateMethods, Empty},
+			{"/api/v1/playlist", isDisabled(core.ManagePlaylist), UpdateMethods, Empty},
+			{"/api/v1/playback-manager/start-playlist", isDisabled(core.ManagePlaylist), UpdateMethods, Empty},
+			{"/api/v1/playback-manager/playlist-next", isDisabled(core.ManagePlaylist), UpdateMethods, Empty},
+			{"/api/v1/playback-manager/cancel-playlist", isDisabled(core.ManagePlaylist), UpdateMethods, Empty},
 			// playback
-			{"/api/v1/playback-manager", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), UpdateMethods, []string{"/api/v1/playback-manager/start-playlist", "/api/v1/playback-manager/playlist-next", "/api/v1/playback-manager/cancel-playlist"}},
-			{"/api/v1/media-player/start", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
+			{"/api/v1/playback-manager", isDisabled(core.WatchingLocalAnime), UpdateMethods, []string{"/api/v1/playback-manager/start-playlist", "/api/v1/playback-manager/playlist-next", "/api/v1/playback-manager/cancel-playlist"}},
+			{"/api/v1/media-player/start", isDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
+			{"/api/v1/media-player/upnp", isDisabled(core.UpdateSettings), Empty, Empty},
 			// torrent client / auto downloader
-			{"/api/v1/torrent/search", h.App.FeatureManager.IsDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
-			{"/api/v1/torrent-client", h.App.FeatureManager.IsDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
-			{"/api/v1/download-torrent-file", h.App.FeatureManager.IsDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
-			{"/api/v1/auto-downloader", h.App.FeatureManager.IsDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
-			{"/api/v1/auto-select/profile", h.App.FeatureManager.IsDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/torrent/search", isDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/torrent-client", isDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/download-torrent-file", isDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/auto-downloader", isDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/auto-select/profile", isDisabled(core.ManageAutoDownloader), UpdateMethods, Empty},
+			{"/api/v1/auto-downloader", isDisabled(core.ViewAutoDownloader), Empty, Empty},
 			// onlinestream
-			{"/api/v1/onlinestream", h.App.FeatureManager.IsDisabled(core.OnlineStreaming), UpdateMethods, []string{"/api/v1/onlinestream/search", "/api/v1/onlinestream/manual-mapping", "/api/v1/onlinestream/get-mapping", "/api/v1/onlinestream/remove-mapping"}},
-			{"/api/v1/onlinestream/search", h.App.FeatureManager.IsDisabled(core.ManageMangaSource), UpdateMethods, Empty},
-			{"/api/v1/onlinestream/manual-mapping", h.App.FeatureManager.IsDisabled(core.ManageMangaSource), UpdateMethods, Empty},
-			{"/api/v1/onlinestream/get-mapping", h.App.FeatureManager.IsDisabled(core.ManageMangaSource), UpdateMethods, Empty},
-			{"/api/v1/onlinestream/remove-mapping", h.App.FeatureManager.IsDisabled(core.ManageMangaSource), UpdateMethods, Empty},
+			{"/api/v1/onlinestream", isDisabled(core.OnlineStreaming), UpdateMethods, []string{"/api/v1/onlinestream/search", "/api/v1/onlinestream/manual-mapping", "/api/v1/onlinestream/get-mapping", "/api/v1/onlinestream/remove-mapping"}},
+			{"/api/v1/onlinestream/search", isDisabled(core.ManageMangaSource), UpdateMethods, Empty},
+			{"/api/v1/onlinestream/manual-mapping", isDisabled(core.ManageMangaSource), UpdateMethods, Empty},
+			{"/api/v1/onlinestream/get-mapping", isDisabled(core.ManageMangaSource), UpdateMethods, Empty},
+			{"/api/v1/onlinestream/remove-mapping", isDisabled(core.ManageMangaSource), UpdateMethods, Empty},
 			// custom source
-			{"/api/v1/custom-source", h.App.FeatureManager.IsDisabled(core.ManageExtensions), UpdateMethods, Empty},
+			{"/api/v1/custom-source", isDisabled(core.ManageExtensions), UpdateMethods, Empty},
 			// nakama
-			{"/api/v1/nakama", h.App.FeatureManager.IsDisabled(core.ManageNakama), UpdateMethods, Empty},
+			{"/api/v1/nakama", isDisabled(core.ManageNakama), UpdateMethods, Empty},
 			// open in explorer
-			{"/api/v1/open-in-explorer", h.App.FeatureManager.IsDisabled(core.OpenInExplorer), Empty, Empty},
-			{"/api/v1/library/anime-entry/open-in-explorer", h.App.FeatureManager.IsDisabled(core.OpenInExplorer), UpdateMethods, Empty},
+			{"/api/v1/open-in-explorer", isDisabled(core.OpenInExplorer), Empty, Empty},
+			{"/api/v1/library/anime-entry/open-in-explorer", isDisabled(core.OpenInExplorer), UpdateMethods, Empty},
 			// debrid
-			{"/api/v1/debrid", h.App.FeatureManager.IsDisabled(core.ManageDebrid), UpdateMethods, []string{"/api/v1/debrid/settings", "/api/v1/debrid/torrents/info", "/api/v1/debrid/torrents/file-previews"}},
-			{"/api/v1/debrid/stream", h.App.FeatureManager.IsDisabled(core.DebridStreaming), UpdateMethods, Empty},
+			{"/api/v1/debrid", isDisabled(core.ManageDebrid), UpdateMethods, []string{"/api/v1/debrid/settings", "/api/v1/debrid/torrents/info", "/api/v1/debrid/torrents/file-previews"}},
+			{"/api/v1/debrid/stream", isDisabled(core.DebridStreaming), UpdateMethods, Empty},
+			// usenet
+			{"/api/v1/usenet", isDisabled(core.ManageAutoDownloader), UpdateMethods, []string{"/api/v1/usenet/settings"}},
+			// api tokens
+			{"/api/v1/access", isDisabled(core.ManageAccessTokens), Empty, Empty},
 			// home items
-			{"/api/v1/status/home-items", h.App.FeatureManager.IsDisabled(core.ManageHomeScreen), UpdateMethods, Empty},
+			{"/api/v1/status/home-items", isDisabled(core.ManageHomeScreen), UpdateMethods, Empty},
 			// extensions
-			{"/api/v1/extensions", h.App.FeatureManager.IsDisabled(core.ManageExtensions), UpdateMethods, []string{"/api/v1/extensions/all"}},
-			{"/api/v1/extensions/updates", h.App.FeatureManager.IsDisabled(core.ManageExtensions), Empty, Empty},
-			{"/api/v1/extensions/plugin-settings", h.App.FeatureManager.IsDisabled(core.PluginTray), UpdateMethods, Empty},
-			{"/api/v1/extensions/plugin-permissions", h.App.FeatureManager.IsDisabled(core.PluginTray), UpdateMethods, Empty},
+			{"/api/v1/extensions", isDisabled(core.ManageExtensions), UpdateMethods, []string{"/api/v1/extensions/all"}},
+			{"/api/v1/extensions/updates", isDisabled(core.ManageExtensions), Empty, Empty},
+			{"/api/v1/extensions/plugin-settings", isDisabled(core.PluginTray), UpdateMethods, Empty},
+			{"/api/v1/extensions/plugin-permissions", isDisabled(core.PluginTray), UpdateMethods, Empty},
+			{"/api/v1/extensions", isDisabled(core.ViewExtensions), Empty, []string{"/api/v1/extensions/list/manga-provider", "/api/v1/extensions/list/onlinestream-provider", "/api/v1/extensions/list/anime-torrent-provider", "/api/v1/extensions/list/anime-entry-episode-tabs", "/api/v1/extensions/list/custom-source", "/api/v1/extensions/plugin-settings"}},
 			// proxy
-			{"/api/v1/proxy", h.App.FeatureManager.IsDisabled(core.Proxy), Empty, Empty},
-			{"/api/v1/image-proxy", h.App.FeatureManager.IsDisabled(core.Proxy), Empty, Empty},
+			{"/api/v1/proxy", isDisabled(core.Proxy), Empty, Empty},
+			{"/api/v1/image-proxy", isDisabled(core.Proxy), Empty, Empty},
 			// logs
-			{"/api/v1/log", h.App.FeatureManager.IsDisabled(core.ViewLogs), Empty, Empty},
-			{"/api/v1/logs", h.App.FeatureManager.IsDisabled(core.ViewLogs), Empty, Empty},
-			{"/api/v1/logs", h.App.FeatureManager.IsDisabled(core.UpdateSettings), []string{"DELETE"}, Empty},
+			{"/api/v1/log", isDisabled(core.ViewLogs), Empty, Empty},
+			{"/api/v1/logs", isDisabled(core.ViewLogs), Empty, Empty},
+			{"/api/v1/logs", isDisabled(core.UpdateSettings), []string{"DELETE"}, Empty},
 			// torrent stream
-			{"/api/v1/torrentstream", h.App.FeatureManager.IsDisabled(core.TorrentStreaming), UpdateMethods, []string{"/api/v1/torrentstream/settings"}},
+			{"/api/v1/torrentstream", isDisabled(core.TorrentStreaming), UpdateMethods, []string{"/api/v1/torrentstream/settings"}},
 			// transcode
-			{"/api/v1/mediastream", h.App.FeatureManager.IsDisabled(core.Transcode), UpdateMethods, []string{"/api/v1/mediastream/settings"}},
-			{"/api/v1/directstream", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
-			{"/api/v1/mediastream/file", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), Empty, Empty},
-			{"/api/v1/mediastream", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), Empty, Empty},
+			{"/api/v1/mediastream", isDisabled(core.Transcode), UpdateMethods, []string{"/api/v1/mediastream/settings"}},
+			{"/api/v1/directstream", isDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
+			{"/api/v1/mediastream/file", isDisabled(core.WatchingLocalAnime), Empty, Empty},
+			{"/api/v1/mediastream", isDisabled(core.WatchingLocalAnime), Empty, Empty},
 			// continuity
-			{"/api/v1/continuity", h.App.FeatureManager.IsDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
+			{"/api/v1/continuity/item", isDisabled(core.WatchingLocalAnime), UpdateMethods, Empty},
+			{"/api/v1/continuity/reading", isDisabled(core.Reading), Empty, Empty},
 			// manga
-			{"/api/v1/manga", h.App.FeatureManager.IsDisabled(core.ManageMangaSource), UpdateMethods, []string{"/api/v1/manga/pages", "/api/v1/manga/chapters"}},
-			{"/api/v1/manga", h.App.FeatureManager.IsDisabled(core.Reading), UpdateMethods, Empty},
+			{"/api/v1/manga", isDisabled(core.ManageMangaSource), UpdateMethods, []string{"/api/v1/manga/pages", "/api/v1/manga/chapters", "/api/v1/manga/merged-chapters"}},
+			{"/api/v1/manga", isDisabled(core.Reading), UpdateMethods, Empty},
+			{"/api/v1/opds", isDisabled(core.Reading), Empty, Empty},
 			// manga downloads
-			{"/api/v1/manga/download", h.App.FeatureManager.IsDisabled(core.ManageMangaDownloads), UpdateMethods, Empty},
+			{"/api/v1/manga/download", isDisabled(core.ManageMangaDownloads), UpdateMethods, Empty},
 			// local anime library
-			{"/api/v1/metadata-provider", h.App.FeatureManager.IsDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
-			{"/api/v1/metadata/parent", h.App.FeatureManager.IsDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
-			{"/api/v1/library", h.App.FeatureManager.IsDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, []string{"/api/v1/library/anime-entry/update-progress", "/api/v1/library/anime-entry/update-repeat"}},
-			{"/api/v1/library/explorer", h.App.FeatureManager.IsDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
+			{"/api/v1/metadata-provider", isDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
+			{"/api/v1/metadata/parent", isDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
+			{"/api/v1/library", isDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, []string{"/api/v1/library/anime-entry/update-progress", "/api/v1/library/anime-entry/update-repeat"}},
+			{"/api/v1/library/explorer", isDisabled(core.ManageLocalAnimeLibrary), UpdateMethods, Empty},
+			{"/api/v1/library/scan-summaries", isDisabled(core.ViewScanSummaries), Empty, Empty},
 		}
 
 		pathPrefixes := make([]string, 0, len(pathFeatureConfigs))
@@ -133,7 +170,7 @@ func (h *Handler) FeaturesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			}
 		}
 
-		if h.App.FeatureManager.IsDisabled(core.PushRequests) {
+		if isDisabled(core.PushRequests) {
 			pathPrefixes = append(pathPrefixes, "/api/v1/anilist/list-anime", "/api/v1/anilist/list-manga", "/api/v1/anilist/list-recent-anime", "/api/v1/manga/anilist/list", "/api/v1/announcements")
 			if !slices.ContainsFunc(pathPrefixes, func(i string) bool { return strings.HasPrefix(path, i) }) {
 				if strings.Contains(strings.Join(UpdateMethods, ","), strings.ToUpper(method)) {
@@ -146,3 +183,81 @@ func (h *Handler) FeaturesMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		return next(c)
 	}
 }
+
+type roleUpdateRoute struct {
+	PathStartsWith string
+	// Feature is the feature the role needs, empty for requests that only read data
+	Feature core.FeatureKey
+}
+
+// roleUpdateRoutes are the routes that API tokens without the admin role can call with POST, PUT, PATCH or DELETE.
+// Other update requests are rejected for these roles, so new routes are restricted to admins until they are listed here.
+var roleUpdateRoutes = []roleUpdateRoute{
+	// queries sent with POST
+	{"/api/v1/announcements", ""},
+	{"/api/v1/anilist/collection", ""},
+	{"/api/v1/anilist/list-anime", ""},
+	{"/api/v1/anilist/list-recent-anime", ""},
+	{"/api/v1/manga/anilist/collection", ""},
+	{"/api/v1/manga/anilist/list", ""},
+	{"/api/v1/custom-source/provider/list", ""},
+	{"/api/v1/extensions/all", core.ViewExtensions},
+	{"/api/v1/discord/presence", ""},
+	// lists
+	{"/api/v1/anilist/list-entry", core.ManageLists},
+	{"/api/v1/library/anime-entry/update-progress", core.ManageLists},
+	{"/api/v1/library/anime-entry/update-repeat", core.ManageLists},
+	{"/api/v1/manga/update-progress", core.ManageLists},
+	{"/api/v1/playback-manager/sync-current-progress", core.ManageLists},
+	{"/api/v1/watch-journal/sessions", core.ManageLists},
+	// playlists
+	{"/api/v1/playlist", core.ManagePlaylist},
+	{"/api/v1/playback-manager/start-playlist", core.ManagePlaylist},
+	{"/api/v1/playback-manager/playlist-next", core.ManagePlaylist},
+	{"/api/v1/playback-manager/cancel-playlist", core.ManagePlaylist},
+	// local playback
+	{"/api/v1/playback-manager/play", core.WatchingLocalAnime},
+	{"/api/v1/playback-manager/next-episode", core.WatchingLocalAnime},
+	{"/api/v1/playback-manager/autoplay-next-episode", core.WatchingLocalAnime},
+	{"/api/v1/playback-manager/manual-tracking", core.WatchingLocalAnime},
+	{"/api/v1/media-player/start", core.WatchingLocalAnime},
+	{"/api/v1/directstream/play/localfile", core.WatchingLocalAnime},
+	{"/api/v1/directstream/subs/convert-subs", core.WatchingLocalAnime},
+	{"/api/v1/continuity/item", core.WatchingLocalAnime},
+	// transcoding
+	{"/api/v1/mediastream/request", core.Transcode},
+	{"/api/v1/mediastream/preload", core.Transcode},
+	{"/api/v1/mediastream/shutdown-transcode", core.Transcode},
+	// torrent streaming
+	{"/api/v1/torrentstream/start", core.TorrentStreaming},
+	{"/api/v1/torrentstream/stop", core.TorrentStreaming},
+	{"/api/v1/torrentstream/drop", core.TorrentStreaming},
+	{"/api/v1/torrentstream/torrent-file-previews", core.TorrentStreaming},
+	{"/api/v1/torrentstream/batch-history", core.TorrentStreaming},
+	// debrid streaming
+	{"/api/v1/debrid/stream", core.DebridStreaming},
+	{"/api/v1/debrid/torrents/info", core.DebridStreaming},
+	{"/api/v1/debrid/torrents/file-previews", core.DebridStreaming},
+	// online streaming
+	{"/api/v1/onlinestream/episode-source", core.OnlineStreaming},
+	{"/api/v1/onlinestream/episode-list", core.OnlineStreaming},
+	// reading
+	{"/api/v1/manga/chapters", core.Reading},
+	{"/api/v1/manga/merged-chapters", core.Reading},
+	{"/api/v1/manga/pages", core.Reading},
+	{"/api/v1/manga/download-data", core.Reading},
+	{"/api/v1/continuity/reading", core.Reading},
+}
+
+// isRoleUpdateAllowed returns true if a role can call the route with an update method.
+func isRoleUpdateAllowed(path string, isDisabled func(core.FeatureKey) bool) bool {
+	for _, route := range roleUpdateRoutes {
+		if !strings.HasPrefix(path, route.PathStartsWith) {
+			continue
+		}
+		if route.Feature == "" || !isDisabled(route.Feature) {
+			return true
+		}
+	}
+	return false
+}
@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"runtime"
 	"seanime/internal/database/db"
 	"seanime/internal/database/models"
+	"seanime/internal/library/autodownloader"
+	"seanime/internal/manga"
 	"seanime/internal/torrents/torrent"
 	"seanime/internal/util"
 	"strings"
@@ -33,6 +36,9 @@ func (h *Handler) HandleGetSettings(c echo.Context) error {
 
 	clientSettings := db.CloneSettings(settings)
 	db.VirtualizeSettingsPaths(clientSettings)
+	if !isAdminRequest(c) {
+		clientSettings = clientSettings.Redacted()
+	}
 
 	return h.RespondWithData(c, clientSettings)
 }
@@ -232,6 +238,10 @@ func (h *Handler) HandleSaveSettings(c echo.Context) error {
 		b.Library.LibraryPaths[i] = filepath.ToSlash(filepath.Clean(path))
 	}
 
+	if err := validateMangaSettings(&b.Manga); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
 	b.Library.LibraryPaths = lo.Filter(b.Library.LibraryPaths, func(s string, _ int) bool {
 		if s == "" || util.IsSameDir(s, b.Library.LibraryPath) {
 			return false
@@ -327,6 +337,10 @@ func (h *Handler) HandlePatchSetting(c echo.Context) error {
 		return h.RespondWithError(c, err)
 	}
 
+	if err := validateMangaSettings(nextSettings.Manga); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
 	if err := h.guardStrictSettingsMutation(c, prevSettings, nextSettings.Library, nextSettings.Manga); err != nil {
 		return err
 	}
@@ -353,6 +367,14 @@ func (h *Handler) HandlePatchSetting(c echo.Context) error {
 	return h.RespondWithData(c, status)
 }
 
+// validateMangaSettings checks the page processing settings of downloaded chapters.
+func validateMangaSettings(settings *models.MangaSettings) error {
+	if opts := manga.NewPageProcessingOptions(settings); opts != nil {
+		return opts.Validate()
+	}
+	return nil
+}
+
 // HandleSaveAutoDownloaderSettings
 //
 //	@summary updates the auto-downloader settings.
@@ -368,6 +390,7 @@ func (h *Handler) HandleSaveAutoDownloaderSettings(c echo.Context) error {
 		EnableEnhancedQueries bool   `json:"enableEnhancedQueries"`
 		EnableSeasonCheck     bool   `json:"enableSeasonCheck"`
 		UseDebrid             bool   `json:"useDebrid"`
+		FeedInterval          int    `json:"feedInterval"`
 	}
 
 	var b body
@@ -385,6 +408,9 @@ func (h *Handler) HandleSaveAutoDownloaderSettings(c echo.Context) error {
 	if b.Interval < 15 {
 		return h.RespondWithError(c, errors.New("interval must be at least 15 minutes"))
 	}
+	if b.FeedInterval != 0 && b.FeedInterval < autodownloader.MinFeedInterval {
+		return h.RespondWithError(c, fmt.Errorf("feed interval must be at least %d minutes", autodownloader.MinFeedInterval))
+	}
 
 	autoDownloaderSettings := &models.AutoDownloaderSettings{
 		Provider:              b.Provider,
@@ -394,6 +420,7 @@ func (h *Handler) HandleSaveAutoDownloaderSettings(c echo.Context) error {
 		EnableEnhancedQueries: b.EnableEnhancedQueries,
 		EnableSeasonCheck:     b.EnableSeasonCheck,
 		UseDebrid:             b.UseDebrid,
+		FeedInterval:          b.FeedInterval,
 	}
 
 	currSettings.AutoDownloader = autoDownloaderSettings
@@ -7,6 +7,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"runtime/pprof"
+	"seanime/internal/access"
 	"seanime/internal/constants"
 	"seanime/internal/core"
 	"seanime/internal/database/models"
@@ -40,11 +41,15 @@ type Status struct {
 	MediastreamSettings   *models.MediastreamSettings   `json:"mediastreamSettings"`
 	TorrentstreamSettings *models.TorrentstreamSettings `json:"torrentstreamSettings"`
 	DebridSettings        *models.DebridSettings        `json:"debridSettings"`
+	UsenetSettings        *models.UsenetSettings        `json:"usenetSettings"`
+	DlnaSettings          *models.DlnaSettings          `json:"dlnaSettings"`
 	AnilistClientID       string                        `json:"anilistClientId"`
 	Updating              bool                          `json:"updating"`         // If true, a new screen will be displayed
 	IsDesktopSidecar      bool                          `json:"isDesktopSidecar"` // The server is running as a desktop sidecar
 	FeatureFlags          core.FeatureFlags             `json:"featureFlags"`
 	DisabledFeatures      []core.FeatureKey             `json:"disabledFeatures"`
+	AccessRole            access.Role                   `json:"accessRole"` // Role of the API token used by the client, "admin" otherwise
+	Profile               *models.Profile               `json:"profile"`    // Active profile
 	ServerReady           bool                          `json:"serverReady"`
 	ServerHasPassword     bool                          `json:"serverHasPassword"`
 	ShowChangelogTour     string                        `json:"showChangelogTour"`
@@ -103,6 +108,8 @@ func (h *Handler) NewStatus(c echo.Context) *Status {
 		clientInfoCache.Set(c.Request().UserAgent(), clientInfo)
 	}
 
+	profile, _ := h.App.GetActiveProfile()
+
 	theme, _ = h.App.Database.GetThemeCopy()
 	theme.HomeItems = nil
 
@@ -121,16 +128,26 @@ func (h *Handler) NewStatus(c echo.Context) *Status {
 		MediastreamSettings:   h.App.SecondarySettings.Mediastream,
 		TorrentstreamSettings: h.App.SecondarySettings.Torrentstream,
 		DebridSettings:        h.App.SecondarySettings.Debrid,
+		UsenetSettings:        h.App.SecondarySettings.Usenet,
+		DlnaSettings:          h.App.SecondarySettings.Dlna,
 		AnilistClientID:       h.App.Config.Anilist.ClientID,
 		Updating:              false,
 		IsDesktopSidecar:      h.App.IsDesktopSidecar,
 		FeatureFlags:          h.App.FeatureFlags,
 		ServerReady:           h.App.ServerReady,
 		ServerHasPassword:     h.App.Config.Server.Password != "",
-		DisabledFeatures:      h.App.FeatureManager.DisabledFeatures,
+		DisabledFeatures:      h.App.FeatureManager.GetDisabledFeaturesForRole(getContextAccessRole(c)),
+		AccessRole:            getContextAccessRole(c),
+		Profile:               profile,
 		ShowChangelogTour:     h.App.ShowTour,
 	}
 
+	if !isAdminRequest(c) {
+		status.Settings = status.Settings.Redacted()
+		status.DebridSettings = status.DebridSettings.Redacted()
+		status.UsenetSettings = status.UsenetSettings.Redacted()
+	}
+
 	return status
 }
 
@@ -188,6 +205,7 @@ func (h *Handler) HandleGetLogContent(c echo.Context) error {
 		Content:        contentB,
 		Settings:       h.App.Settings,
 		DebridSettings: h.App.SecondarySettings.Debrid,
+		UsenetSettings: h.App.SecondarySettings.Usenet,
 		Username:       h.App.GetUsername(),
 	})
 
@@ -371,6 +389,7 @@ func (h *Handler) HandleGetLatestLogContent(c echo.Context) error {
 		Content:        contentB,
 		Settings:       h.App.Settings,
 		DebridSettings: h.App.SecondarySettings.Debrid,
+		UsenetSettings: h.App.SecondarySettings.Usenet,
 		Username:       h.App.GetUsername(),
 	})
 
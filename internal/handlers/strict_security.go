@@ -215,7 +215,7 @@ func torrentstreamRootsChanged(prev *models.TorrentstreamSettings, next *models.
 
 func usesExternalMediaPlayer(settings *models.Settings) bool {
 	switch settings.GetMediaPlayer().Default {
-	case "vlc", "mpc-hc", "mpv", "iina":
+	case "vlc", "mpc-hc", "mpv", "iina", "kodi", "upnp":
 		return true
 	default:
 		return false
@@ -12,6 +12,7 @@ import (
 	"seanime/internal/library/autodownloader"
 	"seanime/internal/torrent_clients/torrent_client"
 	torrentrepo "seanime/internal/torrents/torrent"
+	"seanime/internal/usenet/usenet"
 	"seanime/internal/util"
 
 	"github.com/goccy/go-json"
@@ -414,6 +415,7 @@ func (h *Handler) HandleTorrentClientDownload(c echo.Context) error {
 //
 //	@summary adds magnets to the torrent client based on the AutoDownloader item.
 //	@desc This is used to download torrents that were queued by the AutoDownloader.
+//	@desc Queued NZB releases are sent to the Usenet download client instead.
 //	@desc The item will be removed from the queue if the magnet was added successfully.
 //	@desc The AutoDownloader items should be re-fetched after this.
 //	@route /api/v1/torrent-client/rule-magnet [POST]
@@ -440,21 +442,26 @@ func (h *Handler) HandleTorrentClientAddMagnetFromRule(c echo.Context) error {
 	}
 
 	magnetURL := b.MagnetUrl
+	var queuedItem *models.AutoDownloaderItem
 	if magnetURL == "" {
 		item, err := h.App.Database.GetAutoDownloaderItem(b.QueuedItemId)
 		if err != nil {
 			return h.RespondWithError(c, err)
 		}
+		queuedItem = item
 
-		magnetURL, err = resolveAutoDownloaderItemMagnet(item, h.App.TorrentRepository)
-		if err != nil {
-			return h.RespondWithError(c, err)
-		}
+		// NZB releases don't have magnet links
+		if item.NzbUrl == "" {
+			magnetURL, err = resolveAutoDownloaderItemMagnet(item, h.App.TorrentRepository)
+			if err != nil {
+				return h.RespondWithError(c, err)
+			}
 
-		if item.Magnet != magnetURL {
-			item.Magnet = magnetURL
-			if err := h.App.Database.UpdateAutoDownloaderItem(item.ID, item); err != nil {
-				h.App.Logger.Warn().Err(err).Uint("queuedItemId", item.ID).Msg("torrent client: Failed to cache resolved queued magnet")
+			if item.Magnet != magnetURL {
+				item.Magnet = magnetURL
+				if err := h.App.Database.UpdateAutoDownloaderItem(item.ID, item); err != nil {
+					h.App.Logger.Warn().Err(err).Uint("queuedItemId", item.ID).Msg("torrent client: Failed to cache resolved queued magnet")
+				}
 			}
 		}
 	}
@@ -473,6 +480,25 @@ func (h *Handler) HandleTorrentClientAddMagnetFromRule(c echo.Context) error {
 		return err
 	}
 
+	if queuedItem != nil && queuedItem.NzbUrl != "" {
+		if !h.App.UsenetClientRepository.HasProvider() {
+			return h.RespondWithError(c, errors.New("usenet download client not set"))
+		}
+
+		_, err = h.App.UsenetClientRepository.AddNzb(usenet.AddNzbOptions{
+			URL:  queuedItem.NzbUrl,
+			Name: queuedItem.TorrentName,
+		}, rule.Destination, rule.MediaId, queuedItem.Hash)
+		if err != nil {
+			return h.RespondWithError(c, err)
+		}
+
+		// the NZB was added successfully, remove the item from the queue
+		_ = h.App.Database.DeleteAutoDownloaderItem(queuedItem.ID)
+
+		return h.RespondWithData(c, true)
+	}
+
 	// try to start torrent client if it's not running
 	if err := h.guardPrivilegedTorrentClient(c, h.App.Settings); err != nil {
 		return err
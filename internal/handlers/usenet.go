@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"errors"
+	"seanime/internal/api/anilist"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
+	"seanime/internal/torrents/torznab"
+	"seanime/internal/usenet/usenet"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetUsenetSettings
+//
+//	@summary get usenet settings.
+//	@desc This returns the Usenet download client settings.
+//	@returns models.UsenetSettings
+//	@route /api/v1/usenet/settings [GET]
+func (h *Handler) HandleGetUsenetSettings(c echo.Context) error {
+	usenetSettings, found := h.App.Database.GetUsenetSettings()
+	if !found {
+		return h.RespondWithError(c, errors.New("usenet settings not found"))
+	}
+	if !isAdminRequest(c) {
+		usenetSettings = usenetSettings.Redacted()
+	}
+
+	return h.RespondWithData(c, usenetSettings)
+}
+
+// HandleSaveUsenetSettings
+//
+//	@summary save usenet settings.
+//	@desc This saves the Usenet download client settings.
+//	@desc The client should refetch the server status.
+//	@returns models.UsenetSettings
+//	@route /api/v1/usenet/settings [PATCH]
+func (h *Handler) HandleSaveUsenetSettings(c echo.Context) error {
+
+	type body struct {
+		Settings models.UsenetSettings `json:"settings"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	settings, err := h.App.Database.UpsertUsenetSettings(&b.Settings)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	h.App.InitOrRefreshUsenetSettings()
+
+	return h.RespondWithData(c, settings)
+}
+
+// HandleTestUsenetConnection
+//
+//	@summary test the connection to the usenet download client.
+//	@desc This returns the version of the download client if the connection is successful.
+//	@returns string
+//	@route /api/v1/usenet/test [POST]
+func (h *Handler) HandleTestUsenetConnection(c echo.Context) error {
+	version, err := h.App.UsenetClientRepository.TestConnection()
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, version)
+}
+
+// HandleGetUsenetJobs
+//
+//	@summary get jobs from the usenet download client.
+//	@desc This returns the queued and completed jobs of the download client.
+//	@returns []usenet.Job
+//	@route /api/v1/usenet/jobs [GET]
+func (h *Handler) HandleGetUsenetJobs(c echo.Context) error {
+	jobs, err := h.App.UsenetClientRepository.GetJobs()
+	if err != nil {
+		h.App.Logger.Err(err).Msg("usenet: Failed to get jobs")
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, jobs)
+}
+
+// HandleUsenetAddNzbs
+//
+//	@summary add NZB releases to the usenet download client.
+//	@desc This sends NZB releases returned by the Newznab provider to the download client.
+//	@desc Completed jobs are moved to the destination and the library is scanned.
+//	@returns bool
+//	@route /api/v1/usenet/nzbs [POST]
+func (h *Handler) HandleUsenetAddNzbs(c echo.Context) error {
+
+	type body struct {
+		Torrents    []hibiketorrent.AnimeTorrent `json:"torrents"`
+		Media       *anilist.BaseAnime           `json:"media"`
+		Destination string                       `json:"destination"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.guardStrictLocalOnlyAction(c); err != nil {
+		return err
+	}
+
+	if err := h.guardStrictFilesystemPath(c, b.Destination); err != nil {
+		return err
+	}
+
+	if !h.App.UsenetClientRepository.HasProvider() {
+		return h.RespondWithError(c, errors.New("usenet download client not set"))
+	}
+
+	mediaId := 0
+	if b.Media != nil {
+		mediaId = b.Media.ID
+	}
+
+	for _, release := range b.Torrents {
+		var err error
+		if !torznab.IsNzbRelease(&release) {
+			err = errors.New("usenet: Not an NZB release")
+		} else {
+			_, err = h.App.UsenetClientRepository.AddNzb(usenet.AddNzbOptions{
+				URL:  release.DownloadUrl,
+				Name: release.Name,
+			}, b.Destination, mediaId, release.InfoHash)
+		}
+		if err != nil {
+			// If there is only one release, return the error
+			if len(b.Torrents) == 1 {
+				return h.RespondWithError(c, err)
+			}
+			// If there are multiple releases, send an error toast and continue to the next one
+			h.App.Logger.Err(err).Msg("usenet: Failed to add NZB")
+			h.App.WSEventManager.SendEvent(events.ErrorToast, err.Error())
+		}
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleDeleteUsenetJob
+//
+//	@summary remove a job from the usenet download client.
+//	@desc This removes a job from the queue or history of the download client.
+//	@returns bool
+//	@route /api/v1/usenet/job [DELETE]
+func (h *Handler) HandleDeleteUsenetJob(c echo.Context) error {
+
+	type body struct {
+		ID          string `json:"id"`
+		DeleteFiles bool   `json:"deleteFiles"`
+	}
+
+	var b body
+	if err := c.Bind(&b); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if err := h.App.UsenetClientRepository.DeleteJob(b.ID, b.DeleteFiles); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
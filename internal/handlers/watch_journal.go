@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"seanime/internal/database/models"
+	"seanime/internal/watchjournal"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// HandleGetWatchSessions
+//
+//	@summary returns the viewing sessions recorded in the watch journal.
+//	@desc The 'from' and 'to' query parameters are inclusive dates, e.g. "2026-03-01". The 'mediaId' query parameter filters the sessions by media.
+//	@desc Sessions are returned oldest first.
+//	@route /api/v1/watch-journal/sessions [GET]
+//	@returns []models.WatchSession
+func (h *Handler) HandleGetWatchSessions(c echo.Context) error {
+	from, to, err := parseWatchJournalRange(c, time.Time{})
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+
+	sessions, err := h.App.Database.GetWatchSessions(from, to)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	if c.QueryParam("mediaId") != "" {
+		mediaId, err := strconv.Atoi(c.QueryParam("mediaId"))
+		if err != nil {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("invalid media ID"))
+		}
+		filtered := make([]*models.WatchSession, 0)
+		for _, s := range sessions {
+			if s.MediaId == mediaId {
+				filtered = append(filtered, s)
+			}
+		}
+		sessions = filtered
+	}
+
+	return h.RespondWithData(c, sessions)
+}
+
+// HandleDeleteWatchSession
+//
+//	@summary deletes a viewing session from the watch journal.
+//	@param id - int - true - "The session ID"
+//	@route /api/v1/watch-journal/sessions/{id} [DELETE]
+//	@returns bool
+func (h *Handler) HandleDeleteWatchSession(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("invalid session ID"))
+	}
+
+	if err := h.App.Database.DeleteWatchSession(uint(id)); err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, true)
+}
+
+// HandleGetWatchTimeStats
+//
+//	@summary returns the time watched per day, week or month.
+//	@desc The 'period' query parameter is "day" (default), "week" or "month".
+//	@desc The 'from' and 'to' query parameters are inclusive dates. The range defaults to the last 30 days, 12 weeks or 12 months.
+//	@desc Periods without sessions are included.
+//	@route /api/v1/watch-journal/stats/time [GET]
+//	@returns []watchjournal.PeriodStat
+func (h *Handler) HandleGetWatchTimeStats(c echo.Context) error {
+	period := watchjournal.DayPeriod
+	if c.QueryParam("period") != "" {
+		var err error
+		period, err = watchjournal.ParsePeriod(c.QueryParam("period"))
+		if err != nil {
+			return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+		}
+	}
+
+	today := startOfDay(time.Now())
+	defaultFrom := today.AddDate(0, 0, -29)
+	switch period {
+	case watchjournal.WeekPeriod:
+		defaultFrom = today.AddDate(0, 0, -7*11)
+	case watchjournal.MonthPeriod:
+		defaultFrom = today.AddDate(0, -11, 0)
+	}
+
+	from, to, err := parseWatchJournalRange(c, defaultFrom)
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+	if to.IsZero() {
+		to = today.AddDate(0, 0, 1)
+	}
+
+	sessions, err := h.App.Database.GetWatchSessions(from, to)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, watchjournal.TimeByPeriod(sessions, period, from, to))
+}
+
+// HandleGetWatchBreakdownStats
+//
+//	@summary returns the time watched per genre or studio.
+//	@desc The 'by' query parameter is "genre" (default) or "studio".
+//	@desc The 'from' and 'to' query parameters are inclusive dates, all sessions are used by default.
+//	@desc The time of a session counts towards each genre and main studio of the media.
+//	@route /api/v1/watch-journal/stats/breakdown [GET]
+//	@returns []watchjournal.BreakdownItem
+func (h *Handler) HandleGetWatchBreakdownStats(c echo.Context) error {
+	by := c.QueryParam("by")
+	if by == "" {
+		by = "genre"
+	}
+	if by != "genre" && by != "studio" {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("breakdown must be by genre or studio"))
+	}
+
+	from, to, err := parseWatchJournalRange(c, time.Time{})
+	if err != nil {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, err)
+	}
+
+	sessions, err := h.App.Database.GetWatchSessions(from, to)
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	media := h.getWatchJournalMediaInfo(c, sessions, by == "studio")
+
+	if by == "studio" {
+		return h.RespondWithData(c, watchjournal.ByStudio(sessions, media))
+	}
+	return h.RespondWithData(c, watchjournal.ByGenre(sessions, media))
+}
+
+// HandleGetWatchRewatchStats
+//
+//	@summary returns the media whose episodes were completed more than once.
+//	@route /api/v1/watch-journal/stats/rewatches [GET]
+//	@returns []watchjournal.MediaRewatch
+func (h *Handler) HandleGetWatchRewatchStats(c echo.Context) error {
+	sessions, err := h.App.Database.GetWatchSessions(time.Time{}, time.Time{})
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, watchjournal.Rewatches(sessions))
+}
+
+// HandleGetWatchStreakStats
+//
+//	@summary returns the current and longest streaks of consecutive days with a viewing session.
+//	@route /api/v1/watch-journal/stats/streaks [GET]
+//	@returns watchjournal.Streaks
+func (h *Handler) HandleGetWatchStreakStats(c echo.Context) error {
+	sessions, err := h.App.Database.GetWatchSessions(time.Time{}, time.Time{})
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	return h.RespondWithData(c, watchjournal.GetStreaks(sessions, time.Now()))
+}
+
+// HandleGetWatchYearSummary
+//
+//	@summary returns the summary of the viewing sessions of a year.
+//	@param year - int - true - "The year"
+//	@route /api/v1/watch-journal/stats/year/{year} [GET]
+//	@returns watchjournal.YearSummary
+func (h *Handler) HandleGetWatchYearSummary(c echo.Context) error {
+	year, err := strconv.Atoi(c.Param("year"))
+	if err != nil || year < 1 || year > 9999 {
+		return h.RespondWithStatusError(c, http.StatusBadRequest, errors.New("invalid year"))
+	}
+
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+	sessions, err := h.App.Database.GetWatchSessions(from, from.AddDate(1, 0, 0))
+	if err != nil {
+		return h.RespondWithError(c, err)
+	}
+
+	media := h.getWatchJournalMediaInfo(c, sessions, false)
+
+	return h.RespondWithData(c, watchjournal.NewYearSummary(year, sessions, media, time.Local))
+}
+
+// parseWatchJournalRange parses the inclusive 'from' and 'to' dates of the query and returns the range [from, to).
+// A missing 'to' date returns a zero time.
+func parseWatchJournalRange(c echo.Context, defaultFrom time.Time) (from time.Time, to time.Time, err error) {
+	from = defaultFrom
+	if v := c.QueryParam("from"); v != "" {
+		from, err = time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'from' date, expected YYYY-MM-DD")
+		}
+	}
+	if v := c.QueryParam("to"); v != "" {
+		to, err = time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid 'to' date, expected YYYY-MM-DD")
+		}
+		to = to.AddDate(0, 0, 1)
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, errors.New("'from' must not be after 'to'")
+	}
+	return from, to, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+// getWatchJournalMediaInfo returns the genres and studios of the media of the sessions.
+// Genres come from the anime collection when possible, the details of the other media are fetched.
+// Media whose details cannot be fetched have no genres and studios.
+func (h *Handler) getWatchJournalMediaInfo(c echo.Context, sessions []*models.WatchSession, withStudios bool) map[int]*watchjournal.MediaInfo {
+	ret := make(map[int]*watchjournal.MediaInfo)
+
+	animeCollection, _ := h.App.GetAnimeCollection(false)
+
+	for _, s := range sessions {
+		if _, ok := ret[s.MediaId]; ok {
+			continue
+		}
+
+		if !withStudios {
+			if media, found := animeCollection.FindAnime(s.MediaId); found {
+				ret[s.MediaId] = &watchjournal.MediaInfo{Genres: derefStrings(media.GetGenres())}
+				continue
+			}
+		}
+
+		details, ok := detailsCache.Get(s.MediaId)
+		if !ok {
+			if c.Request().Context().Err() != nil {
+				break
+			}
+			var err error
+			details, err = h.App.AnilistPlatformRef.Get().GetAnimeDetails(c.Request().Context(), s.MediaId)
+			if err != nil || details == nil {
+				h.App.Logger.Warn().Err(err).Int("mediaId", s.MediaId).Msg("watch journal: Failed to get media details")
+				ret[s.MediaId] = &watchjournal.MediaInfo{}
+				continue
+			}
+			detailsCache.Set(s.MediaId, details)
+		}
+
+		info := &watchjournal.MediaInfo{Genres: derefStrings(details.GetGenres())}
+		for _, studio := range details.GetStudios().GetNodes() {
+			info.Studios = append(info.Studios, studio.GetName())
+		}
+		ret[s.MediaId] = info
+	}
+
+	return ret
+}
+
+func derefStrings(values []*string) []string {
+	ret := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != nil {
+			ret = append(ret, *v)
+		}
+	}
+	return ret
+}
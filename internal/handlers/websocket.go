@@ -26,7 +26,7 @@ func (h *Handler) webSocketEventHandler(c echo.Context) error {
 	// When a server password is set, require auth via query param
 	if h.App.Config.Server.Password != "" {
 		token := c.QueryParam("token")
-		if token != h.App.ServerPasswordHash {
+		if _, isApiToken := h.App.AccessManager.Resolve(token); token != h.App.ServerPasswordHash && !isApiToken {
 			authKey := authFailureRateLimitKey(req)
 			if !authFailureRateLimits.allow(authKey, maxAuthFailuresPerWindow, authFailureWindow) {
 				return c.JSON(http.StatusTooManyRequests, NewErrorResponse(errTooManyAuthenticationAttempts))
@@ -0,0 +1,161 @@
+// Package icalendar writes iCalendar (RFC 5545) feeds that calendar apps can subscribe to.
+package icalendar
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// Lines longer than this number of octets are folded
+	maxLineLength = 75
+	timeFormat    = "20060102T150405Z"
+)
+
+type (
+	Calendar struct {
+		// ProductID identifies the application that created the calendar, e.g. "-//Seanime//Schedule//EN"
+		ProductID string
+		// Name is displayed by calendar apps
+		Name string
+		// RefreshInterval is how often calendar apps should fetch the feed, not sent if 0
+		RefreshInterval time.Duration
+		// Stamp is the time the calendar was created
+		Stamp  time.Time
+		Events []*Event
+	}
+
+	Event struct {
+		// UID must be the same for the same event across updates of the feed
+		UID         string
+		Start       time.Time
+		Duration    time.Duration
+		Summary     string
+		Description string
+		URL         string
+		Categories  []string
+	}
+)
+
+// Write writes the calendar in the iCalendar format.
+func (c *Calendar) Write(w io.Writer) error {
+	lw := &lineWriter{w: bufio.NewWriter(w)}
+
+	lw.line("BEGIN:VCALENDAR")
+	lw.line("VERSION:2.0")
+	lw.line("PRODID:" + escapeText(c.ProductID))
+	lw.line("CALSCALE:GREGORIAN")
+	lw.line("METHOD:PUBLISH")
+	if c.Name != "" {
+		lw.line("X-WR-CALNAME:" + escapeText(c.Name))
+	}
+	if c.RefreshInterval > 0 {
+		refresh := formatDuration(c.RefreshInterval)
+		lw.line("REFRESH-INTERVAL;VALUE=DURATION:" + refresh)
+		lw.line("X-PUBLISHED-TTL:" + refresh)
+	}
+
+	for _, event := range c.Events {
+		lw.line("BEGIN:VEVENT")
+		lw.line("UID:" + escapeText(event.UID))
+		lw.line("DTSTAMP:" + c.Stamp.UTC().Format(timeFormat))
+		lw.line("DTSTART:" + event.Start.UTC().Format(timeFormat))
+		if event.Duration > 0 {
+			lw.line("DURATION:" + formatDuration(event.Duration))
+		}
+		lw.line("SUMMARY:" + escapeText(event.Summary))
+		if event.Description != "" {
+			lw.line("DESCRIPTION:" + escapeText(event.Description))
+		}
+		if event.URL != "" {
+			lw.line("URL;VALUE=URI:" + event.URL)
+		}
+		if len(event.Categories) > 0 {
+			categories := make([]string, 0, len(event.Categories))
+			for _, category := range event.Categories {
+				categories = append(categories, escapeText(category))
+			}
+			lw.line("CATEGORIES:" + strings.Join(categories, ","))
+		}
+		lw.line("END:VEVENT")
+	}
+
+	lw.line("END:VCALENDAR")
+
+	if lw.err != nil {
+		return lw.err
+	}
+	return lw.w.Flush()
+}
+
+// lineWriter writes content lines terminated by CRLF and folds the long ones.
+type lineWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (lw *lineWriter) line(s string) {
+	if lw.err != nil {
+		return
+	}
+
+	// The continuation lines start with a space, which counts towards their length
+	limit := maxLineLength
+	for len(s) > limit {
+		// Do not split UTF-8 sequences
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		if _, lw.err = lw.w.WriteString(s[:cut] + "\r\n "); lw.err != nil {
+			return
+		}
+		s = s[cut:]
+		limit = maxLineLength - 1
+	}
+	_, lw.err = lw.w.WriteString(s + "\r\n")
+}
+
+var textEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\r\n", `\n`,
+	"\n", `\n`,
+)
+
+// escapeText escapes a TEXT value.
+func escapeText(s string) string {
+	return textEscaper.Replace(s)
+}
+
+// formatDuration formats a positive duration as a DURATION value, e.g. PT1H30M.
+func formatDuration(d time.Duration) string {
+	d = d.Truncate(time.Second)
+	var sb strings.Builder
+	sb.WriteString("P")
+	if days := int(d / (24 * time.Hour)); days > 0 {
+		sb.WriteString(strconv.Itoa(days) + "D")
+		d -= time.Duration(days) * 24 * time.Hour
+	}
+	if d <= 0 {
+		return sb.String()
+	}
+	sb.WriteString("T")
+	if hours := int(d / time.Hour); hours > 0 {
+		sb.WriteString(strconv.Itoa(hours) + "H")
+		d -= time.Duration(hours) * time.Hour
+	}
+	if minutes := int(d / time.Minute); minutes > 0 {
+		sb.WriteString(strconv.Itoa(minutes) + "M")
+		d -= time.Duration(minutes) * time.Minute
+	}
+	if seconds := int(d / time.Second); seconds > 0 {
+		sb.WriteString(strconv.Itoa(seconds) + "S")
+	}
+	return sb.String()
+}
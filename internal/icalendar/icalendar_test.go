@@ -0,0 +1,59 @@
+package icalendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCalendarWrite(t *testing.T) {
+	start := time.Date(2026, 10, 19, 15, 30, 0, 0, time.FixedZone("JST", 9*3600))
+	calendar := &Calendar{
+		ProductID:       "-//Seanime//Schedule//EN",
+		Name:            "Seanime",
+		RefreshInterval: time.Hour,
+		Stamp:           time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC),
+		Events: []*Event{{
+			UID:         "1-2@seanime",
+			Start:       start,
+			Duration:    24 * time.Minute,
+			Summary:     "Frieren; Beyond Journey's End, Episode 2",
+			Description: "Line 1\nLine 2 \\ " + strings.Repeat("葬送", 30),
+			URL:         "https://anilist.co/anime/1",
+			Categories:  []string{"Watching", "A,B"},
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, calendar.Write(&buf))
+	out := buf.String()
+
+	require.True(t, strings.HasPrefix(out, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	require.True(t, strings.HasSuffix(out, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	require.Contains(t, out, "REFRESH-INTERVAL;VALUE=DURATION:PT1H\r\n")
+	// Times are in UTC
+	require.Contains(t, out, "DTSTART:20261019T063000Z\r\n")
+	require.Contains(t, out, "DTSTAMP:20261019T000000Z\r\n")
+	require.Contains(t, out, "DURATION:PT24M\r\n")
+	require.Contains(t, out, `SUMMARY:Frieren\; Beyond Journey's End\, Episode 2`+"\r\n")
+	require.Contains(t, out, `CATEGORIES:Watching,A\,B`+"\r\n")
+
+	// Long lines are folded without splitting characters
+	for _, line := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		require.LessOrEqual(t, len(line), maxLineLength, line)
+		require.True(t, utf8.ValidString(line), line)
+	}
+	unfolded := strings.ReplaceAll(out, "\r\n ", "")
+	require.Contains(t, unfolded, `DESCRIPTION:Line 1\nLine 2 \\ `+strings.Repeat("葬送", 30)+"\r\n")
+}
+
+func TestFormatDuration(t *testing.T) {
+	require.Equal(t, "PT24M", formatDuration(24*time.Minute))
+	require.Equal(t, "PT1H30M", formatDuration(90*time.Minute))
+	require.Equal(t, "P1D", formatDuration(24*time.Hour))
+	require.Equal(t, "P2DT3H5S", formatDuration(51*time.Hour+5*time.Second))
+}
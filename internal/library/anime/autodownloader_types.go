@@ -89,6 +89,15 @@ type (
 		Providers []string `json:"providers"`
 	}
 
+	// AutoDownloaderFeed is a user-defined RSS/Atom feed polled for new releases.
+	// Items from feeds are matched against every enabled rule, regardless of the rule's providers.
+	AutoDownloaderFeed struct {
+		DbID    uint   `json:"dbId"`
+		Name    string `json:"name"`
+		URL     string `json:"url"`
+		Enabled bool   `json:"enabled"`
+	}
+
 	AutoDownloaderCondition struct {
 		ID      string                                `json:"id"`
 		Term    string                                `json:"term"`
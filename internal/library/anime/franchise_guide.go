@@ -0,0 +1,326 @@
+package anime
+
+import (
+	"cmp"
+	"seanime/internal/api/anilist"
+	"slices"
+)
+
+type (
+	// FranchiseGuideOrder is the order of the main story in a FranchiseGuide.
+	FranchiseGuideOrder string
+	// FranchiseGuideEntryKind labels an entry of a FranchiseGuide.
+	FranchiseGuideEntryKind string
+)
+
+const (
+	// FranchiseGuideReleaseOrder sorts all the entries by release date.
+	FranchiseGuideReleaseOrder FranchiseGuideOrder = "release"
+	// FranchiseGuideChronologicalOrder follows the prequel/sequel chain of the story.
+	// Optional entries come right after the entry they are related to.
+	FranchiseGuideChronologicalOrder FranchiseGuideOrder = "chronological"
+)
+
+const (
+	FranchiseGuideMainEntry        FranchiseGuideEntryKind = "main"
+	FranchiseGuideMovieEntry       FranchiseGuideEntryKind = "movie"
+	FranchiseGuideOvaEntry         FranchiseGuideEntryKind = "ova"
+	FranchiseGuideSpecialEntry     FranchiseGuideEntryKind = "special"
+	FranchiseGuideSideStoryEntry   FranchiseGuideEntryKind = "side_story"
+	FranchiseGuideSpinOffEntry     FranchiseGuideEntryKind = "spin_off"
+	FranchiseGuideAlternativeEntry FranchiseGuideEntryKind = "alternative"
+	FranchiseGuideRecapEntry       FranchiseGuideEntryKind = "recap"
+)
+
+// franchiseGuideOptionalRelations are the relations of the main story entries that are listed as optional entries.
+var franchiseGuideOptionalRelations = []anilist.MediaRelation{
+	anilist.MediaRelationSideStory,
+	anilist.MediaRelationSpinOff,
+	anilist.MediaRelationAlternative,
+	anilist.MediaRelationSummary,
+	anilist.MediaRelationCompilation,
+}
+
+type (
+	// FranchiseGuide is the watch order of a franchise.
+	// The main story is made of the media linked by prequel/sequel relations, see anilist.FetchMediaTree.
+	FranchiseGuide struct {
+		Order   FranchiseGuideOrder    `json:"order"`
+		Entries []*FranchiseGuideEntry `json:"entries"`
+		// NextMediaId is the first main story entry that is not watched, 0 if the main story is watched
+		NextMediaId           int `json:"nextMediaId"`
+		MainEntryCount        int `json:"mainEntryCount"`
+		WatchedMainEntryCount int `json:"watchedMainEntryCount"`
+	}
+
+	FranchiseGuideEntry struct {
+		Media    *anilist.BaseAnime      `json:"media"`
+		Kind     FranchiseGuideEntryKind `json:"kind"`
+		Optional bool                    `json:"optional"`
+		// RelatedMediaId is the main story entry an optional entry is related to
+		RelatedMediaId int                   `json:"relatedMediaId,omitempty"`
+		Relation       anilist.MediaRelation `json:"relation,omitempty"`
+		// InLibrary is true if the library has main episodes of the media
+		InLibrary         bool                     `json:"inLibrary"`
+		LocalEpisodeCount int                      `json:"localEpisodeCount"`
+		ListStatus        *anilist.MediaListStatus `json:"listStatus,omitempty"`
+		Progress          int                      `json:"progress"`
+		Watched           bool                     `json:"watched"`
+	}
+
+	NewFranchiseGuideOptions struct {
+		// Tree is the main story, fetched with anilist.FetchMediaTreeAll
+		Tree            *anilist.CompleteAnimeRelationTree
+		Order           FranchiseGuideOrder
+		AnimeCollection *anilist.AnimeCollection
+		LocalFiles      []*LocalFile
+	}
+)
+
+func (o FranchiseGuideOrder) IsValid() bool {
+	return o == FranchiseGuideReleaseOrder || o == FranchiseGuideChronologicalOrder
+}
+
+// FranchiseGuideRoot returns the ID of the media whose relation tree should be used to build the guide of the franchise of the media.
+// Side stories, spin-offs and recaps usually have no prequel or sequel, the guide is built from their parent instead.
+func FranchiseGuideRoot(media *anilist.CompleteAnime) int {
+	parentId := 0
+	for _, edge := range media.GetRelations().GetEdges() {
+		if edge.GetRelationType() == nil || edge.GetNode() == nil {
+			continue
+		}
+		switch *edge.GetRelationType() {
+		case anilist.MediaRelationPrequel, anilist.MediaRelationSequel:
+			if edge.IsBroadRelationFormat() {
+				return media.GetID()
+			}
+		case anilist.MediaRelationParent:
+			if parentId == 0 && edge.IsBroadRelationFormat() {
+				parentId = edge.GetNode().GetID()
+			}
+		}
+	}
+	if parentId != 0 {
+		return parentId
+	}
+	return media.GetID()
+}
+
+// NewFranchiseGuide returns the watch order of the franchise in the tree.
+// Side stories, spin-offs, alternative versions and recaps related to the main story are labeled and optional.
+// Entries are annotated with the local library and the list entries of the collection.
+func NewFranchiseGuide(opts *NewFranchiseGuideOptions) *FranchiseGuide {
+	ret := &FranchiseGuide{
+		Order:   opts.Order,
+		Entries: make([]*FranchiseGuideEntry, 0),
+	}
+	if opts.Tree == nil {
+		return ret
+	}
+
+	nodes := make(map[int]*anilist.CompleteAnime)
+	bases := make(map[int]*anilist.BaseAnime)
+	opts.Tree.Range(func(id int, media *anilist.CompleteAnime) bool {
+		if media != nil {
+			nodes[id] = media
+			bases[id] = media.ToBaseAnime()
+		}
+		return true
+	})
+
+	mainStory := sortFranchiseMainStory(nodes, bases, opts.Order)
+
+	// Optional entries, related to the first main story entry that lists them
+	optional := make(map[int][]*FranchiseGuideEntry)
+	seen := make(map[int]struct{})
+	for _, media := range mainStory {
+		for _, edge := range media.GetRelations().GetEdges() {
+			if edge.GetRelationType() == nil || !slices.Contains(franchiseGuideOptionalRelations, *edge.GetRelationType()) {
+				continue
+			}
+			if !edge.IsBroadRelationFormat() || edge.GetNode().GetStatus() == nil || *edge.GetNode().GetStatus() == anilist.MediaStatusNotYetReleased {
+				continue
+			}
+			id := edge.GetNode().GetID()
+			if _, ok := nodes[id]; ok {
+				continue
+			}
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			optional[media.GetID()] = append(optional[media.GetID()], &FranchiseGuideEntry{
+				Media:          edge.GetNode(),
+				Kind:           franchiseGuideEntryKind(*edge.GetRelationType(), edge.GetNode().GetFormat()),
+				Optional:       true,
+				RelatedMediaId: media.GetID(),
+				Relation:       *edge.GetRelationType(),
+			})
+		}
+	}
+
+	for _, media := range mainStory {
+		ret.Entries = append(ret.Entries, &FranchiseGuideEntry{
+			Media: bases[media.GetID()],
+			Kind:  FranchiseGuideMainEntry,
+		})
+		entries := optional[media.GetID()]
+		slices.SortStableFunc(entries, func(a, b *FranchiseGuideEntry) int {
+			return compareFranchiseGuideMedia(a.Media, b.Media)
+		})
+		ret.Entries = append(ret.Entries, entries...)
+	}
+
+	if opts.Order == FranchiseGuideReleaseOrder {
+		// Main story entries come first when released on the same date
+		slices.SortStableFunc(ret.Entries, func(a, b *FranchiseGuideEntry) int {
+			return cmp.Compare(mediaStartDate(a.Media), mediaStartDate(b.Media))
+		})
+	}
+
+	lfw := NewLocalFileWrapper(opts.LocalFiles)
+	for _, entry := range ret.Entries {
+		if lfEntry, ok := lfw.GetLocalEntryById(entry.Media.GetID()); ok {
+			mainLfs, _ := lfEntry.GetMainLocalFiles()
+			entry.LocalEpisodeCount = len(mainLfs)
+			entry.InLibrary = entry.LocalEpisodeCount > 0
+		}
+		if listEntry, ok := opts.AnimeCollection.GetListEntryFromAnimeId(entry.Media.GetID()); ok {
+			entry.ListStatus = listEntry.GetStatus()
+			entry.Progress = listEntry.GetProgressSafe()
+			entry.Watched = isFranchiseGuideEntryWatched(entry)
+		}
+
+		if entry.Optional {
+			continue
+		}
+		ret.MainEntryCount++
+		if entry.Watched {
+			ret.WatchedMainEntryCount++
+		} else if ret.NextMediaId == 0 {
+			ret.NextMediaId = entry.Media.GetID()
+		}
+	}
+
+	return ret
+}
+
+// sortFranchiseMainStory sorts the media of the main story.
+// The chronological order follows the prequel/sequel relations, media that are not linked together are sorted by release date.
+func sortFranchiseMainStory(nodes map[int]*anilist.CompleteAnime, bases map[int]*anilist.BaseAnime, order FranchiseGuideOrder) []*anilist.CompleteAnime {
+	ret := make([]*anilist.CompleteAnime, 0, len(nodes))
+	for _, media := range nodes {
+		ret = append(ret, media)
+	}
+	slices.SortFunc(ret, func(a, b *anilist.CompleteAnime) int {
+		return compareFranchiseGuideMedia(bases[a.GetID()], bases[b.GetID()])
+	})
+	if order != FranchiseGuideChronologicalOrder {
+		return ret
+	}
+
+	// Prequels of each media in the tree
+	prequels := make(map[int]map[int]struct{}, len(nodes))
+	addPrequel := func(id int, prequelId int) {
+		if _, ok := nodes[id]; !ok || id == prequelId {
+			return
+		}
+		if _, ok := nodes[prequelId]; !ok {
+			return
+		}
+		if prequels[id] == nil {
+			prequels[id] = make(map[int]struct{})
+		}
+		prequels[id][prequelId] = struct{}{}
+	}
+	for _, media := range ret {
+		for _, edge := range media.GetRelations().GetEdges() {
+			if edge.GetRelationType() == nil || edge.GetNode() == nil {
+				continue
+			}
+			switch *edge.GetRelationType() {
+			case anilist.MediaRelationPrequel:
+				addPrequel(media.GetID(), edge.GetNode().GetID())
+			case anilist.MediaRelationSequel:
+				addPrequel(edge.GetNode().GetID(), media.GetID())
+			}
+		}
+	}
+
+	// Pick the earliest media whose prequels are all sorted.
+	// Cycles in the relations are broken by picking the earliest media left.
+	sorted := make([]*anilist.CompleteAnime, 0, len(ret))
+	done := make(map[int]struct{}, len(ret))
+	for len(sorted) < len(ret) {
+		var next *anilist.CompleteAnime
+		for _, media := range ret {
+			if _, ok := done[media.GetID()]; ok {
+				continue
+			}
+			ready := true
+			for prequelId := range prequels[media.GetID()] {
+				if _, ok := done[prequelId]; !ok {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = media
+				break
+			}
+		}
+		if next == nil {
+			for _, media := range ret {
+				if _, ok := done[media.GetID()]; !ok {
+					next = media
+					break
+				}
+			}
+		}
+		done[next.GetID()] = struct{}{}
+		sorted = append(sorted, next)
+	}
+
+	return sorted
+}
+
+func compareFranchiseGuideMedia(a, b *anilist.BaseAnime) int {
+	return cmp.Or(
+		cmp.Compare(mediaStartDate(a), mediaStartDate(b)),
+		cmp.Compare(a.GetID(), b.GetID()),
+	)
+}
+
+func franchiseGuideEntryKind(relation anilist.MediaRelation, format *anilist.MediaFormat) FranchiseGuideEntryKind {
+	switch relation {
+	case anilist.MediaRelationSummary, anilist.MediaRelationCompilation:
+		return FranchiseGuideRecapEntry
+	case anilist.MediaRelationSpinOff:
+		return FranchiseGuideSpinOffEntry
+	case anilist.MediaRelationAlternative:
+		return FranchiseGuideAlternativeEntry
+	}
+
+	if format != nil {
+		switch *format {
+		case anilist.MediaFormatMovie:
+			return FranchiseGuideMovieEntry
+		case anilist.MediaFormatOva:
+			return FranchiseGuideOvaEntry
+		case anilist.MediaFormatSpecial:
+			return FranchiseGuideSpecialEntry
+		}
+	}
+	return FranchiseGuideSideStoryEntry
+}
+
+func isFranchiseGuideEntryWatched(entry *FranchiseGuideEntry) bool {
+	if entry.ListStatus != nil {
+		switch *entry.ListStatus {
+		case anilist.MediaListStatusCompleted, anilist.MediaListStatusRepeating:
+			return true
+		}
+	}
+	total := entry.Media.GetTotalEpisodeCount()
+	return total > 0 && entry.Progress >= total
+}
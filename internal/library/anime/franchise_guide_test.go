@@ -0,0 +1,162 @@
+package anime_test
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/library/anime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newFranchiseTestNode(id int, format anilist.MediaFormat, year, month int) *anilist.BaseAnime {
+	return &anilist.BaseAnime{
+		ID:        id,
+		Format:    new(format),
+		Status:    new(anilist.MediaStatusFinished),
+		Episodes:  new(12),
+		StartDate: &anilist.BaseAnime_StartDate{Year: new(year), Month: new(month)},
+	}
+}
+
+func newFranchiseTestEdge(relation anilist.MediaRelation, node *anilist.BaseAnime) *anilist.CompleteAnime_Relations_Edges {
+	return &anilist.CompleteAnime_Relations_Edges{RelationType: new(relation), Node: node}
+}
+
+func newFranchiseTestMedia(node *anilist.BaseAnime, edges ...*anilist.CompleteAnime_Relations_Edges) *anilist.CompleteAnime {
+	return &anilist.CompleteAnime{
+		ID:        node.ID,
+		Format:    node.Format,
+		Status:    node.Status,
+		Episodes:  node.Episodes,
+		StartDate: &anilist.CompleteAnime_StartDate{Year: node.StartDate.Year, Month: node.StartDate.Month},
+		Relations: &anilist.CompleteAnime_Relations{Edges: edges},
+	}
+}
+
+func TestNewFranchiseGuide(t *testing.T) {
+	season1 := newFranchiseTestNode(1, anilist.MediaFormatTv, 2013, 4)
+	season2 := newFranchiseTestNode(2, anilist.MediaFormatTv, 2017, 4)
+	// Takes place between the two seasons but was released after the second one
+	movie := newFranchiseTestNode(3, anilist.MediaFormatMovie, 2018, 1)
+	movie.Episodes = new(1)
+	ova := newFranchiseTestNode(10, anilist.MediaFormatOva, 2014, 1)
+	recap := newFranchiseTestNode(11, anilist.MediaFormatSpecial, 2017, 1)
+	spinOff := newFranchiseTestNode(12, anilist.MediaFormatOna, 2020, 7)
+	manga := newFranchiseTestNode(20, anilist.MediaFormatManga, 2010, 1)
+	upcoming := newFranchiseTestNode(13, anilist.MediaFormatOva, 2027, 1)
+	upcoming.Status = new(anilist.MediaStatusNotYetReleased)
+
+	tree := anilist.NewCompleteAnimeRelationTree()
+	tree.Set(1, newFranchiseTestMedia(season1,
+		newFranchiseTestEdge(anilist.MediaRelationSequel, movie),
+		newFranchiseTestEdge(anilist.MediaRelationSpinOff, spinOff),
+		newFranchiseTestEdge(anilist.MediaRelationSideStory, ova),
+		newFranchiseTestEdge(anilist.MediaRelationSource, manga),
+		newFranchiseTestEdge(anilist.MediaRelationSideStory, upcoming),
+	))
+	tree.Set(3, newFranchiseTestMedia(movie,
+		newFranchiseTestEdge(anilist.MediaRelationPrequel, season1),
+		newFranchiseTestEdge(anilist.MediaRelationSequel, season2),
+	))
+	tree.Set(2, newFranchiseTestMedia(season2,
+		newFranchiseTestEdge(anilist.MediaRelationPrequel, movie),
+		newFranchiseTestEdge(anilist.MediaRelationSummary, recap),
+		newFranchiseTestEdge(anilist.MediaRelationSideStory, ova),
+	))
+
+	animeCollection := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+				Entries: []*anilist.AnimeListEntry{
+					{Status: new(anilist.MediaListStatusCompleted), Progress: new(12), Media: season1},
+					{Status: new(anilist.MediaListStatusCurrent), Progress: new(1), Media: movie},
+					{Status: new(anilist.MediaListStatusCurrent), Progress: new(3), Media: season2},
+				},
+			}},
+		},
+	}
+	localFiles := []*anime.LocalFile{
+		{MediaId: 2, Metadata: &anime.LocalFileMetadata{Episode: 4, Type: anime.LocalFileTypeMain}},
+		{MediaId: 2, Metadata: &anime.LocalFileMetadata{Episode: 5, Type: anime.LocalFileTypeMain}},
+		{MediaId: 2, Metadata: &anime.LocalFileMetadata{Episode: 0, Type: anime.LocalFileTypeSpecial}},
+		{MediaId: 10, Metadata: &anime.LocalFileMetadata{Episode: 1, Type: anime.LocalFileTypeMain}},
+	}
+
+	entryIds := func(guide *anime.FranchiseGuide) []int {
+		ret := make([]int, 0, len(guide.Entries))
+		for _, e := range guide.Entries {
+			ret = append(ret, e.Media.GetID())
+		}
+		return ret
+	}
+
+	t.Run("chronological", func(t *testing.T) {
+		guide := anime.NewFranchiseGuide(&anime.NewFranchiseGuideOptions{
+			Tree:            tree,
+			Order:           anime.FranchiseGuideChronologicalOrder,
+			AnimeCollection: animeCollection,
+			LocalFiles:      localFiles,
+		})
+
+		require.Equal(t, []int{1, 10, 12, 3, 2, 11}, entryIds(guide))
+		require.Equal(t, 2, guide.NextMediaId)
+		require.Equal(t, 3, guide.MainEntryCount)
+		require.Equal(t, 2, guide.WatchedMainEntryCount)
+
+		byId := make(map[int]*anime.FranchiseGuideEntry)
+		for _, e := range guide.Entries {
+			byId[e.Media.GetID()] = e
+		}
+
+		require.Equal(t, anime.FranchiseGuideMainEntry, byId[3].Kind)
+		require.False(t, byId[3].Optional)
+		require.True(t, byId[3].Watched)
+
+		require.Equal(t, anime.FranchiseGuideOvaEntry, byId[10].Kind)
+		require.True(t, byId[10].Optional)
+		require.Equal(t, 1, byId[10].RelatedMediaId)
+		require.Equal(t, anilist.MediaRelationSideStory, byId[10].Relation)
+		require.True(t, byId[10].InLibrary)
+		require.Nil(t, byId[10].ListStatus)
+		require.False(t, byId[10].Watched)
+
+		require.Equal(t, anime.FranchiseGuideSpinOffEntry, byId[12].Kind)
+		require.Equal(t, anime.FranchiseGuideRecapEntry, byId[11].Kind)
+		require.Equal(t, 2, byId[11].RelatedMediaId)
+
+		require.True(t, byId[2].InLibrary)
+		require.Equal(t, 2, byId[2].LocalEpisodeCount)
+		require.Equal(t, anilist.MediaListStatusCurrent, *byId[2].ListStatus)
+		require.Equal(t, 3, byId[2].Progress)
+		require.False(t, byId[2].Watched)
+	})
+
+	t.Run("release", func(t *testing.T) {
+		guide := anime.NewFranchiseGuide(&anime.NewFranchiseGuideOptions{
+			Tree:            tree,
+			Order:           anime.FranchiseGuideReleaseOrder,
+			AnimeCollection: animeCollection,
+			LocalFiles:      localFiles,
+		})
+
+		require.Equal(t, []int{1, 10, 11, 2, 3, 12}, entryIds(guide))
+		require.Equal(t, 2, guide.NextMediaId)
+	})
+}
+
+func TestFranchiseGuideRoot(t *testing.T) {
+	season1 := newFranchiseTestNode(1, anilist.MediaFormatTv, 2013, 4)
+	season2 := newFranchiseTestNode(2, anilist.MediaFormatTv, 2017, 4)
+	ova := newFranchiseTestNode(10, anilist.MediaFormatOva, 2014, 1)
+
+	// Side stories are guided from their parent
+	require.Equal(t, 1, anime.FranchiseGuideRoot(newFranchiseTestMedia(ova,
+		newFranchiseTestEdge(anilist.MediaRelationParent, season1),
+	)))
+	// Media with a prequel or a sequel are part of the main story
+	require.Equal(t, 2, anime.FranchiseGuideRoot(newFranchiseTestMedia(season2,
+		newFranchiseTestEdge(anilist.MediaRelationPrequel, season1),
+		newFranchiseTestEdge(anilist.MediaRelationParent, season1),
+	)))
+	require.Equal(t, 1, anime.FranchiseGuideRoot(newFranchiseTestMedia(season1)))
+}
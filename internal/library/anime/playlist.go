@@ -6,6 +6,8 @@ type (
 		DbId     uint               `json:"dbId"` // DbId is the database ID of the models.Playlist
 		Name     string             `json:"name"` // Name is the name of the playlist
 		Episodes []*PlaylistEpisode `json:"episodes"`
+		// Rules are set for smart playlists, the episodes are selected again each time the playlist is started
+		Rules *PlaylistRules `json:"rules,omitempty"`
 	}
 
 	WatchType string
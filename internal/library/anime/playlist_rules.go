@@ -0,0 +1,327 @@
+package anime
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"seanime/internal/api/anilist"
+	"slices"
+	"time"
+)
+
+const (
+	// PlaylistRuleNextEpisode selects the next unwatched episode of each show
+	PlaylistRuleNextEpisode PlaylistRuleEpisodes = "next"
+	// PlaylistRuleUnwatchedEpisodes selects all the episodes after the progress of each show
+	PlaylistRuleUnwatchedEpisodes PlaylistRuleEpisodes = "unwatched"
+	// PlaylistRuleAllEpisodes selects all the episodes, e.g. to rewatch completed shows
+	PlaylistRuleAllEpisodes PlaylistRuleEpisodes = "all"
+)
+
+const (
+	// PlaylistRuleAnySource uses the local files and streams the episodes that are not downloaded
+	PlaylistRuleAnySource   PlaylistRuleSource = "any"
+	PlaylistRuleLocalSource PlaylistRuleSource = "local"
+	// PlaylistRuleStreamSource streams all the episodes with PlaylistRules.StreamWatchType
+	PlaylistRuleStreamSource PlaylistRuleSource = "stream"
+)
+
+const (
+	// PlaylistRuleReleaseOrder plays the shows by start date, and their episodes in order
+	PlaylistRuleReleaseOrder PlaylistRuleOrder = "release"
+	// PlaylistRuleAiringOrder plays the episodes by air date, oldest first
+	PlaylistRuleAiringOrder PlaylistRuleOrder = "airing"
+	// PlaylistRuleRecentOrder plays the shows watched most recently first, and their episodes in order
+	PlaylistRuleRecentOrder PlaylistRuleOrder = "recent"
+	PlaylistRuleRandomOrder PlaylistRuleOrder = "random"
+)
+
+// Largest number of episodes of a smart playlist
+const maxPlaylistRulesLimit = 500
+
+type (
+	PlaylistRuleEpisodes string
+	PlaylistRuleSource   string
+	PlaylistRuleOrder    string
+
+	// PlaylistRules define a smart playlist.
+	// The episodes of a smart playlist are selected from the user's list when the playlist is started.
+	PlaylistRules struct {
+		// Statuses filters the shows by list status, any status if empty
+		Statuses []anilist.MediaListStatus `json:"statuses,omitempty"`
+		// MediaIds restricts the playlist to these shows, e.g. the entries of a franchise
+		// Shows that are not in the list are included when no status is set
+		MediaIds []int `json:"mediaIds,omitempty"`
+		// Genres filters the shows having at least one of the genres
+		Genres []string `json:"genres,omitempty"`
+		// Formats filters the shows by format, e.g. TV
+		Formats        []anilist.MediaFormat `json:"formats,omitempty"`
+		Episodes       PlaylistRuleEpisodes  `json:"episodes"`
+		ExcludeFillers bool                  `json:"excludeFillers"`
+		Sources        PlaylistRuleSource    `json:"sources"`
+		// StreamWatchType is used to play the episodes that are not in the library, WatchTypeTorrent if empty
+		StreamWatchType WatchType         `json:"streamWatchType,omitempty"`
+		Order           PlaylistRuleOrder `json:"order"`
+		// Limit is the maximum number of episodes, 0 for the maximum
+		Limit int `json:"limit,omitempty"`
+	}
+
+	// PlaylistRuleCandidate is a show the rules are evaluated against.
+	PlaylistRuleCandidate struct {
+		Media *anilist.BaseAnime
+		// Progress is the progress of the list entry, 0 if the show is not in the list
+		Progress int
+		// LocalEpisodes are the main episodes in the library
+		LocalEpisodes []*Episode
+		// StreamEpisodes are the main episodes that can be streamed
+		StreamEpisodes []*Episode
+		// LastWatchedAt is the last time the show was watched, used by PlaylistRuleRecentOrder
+		LastWatchedAt time.Time
+	}
+)
+
+// Normalize sets the default values of the rules.
+func (r *PlaylistRules) Normalize() {
+	if r.Episodes == "" {
+		r.Episodes = PlaylistRuleNextEpisode
+	}
+	if r.Sources == "" {
+		r.Sources = PlaylistRuleAnySource
+	}
+	if r.StreamWatchType == "" {
+		r.StreamWatchType = WatchTypeTorrent
+	}
+	if r.Order == "" {
+		r.Order = PlaylistRuleReleaseOrder
+	}
+}
+
+// Validate returns an error if the rules are invalid, Normalize should be called first.
+func (r *PlaylistRules) Validate() error {
+	switch r.Episodes {
+	case PlaylistRuleNextEpisode, PlaylistRuleUnwatchedEpisodes, PlaylistRuleAllEpisodes:
+	default:
+		return fmt.Errorf("invalid episode selection %q", r.Episodes)
+	}
+	switch r.Sources {
+	case PlaylistRuleAnySource, PlaylistRuleLocalSource, PlaylistRuleStreamSource:
+	default:
+		return fmt.Errorf("invalid episode source %q", r.Sources)
+	}
+	switch r.StreamWatchType {
+	case WatchTypeTorrent, WatchTypeDebrid, WatchTypeOnline:
+	default:
+		return fmt.Errorf("invalid stream watch type %q", r.StreamWatchType)
+	}
+	switch r.Order {
+	case PlaylistRuleReleaseOrder, PlaylistRuleAiringOrder, PlaylistRuleRecentOrder, PlaylistRuleRandomOrder:
+	default:
+		return fmt.Errorf("invalid order %q", r.Order)
+	}
+	for _, status := range r.Statuses {
+		if !status.IsValid() {
+			return fmt.Errorf("invalid list status %q", status)
+		}
+	}
+	for _, format := range r.Formats {
+		if !format.IsValid() {
+			return fmt.Errorf("invalid format %q", format)
+		}
+	}
+	if r.Limit < 0 || r.Limit > maxPlaylistRulesLimit {
+		return fmt.Errorf("limit must be between 0 and %d", maxPlaylistRulesLimit)
+	}
+	if len(r.Statuses) == 0 && len(r.MediaIds) == 0 {
+		return errors.New("rules must select shows by list status or media")
+	}
+	return nil
+}
+
+// UsesLocalFiles returns true if the episodes can be played from the library.
+func (r *PlaylistRules) UsesLocalFiles() bool {
+	return r.Sources != PlaylistRuleStreamSource
+}
+
+// UsesStreams returns true if the episodes can be streamed.
+func (r *PlaylistRules) UsesStreams() bool {
+	return r.Sources != PlaylistRuleLocalSource
+}
+
+// MatchesMedia returns true if the show is selected by the rules.
+// status is nil if the show is not in the list.
+func (r *PlaylistRules) MatchesMedia(media *anilist.BaseAnime, status *anilist.MediaListStatus) bool {
+	if media == nil {
+		return false
+	}
+	if len(r.Statuses) > 0 && (status == nil || !slices.Contains(r.Statuses, *status)) {
+		return false
+	}
+	if len(r.MediaIds) > 0 && !slices.Contains(r.MediaIds, media.GetID()) {
+		return false
+	}
+	if len(r.Formats) > 0 && (media.GetFormat() == nil || !slices.Contains(r.Formats, *media.GetFormat())) {
+		return false
+	}
+	if len(r.Genres) > 0 && !slices.ContainsFunc(media.GetGenres(), func(genre *string) bool {
+		return genre != nil && slices.Contains(r.Genres, *genre)
+	}) {
+		return false
+	}
+	return true
+}
+
+// Evaluate returns the episodes of the candidates selected by the rules, in the order of the rules.
+// rnd is used by PlaylistRuleRandomOrder, the global source is used if nil.
+func (r *PlaylistRules) Evaluate(candidates []*PlaylistRuleCandidate, rnd *rand.Rand) []*PlaylistEpisode {
+	type selected struct {
+		candidate *PlaylistRuleCandidate
+		episode   *PlaylistEpisode
+	}
+
+	ret := make([]selected, 0)
+	for _, c := range candidates {
+		for _, episode := range r.evaluateCandidate(c) {
+			ret = append(ret, selected{candidate: c, episode: episode})
+		}
+	}
+
+	byEpisode := func(a, b selected) int {
+		return cmp.Or(
+			cmp.Compare(a.candidate.Media.GetID(), b.candidate.Media.GetID()),
+			cmp.Compare(a.episode.Episode.GetProgressNumber(), b.episode.Episode.GetProgressNumber()),
+		)
+	}
+
+	switch r.Order {
+	case PlaylistRuleRandomOrder:
+		shuffle := rand.Shuffle
+		if rnd != nil {
+			shuffle = rnd.Shuffle
+		}
+		shuffle(len(ret), func(i, j int) { ret[i], ret[j] = ret[j], ret[i] })
+	case PlaylistRuleAiringOrder:
+		slices.SortStableFunc(ret, func(a, b selected) int {
+			return cmp.Or(
+				cmp.Compare(episodeAirDate(a.episode.Episode, a.candidate.Media), episodeAirDate(b.episode.Episode, b.candidate.Media)),
+				byEpisode(a, b),
+			)
+		})
+	case PlaylistRuleRecentOrder:
+		slices.SortStableFunc(ret, func(a, b selected) int {
+			return cmp.Or(
+				b.candidate.LastWatchedAt.Compare(a.candidate.LastWatchedAt),
+				byEpisode(a, b),
+			)
+		})
+	default:
+		slices.SortStableFunc(ret, func(a, b selected) int {
+			return cmp.Or(
+				cmp.Compare(mediaStartDate(a.candidate.Media), mediaStartDate(b.candidate.Media)),
+				byEpisode(a, b),
+			)
+		})
+	}
+
+	limit := maxPlaylistRulesLimit
+	if r.Limit > 0 {
+		limit = r.Limit
+	}
+
+	episodes := make([]*PlaylistEpisode, 0, min(len(ret), limit))
+	for _, s := range ret[:min(len(ret), limit)] {
+		episodes = append(episodes, s.episode)
+	}
+	return episodes
+}
+
+// evaluateCandidate returns the episodes of the show selected by the rules, in order.
+func (r *PlaylistRules) evaluateCandidate(c *PlaylistRuleCandidate) []*PlaylistEpisode {
+	// Episodes by progress number, local files first
+	episodes := make(map[int]*PlaylistEpisode)
+	if r.UsesLocalFiles() {
+		for _, ep := range c.LocalEpisodes {
+			if ep == nil || ep.LocalFile == nil || !ep.IsMain() {
+				continue
+			}
+			if _, ok := episodes[ep.ProgressNumber]; ok {
+				continue
+			}
+			episodes[ep.ProgressNumber] = &PlaylistEpisode{Episode: ep, WatchType: WatchTypeLocalFile}
+		}
+	}
+	if r.UsesStreams() {
+		for _, ep := range c.StreamEpisodes {
+			if ep == nil || ep.Type != LocalFileTypeMain {
+				continue
+			}
+			if _, ok := episodes[ep.ProgressNumber]; ok {
+				continue
+			}
+			episodes[ep.ProgressNumber] = &PlaylistEpisode{Episode: ep, WatchType: r.StreamWatchType}
+		}
+	}
+
+	ret := make([]*PlaylistEpisode, 0)
+	for _, progressNumber := range slices.Sorted(maps.Keys(episodes)) {
+		episode := episodes[progressNumber]
+		if r.Episodes != PlaylistRuleAllEpisodes && progressNumber <= c.Progress {
+			continue
+		}
+		if r.ExcludeFillers && episode.Episode.EpisodeMetadata != nil && episode.Episode.EpisodeMetadata.IsFiller {
+			continue
+		}
+		if episode.Episode.BaseAnime == nil {
+			episode.Episode.BaseAnime = c.Media
+		}
+		ret = append(ret, episode)
+		if r.Episodes == PlaylistRuleNextEpisode {
+			break
+		}
+	}
+
+	// The next episode is not available
+	if r.Episodes == PlaylistRuleNextEpisode && len(ret) == 1 && !r.isNextEpisode(c, episodes, ret[0]) {
+		return nil
+	}
+
+	return ret
+}
+
+// isNextEpisode returns true if no episode between the progress and the selected one is missing, except fillers that are skipped.
+func (r *PlaylistRules) isNextEpisode(c *PlaylistRuleCandidate, episodes map[int]*PlaylistEpisode, selected *PlaylistEpisode) bool {
+	for progressNumber := c.Progress + 1; progressNumber < selected.Episode.ProgressNumber; progressNumber++ {
+		if _, ok := episodes[progressNumber]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// mediaStartDate returns the start date of the media as a sortable number, unknown dates come last.
+func mediaStartDate(media *anilist.BaseAnime) int {
+	date := media.GetStartDate()
+	if date == nil || date.GetYear() == nil {
+		return math.MaxInt
+	}
+	ret := *date.GetYear() * 10000
+	if date.GetMonth() != nil {
+		ret += *date.GetMonth() * 100
+	}
+	if date.GetDay() != nil {
+		ret += *date.GetDay()
+	}
+	return ret
+}
+
+// episodeAirDate returns the air date of the episode as a sortable number, the start date of the media if unknown.
+func episodeAirDate(episode *Episode, media *anilist.BaseAnime) int {
+	if episode.EpisodeMetadata != nil && episode.EpisodeMetadata.AirDate != "" {
+		if t, err := time.Parse(time.DateOnly, episode.EpisodeMetadata.AirDate); err == nil {
+			return t.Year()*10000 + int(t.Month())*100 + t.Day()
+		}
+	}
+	return mediaStartDate(media)
+}
@@ -0,0 +1,256 @@
+package anime
+
+import (
+	"math/rand/v2"
+	"seanime/internal/api/anilist"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newRulesTestMedia(id int, year int, genres ...string) *anilist.BaseAnime {
+	media := &anilist.BaseAnime{
+		ID:        id,
+		Episodes:  new(12),
+		Format:    new(anilist.MediaFormatTv),
+		StartDate: &anilist.BaseAnime_StartDate{Year: new(year), Month: new(4)},
+	}
+	for _, genre := range genres {
+		media.Genres = append(media.Genres, new(genre))
+	}
+	return media
+}
+
+func newRulesTestLocalEpisodes(numbers ...int) []*Episode {
+	ret := make([]*Episode, 0, len(numbers))
+	for _, n := range numbers {
+		ret = append(ret, &Episode{
+			Type:           LocalFileTypeMain,
+			EpisodeNumber:  n,
+			ProgressNumber: n,
+			LocalFile:      &LocalFile{Metadata: &LocalFileMetadata{Episode: n, Type: LocalFileTypeMain}},
+		})
+	}
+	return ret
+}
+
+func newRulesTestStreamEpisodes(firstAirDate string, fillers []int, count int) []*Episode {
+	first, err := time.Parse(time.DateOnly, firstAirDate)
+	if err != nil {
+		panic(err)
+	}
+	ret := make([]*Episode, 0, count)
+	for n := 1; n <= count; n++ {
+		ep := &Episode{
+			Type:           LocalFileTypeMain,
+			EpisodeNumber:  n,
+			ProgressNumber: n,
+			EpisodeMetadata: &EpisodeMetadata{
+				AirDate: first.AddDate(0, 0, 7*(n-1)).Format(time.DateOnly),
+			},
+		}
+		for _, f := range fillers {
+			if f == n {
+				ep.EpisodeMetadata.IsFiller = true
+			}
+		}
+		ret = append(ret, ep)
+	}
+	return ret
+}
+
+func playlistEpisodeKeys(episodes []*PlaylistEpisode) [][3]any {
+	ret := make([][3]any, 0, len(episodes))
+	for _, e := range episodes {
+		ret = append(ret, [3]any{e.Episode.BaseAnime.GetID(), e.Episode.ProgressNumber, e.WatchType})
+	}
+	return ret
+}
+
+func TestPlaylistRulesValidate(t *testing.T) {
+	rules := &PlaylistRules{Statuses: []anilist.MediaListStatus{anilist.MediaListStatusCurrent}}
+	rules.Normalize()
+	require.NoError(t, rules.Validate())
+	require.Equal(t, PlaylistRuleNextEpisode, rules.Episodes)
+	require.Equal(t, PlaylistRuleAnySource, rules.Sources)
+	require.Equal(t, WatchTypeTorrent, rules.StreamWatchType)
+	require.Equal(t, PlaylistRuleReleaseOrder, rules.Order)
+
+	invalid := []*PlaylistRules{
+		{},
+		{MediaIds: []int{1}, Order: "newest"},
+		{MediaIds: []int{1}, StreamWatchType: WatchTypeLocalFile},
+		{Statuses: []anilist.MediaListStatus{"WATCHING"}},
+		{MediaIds: []int{1}, Limit: 1000},
+	}
+	for _, r := range invalid {
+		r.Normalize()
+		require.Error(t, r.Validate())
+	}
+}
+
+func TestPlaylistRulesMatchesMedia(t *testing.T) {
+	media := newRulesTestMedia(1, 2020, "Action", "Drama")
+	current := new(anilist.MediaListStatusCurrent)
+
+	require.True(t, (&PlaylistRules{Statuses: []anilist.MediaListStatus{anilist.MediaListStatusCurrent}}).MatchesMedia(media, current))
+	require.False(t, (&PlaylistRules{Statuses: []anilist.MediaListStatus{anilist.MediaListStatusCompleted}}).MatchesMedia(media, current))
+	require.False(t, (&PlaylistRules{Statuses: []anilist.MediaListStatus{anilist.MediaListStatusCurrent}}).MatchesMedia(media, nil))
+	require.True(t, (&PlaylistRules{MediaIds: []int{1, 2}}).MatchesMedia(media, nil))
+	require.False(t, (&PlaylistRules{MediaIds: []int{2}}).MatchesMedia(media, current))
+	require.True(t, (&PlaylistRules{MediaIds: []int{1}, Genres: []string{"Drama", "Comedy"}}).MatchesMedia(media, current))
+	require.False(t, (&PlaylistRules{MediaIds: []int{1}, Genres: []string{"Comedy"}}).MatchesMedia(media, current))
+	require.False(t, (&PlaylistRules{MediaIds: []int{1}, Formats: []anilist.MediaFormat{anilist.MediaFormatMovie}}).MatchesMedia(media, current))
+}
+
+func TestPlaylistRulesNextEpisodes(t *testing.T) {
+	// Next unwatched episode of every watching show, oldest airing first
+	rules := &PlaylistRules{
+		Statuses: []anilist.MediaListStatus{anilist.MediaListStatusCurrent},
+		Order:    PlaylistRuleAiringOrder,
+	}
+	rules.Normalize()
+
+	candidates := []*PlaylistRuleCandidate{
+		{
+			// Next episode is downloaded
+			Media:          newRulesTestMedia(1, 2024),
+			Progress:       3,
+			LocalEpisodes:  newRulesTestLocalEpisodes(3, 4),
+			StreamEpisodes: newRulesTestStreamEpisodes("2024-04-01", nil, 12),
+		},
+		{
+			// Next episode is streamed, it aired before the one of the first show
+			Media:          newRulesTestMedia(2, 2023),
+			Progress:       5,
+			StreamEpisodes: newRulesTestStreamEpisodes("2023-01-01", nil, 12),
+		},
+		{
+			// Finished
+			Media:          newRulesTestMedia(3, 2022),
+			Progress:       12,
+			StreamEpisodes: newRulesTestStreamEpisodes("2022-01-01", nil, 12),
+		},
+	}
+
+	episodes := rules.Evaluate(candidates, nil)
+	require.Equal(t, [][3]any{
+		{2, 6, WatchTypeTorrent},
+		{1, 4, WatchTypeLocalFile},
+	}, playlistEpisodeKeys(episodes))
+
+	// The next episode is not in the library
+	rules.Sources = PlaylistRuleLocalSource
+	candidates[0].Progress = 4
+	candidates[0].LocalEpisodes = newRulesTestLocalEpisodes(3, 4, 6)
+	require.Empty(t, rules.Evaluate(candidates, nil))
+}
+
+func TestPlaylistRulesFranchiseReleaseOrder(t *testing.T) {
+	// All unwatched episodes of a franchise in release order, mixing local files and streams
+	rules := &PlaylistRules{
+		MediaIds: []int{10, 11},
+		Episodes: PlaylistRuleUnwatchedEpisodes,
+		Sources:  PlaylistRuleAnySource,
+		Order:    PlaylistRuleReleaseOrder,
+	}
+	rules.Normalize()
+
+	sequel := newRulesTestMedia(11, 2021)
+	sequel.Episodes = new(3)
+	candidates := []*PlaylistRuleCandidate{
+		{
+			Media:          sequel,
+			LocalEpisodes:  newRulesTestLocalEpisodes(2),
+			StreamEpisodes: newRulesTestStreamEpisodes("2021-01-01", nil, 3),
+		},
+		{
+			Media:          newRulesTestMedia(10, 2019),
+			Progress:       10,
+			StreamEpisodes: newRulesTestStreamEpisodes("2019-01-01", nil, 12),
+		},
+	}
+
+	episodes := rules.Evaluate(candidates, nil)
+	require.Equal(t, [][3]any{
+		{10, 11, WatchTypeTorrent},
+		{10, 12, WatchTypeTorrent},
+		{11, 1, WatchTypeTorrent},
+		{11, 2, WatchTypeLocalFile},
+		{11, 3, WatchTypeTorrent},
+	}, playlistEpisodeKeys(episodes))
+}
+
+func TestPlaylistRulesRandomWithoutFillers(t *testing.T) {
+	// Random filler-free episodes from the completed list
+	rules := &PlaylistRules{
+		Statuses:        []anilist.MediaListStatus{anilist.MediaListStatusCompleted},
+		Episodes:        PlaylistRuleAllEpisodes,
+		ExcludeFillers:  true,
+		Sources:         PlaylistRuleStreamSource,
+		StreamWatchType: WatchTypeDebrid,
+		Order:           PlaylistRuleRandomOrder,
+		Limit:           5,
+	}
+	rules.Normalize()
+
+	candidates := []*PlaylistRuleCandidate{
+		{
+			Media:          newRulesTestMedia(1, 2010),
+			Progress:       12,
+			LocalEpisodes:  newRulesTestLocalEpisodes(1, 2, 3),
+			StreamEpisodes: newRulesTestStreamEpisodes("2010-01-01", []int{4, 5, 6, 7, 8, 9, 10, 11, 12}, 12),
+		},
+		{
+			Media:          newRulesTestMedia(2, 2012),
+			Progress:       12,
+			StreamEpisodes: newRulesTestStreamEpisodes("2012-01-01", []int{1, 2, 3, 4, 5, 6, 7, 8, 9}, 12),
+		},
+	}
+
+	episodes := rules.Evaluate(candidates, rand.New(rand.NewPCG(1, 2)))
+	require.Len(t, episodes, 5)
+	seen := make(map[[2]int]struct{})
+	for _, e := range episodes {
+		require.Equal(t, WatchTypeDebrid, e.WatchType)
+		require.False(t, e.Episode.EpisodeMetadata.IsFiller)
+		seen[[2]int{e.Episode.BaseAnime.GetID(), e.Episode.ProgressNumber}] = struct{}{}
+	}
+	require.Len(t, seen, 5)
+
+	// The order depends on the source
+	other := rules.Evaluate(candidates, rand.New(rand.NewPCG(3, 4)))
+	require.Len(t, other, 5)
+}
+
+func TestPlaylistRulesNextEpisodeSkipsFillers(t *testing.T) {
+	rules := &PlaylistRules{
+		Statuses:       []anilist.MediaListStatus{anilist.MediaListStatusCurrent},
+		ExcludeFillers: true,
+		Order:          PlaylistRuleRecentOrder,
+	}
+	rules.Normalize()
+
+	now := time.Now()
+	candidates := []*PlaylistRuleCandidate{
+		{
+			Media:          newRulesTestMedia(1, 2010),
+			Progress:       2,
+			StreamEpisodes: newRulesTestStreamEpisodes("2010-01-01", []int{3, 4}, 12),
+			LastWatchedAt:  now.Add(-48 * time.Hour),
+		},
+		{
+			Media:          newRulesTestMedia(2, 2012),
+			Progress:       0,
+			StreamEpisodes: newRulesTestStreamEpisodes("2012-01-01", nil, 12),
+			LastWatchedAt:  now,
+		},
+	}
+
+	episodes := rules.Evaluate(candidates, nil)
+	require.Equal(t, [][3]any{
+		{2, 1, WatchTypeTorrent},
+		{1, 5, WatchTypeTorrent},
+	}, playlistEpisodeKeys(episodes))
+}
@@ -0,0 +1,176 @@
+package anime
+
+import (
+	"fmt"
+	"seanime/internal/api/anilist"
+	"seanime/internal/icalendar"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	scheduleCalendarProductID = "-//Seanime//Airing Schedule//EN"
+	// Used when the duration of the episodes is unknown
+	defaultEpisodeDuration = 24 * time.Minute
+)
+
+// DefaultScheduleCalendarStatuses are the list statuses of the entries published by the calendar when no filter is set.
+var DefaultScheduleCalendarStatuses = []anilist.MediaListStatus{
+	anilist.MediaListStatusCurrent,
+	anilist.MediaListStatusRepeating,
+	anilist.MediaListStatusPlanning,
+}
+
+type ScheduleCalendarOptions struct {
+	// Statuses filters the entries by list status, DefaultScheduleCalendarStatuses if empty
+	Statuses []anilist.MediaListStatus
+	// Delays is the expected release delay of each show, by media ID, e.g. when the episodes are released after airing in Japan
+	Delays map[int]time.Duration
+	// LocalFiles are used to mark the episodes that are already downloaded
+	LocalFiles []*LocalFile
+	// Stamp is the time the calendar is created
+	Stamp time.Time
+}
+
+// NewScheduleCalendar returns the iCalendar feed of the schedule items of the entries in the collection.
+func NewScheduleCalendar(items []*ScheduleItem, animeCollection *anilist.AnimeCollection, opts *ScheduleCalendarOptions) *icalendar.Calendar {
+	ret := &icalendar.Calendar{
+		ProductID:       scheduleCalendarProductID,
+		Name:            "Seanime - Airing Schedule",
+		RefreshInterval: time.Hour,
+		Stamp:           opts.Stamp,
+		Events:          make([]*icalendar.Event, 0, len(items)),
+	}
+
+	statuses := opts.Statuses
+	if len(statuses) == 0 {
+		statuses = DefaultScheduleCalendarStatuses
+	}
+
+	entries := make(map[int]*anilist.AnimeListEntry)
+	if animeCollection != nil && animeCollection.MediaListCollection != nil {
+		for _, list := range animeCollection.MediaListCollection.GetLists() {
+			for _, entry := range list.GetEntries() {
+				entries[entry.GetMedia().GetID()] = entry
+			}
+		}
+	}
+
+	// Episodes downloaded by media ID
+	downloaded := make(map[int]map[int]struct{})
+	for _, lf := range opts.LocalFiles {
+		if lf.MediaId == 0 || !lf.IsMain() {
+			continue
+		}
+		if _, ok := downloaded[lf.MediaId]; !ok {
+			downloaded[lf.MediaId] = make(map[int]struct{})
+		}
+		downloaded[lf.MediaId][lf.GetEpisodeNumber()] = struct{}{}
+	}
+
+	for _, item := range items {
+		entry, ok := entries[item.MediaId]
+		if !ok || entry.GetStatus() == nil || !slices.Contains(statuses, *entry.GetStatus()) {
+			continue
+		}
+
+		delay := opts.Delays[item.MediaId]
+		_, isDownloaded := downloaded[item.MediaId][item.EpisodeNumber]
+
+		duration := defaultEpisodeDuration
+		if d := entry.GetMedia().GetDuration(); d != nil && *d > 0 {
+			duration = time.Duration(*d) * time.Minute
+		}
+
+		summary := item.Title
+		if !item.IsMovie {
+			summary = fmt.Sprintf("%s - Episode %d", item.Title, item.EpisodeNumber)
+		}
+		if item.IsSeasonFinale && !item.IsMovie {
+			summary += " (Finale)"
+		}
+
+		url := fmt.Sprintf("https://anilist.co/anime/%d", item.MediaId)
+
+		description := make([]string, 0, 4)
+		if total := entry.GetMedia().GetTotalEpisodeCount(); total > 0 && !item.IsMovie {
+			description = append(description, fmt.Sprintf("Episode %d of %d", item.EpisodeNumber, total))
+		}
+		if delay != 0 {
+			description = append(description, fmt.Sprintf("Airs at %s UTC, expected release delayed by %s", item.DateTime.UTC().Format("2006-01-02 15:04"), formatDelay(delay)))
+		}
+		if isDownloaded {
+			description = append(description, "Downloaded")
+		} else {
+			description = append(description, "Not downloaded")
+		}
+		description = append(description, url)
+
+		categories := []string{formatListStatus(*entry.GetStatus())}
+		if isDownloaded {
+			categories = append(categories, "Downloaded")
+		}
+
+		ret.Events = append(ret.Events, &icalendar.Event{
+			UID:         fmt.Sprintf("%d-%d@schedule.seanime", item.MediaId, item.EpisodeNumber),
+			Start:       item.DateTime.Add(delay),
+			Duration:    duration,
+			Summary:     summary,
+			Description: strings.Join(description, "\n"),
+			URL:         url,
+			Categories:  categories,
+		})
+	}
+
+	slices.SortStableFunc(ret.Events, func(a, b *icalendar.Event) int {
+		return a.Start.Compare(b.Start)
+	})
+
+	return ret
+}
+
+// ParseScheduleCalendarStatuses parses a comma-separated list of statuses, e.g. "current,planning".
+func ParseScheduleCalendarStatuses(value string) ([]anilist.MediaListStatus, error) {
+	ret := make([]anilist.MediaListStatus, 0)
+	for _, s := range strings.Split(value, ",") {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		status := anilist.MediaListStatus(s)
+		if !status.IsValid() {
+			return nil, fmt.Errorf("invalid list status %q", s)
+		}
+		ret = append(ret, status)
+	}
+	return ret, nil
+}
+
+func formatListStatus(status anilist.MediaListStatus) string {
+	switch status {
+	case anilist.MediaListStatusCurrent:
+		return "Watching"
+	case anilist.MediaListStatusRepeating:
+		return "Rewatching"
+	case anilist.MediaListStatusPlanning:
+		return "Planning"
+	case anilist.MediaListStatusPaused:
+		return "Paused"
+	case anilist.MediaListStatusCompleted:
+		return "Completed"
+	case anilist.MediaListStatusDropped:
+		return "Dropped"
+	}
+	return string(status)
+}
+
+func formatDelay(d time.Duration) string {
+	if d < 0 {
+		return "-" + formatDelay(-d)
+	}
+	if d%time.Hour == 0 {
+		return fmt.Sprintf("%dh", d/time.Hour)
+	}
+	return strings.TrimSuffix(d.Truncate(time.Minute).String(), "0s")
+}
@@ -0,0 +1,76 @@
+package anime_test
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/library/anime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewScheduleCalendar(t *testing.T) {
+	animeCollection := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{{
+				Entries: []*anilist.AnimeListEntry{
+					{Status: new(anilist.MediaListStatusCurrent), Media: &anilist.BaseAnime{ID: 154587, Episodes: new(12)}},
+					{Status: new(anilist.MediaListStatusPaused), Media: &anilist.BaseAnime{ID: 146065, Duration: new(45)}},
+				},
+			}},
+		},
+	}
+
+	items := []*anime.ScheduleItem{
+		{MediaId: 154587, Title: "Frieren", EpisodeNumber: 12, DateTime: time.Unix(1_700_000_200, 0).UTC(), IsSeasonFinale: true},
+		{MediaId: 154587, Title: "Frieren", EpisodeNumber: 11, DateTime: time.Unix(1_700_000_100, 0).UTC()},
+		{MediaId: 146065, Title: "Paused show", EpisodeNumber: 3, DateTime: time.Unix(1_700_000_000, 0).UTC()},
+		// Not in the collection
+		{MediaId: 1, Title: "Unknown", EpisodeNumber: 1, DateTime: time.Unix(1_700_000_000, 0).UTC()},
+	}
+	localFiles := []*anime.LocalFile{
+		{MediaId: 154587, Metadata: &anime.LocalFileMetadata{Episode: 11, Type: anime.LocalFileTypeMain}},
+	}
+
+	calendar := anime.NewScheduleCalendar(items, animeCollection, &anime.ScheduleCalendarOptions{
+		Delays:     map[int]time.Duration{154587: 90 * time.Minute},
+		LocalFiles: localFiles,
+	})
+
+	// The paused show is filtered out by default
+	require.Len(t, calendar.Events, 2)
+
+	downloaded := calendar.Events[0]
+	require.Equal(t, "154587-11@schedule.seanime", downloaded.UID)
+	require.Equal(t, time.Unix(1_700_000_100, 0).Add(90*time.Minute).UTC(), downloaded.Start.UTC())
+	require.Equal(t, "Frieren - Episode 11", downloaded.Summary)
+	require.Equal(t, "https://anilist.co/anime/154587", downloaded.URL)
+	require.Equal(t, []string{"Watching", "Downloaded"}, downloaded.Categories)
+	require.Contains(t, downloaded.Description, "Episode 11 of 12")
+	require.Contains(t, downloaded.Description, "expected release delayed by 1h30m")
+
+	finale := calendar.Events[1]
+	require.Equal(t, "Frieren - Episode 12 (Finale)", finale.Summary)
+	require.Contains(t, finale.Description, "Not downloaded")
+
+	calendar = anime.NewScheduleCalendar(items, animeCollection, &anime.ScheduleCalendarOptions{
+		Statuses: []anilist.MediaListStatus{anilist.MediaListStatusPaused},
+	})
+	require.Len(t, calendar.Events, 1)
+	require.Equal(t, "146065-3@schedule.seanime", calendar.Events[0].UID)
+	require.Equal(t, time.Unix(1_700_000_000, 0).UTC(), calendar.Events[0].Start)
+	require.Equal(t, 45*time.Minute, calendar.Events[0].Duration)
+}
+
+func TestParseScheduleCalendarStatuses(t *testing.T) {
+	statuses, err := anime.ParseScheduleCalendarStatuses("current, planning,")
+	require.NoError(t, err)
+	require.Equal(t, []anilist.MediaListStatus{anilist.MediaListStatusCurrent, anilist.MediaListStatusPlanning}, statuses)
+
+	statuses, err = anime.ParseScheduleCalendarStatuses("")
+	require.NoError(t, err)
+	require.Empty(t, statuses)
+
+	_, err = anime.ParseScheduleCalendarStatuses("watching")
+	require.Error(t, err)
+}
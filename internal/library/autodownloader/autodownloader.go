@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"seanime/internal/api/anilist"
 	"seanime/internal/api/metadata"
 	"seanime/internal/api/metadata_provider"
@@ -14,11 +15,15 @@ import (
 	"seanime/internal/debrid/debrid"
 	"seanime/internal/events"
 	"seanime/internal/extension"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
 	"seanime/internal/hook"
 	"seanime/internal/library/anime"
 	"seanime/internal/notifier"
 	"seanime/internal/torrent_clients/torrent_client"
 	"seanime/internal/torrents/torrent"
+	"seanime/internal/torrents/torznab"
+	usenet_client "seanime/internal/usenet/client"
+	"seanime/internal/usenet/usenet"
 	"seanime/internal/util"
 	"seanime/internal/util/comparison"
 	"slices"
@@ -44,6 +49,7 @@ type (
 		torrentClientRepository *torrent_client.Repository
 		torrentRepository       *torrent.Repository
 		debridClientRepository  *debrid_client.Repository
+		usenetClientRepository  *usenet_client.Repository
 		database                *db.Database
 		animeCollection         mo.Option[*anilist.AnimeCollection]
 		wsEventManager          events.WSEventManagerInterface
@@ -56,6 +62,12 @@ type (
 		mu                      sync.Mutex
 		isOfflineRef            *util.Ref[bool]
 		simulationResults       []*SimulationResult // Stores results when running in simulation mode
+		feedClient              *http.Client
+		feedSeen                map[string]time.Time // Info hash or link -> time the RSS feed item was first seen
+		feedSeeded              bool                 // True once the items of the first poll have been marked as seen
+		feedPollerCancel        context.CancelFunc
+		feedMu                  sync.Mutex
+		runMu                   sync.Mutex
 	}
 
 	// SimulationResult represents a torrent that would be downloaded in simulation mode
@@ -79,6 +91,7 @@ type (
 		Database                *db.Database
 		MetadataProviderRef     *util.Ref[metadata_provider.Provider]
 		DebridClientRepository  *debrid_client.Repository
+		UsenetClientRepository  *usenet_client.Repository
 		IsOfflineRef            *util.Ref[bool]
 	}
 )
@@ -93,6 +106,7 @@ func New(opts *NewAutoDownloaderOptions) *AutoDownloader {
 		animeCollection:         mo.None[*anilist.AnimeCollection](),
 		metadataProviderRef:     opts.MetadataProviderRef,
 		debridClientRepository:  opts.DebridClientRepository,
+		usenetClientRepository:  opts.UsenetClientRepository,
 		settings: &models.AutoDownloaderSettings{
 			Provider:              "", // Default provider, will be updated after the settings are fetched
 			Interval:              20,
@@ -107,6 +121,8 @@ func New(opts *NewAutoDownloaderOptions) *AutoDownloader {
 		mu:                sync.Mutex{},
 		isOfflineRef:      opts.IsOfflineRef,
 		simulationResults: make([]*SimulationResult, 0),
+		feedClient:        newFeedClient(),
+		feedSeen:          make(map[string]time.Time),
 	}
 }
 
@@ -130,6 +146,12 @@ func (ad *AutoDownloader) SetSettings(settings *models.AutoDownloaderSettings) {
 		defer ad.mu.Unlock()
 		ad.settings = settings
 		ad.settingsUpdatedCh <- struct{}{} // Notify that the settings have been updated
+		// Restart the poller so that the new interval is used right away
+		if ad.settings.Enabled {
+			ad.startFeedPoller()
+		} else {
+			ad.StopFeedPoller()
+		}
 		if ad.settings.Enabled {
 			ad.startCh <- false // Start the auto downloader
 		} else if !ad.settings.Enabled {
@@ -222,6 +244,9 @@ func (ad *AutoDownloader) Start() {
 		}
 		ad.mu.Unlock()
 
+		// Poll the RSS feeds in the background
+		ad.startFeedPoller()
+
 		// Start the auto downloader
 		ad.start()
 	}()
@@ -315,6 +340,15 @@ func (ad *AutoDownloader) checkForNewEpisodes(ctx context.Context, isSimulation
 		return
 	}
 
+	ad.processRunData(isSimulation, data)
+}
+
+// processRunData matches the fetched torrents against the rules and handles the best candidates.
+func (ad *AutoDownloader) processRunData(isSimulation bool, data *runData) {
+	// Runs triggered by the RSS feeds and the regular interval should not handle the same torrents at the same time
+	ad.runMu.Lock()
+	defer ad.runMu.Unlock()
+
 	// Event
 	event := &AutoDownloaderRunStartedEvent{
 		Rules:        data.rules,
@@ -441,6 +475,49 @@ func buildExistingTorrentHashes(existingTorrents []*torrent_client.Torrent, exis
 
 // fetchRunData fetches all data needed for checking new episodes
 func (ad *AutoDownloader) fetchRunData(ctx context.Context, ruleIDs ...uint) (*runData, error) {
+	data, err := ad.fetchRunRules(ruleIDs...)
+	if err != nil {
+		return nil, err
+	}
+	rules, profiles := data.rules, data.profiles
+
+	// Identify distinct providers from rules and profiles
+	// Returns the default provider + any other provider used by rules or profiles
+	providerExtensions := ad.getProvidersForRules(rules, profiles)
+
+	beforeFetchEvent := &AutoDownloaderBeforeFetchTorrentsEvent{
+		Rules:           rules,
+		Profiles:        profiles,
+		ProviderIDs:     lo.Map(providerExtensions, func(ext extension.AnimeTorrentProviderExtension, _ int) string { return ext.GetID() }),
+		DefaultProvider: ad.settings.Provider,
+	}
+	_ = hook.GlobalHookManager.OnAutoDownloaderBeforeFetchTorrents().Trigger(beforeFetchEvent)
+
+	var torrents []*NormalizedTorrent
+	if beforeFetchEvent.DefaultPrevented {
+		torrents = mergeNormalizedTorrents(beforeFetchEvent.Torrents)
+	} else {
+		// Fetch torrents from all identified providers
+		torrents, err = ad.fetchTorrentsFromProviders(ctx, providerExtensions, rules, profiles)
+		// Include the latest items from the RSS feeds
+		feedTorrents := ad.fetchTorrentsFromFeeds(ctx)
+		if err != nil {
+			if len(feedTorrents) == 0 {
+				return nil, fmt.Errorf("failed to get latest torrents: %w", err)
+			}
+			ad.logger.Warn().Err(err).Msg("autodownloader: Failed to get latest torrents from providers, using RSS feeds only")
+		}
+
+		torrents = mergeNormalizedTorrents(beforeFetchEvent.Torrents, torrents, feedTorrents)
+	}
+
+	ad.setRunDataTorrents(data, torrents)
+
+	return data, nil
+}
+
+// fetchRunRules fetches the rules, profiles and local files needed for checking new episodes.
+func (ad *AutoDownloader) fetchRunRules(ruleIDs ...uint) (*runData, error) {
 	// Get rules from the database
 	rules, err := db_bridge.GetAutoDownloaderRules(ad.database)
 	if err != nil {
@@ -488,31 +565,15 @@ func (ad *AutoDownloader) fetchRunData(ctx context.Context, ruleIDs ...uint) (*r
 	}
 	lfWrapper := anime.NewLocalFileWrapper(lfs)
 
-	// Identify distinct providers from rules and profiles
-	// Returns the default provider + any other provider used by rules or profiles
-	providerExtensions := ad.getProvidersForRules(rules, profiles)
-
-	beforeFetchEvent := &AutoDownloaderBeforeFetchTorrentsEvent{
-		Rules:           rules,
-		Profiles:        profiles,
-		ProviderIDs:     lo.Map(providerExtensions, func(ext extension.AnimeTorrentProviderExtension, _ int) string { return ext.GetID() }),
-		DefaultProvider: ad.settings.Provider,
-	}
-	_ = hook.GlobalHookManager.OnAutoDownloaderBeforeFetchTorrents().Trigger(beforeFetchEvent)
-
-	var torrents []*NormalizedTorrent
-	if beforeFetchEvent.DefaultPrevented {
-		torrents = mergeNormalizedTorrents(beforeFetchEvent.Torrents)
-	} else {
-		// Fetch torrents from all identified providers
-		torrents, err = ad.fetchTorrentsFromProviders(ctx, providerExtensions, rules, profiles)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get latest torrents: %w", err)
-		}
-
-		torrents = mergeNormalizedTorrents(beforeFetchEvent.Torrents, torrents)
-	}
+	return &runData{
+		rules:            rules,
+		profiles:         profiles,
+		localFileWrapper: lfWrapper,
+	}, nil
+}
 
+// setRunDataTorrents sets the fetched torrents and the torrents that already exist in the client or debrid service.
+func (ad *AutoDownloader) setRunDataTorrents(data *runData, torrents []*NormalizedTorrent) {
 	// Event
 	fetchedEvent := &AutoDownloaderTorrentsFetchedEvent{
 		Torrents: torrents,
@@ -537,14 +598,9 @@ func (ad *AutoDownloader) fetchRunData(ctx context.Context, ruleIDs ...uint) (*r
 		}
 	}
 
-	return &runData{
-		rules:                  rules,
-		profiles:               profiles,
-		localFileWrapper:       lfWrapper,
-		torrents:               torrents,
-		existingTorrentHashes:  buildExistingTorrentHashes(existingTorrents, existingDebridTorrents),
-		existingDebridTorrents: existingDebridTorrents,
-	}, nil
+	data.torrents = torrents
+	data.existingTorrentHashes = buildExistingTorrentHashes(existingTorrents, existingDebridTorrents)
+	data.existingDebridTorrents = existingDebridTorrents
 }
 
 // Candidate represents a potential torrent to download with its score
@@ -1305,16 +1361,23 @@ func (ad *AutoDownloader) downloadTorrent(isSimulation bool, t *NormalizedTorren
 	}
 
 	// Use the provider that found the torrent
-	providerExtension, found := ad.torrentRepository.GetAnimeProviderExtension(t.ExtensionID)
-	if !found {
-		// This shouldn't happen
-		ad.logger.Error().Str("extensionId", t.ExtensionID).Msg("autodownloader: Provider extension not found and no default provider available")
-		return itemActionResult{}
+	// Torrents from RSS feeds don't have a provider, their magnet link is resolved from the feed item
+	var provider hibiketorrent.AnimeProvider
+	if t.FeedID == 0 {
+		providerExtension, found := ad.torrentRepository.GetAnimeProviderExtension(t.ExtensionID)
+		if !found {
+			// This shouldn't happen
+			ad.logger.Error().Str("extensionId", t.ExtensionID).Msg("autodownloader: Provider extension not found and no default provider available")
+			return itemActionResult{}
+		}
+		provider = providerExtension.GetProvider()
 	}
 
+	// NZB releases are sent to the Usenet download client
+	useUsenet := torznab.IsNzbRelease(t.AnimeTorrent)
 	useDebrid := false
 
-	if ad.settings.UseDebrid {
+	if ad.settings.UseDebrid && !useUsenet {
 		// Check if the debrid provider is enabled
 		if !ad.debridClientRepository.HasProvider() || !ad.debridClientRepository.GetSettings().Enabled {
 			ad.logger.Error().Msg("autodownloader: Debrid provider not found or not enabled")
@@ -1327,13 +1390,17 @@ func (ad *AutoDownloader) downloadTorrent(isSimulation bool, t *NormalizedTorren
 
 	// Get torrent magnet (use stored magnet if this is a delayed item)
 	var magnet string
+	var nzbUrl string
 	var err error
-	if existingItem != nil && existingItem.Magnet != "" {
+	if useUsenet {
+		// NZB releases don't have magnet links, the NZB URL is stored so that queued items can be sent to the download client
+		nzbUrl = t.DownloadUrl
+	} else if existingItem != nil && existingItem.Magnet != "" {
 		// Use stored magnet for delayed items
 		magnet = existingItem.Magnet
 	} else {
 		// Fetch magnet from provider
-		magnet, err = t.GetMagnet(providerExtension.GetProvider())
+		magnet, err = t.GetMagnet(provider)
 		if err != nil {
 			// Try to construct from hash as fallback
 			if t.InfoHash != "" {
@@ -1350,7 +1417,35 @@ func (ad *AutoDownloader) downloadTorrent(isSimulation bool, t *NormalizedTorren
 	downloadImmediately := ad.settings.DownloadAutomatically
 
 downloadScope:
-	if useDebrid {
+	if useUsenet {
+		//
+		// Usenet
+		//
+
+		if downloadImmediately {
+			if ad.usenetClientRepository == nil || !ad.usenetClientRepository.HasProvider() {
+				ad.logger.Error().Str("name", t.Name).Msg("autodownloader: Usenet download client not found or not enabled")
+				downloadImmediately = false
+				ad.logger.Warn().Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: NZB will be queued.")
+				goto downloadScope
+			}
+
+			// Send the NZB to the download client, the scanner is notified when the job completes
+			_, err := ad.usenetClientRepository.AddNzb(usenet.AddNzbOptions{
+				URL:  nzbUrl,
+				Name: t.Name,
+			}, rule.Destination, rule.MediaId, t.InfoHash)
+			if err != nil {
+				ad.logger.Error().Err(err).Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: Failed to add NZB to usenet client")
+				downloadImmediately = false
+				ad.logger.Warn().Str("link", t.Link).Str("name", t.Name).Msg("autodownloader: NZB will be queued.")
+				goto downloadScope
+			}
+
+			downloaded = true
+		}
+
+	} else if useDebrid {
 		//
 		// Debrid
 		//
@@ -1452,6 +1547,7 @@ downloadScope:
 		existingItem.Link = t.Link
 		existingItem.Hash = t.InfoHash
 		existingItem.Magnet = magnet
+		existingItem.NzbUrl = nzbUrl
 		existingItem.TorrentName = t.Name
 		existingItem.Downloaded = downloaded
 		existingItem.IsDelayed = false
@@ -1469,6 +1565,7 @@ downloadScope:
 			Link:        t.Link,
 			Hash:        t.InfoHash,
 			Magnet:      magnet,
+			NzbUrl:      nzbUrl,
 			TorrentName: t.Name,
 			Downloaded:  downloaded,
 			IsDelayed:   false,
@@ -1840,7 +1937,8 @@ func (ad *AutoDownloader) getProvidersForRules(rules []*anime.AutoDownloaderRule
 }
 
 func (ad *AutoDownloader) isProviderMatch(t *NormalizedTorrent, rule *anime.AutoDownloaderRule) bool {
-	if len(rule.Providers) == 0 {
+	// RSS feeds are not bound to providers
+	if len(rule.Providers) == 0 || t.FeedID != 0 {
 		return true
 	}
 	return lo.Contains(rule.Providers, t.ExtensionID)
@@ -0,0 +1,232 @@
+package autodownloader
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/library/anime"
+	"seanime/internal/torrents/rssfeed"
+	"seanime/internal/util"
+	"sync"
+	"time"
+
+	"github.com/5rahim/habari"
+	"github.com/samber/lo"
+)
+
+const (
+	// DefaultFeedInterval is the default interval in minutes at which RSS feeds are polled.
+	DefaultFeedInterval = 5
+	// MinFeedInterval is the minimum interval in minutes at which RSS feeds can be polled.
+	MinFeedInterval = 2
+
+	// Items are remembered for this long to avoid processing the same release twice
+	feedSeenTTL = 7 * 24 * time.Hour
+)
+
+// getFeedInterval returns the interval at which RSS feeds are polled.
+func (ad *AutoDownloader) getFeedInterval() time.Duration {
+	ad.mu.Lock()
+	defer ad.mu.Unlock()
+
+	interval := DefaultFeedInterval
+	if ad.settings != nil && ad.settings.FeedInterval >= MinFeedInterval {
+		interval = ad.settings.FeedInterval
+	}
+	return time.Duration(interval) * time.Minute
+}
+
+// startFeedPoller polls the RSS feeds on their own interval, which is usually much shorter than the search interval.
+// New items are matched against the rules right away, without querying the providers.
+// A poller that is already running is replaced.
+func (ad *AutoDownloader) startFeedPoller() {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	ad.feedMu.Lock()
+	if ad.feedPollerCancel != nil {
+		ad.feedPollerCancel()
+	}
+	ad.feedPollerCancel = cancel
+	ad.feedMu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(ad.getFeedInterval()):
+			}
+			if ad.IsEnabled() {
+				ad.checkFeeds(ctx)
+			}
+		}
+	}()
+}
+
+// StopFeedPoller stops polling the RSS feeds, e.g. when the AutoDownloader is disabled or the app shuts down.
+func (ad *AutoDownloader) StopFeedPoller() {
+	if ad == nil {
+		return
+	}
+
+	ad.feedMu.Lock()
+	defer ad.feedMu.Unlock()
+
+	if ad.feedPollerCancel != nil {
+		ad.feedPollerCancel()
+		ad.feedPollerCancel = nil
+	}
+}
+
+// checkFeeds fetches the RSS feeds and runs the rules against the items that haven't been seen before.
+func (ad *AutoDownloader) checkFeeds(ctx context.Context) {
+	defer util.HandlePanicInModuleThen("autodownloader/checkFeeds", func() {})
+
+	if ad.isOfflineRef.Get() {
+		return
+	}
+
+	torrents := ad.fetchTorrentsFromFeeds(ctx)
+
+	newTorrents := ad.filterUnseenFeedTorrents(torrents)
+	if len(newTorrents) == 0 {
+		return
+	}
+
+	ad.logger.Debug().Int("count", len(newTorrents)).Msg("autodownloader: Found new items in RSS feeds")
+
+	data, err := ad.fetchRunRules()
+	if err != nil {
+		ad.logger.Error().Err(err).Msg("autodownloader: Failed to fetch check data for RSS feeds")
+		return
+	}
+	ad.setRunDataTorrents(data, newTorrents)
+
+	ad.processRunData(false, data)
+}
+
+// fetchTorrentsFromFeeds fetches all enabled RSS feeds concurrently.
+// Feeds that fail are logged and skipped.
+func (ad *AutoDownloader) fetchTorrentsFromFeeds(ctx context.Context) []*NormalizedTorrent {
+	feeds, err := db_bridge.GetAutoDownloaderFeeds(ad.database)
+	if err != nil {
+		ad.logger.Error().Err(err).Msg("autodownloader: Failed to fetch RSS feeds")
+		return nil
+	}
+
+	feeds = lo.Filter(feeds, func(f *anime.AutoDownloaderFeed, _ int) bool {
+		return f.Enabled && f.URL != ""
+	})
+	if len(feeds) == 0 {
+		return nil
+	}
+
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	ret := make([]*NormalizedTorrent, 0)
+
+	for _, feed := range feeds {
+		wg.Add(1)
+		go func(feed *anime.AutoDownloaderFeed) {
+			defer wg.Done()
+			defer util.HandlePanicInModuleThen("autodownloader/fetchTorrentsFromFeeds", func() {})
+
+			torrents, err := ad.fetchFeed(ctx, feed)
+			if err != nil {
+				ad.logger.Error().Err(err).Str("feed", feed.Name).Msg("autodownloader: Failed to fetch RSS feed")
+				return
+			}
+
+			mu.Lock()
+			ret = append(ret, torrents...)
+			mu.Unlock()
+		}(feed)
+	}
+	wg.Wait()
+
+	return mergeNormalizedTorrents(ret)
+}
+
+// fetchFeed fetches a single feed and normalizes its items.
+// Items that only link to a .torrent file are kept, they are added to the torrent client by URL and deduplicated by their link.
+func (ad *AutoDownloader) fetchFeed(ctx context.Context, feed *anime.AutoDownloaderFeed) ([]*NormalizedTorrent, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	items, err := rssfeed.Fetch(ctx, ad.feedClient, feed.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]*NormalizedTorrent, 0, len(items))
+	for _, item := range items {
+		item.Provider = fmt.Sprintf("rss-feed-%d", feed.DbID)
+		if item.MagnetLink == "" && item.InfoHash != "" {
+			item.MagnetLink = fmt.Sprintf("magnet:?xt=urn:btih:%s", item.InfoHash)
+		}
+		magnet := item.MagnetLink
+		if magnet == "" {
+			// Torrent clients accept the URL of a .torrent file in place of a magnet link
+			magnet = item.DownloadUrl
+		}
+		ret = append(ret, &NormalizedTorrent{
+			AnimeTorrent: item,
+			ParsedData:   habari.Parse(item.Name),
+			magnet:       magnet,
+			FeedID:       feed.DbID,
+		})
+	}
+
+	return ret, nil
+}
+
+// PreviewFeed fetches a feed and returns the items the AutoDownloader would consider.
+func (ad *AutoDownloader) PreviewFeed(ctx context.Context, feed *anime.AutoDownloaderFeed) ([]*NormalizedTorrent, error) {
+	return ad.fetchFeed(ctx, feed)
+}
+
+// filterUnseenFeedTorrents returns the torrents that haven't been returned by a previous poll and marks them as seen.
+// Seen items are only kept in memory, so the items of the first poll are marked as seen without being returned.
+// Otherwise every item of the feeds would be processed again after a restart.
+// Releases published while the app was not running are found by the regular search.
+func (ad *AutoDownloader) filterUnseenFeedTorrents(torrents []*NormalizedTorrent) []*NormalizedTorrent {
+	ad.feedMu.Lock()
+	defer ad.feedMu.Unlock()
+
+	seeding := !ad.feedSeeded
+	ad.feedSeeded = true
+
+	now := time.Now()
+	for key, seenAt := range ad.feedSeen {
+		if now.Sub(seenAt) > feedSeenTTL {
+			delete(ad.feedSeen, key)
+		}
+	}
+
+	ret := make([]*NormalizedTorrent, 0)
+	for _, t := range torrents {
+		key := torrentDedupeKey(t)
+		if key == "" {
+			if !seeding {
+				ret = append(ret, t)
+			}
+			continue
+		}
+		if _, found := ad.feedSeen[key]; found {
+			continue
+		}
+		ad.feedSeen[key] = now
+		if !seeding {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret
+}
+
+func newFeedClient() *http.Client {
+	return &http.Client{
+		Timeout: 30 * time.Second,
+	}
+}
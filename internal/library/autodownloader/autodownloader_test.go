@@ -2,6 +2,8 @@ package autodownloader
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"seanime/internal/api/anilist"
 	"seanime/internal/database/db_bridge"
 	"seanime/internal/database/models"
@@ -11,6 +13,7 @@ import (
 	"seanime/internal/hook_resolver"
 	"seanime/internal/library/anime"
 	"seanime/internal/torrent_clients/torrent_client"
+	"seanime/internal/torrents/torznab"
 	"seanime/internal/util"
 	"testing"
 	"time"
@@ -322,6 +325,97 @@ func TestIsTorrentAlreadyDownloaded(t *testing.T) {
 	}
 }
 
+func TestFilterUnseenFeedTorrents(t *testing.T) {
+	ad := &AutoDownloader{feedSeen: make(map[string]time.Time)}
+
+	torrents := []*NormalizedTorrent{
+		{AnimeTorrent: &hibiketorrent.AnimeTorrent{InfoHash: "HASH1"}},
+		{AnimeTorrent: &hibiketorrent.AnimeTorrent{InfoHash: "hash2"}},
+	}
+
+	// The items of the first poll are only marked as seen
+	assert.Empty(t, ad.filterUnseenFeedTorrents(torrents))
+	assert.Len(t, ad.feedSeen, 2)
+	assert.Empty(t, ad.filterUnseenFeedTorrents(torrents))
+
+	// Already seen items are ignored on the next poll
+	torrents = append(torrents, &NormalizedTorrent{AnimeTorrent: &hibiketorrent.AnimeTorrent{InfoHash: "hash3"}})
+	unseen := ad.filterUnseenFeedTorrents(torrents)
+	require.Len(t, unseen, 1)
+	assert.Equal(t, "hash3", unseen[0].InfoHash)
+
+	// Expired items are forgotten
+	ad.feedSeen["hash1"] = time.Now().Add(-feedSeenTTL - time.Hour)
+	unseen = ad.filterUnseenFeedTorrents(torrents)
+	require.Len(t, unseen, 1)
+	assert.Equal(t, "HASH1", unseen[0].InfoHash)
+
+	// Items without an info hash are deduplicated by their link
+	torrentFile := &NormalizedTorrent{AnimeTorrent: &hibiketorrent.AnimeTorrent{DownloadUrl: "https://example.com/1.torrent"}}
+	assert.Len(t, ad.filterUnseenFeedTorrents([]*NormalizedTorrent{torrentFile}), 1)
+	assert.Empty(t, ad.filterUnseenFeedTorrents([]*NormalizedTorrent{torrentFile}))
+}
+
+func TestStopFeedPoller(t *testing.T) {
+	ad := &AutoDownloader{}
+
+	ad.startFeedPoller()
+	first := ad.feedPollerCancel
+	require.NotNil(t, first)
+
+	// Starting the poller again replaces it
+	ad.startFeedPoller()
+	require.NotNil(t, ad.feedPollerCancel)
+
+	ad.StopFeedPoller()
+	assert.Nil(t, ad.feedPollerCancel)
+}
+
+func TestFetchFeedKeepsTorrentFileItems(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0">
+  <channel>
+    <item>
+      <title>[SubsPlease] Frieren - 01 (1080p).mkv</title>
+      <link>https://example.com/download/1.torrent</link>
+    </item>
+    <item>
+      <title>[SubsPlease] Frieren - 01 (1080p).mkv</title>
+      <link>https://example.com/download/1.torrent</link>
+    </item>
+    <item>
+      <title>[SubsPlease] Frieren - 02 (1080p).mkv</title>
+      <link>magnet:?xt=urn:btih:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA</link>
+    </item>
+  </channel>
+</rss>`))
+	}))
+	defer server.Close()
+
+	ad := &AutoDownloader{feedClient: server.Client()}
+	torrents, err := ad.fetchFeed(t.Context(), &anime.AutoDownloaderFeed{DbID: 1, URL: server.URL})
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+
+	magnet, err := torrents[0].GetMagnet(nil)
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com/download/1.torrent", magnet)
+	magnet, err = torrents[2].GetMagnet(nil)
+	require.NoError(t, err)
+	assert.Contains(t, magnet, "magnet:?xt=urn:btih:")
+
+	assert.Len(t, mergeNormalizedTorrents(torrents), 2)
+}
+
+func TestIsProviderMatchFeedTorrent(t *testing.T) {
+	ad := &AutoDownloader{}
+	rule := &anime.AutoDownloaderRule{Providers: []string{"nyaa"}}
+
+	assert.False(t, ad.isProviderMatch(&NormalizedTorrent{AnimeTorrent: &hibiketorrent.AnimeTorrent{}, ExtensionID: "animetosho"}, rule))
+	assert.True(t, ad.isProviderMatch(&NormalizedTorrent{AnimeTorrent: &hibiketorrent.AnimeTorrent{}, FeedID: 1}, rule))
+}
+
 func TestIsEpisodeAlreadyHandled(t *testing.T) {
 	ad := &AutoDownloader{}
 
@@ -1279,6 +1373,22 @@ func TestDelayIntegration(t *testing.T) {
 				assert.Equal(t, "hash1", items[0].Hash)
 			},
 		},
+		{
+			name: "Queue NZB release",
+			torrents: []*hibiketorrent.AnimeTorrent{
+				{Name: "[SubsPlease] Sousou no Frieren - 01 (1080p).mkv", Provider: torznab.NewznabProviderID, InfoHash: "nzb1", DownloadUrl: "https://indexer.example.com/getnzb/1.nzb"},
+			},
+			profile: &anime.AutoDownloaderProfile{
+				Conditions: []anime.AutoDownloaderCondition{{Term: "1080p", Action: anime.AutoDownloaderProfileRuleFormatActionScore, Score: 10}},
+			},
+			expectedQueued: 1,
+			checkDelayedItemFunc: func(t *testing.T, items []*models.AutoDownloaderItem, _ []*SimulationResult) {
+				require.Len(t, items, 1)
+				assert.Empty(t, items[0].Magnet)
+				// The NZB is sent to the download client when the item is downloaded from the queue
+				assert.Equal(t, "https://indexer.example.com/getnzb/1.nzb", items[0].NzbUrl)
+			},
+		},
 		{
 			name: "Queue item for delay",
 			torrents: []*hibiketorrent.AnimeTorrent{
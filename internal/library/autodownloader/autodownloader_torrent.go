@@ -23,12 +23,14 @@ type (
 		ParsedData  *habari.Metadata `json:"parsedData"`
 		magnet      string           // Access using GetMagnet()
 		ExtensionID string
+		// FeedID is the DB id of the RSS feed the torrent comes from, 0 if it comes from a provider
+		FeedID uint `json:"feedId,omitempty"`
 	}
 )
 
 func mergeNormalizedTorrents(groups ...[]*NormalizedTorrent) []*NormalizedTorrent {
 	ret := make([]*NormalizedTorrent, 0)
-	seenHashes := make(map[string]struct{})
+	seenKeys := make(map[string]struct{})
 
 	for _, group := range groups {
 		for _, torrent := range group {
@@ -40,12 +42,11 @@ func mergeNormalizedTorrents(groups ...[]*NormalizedTorrent) []*NormalizedTorren
 				torrent.ParsedData = habari.Parse(torrent.Name)
 			}
 
-			normalizedHash := normalizeTorrentHash(torrent.InfoHash)
-			if normalizedHash != "" {
-				if _, found := seenHashes[normalizedHash]; found {
+			if key := torrentDedupeKey(torrent); key != "" {
+				if _, found := seenKeys[key]; found {
 					continue
 				}
-				seenHashes[normalizedHash] = struct{}{}
+				seenKeys[key] = struct{}{}
 			}
 
 			ret = append(ret, torrent)
@@ -55,6 +56,18 @@ func mergeNormalizedTorrents(groups ...[]*NormalizedTorrent) []*NormalizedTorren
 	return ret
 }
 
+// torrentDedupeKey returns the key used to deduplicate a torrent.
+// It is the info hash, or the link of the .torrent file for feed items that don't have one.
+func torrentDedupeKey(t *NormalizedTorrent) string {
+	if hash := normalizeTorrentHash(t.InfoHash); hash != "" {
+		return hash
+	}
+	if t.DownloadUrl != "" {
+		return t.DownloadUrl
+	}
+	return t.Link
+}
+
 func (ad *AutoDownloader) fetchTorrentsFromProviders(
 	ctx context.Context,
 	providers []extension.AnimeTorrentProviderExtension,
@@ -282,8 +295,12 @@ func (ad *AutoDownloader) fetchTorrentsFromProviders(
 }
 
 // GetMagnet returns the magnet link for the torrent.
+// providerExtension can be nil if the magnet link is already known (e.g. torrents from RSS feeds).
 func (t *NormalizedTorrent) GetMagnet(providerExtension hibiketorrent.AnimeProvider) (string, error) {
 	if t.magnet == "" {
+		if providerExtension == nil {
+			return "", errors.New("no provider to resolve the magnet link")
+		}
 		magnet, err := providerExtension.GetTorrentMagnetLink(t.AnimeTorrent)
 		if err != nil {
 			return "", err
@@ -9,6 +9,7 @@ import (
 	"seanime/internal/database/db_bridge"
 	"seanime/internal/database/models"
 	"seanime/internal/events"
+	"seanime/internal/library/anime"
 	"seanime/internal/library/autodownloader"
 	"seanime/internal/library/scanner"
 	"seanime/internal/library/summary"
@@ -41,7 +42,9 @@ type (
 		logsDir             string
 		scanning            atomic.Bool
 		onRefreshCollection func()
+		onScanCompleted     func(lfs []*anime.LocalFile)
 		animeCollection     *anilist.AnimeCollection
+		folderMediaSource   scanner.FolderMediaSource
 	}
 	NewAutoScannerOptions struct {
 		Database            *db.Database
@@ -54,6 +57,10 @@ type (
 		MetadataProviderRef *util.Ref[metadata_provider.Provider]
 		LogsDir             string
 		OnRefreshCollection func()
+		// OnScanCompleted is called with the local files once they are saved
+		OnScanCompleted func(lfs []*anime.LocalFile)
+		// Optional, used to match library folders to media directly
+		FolderMediaSource scanner.FolderMediaSource
 	}
 )
 
@@ -79,6 +86,8 @@ func New(opts *NewAutoScannerOptions) *AutoScanner {
 		metadataProviderRef: opts.MetadataProviderRef,
 		logsDir:             opts.LogsDir,
 		onRefreshCollection: opts.OnRefreshCollection,
+		onScanCompleted:     opts.OnScanCompleted,
+		folderMediaSource:   opts.FolderMediaSource,
 	}
 }
 
@@ -301,6 +310,7 @@ func (as *AutoScanner) scan() {
 		ExistingShelvedFiles: existingShelvedLfs,
 		ConfigAsString:       as.settings.ScannerConfig,
 		AnimeCollection:      as.animeCollection,
+		FolderMediaSource:    as.folderMediaSource,
 	}
 
 	allLfs, err := sc.Scan(context.Background())
@@ -322,6 +332,10 @@ func (as *AutoScanner) scan() {
 			as.logger.Error().Err(err).Msg("autoscanner: failed to insert local files")
 			return
 		}
+
+		if as.onScanCompleted != nil {
+			go as.onScanCompleted(allLfs)
+		}
 	}
 
 	if as.db != nil {
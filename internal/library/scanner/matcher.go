@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"path"
 	"regexp"
 	"runtime"
 	"seanime/internal/api/anilist"
@@ -56,7 +57,9 @@ type Matcher struct {
 	Debug             bool
 	UseLegacyMatching bool
 	Config            *Config
-	matchingRules     map[string]*compiledMatchingRule
+	// Optional, files under these folders are matched to the media directly, key is the normalized folder path
+	FolderMediaIds map[string]int
+	matchingRules  map[string]*compiledMatchingRule
 }
 
 type compiledMatchingRule struct {
@@ -239,6 +242,10 @@ func (m *Matcher) matchLocalFile(lf *anime.LocalFile) {
 		return
 	}
 
+	if m.applyFolderMedia(lf) {
+		return
+	}
+
 	// Check if the local file or any of its folders have a parsed title.
 	// If not, we skip it,
 	if lf.GetParsedTitle() == "" {
@@ -1207,6 +1214,38 @@ func (m *Matcher) applyMatcingRule(lf *anime.LocalFile) bool {
 	return false
 }
 
+// applyFolderMedia matches the file to the media of the closest parent folder in FolderMediaIds.
+func (m *Matcher) applyFolderMedia(lf *anime.LocalFile) bool {
+	if len(m.FolderMediaIds) == 0 {
+		return false
+	}
+
+	dir := path.Dir(lf.GetNormalizedPath())
+	for {
+		if mediaId, ok := m.FolderMediaIds[dir]; ok {
+			lf.MediaId = mediaId
+
+			if m.ScanLogger != nil {
+				m.ScanLogger.LogMatcher(zerolog.DebugLevel).
+					Str("filename", lf.Name).
+					Int("id", mediaId).
+					Str("folder", dir).
+					Msg("Matched by folder")
+			}
+			if m.ScanSummaryLogger != nil {
+				m.ScanSummaryLogger.LogSuccessfullyMatched(lf, mediaId)
+			}
+			return true
+		}
+
+		parent := path.Dir(dir)
+		if parent == dir {
+			return false
+		}
+		dir = parent
+	}
+}
+
 func (m *Matcher) getIgnoredSynonyms(candidates []*anime.NormalizedMedia) map[int]map[string]struct{} {
 	// Filter out synonyms that are shared between multiple candidates
 	// We keep the synonym only for the candidate with the shortest main title
@@ -1293,6 +1332,11 @@ func (m *Matcher) matchLocalFileLegacy(lf *anime.LocalFile) {
 		m.ScanSummaryLogger.LogFileNotMatched(lf, "Already matched")
 		return
 	}
+
+	if m.applyFolderMedia(lf) {
+		return
+	}
+
 	// Check if the local file has a title
 	if lf.GetParsedTitle() == "" {
 		if m.ScanLogger != nil {
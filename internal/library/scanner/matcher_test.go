@@ -1596,3 +1596,41 @@ func TestMatcher_applyMatchingRule(t *testing.T) {
 	}
 
 }
+
+func TestMatcher_applyFolderMedia(t *testing.T) {
+	concert := &anilist.BaseAnime{ID: 3000001, Title: &anilist.BaseAnime_Title{English: new("Concert Live")}, Format: new(anilist.MediaFormatTv)}
+	encore := &anilist.BaseAnime{ID: 3000002, Title: &anilist.BaseAnime_Title{English: new("Encore")}, Format: new(anilist.MediaFormatTv)}
+	frieren := &anilist.BaseAnime{ID: 154587, Title: &anilist.BaseAnime_Title{English: new("Frieren: Beyond Journey's End"), Romaji: new("Sousou no Frieren")}, Format: new(anilist.MediaFormatTv)}
+
+	libraryPaths := []string{"/library"}
+	lfs := []*anime.LocalFile{
+		anime.NewLocalFileS("/library/Concert Live/Disc 1/Live - 01.mkv", libraryPaths),
+		anime.NewLocalFileS("/library/Concert Live/Encore/Encore - 01.mkv", libraryPaths),
+		anime.NewLocalFileS("/library/Sousou no Frieren/Sousou no Frieren - 01.mkv", libraryPaths),
+	}
+
+	mc := NewMediaContainer(&MediaContainerOptions{
+		AllMedia: []*anime.NormalizedMedia{anime.NewNormalizedMedia(concert), anime.NewNormalizedMedia(encore), anime.NewNormalizedMedia(frieren)},
+	})
+
+	matcher := &Matcher{
+		LocalFiles:     lfs,
+		MediaContainer: mc,
+		Logger:         util.NewLogger(),
+		FolderMediaIds: map[string]int{
+			util.NormalizePath("/library/Concert Live"):        concert.ID,
+			util.NormalizePath("/library/Concert Live/Encore"): encore.ID,
+		},
+	}
+
+	err := matcher.MatchLocalFilesWithMedia()
+	assert.NoError(t, err)
+
+	// Files are matched to the closest folder
+	assert.Equal(t, concert.ID, lfs[0].MediaId)
+	assert.Equal(t, encore.ID, lfs[1].MediaId)
+	// Other folders go through title matching
+	assert.Equal(t, frieren.ID, lfs[2].MediaId)
+
+	assert.ElementsMatch(t, []int{concert.ID, encore.ID}, getMatchedFolderMediaIds(lfs, matcher.FolderMediaIds))
+}
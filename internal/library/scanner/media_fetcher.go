@@ -45,6 +45,8 @@ type MediaFetcherOptions struct {
 	ScanLogger                 *ScanLogger
 	// used for adding custom sources
 	OptionalAnimeCollection *anilist.AnimeCollection
+	// Media that library folders are matched to, see FolderMediaSource
+	FolderMedia []*anilist.BaseAnime
 }
 
 // NewMediaFetcher
@@ -175,6 +177,14 @@ func NewMediaFetcher(ctx context.Context, opts *MediaFetcherOptions) (ret *Media
 
 	mf.AllMedia = NormalizedMediaFromAnilistComplete(allCompleteAnime)
 
+	// Add the media of library folders that are not in the collection
+	for _, media := range opts.FolderMedia {
+		if media == nil || lo.ContainsBy(mf.AllMedia, func(m *anime.NormalizedMedia) bool { return m.ID == media.ID }) {
+			continue
+		}
+		mf.AllMedia = append(mf.AllMedia, anime.NewNormalizedMedia(media))
+	}
+
 	// +-------------------------+
 	// |  Enhanced (Offline DB)  |
 	// +-------------------------+
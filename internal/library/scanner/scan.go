@@ -52,6 +52,15 @@ type Scanner struct {
 	ConfigAsString       string
 	// Optional, used to add custom sources
 	AnimeCollection *anilist.AnimeCollection
+	// Optional, used to match library folders to media directly
+	FolderMediaSource FolderMediaSource
+}
+
+// FolderMediaSource provides media that the files of a library folder are matched to without comparing titles,
+// e.g. folders described by NFO files.
+type FolderMediaSource interface {
+	// GetFolderMedia returns the media of each folder found in the library paths, keyed by normalized folder path.
+	GetFolderMedia(ctx context.Context, libraryPaths []string) map[string]*anilist.BaseAnime
 }
 
 // Scan will scan the directory and return a list of anime.LocalFile.
@@ -337,6 +346,28 @@ func (scn *Scanner) Scan(ctx context.Context) (lfs []*anime.LocalFile, err error
 		scn.WSEventManager.SendEvent(events.EventScanStatus, "Fetching media...")
 	}
 
+	// +---------------------+
+	// |    Folder media     |
+	// +---------------------+
+
+	folderMedia := make([]*anilist.BaseAnime, 0)
+	folderMediaIds := make(map[string]int)
+	if scn.FolderMediaSource != nil {
+		for dir, media := range scn.FolderMediaSource.GetFolderMedia(ctx, libraryPaths) {
+			folderMediaIds[dir] = media.ID
+			if !lo.ContainsBy(folderMedia, func(m *anilist.BaseAnime) bool { return m.ID == media.ID }) {
+				folderMedia = append(folderMedia, media)
+			}
+		}
+
+		scn.Logger.Debug().Int("count", len(folderMediaIds)).Msg("scanner: Retrieved folder media")
+		if scn.ScanLogger != nil {
+			scn.ScanLogger.logger.Info().
+				Any("count", len(folderMediaIds)).
+				Msg("Retrieved folder media")
+		}
+	}
+
 	// +---------------------+
 	// |    MediaFetcher     |
 	// +---------------------+
@@ -354,6 +385,7 @@ func (scn *Scanner) Scan(ctx context.Context) (lfs []*anime.LocalFile, err error
 		DisableAnimeCollection:     false,
 		ScanLogger:                 scn.ScanLogger,
 		OptionalAnimeCollection:    scn.AnimeCollection,
+		FolderMedia:                folderMedia,
 	})
 	if err != nil {
 		return nil, err
@@ -391,6 +423,7 @@ func (scn *Scanner) Scan(ctx context.Context) (lfs []*anime.LocalFile, err error
 		Threshold:         scn.MatchingThreshold,
 		UseLegacyMatching: scn.UseLegacyMatching,
 		Config:            scn.Config,
+		FolderMediaIds:    folderMediaIds,
 	}
 
 	scn.WSEventManager.SendEvent(events.EventScanProgress, 60)
@@ -436,10 +469,20 @@ func (scn *Scanner) Scan(ctx context.Context) (lfs []*anime.LocalFile, err error
 
 	// Add non-added media entries to AniList collection
 	// Max of 4 to avoid rate limit issues
+	missingMediaIds := make([]int, 0)
 	if len(mf.UnknownMediaIds) < 5 {
+		missingMediaIds = append(missingMediaIds, mf.UnknownMediaIds...)
+	}
+	// Folder media are not on AniList, they are always added when files are matched to them
+	for _, id := range getMatchedFolderMediaIds(localFiles, folderMediaIds) {
+		if !lo.Contains(mf.CollectionMediaIds, id) && !lo.Contains(missingMediaIds, id) {
+			missingMediaIds = append(missingMediaIds, id)
+		}
+	}
+	if len(missingMediaIds) > 0 {
 		scn.WSEventManager.SendEvent(events.EventScanStatus, "Adding missing media to AniList...")
 
-		if err = scn.PlatformRef.Get().AddMediaToCollection(ctx, mf.UnknownMediaIds); err != nil {
+		if err = scn.PlatformRef.Get().AddMediaToCollection(ctx, missingMediaIds); err != nil {
 			scn.Logger.Warn().Msg("scanner: An error occurred while adding media to planning list: " + err.Error())
 		}
 	}
@@ -499,6 +542,26 @@ func (scn *Scanner) GetShelvedLocalFiles() []*anime.LocalFile {
 	return scn.shelvedLocalFiles
 }
 
+// getMatchedFolderMediaIds returns the IDs of the folder media that local files were matched to.
+func getMatchedFolderMediaIds(lfs []*anime.LocalFile, folderMediaIds map[string]int) []int {
+	if len(folderMediaIds) == 0 {
+		return nil
+	}
+
+	ids := make(map[int]struct{}, len(folderMediaIds))
+	for _, id := range folderMediaIds {
+		ids[id] = struct{}{}
+	}
+
+	ret := make([]int, 0)
+	for _, lf := range lfs {
+		if _, ok := ids[lf.MediaId]; ok && !lo.Contains(ret, lf.MediaId) {
+			ret = append(ret, lf.MediaId)
+		}
+	}
+	return ret
+}
+
 func (scn *Scanner) mergeSkippedLfsF(
 	localFiles []*anime.LocalFile,
 	skippedLfs map[string]*anime.LocalFile,
@@ -5,6 +5,7 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	"github.com/glebarez/sqlite"
@@ -14,8 +15,9 @@ import (
 )
 
 type Database struct {
-	gormdb *gorm.DB
-	logger *zerolog.Logger
+	gormdb         *gorm.DB
+	logger         *zerolog.Logger
+	simulatedScope atomic.Uint64 // Profile scope of the simulated collections, see db.GetProfileScope
 }
 
 func newLocalSyncDatabase(appDataDir, dbName string, logger *zerolog.Logger) (*Database, error) {
@@ -1,6 +1,7 @@
 package local
 
 import (
+	"fmt"
 	"seanime/internal/api/anilist"
 	"seanime/internal/customsource"
 
@@ -274,9 +275,37 @@ func (ldb *Database) filterOutCustomSourceManga(mc *anilist.MangaCollection) *an
 	return filtered
 }
 
+// simulatedCollectionType returns the type under which the simulated collection of the current profile is stored.
+// The default profile (scope 0) uses the type of the collections created before profiles existed.
+func (ldb *Database) simulatedCollectionType(collectionType string) string {
+	return simulatedCollectionTypeForScope(collectionType, uint(ldb.simulatedScope.Load()))
+}
+
+func simulatedCollectionTypeForScope(collectionType string, scope uint) string {
+	if scope == 0 {
+		return collectionType
+	}
+	return fmt.Sprintf("%s_profile_%d", collectionType, scope)
+}
+
+func (ldb *Database) SetSimulatedCollectionScope(scope uint) {
+	ldb.simulatedScope.Store(uint64(scope))
+}
+
+// DeleteSimulatedCollections deletes the simulated collections of a profile.
+func (ldb *Database) DeleteSimulatedCollections(scope uint) error {
+	if scope == 0 {
+		return nil
+	}
+	return ldb.gormdb.Where("type IN ?", []string{
+		simulatedCollectionTypeForScope(AnimeType, scope),
+		simulatedCollectionTypeForScope(MangaType, scope),
+	}).Delete(&SimulatedCollection{}).Error
+}
+
 func (ldb *Database) _getSimulatedCollection(collectionType string) (*SimulatedCollection, bool) {
 	var lc SimulatedCollection
-	err := ldb.gormdb.Where("type = ?", collectionType).First(&lc).Error
+	err := ldb.gormdb.Where("type = ?", ldb.simulatedCollectionType(collectionType)).First(&lc).Error
 	return &lc, err == nil
 }
 
@@ -295,7 +324,7 @@ func (ldb *Database) _saveSimulatedCollection(collectionType string, value inter
 	}
 
 	lcN := SimulatedCollection{
-		Type:  collectionType,
+		Type:  ldb.simulatedCollectionType(collectionType),
 		Value: marshalledValue,
 	}
 
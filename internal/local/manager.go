@@ -88,6 +88,11 @@ type Manager interface {
 	SynchronizeAnilistToSimulatedCollection() error
 
 	SetOffline(bool)
+
+	// SetProfileScope switches the simulated collections to the ones of the profile.
+	SetProfileScope(scope uint)
+	// DeleteProfileData deletes the simulated collections of a profile.
+	DeleteProfileData(scope uint) error
 }
 
 type (
@@ -197,6 +202,14 @@ func (m *ManagerImpl) GetOfflineMetadataProvider() metadata_provider.Provider {
 	return m.offlineMetadataProvider
 }
 
+func (m *ManagerImpl) SetProfileScope(scope uint) {
+	m.localDb.SetSimulatedCollectionScope(scope)
+}
+
+func (m *ManagerImpl) DeleteProfileData(scope uint) error {
+	return m.localDb.DeleteSimulatedCollections(scope)
+}
+
 func (m *ManagerImpl) SetOffline(enabled bool) {
 	m.isOffline = enabled
 }
@@ -4,7 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"os"
 	"seanime/internal/api/anilist"
 	"seanime/internal/extension"
 	hibikemanga "seanime/internal/extension/hibike/manga"
@@ -348,7 +347,7 @@ type MangaLatestChapterNumberItem struct {
 }
 
 // GetMangaLatestChapterNumbersMap retrieves the latest chapter number for all manga entries.
-// It scans the cache directory for chapter containers and counts the number of chapters fetched from the provider for each manga.
+// It goes through the file cache buckets of the chapter containers and counts the number of chapters fetched from the provider for each manga.
 //
 // Unlike [GetMangaLatestChapterNumberMap], it will segregate the chapter numbers by scanlator and language.
 func (r *Repository) GetMangaLatestChapterNumbersMap() (ret map[int][]MangaLatestChapterNumberItem, err error) {
@@ -361,18 +360,9 @@ func (r *Repository) GetMangaLatestChapterNumbersMap() (ret map[int][]MangaLates
 	}
 
 	// Go through all chapter container caches
-	entries, err := os.ReadDir(r.cacheDir)
-	if err != nil {
-		return nil, err
-	}
-
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-
-		// Get the provider and mediaId from the file cache name
-		provider, mediaId, ok := parseChapterFileName(entry.Name())
+	for _, bucketName := range r.fileCacher.GetBucketNames() {
+		// Get the provider and mediaId from the bucket name
+		provider, mediaId, ok := parseChapterFileName(bucketName)
 		if !ok {
 			continue
 		}
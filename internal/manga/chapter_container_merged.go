@@ -0,0 +1,264 @@
+package manga
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const maxMergedProviders = 10
+
+var ErrNoMergedProviders = errors.New("no providers selected for the merged chapter list")
+
+type (
+	// MergedChapterContainer combines the chapter lists of several providers.
+	// Chapters are deduplicated by chapter number, each chapter is served by the first provider in priority order that has it.
+	MergedChapterContainer struct {
+		MediaId int `json:"mediaId"`
+		// Providers are the merged providers, in priority order
+		Providers []string         `json:"providers"`
+		Chapters  []*MergedChapter `json:"chapters"`
+		// ProviderErrors are the providers whose chapters could not be fetched
+		ProviderErrors map[string]string `json:"providerErrors,omitempty"`
+	}
+
+	MergedChapter struct {
+		// Number is the normalized chapter number, empty if the chapter has no number
+		Number string `json:"number"`
+		// Chapter is the chapter of the provider that serves it, Chapter.Provider marks the provider
+		Chapter *hibikemanga.ChapterDetails `json:"chapter"`
+		// Fallbacks are the same chapter from other providers, in the order they are tried when the pages cannot be fetched
+		Fallbacks []*hibikemanga.ChapterDetails `json:"fallbacks,omitempty"`
+	}
+
+	GetMergedMangaChapterContainerOptions struct {
+		MediaId int
+		Titles  []*string
+		Year    int
+		// Providers overrides the merged providers of the entry preference
+		Providers []string
+	}
+)
+
+// GetMergedMangaChapterContainer returns the merged chapter list of a manga entry.
+// The chapter list of each provider is fetched with GetMangaChapterContainer and filtered with the scanlator and language filters of the entry.
+func (r *Repository) GetMergedMangaChapterContainer(opts *GetMergedMangaChapterContainerOptions) (ret *MergedChapterContainer, err error) {
+	defer util.HandlePanicInModuleWithError("manga/GetMergedMangaChapterContainer", &err)
+
+	preference, err := r.getEntryPreference(opts.MediaId)
+	if err != nil {
+		return nil, err
+	}
+
+	providers := normalizeMergedProviders(opts.Providers)
+	if len(providers) == 0 {
+		providers = preference.MergedProviders
+	}
+	if len(providers) == 0 {
+		return nil, ErrNoMergedProviders
+	}
+	if err := isValidMergedProviders(providers); err != nil {
+		return nil, err
+	}
+
+	containers := make([]*ChapterContainer, len(providers))
+	providerErrors := make(map[string]string)
+	mu := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for i, provider := range providers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			container, err := r.GetMangaChapterContainer(&GetMangaChapterContainerOptions{
+				Provider: provider,
+				MediaId:  opts.MediaId,
+				Titles:   opts.Titles,
+				Year:     opts.Year,
+			})
+			if err != nil {
+				r.logger.Warn().Err(err).Str("provider", provider).Int("mediaId", opts.MediaId).Msg("manga: Failed to get chapters for merged list")
+				mu.Lock()
+				providerErrors[provider] = err.Error()
+				mu.Unlock()
+				return
+			}
+			containers[i] = container
+		}()
+	}
+	wg.Wait()
+
+	if len(providerErrors) == len(providers) {
+		return nil, ErrNoChapters
+	}
+
+	ret = &MergedChapterContainer{
+		MediaId:   opts.MediaId,
+		Providers: providers,
+		Chapters:  mergeChapterContainers(containers, preference.Filters),
+	}
+	if len(providerErrors) > 0 {
+		ret.ProviderErrors = providerErrors
+	}
+
+	r.logger.Debug().Int("mediaId", opts.MediaId).Int("chapters", len(ret.Chapters)).Strs("providers", providers).Msg("manga: Merged chapter lists")
+
+	return ret, nil
+}
+
+// GetMangaPageContainerWithFallback returns the pages of a chapter of the merged chapter list.
+// If the pages cannot be fetched from the provider of the chapter, the same chapter is tried on the other merged providers.
+// The provider and chapter ID of the returned container are those of the provider that served the pages.
+func (r *Repository) GetMangaPageContainerWithFallback(
+	provider string,
+	mediaId int,
+	chapterId string,
+	doublePage bool,
+	isOfflineRef *util.Ref[bool],
+) (*PageContainer, error) {
+	ret, err := r.GetMangaPageContainer(provider, mediaId, chapterId, doublePage, isOfflineRef)
+	if err == nil {
+		return ret, nil
+	}
+	if isOfflineRef.Get() {
+		return nil, err
+	}
+
+	preference, prefErr := r.getEntryPreference(mediaId)
+	if prefErr != nil || len(preference.MergedProviders) == 0 {
+		return nil, err
+	}
+
+	// Only the cached chapter lists are used, they were fetched when the merged list was built
+	containers := make([]*ChapterContainer, 0, len(preference.MergedProviders))
+	for _, p := range preference.MergedProviders {
+		var container *ChapterContainer
+		bucket := r.getFcProviderBucket(p, mediaId, bucketTypeChapter)
+		if found, _ := r.fileCacher.Get(bucket, getMangaChapterContainerCacheKey(p, mediaId), &container); found && container != nil {
+			containers = append(containers, container)
+		}
+	}
+
+	merged, ok := findMergedChapter(mergeChapterContainers(containers, preference.Filters), provider, chapterId)
+	if !ok {
+		return nil, err
+	}
+
+	errs := []error{fmt.Errorf("%s: %w", provider, err)}
+	for _, ch := range append([]*hibikemanga.ChapterDetails{merged.Chapter}, merged.Fallbacks...) {
+		if ch.Provider == provider && ch.ID == chapterId {
+			continue
+		}
+		r.logger.Debug().Str("provider", ch.Provider).Str("chapterId", ch.ID).Msg("manga: Falling back to another provider")
+		container, fallbackErr := r.GetMangaPageContainer(ch.Provider, mediaId, ch.ID, doublePage, isOfflineRef)
+		if fallbackErr == nil {
+			return container, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", ch.Provider, fallbackErr))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func (r *Repository) getEntryPreference(mediaId int) (MangaEntryPreference, error) {
+	preferences, err := r.GetMangaPreferences()
+	if err != nil {
+		return MangaEntryPreference{}, err
+	}
+	return preferences.Entries[mediaId], nil
+}
+
+// mergeChapterContainers merges the chapter lists of the containers, given in priority order.
+// The returned chapters are sorted by chapter number.
+func mergeChapterContainers(containers []*ChapterContainer, filters map[string]MangaProviderFilter) []*MergedChapter {
+	ret := make([]*MergedChapter, 0)
+	byNumber := make(map[string]*MergedChapter)
+
+	for _, container := range containers {
+		if container == nil {
+			continue
+		}
+		filter := filters[container.Provider]
+		for _, ch := range container.Chapters {
+			if ch == nil || !filter.matches(ch) {
+				continue
+			}
+
+			number := normalizeChapterNumber(ch.Chapter)
+			// Chapters without a number cannot be matched with the other providers
+			if number == "" {
+				ret = append(ret, &MergedChapter{Chapter: ch})
+				continue
+			}
+
+			if merged, ok := byNumber[number]; ok {
+				merged.Fallbacks = append(merged.Fallbacks, ch)
+				continue
+			}
+			merged := &MergedChapter{Number: number, Chapter: ch}
+			byNumber[number] = merged
+			ret = append(ret, merged)
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *MergedChapter) int {
+		numA, errA := strconv.ParseFloat(a.Number, 64)
+		numB, errB := strconv.ParseFloat(b.Number, 64)
+		// Chapters without a number go last
+		if errA != nil || errB != nil {
+			return cmp.Compare(boolToInt(errA != nil), boolToInt(errB != nil))
+		}
+		return cmp.Compare(numA, numB)
+	})
+
+	return ret
+}
+
+func findMergedChapter(chapters []*MergedChapter, provider string, chapterId string) (*MergedChapter, bool) {
+	for _, merged := range chapters {
+		if merged.Chapter.Provider == provider && merged.Chapter.ID == chapterId {
+			return merged, true
+		}
+		for _, ch := range merged.Fallbacks {
+			if ch.Provider == provider && ch.ID == chapterId {
+				return merged, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// matches returns true if the chapter passes the scanlator and language filters.
+func (f MangaProviderFilter) matches(ch *hibikemanga.ChapterDetails) bool {
+	if f.Language != "" && ch.Language != f.Language {
+		return false
+	}
+	if len(f.Scanlators) > 0 && !slices.Contains(f.Scanlators, ch.Scanlator) {
+		return false
+	}
+	return true
+}
+
+// normalizeChapterNumber returns the chapter number in a form that can be compared across providers, e.g. "01" and "1.0" become "1".
+func normalizeChapterNumber(number string) string {
+	number = strings.TrimSpace(number)
+	if number == "" {
+		return ""
+	}
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return strings.ToLower(number)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
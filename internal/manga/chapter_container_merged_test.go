@@ -0,0 +1,115 @@
+package manga
+
+import (
+	"seanime/internal/database/models"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/testmocks"
+	"seanime/internal/testutil"
+	"seanime/internal/util"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeChapterContainers(t *testing.T) {
+	containers := []*ChapterContainer{
+		{
+			Provider: "provider-a",
+			Chapters: []*hibikemanga.ChapterDetails{
+				{Provider: "provider-a", ID: "a-2", Chapter: "2", Language: "en"},
+				{Provider: "provider-a", ID: "a-1", Chapter: "1", Language: "en"},
+				{Provider: "provider-a", ID: "a-1-fr", Chapter: "1", Language: "fr"},
+			},
+		},
+		nil,
+		{
+			Provider: "provider-b",
+			Chapters: []*hibikemanga.ChapterDetails{
+				{Provider: "provider-b", ID: "b-1", Chapter: "01", Scanlator: "Group B"},
+				{Provider: "provider-b", ID: "b-1-other", Chapter: "1.0", Scanlator: "Other"},
+				{Provider: "provider-b", ID: "b-3", Chapter: "3", Scanlator: "Group B"},
+				{Provider: "provider-b", ID: "b-extra", Chapter: "", Scanlator: "Group B"},
+				{Provider: "provider-b", ID: "b-1.5", Chapter: "1.5", Scanlator: "Group B"},
+			},
+		},
+	}
+	filters := map[string]MangaProviderFilter{
+		"provider-a": {Language: "en"},
+		"provider-b": {Scanlators: []string{"Group B"}},
+	}
+
+	merged := mergeChapterContainers(containers, filters)
+	require.Len(t, merged, 5)
+
+	require.Equal(t, "1", merged[0].Number)
+	require.Equal(t, "a-1", merged[0].Chapter.ID)
+	require.Len(t, merged[0].Fallbacks, 1)
+	require.Equal(t, "b-1", merged[0].Fallbacks[0].ID)
+
+	require.Equal(t, "1.5", merged[1].Number)
+	require.Equal(t, "provider-b", merged[1].Chapter.Provider)
+	require.Equal(t, "a-2", merged[2].Chapter.ID)
+	require.Empty(t, merged[2].Fallbacks)
+	require.Equal(t, "b-3", merged[3].Chapter.ID)
+	// Chapters without a number are kept at the end
+	require.Equal(t, "b-extra", merged[4].Chapter.ID)
+	require.Empty(t, merged[4].Number)
+
+	found, ok := findMergedChapter(merged, "provider-b", "b-1")
+	require.True(t, ok)
+	require.Equal(t, "a-1", found.Chapter.ID)
+}
+
+func TestGetMangaPageContainerWithFallback(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	repository := NewTestRepositoryWithEnv(env, env.NewDatabase("manga_merged_chapters"))
+	repository.SetSettings(&models.Settings{Manga: &models.MangaSettings{}})
+
+	// Provider A lists the chapter but cannot serve its pages
+	providerA := testmocks.NewFakeMangaProviderBuilder().
+		WithSearchResults(&hibikemanga.SearchResult{ID: "manga-a", Title: "Manga"}).
+		WithChapters("manga-a", &hibikemanga.ChapterDetails{ID: "a-1", Chapter: "1"}).
+		Build()
+	providerB := testmocks.NewFakeMangaProviderBuilder().
+		WithSearchResults(&hibikemanga.SearchResult{ID: "manga-b", Title: "Manga"}).
+		WithChapters("manga-b",
+			&hibikemanga.ChapterDetails{ID: "b-1", Chapter: "1"},
+			&hibikemanga.ChapterDetails{ID: "b-2", Chapter: "2"},
+		).
+		WithPages("b-1", &hibikemanga.ChapterPage{URL: "https://cdn.example.com/b-1/0.png", Index: 0}).
+		Build()
+	for id, provider := range map[string]*testmocks.FakeMangaProvider{"provider-a": providerA, "provider-b": providerB} {
+		repository.extensionBankRef.Get().Set(id, extension.NewMangaProviderExtension(&extension.Extension{
+			ID: id, Name: id, Type: extension.TypeMangaProvider,
+		}, provider))
+	}
+
+	title := "Manga"
+	_, err := repository.GetMergedMangaChapterContainer(&GetMergedMangaChapterContainerOptions{MediaId: 1, Titles: []*string{&title}})
+	require.ErrorIs(t, err, ErrNoMergedProviders)
+
+	_, err = repository.PatchPreference(1, &MangaPreferencePatch{MergedProviders: new([]string{"provider-a", " provider-b", "provider-a"})}, false)
+	require.NoError(t, err)
+
+	container, err := repository.GetMergedMangaChapterContainer(&GetMergedMangaChapterContainerOptions{MediaId: 1, Titles: []*string{&title}})
+	require.NoError(t, err)
+	require.Equal(t, []string{"provider-a", "provider-b"}, container.Providers)
+	require.Len(t, container.Chapters, 2)
+	require.Equal(t, "provider-a", container.Chapters[0].Chapter.Provider)
+	require.Equal(t, "provider-b", container.Chapters[1].Chapter.Provider)
+
+	// Without fallback the chapter is unavailable
+	_, err = repository.GetMangaPageContainer("provider-a", 1, "a-1", false, util.NewRef(false))
+	require.Error(t, err)
+
+	pages, err := repository.GetMangaPageContainerWithFallback("provider-a", 1, "a-1", false, util.NewRef(false))
+	require.NoError(t, err)
+	require.Equal(t, "provider-b", pages.Provider)
+	require.Equal(t, "b-1", pages.ChapterId)
+	require.Len(t, pages.Pages, 1)
+
+	// No other provider has the chapter
+	_, err = repository.GetMangaPageContainerWithFallback("provider-b", 1, "b-2", false, util.NewRef(false))
+	require.Error(t, err)
+}
@@ -0,0 +1,258 @@
+package manga
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"seanime/internal/extension"
+	manga_providers "seanime/internal/manga/providers"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ChapterFormat is the format of a chapter stored on disk.
+type ChapterFormat string
+
+const (
+	// ChapterFormatImages is a directory of images, downloaded chapters use this format
+	ChapterFormatImages ChapterFormat = "images"
+	ChapterFormatCBZ    ChapterFormat = "cbz"
+	ChapterFormatCBR    ChapterFormat = "cbr"
+	ChapterFormatPDF    ChapterFormat = "pdf"
+)
+
+var ErrChapterPagesUnavailable = errors.New("the pages of this chapter cannot be read individually")
+
+// ChapterFiles gives access to the pages of a downloaded chapter or a chapter of the local provider.
+type ChapterFiles struct {
+	// Path is the directory or the file of the chapter
+	Path    string
+	Format  ChapterFormat
+	ModTime time.Time
+	// pages are the file names relative to Path or the entries of the archive, in reading order.
+	// Empty for formats whose pages cannot be read individually.
+	pages []string
+}
+
+// GetChapterFiles returns the files of a downloaded chapter or a chapter of the local provider.
+func (r *Repository) GetChapterFiles(provider string, mediaId int, chapterId string) (*ChapterFiles, error) {
+	if provider == manga_providers.LocalProvider {
+		return r.getLocalChapterFiles(chapterId)
+	}
+
+	chapterDir, err := r.findDownloadedChapterDir(provider, mediaId, chapterId)
+	if err != nil {
+		return nil, err
+	}
+
+	registry, err := r.readDownloadedChapterRegistry(chapterDir)
+	if err != nil {
+		return nil, err
+	}
+
+	path := filepath.Join(r.downloadDir, chapterDir)
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &ChapterFiles{
+		Path:    path,
+		Format:  ChapterFormatImages,
+		ModTime: stat.ModTime(),
+		pages:   make([]string, 0, len(*registry)),
+	}
+	for _, index := range slices.Sorted(maps.Keys(*registry)) {
+		ret.pages = append(ret.pages, (*registry)[index].Filename)
+	}
+
+	return ret, nil
+}
+
+func (r *Repository) getLocalChapterFiles(chapterId string) (*ChapterFiles, error) {
+	providerExtension, ok := extension.GetExtension[extension.MangaProviderExtension](r.extensionBankRef.Get(), manga_providers.LocalProvider)
+	if !ok {
+		return nil, ErrChapterNotFound
+	}
+	localProvider, ok := providerExtension.GetProvider().(*manga_providers.Local)
+	if !ok {
+		return nil, ErrChapterNotFound
+	}
+
+	path, err := localProvider.GetChapterPath(chapterId)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := os.Stat(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrChapterNotFound
+		}
+		return nil, err
+	}
+
+	ret := &ChapterFiles{
+		Path:    path,
+		ModTime: stat.ModTime(),
+	}
+
+	if stat.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		names := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				names = append(names, entry.Name())
+			}
+		}
+		ret.Format = ChapterFormatImages
+		ret.pages = manga_providers.SortPageFilenames(names)
+		return ret, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".cbz", ".zip":
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+
+		names := make([]string, 0, len(zr.File))
+		for _, f := range zr.File {
+			if !f.FileInfo().IsDir() {
+				names = append(names, f.Name)
+			}
+		}
+		ret.Format = ChapterFormatCBZ
+		ret.pages = manga_providers.SortPageFilenames(names)
+	case ".cbr":
+		ret.Format = ChapterFormatCBR
+	case ".pdf":
+		ret.Format = ChapterFormatPDF
+	default:
+		return nil, fmt.Errorf("manga: Unsupported chapter file: %s", filepath.Base(path))
+	}
+
+	return ret, nil
+}
+
+// PageCount returns the number of pages that can be read individually.
+func (f *ChapterFiles) PageCount() int {
+	return len(f.pages)
+}
+
+// PageName returns the file name of a page.
+func (f *ChapterFiles) PageName(index int) (string, bool) {
+	if index < 0 || index >= len(f.pages) {
+		return "", false
+	}
+	return filepath.Base(f.pages[index]), true
+}
+
+// OpenPage opens a page of the chapter.
+func (f *ChapterFiles) OpenPage(index int) (io.ReadCloser, error) {
+	if len(f.pages) == 0 {
+		return nil, ErrChapterPagesUnavailable
+	}
+	if index < 0 || index >= len(f.pages) {
+		return nil, fmt.Errorf("manga: Page %d not found", index)
+	}
+
+	switch f.Format {
+	case ChapterFormatImages:
+		return os.Open(filepath.Join(f.Path, f.pages[index]))
+	case ChapterFormatCBZ:
+		zr, err := zip.OpenReader(f.Path)
+		if err != nil {
+			return nil, err
+		}
+		page, err := openArchiveEntry(&zr.Reader, f.pages[index])
+		if err != nil {
+			_ = zr.Close()
+			return nil, err
+		}
+		return &archivePage{ReadCloser: page, archive: zr}, nil
+	default:
+		return nil, ErrChapterPagesUnavailable
+	}
+}
+
+// WalkPages calls fn with each page of the chapter in reading order.
+func (f *ChapterFiles) WalkPages(fn func(name string, r io.Reader) error) error {
+	if len(f.pages) == 0 {
+		return ErrChapterPagesUnavailable
+	}
+
+	switch f.Format {
+	case ChapterFormatImages:
+		for _, name := range f.pages {
+			err := func() error {
+				file, err := os.Open(filepath.Join(f.Path, name))
+				if err != nil {
+					return err
+				}
+				defer file.Close()
+				return fn(filepath.Base(name), file)
+			}()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case ChapterFormatCBZ:
+		zr, err := zip.OpenReader(f.Path)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+
+		for _, name := range f.pages {
+			err := func() error {
+				page, err := openArchiveEntry(&zr.Reader, name)
+				if err != nil {
+					return err
+				}
+				defer page.Close()
+				return fn(filepath.Base(name), page)
+			}()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return ErrChapterPagesUnavailable
+	}
+}
+
+func openArchiveEntry(zr *zip.Reader, name string) (io.ReadCloser, error) {
+	for _, file := range zr.File {
+		if file.Name == name {
+			return file.Open()
+		}
+	}
+	return nil, fmt.Errorf("manga: Archive entry %s not found", name)
+}
+
+// archivePage closes the archive along with the page.
+type archivePage struct {
+	io.ReadCloser
+	archive io.Closer
+}
+
+func (p *archivePage) Close() error {
+	err := p.ReadCloser.Close()
+	if cerr := p.archive.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -102,6 +102,8 @@ func (r *Repository) GetMangaPageContainer(
 		pageDimensions, _ := r.getPageDimensions(doublePage, provider, mediaId, chapterId, container.Pages)
 		container.PageDimensions = pageDimensions
 
+		r.registerPageContainer(container)
+
 		r.logger.Debug().Str("key", pageContainerKey).Msg("manga: Page Container Cache HIT")
 		return container, nil
 	}
@@ -171,6 +173,7 @@ func (r *Repository) GetMangaPageContainer(
 		if err != nil {
 			r.logger.Warn().Err(err).Msg("manga: Failed to populate cache")
 		}
+		r.registerPageContainer(container)
 	}
 
 	r.logger.Debug().Str("key", pageContainerKey).Msg("manga: Retrieved pages")
@@ -212,7 +215,7 @@ func (r *Repository) getPageDimensions(enabled bool, provider string, mediaId in
 			if page.Buf != nil {
 				buf = page.Buf
 			} else {
-				buf, err = manga_providers.GetImageByProxy(page.URL, page.Headers)
+				buf, err = r.getPageImage(provider, mediaId, chapterId, page)
 				if err != nil {
 					return
 				}
@@ -10,6 +10,7 @@ import (
 	"seanime/internal/events"
 	"seanime/internal/hook"
 	chapter_downloader "seanime/internal/manga/downloader"
+	"seanime/internal/manga/pageprocess"
 	manga_providers "seanime/internal/manga/providers"
 	"seanime/internal/util"
 	"seanime/internal/util/filecache"
@@ -98,6 +99,8 @@ func NewDownloader(opts *NewDownloaderOptions) *Downloader {
 		WSEventManager: opts.WSEventManager,
 		Database:       opts.Database,
 		DownloadDir:    opts.DownloadDir,
+
+		AuthorizePageRequest: opts.Repository.authorizePageRequest,
 	})
 
 	go d.hydrateMediaMap()
@@ -280,6 +283,41 @@ func (d *Downloader) StopChapterDownloadQueue() {
 	d.chapterDownloader.Stop()
 }
 
+// SetPageProcessingOptions sets the processing applied to the pages of downloaded chapters.
+func (d *Downloader) SetPageProcessingOptions(settings *models.MangaSettings) {
+	d.chapterDownloader.SetPageProcessingOptions(NewPageProcessingOptions(settings))
+}
+
+// ProcessChapters applies the page processing options to chapters that have already been downloaded.
+func (d *Downloader) ProcessChapters(ids []chapter_downloader.DownloadID) (err error) {
+	for _, id := range ids {
+		if err = d.chapterDownloader.ProcessChapter(id); err != nil {
+			d.logger.Error().Err(err).Msgf("manga downloader: Failed to process chapter %s", id.ChapterId)
+			return err
+		}
+	}
+	return nil
+}
+
+// NewPageProcessingOptions returns the page processing options of the settings, nil if no processing is enabled.
+func NewPageProcessingOptions(settings *models.MangaSettings) *pageprocess.Options {
+	if settings == nil {
+		return nil
+	}
+	opts := &pageprocess.Options{
+		SplitSpreads: settings.PageSplitSpreads,
+		LeftToRight:  settings.PageLeftToRight,
+		TrimBorders:  settings.PageTrimBorders,
+		Grayscale:    settings.PageGrayscale,
+		Format:       pageprocess.Format(settings.PageFormat),
+		Quality:      settings.PageQuality,
+	}
+	if !opts.IsEnabled() {
+		return nil
+	}
+	return opts
+}
+
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 type (
@@ -12,8 +12,10 @@ import (
 	"seanime/internal/database/db"
 	"seanime/internal/events"
 	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/manga/pageprocess"
 	manga_providers "seanime/internal/manga/providers"
 	"seanime/internal/util"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -47,6 +49,11 @@ type (
 		cancelCh            chan struct{}   // Close to cancel the download process
 		runCh               chan *QueueInfo // Receives a signal to download the next item
 		chapterDownloadedCh chan DownloadID // Sends a signal when a chapter has been downloaded
+		// processingOptions are applied to the pages as they are downloaded, nil if disabled
+		processingOptions *pageprocess.Options
+		processingMu      sync.RWMutex
+		// authorizePageRequest adds the credentials of the provider to the request of a page image, can be nil
+		authorizePageRequest func(url string) (reqUrl string, headers map[string]string, ok bool)
 	}
 
 	//+-------------------------------------------------------------------------------------------------------------------+
@@ -70,6 +77,8 @@ type (
 		Size        int64  `json:"size"`
 		Width       int    `json:"width"`
 		Height      int    `json:"height"`
+		// Processed is true if the page was modified by the processing pipeline, Width and Height are the processed dimensions
+		Processed bool `json:"processed,omitempty"`
 	}
 )
 
@@ -79,6 +88,9 @@ type (
 		WSEventManager events.WSEventManagerInterface
 		DownloadDir    string
 		Database       *db.Database
+		// AuthorizePageRequest returns the request of a page image that requires the credentials of its provider.
+		// The credentials are not stored in the pages of the queue, which are sent to the client.
+		AuthorizePageRequest func(url string) (reqUrl string, headers map[string]string, ok bool)
 	}
 
 	DownloadOptions struct {
@@ -99,6 +111,8 @@ func NewDownloader(opts *NewDownloaderOptions) *Downloader {
 		runCh:               runCh,
 		queue:               NewQueue(opts.Database, opts.Logger, opts.WSEventManager, runCh),
 		chapterDownloadedCh: make(chan DownloadID, 100),
+
+		authorizePageRequest: opts.AuthorizePageRequest,
 	}
 
 	return d
@@ -224,7 +238,8 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 
 	cd.logger.Debug().Msgf("chapter downloader: Downloading chapter %s images to %s", queueInfo.ChapterId, destination)
 
-	registry := make(Registry)
+	// Downloaded pages by page index, a split spread results in two pages
+	pages := make(map[int][]PageInfo)
 
 	// calculateBatchSize calculates the batch size based on the number of URLs.
 	calculateBatchSize := func(numURLs int) int {
@@ -246,7 +261,7 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 	for _, page := range queueInfo.Pages {
 		semaphore <- struct{}{} // Acquire semaphore
 		wg.Add(1)
-		go func(page *hibikemanga.ChapterPage, registry *Registry) {
+		go func(page *hibikemanga.ChapterPage) {
 			defer func() {
 				<-semaphore // Release semaphore
 				wg.Done()
@@ -256,14 +271,14 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 				//cd.logger.Warn().Msg("chapter downloader: Download goroutine canceled")
 				return
 			default:
-				cd.downloadPage(page, destination, registry)
+				cd.downloadPage(page, destination, pages)
 			}
-		}(page, &registry)
+		}(page)
 	}
 	wg.Wait()
 
 	// Write the registry
-	_ = registry.save(queueInfo, destination, cd.logger)
+	_ = saveRegistry(queueInfo, pages, destination, cd.logger)
 
 	cd.queue.HasCompleted(queueInfo)
 
@@ -279,8 +294,9 @@ func (cd *Downloader) downloadChapterImages(queueInfo *QueueInfo) (err error) {
 }
 
 // downloadPage downloads a single page from the URL and saves it to the destination directory.
-// It also updates the Registry with the page information.
-func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination string, registry *Registry) {
+// The page is processed if processing options are set.
+// It also adds the page information to pages.
+func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination string, pages map[int][]PageInfo) {
 
 	defer util.HandlePanicInModuleThen("manga/downloader/downloadImage", func() {
 	})
@@ -289,7 +305,14 @@ func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination st
 
 	imgID := fmt.Sprintf("%02d", page.Index+1)
 
-	buf, err := manga_providers.GetImageByProxy(page.URL, page.Headers)
+	reqUrl, headers := page.URL, page.Headers
+	if cd.authorizePageRequest != nil {
+		if authorizedUrl, authorizedHeaders, ok := cd.authorizePageRequest(page.URL); ok {
+			reqUrl, headers = authorizedUrl, authorizedHeaders
+		}
+	}
+
+	buf, err := manga_providers.GetImageByProxy(reqUrl, headers)
 	if err != nil {
 		cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to get image from URL %s", page.URL)
 		return
@@ -302,43 +325,85 @@ func (cd *Downloader) downloadPage(page *hibikemanga.ChapterPage, destination st
 		return
 	}
 
-	filename := imgID + "." + format
-
-	// Create the file
-	filePath := filepath.Join(destination, filename)
-	file, err := os.Create(filePath)
-	if err != nil {
-		cd.logger.Error().Err(err).Msgf("chapter downloader: Failed to create file for image %s", imgID)
-		return
+	processed := []*pageprocess.Page{{Buf: buf, Format: format, Width: width, Height: height}}
+	if opts := cd.getPageProcessingOptions(); opts != nil {
+		if ret, err := pageprocess.Process(buf, opts); err == nil {
+			processed = ret
+		} else {
+			// Keep the original page, e.g. AVIF pages cannot be decoded
+			cd.logger.Warn().Err(err).Msgf("chapter downloader: Failed to process image from URL %s", page.URL)
+		}
 	}
-	defer file.Close()
 
-	// Copy the image data to the file
-	_, err = io.Copy(file, bytes.NewReader(buf))
+	infos, err := writePages(destination, imgID, page.URL, processed)
 	if err != nil {
 		cd.logger.Error().Err(err).Msgf("image downloader: Failed to write image data to file for image from %s", page.URL)
 		return
 	}
 
-	// Update registry
 	cd.downloadMu.Lock()
-	(*registry)[page.Index] = PageInfo{
-		Index:       page.Index,
-		Width:       width,
-		Height:      height,
-		Filename:    filename,
-		OriginalURL: page.URL,
-		Size:        int64(len(buf)),
-	}
+	pages[page.Index] = infos
 	cd.downloadMu.Unlock()
 
 	return
 }
 
+// writePages writes the pages resulting from the image to the destination directory.
+// The pages of a split image are suffixed with their position, e.g. 01-1.png and 01-2.png.
+func writePages(destination string, imgID string, originalURL string, pages []*pageprocess.Page) ([]PageInfo, error) {
+	ret := make([]PageInfo, 0, len(pages))
+	for i, page := range pages {
+		filename := imgID + "." + page.Format
+		if len(pages) > 1 {
+			filename = fmt.Sprintf("%s-%d.%s", imgID, i+1, page.Format)
+		}
+
+		// Create the file
+		file, err := os.Create(filepath.Join(destination, filename))
+		if err != nil {
+			return nil, err
+		}
+		// Copy the image data to the file
+		_, err = io.Copy(file, bytes.NewReader(page.Buf))
+		_ = file.Close()
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, PageInfo{
+			Width:       page.Width,
+			Height:      page.Height,
+			Filename:    filename,
+			OriginalURL: originalURL,
+			Size:        int64(len(page.Buf)),
+			Processed:   page.Modified,
+		})
+	}
+	return ret, nil
+}
+
+// newRegistry numbers the downloaded pages in reading order.
+func newRegistry(pages map[int][]PageInfo) Registry {
+	indexes := make([]int, 0, len(pages))
+	for index := range pages {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+
+	ret := make(Registry)
+	for _, index := range indexes {
+		for _, info := range pages[index] {
+			info.Index = len(ret)
+			ret[info.Index] = info
+		}
+	}
+	return ret
+}
+
 ////////////////////////
 
-// save saves the Registry content to a file in the chapter directory.
-func (r *Registry) save(queueInfo *QueueInfo, destination string, logger *zerolog.Logger) (err error) {
+// saveRegistry saves the Registry of the downloaded pages to a file in the chapter directory.
+func saveRegistry(queueInfo *QueueInfo, pages map[int][]PageInfo, destination string, logger *zerolog.Logger) (err error) {
 
 	defer util.HandlePanicInModuleThen("manga/downloader/save", func() {
 		err = fmt.Errorf("chapter downloader: Failed to save registry content")
@@ -347,7 +412,7 @@ func (r *Registry) save(queueInfo *QueueInfo, destination string, logger *zerolo
 	// Verify all images have been downloaded
 	allDownloaded := true
 	for _, page := range queueInfo.Pages {
-		if _, ok := (*r)[page.Index]; !ok {
+		if _, ok := pages[page.Index]; !ok {
 			allDownloaded = false
 			break
 		}
@@ -362,6 +427,12 @@ func (r *Registry) save(queueInfo *QueueInfo, destination string, logger *zerolo
 		return fmt.Errorf("chapter downloader: Not all images have been downloaded, operation aborted")
 	}
 
+	registry := newRegistry(pages)
+	return registry.save(destination)
+}
+
+// save writes the Registry to 📄 registry.json in the chapter directory.
+func (r *Registry) save(destination string) (err error) {
 	// Create registry file
 	var data []byte
 	data, err = json.Marshal(*r)
@@ -14,6 +14,7 @@ import (
 	"seanime/internal/database/db"
 	"seanime/internal/events"
 	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/manga/pageprocess"
 	"seanime/internal/testutil"
 
 	"github.com/goccy/go-json"
@@ -139,3 +140,100 @@ func TestDownloadChapterImagesWritesRegistry(t *testing.T) {
 	require.NoError(t, err)
 	require.Empty(t, queueItems)
 }
+
+func newTestSpreadPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 64, A: 255})
+		}
+	}
+	require.NoError(t, png.Encode(&buf, img))
+
+	return buf.Bytes()
+}
+
+func downloadTestChapter(t *testing.T, downloader *Downloader, database *db.Database, id DownloadID, pages []*hibikemanga.ChapterPage) Registry {
+	t.Helper()
+
+	require.NoError(t, downloader.AddToQueue(DownloadOptions{DownloadID: id, Pages: pages}))
+	require.NoError(t, database.UpdateChapterDownloadQueueItemStatus(id.Provider, id.MediaId, id.ChapterId, string(QueueStatusDownloading)))
+	downloader.queue.current = &QueueInfo{
+		DownloadID: id,
+		Pages:      pages,
+		Status:     QueueStatusDownloading,
+	}
+	require.NoError(t, downloader.downloadChapterImages(downloader.queue.current))
+
+	return readTestRegistry(t, downloader, id)
+}
+
+func readTestRegistry(t *testing.T, downloader *Downloader, id DownloadID) Registry {
+	t.Helper()
+
+	registryBytes, err := os.ReadFile(downloader.getChapterRegistryPath(id))
+	require.NoError(t, err)
+	var registry Registry
+	require.NoError(t, json.Unmarshal(registryBytes, &registry))
+	return registry
+}
+
+func TestDownloadChapterImagesProcessesPages(t *testing.T) {
+	downloader, database, _ := newTestDownloader(t)
+
+	spread := newTestSpreadPNG(t, 40, 20)
+	single := newTestSpreadPNG(t, 20, 30)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/png")
+		if r.URL.Path == "/spread.png" {
+			_, _ = w.Write(spread)
+			return
+		}
+		_, _ = w.Write(single)
+	}))
+	defer server.Close()
+
+	pages := []*hibikemanga.ChapterPage{
+		{Index: 0, URL: server.URL + "/spread.png"},
+		{Index: 1, URL: server.URL + "/single.png"},
+	}
+
+	downloader.SetPageProcessingOptions(&pageprocess.Options{SplitSpreads: true})
+	registry := downloadTestChapter(t, downloader, database, DownloadID{Provider: "test-provider", MediaId: 1, ChapterId: "chapter-1", ChapterNumber: "1"}, pages)
+
+	// The spread is split in two pages, the right half first
+	require.Len(t, registry, 3)
+	require.Equal(t, "01-1.png", registry[0].Filename)
+	require.Equal(t, "01-2.png", registry[1].Filename)
+	require.True(t, registry[0].Processed)
+	require.Equal(t, 20, registry[0].Width)
+	require.Equal(t, 20, registry[0].Height)
+	require.Equal(t, 1, registry[1].Index)
+	// The other page is unchanged
+	require.Equal(t, "02.png", registry[2].Filename)
+	require.False(t, registry[2].Processed)
+	require.Equal(t, int64(len(single)), registry[2].Size)
+
+	// Chapters downloaded before enabling processing
+	downloader.SetPageProcessingOptions(nil)
+	id := DownloadID{Provider: "test-provider", MediaId: 1, ChapterId: "chapter-2", ChapterNumber: "2"}
+	registry = downloadTestChapter(t, downloader, database, id, pages)
+	require.Len(t, registry, 2)
+	require.ErrorIs(t, downloader.ProcessChapter(id), ErrPageProcessingDisabled)
+
+	downloader.SetPageProcessingOptions(&pageprocess.Options{SplitSpreads: true, Grayscale: true})
+	require.NoError(t, downloader.ProcessChapter(id))
+	registry = readTestRegistry(t, downloader, id)
+	require.Len(t, registry, 3)
+	chapterDir := downloader.getChapterDownloadDir(id)
+	for _, info := range registry {
+		require.True(t, info.Processed)
+		_, err := os.Stat(filepath.Join(chapterDir, info.Filename))
+		require.NoError(t, err)
+	}
+	_, err := os.Stat(filepath.Join(chapterDir, "01.png"))
+	require.True(t, os.IsNotExist(err))
+}
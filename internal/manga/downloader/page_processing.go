@@ -0,0 +1,104 @@
+package chapter_downloader
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"seanime/internal/manga/pageprocess"
+	"slices"
+	"strings"
+
+	"github.com/goccy/go-json"
+)
+
+var ErrPageProcessingDisabled = errors.New("chapter downloader: page processing is disabled")
+
+// SetPageProcessingOptions sets the processing applied to the pages of the chapters downloaded from now on.
+// Processing is disabled if opts is nil or no step is enabled.
+func (cd *Downloader) SetPageProcessingOptions(opts *pageprocess.Options) {
+	cd.processingMu.Lock()
+	defer cd.processingMu.Unlock()
+
+	if !opts.IsEnabled() {
+		cd.processingOptions = nil
+		return
+	}
+	cd.processingOptions = opts
+}
+
+func (cd *Downloader) getPageProcessingOptions() *pageprocess.Options {
+	cd.processingMu.RLock()
+	defer cd.processingMu.RUnlock()
+	return cd.processingOptions
+}
+
+// ProcessChapter applies the page processing options to a chapter that has already been downloaded.
+// Pages that have already been processed are left as they are.
+func (cd *Downloader) ProcessChapter(id DownloadID) error {
+	opts := cd.getPageProcessingOptions()
+	if opts == nil {
+		return ErrPageProcessingDisabled
+	}
+
+	cd.mu.Lock()
+	defer cd.mu.Unlock()
+
+	destination := cd.getChapterDownloadDir(id)
+	data, err := os.ReadFile(filepath.Join(destination, "registry.json"))
+	if err != nil {
+		return err
+	}
+	var registry Registry
+	if err := json.Unmarshal(data, &registry); err != nil {
+		return err
+	}
+
+	pages := make(map[int][]PageInfo, len(registry))
+	var removed []string
+	modified := false
+	for index, info := range registry {
+		if info.Processed {
+			pages[index] = []PageInfo{info}
+			continue
+		}
+
+		buf, err := os.ReadFile(filepath.Join(destination, info.Filename))
+		if err != nil {
+			return err
+		}
+		processed, err := pageprocess.Process(buf, opts)
+		if err != nil || (len(processed) == 1 && !processed[0].Modified) {
+			pages[index] = []PageInfo{info}
+			continue
+		}
+
+		imgID := strings.TrimSuffix(info.Filename, filepath.Ext(info.Filename))
+		infos, err := writePages(destination, imgID, info.OriginalURL, processed)
+		if err != nil {
+			return err
+		}
+		pages[index] = infos
+		modified = true
+
+		// The original file is removed once the registry is updated
+		if !slices.ContainsFunc(infos, func(i PageInfo) bool { return i.Filename == info.Filename }) {
+			removed = append(removed, info.Filename)
+		}
+	}
+
+	if !modified {
+		return nil
+	}
+
+	registry = newRegistry(pages)
+	if err := registry.save(destination); err != nil {
+		return err
+	}
+
+	for _, filename := range removed {
+		_ = os.Remove(filepath.Join(destination, filename))
+	}
+
+	cd.logger.Debug().Msgf("chapter downloader: Processed the pages of chapter %s", id.ChapterId)
+	return nil
+}
@@ -2,6 +2,7 @@ package manga
 
 import (
 	"cmp"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -236,55 +237,17 @@ func (r *Repository) getDownloadedMangaPageContainer(
 ) (*PageContainer, error) {
 
 	// Check if the chapter is downloaded
-	found := false
-
-	// Read download directory
-	files, err := os.ReadDir(r.downloadDir)
+	chapterDir, err := r.findDownloadedChapterDir(provider, mediaId, chapterId) // e.g. manga_comick_123_10010_13
 	if err != nil {
-		r.logger.Error().Err(err).Msg("manga: Failed to read download directory")
 		return nil, err
 	}
 
-	chapterDir := "" // e.g. manga_comick_123_10010_13
-	for _, file := range files {
-		if file.IsDir() {
-
-			downloadId, ok := chapter_downloader.ParseChapterDirName(file.Name())
-			if !ok {
-				continue
-			}
-
-			if downloadId.Provider == provider &&
-				downloadId.MediaId == mediaId &&
-				downloadId.ChapterId == chapterId {
-				found = true
-				chapterDir = file.Name()
-				break
-			}
-		}
-	}
-
-	if !found {
-		return nil, ErrChapterNotDownloaded
-	}
-
 	r.logger.Debug().Msg("manga: Found downloaded chapter directory")
 
-	// Open registry file
-	registryFile, err := os.Open(filepath.Join(r.downloadDir, chapterDir, "registry.json"))
-	if err != nil {
-		r.logger.Error().Err(err).Msg("manga: Failed to open registry file")
-		return nil, err
-	}
-	defer registryFile.Close()
-
 	r.logger.Debug().Str("chapterId", chapterId).Msg("manga: Reading registry file")
 
-	// Read registry file
-	var pageRegistry *chapter_downloader.Registry
-	err = json.NewDecoder(registryFile).Decode(&pageRegistry)
+	pageRegistry, err := r.readDownloadedChapterRegistry(chapterDir)
 	if err != nil {
-		r.logger.Error().Err(err).Msg("manga: Failed to decode registry file")
 		return nil, err
 	}
 
@@ -321,3 +284,56 @@ func (r *Repository) getDownloadedMangaPageContainer(
 
 	return container, nil
 }
+
+// findDownloadedChapterDir returns the name of the directory of a downloaded chapter.
+func (r *Repository) findDownloadedChapterDir(provider string, mediaId int, chapterId string) (string, error) {
+	// Read download directory
+	files, err := os.ReadDir(r.downloadDir)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("manga: Failed to read download directory")
+		return "", err
+	}
+
+	for _, file := range files {
+		if !file.IsDir() {
+			continue
+		}
+
+		downloadId, ok := chapter_downloader.ParseChapterDirName(file.Name())
+		if !ok {
+			continue
+		}
+
+		if downloadId.Provider == provider &&
+			downloadId.MediaId == mediaId &&
+			downloadId.ChapterId == chapterId {
+			return file.Name(), nil
+		}
+	}
+
+	return "", ErrChapterNotDownloaded
+}
+
+// readDownloadedChapterRegistry reads the registry file of a downloaded chapter.
+func (r *Repository) readDownloadedChapterRegistry(chapterDir string) (*chapter_downloader.Registry, error) {
+	// Open registry file
+	registryFile, err := os.Open(filepath.Join(r.downloadDir, chapterDir, "registry.json"))
+	if err != nil {
+		r.logger.Error().Err(err).Msg("manga: Failed to open registry file")
+		return nil, err
+	}
+	defer registryFile.Close()
+
+	// Read registry file
+	var pageRegistry *chapter_downloader.Registry
+	err = json.NewDecoder(registryFile).Decode(&pageRegistry)
+	if err != nil {
+		r.logger.Error().Err(err).Msg("manga: Failed to decode registry file")
+		return nil, err
+	}
+	if pageRegistry == nil {
+		return nil, errors.New("manga: Empty registry file")
+	}
+
+	return pageRegistry, nil
+}
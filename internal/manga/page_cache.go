@@ -0,0 +1,378 @@
+package manga
+
+import (
+	"errors"
+	"fmt"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/manga/pagecache"
+	manga_providers "seanime/internal/manga/providers"
+	"seanime/internal/util"
+	proxies "seanime/internal/util/proxies"
+	"strconv"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sync/singleflight"
+)
+
+// Pages of provider chapters are served through the image proxy, which only receives the URL of the page.
+// The pages of the containers returned to the client are registered so that the proxy can find the chapter and page of a URL,
+// serve it from the page cache and prefetch the following pages.
+// The credentials of providers like Komga and Kavita are never sent to the client, they are added to the requests made by the server.
+
+const (
+	// DefaultPrefetchPages is the default number of pages fetched ahead of the page being read
+	DefaultPrefetchPages = 5
+	// maxRegisteredChapters is the number of chapters whose page URLs are remembered
+	maxRegisteredChapters = 50
+	// prefetchConcurrency is the number of pages fetched at the same time by the prefetcher
+	prefetchConcurrency = 2
+)
+
+type (
+	pageCacheManager struct {
+		logger        *zerolog.Logger
+		cache         *pagecache.Cache
+		prefetchPages int
+		fetch         func(url string, headers map[string]string) ([]byte, string, error)
+		// authorize returns the request of a page image that requires the credentials of its provider
+		authorize func(url string) (reqUrl string, headers map[string]string, ok bool)
+
+		// mu guards the registered chapters
+		mu       sync.Mutex
+		chapters []*registeredChapter
+		pages    map[string]*registeredPage // Indexed by URL
+
+		group       singleflight.Group
+		prefetchSem chan struct{}
+	}
+
+	registeredChapter struct {
+		provider  string
+		mediaId   int
+		chapterId string
+		pages     []*hibikemanga.ChapterPage
+		// nextPrefetched is true once the next chapter was prefetched
+		nextPrefetched bool
+	}
+
+	registeredPage struct {
+		chapter *registeredChapter
+		// position is the position of the page in the chapter, which is not always the page index
+		position int
+	}
+)
+
+func newPageCacheManager(logger *zerolog.Logger, cacheDir string) *pageCacheManager {
+	ret := &pageCacheManager{
+		logger:        logger,
+		prefetchPages: DefaultPrefetchPages,
+		fetch:         (&proxies.ImageProxy{}).GetImage,
+		pages:         make(map[string]*registeredPage),
+		prefetchSem:   make(chan struct{}, prefetchConcurrency),
+	}
+
+	if cacheDir != "" {
+		cache, err := pagecache.New(logger, cacheDir)
+		if err != nil {
+			logger.Error().Err(err).Msg("manga: Failed to open the page cache")
+		} else {
+			ret.cache = cache
+		}
+	}
+
+	return ret
+}
+
+func (c *registeredChapter) key(position int) pagecache.Key {
+	return pagecache.Key{
+		Provider:  c.provider,
+		MediaId:   c.mediaId,
+		ChapterId: c.chapterId,
+		Index:     c.pages[position].Index,
+	}
+}
+
+// SetPageCacheOptions sets the size budget of the page cache in bytes and the number of pages to prefetch.
+// A size of 0 disables eviction, 0 pages disables prefetching.
+func (r *Repository) SetPageCacheOptions(maxSize int64, prefetchPages int) {
+	if r.pageCache.cache != nil {
+		r.pageCache.cache.SetMaxSize(maxSize)
+	}
+	r.pageCache.mu.Lock()
+	r.pageCache.prefetchPages = prefetchPages
+	r.pageCache.mu.Unlock()
+}
+
+// GetPageCacheStats returns the stats of the page cache, nil if the cache is not available.
+func (r *Repository) GetPageCacheStats() *pagecache.Stats {
+	if r.pageCache.cache == nil {
+		return nil
+	}
+	return r.pageCache.cache.GetStats()
+}
+
+// ClearPageCache removes the cached pages of a media, or all the cached pages if mediaId is 0.
+func (r *Repository) ClearPageCache(mediaId int) error {
+	if r.pageCache.cache == nil {
+		return nil
+	}
+	if mediaId == 0 {
+		return r.pageCache.cache.Clear()
+	}
+	return r.pageCache.cache.RemoveMedia(mediaId)
+}
+
+// registerPageContainer remembers the pages of a chapter fetched from a provider.
+func (r *Repository) registerPageContainer(container *PageContainer) {
+	m := r.pageCache
+	if container == nil || len(container.Pages) == 0 {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for i, ch := range m.chapters {
+		if ch.provider == container.Provider && ch.mediaId == container.MediaId && ch.chapterId == container.ChapterId {
+			m.unregisterLocked(i)
+			break
+		}
+	}
+	if len(m.chapters) >= maxRegisteredChapters {
+		m.unregisterLocked(0)
+	}
+
+	ch := &registeredChapter{
+		provider:  container.Provider,
+		mediaId:   container.MediaId,
+		chapterId: container.ChapterId,
+		pages:     container.Pages,
+	}
+	m.chapters = append(m.chapters, ch)
+	for i, page := range ch.pages {
+		if page.URL != "" && page.Buf == nil {
+			m.pages[page.URL] = &registeredPage{chapter: ch, position: i}
+		}
+	}
+}
+
+func (m *pageCacheManager) unregisterLocked(i int) {
+	for _, page := range m.chapters[i].pages {
+		if p, ok := m.pages[page.URL]; ok && p.chapter == m.chapters[i] {
+			delete(m.pages, page.URL)
+		}
+	}
+	m.chapters = append(m.chapters[:i], m.chapters[i+1:]...)
+}
+
+// GetCachedImage returns the image of a registered page from the page cache, fetching and storing it if needed.
+// It also prefetches the following pages.
+// ok is false if the URL is not a registered page, in which case the caller fetches the image itself.
+func (r *Repository) GetCachedImage(url string, headers map[string]string) (buf []byte, contentType string, ok bool, err error) {
+	m := r.pageCache
+
+	var page *registeredPage
+	found := false
+	if m.cache != nil {
+		m.mu.Lock()
+		page, found = m.pages[url]
+		m.mu.Unlock()
+	}
+	if !found {
+		// Pages that require credentials are always fetched by the server
+		if _, _, authorized := m.authorizeRequest(url); authorized {
+			buf, contentType, err = m.fetchPage(url, headers)
+			return buf, contentType, true, err
+		}
+		return nil, "", false, nil
+	}
+
+	buf, contentType, err = m.getPage(page.chapter, page.position)
+	if err != nil {
+		return nil, "", true, err
+	}
+
+	go r.prefetch(page.chapter, page.position+1)
+
+	return buf, contentType, true, nil
+}
+
+// getPageImage returns the image of a page from the page cache, fetching and storing it if needed.
+func (r *Repository) getPageImage(provider string, mediaId int, chapterId string, page *hibikemanga.ChapterPage) ([]byte, error) {
+	m := r.pageCache
+	if m.cache == nil {
+		buf, _, err := m.fetchPage(page.URL, page.Headers)
+		return buf, err
+	}
+
+	key := pagecache.Key{Provider: provider, MediaId: mediaId, ChapterId: chapterId, Index: page.Index}
+	buf, _, err := m.getOrFetch(key, page.URL, page.Headers)
+	return buf, err
+}
+
+func (m *pageCacheManager) getPage(ch *registeredChapter, position int) ([]byte, string, error) {
+	page := ch.pages[position]
+	return m.getOrFetch(ch.key(position), page.URL, page.Headers)
+}
+
+// authorizeRequest returns the request of a page image with the credentials of its provider.
+// ok is false if the page does not require credentials.
+func (m *pageCacheManager) authorizeRequest(url string) (string, map[string]string, bool) {
+	if m.authorize == nil {
+		return "", nil, false
+	}
+	return m.authorize(url)
+}
+
+// fetchPage fetches a page image, adding the credentials of its provider if needed.
+func (m *pageCacheManager) fetchPage(url string, headers map[string]string) ([]byte, string, error) {
+	if reqUrl, reqHeaders, ok := m.authorizeRequest(url); ok {
+		url, headers = reqUrl, reqHeaders
+	}
+	return m.fetch(url, headers)
+}
+
+// getOrFetch returns the page from the cache or fetches it.
+// Concurrent requests for the same page, e.g. from the reader and the prefetcher, share the same fetch.
+func (m *pageCacheManager) getOrFetch(key pagecache.Key, url string, headers map[string]string) ([]byte, string, error) {
+	if buf, contentType, ok := m.cache.Get(key); ok {
+		return buf, contentType, nil
+	}
+
+	type result struct {
+		buf         []byte
+		contentType string
+	}
+
+	groupKey := key.Provider + "$" + strconv.Itoa(key.MediaId) + "$" + key.ChapterId + "$" + strconv.Itoa(key.Index)
+	v, err, _ := m.group.Do(groupKey, func() (interface{}, error) {
+		buf, contentType, err := m.fetchPage(url, headers)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.cache.Set(key, buf, contentType); err != nil && !errors.Is(err, pagecache.ErrNotImage) {
+			m.logger.Warn().Err(err).Msg("manga: Failed to cache page")
+		}
+		return &result{buf: buf, contentType: contentType}, nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	res := v.(*result)
+	return res.buf, res.contentType, nil
+}
+
+// prefetch fetches the pages of a chapter starting at the given position.
+// When the end of the chapter is within reach, the next chapter is prefetched as well.
+func (r *Repository) prefetch(ch *registeredChapter, from int) {
+	m := r.pageCache
+	if m.cache == nil {
+		return
+	}
+
+	m.mu.Lock()
+	count := m.prefetchPages
+	prefetchNext := count > 0 && from+count >= len(ch.pages) && !ch.nextPrefetched
+	if prefetchNext {
+		ch.nextPrefetched = true
+	}
+	m.mu.Unlock()
+
+	for position := from; position < min(from+count, len(ch.pages)); position++ {
+		key := ch.key(position)
+		if m.cache.Has(key) {
+			continue
+		}
+		m.prefetchSem <- struct{}{}
+		_, _, err := m.getOrFetch(key, ch.pages[position].URL, ch.pages[position].Headers)
+		<-m.prefetchSem
+		if err != nil {
+			m.logger.Debug().Err(err).Int("index", key.Index).Msg("manga: Failed to prefetch page")
+			return
+		}
+	}
+
+	if prefetchNext {
+		if err := r.prefetchNextChapter(ch); err != nil {
+			m.logger.Debug().Err(err).Str("chapterId", ch.chapterId).Msg("manga: Failed to prefetch next chapter")
+		}
+	}
+}
+
+// prefetchNextChapter fetches the page container of the chapter that follows the given one and its first pages.
+func (r *Repository) prefetchNextChapter(ch *registeredChapter) error {
+	var chapterContainer *ChapterContainer
+	containerBucket := r.getFcProviderBucket(ch.provider, ch.mediaId, bucketTypeChapter)
+	if found, _ := r.fileCacher.Get(containerBucket, getMangaChapterContainerCacheKey(ch.provider, ch.mediaId), &chapterContainer); !found {
+		return ErrNoChapters
+	}
+
+	next, ok := findNextChapter(chapterContainer.Chapters, ch.chapterId)
+	if !ok {
+		return nil
+	}
+
+	container, err := r.GetMangaPageContainer(ch.provider, ch.mediaId, next.ID, false, util.NewRef(false))
+	if err != nil {
+		return err
+	}
+	if container.IsDownloaded {
+		return nil
+	}
+
+	r.pageCache.mu.Lock()
+	var nextCh *registeredChapter
+	for _, registered := range r.pageCache.chapters {
+		if registered.provider == ch.provider && registered.mediaId == ch.mediaId && registered.chapterId == next.ID {
+			nextCh = registered
+		}
+	}
+	r.pageCache.mu.Unlock()
+	if nextCh == nil {
+		return fmt.Errorf("manga: Chapter %s not registered", next.ID)
+	}
+
+	r.logger.Debug().Str("chapterId", next.ID).Msg("manga: Prefetching next chapter")
+	r.prefetch(nextCh, 0)
+	return nil
+}
+
+// authorizePageRequest returns the request of a page image with the credentials of the provider it belongs to.
+// ok is false if no provider requires credentials for the URL.
+func (r *Repository) authorizePageRequest(url string) (reqUrl string, headers map[string]string, ok bool) {
+	defer util.HandlePanicInModuleThen("manga/authorizePageRequest", func() {})
+
+	extension.RangeExtensions(r.extensionBankRef.Get(), func(id string, ext extension.MangaProviderExtension) bool {
+		authorizer, isAuthorizer := ext.GetProvider().(manga_providers.PageRequestAuthorizer)
+		if !isAuthorizer {
+			return true
+		}
+		reqUrl, headers, ok = authorizer.AuthorizePageRequest(url)
+		return !ok
+	})
+	return
+}
+
+// findNextChapter returns the chapter that follows the given one in reading order.
+func findNextChapter(chapters []*hibikemanga.ChapterDetails, chapterId string) (*hibikemanga.ChapterDetails, bool) {
+	var current *hibikemanga.ChapterDetails
+	for _, c := range chapters {
+		if c.ID == chapterId {
+			current = c
+			break
+		}
+	}
+	if current == nil {
+		return nil, false
+	}
+
+	for _, c := range chapters {
+		if c.Index == current.Index+1 {
+			return c, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,196 @@
+package manga
+
+import (
+	"fmt"
+	"seanime/internal/database/models"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/manga/pagecache"
+	"seanime/internal/testmocks"
+	"seanime/internal/testutil"
+	"seanime/internal/util"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestPages(chapterId string, count int) []*hibikemanga.ChapterPage {
+	ret := make([]*hibikemanga.ChapterPage, 0, count)
+	for i := 0; i < count; i++ {
+		ret = append(ret, &hibikemanga.ChapterPage{
+			URL:     fmt.Sprintf("https://cdn.example.com/%s/%d.png", chapterId, i),
+			Index:   i,
+			Headers: map[string]string{"Referer": "https://example.com"},
+		})
+	}
+	return ret
+}
+
+func TestPageCacheServesAndPrefetchesPages(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	repository := NewTestRepositoryWithEnv(env, env.NewDatabase("manga_page_cache"))
+	repository.SetSettings(&models.Settings{Manga: &models.MangaSettings{}})
+	repository.SetPageCacheOptions(pagecache.DefaultMaxSize, 3)
+
+	provider := testmocks.NewFakeMangaProviderBuilder().
+		WithSearchResults(&hibikemanga.SearchResult{ID: "manga-1", Title: "Manga"}).
+		WithChapters("manga-1",
+			&hibikemanga.ChapterDetails{ID: "ch-1", Chapter: "1", Index: 0},
+			&hibikemanga.ChapterDetails{ID: "ch-2", Chapter: "2", Index: 1},
+		).
+		WithPages("ch-1", newTestPages("ch-1", 5)...).
+		WithPages("ch-2", newTestPages("ch-2", 5)...).
+		Build()
+	repository.extensionBankRef.Get().Set("provider-a", extension.NewMangaProviderExtension(&extension.Extension{
+		ID: "provider-a", Name: "Provider A", Type: extension.TypeMangaProvider,
+	}, provider))
+
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	repository.pageCache.fetch = func(url string, headers map[string]string) ([]byte, string, error) {
+		mu.Lock()
+		fetched[url]++
+		mu.Unlock()
+		return []byte("\x89PNG\r\n\x1a\n" + url), "image/png", nil
+	}
+	fetchCount := func(url string) int {
+		mu.Lock()
+		defer mu.Unlock()
+		return fetched[url]
+	}
+
+	title := "Manga"
+	_, err := repository.GetMangaChapterContainer(&GetMangaChapterContainerOptions{
+		Provider: "provider-a", MediaId: 1, Titles: []*string{&title}, skipCache: true,
+	})
+	require.NoError(t, err)
+
+	container, err := repository.GetMangaPageContainer("provider-a", 1, "ch-1", false, util.NewRef(false))
+	require.NoError(t, err)
+
+	// Unknown URLs are left to the proxy
+	_, _, ok, err := repository.GetCachedImage("https://other.example.com/1.png", nil)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	buf, contentType, ok, err := repository.GetCachedImage(container.Pages[0].URL, container.Pages[0].Headers)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "image/png", contentType)
+	require.Contains(t, string(buf), container.Pages[0].URL)
+
+	// The next pages are prefetched
+	require.Eventually(t, func() bool {
+		return fetchCount(container.Pages[3].URL) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Zero(t, fetchCount(container.Pages[4].URL))
+
+	// Pages are served from the cache
+	_, _, ok, err = repository.GetCachedImage(container.Pages[1].URL, container.Pages[1].Headers)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, 1, fetchCount(container.Pages[1].URL))
+
+	// Reading near the end of the chapter prefetches the next chapter
+	_, _, ok, err = repository.GetCachedImage(container.Pages[3].URL, container.Pages[3].Headers)
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Eventually(t, func() bool {
+		return fetchCount("https://cdn.example.com/ch-2/2.png") == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	require.Zero(t, fetchCount("https://cdn.example.com/ch-2/3.png"))
+
+	require.Eventually(t, func() bool {
+		return repository.GetPageCacheStats().Pages == 8
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, repository.ClearPageCache(1))
+	require.Zero(t, repository.GetPageCacheStats().Pages)
+}
+
+// authorizedTestProvider requires an API key for the pages of its server
+type authorizedTestProvider struct {
+	hibikemanga.Provider
+}
+
+func (p *authorizedTestProvider) AuthorizePageRequest(pageUrl string) (string, map[string]string, bool) {
+	if !strings.HasPrefix(pageUrl, "https://cdn.example.com/") {
+		return "", nil, false
+	}
+	return pageUrl + "?apiKey=secret", map[string]string{"X-API-Key": "secret"}, true
+}
+
+func TestPageCacheAuthorizesPageRequests(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	repository := NewTestRepositoryWithEnv(env, env.NewDatabase("manga_page_cache_auth"))
+	repository.SetSettings(&models.Settings{Manga: &models.MangaSettings{}})
+	repository.SetPageCacheOptions(pagecache.DefaultMaxSize, 0)
+
+	provider := testmocks.NewFakeMangaProviderBuilder().
+		WithSearchResults(&hibikemanga.SearchResult{ID: "manga-1", Title: "Manga"}).
+		WithChapters("manga-1", &hibikemanga.ChapterDetails{ID: "ch-1", Chapter: "1", Index: 0}).
+		WithPages("ch-1", newTestPages("ch-1", 2)...).
+		Build()
+	repository.extensionBankRef.Get().Set("provider-a", extension.NewMangaProviderExtension(&extension.Extension{
+		ID: "provider-a", Name: "Provider A", Type: extension.TypeMangaProvider,
+	}, &authorizedTestProvider{Provider: provider}))
+
+	var mu sync.Mutex
+	requests := make(map[string]map[string]string)
+	repository.pageCache.fetch = func(url string, headers map[string]string) ([]byte, string, error) {
+		mu.Lock()
+		requests[url] = headers
+		mu.Unlock()
+		return []byte("\x89PNG\r\n\x1a\n" + url), "image/png", nil
+	}
+
+	title := "Manga"
+	_, err := repository.GetMangaChapterContainer(&GetMangaChapterContainerOptions{
+		Provider: "provider-a", MediaId: 1, Titles: []*string{&title}, skipCache: true,
+	})
+	require.NoError(t, err)
+
+	container, err := repository.GetMangaPageContainer("provider-a", 1, "ch-1", false, util.NewRef(false))
+	require.NoError(t, err)
+
+	// Registered pages are fetched with the credentials
+	_, _, ok, err := repository.GetCachedImage(container.Pages[0].URL, nil)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// Unregistered pages of the provider are fetched by the server as well, even without a cache
+	repository.pageCache.cache = nil
+	_, _, ok, err = repository.GetCachedImage("https://cdn.example.com/other/0.png", map[string]string{"X-API-Key": "client"})
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.Equal(t, "secret", requests[container.Pages[0].URL+"?apiKey=secret"]["X-API-Key"])
+	require.Equal(t, "secret", requests["https://cdn.example.com/other/0.png?apiKey=secret"]["X-API-Key"])
+	require.Len(t, requests, 2)
+}
+
+func TestFindNextChapter(t *testing.T) {
+	chapters := []*hibikemanga.ChapterDetails{
+		{ID: "c", Index: 2},
+		{ID: "a", Index: 0},
+		{ID: "b", Index: 1},
+	}
+
+	next, ok := findNextChapter(chapters, "a")
+	require.True(t, ok)
+	require.Equal(t, "b", next.ID)
+
+	next, ok = findNextChapter(chapters, "b")
+	require.True(t, ok)
+	require.Equal(t, "c", next.ID)
+
+	_, ok = findNextChapter(chapters, "c")
+	require.False(t, ok)
+	_, ok = findNextChapter(chapters, "unknown")
+	require.False(t, ok)
+}
@@ -0,0 +1,413 @@
+package pagecache
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// The cache stores the images of manga pages fetched from provider hosts.
+// Pages are written under {dir}/{mediaId}/{chapter}/{index}{ext}, where the chapter directory is a hash of the provider and chapter ID.
+// An index of the files is kept in memory, the least recently read pages are removed when the cache exceeds its size budget.
+
+const (
+	// DefaultMaxSize is the default size budget of the cache
+	DefaultMaxSize int64 = 512 << 20
+	// evictionTarget is the fraction of the budget the cache is brought back to when it exceeds it
+	evictionTarget = 0.9
+	tmpExt         = ".tmp"
+	defaultExt     = ".bin"
+)
+
+var ErrNotImage = errors.New("pagecache: not an image")
+
+// extensions maps the image types to the extension of the cached files, the content type is recovered from the extension.
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+	"image/avif": ".avif",
+	"image/bmp":  ".bmp",
+}
+
+// Key identifies a page.
+type Key struct {
+	Provider  string
+	MediaId   int
+	ChapterId string
+	Index     int
+}
+
+// path returns the path of the page relative to the cache directory, without extension.
+func (k Key) path() string {
+	sum := sha256.Sum256([]byte(k.Provider + "$" + k.ChapterId))
+	return filepath.Join(strconv.Itoa(k.MediaId), hex.EncodeToString(sum[:8]), strconv.Itoa(k.Index))
+}
+
+type (
+	Cache struct {
+		logger *zerolog.Logger
+		dir    string
+
+		// mu guards the index
+		mu        sync.Mutex
+		entries   map[string]*entry // Indexed by Key.path
+		size      int64
+		maxSize   int64
+		hits      int64
+		misses    int64
+		evictions int64
+	}
+
+	entry struct {
+		mediaId    int
+		ext        string
+		size       int64
+		accessedAt time.Time
+	}
+
+	Stats struct {
+		Size      int64 `json:"size"`
+		MaxSize   int64 `json:"maxSize"`
+		Pages     int   `json:"pages"`
+		Hits      int64 `json:"hits"`
+		Misses    int64 `json:"misses"`
+		Evictions int64 `json:"evictions"`
+		// Media is sorted by size, largest first
+		Media []*MediaStats `json:"media"`
+	}
+
+	MediaStats struct {
+		MediaId        int        `json:"mediaId"`
+		Size           int64      `json:"size"`
+		Pages          int        `json:"pages"`
+		LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
+	}
+)
+
+// New opens the cache of the given directory and indexes the pages it contains.
+func New(logger *zerolog.Logger, dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		logger:  logger,
+		dir:     dir,
+		entries: make(map[string]*entry),
+		maxSize: DefaultMaxSize,
+	}
+
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Cache) load() error {
+	return filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		// Leftover from an interrupted write
+		if strings.HasSuffix(path, tmpExt) {
+			_ = os.Remove(path)
+			return nil
+		}
+
+		rel, err := filepath.Rel(c.dir, path)
+		if err != nil {
+			return nil
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if len(parts) != 3 {
+			return nil
+		}
+		mediaId, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+
+		ext := filepath.Ext(rel)
+		c.entries[strings.TrimSuffix(rel, ext)] = &entry{
+			mediaId:    mediaId,
+			ext:        ext,
+			size:       info.Size(),
+			accessedAt: info.ModTime(),
+		}
+		c.size += info.Size()
+		return nil
+	})
+}
+
+// SetMaxSize sets the size budget of the cache in bytes, 0 disables eviction.
+func (c *Cache) SetMaxSize(size int64) {
+	c.mu.Lock()
+	c.maxSize = size
+	removed := c.evictLocked()
+	c.mu.Unlock()
+	c.removeFiles(removed)
+}
+
+// Has returns true if the page is cached.
+func (c *Cache) Has(key Key) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.entries[key.path()]
+	return ok
+}
+
+// Get returns the image of a page and its content type.
+func (c *Cache) Get(key Key) ([]byte, string, bool) {
+	path := key.path()
+
+	c.mu.Lock()
+	e, ok := c.entries[path]
+	if !ok {
+		c.misses++
+		c.mu.Unlock()
+		return nil, "", false
+	}
+	ext := e.ext
+	c.mu.Unlock()
+
+	filename := filepath.Join(c.dir, path+ext)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		// The file was removed by something else
+		c.mu.Lock()
+		c.removeEntryLocked(path)
+		c.misses++
+		c.mu.Unlock()
+		return nil, "", false
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[path]; ok {
+		e.accessedAt = now
+	}
+	c.hits++
+	c.mu.Unlock()
+	// The modification time keeps track of the last access across restarts
+	_ = os.Chtimes(filename, now, now)
+
+	return data, contentTypeFromExt(ext), true
+}
+
+// Set stores the image of a page.
+// Returns ErrNotImage if the data is not an image, e.g. an error page returned by the host.
+func (c *Cache) Set(key Key, data []byte, contentType string) error {
+	ext, ok := extFromContentType(contentType, data)
+	if !ok {
+		return ErrNotImage
+	}
+
+	path := key.path()
+	filename := filepath.Join(c.dir, path+ext)
+	if err := os.MkdirAll(filepath.Dir(filename), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filename), "*"+tmpExt)
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filename)
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	c.mu.Lock()
+	var removed []string
+	if prev, ok := c.entries[path]; ok && prev.ext != ext {
+		removed = append(removed, path+prev.ext)
+	}
+	c.removeEntryLocked(path)
+	c.entries[path] = &entry{
+		mediaId:    key.MediaId,
+		ext:        ext,
+		size:       int64(len(data)),
+		accessedAt: time.Now(),
+	}
+	c.size += int64(len(data))
+	removed = append(removed, c.evictLocked()...)
+	c.mu.Unlock()
+
+	c.removeFiles(removed)
+	return nil
+}
+
+// RemoveMedia removes the pages of all the chapters of a media.
+func (c *Cache) RemoveMedia(mediaId int) error {
+	c.mu.Lock()
+	for path, e := range c.entries {
+		if e.mediaId == mediaId {
+			c.removeEntryLocked(path)
+		}
+	}
+	c.mu.Unlock()
+
+	return os.RemoveAll(filepath.Join(c.dir, strconv.Itoa(mediaId)))
+}
+
+// Clear removes all the pages.
+func (c *Cache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(c.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	c.entries = make(map[string]*entry)
+	c.size = 0
+	return nil
+}
+
+func (c *Cache) GetStats() *Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := &Stats{
+		Size:      c.size,
+		MaxSize:   c.maxSize,
+		Pages:     len(c.entries),
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Media:     make([]*MediaStats, 0),
+	}
+
+	media := make(map[int]*MediaStats)
+	for _, e := range c.entries {
+		ms, ok := media[e.mediaId]
+		if !ok {
+			ms = &MediaStats{MediaId: e.mediaId}
+			media[e.mediaId] = ms
+			ret.Media = append(ret.Media, ms)
+		}
+		ms.Size += e.size
+		ms.Pages++
+		if ms.LastAccessedAt == nil || e.accessedAt.After(*ms.LastAccessedAt) {
+			ms.LastAccessedAt = new(e.accessedAt)
+		}
+	}
+
+	slices.SortFunc(ret.Media, func(a, b *MediaStats) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.MediaId, b.MediaId))
+	})
+
+	return ret
+}
+
+func (c *Cache) removeEntryLocked(path string) {
+	if e, ok := c.entries[path]; ok {
+		c.size -= e.size
+		delete(c.entries, path)
+	}
+}
+
+// evictLocked removes the least recently read pages from the index until the cache is within its budget.
+// It returns the files to remove.
+func (c *Cache) evictLocked() []string {
+	if c.maxSize <= 0 || c.size <= c.maxSize {
+		return nil
+	}
+
+	type candidate struct {
+		path       string
+		accessedAt time.Time
+	}
+	candidates := make([]candidate, 0, len(c.entries))
+	for path, e := range c.entries {
+		candidates = append(candidates, candidate{path: path, accessedAt: e.accessedAt})
+	}
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		return a.accessedAt.Compare(b.accessedAt)
+	})
+
+	target := int64(float64(c.maxSize) * evictionTarget)
+	var removed []string
+	for _, cand := range candidates {
+		if c.size <= target {
+			break
+		}
+		removed = append(removed, cand.path+c.entries[cand.path].ext)
+		c.removeEntryLocked(cand.path)
+		c.evictions++
+	}
+
+	if len(removed) > 0 {
+		c.logger.Debug().Int("count", len(removed)).Msg("pagecache: Evicted pages")
+	}
+
+	return removed
+}
+
+// removeFiles removes the files of evicted pages and the chapter directories left empty.
+func (c *Cache) removeFiles(paths []string) {
+	for _, path := range paths {
+		filename := filepath.Join(c.dir, path)
+		_ = os.Remove(filename)
+		// Fails if the directory is not empty
+		_ = os.Remove(filepath.Dir(filename))
+	}
+}
+
+func extFromContentType(contentType string, data []byte) (string, bool) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	if !strings.HasPrefix(mediaType, "image/") {
+		// Some hosts do not send a content type or send a generic one
+		mediaType = http.DetectContentType(data)
+		if !strings.HasPrefix(mediaType, "image/") {
+			return "", false
+		}
+	}
+	if ext, ok := extensions[mediaType]; ok {
+		return ext, true
+	}
+	return defaultExt, true
+}
+
+func contentTypeFromExt(ext string) string {
+	for contentType, e := range extensions {
+		if e == ext {
+			return contentType
+		}
+	}
+	return "application/octet-stream"
+}
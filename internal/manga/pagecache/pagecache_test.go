@@ -0,0 +1,111 @@
+package pagecache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n")
+
+func page(size int) []byte {
+	return append(bytes.Clone(pngHeader), make([]byte, size-len(pngHeader))...)
+}
+
+func TestCacheSetGet(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(util.NewLogger(), dir)
+	require.NoError(t, err)
+
+	key := Key{Provider: "comick", MediaId: 30013, ChapterId: "ch/1", Index: 0}
+	_, _, ok := c.Get(key)
+	require.False(t, ok)
+
+	require.NoError(t, c.Set(key, page(100), "image/png"))
+	require.True(t, c.Has(key))
+
+	data, contentType, ok := c.Get(key)
+	require.True(t, ok)
+	require.Equal(t, "image/png", contentType)
+	require.Len(t, data, 100)
+
+	// The content type is sniffed when the host sends a generic one
+	jpegKey := Key{Provider: "comick", MediaId: 30013, ChapterId: "ch/1", Index: 1}
+	require.NoError(t, c.Set(jpegKey, []byte("\xff\xd8\xff\xe0 jpeg"), "application/octet-stream"))
+	_, contentType, ok = c.Get(jpegKey)
+	require.True(t, ok)
+	require.Equal(t, "image/jpeg", contentType)
+
+	// Error pages are not cached
+	htmlKey := Key{Provider: "comick", MediaId: 30013, ChapterId: "ch/1", Index: 2}
+	require.ErrorIs(t, c.Set(htmlKey, []byte("<html>Forbidden</html>"), "text/html"), ErrNotImage)
+	require.False(t, c.Has(htmlKey))
+
+	stats := c.GetStats()
+	require.Equal(t, 2, stats.Pages)
+	require.Equal(t, int64(2), stats.Hits)
+	require.Equal(t, int64(1), stats.Misses)
+	require.Len(t, stats.Media, 1)
+	require.Equal(t, 30013, stats.Media[0].MediaId)
+
+	// The index is rebuilt when the cache is opened again
+	c2, err := New(util.NewLogger(), dir)
+	require.NoError(t, err)
+	require.True(t, c2.Has(key))
+	require.True(t, c2.Has(jpegKey))
+	require.Equal(t, stats.Size, c2.GetStats().Size)
+}
+
+func TestCacheEviction(t *testing.T) {
+	dir := t.TempDir()
+	c, err := New(util.NewLogger(), dir)
+	require.NoError(t, err)
+	c.SetMaxSize(1300)
+
+	keys := make([]Key, 0)
+	for i := 0; i < 4; i++ {
+		key := Key{Provider: "comick", MediaId: 1, ChapterId: "ch_1", Index: i}
+		require.NoError(t, c.Set(key, page(300), "image/png"))
+		keys = append(keys, key)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// The first page was read recently, the second one is the least recently read
+	_, _, ok := c.Get(keys[0])
+	require.True(t, ok)
+
+	require.NoError(t, c.Set(Key{Provider: "comick", MediaId: 1, ChapterId: "ch_2", Index: 0}, page(300), "image/png"))
+
+	stats := c.GetStats()
+	require.LessOrEqual(t, stats.Size, int64(1170))
+	require.True(t, c.Has(keys[0]))
+	require.False(t, c.Has(keys[1]))
+	require.Positive(t, stats.Evictions)
+
+	_, err = os.Stat(filepath.Join(dir, keys[1].path()+".png"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCacheRemoveMedia(t *testing.T) {
+	c, err := New(util.NewLogger(), t.TempDir())
+	require.NoError(t, err)
+
+	a := Key{Provider: "comick", MediaId: 1, ChapterId: "ch_1", Index: 0}
+	b := Key{Provider: "comick", MediaId: 2, ChapterId: "ch_1", Index: 0}
+	require.NoError(t, c.Set(a, page(100), "image/png"))
+	require.NoError(t, c.Set(b, page(100), "image/png"))
+
+	require.NoError(t, c.RemoveMedia(1))
+	require.False(t, c.Has(a))
+	require.True(t, c.Has(b))
+	require.Equal(t, int64(100), c.GetStats().Size)
+
+	require.NoError(t, c.Clear())
+	require.False(t, c.Has(b))
+	require.Zero(t, c.GetStats().Size)
+}
@@ -0,0 +1,317 @@
+// Package pageprocess transforms manga pages before they are stored:
+// double-page spreads are split, borders are trimmed, pages are converted to grayscale and recompressed.
+package pageprocess
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+	_ "image/gif"
+)
+
+type Format string
+
+const (
+	// FormatOriginal keeps the format of JPEG pages, WebP pages are stored as JPEG and pages in other formats as PNG once modified
+	FormatOriginal Format = ""
+	FormatJPEG     Format = "jpeg"
+
+	DefaultQuality = 85
+
+	// A page is a spread if its width is larger than its height by this ratio
+	spreadRatio = 1.1
+	// Luminance difference tolerated when trimming borders
+	borderTolerance = 24
+	// Share of the pixels of a row or column that must be of the border color
+	borderCoverage = 0.995
+	// Trimming is skipped if it would remove more than this share of the width or height, e.g. mostly blank pages
+	maxTrimRatio = 0.4
+)
+
+var ErrUnsupported = errors.New("image format not supported")
+
+type (
+	Options struct {
+		SplitSpreads bool `json:"splitSpreads"`
+		// LeftToRight puts the left half of a split spread first.
+		// Manga is read right to left so the right half comes first by default.
+		LeftToRight bool   `json:"leftToRight"`
+		TrimBorders bool   `json:"trimBorders"`
+		Grayscale   bool   `json:"grayscale"`
+		Format      Format `json:"format"`
+		// Quality of JPEG pages, from 1 to 100, DefaultQuality if 0
+		Quality int `json:"quality"`
+	}
+
+	// Page is a processed page.
+	Page struct {
+		Buf []byte
+		// Format is the image format, used as the file extension, e.g. "jpeg"
+		Format string
+		Width  int
+		Height int
+		// Modified is false if Buf is the original image
+		Modified bool
+	}
+)
+
+// IsEnabled returns true if any processing step is enabled.
+func (o *Options) IsEnabled() bool {
+	return o != nil && (o.SplitSpreads || o.TrimBorders || o.Grayscale || o.Format != FormatOriginal)
+}
+
+func (o *Options) Validate() error {
+	switch o.Format {
+	case FormatOriginal, FormatJPEG:
+	default:
+		return errors.New("invalid page format, expected \"jpeg\"")
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return errors.New("page quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Process applies the options to a page and returns the resulting pages, two if the page was a split spread.
+// If the page does not change, or converting it would make it larger, the original image is returned.
+// Each page is kept in the smaller of its encodings, see encode.
+// Images that cannot be decoded, e.g. AVIF, return ErrUnsupported.
+func Process(buf []byte, opts *Options) ([]*Page, error) {
+	img, format, err := image.Decode(bytes.NewReader(buf))
+	if err != nil {
+		return nil, ErrUnsupported
+	}
+
+	original := &Page{
+		Buf:    buf,
+		Format: format,
+		Width:  img.Bounds().Dx(),
+		Height: img.Bounds().Dy(),
+	}
+	if !opts.IsEnabled() {
+		return []*Page{original}, nil
+	}
+
+	images := []image.Image{img}
+	if opts.SplitSpreads {
+		images = splitSpread(img, opts.LeftToRight)
+	}
+	if opts.TrimBorders {
+		for i, m := range images {
+			images[i] = trimBorders(m)
+		}
+	}
+	if opts.Grayscale {
+		for i, m := range images {
+			if !isGray(m) {
+				images[i] = toGray(m)
+			}
+		}
+	}
+
+	// Nothing changed and the format is kept
+	if len(images) == 1 && images[0] == img && (opts.Format == FormatOriginal || string(opts.Format) == format) {
+		return []*Page{original}, nil
+	}
+
+	ret := make([]*Page, 0, len(images))
+	for _, m := range images {
+		page, err := encode(m, format, opts)
+		if err != nil {
+			return nil, err
+		}
+		// Recompressing the unmodified image made it larger
+		if m == img && len(page.Buf) >= len(buf) {
+			page = original
+		}
+		ret = append(ret, page)
+	}
+
+	return ret, nil
+}
+
+// encode encodes a page as JPEG, or as PNG if the source format is kept and is not lossy.
+// Grayscale pages are usually smaller as PNG, so they are encoded in both formats and the smaller one is kept.
+func encode(img image.Image, sourceFormat string, opts *Options) (*Page, error) {
+	lossy := opts.Format != FormatOriginal || sourceFormat == "jpeg" || sourceFormat == "webp"
+
+	var ret *Page
+	if lossy {
+		quality := opts.Quality
+		if quality == 0 {
+			quality = DefaultQuality
+		}
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			return nil, err
+		}
+		ret = newPage(img, buf.Bytes(), string(FormatJPEG))
+		if !isGray(img) {
+			return ret, nil
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if ret == nil || buf.Len() < len(ret.Buf) {
+		ret = newPage(img, buf.Bytes(), "png")
+	}
+
+	return ret, nil
+}
+
+func newPage(img image.Image, buf []byte, format string) *Page {
+	return &Page{
+		Buf:      buf,
+		Format:   format,
+		Width:    img.Bounds().Dx(),
+		Height:   img.Bounds().Dy(),
+		Modified: true,
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// splitSpread splits a double-page spread in two halves, in reading order.
+func splitSpread(img image.Image, leftToRight bool) []image.Image {
+	bounds := img.Bounds()
+	sub, ok := img.(subImager)
+	if !ok || float64(bounds.Dx()) < float64(bounds.Dy())*spreadRatio {
+		return []image.Image{img}
+	}
+
+	middle := bounds.Min.X + bounds.Dx()/2
+	left := sub.SubImage(image.Rect(bounds.Min.X, bounds.Min.Y, middle, bounds.Max.Y))
+	right := sub.SubImage(image.Rect(middle, bounds.Min.Y, bounds.Max.X, bounds.Max.Y))
+	if leftToRight {
+		return []image.Image{left, right}
+	}
+	return []image.Image{right, left}
+}
+
+// trimBorders removes the white or black borders of the image.
+// The border color is taken from the corners, nothing is trimmed if they are not all white or all black.
+func trimBorders(img image.Image) image.Image {
+	bounds := img.Bounds()
+	sub, ok := img.(subImager)
+	if !ok || bounds.Dx() < 3 || bounds.Dy() < 3 {
+		return img
+	}
+
+	luma := func(x, y int) int {
+		return int(color.GrayModel.Convert(img.At(x, y)).(color.Gray).Y)
+	}
+	if gray, ok := img.(*image.Gray); ok {
+		luma = func(x, y int) int { return int(gray.GrayAt(x, y).Y) }
+	} else if ycbcr, ok := img.(*image.YCbCr); ok {
+		luma = func(x, y int) int { return int(ycbcr.Y[ycbcr.YOffset(x, y)]) }
+	}
+
+	corners := []int{
+		luma(bounds.Min.X, bounds.Min.Y),
+		luma(bounds.Max.X-1, bounds.Min.Y),
+		luma(bounds.Min.X, bounds.Max.Y-1),
+		luma(bounds.Max.X-1, bounds.Max.Y-1),
+	}
+	var border int
+	switch {
+	case allWithin(corners, 255):
+		border = 255
+	case allWithin(corners, 0):
+		border = 0
+	default:
+		return img
+	}
+
+	isBorder := func(v int) bool {
+		return abs(v-border) <= borderTolerance
+	}
+	isBorderLine := func(x0, y0, dx, dy, n int) bool {
+		misses := 0
+		allowed := int(float64(n)*(1-borderCoverage) + 0.5)
+		for i := 0; i < n; i++ {
+			if !isBorder(luma(x0+i*dx, y0+i*dy)) {
+				misses++
+				if misses > allowed {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	rect := bounds
+	for rect.Min.Y < rect.Max.Y-1 && isBorderLine(rect.Min.X, rect.Min.Y, 1, 0, rect.Dx()) {
+		rect.Min.Y++
+	}
+	for rect.Max.Y-1 > rect.Min.Y && isBorderLine(rect.Min.X, rect.Max.Y-1, 1, 0, rect.Dx()) {
+		rect.Max.Y--
+	}
+	for rect.Min.X < rect.Max.X-1 && isBorderLine(rect.Min.X, rect.Min.Y, 0, 1, rect.Dy()) {
+		rect.Min.X++
+	}
+	for rect.Max.X-1 > rect.Min.X && isBorderLine(rect.Max.X-1, rect.Min.Y, 0, 1, rect.Dy()) {
+		rect.Max.X--
+	}
+
+	if rect == bounds ||
+		float64(bounds.Dx()-rect.Dx()) > float64(bounds.Dx())*maxTrimRatio ||
+		float64(bounds.Dy()-rect.Dy()) > float64(bounds.Dy())*maxTrimRatio {
+		return img
+	}
+
+	return sub.SubImage(rect)
+}
+
+func isGray(img image.Image) bool {
+	_, ok := img.(*image.Gray)
+	return ok
+}
+
+func toGray(img image.Image) *image.Gray {
+	bounds := img.Bounds()
+	ret := image.NewGray(bounds)
+	if ycbcr, ok := img.(*image.YCbCr); ok {
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				ret.Pix[ret.PixOffset(x, y)] = ycbcr.Y[ycbcr.YOffset(x, y)]
+			}
+		}
+		return ret
+	}
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			ret.Set(x, y, img.At(x, y))
+		}
+	}
+	return ret
+}
+
+func allWithin(values []int, target int) bool {
+	for _, v := range values {
+		if abs(v-target) > borderTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,143 @@
+package pageprocess
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math/rand/v2"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+// newTestPage returns a white page with black content in the given rectangle.
+func newTestPage(width, height int, content image.Rectangle) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+			if (image.Point{X: x, Y: y}).In(content) {
+				c = color.RGBA{R: uint8(x), G: 30, B: uint8(y), A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func requireSamePixels(t *testing.T, expected image.Image, actual image.Image) {
+	require.Equal(t, expected.Bounds().Size(), actual.Bounds().Size())
+	eb, ab := expected.Bounds(), actual.Bounds()
+	for y := 0; y < eb.Dy(); y++ {
+		for x := 0; x < eb.Dx(); x++ {
+			e := color.NRGBAModel.Convert(expected.At(eb.Min.X+x, eb.Min.Y+y))
+			a := color.NRGBAModel.Convert(actual.At(ab.Min.X+x, ab.Min.Y+y))
+			if e != a {
+				require.Failf(t, "pixel mismatch", "at (%d, %d): expected %v, got %v", x, y, e, a)
+			}
+		}
+	}
+}
+
+func TestProcess(t *testing.T) {
+	// Unchanged pages are returned as they are
+	page := encodePNG(t, newTestPage(100, 150, image.Rect(0, 0, 100, 150)))
+	pages, err := Process(page, &Options{SplitSpreads: true, TrimBorders: true})
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	require.False(t, pages[0].Modified)
+	require.Equal(t, page, pages[0].Buf)
+
+	_, err = Process([]byte("not an image"), &Options{Grayscale: true})
+	require.ErrorIs(t, err, ErrUnsupported)
+
+	// The spread is split in reading order and the halves are trimmed
+	spread := newTestPage(300, 200, image.Rect(20, 10, 280, 190))
+	spread.Set(0, 100, color.RGBA{R: 1, A: 255}) // Noise on the border
+	pages, err = Process(encodePNG(t, spread), &Options{SplitSpreads: true, TrimBorders: true, Grayscale: true})
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	for _, p := range pages {
+		require.True(t, p.Modified)
+		require.Equal(t, "png", p.Format)
+		require.Equal(t, 130, p.Width)
+		require.Equal(t, 180, p.Height)
+		img, err := png.Decode(bytes.NewReader(p.Buf))
+		require.NoError(t, err)
+		require.IsType(t, &image.Gray{}, img)
+	}
+	right, err := png.Decode(bytes.NewReader(pages[0].Buf))
+	require.NoError(t, err)
+	// The right half starts at x=150
+	expected := toGray(spread.SubImage(image.Rect(150, 10, 280, 190)))
+	requireSamePixels(t, expected, right)
+
+	pages, err = Process(encodePNG(t, spread), &Options{SplitSpreads: true, LeftToRight: true, Format: FormatJPEG, Quality: 100})
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	for _, p := range pages {
+		require.Equal(t, "jpeg", p.Format)
+		require.Equal(t, 150, p.Width)
+		_, err = jpeg.Decode(bytes.NewReader(p.Buf))
+		require.NoError(t, err)
+	}
+
+	// Grayscale halves are smaller as PNG
+	pages, err = Process(encodePNG(t, spread), &Options{SplitSpreads: true, Grayscale: true, Format: FormatJPEG})
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	for _, p := range pages {
+		require.Equal(t, "png", p.Format)
+	}
+
+	// The page is kept if converting it does not make it smaller
+	pages, err = Process(encodePNG(t, spread), &Options{Format: FormatJPEG, Quality: 50})
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	require.False(t, pages[0].Modified)
+
+	rng := rand.New(rand.NewPCG(5, 6))
+	scan := image.NewGray(image.Rect(0, 0, 200, 300))
+	for i := range scan.Pix {
+		scan.Pix[i] = uint8(rng.IntN(256))
+	}
+	pages, err = Process(encodePNG(t, scan), &Options{Format: FormatJPEG, Quality: 50})
+	require.NoError(t, err)
+	require.Len(t, pages, 1)
+	require.True(t, pages[0].Modified)
+	require.Equal(t, "jpeg", pages[0].Format)
+	_, err = jpeg.Decode(bytes.NewReader(pages[0].Buf))
+	require.NoError(t, err)
+}
+
+func TestTrimBordersSkipsMostlyBlankPages(t *testing.T) {
+	img := newTestPage(100, 100, image.Rect(45, 45, 55, 55))
+	require.Equal(t, img, trimBorders(img))
+
+	// Black borders
+	black := image.NewGray(image.Rect(0, 0, 100, 100))
+	for y := 10; y < 90; y++ {
+		for x := 5; x < 95; x++ {
+			black.SetGray(x, y, color.Gray{Y: 200})
+		}
+	}
+	require.Equal(t, image.Rect(5, 10, 95, 90), trimBorders(black).Bounds())
+}
+
+func TestOptionsValidate(t *testing.T) {
+	require.NoError(t, (&Options{}).Validate())
+	require.NoError(t, (&Options{Format: FormatJPEG, Quality: 80}).Validate())
+	require.Error(t, (&Options{Format: "webp"}).Validate())
+	require.Error(t, (&Options{Format: "avif"}).Validate())
+	require.Error(t, (&Options{Quality: 101}).Validate())
+	require.False(t, (*Options)(nil).IsEnabled())
+	require.False(t, (&Options{LeftToRight: true, Quality: 80}).IsEnabled())
+}
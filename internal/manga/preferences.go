@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"seanime/internal/events"
 	"seanime/internal/util/filecache"
+	"slices"
 	"strings"
 )
 
@@ -21,6 +22,9 @@ type MangaProviderFilter struct {
 type MangaEntryPreference struct {
 	Provider string                         `json:"provider"`
 	Filters  map[string]MangaProviderFilter `json:"filters"`
+	// MergedProviders are the providers combined in the merged chapter list, in priority order.
+	// The merged view is disabled when empty.
+	MergedProviders []string `json:"mergedProviders,omitempty"`
 }
 
 type MangaPreferences struct {
@@ -34,8 +38,9 @@ type MangaProviderFilterPatch struct {
 }
 
 type MangaPreferencePatch struct {
-	Provider *string                   `json:"provider,omitempty"`
-	Filter   *MangaProviderFilterPatch `json:"filter,omitempty"`
+	Provider        *string                   `json:"provider,omitempty"`
+	Filter          *MangaProviderFilterPatch `json:"filter,omitempty"`
+	MergedProviders *[]string                 `json:"mergedProviders,omitempty"`
 }
 
 type MangaPreferencesUpdatedPayload struct {
@@ -99,6 +104,30 @@ func isValidProviderFilter(filter *MangaProviderFilterPatch) error {
 	return nil
 }
 
+func isValidMergedProviders(providers []string) error {
+	if len(providers) > maxMergedProviders {
+		return errors.New("too many merged providers")
+	}
+	for _, provider := range providers {
+		if err := isValidProvider(provider); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// normalizeMergedProviders trims the providers and removes duplicates, keeping the priority order.
+func normalizeMergedProviders(providers []string) []string {
+	ret := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		provider = strings.TrimSpace(provider)
+		if !slices.Contains(ret, provider) {
+			ret = append(ret, provider)
+		}
+	}
+	return ret
+}
+
 func (r *Repository) GetMangaPreferences() (*MangaPreferences, error) {
 	r.preferencesMu.Lock()
 	defer r.preferencesMu.Unlock()
@@ -155,6 +184,12 @@ func (r *Repository) ImportPreferences(imported *MangaPreferences) (*MangaPrefer
 		if entry.Filters == nil {
 			entry.Filters = make(map[string]MangaProviderFilter)
 		}
+		if len(entry.MergedProviders) == 0 && len(importedEntry.MergedProviders) > 0 {
+			if err := isValidMergedProviders(importedEntry.MergedProviders); err == nil {
+				entry.MergedProviders = normalizeMergedProviders(importedEntry.MergedProviders)
+				entryChanged = true
+			}
+		}
 		for provider, filter := range importedEntry.Filters {
 			provider = strings.TrimSpace(provider)
 			if _, exists := entry.Filters[provider]; exists {
@@ -198,7 +233,7 @@ func (r *Repository) PatchPreference(mediaId int, patch *MangaPreferencePatch, b
 	if mediaId <= 0 {
 		return nil, errors.New("invalid media id")
 	}
-	if patch == nil || patch.Provider == nil && patch.Filter == nil {
+	if patch == nil || patch.Provider == nil && patch.Filter == nil && patch.MergedProviders == nil {
 		return nil, errors.New("preference update is empty")
 	}
 	if patch.Provider != nil {
@@ -209,6 +244,11 @@ func (r *Repository) PatchPreference(mediaId int, patch *MangaPreferencePatch, b
 	if err := isValidProviderFilter(patch.Filter); err != nil {
 		return nil, err
 	}
+	if patch.MergedProviders != nil {
+		if err := isValidMergedProviders(*patch.MergedProviders); err != nil {
+			return nil, err
+		}
+	}
 
 	r.preferencesMu.Lock()
 	preferences, err := r.getMangaPreferences()
@@ -235,6 +275,9 @@ func (r *Repository) PatchPreference(mediaId int, patch *MangaPreferencePatch, b
 		}
 		entry.Filters[provider] = filter
 	}
+	if patch.MergedProviders != nil {
+		entry.MergedProviders = normalizeMergedProviders(*patch.MergedProviders)
+	}
 	preferences.Entries[mediaId] = entry
 	if err := r.savePreferences(preferences); err != nil {
 		r.preferencesMu.Unlock()
@@ -1,6 +1,7 @@
 package manga
 
 import (
+	"fmt"
 	"seanime/internal/events"
 	"seanime/internal/testutil"
 	"sync"
@@ -61,6 +62,14 @@ func TestMangaPreferenceValidation(t *testing.T) {
 	require.Error(t, err)
 	_, err = repository.PatchPreference(1, &MangaPreferencePatch{Filter: &MangaProviderFilterPatch{Provider: "provider-a"}}, true)
 	require.Error(t, err)
+	_, err = repository.PatchPreference(1, &MangaPreferencePatch{MergedProviders: new([]string{"provider-a", ""})}, true)
+	require.Error(t, err)
+	tooMany := make([]string, maxMergedProviders+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("provider-%d", i)
+	}
+	_, err = repository.PatchPreference(1, &MangaPreferencePatch{MergedProviders: &tooMany}, true)
+	require.Error(t, err)
 }
 
 func TestMangaPreferencesPersistAndMergeConcurrentPatches(t *testing.T) {
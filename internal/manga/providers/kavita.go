@@ -0,0 +1,379 @@
+package manga_providers
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	kavitaPluginName = "Seanime"
+	// Volume and chapter number used by Kavita for loose chapters and volumes without chapters.
+	// Older versions use 0.
+	kavitaDefaultNumber = -100000
+)
+
+type (
+	// Kavita is a built-in manga provider that reads the series of a Kavita server.
+	// Series are matched by title, the chapters of all volumes are listed as chapters.
+	//
+	//	Series ID:  "<libraryId>$<seriesId>"
+	//	Chapter ID: "<libraryId>$<seriesId>$<volumeId>$<chapterId>"
+	Kavita struct {
+		Client *http.Client
+		logger *zerolog.Logger
+
+		mu     sync.RWMutex
+		config mediaServerConfig
+		// JWT returned by the plugin authentication endpoint
+		token string
+	}
+
+	kavitaSearchResult struct {
+		Series []struct {
+			SeriesID      int    `json:"seriesId"`
+			LibraryID     int    `json:"libraryId"`
+			Name          string `json:"name"`
+			OriginalName  string `json:"originalName"`
+			LocalizedName string `json:"localizedName"`
+		} `json:"series"`
+	}
+
+	kavitaVolume struct {
+		ID        int             `json:"id"`
+		MinNumber float64         `json:"minNumber"`
+		Name      string          `json:"name"`
+		Chapters  []kavitaChapter `json:"chapters"`
+	}
+
+	kavitaChapter struct {
+		ID              int     `json:"id"`
+		Range           string  `json:"range"`
+		MinNumber       float64 `json:"minNumber"`
+		Title           string  `json:"title"`
+		Pages           int     `json:"pages"`
+		IsSpecial       bool    `json:"isSpecial"`
+		LastModifiedUtc string  `json:"lastModifiedUtc"`
+	}
+
+	kavitaChapterInfo struct {
+		Pages int `json:"pages"`
+	}
+
+	kavitaChapterId struct {
+		LibraryID int
+		SeriesID  int
+		VolumeID  int
+		ChapterID int
+	}
+)
+
+func NewKavita(logger *zerolog.Logger) hibikemanga.Provider {
+	return &Kavita{
+		Client: newMediaServerClient(),
+		logger: logger,
+	}
+}
+
+func (mp *Kavita) GetSettings() hibikemanga.Settings {
+	return hibikemanga.Settings{
+		SupportsMultiScanlator: false,
+		SupportsMultiLanguage:  false,
+	}
+}
+
+func (mp *Kavita) SetSavedUserConfig(config extension.SavedUserConfig) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.config = parseMediaServerConfig(config)
+	mp.token = ""
+}
+
+func (mp *Kavita) getConfig() (mediaServerConfig, error) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	if mp.config.URL == "" || mp.config.APIKey == "" {
+		return mp.config, ErrMediaServerNotConfigured
+	}
+	return mp.config, nil
+}
+
+// authenticate exchanges the API key for a JWT, the token is reused until the server rejects it.
+func (mp *Kavita) authenticate(config mediaServerConfig, refresh bool) (string, error) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	if mp.token != "" && !refresh {
+		return mp.token, nil
+	}
+
+	var res struct {
+		Token string `json:"token"`
+	}
+	reqUrl := fmt.Sprintf("%s/api/Plugin/authenticate?apiKey=%s&pluginName=%s", config.URL, url.QueryEscape(config.APIKey), kavitaPluginName)
+	if err := doMediaServerRequest(mp.Client, http.MethodPost, reqUrl, nil, nil, &res); err != nil {
+		return "", fmt.Errorf("kavita: Failed to authenticate: %w", err)
+	}
+	if res.Token == "" {
+		return "", errors.New("kavita: Failed to authenticate: no token returned")
+	}
+
+	mp.token = res.Token
+	return mp.token, nil
+}
+
+// request sends an authenticated request, authenticating again once if the token expired.
+func (mp *Kavita) request(config mediaServerConfig, method string, path string, body interface{}, ret interface{}) error {
+	token, err := mp.authenticate(config, false)
+	if err != nil {
+		return err
+	}
+
+	err = doMediaServerRequest(mp.Client, method, config.URL+path, map[string]string{"Authorization": "Bearer " + token}, body, ret)
+	if !errors.Is(err, errMediaServerUnauthorized) {
+		return err
+	}
+
+	token, err = mp.authenticate(config, true)
+	if err != nil {
+		return err
+	}
+	return doMediaServerRequest(mp.Client, method, config.URL+path, map[string]string{"Authorization": "Bearer " + token}, body, ret)
+}
+
+func (mp *Kavita) Search(opts hibikemanga.SearchOptions) ([]*hibikemanga.SearchResult, error) {
+	results := make([]*hibikemanga.SearchResult, 0)
+
+	mp.logger.Debug().Str("query", opts.Query).Msg("kavita: Searching manga")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var res kavitaSearchResult
+	if err := mp.request(config, http.MethodGet, "/api/Search/search?queryString="+url.QueryEscape(opts.Query), nil, &res); err != nil {
+		mp.logger.Error().Err(err).Msg("kavita: Failed to search series")
+		return nil, err
+	}
+
+	for _, series := range res.Series {
+		result := &hibikemanga.SearchResult{
+			ID:       fmt.Sprintf("%d$%d", series.LibraryID, series.SeriesID),
+			Title:    series.Name,
+			Synonyms: make([]string, 0),
+			Provider: KavitaProvider,
+		}
+		for _, name := range []string{series.OriginalName, series.LocalizedName} {
+			if name != "" && name != series.Name && !slices.Contains(result.Synonyms, name) {
+				result.Synonyms = append(result.Synonyms, name)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		mp.logger.Error().Str("query", opts.Query).Msg("kavita: No results found")
+		return nil, ErrNoResults
+	}
+
+	mp.logger.Info().Int("count", len(results)).Msg("kavita: Found results")
+
+	return results, nil
+}
+
+func (mp *Kavita) FindChapters(id string) ([]*hibikemanga.ChapterDetails, error) {
+	ret := make([]*hibikemanga.ChapterDetails, 0)
+
+	mp.logger.Debug().Str("mangaId", id).Msg("kavita: Finding chapters")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var libraryId, seriesId int
+	if _, err := fmt.Sscanf(id, "%d$%d", &libraryId, &seriesId); err != nil {
+		return nil, fmt.Errorf("kavita: Invalid series ID %q", id)
+	}
+
+	var volumes []kavitaVolume
+	if err := mp.request(config, http.MethodGet, fmt.Sprintf("/api/Series/volumes?seriesId=%d", seriesId), nil, &volumes); err != nil {
+		mp.logger.Error().Err(err).Msg("kavita: Failed to get volumes")
+		return nil, err
+	}
+
+	for _, volume := range volumes {
+		isLooseVolume := isKavitaDefaultNumber(volume.MinNumber)
+		for _, chapter := range volume.Chapters {
+			ch := &hibikemanga.ChapterDetails{
+				Provider: KavitaProvider,
+				ID:       fmt.Sprintf("%d$%d$%d$%d", libraryId, seriesId, volume.ID, chapter.ID),
+				URL:      fmt.Sprintf("%s/library/%d/series/%d", config.URL, libraryId, seriesId),
+			}
+
+			switch {
+			case chapter.IsSpecial:
+				// Specials cannot be matched with chapter numbers
+				ch.Title = cmp.Or(chapter.Title, chapter.Range)
+			case isKavitaDefaultNumber(chapter.MinNumber) && !isLooseVolume:
+				// The volume has no chapters, the volume file is listed as a chapter
+				ch.Chapter = formatChapterNumber(volume.MinNumber)
+				ch.Title = "Volume " + ch.Chapter
+			default:
+				ch.Chapter = strings.TrimSpace(chapter.Range)
+				if _, err := strconv.ParseFloat(ch.Chapter, 64); err != nil {
+					ch.Chapter = formatChapterNumber(chapter.MinNumber)
+				}
+				ch.Title = "Chapter " + ch.Chapter
+				if chapter.Title != "" && chapter.Title != chapter.Range {
+					ch.Title += " - " + chapter.Title
+				}
+			}
+
+			// e.g. "2024-01-02T15:04:05.123"
+			if len(chapter.LastModifiedUtc) >= 10 {
+				ch.UpdatedAt = chapter.LastModifiedUtc[:10]
+			}
+			ret = append(ret, ch)
+		}
+	}
+
+	// Volumes are not always sorted by number, specials go last
+	slices.SortStableFunc(ret, func(a, b *hibikemanga.ChapterDetails) int {
+		numA, errA := strconv.ParseFloat(a.Chapter, 64)
+		numB, errB := strconv.ParseFloat(b.Chapter, 64)
+		if errA != nil || errB != nil {
+			return cmp.Compare(boolToInt(errA != nil), boolToInt(errB != nil))
+		}
+		return cmp.Compare(numA, numB)
+	})
+	for i, ch := range ret {
+		ch.Index = uint(i)
+	}
+
+	if len(ret) == 0 {
+		mp.logger.Error().Str("mangaId", id).Msg("kavita: No chapters found")
+		return nil, ErrNoChapters
+	}
+
+	mp.logger.Info().Int("count", len(ret)).Msg("kavita: Found chapters")
+
+	return ret, nil
+}
+
+func (mp *Kavita) FindChapterPages(id string) ([]*hibikemanga.ChapterPage, error) {
+	ret := make([]*hibikemanga.ChapterPage, 0)
+
+	mp.logger.Debug().Str("chapterId", id).Msg("kavita: Finding chapter pages")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	chapterId, err := parseKavitaChapterId(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var info kavitaChapterInfo
+	if err := mp.request(config, http.MethodGet, fmt.Sprintf("/api/Reader/chapter-info?chapterId=%d", chapterId.ChapterID), nil, &info); err != nil {
+		mp.logger.Error().Err(err).Msg("kavita: Failed to get chapter info")
+		return nil, err
+	}
+
+	for i := 0; i < info.Pages; i++ {
+		ret = append(ret, &hibikemanga.ChapterPage{
+			Provider: KavitaProvider,
+			// The image endpoint authenticates with the API key, which is added by AuthorizePageRequest
+			URL:     fmt.Sprintf("%s/api/Reader/image?chapterId=%d&page=%d&extractPdf=true", config.URL, chapterId.ChapterID, i),
+			Index:   i,
+			Headers: map[string]string{},
+		})
+	}
+
+	if len(ret) == 0 {
+		mp.logger.Error().Str("chapterId", id).Msg("kavita: No pages found")
+		return nil, ErrNoPages
+	}
+
+	mp.logger.Info().Int("count", len(ret)).Msg("kavita: Found pages")
+
+	return ret, nil
+}
+
+func (mp *Kavita) AuthorizePageRequest(pageUrl string) (string, map[string]string, bool) {
+	config, err := mp.getConfig()
+	if err != nil || !strings.HasPrefix(pageUrl, config.URL+"/api/Reader/image?") {
+		return "", nil, false
+	}
+	return pageUrl + "&apiKey=" + url.QueryEscape(config.APIKey), map[string]string{}, true
+}
+
+func (mp *Kavita) SyncReadProgress(chapterId string, pageIndex int, completed bool) error {
+	config, err := mp.getConfig()
+	if err != nil || !config.PushProgress {
+		return err
+	}
+
+	id, err := parseKavitaChapterId(chapterId)
+	if err != nil {
+		return err
+	}
+
+	// Kavita marks the chapter as read when the page number reaches the page count
+	pageNum := pageIndex
+	if completed {
+		var info kavitaChapterInfo
+		if err := mp.request(config, http.MethodGet, fmt.Sprintf("/api/Reader/chapter-info?chapterId=%d", id.ChapterID), nil, &info); err != nil {
+			return fmt.Errorf("kavita: Failed to get chapter info: %w", err)
+		}
+		pageNum = info.Pages
+	}
+
+	body := map[string]interface{}{
+		"libraryId": id.LibraryID,
+		"seriesId":  id.SeriesID,
+		"volumeId":  id.VolumeID,
+		"chapterId": id.ChapterID,
+		"pageNum":   pageNum,
+	}
+	if err := mp.request(config, http.MethodPost, "/api/Reader/progress", body, nil); err != nil {
+		return fmt.Errorf("kavita: Failed to update read progress: %w", err)
+	}
+
+	mp.logger.Debug().Str("chapterId", chapterId).Int("pageNum", pageNum).Msg("kavita: Updated read progress")
+
+	return nil
+}
+
+func parseKavitaChapterId(id string) (ret kavitaChapterId, err error) {
+	if _, err = fmt.Sscanf(id, "%d$%d$%d$%d", &ret.LibraryID, &ret.SeriesID, &ret.VolumeID, &ret.ChapterID); err != nil {
+		return ret, fmt.Errorf("kavita: Invalid chapter ID %q", id)
+	}
+	return ret, nil
+}
+
+func isKavitaDefaultNumber(number float64) bool {
+	return number == kavitaDefaultNumber || number == 0
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
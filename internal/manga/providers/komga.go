@@ -0,0 +1,276 @@
+package manga_providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/url"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	// Komga is a built-in manga provider that reads the series of a Komga server.
+	// Series are matched by title, books are listed as chapters.
+	Komga struct {
+		Client *http.Client
+		logger *zerolog.Logger
+
+		mu     sync.RWMutex
+		config mediaServerConfig
+	}
+
+	komgaPage[T any] struct {
+		Content []T `json:"content"`
+	}
+
+	komgaSeries struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Metadata struct {
+			Title           string `json:"title"`
+			AlternateTitles []struct {
+				Label string `json:"label"`
+				Title string `json:"title"`
+			} `json:"alternateTitles"`
+		} `json:"metadata"`
+		BooksMetadata struct {
+			ReleaseDate string `json:"releaseDate"`
+		} `json:"booksMetadata"`
+	}
+
+	komgaBook struct {
+		ID           string `json:"id"`
+		Name         string `json:"name"`
+		Number       int    `json:"number"`
+		LastModified string `json:"lastModified"`
+		Metadata     struct {
+			Title      string  `json:"title"`
+			Number     string  `json:"number"`
+			NumberSort float64 `json:"numberSort"`
+		} `json:"metadata"`
+	}
+
+	komgaBookPage struct {
+		Number int `json:"number"`
+	}
+)
+
+func NewKomga(logger *zerolog.Logger) hibikemanga.Provider {
+	return &Komga{
+		Client: newMediaServerClient(),
+		logger: logger,
+	}
+}
+
+func (mp *Komga) GetSettings() hibikemanga.Settings {
+	return hibikemanga.Settings{
+		SupportsMultiScanlator: false,
+		SupportsMultiLanguage:  false,
+	}
+}
+
+func (mp *Komga) SetSavedUserConfig(config extension.SavedUserConfig) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.config = parseMediaServerConfig(config)
+}
+
+func (mp *Komga) getConfig() (mediaServerConfig, error) {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	if mp.config.URL == "" {
+		return mp.config, ErrMediaServerNotConfigured
+	}
+	return mp.config, nil
+}
+
+// komgaHeaders returns the authentication headers.
+func (c mediaServerConfig) komgaHeaders() map[string]string {
+	if c.APIKey != "" {
+		return map[string]string{"X-API-Key": c.APIKey}
+	}
+	if c.Username != "" {
+		return map[string]string{"Authorization": "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Username+":"+c.Password))}
+	}
+	return map[string]string{}
+}
+
+func (mp *Komga) Search(opts hibikemanga.SearchOptions) ([]*hibikemanga.SearchResult, error) {
+	results := make([]*hibikemanga.SearchResult, 0)
+
+	mp.logger.Debug().Str("query", opts.Query).Msg("komga: Searching manga")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var res komgaPage[komgaSeries]
+	reqUrl := fmt.Sprintf("%s/api/v1/series?search=%s&size=20", config.URL, url.QueryEscape(opts.Query))
+	if err := doMediaServerRequest(mp.Client, http.MethodGet, reqUrl, config.komgaHeaders(), nil, &res); err != nil {
+		mp.logger.Error().Err(err).Msg("komga: Failed to search series")
+		return nil, err
+	}
+
+	for _, series := range res.Content {
+		result := &hibikemanga.SearchResult{
+			ID:       series.ID,
+			Title:    series.Metadata.Title,
+			Synonyms: make([]string, 0),
+			Provider: KomgaProvider,
+		}
+		if result.Title == "" {
+			result.Title = series.Name
+		}
+		if series.Name != result.Title {
+			result.Synonyms = append(result.Synonyms, series.Name)
+		}
+		for _, alt := range series.Metadata.AlternateTitles {
+			if alt.Title != "" {
+				result.Synonyms = append(result.Synonyms, alt.Title)
+			}
+		}
+		// e.g. "2019-03-04"
+		if len(series.BooksMetadata.ReleaseDate) >= 4 {
+			result.Year, _ = strconv.Atoi(series.BooksMetadata.ReleaseDate[:4])
+		}
+		results = append(results, result)
+	}
+
+	if len(results) == 0 {
+		mp.logger.Error().Str("query", opts.Query).Msg("komga: No results found")
+		return nil, ErrNoResults
+	}
+
+	mp.logger.Info().Int("count", len(results)).Msg("komga: Found results")
+
+	return results, nil
+}
+
+func (mp *Komga) FindChapters(id string) ([]*hibikemanga.ChapterDetails, error) {
+	ret := make([]*hibikemanga.ChapterDetails, 0)
+
+	mp.logger.Debug().Str("mangaId", id).Msg("komga: Finding chapters")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var res komgaPage[komgaBook]
+	reqUrl := fmt.Sprintf("%s/api/v1/series/%s/books?unpaged=true&sort=metadata.numberSort,asc", config.URL, url.PathEscape(id))
+	if err := doMediaServerRequest(mp.Client, http.MethodGet, reqUrl, config.komgaHeaders(), nil, &res); err != nil {
+		mp.logger.Error().Err(err).Msg("komga: Failed to get books")
+		return nil, err
+	}
+
+	for i, book := range res.Content {
+		number := strings.TrimSpace(book.Metadata.Number)
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			number = formatChapterNumber(book.Metadata.NumberSort)
+		}
+		title := book.Metadata.Title
+		if title == "" {
+			title = book.Name
+		}
+		ch := &hibikemanga.ChapterDetails{
+			Provider: KomgaProvider,
+			ID:       book.ID,
+			URL:      fmt.Sprintf("%s/book/%s", config.URL, book.ID),
+			Title:    "Chapter " + number,
+			Chapter:  number,
+			Index:    uint(i),
+		}
+		if title != "" && !strings.EqualFold(title, number) {
+			ch.Title += " - " + title
+		}
+		// e.g. "2024-01-02T15:04:05Z"
+		if len(book.LastModified) >= 10 {
+			ch.UpdatedAt = book.LastModified[:10]
+		}
+		ret = append(ret, ch)
+	}
+
+	if len(ret) == 0 {
+		mp.logger.Error().Str("mangaId", id).Msg("komga: No chapters found")
+		return nil, ErrNoChapters
+	}
+
+	mp.logger.Info().Int("count", len(ret)).Msg("komga: Found chapters")
+
+	return ret, nil
+}
+
+func (mp *Komga) FindChapterPages(id string) ([]*hibikemanga.ChapterPage, error) {
+	ret := make([]*hibikemanga.ChapterPage, 0)
+
+	mp.logger.Debug().Str("chapterId", id).Msg("komga: Finding chapter pages")
+
+	config, err := mp.getConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	var res []komgaBookPage
+	reqUrl := fmt.Sprintf("%s/api/v1/books/%s/pages", config.URL, url.PathEscape(id))
+	if err := doMediaServerRequest(mp.Client, http.MethodGet, reqUrl, config.komgaHeaders(), nil, &res); err != nil {
+		mp.logger.Error().Err(err).Msg("komga: Failed to get pages")
+		return nil, err
+	}
+
+	for i, page := range res {
+		ret = append(ret, &hibikemanga.ChapterPage{
+			Provider: KomgaProvider,
+			// Page numbers start from 1
+			// The authentication headers are added by AuthorizePageRequest
+			URL:     fmt.Sprintf("%s/api/v1/books/%s/pages/%d", config.URL, url.PathEscape(id), page.Number),
+			Index:   i,
+			Headers: map[string]string{},
+		})
+	}
+
+	if len(ret) == 0 {
+		mp.logger.Error().Str("chapterId", id).Msg("komga: No pages found")
+		return nil, ErrNoPages
+	}
+
+	mp.logger.Info().Int("count", len(ret)).Msg("komga: Found pages")
+
+	return ret, nil
+}
+
+func (mp *Komga) AuthorizePageRequest(pageUrl string) (string, map[string]string, bool) {
+	config, err := mp.getConfig()
+	if err != nil || !strings.HasPrefix(pageUrl, config.URL+"/api/v1/books/") {
+		return "", nil, false
+	}
+	return pageUrl, config.komgaHeaders(), true
+}
+
+func (mp *Komga) SyncReadProgress(chapterId string, pageIndex int, completed bool) error {
+	config, err := mp.getConfig()
+	if err != nil || !config.PushProgress {
+		return err
+	}
+
+	body := map[string]interface{}{
+		"page":      pageIndex + 1,
+		"completed": completed,
+	}
+	reqUrl := fmt.Sprintf("%s/api/v1/books/%s/read-progress", config.URL, url.PathEscape(chapterId))
+	if err := doMediaServerRequest(mp.Client, http.MethodPatch, reqUrl, config.komgaHeaders(), body, nil); err != nil {
+		return fmt.Errorf("komga: Failed to update read progress: %w", err)
+	}
+
+	mp.logger.Debug().Str("chapterId", chapterId).Int("page", pageIndex+1).Bool("completed", completed).Msg("komga: Updated read progress")
+
+	return nil
+}
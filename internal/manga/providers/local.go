@@ -69,6 +69,22 @@ func (p *Local) SetSourceDirectory(dir string) {
 	}
 }
 
+// GetChapterPath returns the path of a chapter file or directory.
+// The chapter ID is the path of the chapter relative to the source directory.
+func (p *Local) GetChapterPath(id string) (string, error) {
+	if p.dir == "" {
+		return "", fmt.Errorf("source directory is not set")
+	}
+
+	fullpath := filepath.Join(p.dir, filepath.FromSlash(id))
+	rel, err := filepath.Rel(p.dir, fullpath)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return "", fmt.Errorf("invalid chapter id: %s", id)
+	}
+
+	return fullpath, nil
+}
+
 func (p *Local) getAllManga() (res []*hibikemanga.SearchResult, err error) {
 	if p.dir == "" {
 		return make([]*hibikemanga.SearchResult, 0), nil
@@ -532,6 +548,7 @@ func (p *Local) FindChapterPages(id string) (ret []*hibikemanga.ChapterPage, err
 	})
 
 	// Sort pages
+	// Keep the order in sync with SortPageFilenames
 	slices.SortFunc(pages, func(a, b *pageStruct) int {
 		return strings.Compare(filepath.Base(a.LoadedPage.page.URL), filepath.Base(b.LoadedPage.page.URL))
 	})
@@ -559,3 +576,24 @@ func (p *Local) ReadPage(path string) (ret io.ReadCloser, err error) {
 
 	return nil, fmt.Errorf("page not found: %s", path)
 }
+
+// SortPageFilenames returns the page images of a chapter directory or archive in reading order.
+// Names that are not images or not recognized as pages are left out.
+func SortPageFilenames(names []string) []string {
+	ret := make([]string, 0, len(names))
+	for _, name := range names {
+		if !isFileImage(name) {
+			continue
+		}
+		if _, ok := parsePageFilename(filepath.Base(name)); !ok {
+			continue
+		}
+		ret = append(ret, name)
+	}
+
+	slices.SortFunc(ret, func(a, b string) int {
+		return strings.Compare(filepath.Base(a), filepath.Base(b))
+	})
+
+	return ret
+}
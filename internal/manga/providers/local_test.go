@@ -2,6 +2,7 @@ package manga_providers
 
 import (
 	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
 	"testing"
@@ -481,3 +482,19 @@ func TestParsePageFilename(t *testing.T) {
 		})
 	}
 }
+
+func TestSortPageFilenames(t *testing.T) {
+	names := []string{"chapter/1149-002.jpg", "ComicInfo.xml", "chapter/1149-000.jpg", "chapter/", "1149-001.png"}
+	require.Equal(t, []string{"chapter/1149-000.jpg", "1149-001.png", "chapter/1149-002.jpg"}, SortPageFilenames(names))
+}
+
+func TestLocalGetChapterPath(t *testing.T) {
+	p := &Local{dir: t.TempDir()}
+
+	path, err := p.GetChapterPath("series/chapter_1.cbz")
+	require.NoError(t, err)
+	require.Equal(t, filepath.Join(p.dir, "series", "chapter_1.cbz"), path)
+
+	_, err = p.GetChapterPath("../outside/chapter_1.cbz")
+	require.Error(t, err)
+}
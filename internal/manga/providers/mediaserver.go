@@ -0,0 +1,148 @@
+package manga_providers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"seanime/internal/extension"
+	"strings"
+	"time"
+)
+
+var (
+	ErrMediaServerNotConfigured = errors.New("media server is not configured")
+	errMediaServerUnauthorized  = errors.New("unauthorized")
+)
+
+// ReadProgressSyncer is implemented by providers that can push the read progress back to their source.
+type ReadProgressSyncer interface {
+	// SyncReadProgress records that the chapter was read up to the page, from 0.
+	// It does nothing if pushing the progress is disabled by the user.
+	SyncReadProgress(chapterId string, pageIndex int, completed bool) error
+}
+
+// PageRequestAuthorizer is implemented by providers whose page images require credentials.
+// The credentials are not part of the pages sent to the client, they are added when the server fetches the images.
+type PageRequestAuthorizer interface {
+	// AuthorizePageRequest returns the URL and headers used to fetch the image of a page.
+	// ok is false if the URL is not a page of the provider.
+	AuthorizePageRequest(pageUrl string) (reqUrl string, headers map[string]string, ok bool)
+}
+
+// mediaServerConfig is the user configuration shared by the media server providers (Komga, Kavita).
+type mediaServerConfig struct {
+	URL          string
+	APIKey       string
+	Username     string
+	Password     string
+	PushProgress bool
+}
+
+// MediaServerUserConfig returns the user configuration of a media server provider.
+// Username and password are only used by Komga, when no API key is set.
+func MediaServerUserConfig(withBasicAuth bool) *extension.UserConfig {
+	fields := []extension.ConfigField{
+		{
+			Type:  extension.ConfigFieldTypeText,
+			Name:  "url",
+			Label: "Server URL (e.g. http://localhost:25600)",
+		},
+		{
+			Type:  extension.ConfigFieldTypeText,
+			Name:  "apiKey",
+			Label: "API key",
+		},
+	}
+	if withBasicAuth {
+		fields = append(fields,
+			extension.ConfigField{
+				Type:  extension.ConfigFieldTypeText,
+				Name:  "username",
+				Label: "Username (if no API key is set)",
+			},
+			extension.ConfigField{
+				Type:  extension.ConfigFieldTypeText,
+				Name:  "password",
+				Label: "Password (if no API key is set)",
+			},
+		)
+	}
+	fields = append(fields, extension.ConfigField{
+		Type:    extension.ConfigFieldTypeSwitch,
+		Name:    "pushProgress",
+		Label:   "Push read progress to the server",
+		Default: "false",
+	})
+
+	return &extension.UserConfig{
+		Version:        1,
+		RequiresConfig: true,
+		Fields:         fields,
+	}
+}
+
+func parseMediaServerConfig(config extension.SavedUserConfig) mediaServerConfig {
+	return mediaServerConfig{
+		URL:          strings.TrimRight(strings.TrimSpace(config.Values["url"]), "/"),
+		APIKey:       strings.TrimSpace(config.Values["apiKey"]),
+		Username:     strings.TrimSpace(config.Values["username"]),
+		Password:     config.Values["password"],
+		PushProgress: config.Values["pushProgress"] == "true",
+	}
+}
+
+func newMediaServerClient() *http.Client {
+	return &http.Client{
+		Timeout: 60 * time.Second,
+	}
+}
+
+// doMediaServerRequest sends a request to a media server and decodes the JSON response into ret, if not nil.
+func doMediaServerRequest(client *http.Client, method string, url string, headers map[string]string, body interface{}, ret interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, url, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errMediaServerUnauthorized
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if ret == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(ret)
+}
+
+// formatChapterNumber formats a chapter number parsed by a media server, e.g. 1.0 becomes "1".
+func formatChapterNumber(number float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.2f", number), "0"), ".")
+}
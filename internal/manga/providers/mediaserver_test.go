@@ -0,0 +1,252 @@
+package manga_providers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/extension"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/util"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func newFakeKomgaServer(t *testing.T, progress *[]map[string]interface{}) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/series", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Yofukashi no Uta", r.URL.Query().Get("search"))
+		writeJSON(w, map[string]interface{}{
+			"content": []map[string]interface{}{
+				{
+					"id":   "series-1",
+					"name": "Yofukashi no Uta (Digital)",
+					"metadata": map[string]interface{}{
+						"title":           "Yofukashi no Uta",
+						"alternateTitles": []map[string]string{{"label": "English", "title": "Call of the Night"}},
+					},
+					"booksMetadata": map[string]interface{}{"releaseDate": "2019-08-07"},
+				},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/v1/series/series-1/books", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]interface{}{
+			"content": []map[string]interface{}{
+				{"id": "book-1", "name": "Vol. 01", "lastModified": "2024-01-02T15:04:05Z", "metadata": map[string]interface{}{"title": "Night 1", "number": "1", "numberSort": 1}},
+				{"id": "book-2", "name": "Vol. 02", "metadata": map[string]interface{}{"title": "2", "number": "2", "numberSort": 2}},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/v1/books/book-1/pages", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, []map[string]interface{}{{"number": 1}, {"number": 2}})
+	})
+	mux.HandleFunc("PATCH /api/v1/books/book-1/read-progress", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*progress = append(*progress, body)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "komga-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	}))
+}
+
+func TestKomga(t *testing.T) {
+	progress := make([]map[string]interface{}, 0)
+	server := newFakeKomgaServer(t, &progress)
+	defer server.Close()
+
+	provider := NewKomga(util.NewLogger())
+
+	_, err := provider.Search(hibikemanga.SearchOptions{Query: "Yofukashi no Uta"})
+	require.ErrorIs(t, err, ErrMediaServerNotConfigured)
+
+	provider.(extension.Configurable).SetSavedUserConfig(extension.SavedUserConfig{
+		Values: map[string]string{"url": server.URL + "/", "apiKey": "komga-key"},
+	})
+
+	results, err := provider.Search(hibikemanga.SearchOptions{Query: "Yofukashi no Uta"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "series-1", results[0].ID)
+	require.Equal(t, "Yofukashi no Uta", results[0].Title)
+	require.Equal(t, []string{"Yofukashi no Uta (Digital)", "Call of the Night"}, results[0].Synonyms)
+	require.Equal(t, 2019, results[0].Year)
+
+	chapters, err := provider.FindChapters("series-1")
+	require.NoError(t, err)
+	require.Len(t, chapters, 2)
+	require.Equal(t, "book-1", chapters[0].ID)
+	require.Equal(t, "1", chapters[0].Chapter)
+	require.Equal(t, "Chapter 1 - Night 1", chapters[0].Title)
+	require.Equal(t, "2024-01-02", chapters[0].UpdatedAt)
+	require.Equal(t, "Chapter 2", chapters[1].Title)
+	require.Equal(t, uint(1), chapters[1].Index)
+
+	pages, err := provider.FindChapterPages("book-1")
+	require.NoError(t, err)
+	require.Len(t, pages, 2)
+	require.Equal(t, server.URL+"/api/v1/books/book-1/pages/1", pages[0].URL)
+	// The credentials are only added when the server fetches the image
+	require.Empty(t, pages[0].Headers)
+	authorizer := provider.(PageRequestAuthorizer)
+	reqUrl, headers, ok := authorizer.AuthorizePageRequest(pages[0].URL)
+	require.True(t, ok)
+	require.Equal(t, pages[0].URL, reqUrl)
+	require.Equal(t, "komga-key", headers["X-API-Key"])
+	_, _, ok = authorizer.AuthorizePageRequest("https://example.com/api/v1/books/book-1/pages/1")
+	require.False(t, ok)
+
+	// The progress is not pushed unless enabled
+	syncer := provider.(ReadProgressSyncer)
+	require.NoError(t, syncer.SyncReadProgress("book-1", 1, true))
+	require.Empty(t, progress)
+
+	provider.(extension.Configurable).SetSavedUserConfig(extension.SavedUserConfig{
+		Values: map[string]string{"url": server.URL, "apiKey": "komga-key", "pushProgress": "true"},
+	})
+	require.NoError(t, syncer.SyncReadProgress("book-1", 1, true))
+	require.Len(t, progress, 1)
+	require.Equal(t, float64(2), progress[0]["page"])
+	require.Equal(t, true, progress[0]["completed"])
+
+	// Wrong credentials
+	provider.(extension.Configurable).SetSavedUserConfig(extension.SavedUserConfig{
+		Values: map[string]string{"url": server.URL, "username": "user", "password": "pass"},
+	})
+	_, err = provider.FindChapters("series-1")
+	require.Error(t, err)
+}
+
+func newFakeKavitaServer(t *testing.T, progress *[]map[string]interface{}) (*httptest.Server, *int) {
+	var mu sync.Mutex
+	authCount := 0
+	currentToken := ""
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/Search/search", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "Dandadan", r.URL.Query().Get("queryString"))
+		writeJSON(w, map[string]interface{}{
+			"series": []map[string]interface{}{
+				{"seriesId": 12, "libraryId": 3, "name": "Dandadan", "originalName": "ダンダダン", "localizedName": "Dandadan"},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/Series/volumes", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "12", r.URL.Query().Get("seriesId"))
+		writeJSON(w, []map[string]interface{}{
+			{
+				"id": 21, "minNumber": 2, "name": "2",
+				"chapters": []map[string]interface{}{
+					{"id": 103, "range": "-100000", "minNumber": -100000, "pages": 180},
+				},
+			},
+			{
+				"id": 20, "minNumber": -100000, "name": "",
+				"chapters": []map[string]interface{}{
+					{"id": 102, "range": "1.5", "minNumber": 1.5, "title": "Extra", "pages": 10},
+					{"id": 101, "range": "1", "minNumber": 1, "pages": 40, "lastModifiedUtc": "2024-05-06T07:08:09.123"},
+					{"id": 104, "range": "Artbook", "title": "Artbook", "isSpecial": true, "pages": 5},
+				},
+			},
+		})
+	})
+	mux.HandleFunc("GET /api/Reader/chapter-info", func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "101", r.URL.Query().Get("chapterId"))
+		writeJSON(w, map[string]interface{}{"pages": 3})
+	})
+	mux.HandleFunc("POST /api/Reader/progress", func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		*progress = append(*progress, body)
+	})
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path == "/api/Plugin/authenticate" {
+			if r.URL.Query().Get("apiKey") != "kavita-key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			authCount++
+			currentToken = "token-" + string(rune('0'+authCount))
+			writeJSON(w, map[string]interface{}{"token": currentToken})
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer "+currentToken {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})), &authCount
+}
+
+func TestKavita(t *testing.T) {
+	progress := make([]map[string]interface{}, 0)
+	server, authCount := newFakeKavitaServer(t, &progress)
+	defer server.Close()
+
+	provider := NewKavita(util.NewLogger())
+	provider.(extension.Configurable).SetSavedUserConfig(extension.SavedUserConfig{
+		Values: map[string]string{"url": server.URL, "apiKey": "kavita-key", "pushProgress": "true"},
+	})
+
+	results, err := provider.Search(hibikemanga.SearchOptions{Query: "Dandadan"})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "3$12", results[0].ID)
+	require.Equal(t, []string{"ダンダダン"}, results[0].Synonyms)
+
+	chapters, err := provider.FindChapters("3$12")
+	require.NoError(t, err)
+	require.Len(t, chapters, 4)
+	require.Equal(t, "3$12$20$101", chapters[0].ID)
+	require.Equal(t, "1", chapters[0].Chapter)
+	require.Equal(t, "2024-05-06", chapters[0].UpdatedAt)
+	require.Equal(t, "Chapter 1.5 - Extra", chapters[1].Title)
+	// A volume without chapters
+	require.Equal(t, "3$12$21$103", chapters[2].ID)
+	require.Equal(t, "Volume 2", chapters[2].Title)
+	// Specials go last
+	require.Empty(t, chapters[3].Chapter)
+	require.Equal(t, "Artbook", chapters[3].Title)
+	require.Equal(t, uint(3), chapters[3].Index)
+
+	_, err = provider.FindChapters("12")
+	require.Error(t, err)
+
+	pages, err := provider.FindChapterPages("3$12$20$101")
+	require.NoError(t, err)
+	require.Len(t, pages, 3)
+	require.Contains(t, pages[2].URL, "/api/Reader/image?chapterId=101&page=2&extractPdf=true")
+	require.NotContains(t, pages[2].URL, "kavita-key")
+	reqUrl, _, ok := provider.(PageRequestAuthorizer).AuthorizePageRequest(pages[2].URL)
+	require.True(t, ok)
+	require.Contains(t, reqUrl, "apiKey=kavita-key")
+	require.Equal(t, 1, *authCount)
+
+	// The token expired
+	server.Config.Handler.(http.HandlerFunc)(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/Plugin/authenticate?apiKey=kavita-key", nil))
+	require.NoError(t, provider.(ReadProgressSyncer).SyncReadProgress("3$12$20$101", 2, true))
+	require.Equal(t, 3, *authCount)
+	require.Len(t, progress, 1)
+	require.Equal(t, float64(3), progress[0]["libraryId"])
+	require.Equal(t, float64(12), progress[0]["seriesId"])
+	require.Equal(t, float64(20), progress[0]["volumeId"])
+	require.Equal(t, float64(101), progress[0]["chapterId"])
+	require.Equal(t, float64(3), progress[0]["pageNum"])
+}
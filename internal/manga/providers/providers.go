@@ -3,7 +3,9 @@ package manga_providers
 import "errors"
 
 const (
-	LocalProvider string = "local-manga"
+	LocalProvider  string = "local-manga"
+	KomgaProvider  string = "komga"
+	KavitaProvider string = "kavita"
 )
 
 var (
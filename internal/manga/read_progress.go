@@ -0,0 +1,27 @@
+package manga
+
+import (
+	"seanime/internal/extension"
+	manga_providers "seanime/internal/manga/providers"
+	"seanime/internal/util"
+)
+
+// SyncProviderReadProgress pushes the read progress of a chapter back to the provider, e.g. a Komga or Kavita server.
+// It does nothing if the provider does not support it.
+func (r *Repository) SyncProviderReadProgress(provider string, chapterId string, pageIndex int, completed bool) {
+	defer util.HandlePanicInModuleThen("manga/SyncProviderReadProgress", func() {})
+
+	providerExtension, ok := extension.GetExtension[extension.MangaProviderExtension](r.extensionBankRef.Get(), provider)
+	if !ok {
+		return
+	}
+
+	syncer, ok := providerExtension.GetProvider().(manga_providers.ReadProgressSyncer)
+	if !ok {
+		return
+	}
+
+	if err := syncer.SyncReadProgress(chapterId, pageIndex, completed); err != nil {
+		r.logger.Warn().Err(err).Str("provider", provider).Str("chapterId", chapterId).Msg("manga: Failed to sync read progress")
+	}
+}
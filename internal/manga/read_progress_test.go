@@ -0,0 +1,44 @@
+package manga
+
+import (
+	"seanime/internal/extension"
+	"seanime/internal/testmocks"
+	"seanime/internal/testutil"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeReadProgressSyncer struct {
+	*testmocks.FakeMangaProvider
+	synced []string
+}
+
+func (p *fakeReadProgressSyncer) SyncReadProgress(chapterId string, pageIndex int, completed bool) error {
+	if completed {
+		chapterId += "$completed"
+	}
+	p.synced = append(p.synced, chapterId)
+	return nil
+}
+
+func TestSyncProviderReadProgress(t *testing.T) {
+	env := testutil.NewTestEnv(t)
+	repository := NewTestRepositoryWithEnv(env, env.NewDatabase("manga_read_progress"))
+
+	syncer := &fakeReadProgressSyncer{FakeMangaProvider: testmocks.NewFakeMangaProviderBuilder().Build()}
+	repository.extensionBankRef.Get().Set("komga", extension.NewMangaProviderExtension(&extension.Extension{
+		ID: "komga", Name: "Komga", Type: extension.TypeMangaProvider,
+	}, syncer))
+	repository.extensionBankRef.Get().Set("provider-a", extension.NewMangaProviderExtension(&extension.Extension{
+		ID: "provider-a", Name: "Provider A", Type: extension.TypeMangaProvider,
+	}, testmocks.NewFakeMangaProviderBuilder().Build()))
+
+	repository.SyncProviderReadProgress("komga", "book-1", 3, false)
+	repository.SyncProviderReadProgress("komga", "book-1", 9, true)
+	// Providers that don't support it and unknown providers are ignored
+	repository.SyncProviderReadProgress("provider-a", "ch-1", 0, true)
+	repository.SyncProviderReadProgress("unknown", "ch-1", 0, true)
+
+	require.Equal(t, []string{"book-1", "book-1$completed"}, syncer.synced)
+}
@@ -6,6 +6,7 @@ import (
 	_ "image/png"  // Register PNG format
 	"io"
 	"net/http"
+	"path/filepath"
 	"seanime/internal/database/db"
 	"seanime/internal/database/models"
 	"seanime/internal/events"
@@ -46,6 +47,7 @@ type (
 		sourceRefreshLog map[string]mangaSourceRefreshCompleted
 		downloadDir      string
 		db               *db.Database
+		pageCache        *pageCacheManager
 
 		settings *models.Settings
 	}
@@ -74,6 +76,12 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		db:               opts.Database,
 		sourceRefreshLog: make(map[string]mangaSourceRefreshCompleted),
 	}
+	pageCacheDir := ""
+	if opts.CacheDir != "" {
+		pageCacheDir = filepath.Join(opts.CacheDir, "manga-pages")
+	}
+	r.pageCache = newPageCacheManager(opts.Logger, pageCacheDir)
+	r.pageCache.authorize = r.authorizePageRequest
 	return r
 }
 
@@ -0,0 +1,305 @@
+package kodi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrNoActivePlayer = errors.New("kodi: No active video player")
+)
+
+// Kodi controls a Kodi instance through its JSON-RPC API.
+// Kodi is not started by Seanime, it must be running with "Allow remote control via HTTP" enabled.
+// Since Kodi usually runs on another device, local files must be reachable with the same path from it.
+type Kodi struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	Logger   *zerolog.Logger
+
+	client *http.Client
+	nextId atomic.Int64
+}
+
+type (
+	rpcRequest struct {
+		JsonRPC string      `json:"jsonrpc"`
+		Method  string      `json:"method"`
+		Params  interface{} `json:"params,omitempty"`
+		ID      int64       `json:"id"`
+	}
+
+	rpcResponse struct {
+		ID     int64           `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *rpcError       `json:"error"`
+	}
+
+	rpcError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+
+	// Time is the time format used by Kodi
+	Time struct {
+		Hours        int `json:"hours"`
+		Minutes      int `json:"minutes"`
+		Seconds      int `json:"seconds"`
+		Milliseconds int `json:"milliseconds"`
+	}
+
+	// Status is the state of the active video player
+	Status struct {
+		// Filepath is the path or URL of the playing item
+		Filepath string
+		// Filename is the base name of Filepath
+		Filename string
+		// Position in seconds
+		Position float64
+		// Duration in seconds
+		Duration float64
+		Paused   bool
+	}
+)
+
+func (e *rpcError) Error() string {
+	return fmt.Sprintf("kodi: %s (%d)", e.Message, e.Code)
+}
+
+func (t Time) ToSeconds() float64 {
+	return float64(t.Hours*3600+t.Minutes*60+t.Seconds) + float64(t.Milliseconds)/1000
+}
+
+// NewTime converts seconds to the time format used by Kodi.
+func NewTime(seconds float64) Time {
+	if seconds < 0 {
+		seconds = 0
+	}
+	ms := int(seconds * 1000)
+	return Time{
+		Hours:        ms / 3_600_000,
+		Minutes:      (ms / 60_000) % 60,
+		Seconds:      (ms / 1000) % 60,
+		Milliseconds: ms % 1000,
+	}
+}
+
+// DefaultPort is the default port of the Kodi web server
+const DefaultPort = 8080
+
+func (k *Kodi) url() string {
+	port := k.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+	return fmt.Sprintf("http://%s:%d/jsonrpc", k.Host, port)
+}
+
+func (k *Kodi) httpClient() *http.Client {
+	if k.client == nil {
+		k.client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return k.client
+}
+
+// call sends a JSON-RPC request to Kodi and decodes the result into ret.
+func (k *Kodi) call(method string, params interface{}, ret interface{}) error {
+	body, err := json.Marshal(&rpcRequest{
+		JsonRPC: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      k.nextId.Add(1),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, k.url(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if k.Username != "" || k.Password != "" {
+		req.SetBasicAuth(k.Username, k.Password)
+	}
+
+	resp, err := k.httpClient().Do(req)
+	if err != nil {
+		return fmt.Errorf("kodi: Failed to connect: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		return errors.New("kodi: Invalid username or password")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("kodi: http error code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("kodi: Failed to read response: %w", err)
+	}
+
+	var res rpcResponse
+	if err := json.Unmarshal(data, &res); err != nil {
+		return fmt.Errorf("kodi: Invalid response: %w", err)
+	}
+	if res.Error != nil {
+		return res.Error
+	}
+
+	if ret != nil && len(res.Result) > 0 {
+		if err := json.Unmarshal(res.Result, ret); err != nil {
+			return fmt.Errorf("kodi: Invalid result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Start checks that Kodi is reachable.
+func (k *Kodi) Start() error {
+	var pong string
+	if err := k.call("JSONRPC.Ping", nil, &pong); err != nil {
+		k.Logger.Error().Err(err).Msg("kodi: Could not reach Kodi")
+		return err
+	}
+	return nil
+}
+
+// OpenAndPlay plays a file path or a stream URL.
+func (k *Kodi) OpenAndPlay(path string) error {
+	k.Logger.Trace().Str("path", path).Msg("kodi: Opening and playing")
+
+	params := map[string]interface{}{
+		"item": map[string]interface{}{"file": path},
+	}
+	if err := k.call("Player.Open", params, nil); err != nil {
+		k.Logger.Error().Err(err).Msg("kodi: Failed to open and play")
+		return err
+	}
+	return nil
+}
+
+// getVideoPlayerId returns the id of the active video player.
+func (k *Kodi) getVideoPlayerId() (int, error) {
+	var players []struct {
+		PlayerID int    `json:"playerid"`
+		Type     string `json:"type"`
+	}
+	if err := k.call("Player.GetActivePlayers", nil, &players); err != nil {
+		return 0, err
+	}
+
+	for _, p := range players {
+		if p.Type == "video" {
+			return p.PlayerID, nil
+		}
+	}
+
+	return 0, ErrNoActivePlayer
+}
+
+// GetStatus returns the state of the active video player.
+func (k *Kodi) GetStatus() (*Status, error) {
+	playerId, err := k.getVideoPlayerId()
+	if err != nil {
+		return nil, err
+	}
+
+	var props struct {
+		Time      Time `json:"time"`
+		TotalTime Time `json:"totaltime"`
+		Speed     int  `json:"speed"`
+	}
+	err = k.call("Player.GetProperties", map[string]interface{}{
+		"playerid":   playerId,
+		"properties": []string{"time", "totaltime", "speed"},
+	}, &props)
+	if err != nil {
+		return nil, err
+	}
+
+	var item struct {
+		Item struct {
+			File  string `json:"file"`
+			Label string `json:"label"`
+		} `json:"item"`
+	}
+	err = k.call("Player.GetItem", map[string]interface{}{
+		"playerid":   playerId,
+		"properties": []string{"file"},
+	}, &item)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &Status{
+		Filepath: item.Item.File,
+		Filename: filepath.Base(filepath.FromSlash(item.Item.File)),
+		Position: props.Time.ToSeconds(),
+		Duration: props.TotalTime.ToSeconds(),
+		Paused:   props.Speed == 0,
+	}
+	if ret.Filepath == "" {
+		ret.Filename = item.Item.Label
+	}
+
+	return ret, nil
+}
+
+func (k *Kodi) setPlaying(play bool) error {
+	playerId, err := k.getVideoPlayerId()
+	if err != nil {
+		return err
+	}
+	return k.call("Player.PlayPause", map[string]interface{}{
+		"playerid": playerId,
+		"play":     play,
+	}, nil)
+}
+
+func (k *Kodi) Pause() error {
+	return k.setPlaying(false)
+}
+
+func (k *Kodi) Resume() error {
+	return k.setPlaying(true)
+}
+
+// SeekTo seeks to the position in seconds.
+func (k *Kodi) SeekTo(seconds float64) error {
+	playerId, err := k.getVideoPlayerId()
+	if err != nil {
+		return err
+	}
+	return k.call("Player.Seek", map[string]interface{}{
+		"playerid": playerId,
+		"value":    map[string]interface{}{"time": NewTime(seconds)},
+	}, nil)
+}
+
+// Stop stops the active video player.
+// It does nothing if nothing is playing.
+func (k *Kodi) Stop() error {
+	playerId, err := k.getVideoPlayerId()
+	if err != nil {
+		if errors.Is(err, ErrNoActivePlayer) {
+			return nil
+		}
+		return err
+	}
+	return k.call("Player.Stop", map[string]interface{}{"playerid": playerId}, nil)
+}
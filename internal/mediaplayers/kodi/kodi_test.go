@@ -0,0 +1,179 @@
+package kodi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"seanime/internal/util"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeKodi is a minimal Kodi JSON-RPC server with a single video player.
+type fakeKodi struct {
+	mu       sync.Mutex
+	file     string
+	playing  bool
+	position float64
+	duration float64
+	methods  []string
+}
+
+func (f *fakeKodi) handler(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "kodi" || pass != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Method string                     `json:"method"`
+			Params map[string]json.RawMessage `json:"params"`
+			ID     int64                      `json:"id"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.methods = append(f.methods, req.Method)
+
+		var result interface{} = "OK"
+		switch req.Method {
+		case "JSONRPC.Ping":
+			result = "pong"
+		case "Player.Open":
+			var item struct {
+				File string `json:"file"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params["item"], &item))
+			f.file = item.File
+			f.playing = true
+			f.position = 0
+			f.duration = 1420.5
+		case "Player.GetActivePlayers":
+			if f.file == "" {
+				result = []interface{}{}
+			} else {
+				result = []interface{}{map[string]interface{}{"playerid": 1, "type": "video"}}
+			}
+		case "Player.GetProperties":
+			speed := 0
+			if f.playing {
+				speed = 1
+			}
+			result = map[string]interface{}{
+				"time":      NewTime(f.position),
+				"totaltime": NewTime(f.duration),
+				"speed":     speed,
+			}
+		case "Player.GetItem":
+			result = map[string]interface{}{"item": map[string]interface{}{"file": f.file, "label": "Episode", "type": "unknown"}}
+		case "Player.PlayPause":
+			var play bool
+			require.NoError(t, json.Unmarshal(req.Params["play"], &play))
+			f.playing = play
+		case "Player.Seek":
+			var value struct {
+				Time Time `json:"time"`
+			}
+			require.NoError(t, json.Unmarshal(req.Params["value"], &value))
+			f.position = value.Time.ToSeconds()
+		case "Player.Stop":
+			f.file = ""
+			f.playing = false
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"id":      req.ID,
+				"error":   map[string]interface{}{"code": -32601, "message": "Method not found."},
+			})
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  result,
+		})
+	}
+}
+
+func newTestKodi(t *testing.T, f *fakeKodi) *Kodi {
+	server := httptest.NewServer(f.handler(t))
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	port, err := strconv.Atoi(u.Port())
+	require.NoError(t, err)
+
+	return &Kodi{
+		Host:     u.Hostname(),
+		Port:     port,
+		Username: "kodi",
+		Password: "secret",
+		Logger:   util.NewLogger(),
+	}
+}
+
+func TestKodi_Playback(t *testing.T) {
+	f := &fakeKodi{}
+	k := newTestKodi(t, f)
+
+	require.NoError(t, k.Start())
+
+	_, err := k.GetStatus()
+	require.ErrorIs(t, err, ErrNoActivePlayer)
+
+	require.NoError(t, k.OpenAndPlay("/mnt/anime/Frieren/[SubsPlease] Frieren - 01 (1080p).mkv"))
+
+	status, err := k.GetStatus()
+	require.NoError(t, err)
+	require.Equal(t, "/mnt/anime/Frieren/[SubsPlease] Frieren - 01 (1080p).mkv", status.Filepath)
+	require.Equal(t, "[SubsPlease] Frieren - 01 (1080p).mkv", status.Filename)
+	require.False(t, status.Paused)
+	require.InDelta(t, 1420.5, status.Duration, 0.001)
+
+	require.NoError(t, k.Pause())
+	require.NoError(t, k.SeekTo(754.25))
+
+	status, err = k.GetStatus()
+	require.NoError(t, err)
+	require.True(t, status.Paused)
+	require.InDelta(t, 754.25, status.Position, 0.001)
+
+	require.NoError(t, k.Resume())
+	require.NoError(t, k.Stop())
+	// Stopping when nothing is playing is not an error
+	require.NoError(t, k.Stop())
+
+	_, err = k.GetStatus()
+	require.ErrorIs(t, err, ErrNoActivePlayer)
+}
+
+func TestKodi_Errors(t *testing.T) {
+	f := &fakeKodi{}
+	k := newTestKodi(t, f)
+
+	k.Password = "wrong"
+	require.Error(t, k.Start())
+
+	k.Password = "secret"
+	err := k.call("Player.Unknown", nil, nil)
+	require.Error(t, err)
+	var rpcErr *rpcError
+	require.ErrorAs(t, err, &rpcErr)
+	require.Equal(t, -32601, rpcErr.Code)
+}
+
+func TestNewTime(t *testing.T) {
+	tm := NewTime(3725.5)
+	require.Equal(t, Time{Hours: 1, Minutes: 2, Seconds: 5, Milliseconds: 500}, tm)
+	require.InDelta(t, 3725.5, tm.ToSeconds(), 0.001)
+	require.Equal(t, Time{}, NewTime(-3))
+}
@@ -8,8 +8,10 @@ import (
 	"seanime/internal/events"
 	"seanime/internal/hook"
 	"seanime/internal/mediaplayers/iina"
+	"seanime/internal/mediaplayers/kodi"
 	mpchc2 "seanime/internal/mediaplayers/mpchc"
 	"seanime/internal/mediaplayers/mpv"
+	"seanime/internal/mediaplayers/upnp"
 	vlc2 "seanime/internal/mediaplayers/vlc"
 	"seanime/internal/util/result"
 	"sync"
@@ -38,6 +40,8 @@ type (
 		MpcHc                 *mpchc2.MpcHc
 		Mpv                   *mpv.Mpv
 		Iina                  *iina.Iina
+		Kodi                  *kodi.Kodi
+		Upnp                  *upnp.Upnp
 		wsEventManager        events.WSEventManagerInterface
 		continuityManager     *continuity.Manager
 		playerInUse           string
@@ -58,6 +62,8 @@ type (
 		MpcHc             *mpchc2.MpcHc
 		Mpv               *mpv.Mpv
 		Iina              *iina.Iina
+		Kodi              *kodi.Kodi
+		Upnp              *upnp.Upnp
 		WSEventManager    events.WSEventManagerInterface
 		ContinuityManager *continuity.Manager
 	}
@@ -150,6 +156,8 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		MpcHc:                 opts.MpcHc,
 		Mpv:                   opts.Mpv,
 		Iina:                  opts.Iina,
+		Kodi:                  opts.Kodi,
+		Upnp:                  opts.Upnp,
 		wsEventManager:        opts.WSEventManager,
 		continuityManager:     opts.ContinuityManager,
 		completionThreshold:   0.8,
@@ -332,6 +340,47 @@ func (m *Repository) Play(path string) error {
 			}
 		}
 
+		return nil
+	case "kodi":
+		err := m.Kodi.Start()
+		if err != nil {
+			m.Logger.Error().Err(err).Msg("media player: Could not reach Kodi")
+			return fmt.Errorf("could not reach Kodi, %w", err)
+		}
+		err = m.Kodi.OpenAndPlay(path)
+		if err != nil {
+			m.Logger.Error().Err(err).Msg("media player: Could not open and play video using Kodi")
+			return fmt.Errorf("could not open and play video, %w", err)
+		}
+
+		if m.continuityManager.GetSettings().WatchContinuityEnabled {
+			if lastWatched.Found {
+				time.Sleep(400 * time.Millisecond)
+				_ = m.Kodi.SeekTo(lastWatched.Item.CurrentTime)
+			}
+		}
+
+		return nil
+	case "upnp":
+		err := m.Upnp.Start()
+		if err != nil {
+			m.Logger.Error().Err(err).Msg("media player: Could not reach UPnP renderer")
+			return fmt.Errorf("could not reach UPnP renderer, %w", err)
+		}
+		err = m.Upnp.OpenAndPlay(path)
+		if err != nil {
+			m.Logger.Error().Err(err).Msg("media player: Could not cast video to UPnP renderer")
+			return fmt.Errorf("could not open and play video, %w", err)
+		}
+
+		if m.continuityManager.GetSettings().WatchContinuityEnabled {
+			if lastWatched.Found {
+				// Renderers need some time to buffer before they accept seeking
+				time.Sleep(2 * time.Second)
+				_ = m.Upnp.SeekTo(lastWatched.Item.CurrentTime)
+			}
+		}
+
 		return nil
 	default:
 		return errors.New("no default media player set")
@@ -370,6 +419,10 @@ func (m *Repository) Pause() error {
 		return m.Mpv.Pause()
 	case "iina":
 		return m.Iina.Pause()
+	case "kodi":
+		return m.Kodi.Pause()
+	case "upnp":
+		return m.Upnp.Pause()
 	default:
 		return errors.New("no default media player set")
 	}
@@ -385,6 +438,10 @@ func (m *Repository) Resume() error {
 		return m.Mpv.Resume()
 	case "iina":
 		return m.Iina.Resume()
+	case "kodi":
+		return m.Kodi.Resume()
+	case "upnp":
+		return m.Upnp.Resume()
 	default:
 		return errors.New("no default media player set")
 	}
@@ -400,6 +457,10 @@ func (m *Repository) SeekTo(seconds float64) error {
 		return m.Mpv.SeekTo(seconds)
 	case "iina":
 		return m.Iina.SeekTo(seconds)
+	case "kodi":
+		return m.Kodi.SeekTo(seconds)
+	case "upnp":
+		return m.Upnp.SeekTo(seconds)
 	default:
 		return errors.New("no default media player set")
 	}
@@ -420,6 +481,10 @@ func (m *Repository) Stream(streamUrl string, episode int, mediaId int, windowTi
 		// MPV does not need to be started
 	case "iina":
 		// IINA does not need to be started
+	case "kodi":
+		err = m.Kodi.Start()
+	case "upnp":
+		err = m.Upnp.Start()
 	default:
 		return errors.New("no default media player set")
 	}
@@ -488,6 +553,26 @@ func (m *Repository) Stream(streamUrl string, episode int, mediaId int, windowTi
 			err = m.Iina.OpenAndPlay(streamUrl, args...)
 		}
 
+	case "kodi":
+		err = m.Kodi.OpenAndPlay(streamUrl)
+
+		if err == nil && m.continuityManager.GetSettings().WatchContinuityEnabled {
+			if lastWatched.Found {
+				time.Sleep(400 * time.Millisecond)
+				_ = m.Kodi.SeekTo(lastWatched.Item.CurrentTime)
+			}
+		}
+
+	case "upnp":
+		err = m.Upnp.OpenAndPlay(streamUrl)
+
+		if err == nil && m.continuityManager.GetSettings().WatchContinuityEnabled {
+			if lastWatched.Found {
+				time.Sleep(2 * time.Second)
+				_ = m.Upnp.SeekTo(lastWatched.Item.CurrentTime)
+			}
+		}
+
 	}
 
 	if err != nil {
@@ -512,6 +597,10 @@ func (m *Repository) Cancel() {
 		go m.Mpv.CloseAll()
 	case "iina":
 		go m.Iina.CloseAll()
+	case "kodi":
+		go m.Kodi.Stop()
+	case "upnp":
+		go m.Upnp.Stop()
 	}
 	m.mu.Unlock()
 }
@@ -530,6 +619,10 @@ func (m *Repository) Stop() {
 		m.Mpv.CloseAll()
 	case "iina":
 		m.Iina.CloseAll()
+	case "kodi":
+		_ = m.Kodi.Stop()
+	case "upnp":
+		_ = m.Upnp.Stop()
 	}
 	m.mu.Unlock()
 }
@@ -929,6 +1022,10 @@ func (m *Repository) getStatus() (interface{}, error) {
 		return m.Mpv.GetPlaybackStatus()
 	case "iina":
 		return m.Iina.GetPlaybackStatus()
+	case "kodi":
+		return m.Kodi.GetStatus()
+	case "upnp":
+		return m.Upnp.GetStatus()
 	}
 	return nil, errors.New("unsupported media player")
 }
@@ -944,6 +1041,25 @@ func clampPercentage(v float64) float64 {
 	return v
 }
 
+// processRemotePlayerStatus updates the playback status from players that report their position and duration in seconds,
+// i.e. Kodi and UPnP renderers.
+func (m *Repository) processRemotePlayerStatus(filepath, filename string, position, duration float64, paused bool) bool {
+	if duration == 0 {
+		return false
+	}
+
+	m.currentPlaybackStatus.CompletionPercentage = clampPercentage(position / duration)
+	m.currentPlaybackStatus.Playing = !paused
+	m.currentPlaybackStatus.Filename = filename
+	m.currentPlaybackStatus.Duration = int(duration * 1000)
+	m.currentPlaybackStatus.Filepath = filepath
+
+	m.currentPlaybackStatus.CurrentTimeInSeconds = position
+	m.currentPlaybackStatus.DurationInSeconds = duration
+
+	return true
+}
+
 func (m *Repository) processStatus(player string, status interface{}) bool {
 	m.currentPlaybackStatus.PlaybackType = PlaybackTypeFile
 	switch player {
@@ -1014,6 +1130,20 @@ func (m *Repository) processStatus(player string, status interface{}) bool {
 		m.currentPlaybackStatus.DurationInSeconds = st.Duration
 
 		return true
+	case "kodi":
+		// Process Kodi status
+		st, ok := status.(*kodi.Status)
+		if !ok || st == nil {
+			return false
+		}
+		return m.processRemotePlayerStatus(st.Filepath, st.Filename, st.Position, st.Duration, st.Paused)
+	case "upnp":
+		// Process UPnP renderer status
+		st, ok := status.(*upnp.Status)
+		if !ok || st == nil {
+			return false
+		}
+		return m.processRemotePlayerStatus(st.Filepath, st.Filename, st.Position, st.Duration, st.Paused)
 	default:
 		return false
 	}
@@ -1090,6 +1220,20 @@ func (m *Repository) processStreamStatus(player string, status interface{}) bool
 		m.currentPlaybackStatus.DurationInSeconds = st.Duration
 
 		return true
+	case "kodi":
+		// Process Kodi status
+		st, ok := status.(*kodi.Status)
+		if !ok || st == nil {
+			return false
+		}
+		return m.processRemotePlayerStatus(st.Filepath, st.Filename, st.Position, st.Duration, st.Paused)
+	case "upnp":
+		// Process UPnP renderer status
+		st, ok := status.(*upnp.Status)
+		if !ok || st == nil {
+			return false
+		}
+		return m.processRemotePlayerStatus(st.Filepath, st.Filename, st.Position, st.Duration, st.Paused)
 	default:
 		return false
 	}
@@ -0,0 +1,213 @@
+package upnp
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	TransportStatePlaying       = "PLAYING"
+	TransportStatePaused        = "PAUSED_PLAYBACK"
+	TransportStateStopped       = "STOPPED"
+	TransportStateTransitioning = "TRANSITIONING"
+	TransportStateNoMedia       = "NO_MEDIA_PRESENT"
+)
+
+type (
+	// PositionInfo is the result of the GetPositionInfo action
+	PositionInfo struct {
+		TrackURI string
+		// Position in seconds
+		Position float64
+		// Duration in seconds
+		Duration float64
+	}
+
+	soapFault struct {
+		Code        int    `xml:"detail>UPnPError>errorCode"`
+		Description string `xml:"detail>UPnPError>errorDescription"`
+	}
+
+	soapEnvelope struct {
+		Body struct {
+			Fault *soapFault `xml:"Fault"`
+			Inner []byte     `xml:",innerxml"`
+		} `xml:"Body"`
+	}
+)
+
+func (f *soapFault) Error() string {
+	if f.Description == "" {
+		return fmt.Sprintf("upnp: Renderer error (%d)", f.Code)
+	}
+	return fmt.Sprintf("upnp: %s (%d)", f.Description, f.Code)
+}
+
+// call invokes an AVTransport action and decodes the response arguments into ret.
+func (r *Renderer) call(ctx context.Context, client *http.Client, action string, args [][2]string, ret interface{}) error {
+	var body strings.Builder
+	body.WriteString(`<?xml version="1.0" encoding="utf-8"?>`)
+	body.WriteString(`<s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/" s:encodingStyle="http://schemas.xmlsoap.org/soap/encoding/"><s:Body>`)
+	body.WriteString(`<u:` + action + ` xmlns:u="` + avTransportType + `">`)
+	body.WriteString(`<InstanceID>0</InstanceID>`)
+	for _, arg := range args {
+		body.WriteString("<" + arg[0] + ">" + html.EscapeString(arg[1]) + "</" + arg[0] + ">")
+	}
+	body.WriteString(`</u:` + action + `>`)
+	body.WriteString(`</s:Body></s:Envelope>`)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.avTransportUrl, strings.NewReader(body.String()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", `text/xml; charset="utf-8"`)
+	req.Header.Set("SOAPAction", fmt.Sprintf(`"%s#%s"`, avTransportType, action))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("upnp: Failed to reach renderer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, descriptionMaxSize))
+	if err != nil {
+		return fmt.Errorf("upnp: Failed to read response: %w", err)
+	}
+
+	var env soapEnvelope
+	if err := xml.Unmarshal(data, &env); err != nil {
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("upnp: http error code: %d", resp.StatusCode)
+		}
+		return fmt.Errorf("upnp: Invalid response: %w", err)
+	}
+	if env.Body.Fault != nil {
+		return env.Body.Fault
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("upnp: http error code: %d", resp.StatusCode)
+	}
+
+	if ret != nil {
+		if err := xml.Unmarshal(env.Body.Inner, ret); err != nil {
+			return fmt.Errorf("upnp: Invalid result: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// SetAVTransportURI loads the URI on the renderer.
+func (r *Renderer) SetAVTransportURI(ctx context.Context, client *http.Client, uri string, title string, mimeType string) error {
+	return r.call(ctx, client, "SetAVTransportURI", [][2]string{
+		{"CurrentURI", uri},
+		{"CurrentURIMetaData", didlMetadata(uri, title, mimeType)},
+	}, nil)
+}
+
+func (r *Renderer) Play(ctx context.Context, client *http.Client) error {
+	return r.call(ctx, client, "Play", [][2]string{{"Speed", "1"}}, nil)
+}
+
+func (r *Renderer) Pause(ctx context.Context, client *http.Client) error {
+	return r.call(ctx, client, "Pause", nil, nil)
+}
+
+func (r *Renderer) Stop(ctx context.Context, client *http.Client) error {
+	return r.call(ctx, client, "Stop", nil, nil)
+}
+
+// Seek seeks to the position in seconds.
+func (r *Renderer) Seek(ctx context.Context, client *http.Client, seconds float64) error {
+	return r.call(ctx, client, "Seek", [][2]string{
+		{"Unit", "REL_TIME"},
+		{"Target", formatTime(seconds)},
+	}, nil)
+}
+
+func (r *Renderer) GetPositionInfo(ctx context.Context, client *http.Client) (*PositionInfo, error) {
+	var res struct {
+		TrackDuration string `xml:"TrackDuration"`
+		TrackURI      string `xml:"TrackURI"`
+		RelTime       string `xml:"RelTime"`
+	}
+	if err := r.call(ctx, client, "GetPositionInfo", nil, &res); err != nil {
+		return nil, err
+	}
+	return &PositionInfo{
+		TrackURI: res.TrackURI,
+		Position: parseTime(res.RelTime),
+		Duration: parseTime(res.TrackDuration),
+	}, nil
+}
+
+// GetTransportState returns the current transport state, e.g. TransportStatePlaying.
+func (r *Renderer) GetTransportState(ctx context.Context, client *http.Client) (string, error) {
+	var res struct {
+		CurrentTransportState string `xml:"CurrentTransportState"`
+	}
+	if err := r.call(ctx, client, "GetTransportInfo", nil, &res); err != nil {
+		return "", err
+	}
+	return res.CurrentTransportState, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// didlMetadata returns the DIDL-Lite metadata describing the item.
+// Some renderers refuse to play a URI without metadata.
+func didlMetadata(uri string, title string, mimeType string) string {
+	var buf bytes.Buffer
+	buf.WriteString(`<DIDL-Lite xmlns="urn:schemas-upnp-org:metadata-1-0/DIDL-Lite/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:upnp="urn:schemas-upnp-org:metadata-1-0/upnp/">`)
+	buf.WriteString(`<item id="0" parentID="-1" restricted="1">`)
+	buf.WriteString(`<dc:title>` + html.EscapeString(title) + `</dc:title>`)
+	buf.WriteString(`<upnp:class>object.item.videoItem</upnp:class>`)
+	buf.WriteString(`<res protocolInfo="http-get:*:` + html.EscapeString(mimeType) + `:*">` + html.EscapeString(uri) + `</res>`)
+	buf.WriteString(`</item></DIDL-Lite>`)
+	return buf.String()
+}
+
+// formatTime formats seconds as H:MM:SS.
+func formatTime(seconds float64) string {
+	if seconds < 0 {
+		seconds = 0
+	}
+	s := int(seconds)
+	return fmt.Sprintf("%d:%02d:%02d", s/3600, (s/60)%60, s%60)
+}
+
+// parseTime parses a H+:MM:SS[.F+] duration into seconds.
+// Invalid values such as "NOT_IMPLEMENTED" return 0.
+func parseTime(value string) float64 {
+	parts := strings.Split(strings.TrimSpace(value), ":")
+	if len(parts) != 3 {
+		return 0
+	}
+
+	var ret float64
+	for i, part := range parts {
+		// Fractions written as F0/F1 are ignored
+		if i == 2 && strings.Contains(part, "/") {
+			part, _, _ = strings.Cut(part, ".")
+		}
+		v, err := strconv.ParseFloat(part, 64)
+		if err != nil || v < 0 {
+			return 0
+		}
+		ret = ret*60 + v
+	}
+	return ret
+}
+
+func isFault(err error, code int) bool {
+	var fault *soapFault
+	return errors.As(err, &fault) && fault.Code == code
+}
@@ -0,0 +1,219 @@
+package upnp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	mediaRendererType     = "urn:schemas-upnp-org:device:MediaRenderer:1"
+	avTransportType       = "urn:schemas-upnp-org:service:AVTransport:1"
+	ssdpAddr              = "239.255.255.250:1900"
+	descriptionMaxSize    = 1 << 20
+	defaultDiscoverWindow = 3 * time.Second
+)
+
+var (
+	ErrNoAVTransport = errors.New("upnp: The device does not support AVTransport")
+)
+
+// Renderer is a UPnP MediaRenderer (smart TV, console, etc.) that supports the AVTransport service.
+type Renderer struct {
+	Name           string `json:"name"`
+	UDN            string `json:"udn"`
+	Location       string `json:"location"` // URL of the device description
+	avTransportUrl string
+}
+
+type deviceDescription struct {
+	URLBase string           `xml:"URLBase"`
+	Device  deviceDescDevice `xml:"device"`
+}
+
+type deviceDescDevice struct {
+	DeviceType   string              `xml:"deviceType"`
+	FriendlyName string              `xml:"friendlyName"`
+	UDN          string              `xml:"UDN"`
+	Services     []deviceDescService `xml:"serviceList>service"`
+	Devices      []deviceDescDevice  `xml:"deviceList>device"`
+}
+
+type deviceDescService struct {
+	ServiceType string `xml:"serviceType"`
+	ControlURL  string `xml:"controlURL"`
+}
+
+// findAVTransport returns the control URL of the AVTransport service of the device or its embedded devices.
+func (d *deviceDescDevice) findAVTransport() (*deviceDescDevice, string, bool) {
+	for _, s := range d.Services {
+		if strings.HasPrefix(s.ServiceType, "urn:schemas-upnp-org:service:AVTransport:") {
+			return d, s.ControlURL, true
+		}
+	}
+	for i := range d.Devices {
+		if dev, controlUrl, ok := d.Devices[i].findAVTransport(); ok {
+			return dev, controlUrl, true
+		}
+	}
+	return nil, "", false
+}
+
+// LoadRenderer fetches the device description at the location.
+func LoadRenderer(ctx context.Context, client *http.Client, location string) (*Renderer, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, location, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("upnp: Failed to reach renderer: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("upnp: Failed to get device description: status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, descriptionMaxSize))
+	if err != nil {
+		return nil, err
+	}
+
+	var desc deviceDescription
+	if err := xml.Unmarshal(data, &desc); err != nil {
+		return nil, fmt.Errorf("upnp: Invalid device description: %w", err)
+	}
+
+	dev, controlUrl, ok := desc.Device.findAVTransport()
+	if !ok {
+		return nil, ErrNoAVTransport
+	}
+
+	base := location
+	if desc.URLBase != "" {
+		base = desc.URLBase
+	}
+	baseUrl, err := url.Parse(base)
+	if err != nil {
+		return nil, err
+	}
+	ref, err := url.Parse(strings.TrimSpace(controlUrl))
+	if err != nil {
+		return nil, err
+	}
+
+	name := desc.Device.FriendlyName
+	if name == "" {
+		name = dev.FriendlyName
+	}
+
+	return &Renderer{
+		Name:           name,
+		UDN:            desc.Device.UDN,
+		Location:       location,
+		avTransportUrl: baseUrl.ResolveReference(ref).String(),
+	}, nil
+}
+
+// Discover searches the local network for media renderers until the timeout expires.
+func Discover(ctx context.Context, timeout time.Duration) ([]*Renderer, error) {
+	if timeout <= 0 {
+		timeout = defaultDiscoverWindow
+	}
+
+	group, err := net.ResolveUDPAddr("udp4", ssdpAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, fmt.Errorf("upnp: Failed to open socket: %w", err)
+	}
+	defer conn.Close()
+
+	mx := int(timeout.Seconds())
+	if mx < 1 {
+		mx = 1
+	}
+	search := []byte(fmt.Sprintf("M-SEARCH * HTTP/1.1\r\n"+
+		"HOST: %s\r\n"+
+		"MAN: \"ssdp:discover\"\r\n"+
+		"MX: %d\r\n"+
+		"ST: %s\r\n"+
+		"\r\n", ssdpAddr, mx, mediaRendererType))
+
+	// Send the request twice since UDP is unreliable
+	for i := 0; i < 2; i++ {
+		if _, err := conn.WriteToUDP(search, group); err != nil {
+			return nil, fmt.Errorf("upnp: Failed to send search request: %w", err)
+		}
+	}
+
+	deadline := time.Now().Add(timeout)
+	_ = conn.SetReadDeadline(deadline)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.SetReadDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	locations := make(map[string]struct{})
+	buf := make([]byte, 4096)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			break
+		}
+		if location, ok := parseSearchResponse(buf[:n]); ok {
+			locations[location] = struct{}{}
+		}
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	ret := make([]*Renderer, 0, len(locations))
+	seen := make(map[string]struct{})
+	for location := range locations {
+		r, err := LoadRenderer(ctx, client, location)
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[r.UDN]; ok {
+			continue
+		}
+		seen[r.UDN] = struct{}{}
+		ret = append(ret, r)
+	}
+
+	return ret, nil
+}
+
+// parseSearchResponse returns the location of a media renderer from an M-SEARCH response.
+func parseSearchResponse(data []byte) (string, bool) {
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), nil)
+	if err != nil {
+		return "", false
+	}
+	_ = resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("St") != mediaRendererType {
+		return "", false
+	}
+	location := resp.Header.Get("Location")
+	return location, location != ""
+}
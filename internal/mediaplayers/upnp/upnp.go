@@ -0,0 +1,447 @@
+package upnp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrNoRenderer = errors.New("upnp: No renderer selected")
+	ErrNotPlaying = errors.New("upnp: Nothing is playing on the renderer")
+	// ErrStreamUnreachable is returned when the stream is served on the loopback address only
+	ErrStreamUnreachable = errors.New("upnp: The renderer cannot reach the stream because the server only listens on this device, set the server host to 0.0.0.0 to cast streams")
+	errFileNotServing    = errors.New("upnp: File is not being served")
+)
+
+// Upnp casts videos to a DLNA/UPnP MediaRenderer (smart TV, console, etc.).
+// Local files are served by an embedded HTTP server listening on every interface,
+// stream URLs pointing to the loopback address are rewritten to the address the renderer can reach.
+type Upnp struct {
+	// RendererLocation is the URL of the device description of the renderer
+	RendererLocation string
+	Logger           *zerolog.Logger
+	// HMACTokenFunc returns the token query parameter that authenticates a request to an endpoint of the server, can be nil.
+	// Renderers cannot send the server password, so the stream URLs of the server are signed.
+	HMACTokenFunc func(endpoint string, symbol string) string
+
+	mu        sync.Mutex
+	client    *http.Client
+	renderer  *Renderer
+	server    *http.Server
+	serverUrl string // Base URL of the file server, e.g. http://192.168.1.2:54321
+	serving   string // Path of the served file
+	token     string // Random token of the served file URL
+	current   string // Path or URL passed to OpenAndPlay
+	started   bool   // Whether the renderer reported the item as playing
+}
+
+// Status is the state of the renderer
+type Status struct {
+	// Filepath is the path or URL that was cast
+	Filepath string
+	// Filename is the base name of Filepath
+	Filename string
+	// Position in seconds
+	Position float64
+	// Duration in seconds
+	Duration float64
+	Paused   bool
+}
+
+func (u *Upnp) httpClient() *http.Client {
+	if u.client == nil {
+		u.client = &http.Client{Timeout: 5 * time.Second}
+	}
+	return u.client
+}
+
+// getRenderer returns the renderer, fetching its description if needed.
+func (u *Upnp) getRenderer() (*Renderer, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.RendererLocation == "" {
+		return nil, ErrNoRenderer
+	}
+	if u.renderer != nil && u.renderer.Location == u.RendererLocation {
+		return u.renderer, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	r, err := LoadRenderer(ctx, u.httpClient(), u.RendererLocation)
+	if err != nil {
+		return nil, err
+	}
+	u.renderer = r
+	return r, nil
+}
+
+// Start checks that the renderer is reachable.
+func (u *Upnp) Start() error {
+	r, err := u.getRenderer()
+	if err != nil {
+		u.Logger.Error().Err(err).Msg("upnp: Could not reach renderer")
+		return err
+	}
+	u.Logger.Debug().Str("renderer", r.Name).Msg("upnp: Renderer found")
+	return nil
+}
+
+// OpenAndPlay casts a local file or a stream URL to the renderer.
+func (u *Upnp) OpenAndPlay(pathOrUrl string) error {
+	r, err := u.getRenderer()
+	if err != nil {
+		return err
+	}
+
+	u.Logger.Trace().Str("path", pathOrUrl).Str("renderer", r.Name).Msg("upnp: Opening and playing")
+
+	uri, err := u.mediaUrl(pathOrUrl, r)
+	if err != nil {
+		u.Logger.Error().Err(err).Msg("upnp: Failed to prepare media URL")
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Some renderers refuse to load a new URI while playing
+	_ = r.Stop(ctx, u.httpClient())
+
+	title := mediaTitle(pathOrUrl)
+	if err := r.SetAVTransportURI(ctx, u.httpClient(), uri, title, mimeType(title)); err != nil {
+		u.Logger.Error().Err(err).Msg("upnp: Failed to set transport URI")
+		return err
+	}
+	if err := r.Play(ctx, u.httpClient()); err != nil {
+		u.Logger.Error().Err(err).Msg("upnp: Failed to start playback")
+		return err
+	}
+
+	u.mu.Lock()
+	u.current = pathOrUrl
+	u.started = false
+	u.mu.Unlock()
+
+	return nil
+}
+
+// GetStatus returns the playback state of the renderer.
+// ErrNotPlaying is returned once the cast item has stopped.
+func (u *Upnp) GetStatus() (*Status, error) {
+	r, err := u.getRenderer()
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	current := u.current
+	u.mu.Unlock()
+	if current == "" {
+		return nil, ErrNotPlaying
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	state, err := r.GetTransportState(ctx, u.httpClient())
+	if err != nil {
+		return nil, err
+	}
+
+	u.mu.Lock()
+	switch state {
+	case TransportStatePlaying, TransportStatePaused:
+		u.started = true
+	case TransportStateStopped, TransportStateNoMedia:
+		// The renderer reports STOPPED before it starts buffering
+		if u.started {
+			u.mu.Unlock()
+			return nil, ErrNotPlaying
+		}
+	}
+	u.mu.Unlock()
+
+	pos, err := r.GetPositionInfo(ctx, u.httpClient())
+	if err != nil {
+		return nil, err
+	}
+
+	return &Status{
+		Filepath: current,
+		Filename: mediaTitle(current),
+		Position: pos.Position,
+		Duration: pos.Duration,
+		Paused:   state != TransportStatePlaying,
+	}, nil
+}
+
+func (u *Upnp) Pause() error {
+	r, err := u.getRenderer()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.Pause(ctx, u.httpClient())
+}
+
+func (u *Upnp) Resume() error {
+	r, err := u.getRenderer()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.Play(ctx, u.httpClient())
+}
+
+// SeekTo seeks to the position in seconds.
+func (u *Upnp) SeekTo(seconds float64) error {
+	r, err := u.getRenderer()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return r.Seek(ctx, u.httpClient(), seconds)
+}
+
+// Stop stops playback on the renderer and stops serving the local file.
+// It does nothing if nothing was cast.
+func (u *Upnp) Stop() error {
+	u.mu.Lock()
+	current := u.current
+	u.current = ""
+	u.started = false
+	u.serving = ""
+	u.token = ""
+	server := u.server
+	u.server = nil
+	u.serverUrl = ""
+	u.mu.Unlock()
+
+	if server != nil {
+		defer server.Close()
+	}
+
+	if current == "" {
+		return nil
+	}
+
+	r, err := u.getRenderer()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	// 701 (Transition not available) is returned when the renderer is already stopped
+	if err := r.Stop(ctx, u.httpClient()); err != nil && !isFault(err, 701) {
+		return err
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// mediaUrl returns the URL the renderer should load for the path or URL.
+func (u *Upnp) mediaUrl(pathOrUrl string, r *Renderer) (string, error) {
+	localIP, err := localIPFor(r.Location)
+	if err != nil {
+		return "", fmt.Errorf("upnp: Failed to find local address: %w", err)
+	}
+
+	if parsed, err := url.Parse(pathOrUrl); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		if !isLoopbackHost(parsed.Hostname()) {
+			return parsed.String(), nil
+		}
+
+		// The stream is served by this server
+		port := parsed.Port()
+		if port == "" {
+			port = "80"
+			if parsed.Scheme == "https" {
+				port = "443"
+			}
+		}
+		parsed.Host = net.JoinHostPort(localIP.String(), port)
+		if !isReachable(parsed.Host) {
+			return "", ErrStreamUnreachable
+		}
+
+		ret := parsed.String()
+		if u.HMACTokenFunc != nil && parsed.Query().Get("token") == "" {
+			symbol := "?"
+			if parsed.RawQuery != "" {
+				symbol = "&"
+			}
+			ret += u.HMACTokenFunc(parsed.Path, symbol)
+		}
+		return ret, nil
+	}
+
+	info, err := os.Stat(pathOrUrl)
+	if err != nil {
+		return "", err
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("upnp: %s is a directory", pathOrUrl)
+	}
+
+	return u.serveFile(pathOrUrl, localIP)
+}
+
+// serveFile makes the file available to the renderer and returns its URL.
+func (u *Upnp) serveFile(path string, localIP net.IP) (string, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	u.serving = path
+	u.token = token
+
+	if u.server == nil {
+		ln, err := net.Listen("tcp", ":0")
+		if err != nil {
+			return "", fmt.Errorf("upnp: Failed to start file server: %w", err)
+		}
+		u.server = &http.Server{Handler: http.HandlerFunc(u.handleFile), ReadHeaderTimeout: 10 * time.Second}
+		go func(server *http.Server) {
+			if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				u.Logger.Error().Err(err).Msg("upnp: File server stopped")
+			}
+		}(u.server)
+		u.serverUrl = "http://" + net.JoinHostPort(localIP.String(), fmt.Sprint(ln.Addr().(*net.TCPAddr).Port))
+	}
+
+	return u.serverUrl + "/" + token + "/" + url.PathEscape(filepath.Base(path)), nil
+}
+
+// handleFile serves the file that is being cast.
+// Only the current file can be requested, using the random token of its URL.
+func (u *Upnp) handleFile(w http.ResponseWriter, r *http.Request) {
+	u.mu.Lock()
+	serving, token := u.serving, u.token
+	u.mu.Unlock()
+
+	reqToken, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if serving == "" || token == "" || reqToken != token {
+		http.Error(w, errFileNotServing.Error(), http.StatusNotFound)
+		return
+	}
+
+	f, err := os.Open(serving)
+	if err != nil {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		http.Error(w, "file not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", mimeType(serving))
+	w.Header().Set("transferMode.dlna.org", "Streaming")
+	w.Header().Set("contentFeatures.dlna.org", "DLNA.ORG_OP=01;DLNA.ORG_CI=0;DLNA.ORG_FLAGS=01700000000000000000000000000000")
+	http.ServeContent(w, r, "", info.ModTime(), f)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// localIPFor returns the local IP address used to reach the host of the URL.
+func localIPFor(rawUrl string) (net.IP, error) {
+	parsed, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+	port := parsed.Port()
+	if port == "" {
+		port = "80"
+	}
+	conn, err := net.Dial("udp", net.JoinHostPort(parsed.Hostname(), port))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).IP, nil
+}
+
+// isReachable returns true if something listens on the address.
+// A server bound to the loopback address is not reachable through the address of the network interface.
+func isReachable(address string) bool {
+	conn, err := net.DialTimeout("tcp", address, 2*time.Second)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && (ip.IsLoopback() || ip.IsUnspecified())
+}
+
+// mediaTitle returns the file name of a path or URL.
+func mediaTitle(pathOrUrl string) string {
+	if parsed, err := url.Parse(pathOrUrl); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		if name, err := url.PathUnescape(path.Base(parsed.Path)); err == nil && name != "/" && name != "." {
+			return name
+		}
+		return pathOrUrl
+	}
+	return filepath.Base(pathOrUrl)
+}
+
+func mimeType(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".mkv":
+		return "video/x-matroska"
+	case ".mp4", ".m4v":
+		return "video/mp4"
+	case ".avi":
+		return "video/x-msvideo"
+	case ".webm":
+		return "video/webm"
+	case ".ts":
+		return "video/mp2t"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(name)); strings.HasPrefix(t, "video/") {
+		return t
+	}
+	// Anime releases are mostly Matroska
+	return "video/x-matroska"
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
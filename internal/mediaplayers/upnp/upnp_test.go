@@ -0,0 +1,304 @@
+package upnp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/util"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRenderer is a minimal MediaRenderer exposing the AVTransport service under an embedded device.
+type fakeRenderer struct {
+	mu       sync.Mutex
+	uri      string
+	metadata string
+	state    string
+	position float64
+	actions  []string
+}
+
+const fakeRendererDescription = `<?xml version="1.0"?>
+<root xmlns="urn:schemas-upnp-org:device-1-0">
+  <specVersion><major>1</major><minor>0</minor></specVersion>
+  <device>
+    <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+    <friendlyName>Living Room TV</friendlyName>
+    <UDN>uuid:fake-renderer</UDN>
+    <deviceList>
+      <device>
+        <deviceType>urn:schemas-upnp-org:device:MediaRenderer:1</deviceType>
+        <friendlyName>Embedded</friendlyName>
+        <serviceList>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:RenderingControl:1</serviceType>
+            <controlURL>/rc</controlURL>
+          </service>
+          <service>
+            <serviceType>urn:schemas-upnp-org:service:AVTransport:1</serviceType>
+            <controlURL>control/avt</controlURL>
+          </service>
+        </serviceList>
+      </device>
+    </deviceList>
+  </device>
+</root>`
+
+func (f *fakeRenderer) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/device/description.xml", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, fakeRendererDescription)
+	})
+	mux.HandleFunc("/device/control/avt", func(w http.ResponseWriter, r *http.Request) {
+		action := strings.TrimSuffix(strings.SplitN(r.Header.Get("SOAPAction"), "#", 2)[1], `"`)
+
+		var env struct {
+			Body struct {
+				Action struct {
+					CurrentURI         string `xml:"CurrentURI"`
+					CurrentURIMetaData string `xml:"CurrentURIMetaData"`
+					Target             string `xml:"Target"`
+				} `xml:",any"`
+			} `xml:"Body"`
+		}
+		require.NoError(t, xml.NewDecoder(r.Body).Decode(&env))
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.actions = append(f.actions, action)
+
+		var out string
+		switch action {
+		case "SetAVTransportURI":
+			f.uri = env.Body.Action.CurrentURI
+			f.metadata = env.Body.Action.CurrentURIMetaData
+			f.state = TransportStateStopped
+			f.position = 0
+		case "Play":
+			if f.uri == "" {
+				writeFault(w, 701, "Transition not available")
+				return
+			}
+			f.state = TransportStatePlaying
+		case "Pause":
+			f.state = TransportStatePaused
+		case "Stop":
+			if f.state == TransportStateStopped || f.state == "" {
+				writeFault(w, 701, "Transition not available")
+				return
+			}
+			f.state = TransportStateStopped
+		case "Seek":
+			f.position = parseTime(env.Body.Action.Target)
+		case "GetTransportInfo":
+			state := f.state
+			if state == "" {
+				state = TransportStateNoMedia
+			}
+			out = "<CurrentTransportState>" + state + "</CurrentTransportState><CurrentTransportStatus>OK</CurrentTransportStatus><CurrentSpeed>1</CurrentSpeed>"
+		case "GetPositionInfo":
+			out = fmt.Sprintf("<Track>1</Track><TrackDuration>0:23:40.500</TrackDuration><TrackURI>%s</TrackURI><RelTime>%s</RelTime>", f.uri, formatTime(f.position))
+		default:
+			writeFault(w, 401, "Invalid Action")
+			return
+		}
+
+		_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><u:%sResponse xmlns:u="%s">%s</u:%sResponse></s:Body></s:Envelope>`,
+			action, avTransportType, out, action)
+	})
+	return mux
+}
+
+func writeFault(w http.ResponseWriter, code int, description string) {
+	w.WriteHeader(http.StatusInternalServerError)
+	_, _ = fmt.Fprintf(w, `<?xml version="1.0"?><s:Envelope xmlns:s="http://schemas.xmlsoap.org/soap/envelope/"><s:Body><s:Fault><faultcode>s:Client</faultcode><faultstring>UPnPError</faultstring><detail><UPnPError xmlns="urn:schemas-upnp-org:control-1-0"><errorCode>%d</errorCode><errorDescription>%s</errorDescription></UPnPError></detail></s:Fault></s:Body></s:Envelope>`,
+		code, description)
+}
+
+func newTestUpnp(t *testing.T, f *fakeRenderer) *Upnp {
+	server := httptest.NewServer(f.handler(t))
+	t.Cleanup(server.Close)
+
+	u := &Upnp{
+		RendererLocation: server.URL + "/device/description.xml",
+		Logger:           util.NewLogger(),
+	}
+	t.Cleanup(func() { _ = u.Stop() })
+	return u
+}
+
+func TestLoadRenderer(t *testing.T) {
+	f := &fakeRenderer{}
+	u := newTestUpnp(t, f)
+
+	r, err := u.getRenderer()
+	require.NoError(t, err)
+	require.Equal(t, "Living Room TV", r.Name)
+	require.Equal(t, "uuid:fake-renderer", r.UDN)
+	// The control URL is resolved against the description location
+	require.True(t, strings.HasSuffix(r.avTransportUrl, "/device/control/avt"))
+}
+
+func TestUpnp_PlayLocalFile(t *testing.T) {
+	f := &fakeRenderer{}
+	u := newTestUpnp(t, f)
+
+	path := filepath.Join(t.TempDir(), "[SubsPlease] Frieren - 01 (1080p).mkv")
+	content := []byte(strings.Repeat("frieren", 1000))
+	require.NoError(t, os.WriteFile(path, content, 0644))
+
+	require.NoError(t, u.Start())
+
+	_, err := u.GetStatus()
+	require.ErrorIs(t, err, ErrNotPlaying)
+
+	require.NoError(t, u.OpenAndPlay(path))
+
+	f.mu.Lock()
+	uri, metadata := f.uri, f.metadata
+	f.mu.Unlock()
+	require.Contains(t, metadata, "object.item.videoItem")
+	require.Contains(t, metadata, "video/x-matroska")
+
+	// The renderer can download the file, with range support
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	require.NoError(t, err)
+	req.Header.Set("Range", "bytes=7-13")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusPartialContent, resp.StatusCode)
+	require.Equal(t, "frieren", string(body))
+
+	// Other paths are not served
+	resp, err = http.Get(uri[:strings.LastIndex(uri[:strings.LastIndex(uri, "/")], "/")] + "/invalid/file.mkv")
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	status, err := u.GetStatus()
+	require.NoError(t, err)
+	require.Equal(t, path, status.Filepath)
+	require.Equal(t, "[SubsPlease] Frieren - 01 (1080p).mkv", status.Filename)
+	require.False(t, status.Paused)
+	require.InDelta(t, 1420.5, status.Duration, 0.001)
+
+	require.NoError(t, u.Pause())
+	require.NoError(t, u.SeekTo(754))
+
+	status, err = u.GetStatus()
+	require.NoError(t, err)
+	require.True(t, status.Paused)
+	require.InDelta(t, 754, status.Position, 0.001)
+
+	require.NoError(t, u.Resume())
+	require.NoError(t, u.Stop())
+	// Stopping when nothing is playing is not an error
+	require.NoError(t, u.Stop())
+
+	_, err = u.GetStatus()
+	require.ErrorIs(t, err, ErrNotPlaying)
+
+	// The file is no longer served
+	_, err = http.Get(uri)
+	require.Error(t, err)
+}
+
+func TestUpnp_PlayStream(t *testing.T) {
+	f := &fakeRenderer{}
+	u := newTestUpnp(t, f)
+
+	streamUrl := "https://debrid.example.com/dl/abc/%5BSubsPlease%5D%20Frieren%20-%2002.mkv"
+	require.NoError(t, u.OpenAndPlay(streamUrl))
+
+	f.mu.Lock()
+	require.Equal(t, streamUrl, f.uri)
+	f.mu.Unlock()
+
+	status, err := u.GetStatus()
+	require.NoError(t, err)
+	require.Equal(t, streamUrl, status.Filepath)
+	require.Equal(t, "[SubsPlease] Frieren - 02.mkv", status.Filename)
+
+	// The renderer stopping on its own ends the playback
+	f.mu.Lock()
+	f.state = TransportStateStopped
+	f.mu.Unlock()
+	_, err = u.GetStatus()
+	require.ErrorIs(t, err, ErrNotPlaying)
+}
+
+func TestUpnp_RendererFault(t *testing.T) {
+	f := &fakeRenderer{}
+	u := newTestUpnp(t, f)
+
+	r, err := u.getRenderer()
+	require.NoError(t, err)
+
+	err = r.Play(t.Context(), u.httpClient())
+	require.Error(t, err)
+	require.True(t, isFault(err, 701))
+}
+
+func TestParseTime(t *testing.T) {
+	require.InDelta(t, 3725.5, parseTime("1:02:05.500"), 0.001)
+	require.InDelta(t, 65, parseTime("00:01:05"), 0.001)
+	require.InDelta(t, 65, parseTime("0:01:05.1/3"), 0.001)
+	require.Zero(t, parseTime("NOT_IMPLEMENTED"))
+	require.Equal(t, "1:02:05", formatTime(3725.5))
+}
+
+func TestParseSearchResponse(t *testing.T) {
+	location, ok := parseSearchResponse([]byte("HTTP/1.1 200 OK\r\n" +
+		"CACHE-CONTROL: max-age=1800\r\n" +
+		"LOCATION: http://192.168.1.20:49152/description.xml\r\n" +
+		"ST: urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+		"USN: uuid:tv::urn:schemas-upnp-org:device:MediaRenderer:1\r\n" +
+		"\r\n"))
+	require.True(t, ok)
+	require.Equal(t, "http://192.168.1.20:49152/description.xml", location)
+
+	_, ok = parseSearchResponse([]byte("HTTP/1.1 200 OK\r\n" +
+		"LOCATION: http://192.168.1.21/description.xml\r\n" +
+		"ST: urn:schemas-upnp-org:device:MediaServer:1\r\n" +
+		"\r\n"))
+	require.False(t, ok)
+}
+
+func TestMediaUrl_RewritesLoopback(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+	port := server.Listener.Addr().(*net.TCPAddr).Port
+
+	u := &Upnp{
+		Logger: util.NewLogger(),
+		HMACTokenFunc: func(endpoint string, symbol string) string {
+			return symbol + "token=signed" + endpoint
+		},
+	}
+	r := &Renderer{Location: "http://127.0.0.1:49152/description.xml"}
+
+	ret, err := u.mediaUrl(fmt.Sprintf("http://localhost:%d/api/v1/torrentstream/stream/video.mkv", port), r)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("http://127.0.0.1:%d/api/v1/torrentstream/stream/video.mkv?token=signed/api/v1/torrentstream/stream/video.mkv", port), ret)
+
+	// Signed URLs are kept as is
+	ret, err = u.mediaUrl(fmt.Sprintf("http://127.0.0.1:%d/api/v1/torrentstream/stream/video.mkv?token=abc", port), r)
+	require.NoError(t, err)
+	require.Equal(t, fmt.Sprintf("http://127.0.0.1:%d/api/v1/torrentstream/stream/video.mkv?token=abc", port), ret)
+
+	// Nothing listens on the address the renderer would use
+	server.Close()
+	_, err = u.mediaUrl(fmt.Sprintf("http://127.0.0.1:%d/api/v1/torrentstream/stream/video.mkv", port), r)
+	require.ErrorIs(t, err, ErrStreamUnreachable)
+}
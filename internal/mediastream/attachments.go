@@ -2,6 +2,7 @@ package mediastream
 
 import (
 	"errors"
+	"net/http"
 	"net/url"
 	"path/filepath"
 	"seanime/internal/events"
@@ -42,6 +43,27 @@ func (r *Repository) ServeEchoExtractedSubtitles(c echo.Context) error {
 	return c.File(filepath.Join(retPath, subFilePath))
 }
 
+// ServeEchoTrickplay serves the WebVTT index and sprite sheets of the current media.
+// The sprite sheets are referenced relative to the index.
+func (r *Repository) ServeEchoTrickplay(c echo.Context) error {
+	if !r.IsInitialized() {
+		return errors.New("module not initialized")
+	}
+
+	mediaContainer, found := r.playbackManager.currentMediaContainer.Get()
+	if !found {
+		return errors.New("no file has been loaded")
+	}
+
+	if !r.trickplayGenerator.IsGenerated(mediaContainer.Hash) {
+		return c.NoContent(http.StatusNotFound)
+	}
+
+	name := filepath.Base(filepath.Clean("/" + c.Param("*")))
+
+	return c.File(filepath.Join(r.trickplayGenerator.GetDir(mediaContainer.Hash), name))
+}
+
 func (r *Repository) ServeEchoExtractedAttachments(c echo.Context) error {
 	if !r.IsInitialized() {
 		r.wsEventManager.SendEvent(events.MediastreamShutdownStream, "Module not initialized")
@@ -24,6 +24,9 @@ type NewCassetteOptions struct {
 	HwAccelCustomSettings string
 	// MaxConcurrency limits simultaneous ffmpeg processes. 0 = NumCPU
 	MaxConcurrency int
+	// Governor is shared with other ffmpeg jobs so that they count against the same limit.
+	// A new governor is created if nil.
+	Governor *Governor
 }
 
 // Cassette is the top-level transcoding orchestrator.
@@ -62,9 +65,14 @@ func New(opts *NewCassetteOptions) (*Cassette, error) {
 		CustomSettings: opts.HwAccelCustomSettings,
 	}, opts.FfmpegPath, opts.Logger)
 
+	governor := opts.Governor
+	if governor == nil {
+		governor = NewGovernor(opts.MaxConcurrency, hwAccel.Name != "disabled", opts.Logger)
+	}
+
 	c := &Cassette{
 		clientChan: make(chan ClientInfo, 1024),
-		governor:   NewGovernor(opts.MaxConcurrency, hwAccel.Name != "disabled", opts.Logger),
+		governor:   governor,
 		logger:     opts.Logger,
 		settings: Settings{
 			StreamDir:   streamDir,
@@ -120,16 +128,21 @@ func (c *Cassette) Destroy() {
 
 // session management
 
+// getSession returns the session of a file transcoded with a profile.
+// Each profile has its own session, the tracker identifies it by its key.
 func (c *Cassette) getSession(
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 ) (*Session, error) {
+	key := profile.sessionKey(filePath)
+
 	// session already exists
-	if v, ok := c.sessions.Load(filePath); ok {
+	if v, ok := c.sessions.Load(key); ok {
 		s := v.(*Session)
 		s.WaitReady()
 		if s.err != nil {
-			c.sessions.Delete(filePath)
+			c.sessions.Delete(key)
 			return nil, s.err
 		}
 		return s, nil
@@ -137,30 +150,30 @@ func (c *Cassette) getSession(
 
 	// create session
 	c.sessionsMu.Lock()
-	if v, ok := c.sessions.Load(filePath); ok {
+	if v, ok := c.sessions.Load(key); ok {
 		c.sessionsMu.Unlock()
 		s := v.(*Session)
 		s.WaitReady()
 		if s.err != nil {
-			c.sessions.Delete(filePath)
+			c.sessions.Delete(key)
 			return nil, s.err
 		}
 		return s, nil
 	}
 
-	s := NewSession(filePath, hash, mediaInfo, &c.settings, c.governor, c.logger)
-	c.sessions.Store(filePath, s)
+	s := NewSession(filePath, hash, mediaInfo, profile, &c.settings, c.governor, c.logger)
+	c.sessions.Store(key, s)
 	c.sessionsMu.Unlock()
 
 	s.WaitReady()
 	if s.err != nil {
-		c.sessions.Delete(filePath)
+		c.sessions.Delete(key)
 		return nil, s.err
 	}
 	return s, nil
 }
 
-// getSessionByPath returns the session for a path
+// getSessionByPath returns the session for a session key
 func (c *Cassette) getSessionByPath(filePath string) *Session {
 	v, ok := c.sessions.Load(filePath)
 	if !ok {
@@ -193,16 +206,17 @@ func (c *Cassette) sendClientInfo(info ClientInfo) {
 func (c *Cassette) GetMaster(
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 	client string,
 	token string,
 ) (string, error) {
 	start := time.Now()
-	s, err := c.getSession(filePath, hash, mediaInfo)
+	s, err := c.getSession(filePath, hash, mediaInfo, profile)
 	if err != nil {
 		return "", err
 	}
 	c.sendClientInfo(ClientInfo{
-		Client: client, Path: filePath,
+		Client: client, Path: profile.sessionKey(filePath),
 		Quality: nil, Audio: -1, Head: -1,
 	})
 	c.logger.Trace().Dur("elapsed", time.Since(start)).Msg("cassette: GetMaster")
@@ -214,11 +228,12 @@ func (c *Cassette) GetMaster(
 func (c *Cassette) GetVideoIndex(
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 	quality Quality,
 	client string,
 	token string,
 ) (string, error) {
-	s, err := c.getSession(filePath, hash, mediaInfo)
+	s, err := c.getSession(filePath, hash, mediaInfo, profile)
 	if err != nil {
 		return "", err
 	}
@@ -229,11 +244,12 @@ func (c *Cassette) GetVideoIndex(
 func (c *Cassette) GetAudioIndex(
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 	audio int32,
 	client string,
 	token string,
 ) (string, error) {
-	s, err := c.getSession(filePath, hash, mediaInfo)
+	s, err := c.getSession(filePath, hash, mediaInfo, profile)
 	if err != nil {
 		return "", err
 	}
@@ -245,16 +261,17 @@ func (c *Cassette) GetVideoSegment(
 	ctx context.Context,
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 	quality Quality,
 	segment int32,
 	client string,
 ) (string, error) {
-	s, err := c.getSession(filePath, hash, mediaInfo)
+	s, err := c.getSession(filePath, hash, mediaInfo, profile)
 	if err != nil {
 		return "", err
 	}
 	c.sendClientInfo(ClientInfo{
-		Client: client, Path: filePath,
+		Client: client, Path: profile.sessionKey(filePath),
 		Quality: &quality, Audio: -1, Head: segment,
 	})
 	return s.GetVideoSegment(ctx, quality, segment)
@@ -265,15 +282,16 @@ func (c *Cassette) GetAudioSegment(
 	ctx context.Context,
 	filePath, hash string,
 	mediaInfo *videofile.MediaInfo,
+	profile TranscodeProfile,
 	audio, segment int32,
 	client string,
 ) (string, error) {
-	s, err := c.getSession(filePath, hash, mediaInfo)
+	s, err := c.getSession(filePath, hash, mediaInfo, profile)
 	if err != nil {
 		return "", err
 	}
 	c.sendClientInfo(ClientInfo{
-		Client: client, Path: filePath,
+		Client: client, Path: profile.sessionKey(filePath),
 		Quality: nil, Audio: audio, Head: segment,
 	})
 	return s.GetAudioSegment(ctx, audio, segment)
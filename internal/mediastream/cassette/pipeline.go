@@ -66,6 +66,11 @@ type Pipeline struct {
 	governor *Governor
 	logger   *zerolog.Logger
 
+	// decodeFlags are placed before -i, usually the hardware decoding flags
+	decodeFlags []string
+	// keepSubtitles keeps the subtitle streams of the input, used to overlay image subtitles
+	keepSubtitles bool
+
 	ctx    context.Context
 	cancel context.CancelFunc
 
@@ -87,12 +92,21 @@ type PipelineConfig struct {
 	Logger     *zerolog.Logger
 	BuildArgs  func(segmentTimes string) []string
 	OutPathFmt func(encoderID int) string
+	// DecodeFlags overrides the decoding flags of the hardware acceleration profile if not nil
+	DecodeFlags []string
+	// KeepSubtitles keeps the subtitle streams of the input
+	KeepSubtitles bool
 }
 
 // NewPipeline creates a pipeline and initializes its segment table
 func NewPipeline(cfg PipelineConfig) *Pipeline {
 	ctx, cancel := context.WithCancel(context.Background())
 
+	decodeFlags := cfg.DecodeFlags
+	if decodeFlags == nil {
+		decodeFlags = cfg.Settings.HwAccel.DecodeFlags
+	}
+
 	length, isDone := cfg.Session.Keyframes.Length()
 	segments := NewSegmentTable(length)
 
@@ -111,6 +125,9 @@ func NewPipeline(cfg PipelineConfig) *Pipeline {
 		cancel:     cancel,
 		buildArgs:  cfg.BuildArgs,
 		outPathFmt: cfg.OutPathFmt,
+
+		decodeFlags:   decodeFlags,
+		keepSubtitles: cfg.KeepSubtitles,
 	}
 
 	if !isDone {
@@ -420,7 +437,7 @@ func (p *Pipeline) runHead(start int32) error {
 	}
 
 	args := []string{"-nostats", "-hide_banner", "-loglevel", "warning"}
-	args = append(args, p.settings.HwAccel.DecodeFlags...)
+	args = append(args, p.decodeFlags...)
 
 	if startRef != 0 {
 		if p.kind == VideoKind {
@@ -439,8 +456,11 @@ func (p *Pipeline) runHead(start int32) error {
 		args = append(args, "-to", fmt.Sprintf("%.6f", endRef))
 	}
 
+	if !p.keepSubtitles {
+		args = append(args, "-sn")
+	}
 	args = append(args,
-		"-sn", "-dn",
+		"-dn",
 		"-i", p.session.Path,
 		"-map_metadata", "-1", // ?
 		"-map_chapters", "-1", // ?
@@ -571,7 +591,7 @@ func (p *Pipeline) reapProcess(encoderID int, cmd *exec.Cmd, stderr *strings.Bui
 	err := cmd.Wait()
 
 	// Check for hardware acceleration failures in stderr
-	if len(p.settings.HwAccel.DecodeFlags) > 0 && DetectHwAccelFailure(stderr.String()) {
+	if len(p.decodeFlags) > 0 && DetectHwAccelFailure(stderr.String()) {
 		p.logger.Warn().Int("eid", encoderID).
 			Str("hwaccel", FormatHwAccelSummary(p.settings.HwAccel)).
 			Msg("cassette: hardware acceleration failed, consider switching to CPU or a different backend")
@@ -9,13 +9,13 @@ import (
 // playlist generation
 
 // GenerateMasterPlaylist builds the hls master playlist using the quality ladder
-func GenerateMasterPlaylist(info *videofile.MediaInfo, ladder []QualityLadderEntry, token string) string {
+func GenerateMasterPlaylist(info *videofile.MediaInfo, ladder []QualityLadderEntry, profile TranscodeProfile, token string) string {
 	var b strings.Builder
 	b.WriteString("#EXTM3U\n")
 
 	if info.Video == nil {
 		// just list audio tracks if audio only
-		writeAudioTracks(&b, info, token)
+		writeAudioTracks(&b, info, profile, token)
 		return b.String()
 	}
 
@@ -55,12 +55,12 @@ func GenerateMasterPlaylist(info *videofile.MediaInfo, ladder []QualityLadderEnt
 		fmt.Fprintf(&b, "./%s/index.m3u8%s\n", entry.Quality, tokenSuffix)
 	}
 
-	writeAudioTracks(&b, info, token)
+	writeAudioTracks(&b, info, profile, token)
 	return b.String()
 }
 
 // writeAudioTracks appends audio track entries to the playlist
-func writeAudioTracks(b *strings.Builder, info *videofile.MediaInfo, token string) {
+func writeAudioTracks(b *strings.Builder, info *videofile.MediaInfo, profile TranscodeProfile, token string) {
 	tokenSuffix := ""
 	if token != "" {
 		tokenSuffix = "?token=" + token
@@ -84,7 +84,7 @@ func writeAudioTracks(b *strings.Builder, info *videofile.MediaInfo, token strin
 		}
 
 		ch := audio.Channels
-		if ch == 0 {
+		if ch == 0 || profile.DownmixAudio {
 			ch = 2
 		}
 		fmt.Fprintf(b, "CHANNELS=\"%d\",", ch)
@@ -0,0 +1,121 @@
+package cassette
+
+import (
+	"fmt"
+	"path/filepath"
+	"seanime/internal/mediastream/videofile"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// TranscodeProfile adapts the stream to clients that cannot render some tracks themselves,
+// e.g. TVs without an ASS renderer or browsers that cannot play surround audio.
+// The zero value is the default profile.
+type TranscodeProfile struct {
+	// BurnSubtitleIndex is the relative index of the subtitle track rendered into the video.
+	// Text tracks (ASS, SRT) are rendered with libass and the fonts attached to the file,
+	// other tracks (PGS, VobSub) are overlaid as images.
+	BurnSubtitleIndex *int32 `json:"burnSubtitleIndex,omitempty"`
+	// DownmixAudio encodes every audio track to stereo AAC with loudness normalization
+	DownmixAudio bool `json:"downmixAudio"`
+}
+
+// audioDownmixFilter normalizes the loudness to the EBU R128 broadcast target.
+// loudnorm resamples to 192kHz so the output rate is set back to 48kHz.
+const audioDownmixFilter = "loudnorm=I=-16:TP=-1.5:LRA=11,aresample=48000"
+
+func (p TranscodeProfile) IsDefault() bool {
+	return p.BurnSubtitleIndex == nil && !p.DownmixAudio
+}
+
+// Key identifies the profile in stream URLs and output directories, it is empty for the default profile.
+func (p TranscodeProfile) Key() string {
+	var parts []string
+	if p.BurnSubtitleIndex != nil {
+		parts = append(parts, fmt.Sprintf("s%d", *p.BurnSubtitleIndex))
+	}
+	if p.DownmixAudio {
+		parts = append(parts, "dm")
+	}
+	return strings.Join(parts, "-")
+}
+
+// ParseTranscodeProfile returns the profile identified by a key returned by TranscodeProfile.Key.
+func ParseTranscodeProfile(key string) (TranscodeProfile, error) {
+	var ret TranscodeProfile
+	if key == "" {
+		return ret, nil
+	}
+	for _, part := range strings.Split(key, "-") {
+		switch {
+		case part == "dm":
+			ret.DownmixAudio = true
+		case strings.HasPrefix(part, "s"):
+			idx, err := strconv.ParseInt(part[1:], 10, 32)
+			if err != nil || idx < 0 {
+				return TranscodeProfile{}, fmt.Errorf("cassette: invalid transcode profile %q", key)
+			}
+			i := int32(idx)
+			ret.BurnSubtitleIndex = &i
+		default:
+			return TranscodeProfile{}, fmt.Errorf("cassette: invalid transcode profile %q", key)
+		}
+	}
+	return ret, nil
+}
+
+// sessionKey identifies the session of a file transcoded with the profile.
+func (p TranscodeProfile) sessionKey(filePath string) string {
+	if p.IsDefault() {
+		return filePath
+	}
+	return filePath + "#" + p.Key()
+}
+
+// outDir returns the directory of the segments of a file transcoded with the profile.
+func (p TranscodeProfile) outDir(streamDir string, hash string) string {
+	if p.IsDefault() {
+		return filepath.Join(streamDir, hash)
+	}
+	return filepath.Join(streamDir, hash+"-"+p.Key())
+}
+
+// burnInFilter returns the filter rendering the subtitle track, placed before the scale filter.
+// The second value is true if the track is an image track, which requires a filter graph with the subtitle stream as input.
+func (p TranscodeProfile) burnInFilter(path string, info *videofile.MediaInfo) (string, bool) {
+	if p.BurnSubtitleIndex == nil {
+		return "", false
+	}
+	idx := *p.BurnSubtitleIndex
+
+	// Only text tracks are listed in the media info
+	isText := slices.ContainsFunc(info.Subtitles, func(s videofile.Subtitle) bool {
+		return int32(s.Index) == idx && !s.IsExternal
+	})
+	if isText {
+		return fmt.Sprintf("subtitles=filename=%s:si=%d", EscapeFilterValue(path), idx), false
+	}
+	// eof_action lets the video through once the subtitle stream ends
+	return fmt.Sprintf("[0:V:0][0:s:%d]overlay=eof_action=pass", idx), true
+}
+
+// EscapeFilterValue escapes a filter option value, then the filter graph.
+func EscapeFilterValue(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `:`, `\:`).Replace(value)
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`, `[`, `\[`, `]`, `\]`, `,`, `\,`, `;`, `\;`).Replace(value)
+}
+
+// softwareFrameDecodeFlags keeps hardware decoding but downloads the frames to system memory
+// so that software filters can be applied before the hardware upload of the scale filter.
+func softwareFrameDecodeFlags(flags []string) []string {
+	ret := make([]string, 0, len(flags))
+	for i := 0; i < len(flags); i++ {
+		if flags[i] == "-hwaccel_output_format" {
+			i++
+			continue
+		}
+		ret = append(ret, flags[i])
+	}
+	return ret
+}
@@ -0,0 +1,79 @@
+package cassette
+
+import (
+	"seanime/internal/mediastream/videofile"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranscodeProfileKey(t *testing.T) {
+	require.Empty(t, TranscodeProfile{}.Key())
+	require.True(t, TranscodeProfile{}.IsDefault())
+
+	idx := int32(2)
+	profile := TranscodeProfile{BurnSubtitleIndex: &idx, DownmixAudio: true}
+	require.Equal(t, "s2-dm", profile.Key())
+
+	parsed, err := ParseTranscodeProfile(profile.Key())
+	require.NoError(t, err)
+	require.Equal(t, profile, parsed)
+
+	parsed, err = ParseTranscodeProfile("dm")
+	require.NoError(t, err)
+	require.Nil(t, parsed.BurnSubtitleIndex)
+	require.True(t, parsed.DownmixAudio)
+
+	for _, key := range []string{"s", "s-1", "x", "dm-"} {
+		_, err = ParseTranscodeProfile(key)
+		require.Error(t, err, key)
+	}
+
+	require.Equal(t, "/streams/abc", TranscodeProfile{}.outDir("/streams", "abc"))
+	require.Equal(t, "/streams/abc-s2-dm", profile.outDir("/streams", "abc"))
+	require.NotEqual(t, TranscodeProfile{}.sessionKey("/a.mkv"), profile.sessionKey("/a.mkv"))
+}
+
+func TestTranscodeProfileBurnInFilter(t *testing.T) {
+	info := &videofile.MediaInfo{
+		Subtitles: []videofile.Subtitle{
+			{Index: 0, Codec: "ass"},
+			// Index 1 is a PGS track, it is not listed
+			{Index: 2, Codec: "subrip"},
+		},
+	}
+
+	filter, image := TranscodeProfile{}.burnInFilter("/anime/a.mkv", info)
+	require.Empty(t, filter)
+	require.False(t, image)
+
+	idx := int32(2)
+	filter, image = TranscodeProfile{BurnSubtitleIndex: &idx}.burnInFilter("/anime/Frieren: 01.mkv", info)
+	require.Equal(t, `subtitles=filename=/anime/Frieren\\: 01.mkv:si=2`, filter)
+	require.False(t, image)
+
+	idx = 1
+	filter, image = TranscodeProfile{BurnSubtitleIndex: &idx}.burnInFilter("/anime/a.mkv", info)
+	require.Equal(t, "[0:V:0][0:s:1]overlay=eof_action=pass", filter)
+	require.True(t, image)
+}
+
+func TestSoftwareFrameDecodeFlags(t *testing.T) {
+	flags := nvidiaProfile("fast").DecodeFlags
+	require.Equal(t, []string{"-hwaccel", "cuda"}, softwareFrameDecodeFlags(flags))
+	require.Equal(t, []string{"-hwaccel", "cuda", "-hwaccel_output_format", "cuda"}, flags)
+	require.Empty(t, softwareFrameDecodeFlags(cpuProfile("fast").DecodeFlags))
+}
+
+func TestGenerateMasterPlaylist_Downmix(t *testing.T) {
+	info := &videofile.MediaInfo{
+		Audios: []videofile.Audio{{Index: 0, Codec: "flac", Channels: 6}},
+	}
+
+	playlist := GenerateMasterPlaylist(info, nil, TranscodeProfile{}, "")
+	require.True(t, strings.Contains(playlist, `CHANNELS="6"`))
+
+	playlist = GenerateMasterPlaylist(info, nil, TranscodeProfile{DownmixAudio: true}, "")
+	require.True(t, strings.Contains(playlist, `CHANNELS="2"`))
+}
@@ -224,6 +224,8 @@ type AudioTranscodeDecision struct {
 	Bitrate string
 	// Channels is the output channel count as a string.
 	Channels string
+	// Filter is the audio filter applied before encoding, if any.
+	Filter string
 }
 
 // DecideAudioTranscode
@@ -23,6 +23,7 @@ type Session struct {
 	Keyframes *KeyframeIndex
 	Info      *videofile.MediaInfo
 	Ladder    []QualityLadderEntry
+	Profile   TranscodeProfile
 
 	// videos and audios are created lazily
 	videosMu sync.Mutex
@@ -40,22 +41,30 @@ type Session struct {
 func NewSession(
 	path, hash string,
 	info *videofile.MediaInfo,
+	profile TranscodeProfile,
 	settings *Settings,
 	governor *Governor,
 	logger *zerolog.Logger,
 ) *Session {
 	s := &Session{
 		Path:     path,
-		Out:      filepath.Join(settings.StreamDir, hash),
+		Out:      profile.outDir(settings.StreamDir, hash),
 		videos:   make(map[Quality]*Pipeline),
 		audios:   make(map[int32]*Pipeline),
 		Info:     info,
 		Ladder:   BuildQualityLadder(info),
+		Profile:  profile,
 		settings: settings,
 		governor: governor,
 		logger:   logger,
 	}
 
+	// Burning subtitles requires encoding the original quality
+	if profile.BurnSubtitleIndex != nil && len(s.Ladder) > 0 {
+		s.Ladder[0].NeedsTranscode = true
+		s.Ladder[0].OriginalCanTransmux = false
+	}
+
 	s.ready.Add(1)
 	go func() {
 		defer s.ready.Done()
@@ -82,7 +91,7 @@ func (s *Session) WaitReady() error {
 
 // GetMaster returns the hls master playlist
 func (s *Session) GetMaster(token string) string {
-	return GenerateMasterPlaylist(s.Info, s.Ladder, token)
+	return GenerateMasterPlaylist(s.Info, s.Ladder, s.Profile, token)
 }
 
 // GetVideoIndex returns the hls variant playlist for a quality
@@ -150,15 +159,27 @@ func (s *Session) getVideoPipeline(q Quality) *Pipeline {
 		}
 	}
 
-	buildArgs := func(segmentTimes string) []string {
-		args := []string{"-map", "0:V:0"}
+	// The subtitle track is rendered before scaling
+	burnIn, burnInImage := s.Profile.burnInFilter(s.Path, s.Info)
+	videoFilterArgs := func(filter string) []string {
+		switch {
+		case burnIn == "":
+			return []string{"-map", "0:V:0", "-vf", filter}
+		case burnInImage:
+			return []string{"-filter_complex", burnIn + "," + filter + "[v]", "-map", "[v]"}
+		default:
+			return []string{"-map", "0:V:0", "-vf", burnIn + "," + filter}
+		}
+	}
 
+	buildArgs := func(segmentTimes string) []string {
 		if canTransmux {
 			// no encode, just copy.
-			args = append(args, "-c:v", "copy")
-			return args
+			return []string{"-map", "0:V:0", "-c:v", "copy"}
 		}
 
+		var args []string
+
 		if q == Original {
 			// Needs transcode even for original quality (e.g. HEVC).
 			args = append(args, s.settings.HwAccel.EncodeFlags...)
@@ -170,8 +191,8 @@ func (s *Session) getVideoPipeline(q Quality) *Pipeline {
 			}
 
 			width := closestEven(int32(s.Info.Video.Width))
+			args = append(args, videoFilterArgs(BuildVideoFilter(&s.settings.HwAccel, s.Info.Video, width, int32(s.Info.Video.Height)))...)
 			args = append(args,
-				"-vf", BuildVideoFilter(&s.settings.HwAccel, s.Info.Video, width, int32(s.Info.Video.Height)),
 				"-bufsize", fmt.Sprint(maxBitrate*5),
 				"-b:v", fmt.Sprint(avgBitrate),
 				"-maxrate", fmt.Sprint(maxBitrate),
@@ -193,9 +214,8 @@ func (s *Session) getVideoPipeline(q Quality) *Pipeline {
 		width := closestEven(int32(
 			float64(q.Height()) / float64(s.Info.Video.Height) * float64(s.Info.Video.Width),
 		))
+		args = append(args, videoFilterArgs(BuildVideoFilter(&s.settings.HwAccel, s.Info.Video, width, int32(q.Height())))...)
 		args = append(args,
-			"-vf", BuildVideoFilter(&s.settings.HwAccel, s.Info.Video, width, int32(q.Height())),
-			// "-vf", fmt.Sprintf(s.settings.HwAccel.ScaleFilter, width, q.Height()),
 			"-bufsize", fmt.Sprint(q.MaxBitrate()*5),
 			"-b:v", fmt.Sprint(q.AverageBitrate()),
 			"-maxrate", fmt.Sprint(q.MaxBitrate()),
@@ -219,15 +239,22 @@ func (s *Session) getVideoPipeline(q Quality) *Pipeline {
 		label = "video (original/transmux)"
 	}
 
+	decodeFlags := s.settings.HwAccel.DecodeFlags
+	if burnIn != "" {
+		decodeFlags = softwareFrameDecodeFlags(decodeFlags)
+	}
+
 	p := NewPipeline(PipelineConfig{
-		Kind:       VideoKind,
-		Label:      label,
-		Session:    s,
-		Settings:   s.settings,
-		Governor:   s.governor,
-		Logger:     s.logger,
-		BuildArgs:  buildArgs,
-		OutPathFmt: outFmt,
+		Kind:          VideoKind,
+		Label:         label,
+		Session:       s,
+		Settings:      s.settings,
+		Governor:      s.governor,
+		Logger:        s.logger,
+		BuildArgs:     buildArgs,
+		OutPathFmt:    outFmt,
+		DecodeFlags:   decodeFlags,
+		KeepSubtitles: burnInImage,
 	})
 	s.videos[q] = p
 	return p
@@ -264,6 +291,14 @@ func (s *Session) getAudioPipeline(idx int32) *Pipeline {
 	if srcAudio != nil {
 		decision = DecideAudioTranscode(srcAudio)
 	}
+	if s.Profile.DownmixAudio {
+		decision = AudioTranscodeDecision{
+			Codec:    "aac",
+			Bitrate:  "128k",
+			Channels: "2",
+			Filter:   audioDownmixFilter,
+		}
+	}
 
 	if decision.Copy {
 		s.logger.Debug().Int32("audio", idx).Str("codec", "copy").
@@ -273,6 +308,7 @@ func (s *Session) getAudioPipeline(idx int32) *Pipeline {
 			Str("codec", decision.Codec).
 			Str("bitrate", decision.Bitrate).
 			Str("channels", decision.Channels).
+			Str("filter", decision.Filter).
 			Msg("cassette: audio needs re-encode")
 	}
 
@@ -286,6 +322,9 @@ func (s *Session) getAudioPipeline(idx int32) *Pipeline {
 			if decision.Bitrate != "" {
 				args = append(args, "-b:a", decision.Bitrate)
 			}
+			if decision.Filter != "" {
+				args = append(args, "-af", decision.Filter)
+			}
 		}
 		return args
 	}
@@ -3,6 +3,7 @@ package mediastream
 import (
 	"errors"
 	"fmt"
+	"seanime/internal/mediastream/trickplay"
 	"seanime/internal/mediastream/videofile"
 	"seanime/internal/util/result"
 
@@ -36,6 +37,9 @@ type (
 		StreamType StreamType           `json:"streamType"` // Tells the frontend how to play the media.
 		StreamUrl  string               `json:"streamUrl"`  // The relative endpoint to stream the media.
 		MediaInfo  *videofile.MediaInfo `json:"mediaInfo"`
+		// TrickplayUrl is the WebVTT index of the seek bar previews, if enabled.
+		// It returns 404 until the thumbnails are generated, see events.MediastreamTrickplayGenerated.
+		TrickplayUrl *string `json:"trickplayUrl,omitempty"`
 		//Metadata  *Metadata       `json:"metadata"`
 		// todo: add more fields (e.g. metadata)
 	}
@@ -73,6 +77,11 @@ func (p *PlaybackManager) RequestPlayback(filepath string, streamType StreamType
 	// Set the current media container.
 	p.currentMediaContainer = mo.Some(ret)
 
+	// Generate the seek bar previews of the file first
+	if ret.TrickplayUrl != nil && !p.repository.trickplayGenerator.IsGenerated(ret.Hash) {
+		p.repository.trickplayGenerator.Request(filepath)
+	}
+
 	p.logger.Info().Str("filepath", filepath).Msg("mediastream: Ready to play media")
 
 	return
@@ -169,6 +178,11 @@ func (p *PlaybackManager) newMediaContainer(filepath string, streamType StreamTy
 	// Set the stream URL.
 	ret.StreamUrl = streamUrl
 
+	if p.repository.trickplayGenerator.GetSettings().Enabled {
+		trickplayUrl := "/api/v1/mediastream/trickplay/" + trickplay.IndexFilename
+		ret.TrickplayUrl = &trickplayUrl
+	}
+
 	// Store the media container in the map.
 	p.mediaContainers.Set(hash, ret)
 
@@ -0,0 +1,638 @@
+package pretranscode
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/goccy/go-json"
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrDisabled    = errors.New("pre-transcode: Pre-transcoding is disabled")
+	ErrJobNotFound = errors.New("pre-transcode: Job not found")
+	ErrNotFinished = errors.New("pre-transcode: Job has not finished")
+)
+
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusCompleted Status = "completed"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+const (
+	jobsFilename = "jobs.json"
+	// progressInterval is the minimum interval between two progress events of a job
+	progressInterval = time.Second
+)
+
+type (
+	// Job transcodes a single file into an offline copy
+	Job struct {
+		ID            string `json:"id"`
+		Path          string `json:"path"`
+		Filename      string `json:"filename"`
+		MediaId       int    `json:"mediaId"`
+		EpisodeNumber int    `json:"episodeNumber"`
+		Profile       string `json:"profile"`
+		// Subtitles is the subtitle mode of the output
+		Subtitles     SubtitleMode `json:"subtitles"`
+		AudioTrack    int          `json:"audioTrack"`
+		SubtitleTrack int          `json:"subtitleTrack"`
+		Status        Status       `json:"status"`
+		// Progress is between 0 and 1
+		Progress float64 `json:"progress"`
+		// Size of the output in bytes, once completed
+		Size      int64     `json:"size"`
+		Error     string    `json:"error,omitempty"`
+		CreatedAt time.Time `json:"createdAt"`
+		UpdatedAt time.Time `json:"updatedAt"`
+	}
+
+	// AddOptions describes a file to transcode
+	AddOptions struct {
+		Path          string
+		MediaId       int
+		EpisodeNumber int
+		Profile       string
+		// Subtitles overrides the subtitle mode of the profile if set
+		Subtitles SubtitleMode
+		// AudioTrack and SubtitleTrack are the relative indexes of the tracks to keep, -1 selects the default track
+		AudioTrack    int
+		SubtitleTrack int
+	}
+
+	Settings struct {
+		Enabled bool
+		// OutputDir is where the offline copies are written
+		OutputDir   string
+		FfmpegPath  string
+		FfprobePath string
+		Preset      string
+	}
+
+	// Queue runs pre-transcode jobs in the background, one file at a time.
+	// A job holds a slot of the governor shared with the live transcoder, the other slots are left to playback sessions.
+	// Jobs are persisted in the output directory so that they survive restarts.
+	Queue struct {
+		logger         *zerolog.Logger
+		wsEventManager events.WSEventManagerInterface
+		getMediaInfo   func(ffprobePath, path string) (*videofile.MediaInfo, error)
+
+		mu       sync.Mutex
+		settings Settings
+		governor *cassette.Governor
+		jobs     []*Job
+		cancels  map[string]context.CancelFunc
+		wakeCh   chan struct{}
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+
+	NewQueueOptions struct {
+		Logger         *zerolog.Logger
+		WSEventManager events.WSEventManagerInterface
+		// GetMediaInfo returns the tracks of a file, usually videofile.MediaInfoExtractor.GetInfo
+		GetMediaInfo func(ffprobePath, path string) (*videofile.MediaInfo, error)
+	}
+)
+
+func NewQueue(opts *NewQueueOptions) *Queue {
+	ctx, cancel := context.WithCancel(context.Background())
+	q := &Queue{
+		logger:         opts.Logger,
+		wsEventManager: opts.WSEventManager,
+		getMediaInfo:   opts.GetMediaInfo,
+		cancels:        make(map[string]context.CancelFunc),
+		wakeCh:         make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	q.wg.Add(1)
+	go q.run()
+	return q
+}
+
+// SetSettings updates the settings and the governor shared with the live transcoder.
+// The jobs of the new output directory are loaded if it changed.
+// Running jobs keep the settings they were started with.
+func (q *Queue) SetSettings(settings Settings, governor *cassette.Governor) {
+	q.mu.Lock()
+	prevDir := q.settings.OutputDir
+	q.settings = settings
+	q.governor = governor
+	if settings.OutputDir != prevDir {
+		for _, cancel := range q.cancels {
+			cancel()
+		}
+		q.jobs = q.loadJobs()
+	}
+	q.mu.Unlock()
+
+	q.wake()
+}
+
+// Shutdown cancels the running jobs and waits for ffmpeg to exit.
+// The jobs are resumed on the next start.
+func (q *Queue) Shutdown() {
+	q.cancel()
+	q.wg.Wait()
+}
+
+// Add queues a job.
+func (q *Queue) Add(opts *AddOptions) (*Job, error) {
+	profile, ok := GetProfile(opts.Profile)
+	if !ok {
+		return nil, fmt.Errorf("pre-transcode: Unknown profile %q", opts.Profile)
+	}
+
+	subtitles := opts.Subtitles
+	switch subtitles {
+	case "":
+		subtitles = profile.Subtitles
+	case SubtitleModeNone, SubtitleModeBurn, SubtitleModeSoft:
+	default:
+		return nil, fmt.Errorf("pre-transcode: Unknown subtitle mode %q", opts.Subtitles)
+	}
+
+	info, err := os.Stat(opts.Path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("pre-transcode: %s is a directory", opts.Path)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.settings.Enabled || q.settings.OutputDir == "" {
+		return nil, ErrDisabled
+	}
+
+	// Return the existing job if the same output was already requested
+	for _, j := range q.jobs {
+		if util.NormalizePath(j.Path) == util.NormalizePath(opts.Path) && j.Profile == profile.Name && j.Subtitles == subtitles &&
+			j.AudioTrack == opts.AudioTrack && j.SubtitleTrack == opts.SubtitleTrack &&
+			j.Status != StatusFailed && j.Status != StatusCancelled {
+			return j.copy(), nil
+		}
+	}
+
+	now := time.Now()
+	job := &Job{
+		ID:            uuid.NewString(),
+		Path:          opts.Path,
+		Filename:      filepath.Base(opts.Path),
+		MediaId:       opts.MediaId,
+		EpisodeNumber: opts.EpisodeNumber,
+		Profile:       profile.Name,
+		Subtitles:     subtitles,
+		AudioTrack:    opts.AudioTrack,
+		SubtitleTrack: opts.SubtitleTrack,
+		Status:        StatusQueued,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	q.jobs = append(q.jobs, job)
+	q.saveJobs()
+	q.sendUpdate(job)
+
+	q.logger.Debug().Str("id", job.ID).Str("path", job.Path).Str("profile", job.Profile).Msg("pre-transcode: Job queued")
+
+	q.wake()
+
+	return job.copy(), nil
+}
+
+// Cancel cancels a queued or running job.
+func (q *Queue) Cancel(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.findJob(id)
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	switch job.Status {
+	case StatusQueued:
+		q.setStatus(job, StatusCancelled, "")
+		// Stop waiting for a governor slot
+		if cancel, ok := q.cancels[id]; ok {
+			cancel()
+		}
+	case StatusRunning:
+		// The job is marked as cancelled once ffmpeg exits
+		if cancel, ok := q.cancels[id]; ok {
+			cancel()
+		}
+	}
+
+	return nil
+}
+
+// Remove cancels the job and deletes its output.
+func (q *Queue) Remove(id string) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.findJob(id)
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	if cancel, ok := q.cancels[id]; ok {
+		cancel()
+		delete(q.cancels, id)
+	}
+
+	_ = os.Remove(q.outputPath(job))
+	_ = os.Remove(q.partPath(job))
+
+	q.jobs = slices.DeleteFunc(q.jobs, func(j *Job) bool { return j.ID == id })
+	q.saveJobs()
+
+	job.Status = StatusCancelled
+	q.sendUpdate(job)
+
+	return nil
+}
+
+// List returns the jobs, in the order they were added.
+func (q *Queue) List() []*Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ret := make([]*Job, 0, len(q.jobs))
+	for _, j := range q.jobs {
+		ret = append(ret, j.copy())
+	}
+	return ret
+}
+
+func (q *Queue) Get(id string) (*Job, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.findJob(id)
+	if !ok {
+		return nil, false
+	}
+	return job.copy(), true
+}
+
+// GetOutput returns the path of the output of a completed job and the name it should be downloaded as.
+func (q *Queue) GetOutput(id string) (path string, name string, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	job, ok := q.findJob(id)
+	if !ok {
+		return "", "", ErrJobNotFound
+	}
+	if job.Status != StatusCompleted {
+		return "", "", ErrNotFinished
+	}
+
+	name = strings.TrimSuffix(job.Filename, filepath.Ext(job.Filename)) + " [" + job.Profile + "].mp4"
+	return q.outputPath(job), name, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// run runs the queued jobs in order, one at a time, as a governor slot becomes available.
+func (q *Queue) run() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.ctx.Done():
+			return
+		case <-q.wakeCh:
+		}
+
+		for {
+			q.mu.Lock()
+			job, ok := q.nextJob()
+			if !ok || q.governor == nil {
+				q.mu.Unlock()
+				break
+			}
+			governor := q.governor
+			settings := q.settings
+			ctx, cancel := context.WithCancel(q.ctx)
+			q.cancels[job.ID] = cancel
+			q.mu.Unlock()
+
+			// Wait for a slot, the job can be cancelled in the meantime
+			release, err := governor.Acquire(ctx)
+			if err != nil {
+				q.mu.Lock()
+				delete(q.cancels, job.ID)
+				if job.Status == StatusQueued && q.ctx.Err() == nil {
+					q.setStatus(job, StatusCancelled, "")
+				}
+				q.mu.Unlock()
+				cancel()
+				if q.ctx.Err() != nil {
+					return
+				}
+				continue
+			}
+
+			q.mu.Lock()
+			if job.Status != StatusQueued {
+				// Cancelled or removed while waiting
+				delete(q.cancels, job.ID)
+				q.mu.Unlock()
+				release()
+				cancel()
+				continue
+			}
+			q.setStatus(job, StatusRunning, "")
+			q.mu.Unlock()
+
+			// Never hold more than one slot so that playback sessions are not starved
+			q.transcode(ctx, job, settings)
+			release()
+			cancel()
+		}
+	}
+}
+
+func (q *Queue) nextJob() (*Job, bool) {
+	if !q.settings.Enabled {
+		return nil, false
+	}
+	for _, j := range q.jobs {
+		if _, started := q.cancels[j.ID]; j.Status == StatusQueued && !started {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+func (q *Queue) wake() {
+	select {
+	case q.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// transcode runs ffmpeg for the job and updates its status.
+func (q *Queue) transcode(ctx context.Context, job *Job, settings Settings) {
+	q.mu.Lock()
+	profile, _ := GetProfile(job.Profile)
+	j := job.copy()
+	outPath := q.outputPath(job)
+	partPath := q.partPath(job)
+	q.mu.Unlock()
+
+	finish := func(status Status, errMsg string) {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		delete(q.cancels, job.ID)
+		if !slices.Contains(q.jobs, job) {
+			// Removed
+			return
+		}
+		if status == StatusCompleted {
+			job.Progress = 1
+			if fi, err := os.Stat(outPath); err == nil {
+				job.Size = fi.Size()
+			}
+		}
+		q.setStatus(job, status, errMsg)
+	}
+
+	q.logger.Info().Str("id", j.ID).Str("path", j.Path).Str("profile", j.Profile).Msg("pre-transcode: Starting job")
+
+	if profile == nil {
+		finish(StatusFailed, "unknown profile")
+		return
+	}
+
+	info, err := q.getMediaInfo(settings.FfprobePath, j.Path)
+	if err != nil {
+		q.logger.Error().Err(err).Str("path", j.Path).Msg("pre-transcode: Failed to get media information")
+		finish(StatusFailed, err.Error())
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(partPath), 0755); err != nil {
+		finish(StatusFailed, err.Error())
+		return
+	}
+
+	args := buildArgs(j, profile, info, settings.Preset, partPath)
+	cmd := util.NewCmdCtx(ctx, settings.FfmpegPath, args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		finish(StatusFailed, err.Error())
+		return
+	}
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+
+	if err := cmd.Start(); err != nil {
+		if ctx.Err() != nil {
+			if q.ctx.Err() == nil {
+				finish(StatusCancelled, "")
+			}
+			return
+		}
+		q.logger.Error().Err(err).Msg("pre-transcode: Failed to start ffmpeg")
+		finish(StatusFailed, err.Error())
+		return
+	}
+
+	q.readProgress(stdout, job, float64(info.Duration))
+	err = cmd.Wait()
+
+	switch {
+	case ctx.Err() != nil:
+		_ = os.Remove(partPath)
+		if q.ctx.Err() != nil {
+			// Shutting down, the job is resumed on the next start
+			return
+		}
+		q.logger.Debug().Str("id", j.ID).Msg("pre-transcode: Job cancelled")
+		finish(StatusCancelled, "")
+	case err != nil:
+		_ = os.Remove(partPath)
+		msg := lastLine(stderr.String())
+		if msg == "" {
+			msg = err.Error()
+		}
+		q.logger.Error().Err(err).Str("id", j.ID).Str("stderr", stderr.String()).Msg("pre-transcode: Job failed")
+		finish(StatusFailed, msg)
+	default:
+		if err := os.Rename(partPath, outPath); err != nil {
+			finish(StatusFailed, err.Error())
+			return
+		}
+		q.logger.Info().Str("id", j.ID).Str("path", j.Path).Msg("pre-transcode: Job completed")
+		finish(StatusCompleted, "")
+	}
+}
+
+// readProgress reads the progress reported by ffmpeg until it exits.
+func (q *Queue) readProgress(r io.Reader, job *Job, duration float64) {
+	scanner := bufio.NewScanner(r)
+	var lastSent time.Time
+	for scanner.Scan() {
+		seconds, ok := parseProgressLine(scanner.Text())
+		if !ok || duration <= 0 {
+			continue
+		}
+		progress := min(max(seconds/duration, 0), 1)
+
+		if time.Since(lastSent) < progressInterval {
+			continue
+		}
+		lastSent = time.Now()
+
+		q.mu.Lock()
+		if job.Status == StatusRunning {
+			job.Progress = progress
+			job.UpdatedAt = time.Now()
+			q.sendUpdate(job)
+		}
+		q.mu.Unlock()
+	}
+}
+
+// parseProgressLine returns the encoded position in seconds from an "out_time_us" line of ffmpeg -progress.
+func parseProgressLine(line string) (float64, bool) {
+	key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+	// out_time_ms is also in microseconds
+	if !ok || (key != "out_time_us" && key != "out_time_ms") {
+		return 0, false
+	}
+	us, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || us < 0 {
+		return 0, false
+	}
+	return float64(us) / 1_000_000, true
+}
+
+func lastLine(s string) string {
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// The following methods must be called with the lock held
+
+func (q *Queue) findJob(id string) (*Job, bool) {
+	for _, j := range q.jobs {
+		if j.ID == id {
+			return j, true
+		}
+	}
+	return nil, false
+}
+
+func (q *Queue) setStatus(job *Job, status Status, errMsg string) {
+	job.Status = status
+	job.Error = errMsg
+	job.UpdatedAt = time.Now()
+	if status != StatusCompleted && status != StatusRunning {
+		job.Progress = 0
+	}
+	q.saveJobs()
+	q.sendUpdate(job)
+}
+
+func (q *Queue) sendUpdate(job *Job) {
+	if q.wsEventManager != nil {
+		q.wsEventManager.SendEvent(events.MediastreamPreTranscodeJobUpdated, job.copy())
+	}
+}
+
+func (q *Queue) outputPath(job *Job) string {
+	return filepath.Join(q.settings.OutputDir, job.ID+".mp4")
+}
+
+func (q *Queue) partPath(job *Job) string {
+	return filepath.Join(q.settings.OutputDir, job.ID+".part.mp4")
+}
+
+func (q *Queue) loadJobs() []*Job {
+	if q.settings.OutputDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(q.settings.OutputDir, jobsFilename))
+	if err != nil {
+		return nil
+	}
+
+	var jobs []*Job
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		q.logger.Error().Err(err).Msg("pre-transcode: Failed to read jobs")
+		return nil
+	}
+
+	for _, j := range jobs {
+		switch j.Status {
+		case StatusRunning:
+			// Interrupted, start over
+			_ = os.Remove(q.partPath(j))
+			j.Status = StatusQueued
+			j.Progress = 0
+		case StatusCompleted:
+			// The output was deleted manually
+			if _, err := os.Stat(q.outputPath(j)); err != nil {
+				j.Status = StatusFailed
+				j.Error = "output file not found"
+			}
+		}
+	}
+
+	return jobs
+}
+
+func (q *Queue) saveJobs() {
+	if q.settings.OutputDir == "" {
+		return
+	}
+	if err := os.MkdirAll(q.settings.OutputDir, 0755); err != nil {
+		q.logger.Error().Err(err).Msg("pre-transcode: Failed to create output directory")
+		return
+	}
+	data, err := json.Marshal(q.jobs)
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(filepath.Join(q.settings.OutputDir, jobsFilename), data, 0644); err != nil {
+		q.logger.Error().Err(err).Msg("pre-transcode: Failed to save jobs")
+	}
+}
+
+func (j *Job) copy() *Job {
+	c := *j
+	return &c
+}
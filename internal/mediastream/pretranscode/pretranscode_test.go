@@ -0,0 +1,274 @@
+package pretranscode
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func testMediaInfo() *videofile.MediaInfo {
+	return &videofile.MediaInfo{
+		Duration: 100,
+		Audios: []videofile.Audio{
+			{Index: 0, Codec: "flac"},
+			{Index: 1, Codec: "aac", IsDefault: true},
+		},
+		Subtitles: []videofile.Subtitle{
+			{Index: 0, Codec: "ass"},
+			{Index: 1, Codec: "ass", IsDefault: true},
+			{Index: 2, Codec: "subrip", IsExternal: true},
+		},
+	}
+}
+
+func TestBuildArgs(t *testing.T) {
+	profile, ok := GetProfile("")
+	require.True(t, ok)
+	require.Equal(t, "720p", profile.Name)
+
+	job := &Job{
+		Path:          "/anime/Frieren: S01E01 [1080p].mkv",
+		Subtitles:     SubtitleModeBurn,
+		AudioTrack:    -1,
+		SubtitleTrack: -1,
+	}
+
+	args := buildArgs(job, profile, testMediaInfo(), "", "/out/job.part.mp4")
+	require.Equal(t, "/anime/Frieren: S01E01 [1080p].mkv", args[slices.Index(args, "-i")+1])
+	// Default tracks are selected
+	require.Contains(t, args, "0:a:1")
+	require.Equal(t, `subtitles=filename=/anime/Frieren\\: S01E01 \[1080p\].mkv:si=1,scale=-2:'min(720,ih)',format=yuv420p`, args[slices.Index(args, "-vf")+1])
+	require.Equal(t, "fast", args[slices.Index(args, "-preset")+1])
+	require.Equal(t, "128k", args[slices.Index(args, "-b:a")+1])
+	require.NotContains(t, args, "mov_text")
+	require.Equal(t, "/out/job.part.mp4", args[len(args)-1])
+
+	// Soft subtitles with explicit tracks
+	job.Subtitles = SubtitleModeSoft
+	job.AudioTrack = 0
+	job.SubtitleTrack = 0
+	args = buildArgs(job, profile, testMediaInfo(), "medium", "/out/job.part.mp4")
+	require.Contains(t, args, "0:a:0")
+	require.Contains(t, args, "0:s:0")
+	require.Contains(t, args, "mov_text")
+	require.Equal(t, "scale=-2:'min(720,ih)',format=yuv420p", args[slices.Index(args, "-vf")+1])
+
+	// No subtitles available
+	info := testMediaInfo()
+	info.Subtitles = info.Subtitles[2:]
+	job.Subtitles = SubtitleModeBurn
+	args = buildArgs(job, profile, info, "", "/out/job.part.mp4")
+	require.Equal(t, "scale=-2:'min(720,ih)',format=yuv420p", args[slices.Index(args, "-vf")+1])
+}
+
+func TestParseProgressLine(t *testing.T) {
+	seconds, ok := parseProgressLine("out_time_us=12500000")
+	require.True(t, ok)
+	require.InDelta(t, 12.5, seconds, 0.001)
+
+	_, ok = parseProgressLine("out_time=00:00:12.500000")
+	require.False(t, ok)
+	_, ok = parseProgressLine("out_time_us=N/A")
+	require.False(t, ok)
+}
+
+// writeFakeFfmpeg writes a script that reports progress and writes its last argument.
+func writeFakeFfmpeg(t *testing.T, dir string, fail bool) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	script := `#!/bin/sh
+for last; do true; done
+echo "out_time_us=50000000"
+echo "progress=continue"
+`
+	if fail {
+		script += `echo "Invalid data found when processing input" >&2
+exit 1
+`
+	} else {
+		script += `sleep 0.2
+echo "offline copy" > "$last"
+echo "out_time_us=100000000"
+echo "progress=end"
+`
+	}
+
+	p := filepath.Join(dir, "ffmpeg")
+	require.NoError(t, os.WriteFile(p, []byte(script), 0755))
+	return p
+}
+
+func newTestQueue(t *testing.T, ffmpegPath string, outDir string) *Queue {
+	logger := zerolog.Nop()
+	q := NewQueue(&NewQueueOptions{
+		Logger:         &logger,
+		WSEventManager: events.NewMockWSEventManager(util.NewLogger()),
+		GetMediaInfo: func(ffprobePath, path string) (*videofile.MediaInfo, error) {
+			return testMediaInfo(), nil
+		},
+	})
+	t.Cleanup(q.Shutdown)
+
+	q.SetSettings(Settings{
+		Enabled:    true,
+		OutputDir:  outDir,
+		FfmpegPath: ffmpegPath,
+	}, cassette.NewGovernor(1, false, util.NewLogger()))
+	return q
+}
+
+func waitForStatus(t *testing.T, q *Queue, id string, status Status) *Job {
+	var job *Job
+	require.Eventually(t, func() bool {
+		var ok bool
+		job, ok = q.Get(id)
+		return ok && job.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+	return job
+}
+
+func TestQueue(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegPath := writeFakeFfmpeg(t, dir, false)
+	outDir := filepath.Join(dir, "offline")
+
+	input := filepath.Join(dir, "[SubsPlease] Frieren - 01 (1080p).mkv")
+	require.NoError(t, os.WriteFile(input, []byte("video"), 0644))
+
+	q := newTestQueue(t, ffmpegPath, outDir)
+
+	_, err := q.Add(&AddOptions{Path: input, Profile: "4k"})
+	require.Error(t, err)
+	_, err = q.Add(&AddOptions{Path: dir, AudioTrack: -1, SubtitleTrack: -1})
+	require.Error(t, err)
+
+	job, err := q.Add(&AddOptions{Path: input, MediaId: 154587, EpisodeNumber: 1, AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+	require.Equal(t, "720p", job.Profile)
+	require.Equal(t, SubtitleModeBurn, job.Subtitles)
+
+	// Adding the same job again returns the existing one
+	again, err := q.Add(&AddOptions{Path: input, AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+	require.Equal(t, job.ID, again.ID)
+
+	_, _, err = q.GetOutput(job.ID)
+	if err == nil {
+		t.Fatal("expected the job to be unfinished")
+	}
+
+	job = waitForStatus(t, q, job.ID, StatusCompleted)
+	require.Equal(t, 1.0, job.Progress)
+	require.NotZero(t, job.Size)
+
+	output, name, err := q.GetOutput(job.ID)
+	require.NoError(t, err)
+	require.Equal(t, "[SubsPlease] Frieren - 01 (1080p) [720p].mp4", name)
+	data, err := os.ReadFile(output)
+	require.NoError(t, err)
+	require.Equal(t, "offline copy\n", string(data))
+
+	// Jobs are persisted in the output directory
+	q2 := newTestQueue(t, ffmpegPath, outDir)
+	jobs := q2.List()
+	require.Len(t, jobs, 1)
+	require.Equal(t, StatusCompleted, jobs[0].Status)
+
+	require.NoError(t, q2.Remove(job.ID))
+	require.Empty(t, q2.List())
+	_, err = os.Stat(output)
+	require.True(t, os.IsNotExist(err))
+	require.ErrorIs(t, q2.Remove(job.ID), ErrJobNotFound)
+}
+
+func TestQueue_Failure(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegPath := writeFakeFfmpeg(t, dir, true)
+
+	input := filepath.Join(dir, "episode.mkv")
+	require.NoError(t, os.WriteFile(input, []byte("video"), 0644))
+
+	q := newTestQueue(t, ffmpegPath, filepath.Join(dir, "offline"))
+
+	job, err := q.Add(&AddOptions{Path: input, Profile: "480p", AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+
+	job = waitForStatus(t, q, job.ID, StatusFailed)
+	require.Equal(t, "Invalid data found when processing input", job.Error)
+	require.Zero(t, job.Progress)
+
+	// A failed job can be queued again
+	retry, err := q.Add(&AddOptions{Path: input, Profile: "480p", AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+	require.NotEqual(t, job.ID, retry.ID)
+}
+
+func TestQueue_CancelWaitingJob(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegPath := writeFakeFfmpeg(t, dir, false)
+
+	input := filepath.Join(dir, "episode.mkv")
+	require.NoError(t, os.WriteFile(input, []byte("video"), 0644))
+
+	governor := cassette.NewGovernor(1, false, util.NewLogger())
+	// Live playback holds the only slot
+	release, err := governor.Acquire(t.Context())
+	require.NoError(t, err)
+
+	q := newTestQueue(t, ffmpegPath, filepath.Join(dir, "offline"))
+	q.SetSettings(Settings{Enabled: true, OutputDir: filepath.Join(dir, "offline"), FfmpegPath: ffmpegPath}, governor)
+
+	job, err := q.Add(&AddOptions{Path: input, AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+
+	time.Sleep(100 * time.Millisecond)
+	job, _ = q.Get(job.ID)
+	require.Equal(t, StatusQueued, job.Status)
+
+	require.NoError(t, q.Cancel(job.ID))
+	waitForStatus(t, q, job.ID, StatusCancelled)
+
+	// The next job starts once the slot is released
+	next, err := q.Add(&AddOptions{Path: input, Profile: "1080p", AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+	release()
+	waitForStatus(t, q, next.ID, StatusCompleted)
+}
+
+func TestQueue_RunsOneJobAtATime(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegPath := writeFakeFfmpeg(t, dir, false)
+
+	input := filepath.Join(dir, "episode.mkv")
+	require.NoError(t, os.WriteFile(input, []byte("video"), 0644))
+
+	governor := cassette.NewGovernor(2, false, util.NewLogger())
+	q := newTestQueue(t, ffmpegPath, filepath.Join(dir, "offline"))
+	q.SetSettings(Settings{Enabled: true, OutputDir: filepath.Join(dir, "offline"), FfmpegPath: ffmpegPath}, governor)
+
+	first, err := q.Add(&AddOptions{Path: input, AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+	second, err := q.Add(&AddOptions{Path: input, Profile: "1080p", AudioTrack: -1, SubtitleTrack: -1})
+	require.NoError(t, err)
+
+	waitForStatus(t, q, first.ID, StatusRunning)
+	// The other slot is left to live playback
+	require.Equal(t, int32(1), governor.Stats().ActiveProcesses)
+	job, _ := q.Get(second.ID)
+	require.Equal(t, StatusQueued, job.Status)
+
+	waitForStatus(t, q, second.ID, StatusCompleted)
+	require.Equal(t, int64(2), governor.Stats().TotalLaunched)
+}
@@ -0,0 +1,164 @@
+package pretranscode
+
+import (
+	"fmt"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/videofile"
+	"strings"
+)
+
+type SubtitleMode string
+
+const (
+	// SubtitleModeNone drops the subtitles
+	SubtitleModeNone SubtitleMode = "none"
+	// SubtitleModeBurn renders the subtitle track into the video
+	SubtitleModeBurn SubtitleMode = "burn"
+	// SubtitleModeSoft keeps the subtitle track as a selectable mov_text track
+	SubtitleModeSoft SubtitleMode = "soft"
+)
+
+// Profile describes the output of a pre-transcode job.
+// The output is always an H.264/AAC MP4 file that can be played offline by any device.
+type Profile struct {
+	Name string `json:"name"`
+	// Height is the maximum height of the output, the source is never upscaled
+	Height int `json:"height"`
+	// Crf is the constant rate factor of the x264 encoder
+	Crf int `json:"crf"`
+	// AudioBitrate in kb/s
+	AudioBitrate int          `json:"audioBitrate"`
+	Subtitles    SubtitleMode `json:"subtitles"`
+}
+
+// Profiles are the available output profiles, from the smallest to the largest output
+var Profiles = []*Profile{
+	{Name: "480p", Height: 480, Crf: 24, AudioBitrate: 96, Subtitles: SubtitleModeBurn},
+	{Name: "720p", Height: 720, Crf: 23, AudioBitrate: 128, Subtitles: SubtitleModeBurn},
+	{Name: "1080p", Height: 1080, Crf: 22, AudioBitrate: 160, Subtitles: SubtitleModeSoft},
+}
+
+const DefaultProfile = "720p"
+
+func GetProfile(name string) (*Profile, bool) {
+	if name == "" {
+		name = DefaultProfile
+	}
+	for _, p := range Profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return nil, false
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// buildArgs returns the ffmpeg arguments transcoding the job input into outPath.
+// Progress is written to stdout in the key=value format of "-progress".
+func buildArgs(job *Job, profile *Profile, info *videofile.MediaInfo, preset string, outPath string) []string {
+	if preset == "" {
+		preset = "fast"
+	}
+
+	args := []string{
+		"-hide_banner", "-nostats", "-loglevel", "error",
+		"-progress", "pipe:1",
+		"-y",
+		"-i", job.Path,
+		"-map", "0:v:0",
+	}
+
+	if audio, ok := selectAudio(info, job.AudioTrack); ok {
+		args = append(args, "-map", fmt.Sprintf("0:a:%d", audio))
+	}
+
+	var filters []string
+	mode := job.Subtitles
+	subtitle, hasSubtitle := selectSubtitle(info, job.SubtitleTrack)
+	if mode == SubtitleModeBurn && hasSubtitle {
+		// The subtitles filter also loads the fonts attached to the file
+		filters = append(filters, fmt.Sprintf("subtitles=filename=%s:si=%d", cassette.EscapeFilterValue(job.Path), subtitle))
+	}
+	if profile.Height > 0 {
+		filters = append(filters, fmt.Sprintf("scale=-2:'min(%d,ih)'", profile.Height))
+	}
+	filters = append(filters, "format=yuv420p")
+
+	args = append(args,
+		"-vf", strings.Join(filters, ","),
+		"-c:v", "libx264",
+		"-preset", preset,
+		"-crf", fmt.Sprint(profile.Crf),
+		"-profile:v", "high",
+		"-tune", "animation",
+		"-c:a", "aac",
+		"-b:a", fmt.Sprintf("%dk", profile.AudioBitrate),
+		"-ac", "2",
+	)
+
+	if mode == SubtitleModeSoft && hasSubtitle {
+		args = append(args, "-map", fmt.Sprintf("0:s:%d", subtitle), "-c:s", "mov_text")
+	}
+
+	args = append(args,
+		"-map_metadata", "-1",
+		"-map_chapters", "0",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		outPath,
+	)
+
+	return args
+}
+
+// selectAudio returns the relative index of the audio track to keep.
+// A negative track selects the default track.
+func selectAudio(info *videofile.MediaInfo, track int) (uint32, bool) {
+	if info == nil || len(info.Audios) == 0 {
+		return 0, false
+	}
+	if track >= 0 {
+		for _, a := range info.Audios {
+			if int(a.Index) == track {
+				return a.Index, true
+			}
+		}
+	}
+	for _, a := range info.Audios {
+		if a.IsDefault {
+			return a.Index, true
+		}
+	}
+	return info.Audios[0].Index, true
+}
+
+// selectSubtitle returns the relative index of the embedded text subtitle track to keep.
+// A negative track selects the default track.
+func selectSubtitle(info *videofile.MediaInfo, track int) (uint32, bool) {
+	if info == nil {
+		return 0, false
+	}
+	var internal []videofile.Subtitle
+	for _, s := range info.Subtitles {
+		if !s.IsExternal {
+			internal = append(internal, s)
+		}
+	}
+	if len(internal) == 0 {
+		return 0, false
+	}
+	if track >= 0 {
+		for _, s := range internal {
+			if int(s.Index) == track {
+				return s.Index, true
+			}
+		}
+	}
+	for _, s := range internal {
+		if s.IsDefault {
+			return s.Index, true
+		}
+	}
+	return internal[0].Index, true
+}
@@ -8,6 +8,8 @@ import (
 	"seanime/internal/events"
 	"seanime/internal/mediacore"
 	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/pretranscode"
+	"seanime/internal/mediastream/trickplay"
 	"seanime/internal/mediastream/videofile"
 	"seanime/internal/player"
 	"seanime/internal/util/filecache"
@@ -28,9 +30,14 @@ type (
 		wsEventManager       events.WSEventManagerInterface
 		mediacoreCoordinator *mediacore.Coordinator
 		fileCacher           *filecache.Cacher
-		reqMu                sync.Mutex
-		cacheDir             string // where attachments are stored
-		transcodeDir         string // where stream segments are stored
+		preTranscodeQueue    *pretranscode.Queue
+		trickplayGenerator   *trickplay.Generator
+		// governor limits the ffmpeg processes of the transcoder and the pre-transcode queue
+		governor        *cassette.Governor
+		governorHwAccel string
+		reqMu           sync.Mutex
+		cacheDir        string // where attachments are stored
+		transcodeDir    string // where stream segments are stored
 	}
 
 	NewRepositoryOptions struct {
@@ -52,6 +59,16 @@ func NewRepository(opts *NewRepositoryOptions) *Repository {
 		mediaInfoExtractor:   videofile.NewMediaInfoExtractor(opts.FileCacher, opts.Logger),
 	}
 	ret.playbackManager = NewPlaybackManager(ret)
+	ret.preTranscodeQueue = pretranscode.NewQueue(&pretranscode.NewQueueOptions{
+		Logger:         opts.Logger,
+		WSEventManager: opts.WSEventManager,
+		GetMediaInfo:   ret.mediaInfoExtractor.GetInfo,
+	})
+	ret.trickplayGenerator = trickplay.NewGenerator(&trickplay.NewGeneratorOptions{
+		Logger:         opts.Logger,
+		WSEventManager: opts.WSEventManager,
+		GetMediaInfo:   ret.mediaInfoExtractor.GetInfo,
+	})
 
 	if opts.MediacoreCoordinator != nil {
 		opts.MediacoreCoordinator.RegisterEventCallback(func(event player.Event) bool {
@@ -74,7 +91,8 @@ func (r *Repository) IsInitialized() bool {
 }
 
 func (r *Repository) OnCleanup() {
-
+	r.preTranscodeQueue.Shutdown()
+	r.trickplayGenerator.Shutdown()
 }
 
 func (r *Repository) InitializeModules(settings *models.MediastreamSettings, cacheDir string, transcodeDir string) {
@@ -104,6 +122,41 @@ func (r *Repository) InitializeModules(settings *models.MediastreamSettings, cac
 	r.cacheDir = cacheDir
 	r.transcodeDir = transcodeDir
 
+	// Create the governor, it is kept across transcoder re-initializations so that running jobs count against the limit
+	if r.governor == nil || r.governorHwAccel != settings.TranscodeHwAccel {
+		switch settings.TranscodeHwAccel {
+		case "", "cpu", "none", "disabled":
+			r.governor = cassette.NewGovernor(0, false, r.logger)
+		default:
+			r.governor = cassette.NewGovernor(0, true, r.logger)
+		}
+		r.governorHwAccel = settings.TranscodeHwAccel
+	}
+
+	// Initialize the pre-transcode queue
+	preTranscodeDir := settings.PreTranscodeLibraryDir
+	if preTranscodeDir == "" {
+		preTranscodeDir = filepath.Join(cacheDir, "pretranscode")
+	}
+	r.preTranscodeQueue.SetSettings(pretranscode.Settings{
+		Enabled:     settings.PreTranscodeEnabled,
+		OutputDir:   preTranscodeDir,
+		FfmpegPath:  settings.FfmpegPath,
+		FfprobePath: settings.FfprobePath,
+		Preset:      settings.TranscodePreset,
+	}, r.governor)
+
+	// Initialize the trickplay generator
+	r.trickplayGenerator.SetSettings(trickplay.Settings{
+		Enabled:     settings.TrickplayEnabled,
+		Mode:        trickplay.Mode(settings.TrickplayMode),
+		Interval:    settings.TrickplayInterval,
+		Width:       settings.TrickplayWidth,
+		FfmpegPath:  settings.FfmpegPath,
+		FfprobePath: settings.FfprobePath,
+		CacheDir:    cacheDir,
+	}, r.governor)
+
 	// Initialize the transcoder
 	if ok := r.initializeTranscoder(r.settings); ok {
 	}
@@ -151,7 +204,9 @@ func (r *Repository) TranscoderIsInitialized() bool {
 	return r.IsInitialized() && r.transcoder.IsPresent()
 }
 
-func (r *Repository) RequestTranscodeStream(filepath string, clientId string) (ret *MediaContainer, err error) {
+// RequestTranscodeStream prepares the transcoded stream of a file.
+// The profile is selected by the client, the stream URL of the returned container includes it.
+func (r *Repository) RequestTranscodeStream(filepath string, clientId string, profile cassette.TranscodeProfile) (ret *MediaContainer, err error) {
 	r.reqMu.Lock()
 	defer r.reqMu.Unlock()
 
@@ -167,6 +222,14 @@ func (r *Repository) RequestTranscodeStream(filepath string, clientId string) (r
 	}
 
 	ret, err = r.playbackManager.RequestPlayback(filepath, StreamTypeTranscode)
+	if err != nil || profile.IsDefault() {
+		return
+	}
+
+	// Media containers are shared between clients
+	withProfile := *ret
+	withProfile.StreamUrl = "/api/v1/mediastream/transcode/profiles/" + profile.Key() + "/master.m3u8"
+	ret = &withProfile
 
 	return
 }
@@ -214,6 +277,28 @@ func (r *Repository) RequestPreloadDirectPlay(filepath string) (err error) {
 	return
 }
 
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Pre-transcode
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// PreTranscodeQueue returns the queue of the offline copies.
+func (r *Repository) PreTranscodeQueue() *pretranscode.Queue {
+	return r.preTranscodeQueue
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Trickplay
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// OnLibraryScanned queues the thumbnail generation of the library files when the trickplay mode is "on-scan".
+func (r *Repository) OnLibraryScanned(paths []string) {
+	settings := r.trickplayGenerator.GetSettings()
+	if !settings.Enabled || settings.Mode != trickplay.ModeOnScan {
+		return
+	}
+	r.trickplayGenerator.Enqueue(paths...)
+}
+
 ///////////////////////////////////////////////////////////////////////////////////////////////
 
 func (r *Repository) initializeTranscoder(settings mo.Option[*models.MediastreamSettings]) bool {
@@ -244,6 +329,7 @@ func (r *Repository) initializeTranscoder(settings mo.Option[*models.Mediastream
 		FfprobePath:           settings.MustGet().FfprobePath,
 		HwAccelCustomSettings: settings.MustGet().TranscodeHwAccelCustomSettings,
 		TempOutDir:            r.transcodeDir,
+		Governor:              r.governor,
 	}
 
 	tc, err := cassette.New(opts)
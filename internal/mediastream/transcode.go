@@ -36,8 +36,21 @@ func (r *Repository) ServeEchoTranscodeStream(c echo.Context, clientId string) e
 
 	token := c.QueryParam("token")
 
+	// The transcode profile is part of the stream URL
+	// /profiles/:key/...
+	profile := cassette.TranscodeProfile{}
+	if rest, ok := strings.CutPrefix(path, "profiles/"); ok {
+		key, rest, _ := strings.Cut(rest, "/")
+		var err error
+		profile, err = cassette.ParseTranscodeProfile(key)
+		if err != nil {
+			return err
+		}
+		path = rest
+	}
+
 	if path == "master.m3u8" {
-		ret, err := r.transcoder.MustGet().GetMaster(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, clientId, token)
+		ret, err := r.transcoder.MustGet().GetMaster(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, profile, clientId, token)
 		if err != nil {
 			return err
 		}
@@ -58,7 +71,7 @@ func (r *Repository) ServeEchoTranscodeStream(c echo.Context, clientId string) e
 			return err
 		}
 
-		ret, err := r.transcoder.MustGet().GetVideoIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, quality, clientId, token)
+		ret, err := r.transcoder.MustGet().GetVideoIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, profile, quality, clientId, token)
 		if err != nil {
 			return err
 		}
@@ -79,7 +92,7 @@ func (r *Repository) ServeEchoTranscodeStream(c echo.Context, clientId string) e
 			return err
 		}
 
-		ret, err := r.transcoder.MustGet().GetAudioIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, int32(audio), clientId, token)
+		ret, err := r.transcoder.MustGet().GetAudioIndex(mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, profile, int32(audio), clientId, token)
 		if err != nil {
 			return err
 		}
@@ -107,7 +120,7 @@ func (r *Repository) ServeEchoTranscodeStream(c echo.Context, clientId string) e
 
 		ret, err := r.transcoder.MustGet().GetVideoSegment(
 			c.Request().Context(),
-			mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, quality, segment, clientId)
+			mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, profile, quality, segment, clientId)
 		if err != nil {
 			return err
 		}
@@ -135,7 +148,7 @@ func (r *Repository) ServeEchoTranscodeStream(c echo.Context, clientId string) e
 
 		ret, err := r.transcoder.MustGet().GetAudioSegment(
 			c.Request().Context(),
-			mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, int32(audio), segment, clientId)
+			mediaContainer.Filepath, mediaContainer.Hash, mediaContainer.MediaInfo, profile, int32(audio), segment, clientId)
 		if err != nil {
 			return err
 		}
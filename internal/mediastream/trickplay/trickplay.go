@@ -0,0 +1,351 @@
+package trickplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+var ErrNoVideo = errors.New("trickplay: File has no video stream")
+
+type Mode string
+
+const (
+	// ModeOnDemand generates the thumbnails of a file when it is played
+	ModeOnDemand Mode = "on-demand"
+	// ModeOnScan generates the thumbnails of the library files after each scan
+	ModeOnScan Mode = "on-scan"
+)
+
+const (
+	DefaultInterval = 10
+	DefaultWidth    = 320
+	// Each sprite sheet is a grid of tileColumns x tileRows thumbnails
+	tileColumns = 10
+	tileRows    = 10
+
+	IndexFilename = "index.vtt"
+)
+
+type (
+	Settings struct {
+		Enabled bool
+		Mode    Mode
+		// Interval is the number of seconds between two thumbnails
+		Interval int
+		// Width of the thumbnails in pixels, the height follows the aspect ratio
+		Width       int
+		FfmpegPath  string
+		FfprobePath string
+		// CacheDir is where the sprite sheets are stored, next to the other extracted files of the videofile package
+		CacheDir string
+	}
+
+	// Generator extracts the thumbnails of files in the background, one file at a time.
+	Generator struct {
+		logger         *zerolog.Logger
+		wsEventManager events.WSEventManagerInterface
+		getMediaInfo   func(ffprobePath, path string) (*videofile.MediaInfo, error)
+
+		mu       sync.Mutex
+		settings Settings
+		governor *cassette.Governor
+		// queue contains the paths waiting to be processed, in order
+		queue  []string
+		wakeCh chan struct{}
+
+		ctx    context.Context
+		cancel context.CancelFunc
+		wg     sync.WaitGroup
+	}
+
+	NewGeneratorOptions struct {
+		Logger         *zerolog.Logger
+		WSEventManager events.WSEventManagerInterface
+		// GetMediaInfo returns the duration and dimensions of a file, usually videofile.MediaInfoExtractor.GetInfo
+		GetMediaInfo func(ffprobePath, path string) (*videofile.MediaInfo, error)
+	}
+
+	// GeneratedEvent is sent with events.MediastreamTrickplayGenerated
+	GeneratedEvent struct {
+		Path string `json:"path"`
+		Hash string `json:"hash"`
+	}
+)
+
+func NewGenerator(opts *NewGeneratorOptions) *Generator {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Generator{
+		logger:         opts.Logger,
+		wsEventManager: opts.WSEventManager,
+		getMediaInfo:   opts.GetMediaInfo,
+		wakeCh:         make(chan struct{}, 1),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+	g.wg.Add(1)
+	go g.run()
+	return g
+}
+
+// SetSettings updates the settings and the governor shared with the other ffmpeg jobs.
+func (g *Generator) SetSettings(settings Settings, governor *cassette.Governor) {
+	if settings.Interval <= 0 {
+		settings.Interval = DefaultInterval
+	}
+	if settings.Width <= 0 {
+		settings.Width = DefaultWidth
+	}
+	// Keep the width even for the yuv420p encoder
+	settings.Width += settings.Width % 2
+	if settings.Mode != ModeOnScan {
+		settings.Mode = ModeOnDemand
+	}
+
+	g.mu.Lock()
+	g.settings = settings
+	g.governor = governor
+	if !settings.Enabled {
+		g.queue = nil
+	}
+	g.mu.Unlock()
+
+	g.wake()
+}
+
+func (g *Generator) GetSettings() Settings {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.settings
+}
+
+// Shutdown stops the generation and waits for ffmpeg to exit.
+func (g *Generator) Shutdown() {
+	g.cancel()
+	g.wg.Wait()
+}
+
+// Enqueue adds files at the end of the queue.
+// Files that already have thumbnails are skipped when they are processed.
+func (g *Generator) Enqueue(paths ...string) {
+	g.mu.Lock()
+	if g.settings.Enabled {
+		for _, p := range paths {
+			if !slices.Contains(g.queue, p) {
+				g.queue = append(g.queue, p)
+			}
+		}
+	}
+	g.mu.Unlock()
+
+	g.wake()
+}
+
+// Request moves a file to the front of the queue, it is used when the file is being played.
+func (g *Generator) Request(path string) {
+	g.mu.Lock()
+	if g.settings.Enabled {
+		g.queue = slices.DeleteFunc(g.queue, func(p string) bool { return p == path })
+		g.queue = slices.Insert(g.queue, 0, path)
+	}
+	g.mu.Unlock()
+
+	g.wake()
+}
+
+// GetDir returns the directory containing the sprite sheets and index of a file hash.
+// The directory depends on the settings so that changing them does not serve stale thumbnails.
+func (g *Generator) GetDir(hash string) string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return getDir(g.settings, hash)
+}
+
+// IsGenerated returns true if the thumbnails of a file hash are ready to be served.
+func (g *Generator) IsGenerated(hash string) bool {
+	_, err := os.Stat(filepath.Join(g.GetDir(hash), IndexFilename))
+	return err == nil
+}
+
+func getDir(settings Settings, hash string) string {
+	return filepath.Join(videofile.GetFileTrickplayCacheDir(settings.CacheDir, hash), fmt.Sprintf("%dw_%ds", settings.Width, settings.Interval))
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// run processes the queued files in order.
+func (g *Generator) run() {
+	defer g.wg.Done()
+	for {
+		select {
+		case <-g.ctx.Done():
+			return
+		case <-g.wakeCh:
+		}
+
+		for {
+			g.mu.Lock()
+			if !g.settings.Enabled || g.governor == nil || len(g.queue) == 0 {
+				g.mu.Unlock()
+				break
+			}
+			path := g.queue[0]
+			g.queue = g.queue[1:]
+			settings := g.settings
+			governor := g.governor
+			g.mu.Unlock()
+
+			if err := g.generate(g.ctx, path, settings, governor); err != nil {
+				if g.ctx.Err() != nil {
+					return
+				}
+				g.logger.Warn().Err(err).Str("path", path).Msg("trickplay: Failed to generate thumbnails")
+			}
+		}
+	}
+}
+
+func (g *Generator) wake() {
+	select {
+	case g.wakeCh <- struct{}{}:
+	default:
+	}
+}
+
+// generate writes the sprite sheets and the WebVTT index of a file.
+// The files are written to a temporary directory that is renamed once complete.
+func (g *Generator) generate(ctx context.Context, path string, settings Settings, governor *cassette.Governor) error {
+	hash, err := videofile.GetHashFromPath(path)
+	if err != nil {
+		return err
+	}
+
+	dir := getDir(settings, hash)
+	if _, err := os.Stat(filepath.Join(dir, IndexFilename)); err == nil {
+		return nil
+	}
+
+	info, err := g.getMediaInfo(settings.FfprobePath, path)
+	if err != nil {
+		return err
+	}
+	if info.Video == nil || info.Video.Width == 0 || info.Video.Height == 0 {
+		return ErrNoVideo
+	}
+	if info.Duration <= 0 {
+		return errors.New("trickplay: Unknown duration")
+	}
+
+	width, height := thumbnailSize(settings.Width, int(info.Video.Width), int(info.Video.Height))
+
+	tmpDir := dir + ".tmp"
+	_ = os.RemoveAll(tmpDir)
+	if err := os.MkdirAll(tmpDir, 0755); err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	release, err := governor.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	g.logger.Debug().Str("path", path).Str("hash", hash).Msg("trickplay: Generating thumbnails")
+
+	args := buildArgs(path, settings.Interval, width, height, filepath.Join(tmpDir, "sprite_%03d.jpg"))
+	cmd := util.NewCmdCtx(ctx, settings.FfmpegPath, args...)
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("trickplay: ffmpeg failed: %s", msg)
+		}
+		return fmt.Errorf("trickplay: ffmpeg failed: %w", err)
+	}
+
+	sprites, _ := filepath.Glob(filepath.Join(tmpDir, "sprite_*.jpg"))
+	if len(sprites) == 0 {
+		return errors.New("trickplay: No thumbnails were extracted")
+	}
+
+	vtt := buildVtt(float64(info.Duration), settings.Interval, width, height, len(sprites))
+	if err := os.WriteFile(filepath.Join(tmpDir, IndexFilename), []byte(vtt), 0644); err != nil {
+		return err
+	}
+
+	_ = os.RemoveAll(dir)
+	if err := os.Rename(tmpDir, dir); err != nil {
+		return err
+	}
+
+	g.logger.Info().Str("path", path).Int("sprites", len(sprites)).Msg("trickplay: Thumbnails generated")
+	g.wsEventManager.SendEvent(events.MediastreamTrickplayGenerated, GeneratedEvent{Path: path, Hash: hash})
+
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// thumbnailSize returns the even dimensions of a thumbnail keeping the aspect ratio of the video.
+func thumbnailSize(width int, videoWidth int, videoHeight int) (int, int) {
+	width = min(width, videoWidth-videoWidth%2)
+	height := (width*videoHeight/videoWidth + 1) &^ 1
+	return width, max(height, 2)
+}
+
+// buildArgs returns the ffmpeg arguments extracting a thumbnail every interval seconds into tiled sprite sheets.
+func buildArgs(path string, interval int, width int, height int, outPattern string) []string {
+	return []string{
+		"-hide_banner", "-nostdin", "-loglevel", "error",
+		// Only decoding keyframes is much faster, the thumbnails are at most one GOP off
+		"-skip_frame", "nokey",
+		"-i", path,
+		"-map", "0:v:0",
+		"-an", "-sn", "-dn",
+		"-vf", fmt.Sprintf("fps=1/%d,scale=%d:%d,tile=%dx%d", interval, width, height, tileColumns, tileRows),
+		"-q:v", "5",
+		"-f", "image2",
+		"-y",
+		outPattern,
+	}
+}
+
+// buildVtt returns the WebVTT index mapping each interval to its thumbnail in the sprite sheets.
+func buildVtt(duration float64, interval int, width int, height int, sprites int) string {
+	perSprite := tileColumns * tileRows
+	count := min(int(math.Ceil(duration/float64(interval))), sprites*perSprite)
+
+	var sb strings.Builder
+	sb.WriteString("WEBVTT\n")
+	for i := 0; i < count; i++ {
+		start := float64(i * interval)
+		end := min(float64((i+1)*interval), duration)
+		pos := i % perSprite
+		x := (pos % tileColumns) * width
+		y := (pos / tileColumns) * height
+		_, _ = fmt.Fprintf(&sb, "\n%s --> %s\nsprite_%03d.jpg#xywh=%d,%d,%d,%d\n",
+			formatTimestamp(start), formatTimestamp(end), i/perSprite+1, x, y, width, height)
+	}
+	return sb.String()
+}
+
+func formatTimestamp(seconds float64) string {
+	ms := int64(seconds * 1000)
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3_600_000, ms/60_000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,128 @@
+package trickplay
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"seanime/internal/events"
+	"seanime/internal/mediastream/cassette"
+	"seanime/internal/mediastream/videofile"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThumbnailSize(t *testing.T) {
+	w, h := thumbnailSize(320, 1920, 1080)
+	require.Equal(t, 320, w)
+	require.Equal(t, 180, h)
+
+	// 4:3
+	w, h = thumbnailSize(320, 640, 480)
+	require.Equal(t, 320, w)
+	require.Equal(t, 240, h)
+
+	// Odd heights are rounded to an even number
+	w, h = thumbnailSize(320, 1920, 800)
+	require.Equal(t, 320, w)
+	require.Equal(t, 134, h)
+
+	// The source is never upscaled
+	w, h = thumbnailSize(320, 255, 144)
+	require.Equal(t, 254, w)
+	require.Equal(t, 144, h)
+}
+
+func TestBuildVtt(t *testing.T) {
+	vtt := buildVtt(1005.5, 10, 320, 180, 2)
+	require.True(t, strings.HasPrefix(vtt, "WEBVTT\n\n"))
+
+	cues := strings.Split(strings.TrimPrefix(vtt, "WEBVTT\n\n"), "\n\n")
+	// 101 thumbnails, the first sheet holds 100 of them
+	require.Len(t, cues, 101)
+	require.Equal(t, "00:00:00.000 --> 00:00:10.000\nsprite_001.jpg#xywh=0,0,320,180", cues[0])
+	require.Equal(t, "00:02:10.000 --> 00:02:20.000\nsprite_001.jpg#xywh=960,180,320,180", cues[13])
+	require.Equal(t, "00:16:40.000 --> 00:16:45.500\nsprite_002.jpg#xywh=0,0,320,180\n", cues[100])
+
+	// Cues are limited to the extracted sprite sheets
+	vtt = buildVtt(1005.5, 10, 320, 180, 1)
+	require.Equal(t, 100, strings.Count(vtt, "-->"))
+}
+
+func writeFakeFfmpeg(t *testing.T, dir string) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+
+	// Writes two sprite sheets using the output pattern
+	script := `#!/bin/sh
+for last; do true; done
+printf "sheet" > "$(printf "$last" 1)"
+printf "sheet" > "$(printf "$last" 2)"
+`
+	p := filepath.Join(dir, "ffmpeg")
+	require.NoError(t, os.WriteFile(p, []byte(script), 0755))
+	return p
+}
+
+func TestGenerator(t *testing.T) {
+	dir := t.TempDir()
+	ffmpegPath := writeFakeFfmpeg(t, dir)
+
+	input := filepath.Join(dir, "episode.mkv")
+	require.NoError(t, os.WriteFile(input, []byte("video"), 0644))
+	noVideo := filepath.Join(dir, "audio.mka")
+	require.NoError(t, os.WriteFile(noVideo, []byte("audio"), 0644))
+
+	logger := zerolog.Nop()
+	ws := events.NewMockWSEventManager(util.NewLogger())
+	g := NewGenerator(&NewGeneratorOptions{
+		Logger:         &logger,
+		WSEventManager: ws,
+		GetMediaInfo: func(ffprobePath, path string) (*videofile.MediaInfo, error) {
+			if path == noVideo {
+				return &videofile.MediaInfo{Duration: 100}, nil
+			}
+			return &videofile.MediaInfo{Duration: 1420, Video: &videofile.Video{Width: 1920, Height: 1080}}, nil
+		},
+	})
+	t.Cleanup(g.Shutdown)
+
+	g.SetSettings(Settings{
+		Enabled:    true,
+		FfmpegPath: ffmpegPath,
+		CacheDir:   filepath.Join(dir, "cache"),
+	}, cassette.NewGovernor(1, false, util.NewLogger()))
+	require.Equal(t, ModeOnDemand, g.GetSettings().Mode)
+
+	hash, err := videofile.GetHashFromPath(input)
+	require.NoError(t, err)
+	require.False(t, g.IsGenerated(hash))
+
+	g.Enqueue(noVideo)
+	g.Request(input)
+	require.Eventually(t, func() bool { return g.IsGenerated(hash) }, 5*time.Second, 10*time.Millisecond)
+
+	outDir := g.GetDir(hash)
+	require.True(t, strings.HasPrefix(outDir, videofile.GetFileTrickplayCacheDir(filepath.Join(dir, "cache"), hash)))
+	vtt, err := os.ReadFile(filepath.Join(outDir, IndexFilename))
+	require.NoError(t, err)
+	// 142 thumbnails over 2 sheets
+	require.Equal(t, 142, strings.Count(string(vtt), "-->"))
+	require.FileExists(t, filepath.Join(outDir, "sprite_002.jpg"))
+	_, err = os.Stat(outDir + ".tmp")
+	require.True(t, os.IsNotExist(err))
+
+	// Changing the settings uses another directory
+	g.SetSettings(Settings{
+		Enabled:    true,
+		Width:      240,
+		FfmpegPath: ffmpegPath,
+		CacheDir:   filepath.Join(dir, "cache"),
+	}, cassette.NewGovernor(1, false, util.NewLogger()))
+	require.False(t, g.IsGenerated(hash))
+}
@@ -20,6 +20,10 @@ func GetFileAttCacheDir(outDir string, hash string) string {
 	return filepath.Join(outDir, "videofiles", hash, "att")
 }
 
+func GetFileTrickplayCacheDir(outDir string, hash string) string {
+	return filepath.Join(outDir, "videofiles", hash, "trickplay")
+}
+
 // ExtractAttachment extracts subtitles and font attachments from a media file
 // using ffmpeg. It skips extraction if the output directory already contains
 // the expected number of subtitle files.
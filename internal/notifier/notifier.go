@@ -30,6 +30,7 @@ const (
 	AutoDownloader Notification = "Auto Downloader"
 	AutoScanner    Notification = "Auto Scanner"
 	Debrid         Notification = "Debrid"
+	Usenet         Notification = "Usenet"
 )
 
 var GlobalNotifier = NewNotifier()
@@ -0,0 +1,310 @@
+package opds
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+const (
+	relAcquisition = "http://opds-spec.org/acquisition"
+	relImage       = "http://opds-spec.org/image"
+	relThumbnail   = "http://opds-spec.org/image/thumbnail"
+	relPseStream   = "http://vaemendis.net/opds-pse/stream"
+
+	typeAtomNavigation  = "application/atom+xml;profile=opds-catalog;kind=navigation"
+	typeAtomAcquisition = "application/atom+xml;profile=opds-catalog;kind=acquisition"
+	typeOpdsJSON        = "application/opds+json"
+)
+
+type feedKind int
+
+const (
+	feedKindNavigation feedKind = iota
+	feedKindAcquisition
+)
+
+type (
+	// feed is a feed independent of the OPDS version, it is written by writeFeedV1 or writeFeedV2.
+	feed struct {
+		id           string
+		title        string
+		path         string
+		up           string
+		updatedAt    time.Time
+		kind         feedKind
+		navigation   []*navigationEntry
+		publications []*publication
+	}
+
+	navigationEntry struct {
+		id        string
+		title     string
+		content   string
+		path      string
+		kind      feedKind
+		image     string
+		updatedAt time.Time
+	}
+
+	// publication is a chapter
+	publication struct {
+		id            string
+		title         string
+		series        string
+		number        string
+		updatedAt     time.Time
+		downloadPath  string
+		downloadType  string
+		thumbnailPath string
+		// pagesPath is the OPDS-PSE template of the pages, empty if the pages cannot be streamed
+		pagesPath string
+		pageType  string
+		pageCount int
+	}
+)
+
+func (k feedKind) atomType() string {
+	if k == feedKindAcquisition {
+		return typeAtomAcquisition
+	}
+	return typeAtomNavigation
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// OPDS 1.2
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type (
+	atomFeed struct {
+		XMLName      xml.Name     `xml:"feed"`
+		Xmlns        string       `xml:"xmlns,attr"`
+		XmlnsOpds    string       `xml:"xmlns:opds,attr"`
+		XmlnsPse     string       `xml:"xmlns:pse,attr"`
+		XmlnsDcterms string       `xml:"xmlns:dcterms,attr"`
+		ID           string       `xml:"id"`
+		Title        string       `xml:"title"`
+		Updated      string       `xml:"updated"`
+		Author       atomAuthor   `xml:"author"`
+		Links        []atomLink   `xml:"link"`
+		Entries      []*atomEntry `xml:"entry"`
+	}
+
+	atomAuthor struct {
+		Name string `xml:"name"`
+	}
+
+	atomEntry struct {
+		ID      string       `xml:"id"`
+		Title   string       `xml:"title"`
+		Updated string       `xml:"updated"`
+		Series  string       `xml:"dcterms:isPartOf,omitempty"`
+		Content *atomContent `xml:"content,omitempty"`
+		Links   []atomLink   `xml:"link"`
+	}
+
+	atomContent struct {
+		Type  string `xml:"type,attr"`
+		Value string `xml:",chardata"`
+	}
+
+	atomLink struct {
+		Rel      string `xml:"rel,attr,omitempty"`
+		Href     string `xml:"href,attr"`
+		Type     string `xml:"type,attr,omitempty"`
+		Title    string `xml:"title,attr,omitempty"`
+		PseCount int    `xml:"pse:count,attr,omitempty"`
+	}
+)
+
+func atomTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func newAtomFeed(f *feed) *atomFeed {
+	ret := &atomFeed{
+		Xmlns:        "http://www.w3.org/2005/Atom",
+		XmlnsOpds:    "http://opds-spec.org/2010/catalog",
+		XmlnsPse:     "http://vaemendis.net/opds-pse/ns",
+		XmlnsDcterms: "http://purl.org/dc/terms/",
+		ID:           f.id,
+		Title:        f.title,
+		Updated:      atomTime(f.updatedAt),
+		Author:       atomAuthor{Name: "Seanime"},
+		Links: []atomLink{
+			{Rel: "self", Href: f.path, Type: f.kind.atomType()},
+			{Rel: "start", Href: feedPath(feedVersion1, "catalog"), Type: typeAtomNavigation},
+		},
+	}
+	if f.up != "" {
+		ret.Links = append(ret.Links, atomLink{Rel: "up", Href: f.up, Type: typeAtomNavigation})
+	}
+
+	for _, nav := range f.navigation {
+		entry := &atomEntry{
+			ID:      nav.id,
+			Title:   nav.title,
+			Updated: atomTime(nav.updatedAt),
+			Links:   []atomLink{{Rel: "subsection", Href: nav.path, Type: nav.kind.atomType()}},
+		}
+		if nav.content != "" {
+			entry.Content = &atomContent{Type: "html", Value: nav.content}
+		}
+		if nav.image != "" {
+			entry.Links = append(entry.Links,
+				atomLink{Rel: relImage, Href: nav.image, Type: imageMimeType(nav.image)},
+				atomLink{Rel: relThumbnail, Href: nav.image, Type: imageMimeType(nav.image)},
+			)
+		}
+		ret.Entries = append(ret.Entries, entry)
+	}
+
+	for _, p := range f.publications {
+		entry := &atomEntry{
+			ID:      p.id,
+			Title:   p.title,
+			Updated: atomTime(p.updatedAt),
+			Series:  p.series,
+			Links:   []atomLink{{Rel: relAcquisition, Href: p.downloadPath, Type: p.downloadType}},
+		}
+		if p.pagesPath != "" {
+			entry.Links = append(entry.Links,
+				atomLink{Rel: relPseStream, Href: p.pagesPath, Type: p.pageType, PseCount: p.pageCount},
+				atomLink{Rel: relImage, Href: p.thumbnailPath, Type: p.pageType},
+				atomLink{Rel: relThumbnail, Href: p.thumbnailPath, Type: p.pageType},
+			)
+		}
+		ret.Entries = append(ret.Entries, entry)
+	}
+
+	return ret
+}
+
+func writeFeedV1(w http.ResponseWriter, f *feed) error {
+	w.Header().Set("Content-Type", f.kind.atomType()+";charset=utf-8")
+	return encodeAtomFeed(w, newAtomFeed(f))
+}
+
+func encodeAtomFeed(w io.Writer, f *atomFeed) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	return xml.NewEncoder(w).Encode(f)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// OPDS 2.0
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type (
+	opds2Feed struct {
+		Metadata     opds2Metadata       `json:"metadata"`
+		Links        []opds2Link         `json:"links"`
+		Navigation   []opds2Link         `json:"navigation,omitempty"`
+		Publications []*opds2Publication `json:"publications,omitempty"`
+	}
+
+	opds2Metadata struct {
+		Title         string `json:"title"`
+		Modified      string `json:"modified,omitempty"`
+		NumberOfItems int    `json:"numberOfItems,omitempty"`
+	}
+
+	opds2Link struct {
+		Href       string         `json:"href"`
+		Type       string         `json:"type,omitempty"`
+		Rel        string         `json:"rel,omitempty"`
+		Title      string         `json:"title,omitempty"`
+		Templated  bool           `json:"templated,omitempty"`
+		Properties map[string]any `json:"properties,omitempty"`
+	}
+
+	opds2Publication struct {
+		Metadata opds2PublicationMetadata `json:"metadata"`
+		Links    []opds2Link              `json:"links"`
+		Images   []opds2Link              `json:"images,omitempty"`
+	}
+
+	opds2PublicationMetadata struct {
+		Type          string          `json:"@type"`
+		Identifier    string          `json:"identifier"`
+		Title         string          `json:"title"`
+		Modified      string          `json:"modified"`
+		NumberOfPages int             `json:"numberOfPages,omitempty"`
+		BelongsTo     *opds2BelongsTo `json:"belongsTo,omitempty"`
+	}
+
+	opds2BelongsTo struct {
+		Series []opds2Contributor `json:"series"`
+	}
+
+	opds2Contributor struct {
+		Name     string  `json:"name"`
+		Position float64 `json:"position,omitempty"`
+	}
+)
+
+func newOpds2Feed(f *feed) *opds2Feed {
+	ret := &opds2Feed{
+		Metadata: opds2Metadata{
+			Title:         f.title,
+			Modified:      atomTime(f.updatedAt),
+			NumberOfItems: len(f.navigation) + len(f.publications),
+		},
+		Links: []opds2Link{
+			{Rel: "self", Href: f.path, Type: typeOpdsJSON},
+			{Rel: "start", Href: feedPath(feedVersion2, "catalog"), Type: typeOpdsJSON},
+		},
+	}
+	if f.up != "" {
+		ret.Links = append(ret.Links, opds2Link{Rel: "up", Href: f.up, Type: typeOpdsJSON})
+	}
+
+	for _, nav := range f.navigation {
+		ret.Navigation = append(ret.Navigation, opds2Link{
+			Href:  nav.path,
+			Type:  typeOpdsJSON,
+			Title: nav.title,
+			Rel:   "subsection",
+		})
+	}
+
+	for _, p := range f.publications {
+		position, _ := strconv.ParseFloat(p.number, 64)
+		pub := &opds2Publication{
+			Metadata: opds2PublicationMetadata{
+				Type:          "http://schema.org/Book",
+				Identifier:    p.id,
+				Title:         p.title,
+				Modified:      atomTime(p.updatedAt),
+				NumberOfPages: p.pageCount,
+				BelongsTo: &opds2BelongsTo{
+					Series: []opds2Contributor{{Name: p.series, Position: position}},
+				},
+			},
+			Links: []opds2Link{{Rel: relAcquisition, Href: p.downloadPath, Type: p.downloadType}},
+		}
+		if p.pagesPath != "" {
+			pub.Links = append(pub.Links, opds2Link{
+				Rel:        relPseStream,
+				Href:       p.pagesPath,
+				Type:       p.pageType,
+				Templated:  true,
+				Properties: map[string]any{"numberOfItems": p.pageCount},
+			})
+			pub.Images = []opds2Link{{Href: p.thumbnailPath, Type: p.pageType}}
+		}
+		ret.Publications = append(ret.Publications, pub)
+	}
+
+	return ret
+}
+
+func writeFeedV2(w http.ResponseWriter, f *feed) error {
+	w.Header().Set("Content-Type", typeOpdsJSON)
+	return json.NewEncoder(w).Encode(newOpds2Feed(f))
+}
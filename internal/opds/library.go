@@ -0,0 +1,196 @@
+package opds
+
+import (
+	"cmp"
+	"encoding/base64"
+	"seanime/internal/api/anilist"
+	"seanime/internal/manga"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type (
+	// Library is the content published by the catalog.
+	// It is built from the manga collection and the chapters available on disk and is only refreshed when the cache expires.
+	Library struct {
+		lists     []*libraryList
+		series    map[int]*librarySeries
+		updatedAt time.Time
+	}
+
+	libraryList struct {
+		status anilist.MediaListStatus
+		title  string
+		series []*librarySeries
+	}
+
+	librarySeries struct {
+		mediaId     int
+		listStatus  anilist.MediaListStatus
+		title       string
+		description string
+		cover       string
+		chapters    []*libraryChapter
+	}
+
+	libraryChapter struct {
+		mediaId  int
+		provider string
+		id       string
+		number   string
+		title    string
+	}
+)
+
+// listTitles are the titles of the lists, in display order
+var listTitles = []struct {
+	status anilist.MediaListStatus
+	title  string
+}{
+	{anilist.MediaListStatusCurrent, "Currently Reading"},
+	{anilist.MediaListStatusRepeating, "Rereading"},
+	{anilist.MediaListStatusPlanning, "Planning"},
+	{anilist.MediaListStatusPaused, "Paused"},
+	{anilist.MediaListStatusCompleted, "Completed"},
+	{anilist.MediaListStatusDropped, "Dropped"},
+}
+
+// NewLibrary returns the series of the collection that have downloaded or local chapters, grouped by list status.
+func NewLibrary(collection *anilist.MangaCollection, containers []*manga.ChapterContainer) *Library {
+	ret := &Library{
+		series:    make(map[int]*librarySeries),
+		updatedAt: time.Now(),
+	}
+
+	containersByMedia := make(map[int][]*manga.ChapterContainer)
+	for _, container := range containers {
+		if container == nil || len(container.Chapters) == 0 {
+			continue
+		}
+		containersByMedia[container.MediaId] = append(containersByMedia[container.MediaId], container)
+	}
+
+	if collection == nil {
+		return ret
+	}
+
+	listsByStatus := make(map[anilist.MediaListStatus]*libraryList)
+	for _, list := range collection.GetMediaListCollection().GetLists() {
+		if list == nil || list.GetStatus() == nil {
+			continue
+		}
+		for _, entry := range list.GetEntries() {
+			media := entry.GetMedia()
+			if media == nil {
+				continue
+			}
+			mediaContainers, ok := containersByMedia[media.GetID()]
+			if !ok {
+				continue
+			}
+			if _, added := ret.series[media.GetID()]; added {
+				continue
+			}
+
+			l, ok := listsByStatus[*list.GetStatus()]
+			if !ok {
+				l = &libraryList{status: *list.GetStatus()}
+				listsByStatus[l.status] = l
+			}
+
+			series := &librarySeries{
+				mediaId:    media.GetID(),
+				listStatus: l.status,
+				title:      media.GetPreferredTitle(),
+				cover:      media.GetCoverImageSafe(),
+				chapters:   newLibraryChapters(media.GetID(), mediaContainers),
+			}
+			if media.GetDescription() != nil {
+				series.description = *media.GetDescription()
+			}
+
+			l.series = append(l.series, series)
+			ret.series[series.mediaId] = series
+		}
+	}
+
+	for _, lt := range listTitles {
+		l, ok := listsByStatus[lt.status]
+		if !ok {
+			continue
+		}
+		l.title = lt.title
+		slices.SortFunc(l.series, func(a, b *librarySeries) int {
+			return cmp.Compare(strings.ToLower(a.title), strings.ToLower(b.title))
+		})
+		ret.lists = append(ret.lists, l)
+	}
+
+	return ret
+}
+
+// newLibraryChapters returns the chapters of all the providers of a series, sorted by chapter number.
+// The provider is added to the titles when the chapters come from more than one provider.
+func newLibraryChapters(mediaId int, containers []*manga.ChapterContainer) []*libraryChapter {
+	ret := make([]*libraryChapter, 0)
+	for _, container := range containers {
+		for _, ch := range container.Chapters {
+			if ch == nil {
+				continue
+			}
+			title := ch.Title
+			if title == "" {
+				title = "Chapter " + ch.Chapter
+			}
+			if len(containers) > 1 {
+				title += " (" + container.Provider + ")"
+			}
+			ret = append(ret, &libraryChapter{
+				mediaId:  mediaId,
+				provider: container.Provider,
+				id:       ch.ID,
+				number:   ch.Chapter,
+				title:    title,
+			})
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *libraryChapter) int {
+		numA, _ := strconv.ParseFloat(a.number, 64)
+		numB, _ := strconv.ParseFloat(b.number, 64)
+		return cmp.Or(cmp.Compare(numA, numB), cmp.Compare(a.provider, b.provider))
+	})
+
+	return ret
+}
+
+func (l *Library) getList(status string) (*libraryList, bool) {
+	for _, list := range l.lists {
+		if strings.EqualFold(string(list.status), status) {
+			return list, true
+		}
+	}
+	return nil, false
+}
+
+func (l *Library) getSeries(mediaId int) (*librarySeries, bool) {
+	series, ok := l.series[mediaId]
+	return series, ok
+}
+
+// getChapter returns a chapter from the parameters of its URL.
+func (s *librarySeries) getChapter(provider string, key string) (*libraryChapter, bool) {
+	for _, ch := range s.chapters {
+		if ch.provider == provider && ch.key() == key {
+			return ch, true
+		}
+	}
+	return nil, false
+}
+
+// key encodes the chapter ID so that it can be used in a URL path, IDs of local chapters are file paths.
+func (ch *libraryChapter) key() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(ch.id))
+}
@@ -0,0 +1,444 @@
+package opds
+
+import (
+	"archive/zip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	"seanime/internal/manga"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// BasePath is the path under which the catalog is served
+	BasePath = "/api/v1/opds"
+
+	v1Path      = BasePath + "/v1.2"
+	v2Path      = BasePath + "/v2"
+	chapterPath = BasePath + "/chapters"
+)
+
+var (
+	// LibraryCacheTTL is the duration for which the published library is cached
+	LibraryCacheTTL = 30 * time.Second
+)
+
+type (
+	// Catalog is an OPDS server publishing the downloaded chapters and the chapters of the local provider to e-readers.
+	// It serves OPDS 1.2 (Atom) and OPDS 2.0 (JSON) feeds, chapters as CBZ archives and single pages for OPDS-PSE streaming.
+	// Authentication is handled by the server.
+	Catalog struct {
+		logger          *zerolog.Logger
+		getContent      GetContentFunc
+		getChapterFiles GetChapterFilesFunc
+		handler         http.Handler
+
+		libraryMu      sync.Mutex
+		library        *Library
+		libraryExpires time.Time
+	}
+
+	// GetContentFunc returns the manga collection and the chapter containers of the chapters available on disk.
+	GetContentFunc func(ctx context.Context) (*anilist.MangaCollection, []*manga.ChapterContainer, error)
+
+	// GetChapterFilesFunc returns the files of a chapter available on disk.
+	GetChapterFilesFunc func(provider string, mediaId int, chapterId string) (*manga.ChapterFiles, error)
+
+	NewCatalogOptions struct {
+		Logger          *zerolog.Logger
+		GetContent      GetContentFunc
+		GetChapterFiles GetChapterFilesFunc
+	}
+)
+
+func NewCatalog(opts *NewCatalogOptions) *Catalog {
+	ret := &Catalog{
+		logger:          opts.Logger,
+		getContent:      opts.GetContent,
+		getChapterFiles: opts.GetChapterFiles,
+	}
+	ret.handler = ret.newHandler()
+	return ret
+}
+
+// RefreshLibrary makes the next request rebuild the library.
+func (c *Catalog) RefreshLibrary() {
+	c.libraryMu.Lock()
+	defer c.libraryMu.Unlock()
+	c.libraryExpires = time.Time{}
+}
+
+func (c *Catalog) getLibrary(ctx context.Context) (*Library, error) {
+	c.libraryMu.Lock()
+	defer c.libraryMu.Unlock()
+
+	if c.library != nil && time.Now().Before(c.libraryExpires) {
+		return c.library, nil
+	}
+
+	collection, containers, err := c.getContent(ctx)
+	if err != nil {
+		if c.library != nil {
+			return c.library, nil
+		}
+		return nil, err
+	}
+
+	c.library = NewLibrary(collection, containers)
+	c.libraryExpires = time.Now().Add(LibraryCacheTTL)
+
+	return c.library, nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Handler returns the HTTP handler serving the feeds, the chapters and the pages.
+func (c *Catalog) Handler() http.Handler {
+	return c.handler
+}
+
+func (c *Catalog) newHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET "+BasePath, c.handleRoot)
+	mux.HandleFunc("GET "+BasePath+"/{$}", c.handleRoot)
+	mux.HandleFunc("GET "+v1Path+"/catalog", c.handleFeed(feedVersion1, c.rootFeed))
+	mux.HandleFunc("GET "+v1Path+"/lists/{status}", c.handleFeed(feedVersion1, c.listFeed))
+	mux.HandleFunc("GET "+v1Path+"/series/{mediaId}", c.handleFeed(feedVersion1, c.seriesFeed))
+	mux.HandleFunc("GET "+v2Path+"/catalog", c.handleFeed(feedVersion2, c.rootFeed))
+	mux.HandleFunc("GET "+v2Path+"/lists/{status}", c.handleFeed(feedVersion2, c.listFeed))
+	mux.HandleFunc("GET "+v2Path+"/series/{mediaId}", c.handleFeed(feedVersion2, c.seriesFeed))
+	mux.HandleFunc("GET "+chapterPath+"/{mediaId}/{provider}/{chapter}/download", c.handleDownload)
+	mux.HandleFunc("GET "+chapterPath+"/{mediaId}/{provider}/{chapter}/pages/{page}", c.handlePage)
+	return mux
+}
+
+// handleRoot redirects to the OPDS 1.2 catalog, which is supported by most readers.
+func (c *Catalog) handleRoot(w http.ResponseWriter, r *http.Request) {
+	target := v1Path + "/catalog"
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+type feedVersion int
+
+const (
+	feedVersion1 feedVersion = iota + 1
+	feedVersion2
+)
+
+// feedBuilder returns the feed of a request, or false if it does not exist.
+type feedBuilder func(r *http.Request, library *Library, version feedVersion) (*feed, bool)
+
+func (c *Catalog) handleFeed(version feedVersion, build feedBuilder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		library, err := c.getLibrary(r.Context())
+		if err != nil {
+			c.logger.Error().Err(err).Msg("opds: Failed to get library")
+			http.Error(w, "failed to get library", http.StatusInternalServerError)
+			return
+		}
+
+		f, ok := build(r, library, version)
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		switch version {
+		case feedVersion2:
+			err = writeFeedV2(w, f)
+		default:
+			err = writeFeedV1(w, f)
+		}
+		if err != nil {
+			c.logger.Error().Err(err).Msg("opds: Failed to write feed")
+		}
+	}
+}
+
+func (c *Catalog) rootFeed(_ *http.Request, library *Library, version feedVersion) (*feed, bool) {
+	ret := &feed{
+		id:        "urn:seanime:opds:catalog",
+		title:     "Seanime",
+		path:      feedPath(version, "catalog"),
+		updatedAt: library.updatedAt,
+		kind:      feedKindNavigation,
+	}
+	for _, list := range library.lists {
+		ret.navigation = append(ret.navigation, &navigationEntry{
+			id:        "urn:seanime:opds:list:" + strings.ToLower(string(list.status)),
+			title:     list.title,
+			content:   fmt.Sprintf("%d series", len(list.series)),
+			path:      feedPath(version, "lists/"+strings.ToLower(string(list.status))),
+			kind:      feedKindNavigation,
+			updatedAt: library.updatedAt,
+		})
+	}
+	return ret, true
+}
+
+func (c *Catalog) listFeed(r *http.Request, library *Library, version feedVersion) (*feed, bool) {
+	list, ok := library.getList(r.PathValue("status"))
+	if !ok {
+		return nil, false
+	}
+
+	ret := &feed{
+		id:        "urn:seanime:opds:list:" + strings.ToLower(string(list.status)),
+		title:     list.title,
+		path:      feedPath(version, "lists/"+strings.ToLower(string(list.status))),
+		updatedAt: library.updatedAt,
+		kind:      feedKindNavigation,
+		up:        feedPath(version, "catalog"),
+	}
+	for _, series := range list.series {
+		ret.navigation = append(ret.navigation, &navigationEntry{
+			id:        "urn:seanime:opds:series:" + strconv.Itoa(series.mediaId),
+			title:     series.title,
+			content:   series.description,
+			path:      feedPath(version, "series/"+strconv.Itoa(series.mediaId)),
+			kind:      feedKindAcquisition,
+			image:     series.cover,
+			updatedAt: library.updatedAt,
+		})
+	}
+	return ret, true
+}
+
+func (c *Catalog) seriesFeed(r *http.Request, library *Library, version feedVersion) (*feed, bool) {
+	mediaId, err := strconv.Atoi(r.PathValue("mediaId"))
+	if err != nil {
+		return nil, false
+	}
+	series, ok := library.getSeries(mediaId)
+	if !ok {
+		return nil, false
+	}
+
+	ret := &feed{
+		id:        "urn:seanime:opds:series:" + strconv.Itoa(series.mediaId),
+		title:     series.title,
+		path:      feedPath(version, "series/"+strconv.Itoa(series.mediaId)),
+		updatedAt: library.updatedAt,
+		kind:      feedKindAcquisition,
+		up:        feedPath(version, "lists/"+strings.ToLower(string(series.listStatus))),
+	}
+	for _, ch := range series.chapters {
+		files, err := c.getChapterFiles(ch.provider, ch.mediaId, ch.id)
+		if err != nil {
+			// The chapter was deleted since the library was built
+			c.logger.Debug().Err(err).Str("chapterId", ch.id).Msg("opds: Skipping chapter")
+			continue
+		}
+
+		p := &publication{
+			id:           "urn:seanime:opds:chapter:" + ch.provider + ":" + strconv.Itoa(ch.mediaId) + ":" + ch.key(),
+			title:        ch.title,
+			series:       series.title,
+			number:       ch.number,
+			updatedAt:    files.ModTime,
+			downloadPath: chapterURL(ch, "download"),
+			downloadType: downloadMimeType(files.Format),
+			pageCount:    files.PageCount(),
+		}
+		if p.pageCount > 0 {
+			p.pagesPath = chapterURL(ch, "pages/{pageNumber}")
+			firstPage, _ := files.PageName(0)
+			p.pageType = imageMimeType(firstPage)
+			p.thumbnailPath = chapterURL(ch, "pages/0")
+		}
+		ret.publications = append(ret.publications, p)
+	}
+	return ret, true
+}
+
+func feedPath(version feedVersion, path string) string {
+	if version == feedVersion2 {
+		return v2Path + "/" + path
+	}
+	return v1Path + "/" + path
+}
+
+func chapterURL(ch *libraryChapter, path string) string {
+	return chapterPath + "/" + strconv.Itoa(ch.mediaId) + "/" + ch.provider + "/" + ch.key() + "/" + path
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (c *Catalog) getRequestedChapter(w http.ResponseWriter, r *http.Request) (*librarySeries, *libraryChapter, *manga.ChapterFiles, bool) {
+	mediaId, err := strconv.Atoi(r.PathValue("mediaId"))
+	if err != nil {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+
+	library, err := c.getLibrary(r.Context())
+	if err != nil {
+		c.logger.Error().Err(err).Msg("opds: Failed to get library")
+		http.Error(w, "failed to get library", http.StatusInternalServerError)
+		return nil, nil, nil, false
+	}
+
+	series, ok := library.getSeries(mediaId)
+	if !ok {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+	ch, ok := series.getChapter(r.PathValue("provider"), r.PathValue("chapter"))
+	if !ok {
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+
+	files, err := c.getChapterFiles(ch.provider, ch.mediaId, ch.id)
+	if err != nil {
+		c.logger.Warn().Err(err).Str("chapterId", ch.id).Msg("opds: Failed to get chapter files")
+		http.NotFound(w, r)
+		return nil, nil, nil, false
+	}
+
+	return series, ch, files, true
+}
+
+// handleDownload serves a chapter, directories of images are streamed as CBZ archives.
+func (c *Catalog) handleDownload(w http.ResponseWriter, r *http.Request) {
+	series, ch, files, ok := c.getRequestedChapter(w, r)
+	if !ok {
+		return
+	}
+
+	filename := sanitizeFilename(series.title + " - " + ch.title)
+
+	if files.Format != manga.ChapterFormatImages {
+		f, err := os.Open(files.Path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer f.Close()
+
+		w.Header().Set("Content-Type", downloadMimeType(files.Format))
+		w.Header().Set("Content-Disposition", contentDisposition(filename+filepath.Ext(files.Path)))
+		http.ServeContent(w, r, "", files.ModTime, f)
+		return
+	}
+
+	w.Header().Set("Content-Type", downloadMimeType(files.Format))
+	w.Header().Set("Content-Disposition", contentDisposition(filename+".cbz"))
+	w.Header().Set("Last-Modified", files.ModTime.UTC().Format(http.TimeFormat))
+
+	if err := writeCBZ(w, files); err != nil {
+		// The response has already started, the reader will get a truncated archive
+		c.logger.Warn().Err(err).Str("chapterId", ch.id).Msg("opds: Failed to stream chapter")
+	}
+}
+
+// writeCBZ writes the pages of a chapter as a CBZ archive.
+// Images are already compressed, so they are stored as is.
+func writeCBZ(w io.Writer, files *manga.ChapterFiles) error {
+	zw := zip.NewWriter(w)
+
+	i := 0
+	err := files.WalkPages(func(name string, r io.Reader) error {
+		fw, err := zw.CreateHeader(&zip.FileHeader{
+			// Prefix the pages with their index so that readers sorting by name keep the order
+			Name:     fmt.Sprintf("%04d%s", i+1, strings.ToLower(filepath.Ext(name))),
+			Method:   zip.Store,
+			Modified: files.ModTime,
+		})
+		if err != nil {
+			return err
+		}
+		i++
+		_, err = io.Copy(fw, r)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// handlePage serves a single page for OPDS-PSE, pages are numbered from 0.
+func (c *Catalog) handlePage(w http.ResponseWriter, r *http.Request) {
+	_, _, files, ok := c.getRequestedChapter(w, r)
+	if !ok {
+		return
+	}
+
+	index, err := strconv.Atoi(r.PathValue("page"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	name, ok := files.PageName(index)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	page, err := files.OpenPage(index)
+	if err != nil {
+		if errors.Is(err, manga.ErrChapterPagesUnavailable) {
+			http.NotFound(w, r)
+			return
+		}
+		c.logger.Warn().Err(err).Msg("opds: Failed to open page")
+		http.Error(w, "failed to open page", http.StatusInternalServerError)
+		return
+	}
+	defer page.Close()
+
+	w.Header().Set("Content-Type", imageMimeType(name))
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	_, _ = io.Copy(w, page)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func downloadMimeType(format manga.ChapterFormat) string {
+	switch format {
+	case manga.ChapterFormatCBR:
+		return "application/vnd.comicbook-rar"
+	case manga.ChapterFormatPDF:
+		return "application/pdf"
+	default:
+		return "application/vnd.comicbook+zip"
+	}
+}
+
+func imageMimeType(name string) string {
+	if t := mime.TypeByExtension(strings.ToLower(filepath.Ext(name))); strings.HasPrefix(t, "image/") {
+		return t
+	}
+	return "image/jpeg"
+}
+
+func contentDisposition(filename string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": filename})
+}
+
+// sanitizeFilename removes the characters that are not allowed in file names.
+func sanitizeFilename(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '/', '\\', ':', '*', '?', '"', '<', '>', '|':
+			return '_'
+		}
+		return r
+	}, name)
+}
@@ -0,0 +1,217 @@
+package opds
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"seanime/internal/api/anilist"
+	hibikemanga "seanime/internal/extension/hibike/manga"
+	"seanime/internal/manga"
+	chapter_downloader "seanime/internal/manga/downloader"
+	"seanime/internal/util"
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-json"
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/require"
+)
+
+// writeDownloadedChapter writes a downloaded chapter like the chapter downloader does.
+func writeDownloadedChapter(t *testing.T, downloadDir string, provider string, mediaId int, chapterId string, number string, pages []string) {
+	dir := filepath.Join(downloadDir, chapter_downloader.FormatChapterDirName(provider, mediaId, chapterId, number))
+	require.NoError(t, os.MkdirAll(dir, 0755))
+
+	registry := make(chapter_downloader.Registry)
+	for i, page := range pages {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, page), []byte("page-"+page), 0644))
+		registry[i] = chapter_downloader.PageInfo{Index: i, Filename: page}
+	}
+	data, err := json.Marshal(registry)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "registry.json"), data, 0644))
+}
+
+func newTestCatalog(t *testing.T) *Catalog {
+	downloadDir := t.TempDir()
+	writeDownloadedChapter(t, downloadDir, "comick", 30013, "ch_2", "2", []string{"01.jpg", "02.png"})
+	writeDownloadedChapter(t, downloadDir, "comick", 30013, "ch_1", "1", []string{"01.jpg", "02.jpg", "03.jpg"})
+
+	logger := util.NewLogger()
+	repository := manga.NewRepository(&manga.NewRepositoryOptions{
+		Logger:      logger,
+		DownloadDir: downloadDir,
+	})
+
+	newEntry := func(id int, title string) *anilist.MangaCollection_MediaListCollection_Lists_Entries {
+		return &anilist.MangaCollection_MediaListCollection_Lists_Entries{
+			Media: &anilist.BaseManga{
+				ID:          id,
+				Title:       &anilist.BaseManga_Title{UserPreferred: lo.ToPtr(title)},
+				CoverImage:  &anilist.BaseManga_CoverImage{Large: lo.ToPtr(fmt.Sprintf("https://img.anili.st/%d.jpg", id))},
+				Description: lo.ToPtr("The <i>description</i>"),
+			},
+		}
+	}
+
+	collection := &anilist.MangaCollection{
+		MediaListCollection: &anilist.MangaCollection_MediaListCollection{
+			Lists: []*anilist.MangaCollection_MediaListCollection_Lists{
+				{
+					Status: lo.ToPtr(anilist.MediaListStatusCurrent),
+					Entries: []*anilist.MangaCollection_MediaListCollection_Lists_Entries{
+						newEntry(30013, "One Piece"),
+						// No chapters on disk
+						newEntry(30002, "Berserk"),
+					},
+				},
+			},
+		},
+	}
+
+	containers := []*manga.ChapterContainer{
+		{
+			MediaId:  30013,
+			Provider: "comick",
+			Chapters: []*hibikemanga.ChapterDetails{
+				{Provider: "comick", ID: "ch_2", Title: "Chapter 2", Chapter: "2"},
+				{Provider: "comick", ID: "ch_1", Title: "Chapter 1", Chapter: "1"},
+			},
+		},
+	}
+
+	return NewCatalog(&NewCatalogOptions{
+		Logger: logger,
+		GetContent: func(ctx context.Context) (*anilist.MangaCollection, []*manga.ChapterContainer, error) {
+			return collection, containers, nil
+		},
+		GetChapterFiles: repository.GetChapterFiles,
+	})
+}
+
+type testAtomFeed struct {
+	Title string `xml:"title"`
+	Links []struct {
+		Rel  string `xml:"rel,attr"`
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Rel   string `xml:"rel,attr"`
+			Href  string `xml:"href,attr"`
+			Type  string `xml:"type,attr"`
+			Count int    `xml:"http://vaemendis.net/opds-pse/ns count,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+func get(t *testing.T, handler http.Handler, path string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec
+}
+
+func getAtomFeed(t *testing.T, handler http.Handler, path string) *testAtomFeed {
+	rec := get(t, handler, path)
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.Contains(t, rec.Header().Get("Content-Type"), "application/atom+xml")
+
+	var ret testAtomFeed
+	require.NoError(t, xml.Unmarshal(rec.Body.Bytes(), &ret))
+	return &ret
+}
+
+func TestFeedV1(t *testing.T) {
+	handler := newTestCatalog(t).Handler()
+
+	rec := get(t, handler, BasePath)
+	require.Equal(t, http.StatusFound, rec.Code)
+	require.Equal(t, v1Path+"/catalog", rec.Header().Get("Location"))
+
+	root := getAtomFeed(t, handler, v1Path+"/catalog")
+	require.Len(t, root.Entries, 1)
+	require.Equal(t, "Currently Reading", root.Entries[0].Title)
+	require.Equal(t, v1Path+"/lists/current", root.Entries[0].Links[0].Href)
+
+	list := getAtomFeed(t, handler, v1Path+"/lists/current")
+	require.Len(t, list.Entries, 1)
+	require.Equal(t, "One Piece", list.Entries[0].Title)
+	require.Equal(t, v1Path+"/series/30013", list.Entries[0].Links[0].Href)
+	require.Equal(t, "https://img.anili.st/30013.jpg", list.Entries[0].Links[2].Href)
+
+	series := getAtomFeed(t, handler, v1Path+"/series/30013")
+	require.Len(t, series.Entries, 2)
+	require.Equal(t, "Chapter 1", series.Entries[0].Title)
+	require.Equal(t, "Chapter 2", series.Entries[1].Title)
+
+	links := series.Entries[0].Links
+	require.Equal(t, relAcquisition, links[0].Rel)
+	require.Equal(t, "application/vnd.comicbook+zip", links[0].Type)
+	require.Equal(t, relPseStream, links[1].Rel)
+	require.True(t, strings.HasSuffix(links[1].Href, "/pages/{pageNumber}"))
+	require.Equal(t, 3, links[1].Count)
+
+	require.Equal(t, http.StatusNotFound, get(t, handler, v1Path+"/series/30002").Code)
+	require.Equal(t, http.StatusNotFound, get(t, handler, v1Path+"/lists/dropped").Code)
+}
+
+func TestFeedV2(t *testing.T) {
+	handler := newTestCatalog(t).Handler()
+
+	rec := get(t, handler, v2Path+"/series/30013")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, typeOpdsJSON, rec.Header().Get("Content-Type"))
+
+	var f opds2Feed
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &f))
+	require.Len(t, f.Publications, 2)
+	require.Equal(t, "Chapter 1", f.Publications[0].Metadata.Title)
+	require.Equal(t, 3, f.Publications[0].Metadata.NumberOfPages)
+	require.Equal(t, 1.0, f.Publications[0].Metadata.BelongsTo.Series[0].Position)
+	require.Len(t, f.Publications[0].Images, 1)
+}
+
+func TestDownloadAndPages(t *testing.T) {
+	handler := newTestCatalog(t).Handler()
+
+	series := getAtomFeed(t, handler, v1Path+"/series/30013")
+	chapter2 := series.Entries[1].Links
+
+	// The chapter is streamed as a CBZ archive
+	rec := get(t, handler, chapter2[0].Href)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Header().Get("Content-Disposition"), "One Piece - Chapter 2.cbz")
+
+	zr, err := zip.NewReader(bytes.NewReader(rec.Body.Bytes()), int64(rec.Body.Len()))
+	require.NoError(t, err)
+	require.Len(t, zr.File, 2)
+	require.Equal(t, "0001.jpg", zr.File[0].Name)
+	require.Equal(t, "0002.png", zr.File[1].Name)
+	f, err := zr.File[1].Open()
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.Equal(t, "page-02.png", string(data))
+
+	// Pages are numbered from 0
+	pagePath := strings.Replace(chapter2[1].Href, "{pageNumber}", "1", 1)
+	rec = get(t, handler, pagePath)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+	require.Equal(t, "page-02.png", rec.Body.String())
+
+	rec = get(t, handler, strings.Replace(chapter2[1].Href, "{pageNumber}", "2", 1))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Unknown chapter
+	rec = get(t, handler, chapterPath+"/30013/comick/bm90LWZvdW5k/download")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -0,0 +1,925 @@
+package mal_platform
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+	"seanime/internal/customsource"
+	"seanime/internal/database/db"
+	"seanime/internal/extension"
+	"seanime/internal/hook"
+	"seanime/internal/platforms/platform"
+	"seanime/internal/platforms/shared_platform"
+	"seanime/internal/util"
+	"seanime/internal/util/limiter"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/lo"
+)
+
+var (
+	// ErrMediaNotFound means the media isn't in the MAL list
+	ErrMediaNotFound = errors.New("media not found")
+	// ErrNoMalID means the AniList media has no MAL counterpart
+	ErrNoMalID = errors.New("media is not on MyAnimeList")
+)
+
+// MalPlatform is used when the user keeps their list on MyAnimeList instead of AniList.
+// Collections are built from the MAL list, with the metadata of the matching AniList media,
+// so the rest of the app keeps working with AniList IDs.
+// List updates are sent to MAL.
+type MalPlatform struct {
+	logger *zerolog.Logger
+	client anilist.AnilistClient // should only receive an unauthenticated client
+	helper *shared_platform.PlatformHelper
+	db     *db.Database
+	// getWrapper returns a MAL client with a valid token
+	getWrapper func() (*mal.Wrapper, error)
+
+	mu                   sync.RWMutex
+	animeCollection      *anilist.AnimeCollection
+	mangaCollection      *anilist.MangaCollection
+	animeCollectionStale bool // set when the list has been modified
+	mangaCollectionStale bool // set when the list has been modified
+	// AniList media keyed by MAL ID, resolved once per refresh
+	animeByMalID   map[int]*anilist.BaseAnime
+	mangaByMalID   map[int]*anilist.BaseManga
+	unmatchedAnime map[int]struct{} // MAL IDs without AniList media
+	unmatchedManga map[int]struct{} // MAL IDs without AniList media
+
+	fetchMu          sync.Mutex // prevents concurrent list fetches
+	resolveRateLimit *limiter.Limiter
+}
+
+func NewMalPlatform(client *util.Ref[anilist.AnilistClient], extensionBankRef *util.Ref[*extension.UnifiedBank], logger *zerolog.Logger, db *db.Database) platform.Platform {
+	mp := &MalPlatform{
+		logger:           logger,
+		client:           shared_platform.NewCacheLayer(client),
+		helper:           shared_platform.NewPlatformHelper(extensionBankRef, db, logger),
+		db:               db,
+		animeByMalID:     make(map[int]*anilist.BaseAnime),
+		mangaByMalID:     make(map[int]*anilist.BaseManga),
+		unmatchedAnime:   make(map[int]struct{}),
+		unmatchedManga:   make(map[int]struct{}),
+		resolveRateLimit: limiter.NewAnilistLimiter(),
+	}
+	mp.getWrapper = mp.newWrapper
+
+	return mp
+}
+
+func (mp *MalPlatform) newWrapper() (*mal.Wrapper, error) {
+	malInfo, err := mp.db.GetMalInfo()
+	if err != nil {
+		return nil, err
+	}
+
+	malInfo, err = mal.VerifyMALAuth(malInfo, mp.db, mp.logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return mal.NewWrapper(malInfo.AccessToken, mp.logger), nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Implementation
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (mp *MalPlatform) SetUsername(username string) {
+	// no-op
+}
+
+func (mp *MalPlatform) Close() {
+	mp.helper.Close()
+}
+
+func (mp *MalPlatform) ClearCache() {
+	mp.helper.ClearCache()
+}
+
+func (mp *MalPlatform) GetCustomSourceManager() *customsource.Manager {
+	return mp.helper.GetCustomSourceManager()
+}
+
+// UpdateEntry updates the MAL list entry of the given media ID.
+// If the entry doesn't exist, it is added to the list.
+func (mp *MalPlatform) UpdateEntry(ctx context.Context, mediaID int, status *anilist.MediaListStatus, scoreRaw *int, progress *int, startedAt *anilist.FuzzyDateInput, completedAt *anilist.FuzzyDateInput) error {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Updating entry")
+
+	return mp.helper.TriggerUpdateEntryHooks(ctx, mediaID, status, scoreRaw, progress, startedAt, completedAt, func(event *platform.PreUpdateEntryEvent) error {
+		// Check if this is a custom source entry (after hooks have been triggered)
+		if handled, err := mp.helper.HandleCustomSourceUpdateEntry(ctx, mediaID, event.Status, event.ScoreRaw, event.Progress, event.StartedAt, event.CompletedAt); handled {
+			return err
+		}
+
+		media, err := mp.findMedia(ctx, mediaID)
+		if err != nil {
+			return err
+		}
+
+		update := listUpdate{
+			status:     event.Status,
+			scoreRaw:   event.ScoreRaw,
+			progress:   event.Progress,
+			startDate:  formatMalDate(event.StartedAt),
+			finishDate: formatMalDate(event.CompletedAt),
+		}
+		if !media.inList && update.status == nil {
+			update.status = new(anilist.MediaListStatusPlanning)
+		}
+
+		return mp.updateListStatus(media, update)
+	})
+}
+
+func (mp *MalPlatform) UpdateEntryProgress(ctx context.Context, mediaID int, progress int, totalEpisodes *int) error {
+	mp.logger.Trace().Int("mediaID", mediaID).Int("progress", progress).Msg("mal platform: Updating entry progress")
+
+	return mp.helper.TriggerUpdateEntryProgressHooks(ctx, mediaID, progress, totalEpisodes, func(event *platform.PreUpdateEntryProgressEvent) error {
+		// Check if this is a custom source entry (after hooks have been triggered)
+		if handled, err := mp.helper.HandleCustomSourceUpdateEntryProgress(ctx, mediaID, *event.Progress, event.TotalCount); handled {
+			return err
+		}
+
+		media, err := mp.findMedia(ctx, mediaID)
+		if err != nil {
+			return err
+		}
+
+		// MAL doesn't fill the dates automatically
+		status := anilist.MediaListStatusCurrent
+		if media.status == anilist.MediaListStatusRepeating {
+			status = anilist.MediaListStatusRepeating
+		}
+		update := listUpdate{
+			status:   &status,
+			progress: event.Progress,
+		}
+		if !media.hasStartDate && *event.Progress > 0 {
+			update.startDate = new(today())
+		}
+		if event.TotalCount != nil && *event.TotalCount > 0 && *event.Progress >= *event.TotalCount {
+			update.status = new(anilist.MediaListStatusCompleted)
+			if !media.hasFinishDate || media.status == anilist.MediaListStatusRepeating {
+				update.finishDate = new(today())
+			}
+		}
+
+		return mp.updateListStatus(media, update)
+	})
+}
+
+func (mp *MalPlatform) UpdateEntryRepeat(ctx context.Context, mediaID int, repeat int) error {
+	mp.logger.Trace().Int("mediaID", mediaID).Int("repeat", repeat).Msg("mal platform: Updating entry repeat")
+
+	return mp.helper.TriggerUpdateEntryRepeatHooks(ctx, mediaID, repeat, func(event *platform.PreUpdateEntryRepeatEvent) error {
+		// Check if this is a custom source entry (after hooks have been triggered)
+		if handled, err := mp.helper.HandleCustomSourceUpdateEntryRepeat(ctx, mediaID, *event.Repeat); handled {
+			return err
+		}
+
+		media, err := mp.findMedia(ctx, mediaID)
+		if err != nil {
+			return err
+		}
+		if !media.inList {
+			return ErrMediaNotFound
+		}
+
+		return mp.updateListStatus(media, listUpdate{repeat: event.Repeat})
+	})
+}
+
+func (mp *MalPlatform) DeleteEntry(ctx context.Context, mediaId, entryId int) error {
+	mp.logger.Trace().Int("entryId", entryId).Int("mediaId", mediaId).Msg("mal platform: Deleting entry")
+
+	return mp.helper.TriggerDeleteEntryHooks(ctx, mediaId, entryId, func(event *platform.PreDeleteEntryEvent) error {
+		if handled, err := mp.helper.HandleCustomSourceDeleteEntry(ctx, *event.MediaID, *event.EntryID); handled {
+			return err
+		}
+
+		media, err := mp.findMedia(ctx, *event.MediaID)
+		if err != nil {
+			return err
+		}
+		if !media.inList {
+			return ErrMediaNotFound
+		}
+
+		wrapper, err := mp.getWrapper()
+		if err != nil {
+			return err
+		}
+
+		if media.isAnime {
+			err = wrapper.DeleteAnimeListItem(media.malID)
+		} else {
+			err = wrapper.DeleteMangaListItem(media.malID)
+		}
+		if err != nil {
+			return err
+		}
+
+		mp.markStale(media.isAnime)
+		return nil
+	})
+}
+
+func (mp *MalPlatform) GetAnime(ctx context.Context, mediaID int) (*anilist.BaseAnime, error) {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Getting anime")
+
+	if cachedAnime, ok := mp.helper.GetCachedBaseAnime(mediaID); ok {
+		mp.logger.Trace().Msg("mal platform: Returning anime from cache")
+		return mp.helper.TriggerGetAnimeEvent(cachedAnime)
+	}
+
+	// Check if this is a custom source entry
+	if media, isCustom, err := mp.helper.HandleCustomSourceAnime(ctx, mediaID); isCustom {
+		if err != nil {
+			return nil, err
+		}
+
+		triggeredMedia, err := mp.helper.TriggerGetAnimeEvent(media)
+		if err != nil {
+			return nil, err
+		}
+
+		mp.helper.SetCachedBaseAnime(mediaID, triggeredMedia)
+		return triggeredMedia, nil
+	}
+
+	resp, err := mp.client.BaseAnimeByID(ctx, &mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	triggeredMedia, err := mp.helper.TriggerGetAnimeEvent(resp.GetMedia())
+	if err != nil {
+		return nil, err
+	}
+
+	mp.helper.SetCachedBaseAnime(mediaID, triggeredMedia)
+	return triggeredMedia, nil
+}
+
+func (mp *MalPlatform) GetAnimeByMalID(ctx context.Context, malID int) (*anilist.BaseAnime, error) {
+	mp.logger.Trace().Int("malID", malID).Msg("mal platform: Getting anime by MAL ID")
+
+	mp.mu.RLock()
+	media, ok := mp.animeByMalID[malID]
+	mp.mu.RUnlock()
+	if ok {
+		return mp.helper.TriggerGetAnimeEvent(media)
+	}
+
+	resp, err := mp.client.BaseAnimeByMalID(ctx, &malID)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.helper.TriggerGetAnimeEvent(resp.GetMedia())
+}
+
+func (mp *MalPlatform) GetAnimeDetails(ctx context.Context, mediaID int) (*anilist.AnimeDetailsById_Media, error) {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Getting anime details")
+
+	// Check if this is a custom source entry
+	if media, isCustom, err := mp.helper.HandleCustomSourceAnimeDetails(ctx, mediaID); isCustom {
+		if err != nil {
+			return nil, err
+		}
+		return mp.helper.TriggerGetAnimeDetailsEvent(media)
+	}
+
+	resp, err := mp.client.AnimeDetailsByID(ctx, &mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.helper.TriggerGetAnimeDetailsEvent(resp.GetMedia())
+}
+
+func (mp *MalPlatform) GetAnimeWithRelations(ctx context.Context, mediaID int) (*anilist.CompleteAnime, error) {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Getting anime with relations")
+
+	if cachedAnime, ok := mp.helper.GetCachedCompleteAnime(mediaID); ok {
+		mp.logger.Trace().Msg("mal platform: Cache HIT for anime with relations")
+		return cachedAnime, nil
+	}
+
+	// Check if this is a custom source entry
+	if media, isCustom, err := mp.helper.HandleCustomSourceAnimeWithRelations(ctx, mediaID); isCustom {
+		if err != nil {
+			return nil, err
+		}
+		mp.helper.SetCachedCompleteAnime(mediaID, media)
+		return media, nil
+	}
+
+	resp, err := mp.client.CompleteAnimeByID(ctx, &mediaID)
+	if err != nil {
+		return nil, err
+	}
+	media := resp.GetMedia()
+
+	mp.helper.SetCachedCompleteAnime(mediaID, media)
+	return media, nil
+}
+
+func (mp *MalPlatform) GetManga(ctx context.Context, mediaID int) (*anilist.BaseManga, error) {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Getting manga")
+
+	if cachedManga, ok := mp.helper.GetCachedBaseManga(mediaID); ok {
+		mp.logger.Trace().Msg("mal platform: Returning manga from cache")
+		return mp.helper.TriggerGetMangaEvent(cachedManga)
+	}
+
+	// Check if this is a custom source entry
+	if media, isCustom, err := mp.helper.HandleCustomSourceManga(ctx, mediaID); isCustom {
+		if err != nil {
+			return nil, err
+		}
+
+		triggeredMedia, err := mp.helper.TriggerGetMangaEvent(media)
+		if err != nil {
+			return nil, err
+		}
+
+		mp.helper.SetCachedBaseManga(mediaID, triggeredMedia)
+		return triggeredMedia, nil
+	}
+
+	resp, err := mp.client.BaseMangaByID(ctx, &mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	triggeredMedia, err := mp.helper.TriggerGetMangaEvent(resp.GetMedia())
+	if err != nil {
+		return nil, err
+	}
+
+	mp.helper.SetCachedBaseManga(mediaID, triggeredMedia)
+	return triggeredMedia, nil
+}
+
+func (mp *MalPlatform) GetMangaDetails(ctx context.Context, mediaID int) (*anilist.MangaDetailsById_Media, error) {
+	mp.logger.Trace().Int("mediaID", mediaID).Msg("mal platform: Getting manga details")
+
+	// Check if this is a custom source entry
+	if media, isCustom, err := mp.helper.HandleCustomSourceMangaDetails(ctx, mediaID); isCustom {
+		return media, err
+	}
+
+	resp, err := mp.client.MangaDetailsByID(ctx, &mediaID)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.GetMedia(), nil
+}
+
+func (mp *MalPlatform) GetAnimeCollection(ctx context.Context, bypassCache bool) (*anilist.AnimeCollection, error) {
+	mp.logger.Trace().Bool("bypassCache", bypassCache).Msg("mal platform: Getting anime collection")
+
+	collection, cached, err := mp.getAnimeCollection(ctx, bypassCache, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		event := new(platform.GetCachedAnimeCollectionEvent)
+		event.AnimeCollection = collection
+		err = hook.GlobalHookManager.OnGetCachedAnimeCollection().Trigger(event)
+		if err != nil {
+			return nil, err
+		}
+		return event.AnimeCollection, nil
+	}
+
+	event := new(platform.GetAnimeCollectionEvent)
+	event.AnimeCollection = collection
+	err = hook.GlobalHookManager.OnGetAnimeCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.AnimeCollection, nil
+}
+
+func (mp *MalPlatform) GetRawAnimeCollection(ctx context.Context, bypassCache bool) (*anilist.AnimeCollection, error) {
+	mp.logger.Trace().Bool("bypassCache", bypassCache).Msg("mal platform: Getting raw anime collection")
+
+	collection, cached, err := mp.getAnimeCollection(ctx, bypassCache, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		event := new(platform.GetCachedRawAnimeCollectionEvent)
+		event.AnimeCollection = collection
+		err = hook.GlobalHookManager.OnGetCachedRawAnimeCollection().Trigger(event)
+		if err != nil {
+			return nil, err
+		}
+		return event.AnimeCollection, nil
+	}
+
+	event := new(platform.GetRawAnimeCollectionEvent)
+	event.AnimeCollection = collection
+	err = hook.GlobalHookManager.OnGetRawAnimeCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.AnimeCollection, nil
+}
+
+// RefreshAnimeCollection refetches the MAL list and the metadata of every entry.
+func (mp *MalPlatform) RefreshAnimeCollection(ctx context.Context) (*anilist.AnimeCollection, error) {
+	mp.logger.Trace().Msg("mal platform: Refreshing anime collection")
+
+	collection, _, err := mp.getAnimeCollection(ctx, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	event := new(platform.GetAnimeCollectionEvent)
+	event.AnimeCollection = collection
+	err = hook.GlobalHookManager.OnGetAnimeCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	event2 := new(platform.GetRawAnimeCollectionEvent)
+	event2.AnimeCollection = collection
+	err = hook.GlobalHookManager.OnGetRawAnimeCollection().Trigger(event2)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.AnimeCollection, nil
+}
+
+// GetAnimeCollectionWithRelations returns the anime collection (without relations)
+func (mp *MalPlatform) GetAnimeCollectionWithRelations(ctx context.Context) (*anilist.AnimeCollectionWithRelations, error) {
+	mp.logger.Trace().Msg("mal platform: Getting anime collection with relations")
+
+	collection, _, err := mp.getAnimeCollection(ctx, false, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// Use JSON to convert the collection structs
+	collectionWithRelations := &anilist.AnimeCollectionWithRelations{}
+
+	marshaled, err := json.Marshal(collection)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(marshaled, collectionWithRelations)
+	if err != nil {
+		return nil, err
+	}
+
+	return collectionWithRelations, nil
+}
+
+func (mp *MalPlatform) GetMangaCollection(ctx context.Context, bypassCache bool) (*anilist.MangaCollection, error) {
+	mp.logger.Trace().Bool("bypassCache", bypassCache).Msg("mal platform: Getting manga collection")
+
+	collection, cached, err := mp.getMangaCollection(ctx, bypassCache, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		event := new(platform.GetCachedMangaCollectionEvent)
+		event.MangaCollection = collection
+		err = hook.GlobalHookManager.OnGetCachedMangaCollection().Trigger(event)
+		if err != nil {
+			return nil, err
+		}
+		return event.MangaCollection, nil
+	}
+
+	event := new(platform.GetMangaCollectionEvent)
+	event.MangaCollection = collection
+	err = hook.GlobalHookManager.OnGetMangaCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.MangaCollection, nil
+}
+
+func (mp *MalPlatform) GetRawMangaCollection(ctx context.Context, bypassCache bool) (*anilist.MangaCollection, error) {
+	mp.logger.Trace().Bool("bypassCache", bypassCache).Msg("mal platform: Getting raw manga collection")
+
+	collection, cached, err := mp.getMangaCollection(ctx, bypassCache, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached {
+		event := new(platform.GetCachedRawMangaCollectionEvent)
+		event.MangaCollection = collection
+		err = hook.GlobalHookManager.OnGetCachedRawMangaCollection().Trigger(event)
+		if err != nil {
+			return nil, err
+		}
+		return event.MangaCollection, nil
+	}
+
+	event := new(platform.GetRawMangaCollectionEvent)
+	event.MangaCollection = collection
+	err = hook.GlobalHookManager.OnGetRawMangaCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.MangaCollection, nil
+}
+
+// RefreshMangaCollection refetches the MAL list and the metadata of every entry.
+func (mp *MalPlatform) RefreshMangaCollection(ctx context.Context) (*anilist.MangaCollection, error) {
+	mp.logger.Trace().Msg("mal platform: Refreshing manga collection")
+
+	collection, _, err := mp.getMangaCollection(ctx, true, true)
+	if err != nil {
+		return nil, err
+	}
+
+	event := new(platform.GetMangaCollectionEvent)
+	event.MangaCollection = collection
+	err = hook.GlobalHookManager.OnGetMangaCollection().Trigger(event)
+	if err != nil {
+		return nil, err
+	}
+
+	event2 := new(platform.GetRawMangaCollectionEvent)
+	event2.MangaCollection = collection
+	err = hook.GlobalHookManager.OnGetRawMangaCollection().Trigger(event2)
+	if err != nil {
+		return nil, err
+	}
+
+	return event.MangaCollection, nil
+}
+
+func (mp *MalPlatform) AddMediaToCollection(ctx context.Context, mIds []int) error {
+	mp.logger.Trace().Interface("mediaIDs", mIds).Msg("mal platform: Adding media to collection")
+
+	for _, mediaID := range mIds {
+		if handled, err := mp.helper.HandleCustomSourceUpdateEntry(ctx, mediaID, new(anilist.MediaListStatusPlanning), new(0), new(0), nil, nil); handled {
+			if err != nil {
+				mp.logger.Warn().Err(err).Int("mediaID", mediaID).Msg("mal platform: Cannot add custom source media to collection")
+			}
+			continue
+		}
+
+		media, err := mp.findMedia(ctx, mediaID)
+		if err != nil {
+			mp.logger.Warn().Err(err).Int("mediaID", mediaID).Msg("mal platform: Cannot add media to collection")
+			continue
+		}
+		if media.inList {
+			continue
+		}
+		if err := mp.updateListStatus(media, listUpdate{status: new(anilist.MediaListStatusPlanning)}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (mp *MalPlatform) GetStudioDetails(ctx context.Context, studioID int) (*anilist.StudioDetails, error) {
+	mp.logger.Trace().Int("studioID", studioID).Msg("mal platform: Getting studio details")
+
+	ret, err := mp.client.StudioDetails(ctx, &studioID)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.helper.TriggerGetStudioDetailsEvent(ret)
+}
+
+func (mp *MalPlatform) GetAnilistClient() anilist.AnilistClient {
+	return mp.client
+}
+
+func (mp *MalPlatform) GetViewerStats(ctx context.Context) (*anilist.ViewerStats, error) {
+	return nil, errors.New("stats are not available for MyAnimeList lists")
+}
+
+func (mp *MalPlatform) GetAnimeAiringSchedule(ctx context.Context) (*anilist.AnimeAiringSchedule, error) {
+	collection, err := mp.GetAnimeCollection(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return mp.helper.BuildAnimeAiringSchedule(ctx, collection, mp.client)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Helper Methods
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// getAnimeCollection returns the cached collection unless it is stale or bypassCache is true.
+// refreshMedia refetches the metadata of every entry instead of the new entries only.
+// The second value is true if the collection comes from the cache.
+func (mp *MalPlatform) getAnimeCollection(ctx context.Context, bypassCache bool, refreshMedia bool) (*anilist.AnimeCollection, bool, error) {
+	mp.mu.RLock()
+	collection, stale := mp.animeCollection, mp.animeCollectionStale
+	mp.mu.RUnlock()
+	if collection != nil && !stale && !bypassCache {
+		return collection, true, nil
+	}
+
+	mp.fetchMu.Lock()
+	defer mp.fetchMu.Unlock()
+
+	fetched, err := mp.fetchAnimeCollection(ctx, refreshMedia)
+	if err != nil {
+		if collection == nil {
+			return nil, false, err
+		}
+		// Keep the app usable when MAL is unreachable
+		mp.logger.Warn().Err(err).Msg("mal platform: Failed to fetch anime list, using the last collection")
+		return collection, true, nil
+	}
+
+	return fetched, false, nil
+}
+
+func (mp *MalPlatform) fetchAnimeCollection(ctx context.Context, refreshMedia bool) (*anilist.AnimeCollection, error) {
+	wrapper, err := mp.getWrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := wrapper.GetAnimeCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	mp.mu.RLock()
+	toResolve := make([]int, 0)
+	for _, entry := range entries {
+		_, resolved := mp.animeByMalID[entry.Node.ID]
+		_, unmatched := mp.unmatchedAnime[entry.Node.ID]
+		if refreshMedia || (!resolved && !unmatched) {
+			toResolve = append(toResolve, entry.Node.ID)
+		}
+	}
+	mp.mu.RUnlock()
+
+	resolved, err := mp.resolveAnime(ctx, toResolve)
+	if err != nil {
+		if !refreshMedia {
+			return nil, err
+		}
+		mp.logger.Warn().Err(err).Msg("mal platform: Failed to refresh anime metadata")
+		toResolve, resolved = nil, nil
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, malID := range toResolve {
+		if media, ok := resolved[malID]; ok {
+			mp.animeByMalID[malID] = media
+			delete(mp.unmatchedAnime, malID)
+		} else if _, ok := mp.animeByMalID[malID]; !ok {
+			mp.unmatchedAnime[malID] = struct{}{}
+		}
+	}
+
+	collection := buildAnimeCollection(entries, mp.animeByMalID)
+	mp.helper.MergeCustomSourceAnimeEntries(collection)
+
+	if len(mp.unmatchedAnime) > 0 {
+		mp.logger.Debug().Int("count", len(mp.unmatchedAnime)).Msg("mal platform: Some anime are not on AniList")
+	}
+
+	mp.animeCollection = collection
+	mp.animeCollectionStale = false
+	return collection, nil
+}
+
+// getMangaCollection returns the cached collection unless it is stale or bypassCache is true.
+// refreshMedia refetches the metadata of every entry instead of the new entries only.
+// The second value is true if the collection comes from the cache.
+func (mp *MalPlatform) getMangaCollection(ctx context.Context, bypassCache bool, refreshMedia bool) (*anilist.MangaCollection, bool, error) {
+	mp.mu.RLock()
+	collection, stale := mp.mangaCollection, mp.mangaCollectionStale
+	mp.mu.RUnlock()
+	if collection != nil && !stale && !bypassCache {
+		return collection, true, nil
+	}
+
+	mp.fetchMu.Lock()
+	defer mp.fetchMu.Unlock()
+
+	fetched, err := mp.fetchMangaCollection(ctx, refreshMedia)
+	if err != nil {
+		if collection == nil {
+			return nil, false, err
+		}
+		// Keep the app usable when MAL is unreachable
+		mp.logger.Warn().Err(err).Msg("mal platform: Failed to fetch manga list, using the last collection")
+		return collection, true, nil
+	}
+
+	return fetched, false, nil
+}
+
+func (mp *MalPlatform) fetchMangaCollection(ctx context.Context, refreshMedia bool) (*anilist.MangaCollection, error) {
+	wrapper, err := mp.getWrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := wrapper.GetMangaCollection()
+	if err != nil {
+		return nil, err
+	}
+
+	mp.mu.RLock()
+	toResolve := make([]int, 0)
+	for _, entry := range entries {
+		_, resolved := mp.mangaByMalID[entry.Node.ID]
+		_, unmatched := mp.unmatchedManga[entry.Node.ID]
+		if refreshMedia || (!resolved && !unmatched) {
+			toResolve = append(toResolve, entry.Node.ID)
+		}
+	}
+	mp.mu.RUnlock()
+
+	resolved, err := mp.resolveManga(ctx, toResolve)
+	if err != nil {
+		if !refreshMedia {
+			return nil, err
+		}
+		mp.logger.Warn().Err(err).Msg("mal platform: Failed to refresh manga metadata")
+		toResolve, resolved = nil, nil
+	}
+
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	for _, malID := range toResolve {
+		if media, ok := resolved[malID]; ok {
+			mp.mangaByMalID[malID] = media
+			delete(mp.unmatchedManga, malID)
+		} else if _, ok := mp.mangaByMalID[malID]; !ok {
+			mp.unmatchedManga[malID] = struct{}{}
+		}
+	}
+
+	collection := buildMangaCollection(entries, mp.mangaByMalID)
+	mp.helper.MergeCustomSourceMangaEntries(collection)
+
+	if len(mp.unmatchedManga) > 0 {
+		mp.logger.Debug().Int("count", len(mp.unmatchedManga)).Msg("mal platform: Some manga are not on AniList")
+	}
+
+	mp.mangaCollection = collection
+	mp.mangaCollectionStale = false
+	return collection, nil
+}
+
+// markStale makes the next collection request refetch the MAL list
+func (mp *MalPlatform) markStale(isAnime bool) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+	if isAnime {
+		mp.animeCollectionStale = true
+	} else {
+		mp.mangaCollectionStale = true
+	}
+}
+
+// listMedia is an AniList media resolved to its MAL counterpart
+type listMedia struct {
+	malID   int
+	isAnime bool
+	// The fields below are only set if the media is in the list
+	inList        bool
+	status        anilist.MediaListStatus
+	hasStartDate  bool
+	hasFinishDate bool
+}
+
+// findMedia looks up the media in the collections, then on AniList.
+func (mp *MalPlatform) findMedia(ctx context.Context, mediaID int) (*listMedia, error) {
+	mp.mu.RLock()
+	for _, list := range mp.animeCollection.GetMediaListCollection().GetLists() {
+		for _, entry := range list.GetEntries() {
+			if entry.GetMedia().GetID() == mediaID && entry.GetMedia().GetIDMal() != nil {
+				mp.mu.RUnlock()
+				return &listMedia{
+					malID:         *entry.GetMedia().GetIDMal(),
+					isAnime:       true,
+					inList:        true,
+					status:        lo.FromPtr(entry.GetStatus()),
+					hasStartDate:  entry.GetStartedAt().GetYear() != nil,
+					hasFinishDate: entry.GetCompletedAt().GetYear() != nil,
+				}, nil
+			}
+		}
+	}
+	for _, list := range mp.mangaCollection.GetMediaListCollection().GetLists() {
+		for _, entry := range list.GetEntries() {
+			if entry.GetMedia().GetID() == mediaID && entry.GetMedia().GetIDMal() != nil {
+				mp.mu.RUnlock()
+				return &listMedia{
+					malID:         *entry.GetMedia().GetIDMal(),
+					isAnime:       false,
+					inList:        true,
+					status:        lo.FromPtr(entry.GetStatus()),
+					hasStartDate:  entry.GetStartedAt().GetYear() != nil,
+					hasFinishDate: entry.GetCompletedAt().GetYear() != nil,
+				}, nil
+			}
+		}
+	}
+	mp.mu.RUnlock()
+
+	if resp, err := mp.client.BaseAnimeByID(ctx, &mediaID); err == nil && resp.GetMedia() != nil {
+		if resp.GetMedia().GetIDMal() == nil {
+			return nil, ErrNoMalID
+		}
+		return &listMedia{malID: *resp.GetMedia().GetIDMal(), isAnime: true}, nil
+	}
+
+	if resp, err := mp.client.BaseMangaByID(ctx, &mediaID); err == nil && resp.GetMedia() != nil {
+		if resp.GetMedia().GetIDMal() == nil {
+			return nil, ErrNoMalID
+		}
+		return &listMedia{malID: *resp.GetMedia().GetIDMal(), isAnime: false}, nil
+	}
+
+	return nil, errors.New("media not found on AniList")
+}
+
+// listUpdate holds the fields sent to MAL, nil fields are left unchanged
+type listUpdate struct {
+	status     *anilist.MediaListStatus
+	scoreRaw   *int
+	progress   *int
+	repeat     *int
+	startDate  *string
+	finishDate *string
+}
+
+func (mp *MalPlatform) updateListStatus(media *listMedia, update listUpdate) error {
+	wrapper, err := mp.getWrapper()
+	if err != nil {
+		return err
+	}
+
+	var status *mal.MediaListStatus
+	var isRepeating *bool
+	if update.status != nil {
+		s, repeating := toMalListStatus(*update.status, media.isAnime)
+		status, isRepeating = &s, &repeating
+	}
+	var score *int
+	if update.scoreRaw != nil {
+		score = new(toMalScore(*update.scoreRaw))
+	}
+
+	if media.isAnime {
+		err = wrapper.UpdateAnimeListStatus(&mal.AnimeListStatusParams{
+			Status:             status,
+			IsRewatching:       isRepeating,
+			NumEpisodesWatched: update.progress,
+			NumTimesRewatched:  update.repeat,
+			Score:              score,
+			StartDate:          update.startDate,
+			FinishDate:         update.finishDate,
+		}, media.malID)
+	} else {
+		err = wrapper.UpdateMangaListStatus(&mal.MangaListStatusParams{
+			Status:          status,
+			IsRereading:     isRepeating,
+			NumChaptersRead: update.progress,
+			NumTimesReread:  update.repeat,
+			Score:           score,
+			StartDate:       update.startDate,
+			FinishDate:      update.finishDate,
+		}, media.malID)
+	}
+	if err != nil {
+		return err
+	}
+
+	mp.markStale(media.isAnime)
+	return nil
+}
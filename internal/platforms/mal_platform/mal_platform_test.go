@@ -0,0 +1,176 @@
+package mal_platform
+
+import (
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListStatusConversion(t *testing.T) {
+	tests := []struct {
+		malStatus   mal.MediaListStatus
+		isRepeating bool
+		expected    anilist.MediaListStatus
+	}{
+		{mal.MediaListStatusWatching, false, anilist.MediaListStatusCurrent},
+		{mal.MediaListStatusWatching, true, anilist.MediaListStatusRepeating},
+		{mal.MediaListStatusReading, false, anilist.MediaListStatusCurrent},
+		{mal.MediaListStatusCompleted, false, anilist.MediaListStatusCompleted},
+		{mal.MediaListStatusCompleted, true, anilist.MediaListStatusRepeating},
+		{mal.MediaListStatusOnHold, false, anilist.MediaListStatusPaused},
+		{mal.MediaListStatusDropped, false, anilist.MediaListStatusDropped},
+		{mal.MediaListStatusPlanToWatch, false, anilist.MediaListStatusPlanning},
+		{mal.MediaListStatusPlanToRead, false, anilist.MediaListStatusPlanning},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.expected, fromMalListStatus(tt.malStatus, tt.isRepeating), tt.malStatus)
+	}
+
+	status, repeating := toMalListStatus(anilist.MediaListStatusRepeating, true)
+	require.Equal(t, mal.MediaListStatusWatching, status)
+	require.True(t, repeating)
+
+	status, repeating = toMalListStatus(anilist.MediaListStatusCurrent, false)
+	require.Equal(t, mal.MediaListStatusReading, status)
+	require.False(t, repeating)
+
+	status, _ = toMalListStatus(anilist.MediaListStatusPlanning, true)
+	require.Equal(t, mal.MediaListStatusPlanToWatch, status)
+
+	status, _ = toMalListStatus(anilist.MediaListStatusPlanning, false)
+	require.Equal(t, mal.MediaListStatusPlanToRead, status)
+
+	status, _ = toMalListStatus(anilist.MediaListStatusPaused, true)
+	require.Equal(t, mal.MediaListStatusOnHold, status)
+}
+
+func TestScoreConversion(t *testing.T) {
+	require.Equal(t, 80.0, fromMalScore(8))
+	require.Equal(t, 8, toMalScore(80))
+	require.Equal(t, 9, toMalScore(85))
+	require.Equal(t, 8, toMalScore(84))
+	require.Equal(t, 10, toMalScore(100))
+	require.Equal(t, 0, toMalScore(0))
+}
+
+func TestDateConversion(t *testing.T) {
+	date := parseMalDate("2024-04-07")
+	require.Equal(t, 2024, *date.Year)
+	require.Equal(t, 4, *date.Month)
+	require.Equal(t, 7, *date.Day)
+
+	// Partial dates
+	date = parseMalDate("2024-04")
+	require.Equal(t, 2024, *date.Year)
+	require.Equal(t, 4, *date.Month)
+	require.Nil(t, date.Day)
+
+	require.Nil(t, parseMalDate("").Year)
+	require.Nil(t, parseMalDate("invalid").Year)
+
+	require.Equal(t, "2024-04-07", *formatMalDate(parseMalDate("2024-04-07")))
+	require.Equal(t, "", *formatMalDate(&anilist.FuzzyDateInput{}))
+	require.Nil(t, formatMalDate(parseMalDate("2024-04")))
+	require.Nil(t, formatMalDate(nil))
+}
+
+func newAnimeListEntry(malID int, status mal.MediaListStatus, progress int) *mal.AnimeListEntry {
+	entry := &mal.AnimeListEntry{}
+	entry.Node.ID = malID
+	entry.ListStatus.Status = status
+	entry.ListStatus.NumEpisodesWatched = progress
+	return entry
+}
+
+func TestBuildAnimeCollection(t *testing.T) {
+	rewatching := newAnimeListEntry(3, mal.MediaListStatusCompleted, 4)
+	rewatching.ListStatus.IsRewatching = true
+	rewatching.ListStatus.NumTimesRewatched = 1
+
+	watching := newAnimeListEntry(1, mal.MediaListStatusWatching, 5)
+	watching.ListStatus.Score = 7
+	watching.ListStatus.StartDate = "2024-01-02"
+
+	entries := []*mal.AnimeListEntry{
+		watching,
+		newAnimeListEntry(2, mal.MediaListStatusPlanToWatch, 0),
+		rewatching,
+		// Not on AniList
+		newAnimeListEntry(4, mal.MediaListStatusWatching, 1),
+	}
+	media := map[int]*anilist.BaseAnime{
+		1: {ID: 101, IDMal: new(1)},
+		2: {ID: 102, IDMal: new(2)},
+		3: {ID: 103, IDMal: new(3)},
+	}
+
+	collection := buildAnimeCollection(entries, media)
+	lists := collection.GetMediaListCollection().GetLists()
+	require.Len(t, lists, 3)
+
+	// Lists follow the status order
+	require.Equal(t, anilist.MediaListStatusCurrent, *lists[0].GetStatus())
+	require.Equal(t, anilist.MediaListStatusRepeating, *lists[1].GetStatus())
+	require.Equal(t, anilist.MediaListStatusPlanning, *lists[2].GetStatus())
+
+	entry := lists[0].GetEntries()[0]
+	require.Len(t, lists[0].GetEntries(), 1)
+	require.Equal(t, 1, entry.GetID())
+	require.Equal(t, 101, entry.GetMedia().GetID())
+	require.Equal(t, 5, *entry.GetProgress())
+	require.Equal(t, 70.0, *entry.GetScore())
+	require.Equal(t, 2024, *entry.GetStartedAt().GetYear())
+	require.Nil(t, entry.GetCompletedAt().GetYear())
+
+	entry = lists[1].GetEntries()[0]
+	require.Equal(t, 103, entry.GetMedia().GetID())
+	require.Equal(t, 1, *entry.GetRepeat())
+}
+
+func TestBuildMangaCollection(t *testing.T) {
+	entry := &mal.MangaListEntry{}
+	entry.Node.ID = 10
+	entry.ListStatus.Status = mal.MediaListStatusReading
+	entry.ListStatus.NumChaptersRead = 42
+	entry.ListStatus.FinishDate = "2023"
+
+	collection := buildMangaCollection([]*mal.MangaListEntry{entry}, map[int]*anilist.BaseManga{
+		10: {ID: 110, IDMal: new(10)},
+	})
+	lists := collection.GetMediaListCollection().GetLists()
+	require.Len(t, lists, 1)
+	require.Equal(t, anilist.MediaListStatusCurrent, *lists[0].GetStatus())
+	require.Equal(t, 42, *lists[0].GetEntries()[0].GetProgress())
+	require.Equal(t, 2023, *lists[0].GetEntries()[0].GetCompletedAt().GetYear())
+}
+
+func TestMalIDsQuery(t *testing.T) {
+	require.True(t, strings.Contains(animeByMalIDsQuery, "media(idMal_in: $ids, type: ANIME)"))
+	require.True(t, strings.Contains(animeByMalIDsQuery, "fragment baseAnime on Media"))
+	require.True(t, strings.Contains(mangaByMalIDsQuery, "media(idMal_in: $ids, type: MANGA)"))
+	require.True(t, strings.Contains(mangaByMalIDsQuery, "fragment baseManga on Media"))
+	require.False(t, strings.Contains(mangaByMalIDsQuery, "BaseMangaById"))
+}
+
+func TestFindMediaInCollection(t *testing.T) {
+	media := map[int]*anilist.BaseAnime{1: {ID: 101, IDMal: new(1)}}
+	entry := newAnimeListEntry(1, mal.MediaListStatusWatching, 3)
+	entry.ListStatus.IsRewatching = true
+
+	mp := &MalPlatform{animeCollection: buildAnimeCollection([]*mal.AnimeListEntry{entry}, media)}
+
+	found, err := mp.findMedia(t.Context(), 101)
+	require.NoError(t, err)
+	require.Equal(t, 1, found.malID)
+	require.True(t, found.isAnime)
+	require.True(t, found.inList)
+	require.Equal(t, anilist.MediaListStatusRepeating, found.status)
+	require.False(t, found.hasStartDate)
+
+	mp.markStale(true)
+	require.True(t, mp.animeCollectionStale)
+	require.False(t, mp.mangaCollectionStale)
+}
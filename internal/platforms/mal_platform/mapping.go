@@ -0,0 +1,354 @@
+package mal_platform
+
+import (
+	"context"
+	"fmt"
+	"seanime/internal/api/anilist"
+	"seanime/internal/api/mal"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-json"
+)
+
+// malIDBatchSize is the maximum number of media returned by a single AniList page
+const malIDBatchSize = 50
+
+var (
+	animeByMalIDsQuery = buildMalIDsQuery(anilist.BaseAnimeByMalIDDocument, "ANIME", "baseAnime")
+	mangaByMalIDsQuery = buildMalIDsQuery(anilist.BaseMangaByIDDocument, "MANGA", "baseManga")
+)
+
+// buildMalIDsQuery reuses the fragments of a generated document to fetch a page of media by MAL IDs.
+func buildMalIDsQuery(document string, mediaType string, fragment string) string {
+	fragments := document[strings.Index(document, "fragment "):]
+	return fmt.Sprintf(`query MediaByMalIds ($ids: [Int]) {
+	Page(page: 1, perPage: %d) {
+		media(idMal_in: $ids, type: %s) {
+			... %s
+		}
+	}
+}
+%s`, malIDBatchSize, mediaType, fragment, fragments)
+}
+
+// resolveAnime fetches the AniList media of the given MAL IDs.
+// IDs without an AniList counterpart are absent from the returned map.
+func (mp *MalPlatform) resolveAnime(ctx context.Context, malIDs []int) (map[int]*anilist.BaseAnime, error) {
+	ret := make(map[int]*anilist.BaseAnime, len(malIDs))
+
+	for start := 0; start < len(malIDs); start += malIDBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var res struct {
+			Page struct {
+				Media []*anilist.BaseAnime `json:"media"`
+			} `json:"Page"`
+		}
+		if err := mp.queryMalIDs(animeByMalIDsQuery, malIDs[start:min(start+malIDBatchSize, len(malIDs))], &res); err != nil {
+			return nil, err
+		}
+
+		for _, media := range res.Page.Media {
+			if media != nil && media.GetIDMal() != nil {
+				ret[*media.GetIDMal()] = media
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+// resolveManga fetches the AniList media of the given MAL IDs.
+// IDs without an AniList counterpart are absent from the returned map.
+func (mp *MalPlatform) resolveManga(ctx context.Context, malIDs []int) (map[int]*anilist.BaseManga, error) {
+	ret := make(map[int]*anilist.BaseManga, len(malIDs))
+
+	for start := 0; start < len(malIDs); start += malIDBatchSize {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		var res struct {
+			Page struct {
+				Media []*anilist.BaseManga `json:"media"`
+			} `json:"Page"`
+		}
+		if err := mp.queryMalIDs(mangaByMalIDsQuery, malIDs[start:min(start+malIDBatchSize, len(malIDs))], &res); err != nil {
+			return nil, err
+		}
+
+		for _, media := range res.Page.Media {
+			if media != nil && media.GetIDMal() != nil {
+				ret[*media.GetIDMal()] = media
+			}
+		}
+	}
+
+	return ret, nil
+}
+
+func (mp *MalPlatform) queryMalIDs(query string, ids []int, ret interface{}) error {
+	mp.resolveRateLimit.Wait()
+
+	body, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": map[string]interface{}{"ids": ids},
+	})
+	if err != nil {
+		return err
+	}
+
+	data, err := mp.client.CustomQuery(body, mp.logger)
+	if err != nil {
+		return err
+	}
+
+	m, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(m, ret)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Collections
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// listStatusOrder is the order of the lists in the collections
+var listStatusOrder = []anilist.MediaListStatus{
+	anilist.MediaListStatusCurrent,
+	anilist.MediaListStatusRepeating,
+	anilist.MediaListStatusPlanning,
+	anilist.MediaListStatusPaused,
+	anilist.MediaListStatusCompleted,
+	anilist.MediaListStatusDropped,
+}
+
+// buildAnimeCollection converts the MAL anime list to an AniList collection.
+// The ID of each entry is the MAL ID of the media.
+func buildAnimeCollection(entries []*mal.AnimeListEntry, media map[int]*anilist.BaseAnime) *anilist.AnimeCollection {
+	lists := make(map[anilist.MediaListStatus]*anilist.AnimeCollection_MediaListCollection_Lists)
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		m, ok := media[entry.Node.ID]
+		if !ok {
+			continue
+		}
+
+		status := fromMalListStatus(entry.ListStatus.Status, entry.ListStatus.IsRewatching)
+		list, ok := lists[status]
+		if !ok {
+			list = &anilist.AnimeCollection_MediaListCollection_Lists{
+				Status:       new(status),
+				Name:         new(string(status)),
+				IsCustomList: new(false),
+				Entries:      []*anilist.AnimeCollection_MediaListCollection_Lists_Entries{},
+			}
+			lists[status] = list
+		}
+
+		startedAt := parseMalDate(entry.ListStatus.StartDate)
+		completedAt := parseMalDate(entry.ListStatus.FinishDate)
+		list.Entries = append(list.Entries, &anilist.AnimeCollection_MediaListCollection_Lists_Entries{
+			ID:       entry.Node.ID,
+			Status:   new(status),
+			Progress: new(entry.ListStatus.NumEpisodesWatched),
+			Repeat:   new(entry.ListStatus.NumTimesRewatched),
+			Score:    new(fromMalScore(entry.ListStatus.Score)),
+			Private:  new(false),
+			Media:    m,
+			StartedAt: &anilist.AnimeCollection_MediaListCollection_Lists_Entries_StartedAt{
+				Year:  startedAt.Year,
+				Month: startedAt.Month,
+				Day:   startedAt.Day,
+			},
+			CompletedAt: &anilist.AnimeCollection_MediaListCollection_Lists_Entries_CompletedAt{
+				Year:  completedAt.Year,
+				Month: completedAt.Month,
+				Day:   completedAt.Day,
+			},
+		})
+	}
+
+	ret := &anilist.AnimeCollection{
+		MediaListCollection: &anilist.AnimeCollection_MediaListCollection{
+			Lists: []*anilist.AnimeCollection_MediaListCollection_Lists{},
+		},
+	}
+	for _, status := range listStatusOrder {
+		if list, ok := lists[status]; ok {
+			ret.MediaListCollection.Lists = append(ret.MediaListCollection.Lists, list)
+		}
+	}
+	return ret
+}
+
+// buildMangaCollection converts the MAL manga list to an AniList collection.
+// The ID of each entry is the MAL ID of the media.
+func buildMangaCollection(entries []*mal.MangaListEntry, media map[int]*anilist.BaseManga) *anilist.MangaCollection {
+	lists := make(map[anilist.MediaListStatus]*anilist.MangaCollection_MediaListCollection_Lists)
+
+	for _, entry := range entries {
+		if entry == nil {
+			continue
+		}
+		m, ok := media[entry.Node.ID]
+		if !ok {
+			continue
+		}
+
+		status := fromMalListStatus(entry.ListStatus.Status, entry.ListStatus.IsRereading)
+		list, ok := lists[status]
+		if !ok {
+			list = &anilist.MangaCollection_MediaListCollection_Lists{
+				Status:       new(status),
+				Name:         new(string(status)),
+				IsCustomList: new(false),
+				Entries:      []*anilist.MangaCollection_MediaListCollection_Lists_Entries{},
+			}
+			lists[status] = list
+		}
+
+		startedAt := parseMalDate(entry.ListStatus.StartDate)
+		completedAt := parseMalDate(entry.ListStatus.FinishDate)
+		list.Entries = append(list.Entries, &anilist.MangaCollection_MediaListCollection_Lists_Entries{
+			ID:       entry.Node.ID,
+			Status:   new(status),
+			Progress: new(entry.ListStatus.NumChaptersRead),
+			Repeat:   new(entry.ListStatus.NumTimesReread),
+			Score:    new(fromMalScore(entry.ListStatus.Score)),
+			Private:  new(false),
+			Media:    m,
+			StartedAt: &anilist.MangaCollection_MediaListCollection_Lists_Entries_StartedAt{
+				Year:  startedAt.Year,
+				Month: startedAt.Month,
+				Day:   startedAt.Day,
+			},
+			CompletedAt: &anilist.MangaCollection_MediaListCollection_Lists_Entries_CompletedAt{
+				Year:  completedAt.Year,
+				Month: completedAt.Month,
+				Day:   completedAt.Day,
+			},
+		})
+	}
+
+	ret := &anilist.MangaCollection{
+		MediaListCollection: &anilist.MangaCollection_MediaListCollection{
+			Lists: []*anilist.MangaCollection_MediaListCollection_Lists{},
+		},
+	}
+	for _, status := range listStatusOrder {
+		if list, ok := lists[status]; ok {
+			ret.MediaListCollection.Lists = append(ret.MediaListCollection.Lists, list)
+		}
+	}
+	return ret
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+// Conversions
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// fromMalListStatus converts a MAL list status, MAL has no repeating status and uses a flag instead.
+func fromMalListStatus(status mal.MediaListStatus, isRepeating bool) anilist.MediaListStatus {
+	switch status {
+	case mal.MediaListStatusWatching, mal.MediaListStatusReading:
+		if isRepeating {
+			return anilist.MediaListStatusRepeating
+		}
+		return anilist.MediaListStatusCurrent
+	case mal.MediaListStatusCompleted:
+		if isRepeating {
+			return anilist.MediaListStatusRepeating
+		}
+		return anilist.MediaListStatusCompleted
+	case mal.MediaListStatusOnHold:
+		return anilist.MediaListStatusPaused
+	case mal.MediaListStatusDropped:
+		return anilist.MediaListStatusDropped
+	default:
+		return anilist.MediaListStatusPlanning
+	}
+}
+
+// toMalListStatus converts an AniList list status, the second value is the repeating flag.
+func toMalListStatus(status anilist.MediaListStatus, isAnime bool) (mal.MediaListStatus, bool) {
+	current, planning := mal.MediaListStatusWatching, mal.MediaListStatusPlanToWatch
+	if !isAnime {
+		current, planning = mal.MediaListStatusReading, mal.MediaListStatusPlanToRead
+	}
+
+	switch status {
+	case anilist.MediaListStatusRepeating:
+		return current, true
+	case anilist.MediaListStatusCompleted:
+		return mal.MediaListStatusCompleted, false
+	case anilist.MediaListStatusPaused:
+		return mal.MediaListStatusOnHold, false
+	case anilist.MediaListStatusDropped:
+		return mal.MediaListStatusDropped, false
+	case anilist.MediaListStatusPlanning:
+		return planning, false
+	default:
+		return current, false
+	}
+}
+
+// fromMalScore converts a 0-10 MAL score to the 0-100 format used by the collections
+func fromMalScore(score int) float64 {
+	return float64(score * 10)
+}
+
+// toMalScore converts a 0-100 score to a 0-10 MAL score
+func toMalScore(scoreRaw int) int {
+	return min(max((scoreRaw+5)/10, 0), 10)
+}
+
+// parseMalDate parses MAL dates, which can be partial (e.g. "2024-04" or "2024").
+func parseMalDate(value string) *anilist.FuzzyDateInput {
+	ret := &anilist.FuzzyDateInput{}
+	if value == "" {
+		return ret
+	}
+
+	parts := strings.Split(value, "-")
+	fields := []**int{&ret.Year, &ret.Month, &ret.Day}
+	for i, part := range parts {
+		if i >= len(fields) {
+			break
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return &anilist.FuzzyDateInput{}
+		}
+		*fields[i] = new(n)
+	}
+	return ret
+}
+
+// formatMalDate returns the MAL date of a fuzzy date.
+// An empty date clears the field, partial dates are not supported by MAL and return nil.
+func formatMalDate(date *anilist.FuzzyDateInput) *string {
+	if date == nil {
+		return nil
+	}
+	if date.Year == nil {
+		return new("")
+	}
+	if date.Month == nil || date.Day == nil {
+		return nil
+	}
+	return new(fmt.Sprintf("%04d-%02d-%02d", *date.Year, *date.Month, *date.Day))
+}
+
+// today returns the current date in the MAL format
+func today() string {
+	return time.Now().Format(time.DateOnly)
+}
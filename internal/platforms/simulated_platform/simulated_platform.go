@@ -716,6 +716,12 @@ func (sp *SimulatedPlatform) AddMediaToCollection(ctx context.Context, mIds []in
 	// DEVNOTE: We assume it's anime for now since it's only been used for anime
 	wrapper := sp.GetAnimeCollectionWrapper()
 	for _, mediaID := range mIds {
+		if handled, err := sp.helper.HandleCustomSourceUpdateEntry(ctx, mediaID, new(anilist.MediaListStatusPlanning), new(0), new(0), nil, nil); handled {
+			if err != nil {
+				sp.logger.Warn().Err(err).Int("mediaID", mediaID).Msg("simulated platform: Cannot add custom source media to collection")
+			}
+			continue
+		}
 		// Try to add as anime first, if it fails, ignore
 		_ = wrapper.AddEntry(mediaID, anilist.MediaListStatusPlanning)
 	}
@@ -3,12 +3,15 @@ package playlist
 import (
 	"context"
 	"encoding/json"
+	"seanime/internal/api/metadata_provider"
+	"seanime/internal/continuity"
 	"seanime/internal/database/db"
 	"seanime/internal/database/db_bridge"
 	debrid_client "seanime/internal/debrid/client"
 	"seanime/internal/directstream"
 	"seanime/internal/events"
 	"seanime/internal/library/anime"
+	"seanime/internal/library/fillermanager"
 	"seanime/internal/library/playbackmanager"
 	"seanime/internal/mediacore"
 	"seanime/internal/nakama"
@@ -111,6 +114,9 @@ type (
 		torrentstreamRepository *torrentstream.Repository
 		debridClientRepository  *debrid_client.Repository
 		nakamaManager           *nakama.Manager
+		metadataProviderRef     *util.Ref[metadata_provider.Provider]
+		fillerManager           *fillermanager.FillerManager
+		continuityManager       *continuity.Manager
 
 		mu     sync.Mutex
 		logger *zerolog.Logger
@@ -135,6 +141,9 @@ type (
 		DebridClientRepository  *debrid_client.Repository
 		MediacoreCoordinator    *mediacore.Coordinator
 		NakamaManager           *nakama.Manager
+		MetadataProviderRef     *util.Ref[metadata_provider.Provider] // Used by smart playlists
+		FillerManager           *fillermanager.FillerManager
+		ContinuityManager       *continuity.Manager
 		Logger                  *zerolog.Logger
 		PlatformRef             *util.Ref[platform.Platform]
 		WSEventManager          events.WSEventManagerInterface
@@ -151,6 +160,9 @@ func NewManager(opts *NewManagerOptions) *Manager {
 		debridClientRepository:  opts.DebridClientRepository,
 		mediacoreCoordinator:    opts.MediacoreCoordinator,
 		nakamaManager:           opts.NakamaManager,
+		metadataProviderRef:     opts.MetadataProviderRef,
+		fillerManager:           opts.FillerManager,
+		continuityManager:       opts.ContinuityManager,
 		platformRef:             opts.PlatformRef,
 		db:                      opts.Database,
 		wsEventManager:          opts.WSEventManager,
@@ -248,8 +260,13 @@ func (m *Manager) listenToEvents() {
 						m.isStartingPlaylist.Store(false)
 						continue
 					}
-					// Start playlist
-					go m.startPlaylist(playlist, &payload)
+					// Start playlist, the episodes of smart playlists are selected first
+					go func() {
+						if playlist.Rules != nil && !m.refreshSmartPlaylist(playlist) {
+							return
+						}
+						m.startPlaylist(playlist, &payload)
+					}()
 				}
 				m.isStartingPlaylist.Store(false)
 			case ClientEventStop:
@@ -0,0 +1,194 @@
+package playlist
+
+import (
+	"context"
+	"errors"
+	"seanime/internal/api/anilist"
+	"seanime/internal/continuity"
+	"seanime/internal/database/db_bridge"
+	"seanime/internal/events"
+	"seanime/internal/library/anime"
+)
+
+var ErrNoSmartPlaylistEpisodes = errors.New("playlist: no episodes match the rules")
+
+// EvaluateRules returns the episodes selected by the rules of a smart playlist.
+// The shows come from the anime collection, their episodes from the library and the metadata provider.
+// Fillers are excluded using the filler manager and the shows are ordered by last watch using the continuity manager.
+func (m *Manager) EvaluateRules(ctx context.Context, rules *anime.PlaylistRules) ([]*anime.PlaylistEpisode, error) {
+	rules.Normalize()
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+
+	animeCollection, err := m.platformRef.Get().GetAnimeCollection(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	lfs, _, err := db_bridge.GetLocalFiles(m.db)
+	if err != nil {
+		return nil, err
+	}
+	lfw := anime.NewLocalFileWrapper(lfs)
+
+	// Shows selected by the rules
+	medias := make([]*anilist.BaseAnime, 0)
+	progress := make(map[int]int)
+	for _, list := range animeCollection.GetMediaListCollection().GetLists() {
+		for _, entry := range list.GetEntries() {
+			if !rules.MatchesMedia(entry.GetMedia(), entry.GetStatus()) {
+				continue
+			}
+			if _, ok := progress[entry.GetMedia().GetID()]; ok {
+				continue
+			}
+			medias = append(medias, entry.GetMedia())
+			progress[entry.GetMedia().GetID()] = entry.GetProgressSafe()
+		}
+	}
+	// Shows that are not in the list
+	if len(rules.Statuses) == 0 {
+		for _, mediaId := range rules.MediaIds {
+			if _, ok := progress[mediaId]; ok {
+				continue
+			}
+			media, err := m.platformRef.Get().GetAnime(ctx, mediaId)
+			if err != nil {
+				m.logger.Warn().Err(err).Int("mediaId", mediaId).Msg("playlist: Failed to get media for smart playlist")
+				continue
+			}
+			if !rules.MatchesMedia(media, nil) {
+				continue
+			}
+			medias = append(medias, media)
+			progress[mediaId] = 0
+		}
+	}
+
+	watchHistory := continuity.WatchHistory{}
+	if m.continuityManager != nil && rules.Order == anime.PlaylistRuleRecentOrder {
+		watchHistory = m.continuityManager.GetWatchHistory()
+	}
+
+	candidates := make([]*anime.PlaylistRuleCandidate, 0, len(medias))
+	for _, media := range medias {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+
+		candidate := &anime.PlaylistRuleCandidate{
+			Media:    media,
+			Progress: progress[media.GetID()],
+		}
+		if item, ok := watchHistory[media.GetID()]; ok {
+			candidate.LastWatchedAt = item.TimeUpdated
+		}
+
+		if _, ok := lfw.GetLocalEntryById(media.GetID()); ok && rules.UsesLocalFiles() {
+			entry, err := anime.NewEntry(ctx, &anime.NewEntryOptions{
+				MediaId:             media.GetID(),
+				LocalFiles:          lfs,
+				AnimeCollection:     animeCollection,
+				PlatformRef:         m.platformRef,
+				MetadataProviderRef: m.metadataProviderRef,
+			})
+			if err != nil {
+				m.logger.Warn().Err(err).Int("mediaId", media.GetID()).Msg("playlist: Failed to get local episodes for smart playlist")
+			} else {
+				candidate.LocalEpisodes = entry.Episodes
+			}
+		}
+
+		if rules.UsesStreams() && !hasAllEpisodes(rules, candidate) {
+			ec, err := anime.NewEpisodeCollection(anime.NewEpisodeCollectionOptions{
+				Media:               media,
+				MetadataProviderRef: m.metadataProviderRef,
+				Logger:              m.logger,
+			})
+			if err != nil {
+				m.logger.Warn().Err(err).Int("mediaId", media.GetID()).Msg("playlist: Failed to get stream episodes for smart playlist")
+			} else {
+				candidate.StreamEpisodes = ec.Episodes
+			}
+		}
+
+		if rules.ExcludeFillers && m.fillerManager != nil {
+			m.fillerManager.HydrateEpisodeFillerData(media.GetID(), candidate.LocalEpisodes)
+			m.fillerManager.HydrateEpisodeFillerData(media.GetID(), candidate.StreamEpisodes)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	episodes := rules.Evaluate(candidates, nil)
+	if len(episodes) == 0 {
+		return nil, ErrNoSmartPlaylistEpisodes
+	}
+
+	return episodes, nil
+}
+
+// refreshSmartPlaylist selects the episodes of a smart playlist again and saves them.
+// It returns false if the playlist cannot be started.
+func (m *Manager) refreshSmartPlaylist(playlist *anime.Playlist) bool {
+	m.wsEventManager.SendEventTo(m.clientId, events.InfoToast, "Selecting the episodes of the playlist...")
+
+	episodes, err := m.EvaluateRules(context.Background(), playlist.Rules)
+	if err != nil {
+		m.logger.Error().Err(err).Uint("dbId", playlist.DbId).Msg("playlist: Failed to evaluate smart playlist")
+		if errors.Is(err, ErrNoSmartPlaylistEpisodes) {
+			m.wsEventManager.SendEventTo(m.clientId, events.ErrorToast, "No episodes match the rules of the playlist")
+		} else {
+			m.wsEventManager.SendEventTo(m.clientId, events.ErrorToast, "Failed to select the episodes of the playlist")
+		}
+		return false
+	}
+
+	playlist.SetEpisodes(episodes)
+	if err := db_bridge.UpdatePlaylist(m.db, playlist); err != nil {
+		m.logger.Warn().Err(err).Uint("dbId", playlist.DbId).Msg("playlist: Failed to save smart playlist episodes")
+	}
+
+	return true
+}
+
+// hasAllEpisodes returns true if the library has all the episodes of the show the rules can select.
+// The stream episodes are not needed in that case.
+func hasAllEpisodes(rules *anime.PlaylistRules, c *anime.PlaylistRuleCandidate) bool {
+	if !rules.UsesLocalFiles() {
+		return false
+	}
+
+	local := make(map[int]struct{}, len(c.LocalEpisodes))
+	for _, ep := range c.LocalEpisodes {
+		if ep.IsMain() {
+			local[ep.ProgressNumber] = struct{}{}
+		}
+	}
+
+	from := c.Progress + 1
+	if rules.Episodes == anime.PlaylistRuleAllEpisodes {
+		from = 1
+	}
+	total := c.Media.GetTotalEpisodeCount()
+	if total > 0 && from > total {
+		return true
+	}
+
+	to := total
+	// The next episode can be a filler that is skipped
+	if rules.Episodes == anime.PlaylistRuleNextEpisode && !rules.ExcludeFillers {
+		to = from
+	}
+	if to <= 0 {
+		return false
+	}
+
+	for progressNumber := from; progressNumber <= to; progressNumber++ {
+		if _, ok := local[progressNumber]; !ok {
+			return false
+		}
+	}
+	return true
+}
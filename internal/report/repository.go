@@ -44,6 +44,7 @@ type SaveIssueReportOptions struct {
 	LocalFiles          []*anime.LocalFile     `json:"localFiles"`
 	Settings            *models.Settings       `json:"settings"`
 	DebridSettings      *models.DebridSettings `json:"debridSettings"`
+	UsenetSettings      *models.UsenetSettings `json:"-"`
 	IsAnimeLibraryIssue bool                   `json:"isAnimeLibraryIssue"`
 	ServerStatus        interface{}            `json:"serverStatus"`
 	ViewportWidth       int                    `json:"viewportWidth"`
@@ -61,6 +62,9 @@ func (r *Repository) SaveIssueReport(opts SaveIssueReportOptions) error {
 	if opts.DebridSettings != nil {
 		toRedact = append(toRedact, opts.DebridSettings.GetSensitiveValues()...)
 	}
+	if opts.UsenetSettings != nil {
+		toRedact = append(toRedact, opts.UsenetSettings.GetSensitiveValues()...)
+	}
 	if opts.Username != "" {
 		toRedact = append(toRedact, opts.Username)
 	}
@@ -130,6 +134,7 @@ type AnonymizeOptions struct {
 	Content        []byte `json:"content"`
 	Settings       *models.Settings
 	DebridSettings *models.DebridSettings
+	UsenetSettings *models.UsenetSettings
 	Username       string
 }
 
@@ -145,6 +150,9 @@ func (r *Repository) Anonymize(opts AnonymizeOptions) string {
 	if opts.DebridSettings != nil {
 		toRedact = append(toRedact, opts.DebridSettings.GetSensitiveValues()...)
 	}
+	if opts.UsenetSettings != nil {
+		toRedact = append(toRedact, opts.UsenetSettings.GetSensitiveValues()...)
+	}
 
 	// Remove empty strings to avoid infinite replacements
 	// don't redact "seanime"
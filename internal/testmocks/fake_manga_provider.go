@@ -11,6 +11,7 @@ type FakeMangaProvider struct {
 	mu            sync.Mutex
 	searchResults []*hibikemanga.SearchResult
 	chapters      map[string][]*hibikemanga.ChapterDetails
+	pages         map[string][]*hibikemanga.ChapterPage
 	searchErr     error
 	chaptersErr   error
 	searchCalls   int
@@ -25,6 +26,7 @@ type FakeMangaProviderBuilder struct {
 func NewFakeMangaProviderBuilder() *FakeMangaProviderBuilder {
 	return &FakeMangaProviderBuilder{provider: &FakeMangaProvider{
 		chapters: make(map[string][]*hibikemanga.ChapterDetails),
+		pages:    make(map[string][]*hibikemanga.ChapterPage),
 	}}
 }
 
@@ -38,6 +40,11 @@ func (b *FakeMangaProviderBuilder) WithChapters(id string, chapters ...*hibikema
 	return b
 }
 
+func (b *FakeMangaProviderBuilder) WithPages(chapterId string, pages ...*hibikemanga.ChapterPage) *FakeMangaProviderBuilder {
+	b.provider.pages[chapterId] = pages
+	return b
+}
+
 func (b *FakeMangaProviderBuilder) WithSearchError(err error) *FakeMangaProviderBuilder {
 	b.provider.searchErr = err
 	return b
@@ -81,8 +88,10 @@ func (p *FakeMangaProvider) FindChapters(id string) ([]*hibikemanga.ChapterDetai
 	return append([]*hibikemanga.ChapterDetails(nil), chapters...), nil
 }
 
-func (p *FakeMangaProvider) FindChapterPages(string) ([]*hibikemanga.ChapterPage, error) {
-	return nil, nil
+func (p *FakeMangaProvider) FindChapterPages(id string) ([]*hibikemanga.ChapterPage, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*hibikemanga.ChapterPage(nil), p.pages[id]...), nil
 }
 
 func (p *FakeMangaProvider) GetSettings() hibikemanga.Settings {
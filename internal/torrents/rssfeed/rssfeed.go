@@ -0,0 +1,204 @@
+package rssfeed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"seanime/internal/constants"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+var magnetHashRegex = regexp.MustCompile(`(?i)xt=urn:btih:([a-z0-9]+)`)
+
+// Fetch downloads and parses an RSS or Atom feed of torrents.
+func Fetch(ctx context.Context, client *http.Client, url string) ([]*hibiketorrent.AnimeTorrent, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Seanime/"+constants.Version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rssfeed: feed returned status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 20<<20))
+	if err != nil {
+		return nil, fmt.Errorf("rssfeed: failed to read feed: %w", err)
+	}
+
+	return Parse(data)
+}
+
+// Parse parses an RSS or Atom feed and normalizes its items into torrents.
+// It understands the common torrent feed extensions (nyaa, ezRSS "torrent", torznab attributes)
+// and falls back to magnet links and enclosures for the info hash and download URL.
+// Items that cannot be downloaded are skipped.
+func Parse(data []byte) ([]*hibiketorrent.AnimeTorrent, error) {
+	feed, err := gofeed.NewParser().ParseString(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("rssfeed: failed to parse feed: %w", err)
+	}
+
+	ret := make([]*hibiketorrent.AnimeTorrent, 0, len(feed.Items))
+	for _, item := range feed.Items {
+		if t := toAnimeTorrent(item); t != nil {
+			ret = append(ret, t)
+		}
+	}
+
+	return ret, nil
+}
+
+func toAnimeTorrent(item *gofeed.Item) *hibiketorrent.AnimeTorrent {
+	if item == nil || strings.TrimSpace(item.Title) == "" {
+		return nil
+	}
+
+	values := extensionValues(item.Extensions)
+
+	ret := &hibiketorrent.AnimeTorrent{
+		Name:          strings.TrimSpace(item.Title),
+		Link:          item.GUID,
+		Seeders:       -1,
+		Leechers:      -1,
+		EpisodeNumber: -1,
+	}
+
+	if item.PublishedParsed != nil {
+		ret.Date = item.PublishedParsed.UTC().Format(time.RFC3339)
+	} else if item.UpdatedParsed != nil {
+		ret.Date = item.UpdatedParsed.UTC().Format(time.RFC3339)
+	}
+
+	// Collect every candidate link, the first magnet link and .torrent link win
+	links := make([]string, 0)
+	if item.Link != "" {
+		links = append(links, item.Link)
+	}
+	links = append(links, item.Links...)
+	for _, enclosure := range item.Enclosures {
+		links = append(links, enclosure.URL)
+		if ret.Size == 0 && enclosure.Length != "" {
+			ret.Size, _ = strconv.ParseInt(enclosure.Length, 10, 64)
+		}
+	}
+	if v := values["magneturi"]; v != "" {
+		links = append([]string{v}, links...)
+	}
+	if v := values["magneturl"]; v != "" {
+		links = append([]string{v}, links...)
+	}
+
+	for _, link := range links {
+		link = strings.TrimSpace(link)
+		switch {
+		case strings.HasPrefix(link, "magnet:"):
+			if ret.MagnetLink == "" {
+				ret.MagnetLink = link
+			}
+		case strings.HasPrefix(link, "http://") || strings.HasPrefix(link, "https://"):
+			if ret.DownloadUrl == "" && isTorrentFileLink(link, item) {
+				ret.DownloadUrl = link
+			} else if !strings.HasPrefix(ret.Link, "http") {
+				ret.Link = link
+			}
+		}
+	}
+
+	ret.InfoHash = strings.ToLower(values["infohash"])
+	if ret.InfoHash == "" && ret.MagnetLink != "" {
+		if m := magnetHashRegex.FindStringSubmatch(ret.MagnetLink); len(m) > 1 {
+			ret.InfoHash = strings.ToLower(m[1])
+		}
+	}
+
+	if v, err := strconv.Atoi(values["seeders"]); err == nil {
+		ret.Seeders = v
+	}
+	if v, err := strconv.Atoi(values["leechers"]); err == nil {
+		ret.Leechers = v
+	} else if peers, err := strconv.Atoi(values["peers"]); err == nil && ret.Seeders >= 0 && peers >= ret.Seeders {
+		ret.Leechers = peers - ret.Seeders
+	}
+	if v, err := strconv.Atoi(values["downloads"]); err == nil {
+		ret.DownloadCount = v
+	} else if v, err := strconv.Atoi(values["grabs"]); err == nil {
+		ret.DownloadCount = v
+	}
+
+	// e.g. "1.4 GiB" (nyaa) or "1503238553" (ezRSS, torznab)
+	for _, key := range []string{"size", "contentlength"} {
+		if ret.Size > 0 || values[key] == "" {
+			break
+		}
+		if v, err := strconv.ParseInt(values[key], 10, 64); err == nil {
+			ret.Size = v
+		} else if v, err := util.StringToBytes(values[key]); err == nil {
+			ret.Size = v
+		}
+	}
+
+	if ret.Link == "" {
+		ret.Link = ret.DownloadUrl
+	}
+
+	if ret.MagnetLink == "" && ret.DownloadUrl == "" {
+		return nil
+	}
+
+	return ret
+}
+
+// isTorrentFileLink returns true if the link points to a .torrent file.
+func isTorrentFileLink(link string, item *gofeed.Item) bool {
+	for _, enclosure := range item.Enclosures {
+		if enclosure.URL == link && enclosure.Type == "application/x-bittorrent" {
+			return true
+		}
+	}
+	lower := strings.ToLower(link)
+	return strings.Contains(lower, ".torrent") || strings.Contains(lower, "/download")
+}
+
+// extensionValues flattens the item's namespaced elements into a case-insensitive map.
+// e.g. <nyaa:infoHash>, <torrent><infoHash>, <torznab:attr name="infohash" value="..."/>
+func extensionValues(extensions ext.Extensions) map[string]string {
+	ret := make(map[string]string)
+	var walk func(elements map[string][]ext.Extension)
+	walk = func(elements map[string][]ext.Extension) {
+		for name, list := range elements {
+			for _, e := range list {
+				key := strings.ToLower(name)
+				value := strings.TrimSpace(e.Value)
+				if key == "attr" {
+					key = strings.ToLower(e.Attrs["name"])
+					value = strings.TrimSpace(e.Attrs["value"])
+				}
+				if _, found := ret[key]; !found && value != "" {
+					ret[key] = value
+				}
+				walk(e.Children)
+			}
+		}
+	}
+	for _, elements := range extensions {
+		walk(elements)
+	}
+	return ret
+}
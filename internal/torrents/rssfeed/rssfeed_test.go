@@ -0,0 +1,97 @@
+package rssfeed
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const nyaaFeed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss xmlns:atom="http://www.w3.org/2005/Atom" xmlns:nyaa="https://nyaa.si/xmlns/nyaa" version="2.0">
+  <channel>
+    <title>Nyaa - Home - Torrent File RSS</title>
+    <item>
+      <title>[SubsPlease] Frieren - 01 (1080p) [ABCDEF01].mkv</title>
+      <link>https://nyaa.si/download/1.torrent</link>
+      <guid isPermaLink="true">https://nyaa.si/view/1</guid>
+      <pubDate>Fri, 29 Sep 2023 17:00:00 -0000</pubDate>
+      <nyaa:seeders>120</nyaa:seeders>
+      <nyaa:leechers>30</nyaa:leechers>
+      <nyaa:downloads>2000</nyaa:downloads>
+      <nyaa:infoHash>AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA</nyaa:infoHash>
+      <nyaa:size>1.5 GiB</nyaa:size>
+    </item>
+    <item>
+      <title>No download link</title>
+      <guid>https://nyaa.si/view/2</guid>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Release group feed</title>
+  <entry>
+    <title>[Group] Show - 05 [720p].mkv</title>
+    <id>tag:example.com,2023:5</id>
+    <link rel="alternate" href="https://example.com/releases/5"/>
+    <link rel="enclosure" href="magnet:?xt=urn:btih:BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB&amp;dn=Show"/>
+    <updated>2023-10-06T17:00:00Z</updated>
+  </entry>
+</feed>`
+
+func TestParseNyaa(t *testing.T) {
+	torrents, err := Parse([]byte(nyaaFeed))
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+
+	tr := torrents[0]
+	require.Equal(t, "[SubsPlease] Frieren - 01 (1080p) [ABCDEF01].mkv", tr.Name)
+	require.Equal(t, "https://nyaa.si/download/1.torrent", tr.DownloadUrl)
+	require.Equal(t, "https://nyaa.si/view/1", tr.Link)
+	require.Equal(t, "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa", tr.InfoHash)
+	require.Equal(t, 120, tr.Seeders)
+	require.Equal(t, 30, tr.Leechers)
+	require.Equal(t, 2000, tr.DownloadCount)
+	require.Equal(t, int64(1610612736), tr.Size)
+	require.Equal(t, "2023-09-29T17:00:00Z", tr.Date)
+	require.Equal(t, -1, tr.EpisodeNumber)
+}
+
+func TestParseAtomMagnet(t *testing.T) {
+	torrents, err := Parse([]byte(atomFeed))
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+
+	tr := torrents[0]
+	require.Equal(t, "bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb", tr.InfoHash)
+	require.Contains(t, tr.MagnetLink, "magnet:?xt=urn:btih:")
+	require.Equal(t, "https://example.com/releases/5", tr.Link)
+	require.Equal(t, -1, tr.Seeders)
+	require.Equal(t, "2023-10-06T17:00:00Z", tr.Date)
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/rss" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rss+xml")
+		_, _ = w.Write([]byte(nyaaFeed))
+	}))
+	defer server.Close()
+
+	torrents, err := Fetch(context.Background(), server.Client(), server.URL+"/rss")
+	require.NoError(t, err)
+	require.Len(t, torrents, 1)
+
+	_, err = Fetch(context.Background(), server.Client(), server.URL+"/missing")
+	require.Error(t, err)
+
+	_, err = Parse([]byte("not a feed"))
+	require.Error(t, err)
+}
@@ -0,0 +1,103 @@
+package torznab
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	magnetHashRegex = regexp.MustCompile(`(?i)xt=urn:btih:([a-z0-9]+)`)
+
+	errInvalidTorrent = errors.New("invalid torrent file")
+)
+
+// infoHashFromMagnet extracts the info hash from a magnet link.
+func infoHashFromMagnet(magnet string) string {
+	m := magnetHashRegex.FindStringSubmatch(magnet)
+	if len(m) < 2 {
+		return ""
+	}
+	return strings.ToLower(m[1])
+}
+
+// infoHashFromTorrentFile computes the v1 info hash of a .torrent file.
+// This only walks the bencoded structure to find the raw "info" dictionary, which is all we need.
+func infoHashFromTorrentFile(data []byte) (string, error) {
+	if len(data) == 0 || data[0] != 'd' {
+		return "", errInvalidTorrent
+	}
+
+	pos := 1
+	for pos < len(data) && data[pos] != 'e' {
+		key, next, err := readBencodeString(data, pos)
+		if err != nil {
+			return "", err
+		}
+		end, err := skipBencodeValue(data, next)
+		if err != nil {
+			return "", err
+		}
+		if key == "info" {
+			sum := sha1.Sum(data[next:end])
+			return hex.EncodeToString(sum[:]), nil
+		}
+		pos = end
+	}
+
+	return "", errInvalidTorrent
+}
+
+// readBencodeString reads a "<length>:<string>" value starting at pos.
+func readBencodeString(data []byte, pos int) (string, int, error) {
+	colon := bytes.IndexByte(data[pos:], ':')
+	if colon <= 0 {
+		return "", 0, errInvalidTorrent
+	}
+	length, err := strconv.Atoi(string(data[pos : pos+colon]))
+	if err != nil || length < 0 {
+		return "", 0, errInvalidTorrent
+	}
+	start := pos + colon + 1
+	if start+length > len(data) {
+		return "", 0, errInvalidTorrent
+	}
+	return string(data[start : start+length]), start + length, nil
+}
+
+// skipBencodeValue returns the position right after the value starting at pos.
+func skipBencodeValue(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return 0, errInvalidTorrent
+	}
+	switch c := data[pos]; {
+	case c == 'i':
+		end := bytes.IndexByte(data[pos:], 'e')
+		if end < 0 {
+			return 0, errInvalidTorrent
+		}
+		return pos + end + 1, nil
+	case c == 'l' || c == 'd':
+		pos++
+		for pos < len(data) && data[pos] != 'e' {
+			next, err := skipBencodeValue(data, pos)
+			if err != nil {
+				return 0, err
+			}
+			pos = next
+		}
+		if pos >= len(data) {
+			return 0, errInvalidTorrent
+		}
+		return pos + 1, nil
+	case c >= '0' && c <= '9':
+		_, next, err := readBencodeString(data, pos)
+		return next, err
+	default:
+		return 0, errInvalidTorrent
+	}
+}
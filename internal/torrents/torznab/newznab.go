@@ -0,0 +1,66 @@
+package torznab
+
+import (
+	"cmp"
+	"errors"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
+	"seanime/internal/usenet/usenet"
+	"strconv"
+	"strings"
+
+	"github.com/rs/zerolog"
+)
+
+const NewznabProviderID = "newznab"
+
+var (
+	ErrNzbRelease = errors.New("newznab: NZB releases cannot be downloaded with a torrent client")
+)
+
+// NewNewznabProvider returns a provider that queries Newznab-compatible Usenet indexers (NZBHydra2, Prowlarr, etc.).
+// Torznab is an extension of the Newznab API so the same client is used, but the results are NZB releases.
+// NZB releases don't have an info hash, it is replaced by usenet.ReleaseHash so that they can be deduplicated like torrents.
+func NewNewznabProvider(logger *zerolog.Logger) hibiketorrent.AnimeProvider {
+	p := NewProvider(logger).(*Provider)
+	p.nzb = true
+	return p
+}
+
+// IsNzbRelease returns true if the torrent is an NZB release returned by the Newznab provider.
+func IsNzbRelease(t *hibiketorrent.AnimeTorrent) bool {
+	return t != nil && t.Provider == NewznabProviderID
+}
+
+// toNzbRelease converts a Newznab feed item. It returns nil if the item cannot be downloaded.
+func toNzbRelease(item *feedItem) *hibiketorrent.AnimeTorrent {
+	ret := &hibiketorrent.AnimeTorrent{
+		Provider:      NewznabProviderID,
+		Name:          strings.TrimSpace(item.Title),
+		Date:          item.Date(),
+		Size:          item.Size,
+		Link:          cmp.Or(item.Comments, item.GUID),
+		Seeders:       -1,
+		Leechers:      -1,
+		DownloadCount: item.AttrInt("grabs"),
+		DownloadUrl:   cmp.Or(item.Enclosure.URL, item.Link),
+		EpisodeNumber: -1,
+	}
+
+	if ret.Size == 0 {
+		ret.Size = item.Enclosure.Length
+	}
+	if ret.Size == 0 {
+		ret.Size, _ = strconv.ParseInt(item.Attr("size"), 10, 64)
+	}
+
+	if ret.Name == "" || !strings.HasPrefix(ret.DownloadUrl, "http") {
+		return nil
+	}
+
+	ret.InfoHash = usenet.ReleaseHash(cmp.Or(item.GUID, ret.DownloadUrl))
+	if ret.Link == "" {
+		ret.Link = ret.DownloadUrl
+	}
+
+	return ret
+}
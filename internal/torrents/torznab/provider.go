@@ -0,0 +1,482 @@
+package torznab
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"seanime/internal/extension"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util/result"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	ProviderID = "torznab"
+
+	// Maximum number of torrent files downloaded at the same time to resolve missing info hashes.
+	resolveConcurrency = 5
+)
+
+var (
+	ErrNoIndexers = errors.New("torznab: no indexers configured")
+)
+
+// Provider is a built-in anime torrent provider that queries Torznab-compatible indexers.
+// It is configured through the extension user config.
+//
+//	indexers:   "<url>|<apikey>[|<cat>,<cat>]" entries separated by new lines or semicolons
+//	categories: comma-separated category IDs used for indexers that don't specify their own
+type Provider struct {
+	logger *zerolog.Logger
+	client *client
+	// Newznab indexers, the results are NZB releases, see NewNewznabProvider
+	nzb bool
+
+	mu         sync.RWMutex
+	indexers   []*Indexer
+	categories []int
+
+	// Capabilities per indexer URL, discovered on first use
+	caps *result.Map[string, *Caps]
+}
+
+func NewProvider(logger *zerolog.Logger) hibiketorrent.AnimeProvider {
+	return &Provider{
+		logger:   logger,
+		client:   newClient(),
+		indexers: make([]*Indexer, 0),
+		caps:     result.NewMap[string, *Caps](),
+	}
+}
+
+// UserConfig returns the user configuration of the built-in extension.
+func UserConfig() *extension.UserConfig {
+	return &extension.UserConfig{
+		Version:        1,
+		RequiresConfig: true,
+		Fields: []extension.ConfigField{
+			{
+				Type:  extension.ConfigFieldTypeText,
+				Name:  "indexers",
+				Label: "Indexers (url|apikey[|categories], separated by semicolons)",
+			},
+			{
+				Type:  extension.ConfigFieldTypeText,
+				Name:  "categories",
+				Label: "Categories (leave empty to discover them from the indexer)",
+			},
+		},
+	}
+}
+
+func (p *Provider) SetSavedUserConfig(config extension.SavedUserConfig) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.indexers = ParseIndexers(config.Values["indexers"])
+	p.categories = parseCategories(config.Values["categories"])
+	// Indexers might have changed, rediscover capabilities
+	p.caps.Clear()
+}
+
+// ParseIndexers parses the "indexers" config value.
+func ParseIndexers(value string) []*Indexer {
+	ret := make([]*Indexer, 0)
+	for _, entry := range strings.FieldsFunc(value, func(r rune) bool { return r == '\n' || r == ';' }) {
+		parts := strings.Split(strings.TrimSpace(entry), "|")
+		if len(parts) == 0 || strings.TrimSpace(parts[0]) == "" {
+			continue
+		}
+		indexer := &Indexer{URL: strings.TrimSpace(parts[0])}
+		if len(parts) > 1 {
+			indexer.APIKey = strings.TrimSpace(parts[1])
+		}
+		if len(parts) > 2 {
+			indexer.Categories = parseCategories(parts[2])
+		}
+		ret = append(ret, indexer)
+	}
+	return ret
+}
+
+func parseCategories(value string) []int {
+	ret := make([]int, 0)
+	for _, s := range strings.Split(value, ",") {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			ret = append(ret, id)
+		}
+	}
+	return ret
+}
+
+func (p *Provider) GetSettings() hibiketorrent.AnimeProviderSettings {
+	// NZB releases cannot be sent to torrent clients, so the Newznab provider cannot be used as the default provider
+	providerType := hibiketorrent.AnimeProviderTypeMain
+	if p.nzb {
+		providerType = hibiketorrent.AnimeProviderTypeSpecial
+	}
+	return hibiketorrent.AnimeProviderSettings{
+		CanSmartSearch: true,
+		SmartSearchFilters: []hibiketorrent.AnimeProviderSmartSearchFilter{
+			hibiketorrent.AnimeProviderSmartSearchFilterQuery,
+			hibiketorrent.AnimeProviderSmartSearchFilterEpisodeNumber,
+			hibiketorrent.AnimeProviderSmartSearchFilterResolution,
+			hibiketorrent.AnimeProviderSmartSearchFilterBatch,
+		},
+		SupportsAdult: false,
+		Type:          providerType,
+	}
+}
+
+func (p *Provider) Search(opts hibiketorrent.AnimeSearchOptions) ([]*hibiketorrent.AnimeTorrent, error) {
+	query := opts.Query
+	if query == "" {
+		query = opts.Media.RomajiTitle
+	}
+
+	return p.search(func(_ *Caps) url.Values {
+		return url.Values{"t": {"search"}, "q": {query}}
+	})
+}
+
+func (p *Provider) SmartSearch(opts hibiketorrent.AnimeSmartSearchOptions) ([]*hibiketorrent.AnimeTorrent, error) {
+	titles := make([]string, 0, 2)
+	if opts.Query != "" {
+		titles = append(titles, opts.Query)
+	} else {
+		if opts.Media.RomajiTitle != "" {
+			titles = append(titles, opts.Media.RomajiTitle)
+		}
+		if opts.Media.EnglishTitle != nil && *opts.Media.EnglishTitle != "" && *opts.Media.EnglishTitle != opts.Media.RomajiTitle {
+			titles = append(titles, *opts.Media.EnglishTitle)
+		}
+	}
+
+	ret := make([]*hibiketorrent.AnimeTorrent, 0)
+	for _, title := range titles {
+		torrents, err := p.search(func(caps *Caps) url.Values {
+			q := title
+			params := url.Values{"t": {"search"}}
+			if opts.EpisodeNumber > 0 && !opts.Batch {
+				// Prefer the tv-search mode when the indexer can filter by episode
+				if caps != nil && caps.Searching.TvSearch.Supports("ep") {
+					params.Set("t", "tvsearch")
+					params.Set("ep", strconv.Itoa(opts.EpisodeNumber))
+				} else {
+					q += fmt.Sprintf(" %02d", opts.EpisodeNumber)
+				}
+			}
+			if opts.Batch {
+				q += " batch"
+			}
+			if opts.Resolution != "" {
+				q += " " + opts.Resolution
+			}
+			params.Set("q", q)
+			return params
+		})
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, torrents...)
+	}
+
+	return mergeTorrents(ret), nil
+}
+
+func (p *Provider) GetLatest() ([]*hibiketorrent.AnimeTorrent, error) {
+	return p.search(func(_ *Caps) url.Values {
+		return url.Values{"t": {"search"}}
+	})
+}
+
+func (p *Provider) GetTorrentInfoHash(torrent *hibiketorrent.AnimeTorrent) (string, error) {
+	if torrent.InfoHash != "" {
+		return torrent.InfoHash, nil
+	}
+	if p.nzb {
+		return usenet.ReleaseHash(cmp.Or(torrent.DownloadUrl, torrent.Link)), nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	if err := p.resolveInfoHash(ctx, torrent); err != nil {
+		return "", err
+	}
+
+	return torrent.InfoHash, nil
+}
+
+func (p *Provider) GetTorrentMagnetLink(torrent *hibiketorrent.AnimeTorrent) (string, error) {
+	if p.nzb {
+		return "", ErrNzbRelease
+	}
+	if torrent.MagnetLink != "" {
+		return torrent.MagnetLink, nil
+	}
+
+	hash, err := p.GetTorrentInfoHash(torrent)
+	if err != nil {
+		return "", err
+	}
+
+	if torrent.MagnetLink != "" {
+		return torrent.MagnetLink, nil
+	}
+
+	return "magnet:?xt=urn:btih:" + hash + "&dn=" + url.QueryEscape(torrent.Name), nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// search queries every indexer concurrently and merges the results.
+// paramsFn builds the request parameters from the indexer's capabilities, which may be nil if they could not be fetched.
+func (p *Provider) search(paramsFn func(caps *Caps) url.Values) ([]*hibiketorrent.AnimeTorrent, error) {
+	p.mu.RLock()
+	indexers := p.indexers
+	categories := p.categories
+	p.mu.RUnlock()
+
+	if len(indexers) == 0 {
+		return nil, ErrNoIndexers
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		torrents = make([]*hibiketorrent.AnimeTorrent, 0)
+		errs     = make([]error, 0)
+	)
+
+	for _, indexer := range indexers {
+		wg.Add(1)
+		go func(indexer *Indexer) {
+			defer wg.Done()
+
+			caps := p.getCaps(ctx, indexer)
+
+			params := paramsFn(caps)
+			if cats := p.getCategories(indexer, caps, categories); len(cats) > 0 {
+				params.Set("cat", joinCategories(cats))
+			}
+
+			items, err := p.client.Search(ctx, indexer, params)
+			if err != nil {
+				p.logger.Error().Err(err).Str("indexer", indexer.URL).Msg("torznab: Search failed")
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				return
+			}
+
+			mu.Lock()
+			for _, item := range items {
+				convert := toAnimeTorrent
+				if p.nzb {
+					convert = toNzbRelease
+				}
+				if t := convert(item); t != nil {
+					torrents = append(torrents, t)
+				}
+			}
+			mu.Unlock()
+		}(indexer)
+	}
+	wg.Wait()
+
+	// Only fail if every indexer failed
+	if len(errs) == len(indexers) {
+		return nil, errors.Join(errs...)
+	}
+
+	if !p.nzb {
+		p.resolveInfoHashes(ctx, torrents)
+	}
+
+	return mergeTorrents(torrents), nil
+}
+
+// getCaps returns the cached capabilities of the indexer, fetching them if needed.
+func (p *Provider) getCaps(ctx context.Context, indexer *Indexer) *Caps {
+	if caps, ok := p.caps.Get(indexer.URL); ok {
+		return caps
+	}
+
+	caps, err := p.client.Caps(ctx, indexer)
+	if err != nil {
+		p.logger.Warn().Err(err).Str("indexer", indexer.URL).Msg("torznab: Failed to fetch capabilities")
+		return nil
+	}
+
+	p.caps.Set(indexer.URL, caps)
+	return caps
+}
+
+// getCategories returns the categories to search in, in order of priority:
+// the indexer's own categories, the global categories, the anime categories discovered from the capabilities, the default anime category.
+func (p *Provider) getCategories(indexer *Indexer, caps *Caps, global []int) []int {
+	if len(indexer.Categories) > 0 {
+		return indexer.Categories
+	}
+	if len(global) > 0 {
+		return global
+	}
+	if caps != nil {
+		if cats := caps.AnimeCategories(); len(cats) > 0 {
+			return cats
+		}
+	}
+	return []int{DefaultAnimeCategory}
+}
+
+func joinCategories(cats []int) string {
+	s := make([]string, len(cats))
+	for i, c := range cats {
+		s[i] = strconv.Itoa(c)
+	}
+	return strings.Join(s, ",")
+}
+
+// resolveInfoHashes downloads the torrent files of results that don't expose an info hash.
+// The AutoDownloader ignores torrents without info hashes.
+func (p *Provider) resolveInfoHashes(ctx context.Context, torrents []*hibiketorrent.AnimeTorrent) {
+	sem := make(chan struct{}, resolveConcurrency)
+	wg := sync.WaitGroup{}
+	for _, t := range torrents {
+		if t.InfoHash != "" || t.DownloadUrl == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(t *hibiketorrent.AnimeTorrent) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			if err := p.resolveInfoHash(ctx, t); err != nil {
+				p.logger.Debug().Err(err).Str("name", t.Name).Msg("torznab: Could not resolve info hash")
+			}
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (p *Provider) resolveInfoHash(ctx context.Context, t *hibiketorrent.AnimeTorrent) error {
+	if t.DownloadUrl == "" {
+		return fmt.Errorf("torznab: no download url for %q", t.Name)
+	}
+
+	data, magnet, err := p.client.Download(ctx, t.DownloadUrl)
+	if err != nil {
+		return err
+	}
+
+	if magnet != "" {
+		t.MagnetLink = magnet
+		t.InfoHash = infoHashFromMagnet(magnet)
+		if t.InfoHash == "" {
+			return fmt.Errorf("torznab: invalid magnet link for %q", t.Name)
+		}
+		return nil
+	}
+
+	hash, err := infoHashFromTorrentFile(data)
+	if err != nil {
+		return err
+	}
+	t.InfoHash = hash
+	return nil
+}
+
+// toAnimeTorrent converts a feed item. It returns nil if the item cannot be downloaded.
+func toAnimeTorrent(item *feedItem) *hibiketorrent.AnimeTorrent {
+	ret := &hibiketorrent.AnimeTorrent{
+		Provider:      ProviderID,
+		Name:          strings.TrimSpace(item.Title),
+		Date:          item.Date(),
+		Size:          item.Size,
+		Link:          item.Comments,
+		EpisodeNumber: -1,
+	}
+
+	if ret.Size == 0 {
+		ret.Size = item.Enclosure.Length
+	}
+	if ret.Size == 0 {
+		ret.Size, _ = strconv.ParseInt(item.Attr("size"), 10, 64)
+	}
+
+	ret.Seeders = item.AttrInt("seeders")
+	if peers := item.AttrInt("peers"); peers >= ret.Seeders {
+		ret.Leechers = peers - ret.Seeders
+	}
+	ret.DownloadCount = item.AttrInt("grabs")
+
+	// The download link can be a magnet link or a .torrent URL
+	downloadUrl := item.Link
+	if downloadUrl == "" {
+		downloadUrl = item.Enclosure.URL
+	}
+	if strings.HasPrefix(downloadUrl, "magnet:") {
+		ret.MagnetLink = downloadUrl
+	} else {
+		ret.DownloadUrl = downloadUrl
+	}
+	if magnet := item.Attr("magneturl"); magnet != "" {
+		ret.MagnetLink = magnet
+	}
+
+	ret.InfoHash = strings.ToLower(item.Attr("infohash"))
+	if ret.InfoHash == "" && ret.MagnetLink != "" {
+		ret.InfoHash = infoHashFromMagnet(ret.MagnetLink)
+	}
+
+	if ret.Link == "" {
+		ret.Link = item.GUID
+	}
+	if ret.Link == "" {
+		ret.Link = downloadUrl
+	}
+
+	if ret.Name == "" || (ret.MagnetLink == "" && ret.DownloadUrl == "") {
+		return nil
+	}
+
+	return ret
+}
+
+// mergeTorrents removes duplicates returned by multiple indexers and sorts the results by date, newest first.
+// Torrents are deduplicated by info hash, or by link if the info hash is unknown.
+func mergeTorrents(torrents []*hibiketorrent.AnimeTorrent) []*hibiketorrent.AnimeTorrent {
+	ret := make([]*hibiketorrent.AnimeTorrent, 0, len(torrents))
+	seen := make(map[string]struct{})
+	for _, t := range torrents {
+		key := t.InfoHash
+		if key == "" {
+			key = cmp.Or(t.DownloadUrl, t.MagnetLink, t.Link)
+		}
+		if _, found := seen[key]; found {
+			continue
+		}
+		seen[key] = struct{}{}
+		ret = append(ret, t)
+	}
+
+	slices.SortStableFunc(ret, func(a, b *hibiketorrent.AnimeTorrent) int {
+		return strings.Compare(b.Date, a.Date)
+	})
+
+	return ret
+}
@@ -0,0 +1,277 @@
+package torznab
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"seanime/internal/constants"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default Newznab/Torznab category for anime ("TV/Anime").
+const DefaultAnimeCategory = 5070
+
+// Maximum size of indexer responses and torrent files
+const maxResponseSize = 10 << 20
+
+var ErrResponseTooLarge = errors.New("indexer response is too large")
+
+type (
+	// Indexer is a single Torznab endpoint configured by the user.
+	// e.g. a Jackett or Prowlarr indexer feed.
+	Indexer struct {
+		URL    string
+		APIKey string
+		// Categories overrides the categories discovered from the indexer's capabilities.
+		Categories []int
+	}
+
+	// Caps is the result of a "t=caps" request.
+	Caps struct {
+		XMLName    xml.Name       `xml:"caps"`
+		Searching  CapsSearching  `xml:"searching"`
+		Categories []CapsCategory `xml:"categories>category"`
+	}
+
+	CapsSearching struct {
+		Search   CapsSearchMode `xml:"search"`
+		TvSearch CapsSearchMode `xml:"tv-search"`
+	}
+
+	CapsSearchMode struct {
+		Available       string `xml:"available,attr"`
+		SupportedParams string `xml:"supportedParams,attr"`
+	}
+
+	CapsCategory struct {
+		ID      int            `xml:"id,attr"`
+		Name    string         `xml:"name,attr"`
+		Subcats []CapsCategory `xml:"subcat"`
+	}
+
+	// feed is the RSS document returned by search requests.
+	feed struct {
+		XMLName xml.Name `xml:"rss"`
+		Channel struct {
+			Items []*feedItem `xml:"item"`
+		} `xml:"channel"`
+	}
+
+	feedItem struct {
+		Title     string `xml:"title"`
+		GUID      string `xml:"guid"`
+		Link      string `xml:"link"`
+		Comments  string `xml:"comments"`
+		PubDate   string `xml:"pubDate"`
+		Size      int64  `xml:"size"`
+		Enclosure struct {
+			URL    string `xml:"url,attr"`
+			Length int64  `xml:"length,attr"`
+		} `xml:"enclosure"`
+		Attrs []struct {
+			Name  string `xml:"name,attr"`
+			Value string `xml:"value,attr"`
+		} `xml:"attr"`
+	}
+
+	// errorResponse is returned by indexers instead of a feed when a request fails.
+	// e.g. <error code="100" description="Incorrect user credentials"/>
+	errorResponse struct {
+		XMLName     xml.Name `xml:"error"`
+		Code        string   `xml:"code,attr"`
+		Description string   `xml:"description,attr"`
+	}
+)
+
+// Supports returns true if the search mode is available and accepts the given parameter.
+func (m CapsSearchMode) Supports(param string) bool {
+	if m.Available != "yes" {
+		return false
+	}
+	for _, p := range strings.Split(m.SupportedParams, ",") {
+		if strings.TrimSpace(p) == param {
+			return true
+		}
+	}
+	return false
+}
+
+// AnimeCategories returns the IDs of every category or subcategory whose name mentions anime.
+func (c *Caps) AnimeCategories() []int {
+	ret := make([]int, 0)
+	var walk func(cats []CapsCategory)
+	walk = func(cats []CapsCategory) {
+		for _, cat := range cats {
+			if strings.Contains(strings.ToLower(cat.Name), "anime") {
+				ret = append(ret, cat.ID)
+			}
+			walk(cat.Subcats)
+		}
+	}
+	walk(c.Categories)
+	return ret
+}
+
+// Attr returns the value of a torznab/newznab attribute.
+func (i *feedItem) Attr(name string) string {
+	for _, attr := range i.Attrs {
+		if strings.EqualFold(attr.Name, name) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func (i *feedItem) AttrInt(name string) int {
+	v, _ := strconv.Atoi(i.Attr(name))
+	return v
+}
+
+// Date returns the publication date in RFC3339 format, or an empty string if it cannot be parsed.
+func (i *feedItem) Date() string {
+	for _, layout := range []string{time.RFC1123Z, time.RFC1123, time.RFC3339} {
+		if t, err := time.Parse(layout, strings.TrimSpace(i.PubDate)); err == nil {
+			return t.UTC().Format(time.RFC3339)
+		}
+	}
+	return ""
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type client struct {
+	httpClient *http.Client
+}
+
+func newClient() *client {
+	return &client{
+		httpClient: &http.Client{
+			Timeout: 30 * time.Second,
+			// Some indexers redirect the download link to a magnet URI, which the client cannot follow.
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if req.URL.Scheme == "magnet" {
+					return http.ErrUseLastResponse
+				}
+				if len(via) >= 10 {
+					return fmt.Errorf("stopped after 10 redirects")
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// get sends a request to the indexer's API endpoint and returns the response body.
+func (c *client) get(ctx context.Context, indexer *Indexer, params url.Values) ([]byte, error) {
+	u, err := url.Parse(indexer.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid indexer url: %w", err)
+	}
+
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	if indexer.APIKey != "" {
+		query.Set("apikey", indexer.APIKey)
+	}
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Seanime/"+constants.Version)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > maxResponseSize {
+		return nil, ErrResponseTooLarge
+	}
+
+	var apiErr errorResponse
+	if xml.Unmarshal(body, &apiErr) == nil && apiErr.XMLName.Local == "error" {
+		return nil, fmt.Errorf("indexer returned error %s: %s", apiErr.Code, apiErr.Description)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("indexer returned status %d", resp.StatusCode)
+	}
+
+	return body, nil
+}
+
+// Caps fetches the capabilities of the indexer.
+func (c *client) Caps(ctx context.Context, indexer *Indexer) (*Caps, error) {
+	body, err := c.get(ctx, indexer, url.Values{"t": {"caps"}})
+	if err != nil {
+		return nil, err
+	}
+
+	var caps Caps
+	if err := xml.Unmarshal(body, &caps); err != nil {
+		return nil, fmt.Errorf("failed to decode capabilities: %w", err)
+	}
+
+	return &caps, nil
+}
+
+// Search sends a search request and returns the items of the resulting feed.
+func (c *client) Search(ctx context.Context, indexer *Indexer, params url.Values) ([]*feedItem, error) {
+	body, err := c.get(ctx, indexer, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var f feed
+	if err := xml.Unmarshal(body, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode feed: %w", err)
+	}
+
+	return f.Channel.Items, nil
+}
+
+// Download fetches the torrent file at the given URL.
+// If the URL redirects to a magnet link, the magnet link is returned instead.
+func (c *client) Download(ctx context.Context, link string) (data []byte, magnet string, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	req.Header.Set("User-Agent", "Seanime/"+constants.Version)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if location := resp.Header.Get("Location"); strings.HasPrefix(location, "magnet:") {
+		return nil, location, nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("download returned status %d", resp.StatusCode)
+	}
+
+	data, err = io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return nil, "", err
+	}
+
+	return data, "", nil
+}
@@ -0,0 +1,287 @@
+package torznab
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"seanime/internal/extension"
+	hibiketorrent "seanime/internal/extension/hibike/torrent"
+	"seanime/internal/util"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testCaps = `<?xml version="1.0" encoding="UTF-8"?>
+<caps>
+  <searching>
+    <search available="yes" supportedParams="q"/>
+    <tv-search available="yes" supportedParams="q,season,ep"/>
+  </searching>
+  <categories>
+    <category id="5000" name="TV">
+      <subcat id="5070" name="TV/Anime"/>
+    </category>
+    <category id="127720" name="Anime"/>
+    <category id="2000" name="Movies"/>
+  </categories>
+</caps>`
+
+const testTorrentFile = "d8:announce3:url4:infod4:name4:test12:piece lengthi16384eee"
+
+type fakeIndexer struct {
+	server    *httptest.Server
+	apiKey    string
+	capsCalls atomic.Int32
+	lastQuery atomic.Value
+}
+
+func newFakeIndexer(t *testing.T, apiKey string) *fakeIndexer {
+	t.Helper()
+
+	f := &fakeIndexer{apiKey: apiKey}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download/3" {
+			_, _ = w.Write([]byte(testTorrentFile))
+			return
+		}
+
+		query := r.URL.Query()
+		if query.Get("apikey") != f.apiKey {
+			_, _ = fmt.Fprint(w, `<error code="100" description="Incorrect user credentials"/>`)
+			return
+		}
+
+		switch query.Get("t") {
+		case "caps":
+			f.capsCalls.Add(1)
+			_, _ = fmt.Fprint(w, testCaps)
+		case "search", "tvsearch":
+			f.lastQuery.Store(query)
+			_, _ = fmt.Fprintf(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:torznab="http://torznab.com/schemas/2015/feed">
+  <channel>
+    <item>
+      <title>[SubsPlease] Frieren - 01 (1080p) [ABCDEF01].mkv</title>
+      <guid>%[1]s/details/1</guid>
+      <comments>%[1]s/details/1</comments>
+      <link>magnet:?xt=urn:btih:AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA&amp;dn=Frieren</link>
+      <pubDate>Fri, 29 Sep 2023 17:00:00 +0000</pubDate>
+      <size>1400000000</size>
+      <torznab:attr name="seeders" value="120"/>
+      <torznab:attr name="peers" value="150"/>
+      <torznab:attr name="grabs" value="2000"/>
+    </item>
+    <item>
+      <title>[SubsPlease] Frieren - 02 (1080p) [ABCDEF02].mkv</title>
+      <guid>%[1]s/details/2</guid>
+      <link>%[1]s/download/2</link>
+      <pubDate>Fri, 06 Oct 2023 17:00:00 +0000</pubDate>
+      <enclosure url="%[1]s/download/2" length="1300000000" type="application/x-bittorrent"/>
+      <torznab:attr name="infohash" value="BBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBBB"/>
+      <torznab:attr name="seeders" value="80"/>
+    </item>
+    <item>
+      <title>[SubsPlease] Frieren - 03 (1080p) [ABCDEF03].mkv</title>
+      <guid>%[1]s/details/3</guid>
+      <link>%[1]s/download/3</link>
+      <pubDate>Fri, 13 Oct 2023 17:00:00 +0000</pubDate>
+    </item>
+  </channel>
+</rss>`, f.server.URL)
+		default:
+			http.Error(w, "unknown function", http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func newTestProvider(t *testing.T, indexers string, categories string) *Provider {
+	t.Helper()
+
+	p := NewProvider(util.NewLogger()).(*Provider)
+	p.SetSavedUserConfig(extension.SavedUserConfig{
+		Version: 1,
+		Values: map[string]string{
+			"indexers":   indexers,
+			"categories": categories,
+		},
+	})
+	return p
+}
+
+func TestParseIndexers(t *testing.T) {
+	indexers := ParseIndexers("http://localhost:9117/api|key1;\n http://localhost:9696/1/api | key2 | 5070,127720 ;;")
+	require.Len(t, indexers, 2)
+
+	require.Equal(t, "http://localhost:9117/api", indexers[0].URL)
+	require.Equal(t, "key1", indexers[0].APIKey)
+	require.Empty(t, indexers[0].Categories)
+
+	require.Equal(t, "http://localhost:9696/1/api", indexers[1].URL)
+	require.Equal(t, "key2", indexers[1].APIKey)
+	require.Equal(t, []int{5070, 127720}, indexers[1].Categories)
+}
+
+func TestSearch(t *testing.T) {
+	indexer := newFakeIndexer(t, "secret")
+	p := newTestProvider(t, indexer.server.URL+"|secret", "")
+
+	torrents, err := p.Search(hibiketorrent.AnimeSearchOptions{Query: "Frieren"})
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+
+	// Categories are discovered from the capabilities
+	query := indexer.lastQuery.Load().(url.Values)
+	require.Equal(t, []string{"5070,127720"}, query["cat"])
+	require.Equal(t, []string{"Frieren"}, query["q"])
+
+	// Sorted by date, newest first
+	require.Contains(t, torrents[0].Name, "Frieren - 03")
+	require.Contains(t, torrents[2].Name, "Frieren - 01")
+
+	// Info hash from the magnet link
+	ep1 := torrents[2]
+	require.Equal(t, ProviderID, ep1.Provider)
+	require.Equal(t, strings.Repeat("a", 40), ep1.InfoHash)
+	require.Equal(t, 120, ep1.Seeders)
+	require.Equal(t, 30, ep1.Leechers)
+	require.Equal(t, 2000, ep1.DownloadCount)
+	require.Equal(t, "2023-09-29T17:00:00Z", ep1.Date)
+	require.Equal(t, -1, ep1.EpisodeNumber)
+
+	// Info hash from the torznab attribute
+	ep2 := torrents[1]
+	require.Equal(t, strings.Repeat("b", 40), ep2.InfoHash)
+	require.Equal(t, int64(1300000000), ep2.Size)
+	require.Equal(t, indexer.server.URL+"/download/2", ep2.DownloadUrl)
+
+	// Info hash resolved from the torrent file
+	ep3 := torrents[0]
+	require.Len(t, ep3.InfoHash, 40)
+	magnet, err := p.GetTorrentMagnetLink(ep3)
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(magnet, "magnet:?xt=urn:btih:"+ep3.InfoHash))
+}
+
+func TestSmartSearchUsesTvSearch(t *testing.T) {
+	indexer := newFakeIndexer(t, "secret")
+	p := newTestProvider(t, indexer.server.URL+"|secret|5070", "")
+
+	_, err := p.SmartSearch(hibiketorrent.AnimeSmartSearchOptions{
+		Media:         hibiketorrent.Media{RomajiTitle: "Sousou no Frieren"},
+		EpisodeNumber: 2,
+		Resolution:    "1080",
+	})
+	require.NoError(t, err)
+
+	query := indexer.lastQuery.Load().(url.Values)
+	require.Equal(t, []string{"tvsearch"}, query["t"])
+	require.Equal(t, []string{"2"}, query["ep"])
+	require.Equal(t, []string{"Sousou no Frieren 1080"}, query["q"])
+	require.Equal(t, []string{"5070"}, query["cat"])
+
+	// Capabilities are cached
+	_, err = p.GetLatest()
+	require.NoError(t, err)
+	require.Equal(t, int32(1), indexer.capsCalls.Load())
+}
+
+func TestSearchMultipleIndexers(t *testing.T) {
+	indexer1 := newFakeIndexer(t, "key1")
+	indexer2 := newFakeIndexer(t, "key2")
+	p := newTestProvider(t, indexer1.server.URL+"|key1;"+indexer2.server.URL+"|wrong", "")
+
+	// One indexer failing doesn't fail the search
+	torrents, err := p.GetLatest()
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+
+	// Duplicates across indexers are merged
+	p = newTestProvider(t, indexer1.server.URL+"|key1;"+indexer2.server.URL+"|key2", "")
+	torrents, err = p.GetLatest()
+	require.NoError(t, err)
+	require.Len(t, torrents, 3)
+
+	p = newTestProvider(t, indexer2.server.URL+"|wrong", "")
+	_, err = p.GetLatest()
+	require.ErrorContains(t, err, "Incorrect user credentials")
+
+	p = newTestProvider(t, "", "")
+	_, err = p.GetLatest()
+	require.ErrorIs(t, err, ErrNoIndexers)
+}
+
+func TestInfoHashFromTorrentFile(t *testing.T) {
+	hash, err := infoHashFromTorrentFile([]byte(testTorrentFile))
+	require.NoError(t, err)
+	// sha1("d4:name4:test12:piece lengthi16384ee")
+	require.Equal(t, "18f630e31806cc7055fdc88f6bb7301051eee4c2", hash)
+
+	_, err = infoHashFromTorrentFile([]byte("d4:name4:test"))
+	require.Error(t, err)
+}
+
+func TestNewznabSearch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("t") {
+		case "caps":
+			_, _ = fmt.Fprint(w, testCaps)
+		default:
+			_, _ = fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:newznab="http://www.newznab.com/DTD/2010/feeds/attributes/">
+  <channel>
+    <item>
+      <title>[SubsPlease] Frieren - 01 (1080p) [ABCDEF01]</title>
+      <guid isPermaLink="true">https://nzb.example/details/abc</guid>
+      <link>https://nzb.example/getnzb/abc.nzb&amp;apikey=secret</link>
+      <pubDate>Fri, 29 Sep 2023 17:00:00 +0000</pubDate>
+      <enclosure url="https://nzb.example/getnzb/abc.nzb&amp;apikey=secret" length="1400000000" type="application/x-nzb"/>
+      <newznab:attr name="grabs" value="15"/>
+    </item>
+    <item>
+      <title>No download link</title>
+      <guid>https://nzb.example/details/def</guid>
+    </item>
+  </channel>
+</rss>`)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	p := NewNewznabProvider(util.NewLogger()).(*Provider)
+	p.SetSavedUserConfig(extension.SavedUserConfig{Values: map[string]string{"indexers": server.URL + "|secret"}})
+	require.Equal(t, hibiketorrent.AnimeProviderTypeSpecial, p.GetSettings().Type)
+
+	releases, err := p.Search(hibiketorrent.AnimeSearchOptions{Query: "Frieren"})
+	require.NoError(t, err)
+	require.Len(t, releases, 1)
+
+	release := releases[0]
+	require.True(t, IsNzbRelease(release))
+	require.Equal(t, "https://nzb.example/getnzb/abc.nzb&apikey=secret", release.DownloadUrl)
+	require.Equal(t, "https://nzb.example/details/abc", release.Link)
+	require.Equal(t, int64(1400000000), release.Size)
+	require.Equal(t, 15, release.DownloadCount)
+	require.Equal(t, -1, release.Seeders)
+	require.Len(t, release.InfoHash, 40)
+
+	_, err = p.GetTorrentMagnetLink(release)
+	require.ErrorIs(t, err, ErrNzbRelease)
+}
+
+func TestGetRejectsLargeResponses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(strings.Repeat("a", maxResponseSize+1)))
+	}))
+	t.Cleanup(server.Close)
+
+	_, err := newClient().Caps(t.Context(), &Indexer{URL: server.URL})
+	require.ErrorIs(t, err, ErrResponseTooLarge)
+}
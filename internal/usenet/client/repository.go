@@ -0,0 +1,319 @@
+package usenet_client
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/notifier"
+	"seanime/internal/usenet/nzbget"
+	"seanime/internal/usenet/sabnzbd"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/samber/mo"
+)
+
+var (
+	ErrProviderNotSet = fmt.Errorf("usenet: Provider not set")
+
+	// WatchInterval is the interval at which the jobs added by Seanime are checked
+	WatchInterval = 30 * time.Second
+)
+
+type (
+	// Repository sends NZBs to the Usenet download client and tracks the jobs added by Seanime.
+	// Completed jobs are moved to their destination and handed to the scanner.
+	Repository struct {
+		provider            mo.Option[usenet.Client]
+		logger              *zerolog.Logger
+		db                  *db.Database
+		settings            *models.UsenetSettings
+		wsEventManager      events.WSEventManagerInterface
+		watchLoopCancelFunc context.CancelFunc
+		onJobCompleted      func()
+	}
+
+	NewRepositoryOptions struct {
+		Logger         *zerolog.Logger
+		WSEventManager events.WSEventManagerInterface
+		Database       *db.Database
+	}
+)
+
+func NewRepository(opts *NewRepositoryOptions) *Repository {
+	return &Repository{
+		provider:       mo.None[usenet.Client](),
+		logger:         opts.Logger,
+		wsEventManager: opts.WSEventManager,
+		db:             opts.Database,
+		settings: &models.UsenetSettings{
+			Enabled: false,
+		},
+	}
+}
+
+// SetOnJobCompleted sets the function called after a tracked job has completed and its files have been moved.
+func (r *Repository) SetOnJobCompleted(fn func()) {
+	r.onJobCompleted = fn
+}
+
+// InitializeProvider is called each time the settings change
+func (r *Repository) InitializeProvider(settings *models.UsenetSettings) error {
+	r.settings = settings
+
+	if !settings.Enabled {
+		r.provider = mo.None[usenet.Client]()
+		r.startOrStopWatchLoop()
+		return nil
+	}
+
+	switch settings.Provider {
+	case "sabnzbd":
+		r.provider = mo.Some(sabnzbd.NewSABnzbd(r.logger, sabnzbd.NewSABnzbdOptions{
+			Host:     settings.Host,
+			ApiKey:   settings.ApiKey,
+			Category: settings.Category,
+		}))
+	case "nzbget":
+		r.provider = mo.Some(nzbget.NewNZBGet(r.logger, nzbget.NewNZBGetOptions{
+			Host:     settings.Host,
+			Username: settings.Username,
+			Password: settings.Password,
+			Category: settings.Category,
+		}))
+	default:
+		r.provider = mo.None[usenet.Client]()
+	}
+
+	if r.provider.IsAbsent() {
+		r.logger.Warn().Str("provider", settings.Provider).Msg("usenet: No provider set")
+	}
+
+	r.startOrStopWatchLoop()
+
+	return nil
+}
+
+func (r *Repository) HasProvider() bool {
+	return r.provider.IsPresent()
+}
+
+func (r *Repository) GetProvider() (usenet.Client, error) {
+	p, found := r.provider.Get()
+	if !found {
+		return nil, ErrProviderNotSet
+	}
+
+	return p, nil
+}
+
+func (r *Repository) GetSettings() *models.UsenetSettings {
+	return r.settings
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// AddNzb sends the NZB to the download client and tracks the job.
+// Once the job completes, its files are moved to the destination (if any) and the scanner is notified.
+//   - hash is the release hash of the NZB, used to mark the AutoDownloader item as downloaded.
+func (r *Repository) AddNzb(opts usenet.AddNzbOptions, destination string, mediaId int, hash string) (string, error) {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return "", err
+	}
+
+	jobId, err := provider.AddNzb(opts)
+	if err != nil {
+		return "", err
+	}
+
+	err = r.db.InsertUsenetJobItem(&models.UsenetJobItem{
+		JobID:       jobId,
+		Provider:    provider.GetSettings().ID,
+		Name:        opts.Name,
+		Hash:        hash,
+		Destination: destination,
+		MediaId:     mediaId,
+	})
+	if err != nil {
+		r.logger.Error().Err(err).Str("jobId", jobId).Msg("usenet: Failed to save job")
+	}
+
+	r.logger.Info().Str("jobId", jobId).Str("name", opts.Name).Msg("usenet: NZB added")
+
+	return jobId, nil
+}
+
+func (r *Repository) GetJobs() ([]*usenet.Job, error) {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return nil, err
+	}
+
+	return provider.GetJobs()
+}
+
+func (r *Repository) DeleteJob(id string, deleteFiles bool) error {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return err
+	}
+
+	if err := provider.DeleteJob(id, deleteFiles); err != nil {
+		return err
+	}
+
+	_ = r.db.DeleteUsenetJobItemByJobId(id)
+
+	return nil
+}
+
+func (r *Repository) TestConnection() (string, error) {
+	provider, err := r.GetProvider()
+	if err != nil {
+		return "", err
+	}
+
+	return provider.TestConnection()
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (r *Repository) startOrStopWatchLoop() {
+	// Cancel the previous loop if it's running
+	if r.watchLoopCancelFunc != nil {
+		r.watchLoopCancelFunc()
+		r.watchLoopCancelFunc = nil
+	}
+
+	provider, found := r.provider.Get()
+	if !r.settings.Enabled || !found {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.watchLoopCancelFunc = cancel
+
+	r.logger.Trace().Msg("usenet: Starting watch loop")
+	go func() {
+		ticker := time.NewTicker(WatchInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				r.logger.Trace().Msg("usenet: Watch loop stopped")
+				return
+			case <-ticker.C:
+				r.processTrackedJobs(provider)
+			}
+		}
+	}()
+}
+
+// processTrackedJobs checks the status of the jobs added by Seanime.
+func (r *Repository) processTrackedJobs(provider usenet.Client) {
+	defer util.HandlePanicInModuleThen("usenet/processTrackedJobs", func() {})
+
+	items, err := r.db.GetUsenetJobItems()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("usenet: Failed to get tracked jobs")
+		return
+	}
+
+	providerId := provider.GetSettings().ID
+	items = filterItems(items, providerId)
+	if len(items) == 0 {
+		return
+	}
+
+	jobs, err := provider.GetJobs()
+	if err != nil {
+		r.logger.Error().Err(err).Msg("usenet: Failed to get jobs")
+		return
+	}
+
+	jobMap := make(map[string]*usenet.Job, len(jobs))
+	for _, job := range jobs {
+		jobMap[job.ID] = job
+	}
+
+	completed := 0
+	for _, item := range items {
+		job, found := jobMap[item.JobID]
+		if !found {
+			// The job was removed from the client
+			r.logger.Debug().Str("jobId", item.JobID).Msg("usenet: Tracked job not found, removing it")
+			_ = r.db.DeleteUsenetJobItemByDbId(item.ID)
+			continue
+		}
+
+		switch job.Status {
+		case usenet.JobStatusCompleted:
+			if r.handleCompletedJob(item, job) {
+				completed++
+			}
+		case usenet.JobStatusFailed:
+			r.logger.Warn().Str("jobId", job.ID).Str("error", job.Error).Msg("usenet: Job failed")
+			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("usenet: Failed to download %q: %s", job.Name, job.Error))
+			_ = r.db.DeleteUsenetJobItemByDbId(item.ID)
+		}
+	}
+
+	if completed > 0 && r.onJobCompleted != nil {
+		r.onJobCompleted()
+	}
+}
+
+// handleCompletedJob moves the files of the job to the destination and stops tracking the job.
+func (r *Repository) handleCompletedJob(item *models.UsenetJobItem, job *usenet.Job) bool {
+	r.logger.Debug().Str("jobId", job.ID).Str("storagePath", job.StoragePath).Msg("usenet: Job completed")
+
+	if item.Destination != "" && job.StoragePath != "" && !isSubPath(item.Destination, job.StoragePath) {
+		if _, err := os.Stat(job.StoragePath); err != nil {
+			// The download client is probably running on another machine
+			r.logger.Warn().Err(err).Str("storagePath", job.StoragePath).Msg("usenet: Downloaded files are not accessible, they will not be moved")
+		} else if err := util.MoveToDestination(job.StoragePath, item.Destination); err != nil {
+			r.logger.Error().Err(err).Str("storagePath", job.StoragePath).Str("destination", item.Destination).Msg("usenet: Failed to move downloaded files")
+			r.wsEventManager.SendEvent(events.ErrorToast, fmt.Sprintf("usenet: Failed to move downloaded files: %v", err))
+			return false
+		}
+	}
+
+	if err := r.db.MarkAutoDownloaderItemsDownloaded(item.MediaId, item.Hash); err != nil {
+		r.logger.Error().Err(err).Msg("usenet: Failed to update auto downloader item")
+	}
+
+	if err := r.db.DeleteUsenetJobItemByDbId(item.ID); err != nil {
+		r.logger.Error().Err(err).Msg("usenet: Failed to remove tracked job")
+	}
+
+	notifier.GlobalNotifier.Notify(notifier.Usenet, fmt.Sprintf("Downloaded %q", job.Name))
+
+	return true
+}
+
+func filterItems(items []*models.UsenetJobItem, providerId string) []*models.UsenetJobItem {
+	ret := make([]*models.UsenetJobItem, 0, len(items))
+	for _, item := range items {
+		if item.Provider == "" || item.Provider == providerId {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// isSubPath returns true if path is inside dir.
+func isSubPath(dir string, path string) bool {
+	rel, err := filepath.Rel(filepath.Clean(dir), filepath.Clean(path))
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
@@ -0,0 +1,134 @@
+package usenet_client
+
+import (
+	"os"
+	"path/filepath"
+	"seanime/internal/database/models"
+	"seanime/internal/events"
+	"seanime/internal/testutil"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"testing"
+
+	"github.com/samber/mo"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeUsenetClient struct {
+	jobs  []*usenet.Job
+	added []usenet.AddNzbOptions
+}
+
+func (f *fakeUsenetClient) GetSettings() usenet.Settings {
+	return usenet.Settings{ID: "fake-client", Name: "Fake Client"}
+}
+
+func (f *fakeUsenetClient) TestConnection() (string, error) {
+	return "1.0", nil
+}
+
+func (f *fakeUsenetClient) AddNzb(opts usenet.AddNzbOptions) (string, error) {
+	f.added = append(f.added, opts)
+	return "job-1", nil
+}
+
+func (f *fakeUsenetClient) GetJobs() ([]*usenet.Job, error) {
+	return f.jobs, nil
+}
+
+func (f *fakeUsenetClient) DeleteJob(id string, deleteFiles bool) error {
+	return nil
+}
+
+func TestCompletedJobIsHandedToScanner(t *testing.T) {
+	logger := util.NewLogger()
+	env := testutil.NewTestEnv(t)
+	database := env.MustNewDatabase(logger)
+
+	client := &fakeUsenetClient{}
+	repo := &Repository{
+		provider:       mo.Some[usenet.Client](client),
+		logger:         logger,
+		db:             database,
+		settings:       &models.UsenetSettings{Enabled: true},
+		wsEventManager: events.NewMockWSEventManager(logger),
+	}
+
+	notified := 0
+	repo.SetOnJobCompleted(func() {
+		notified++
+	})
+
+	hash := usenet.ReleaseHash("https://indexer.example/details/1")
+	require.NoError(t, database.InsertAutoDownloaderItem(&models.AutoDownloaderItem{MediaID: 21, Episode: 1, Hash: hash}))
+
+	destination := t.TempDir()
+	jobId, err := repo.AddNzb(usenet.AddNzbOptions{URL: "https://indexer.example/getnzb/1", Name: "Frieren - 01"}, destination, 21, hash)
+	require.NoError(t, err)
+	require.Equal(t, "job-1", jobId)
+	require.Len(t, client.added, 1)
+
+	items, err := database.GetUsenetJobItems()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "fake-client", items[0].Provider)
+
+	// Still downloading, nothing happens
+	client.jobs = []*usenet.Job{{ID: "job-1", Status: usenet.JobStatusDownloading}}
+	repo.processTrackedJobs(client)
+	require.Equal(t, 0, notified)
+
+	// Completed, the files are moved to the destination
+	storagePath := filepath.Join(t.TempDir(), "Frieren - 01")
+	require.NoError(t, os.MkdirAll(storagePath, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(storagePath, "Frieren - 01.mkv"), []byte("video"), 0644))
+
+	client.jobs = []*usenet.Job{{ID: "job-1", Status: usenet.JobStatusCompleted, StoragePath: storagePath}}
+	repo.processTrackedJobs(client)
+	require.Equal(t, 1, notified)
+	require.FileExists(t, filepath.Join(destination, "Frieren - 01", "Frieren - 01.mkv"))
+
+	adItems, err := database.GetAutoDownloaderItems()
+	require.NoError(t, err)
+	require.Len(t, adItems, 1)
+	require.True(t, adItems[0].Downloaded)
+
+	items, err = database.GetUsenetJobItems()
+	require.NoError(t, err)
+	require.Empty(t, items)
+}
+
+func TestFailedOrRemovedJobsAreNoLongerTracked(t *testing.T) {
+	logger := util.NewLogger()
+	env := testutil.NewTestEnv(t)
+	database := env.MustNewDatabase(logger)
+
+	client := &fakeUsenetClient{}
+	repo := &Repository{
+		provider:       mo.Some[usenet.Client](client),
+		logger:         logger,
+		db:             database,
+		settings:       &models.UsenetSettings{Enabled: true},
+		wsEventManager: events.NewMockWSEventManager(logger),
+	}
+
+	require.NoError(t, database.InsertUsenetJobItem(&models.UsenetJobItem{JobID: "failed", Provider: "fake-client"}))
+	require.NoError(t, database.InsertUsenetJobItem(&models.UsenetJobItem{JobID: "removed", Provider: "fake-client"}))
+	require.NoError(t, database.InsertUsenetJobItem(&models.UsenetJobItem{JobID: "other", Provider: "other-client"}))
+
+	client.jobs = []*usenet.Job{{ID: "failed", Status: usenet.JobStatusFailed, Error: "missing articles"}}
+	repo.processTrackedJobs(client)
+
+	items, err := database.GetUsenetJobItems()
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	require.Equal(t, "other", items[0].JobID)
+}
+
+func TestIsSubPath(t *testing.T) {
+	dir := filepath.Join("library", "anime")
+	require.True(t, isSubPath(dir, filepath.Join(dir, "Frieren")))
+	require.True(t, isSubPath(dir, dir))
+	require.False(t, isSubPath(dir, filepath.Join("library", "anime2")))
+	require.False(t, isSubPath(dir, filepath.Join("downloads", "Frieren")))
+}
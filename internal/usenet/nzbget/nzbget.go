@@ -0,0 +1,320 @@
+package nzbget
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"seanime/internal/constants"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	NZBGet struct {
+		baseUrl  string
+		username string
+		password string
+		category string
+		client   *http.Client
+		logger   *zerolog.Logger
+	}
+
+	NewNZBGetOptions struct {
+		// Host is the URL of the NZBGet web interface, e.g. "http://localhost:6789"
+		Host     string
+		Username string
+		Password string
+		Category string
+	}
+
+	rpcRequest struct {
+		Method string        `json:"method"`
+		Params []interface{} `json:"params"`
+	}
+
+	rpcResponse struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Name    string `json:"name"`
+			Code    int    `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+
+	group struct {
+		NZBID            int    `json:"NZBID"`
+		NZBName          string `json:"NZBName"`
+		Category         string `json:"Category"`
+		Status           string `json:"Status"`
+		FileSizeMB       int64  `json:"FileSizeMB"`
+		RemainingSizeMB  int64  `json:"RemainingSizeMB"`
+		DownloadedSizeMB int64  `json:"DownloadedSizeMB"`
+		DestDir          string `json:"DestDir"`
+	}
+
+	historyItem struct {
+		NZBID      int    `json:"NZBID"`
+		Name       string `json:"Name"`
+		Category   string `json:"Category"`
+		Status     string `json:"Status"`
+		FileSizeMB int64  `json:"FileSizeMB"`
+		DestDir    string `json:"DestDir"`
+		FinalDir   string `json:"FinalDir"`
+	}
+)
+
+func NewNZBGet(logger *zerolog.Logger, opts NewNZBGetOptions) usenet.Client {
+	return &NZBGet{
+		baseUrl:  strings.TrimRight(opts.Host, "/"),
+		username: opts.Username,
+		password: opts.Password,
+		category: opts.Category,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+func (n *NZBGet) GetSettings() usenet.Settings {
+	return usenet.Settings{
+		ID:   "nzbget",
+		Name: "NZBGet",
+	}
+}
+
+// call sends a JSON-RPC request and decodes the result into v.
+func (n *NZBGet) call(method string, v interface{}, params ...interface{}) error {
+	if params == nil {
+		params = []interface{}{}
+	}
+	body, err := json.Marshal(rpcRequest{Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.baseUrl+"/jsonrpc", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Seanime/"+constants.Version)
+	if n.username != "" || n.password != "" {
+		req.SetBasicAuth(n.username, n.password)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nzbget: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("nzbget: %w", usenet.ErrNotAuthenticated)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nzbget: request failed with status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("nzbget: failed to read response: %w", err)
+	}
+
+	var ret rpcResponse
+	if err := json.Unmarshal(data, &ret); err != nil {
+		return fmt.Errorf("nzbget: failed to decode response: %w", err)
+	}
+	if ret.Error != nil {
+		return fmt.Errorf("nzbget: %s", ret.Error.Message)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(ret.Result, v); err != nil {
+		return fmt.Errorf("nzbget: failed to decode result: %w", err)
+	}
+	return nil
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (n *NZBGet) TestConnection() (string, error) {
+	var version string
+	if err := n.call("version", &version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func (n *NZBGet) AddNzb(opts usenet.AddNzbOptions) (string, error) {
+	// NZBGet accepts either the base64 encoded content or a URL
+	content := opts.URL
+	if len(opts.Content) > 0 {
+		content = base64.StdEncoding.EncodeToString(opts.Content)
+	}
+	if content == "" {
+		return "", fmt.Errorf("nzbget: no NZB to add")
+	}
+
+	filename := opts.Name
+	if filename != "" && !strings.HasSuffix(strings.ToLower(filename), ".nzb") {
+		filename += ".nzb"
+	}
+
+	var id int
+	err := n.call("append", &id,
+		filename,                          // NZBFilename
+		content,                           // NZBContent
+		cmp.Or(opts.Category, n.category), // Category
+		0,                                 // Priority
+		false,                             // AddToTop
+		false,                             // AddPaused
+		"",                                // DupeKey
+		0,                                 // DupeScore
+		"SCORE",                           // DupeMode
+		[]interface{}{},                   // PPParameters
+	)
+	if err != nil {
+		return "", err
+	}
+	if id <= 0 {
+		return "", fmt.Errorf("nzbget: NZB was not added")
+	}
+
+	n.logger.Debug().Int("id", id).Str("name", opts.Name).Msg("nzbget: NZB added")
+
+	return strconv.Itoa(id), nil
+}
+
+func (n *NZBGet) GetJobs() ([]*usenet.Job, error) {
+	var groups []*group
+	if err := n.call("listgroups", &groups, 0); err != nil {
+		return nil, err
+	}
+
+	var history []*historyItem
+	if err := n.call("history", &history, false); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*usenet.Job, 0, len(groups)+len(history))
+	for _, g := range groups {
+		ret = append(ret, toQueueJob(g))
+	}
+	for _, h := range history {
+		ret = append(ret, toHistoryJob(h))
+	}
+
+	return ret, nil
+}
+
+func (n *NZBGet) DeleteJob(id string, deleteFiles bool) error {
+	nzbId, err := strconv.Atoi(id)
+	if err != nil {
+		return usenet.ErrJobNotFound
+	}
+
+	jobs, err := n.GetJobs()
+	if err != nil {
+		return err
+	}
+
+	for _, job := range jobs {
+		if job.ID != id {
+			continue
+		}
+
+		command := "GroupDelete"
+		if deleteFiles {
+			command = "GroupFinalDelete"
+		}
+		if job.Status.IsDone() {
+			command = "HistoryDelete"
+			if deleteFiles {
+				command = "HistoryFinalDelete"
+			}
+		}
+
+		var ok bool
+		if err := n.call("editqueue", &ok, command, "", []int{nzbId}); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("nzbget: failed to delete job %s", id)
+		}
+		return nil
+	}
+
+	return usenet.ErrJobNotFound
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func toQueueJob(g *group) *usenet.Job {
+	size := g.FileSizeMB * 1024 * 1024
+
+	percentage := 0
+	if g.FileSizeMB > 0 {
+		percentage = int((g.FileSizeMB - g.RemainingSizeMB) * 100 / g.FileSizeMB)
+	}
+
+	status := usenet.JobStatusPostProcessing
+	switch g.Status {
+	case "QUEUED":
+		status = usenet.JobStatusQueued
+	case "PAUSED":
+		status = usenet.JobStatusPaused
+	case "DOWNLOADING", "FETCHING":
+		status = usenet.JobStatusDownloading
+	}
+
+	return &usenet.Job{
+		ID:                   strconv.Itoa(g.NZBID),
+		Name:                 g.NZBName,
+		Category:             g.Category,
+		Status:               status,
+		Size:                 size,
+		FormattedSize:        util.Bytes(uint64(size)),
+		CompletionPercentage: percentage,
+	}
+}
+
+func toHistoryJob(h *historyItem) *usenet.Job {
+	size := h.FileSizeMB * 1024 * 1024
+
+	// e.g. "SUCCESS/ALL", "SUCCESS/UNPACK", "FAILURE/PAR", "WARNING/SCRIPT", "DELETED/MANUAL"
+	kind, detail, _ := strings.Cut(h.Status, "/")
+
+	job := &usenet.Job{
+		ID:                   strconv.Itoa(h.NZBID),
+		Name:                 h.Name,
+		Category:             h.Category,
+		Size:                 size,
+		FormattedSize:        util.Bytes(uint64(size)),
+		CompletionPercentage: 100,
+		StoragePath:          cmp.Or(h.FinalDir, h.DestDir),
+	}
+
+	switch {
+	case kind == "SUCCESS",
+		kind == "WARNING" && detail == "SCRIPT": // Only the post-processing script failed, the files are there
+		job.Status = usenet.JobStatusCompleted
+	default:
+		job.Status = usenet.JobStatusFailed
+		job.CompletionPercentage = 0
+		job.Error = strings.ToLower(h.Status)
+	}
+
+	return job
+}
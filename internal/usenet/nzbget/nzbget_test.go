@@ -0,0 +1,154 @@
+package nzbget
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeNZBGet struct {
+	server *httptest.Server
+	mu     sync.Mutex
+	calls  []rpcRequest
+}
+
+func newFakeNZBGet(t *testing.T) *fakeNZBGet {
+	t.Helper()
+
+	f := &fakeNZBGet{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok || username != "nzbget" || password != "tegbzn6789" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		require.Equal(t, "/jsonrpc", r.URL.Path)
+
+		var req rpcRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		f.mu.Lock()
+		f.calls = append(f.calls, req)
+		f.mu.Unlock()
+
+		switch req.Method {
+		case "version":
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "result": "24.3"}`)
+		case "append":
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "result": 42}`)
+		case "listgroups":
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "result": [
+				{"NZBID": 1, "NZBName": "[SubsPlease] Frieren - 01 (1080p)", "Category": "anime", "Status": "DOWNLOADING", "FileSizeMB": 1000, "RemainingSizeMB": 250},
+				{"NZBID": 2, "NZBName": "[SubsPlease] Frieren - 02 (1080p)", "Category": "anime", "Status": "UNPACKING", "FileSizeMB": 1000, "RemainingSizeMB": 0}
+			]}`)
+		case "history":
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "result": [
+				{"NZBID": 3, "Name": "[SubsPlease] Frieren - 03 (1080p)", "Category": "anime", "Status": "SUCCESS/ALL", "FileSizeMB": 1000, "DestDir": "/downloads/intermediate/Frieren - 03", "FinalDir": "/downloads/complete/Frieren - 03"},
+				{"NZBID": 4, "Name": "[SubsPlease] Frieren - 04 (1080p)", "Category": "anime", "Status": "FAILURE/PAR", "FileSizeMB": 1000}
+			]}`)
+		case "editqueue":
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "result": true}`)
+		default:
+			_, _ = fmt.Fprint(w, `{"version": "1.1", "error": {"name": "JSONRPCError", "code": 1, "message": "Invalid procedure"}}`)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeNZBGet) lastCall() rpcRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[len(f.calls)-1]
+}
+
+func newTestClient(f *fakeNZBGet) usenet.Client {
+	return NewNZBGet(util.NewLogger(), NewNZBGetOptions{
+		Host:     f.server.URL,
+		Username: "nzbget",
+		Password: "tegbzn6789",
+		Category: "anime",
+	})
+}
+
+func TestTestConnection(t *testing.T) {
+	f := newFakeNZBGet(t)
+
+	version, err := newTestClient(f).TestConnection()
+	require.NoError(t, err)
+	require.Equal(t, "24.3", version)
+
+	client := NewNZBGet(util.NewLogger(), NewNZBGetOptions{Host: f.server.URL, Username: "nzbget", Password: "wrong"})
+	_, err = client.TestConnection()
+	require.ErrorIs(t, err, usenet.ErrNotAuthenticated)
+}
+
+func TestAddNzb(t *testing.T) {
+	f := newFakeNZBGet(t)
+	client := newTestClient(f)
+
+	id, err := client.AddNzb(usenet.AddNzbOptions{URL: "https://indexer.example/getnzb/1", Name: "Frieren - 01"})
+	require.NoError(t, err)
+	require.Equal(t, "42", id)
+
+	call := f.lastCall()
+	require.Equal(t, "append", call.Method)
+	require.Equal(t, "Frieren - 01.nzb", call.Params[0])
+	require.Equal(t, "https://indexer.example/getnzb/1", call.Params[1])
+	require.Equal(t, "anime", call.Params[2])
+
+	_, err = client.AddNzb(usenet.AddNzbOptions{Content: []byte("<nzb/>"), Name: "Frieren - 02.nzb", Category: "tv"})
+	require.NoError(t, err)
+	call = f.lastCall()
+	require.Equal(t, "Frieren - 02.nzb", call.Params[0])
+	require.Equal(t, "PG56Yi8+", call.Params[1])
+	require.Equal(t, "tv", call.Params[2])
+
+	_, err = client.AddNzb(usenet.AddNzbOptions{})
+	require.Error(t, err)
+}
+
+func TestGetJobs(t *testing.T) {
+	f := newFakeNZBGet(t)
+
+	jobs, err := newTestClient(f).GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 4)
+
+	require.Equal(t, "1", jobs[0].ID)
+	require.Equal(t, usenet.JobStatusDownloading, jobs[0].Status)
+	require.Equal(t, 75, jobs[0].CompletionPercentage)
+
+	require.Equal(t, usenet.JobStatusPostProcessing, jobs[1].Status)
+
+	require.Equal(t, usenet.JobStatusCompleted, jobs[2].Status)
+	require.Equal(t, "/downloads/complete/Frieren - 03", jobs[2].StoragePath)
+
+	require.Equal(t, usenet.JobStatusFailed, jobs[3].Status)
+	require.Equal(t, "failure/par", jobs[3].Error)
+}
+
+func TestDeleteJob(t *testing.T) {
+	f := newFakeNZBGet(t)
+	client := newTestClient(f)
+
+	require.NoError(t, client.DeleteJob("1", true))
+	call := f.lastCall()
+	require.Equal(t, "editqueue", call.Method)
+	require.Equal(t, "GroupFinalDelete", call.Params[0])
+	require.Equal(t, []interface{}{float64(1)}, call.Params[2])
+
+	require.NoError(t, client.DeleteJob("3", false))
+	require.Equal(t, "HistoryDelete", f.lastCall().Params[0])
+
+	require.ErrorIs(t, client.DeleteJob("99", false), usenet.ErrJobNotFound)
+	require.ErrorIs(t, client.DeleteJob("abc", false), usenet.ErrJobNotFound)
+}
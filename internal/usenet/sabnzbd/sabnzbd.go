@@ -0,0 +1,346 @@
+package sabnzbd
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"seanime/internal/constants"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+type (
+	SABnzbd struct {
+		baseUrl  string
+		apiKey   string
+		category string
+		client   *http.Client
+		logger   *zerolog.Logger
+	}
+
+	NewSABnzbdOptions struct {
+		// Host is the URL of the SABnzbd web interface, e.g. "http://localhost:8080" or "http://localhost/sabnzbd"
+		Host     string
+		ApiKey   string
+		Category string
+	}
+
+	errorResponse struct {
+		Status *bool  `json:"status"`
+		Error  string `json:"error"`
+	}
+
+	addResponse struct {
+		Status bool     `json:"status"`
+		NzoIds []string `json:"nzo_ids"`
+	}
+
+	queueResponse struct {
+		Queue struct {
+			Slots []*queueSlot `json:"slots"`
+		} `json:"queue"`
+	}
+
+	queueSlot struct {
+		NzoID      string `json:"nzo_id"`
+		Filename   string `json:"filename"`
+		Cat        string `json:"cat"`
+		Status     string `json:"status"`
+		MB         string `json:"mb"`
+		MBLeft     string `json:"mbleft"`
+		Percentage string `json:"percentage"`
+		TimeLeft   string `json:"timeleft"`
+	}
+
+	historyResponse struct {
+		History struct {
+			Slots []*historySlot `json:"slots"`
+		} `json:"history"`
+	}
+
+	historySlot struct {
+		NzoID       string `json:"nzo_id"`
+		Name        string `json:"name"`
+		Category    string `json:"category"`
+		Status      string `json:"status"`
+		Bytes       int64  `json:"bytes"`
+		Storage     string `json:"storage"`
+		FailMessage string `json:"fail_message"`
+	}
+)
+
+func NewSABnzbd(logger *zerolog.Logger, opts NewSABnzbdOptions) usenet.Client {
+	return &SABnzbd{
+		baseUrl:  strings.TrimRight(opts.Host, "/"),
+		apiKey:   opts.ApiKey,
+		category: opts.Category,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+func (s *SABnzbd) GetSettings() usenet.Settings {
+	return usenet.Settings{
+		ID:   "sabnzbd",
+		Name: "SABnzbd",
+	}
+}
+
+func (s *SABnzbd) apiUrl(mode string, params url.Values) string {
+	if params == nil {
+		params = url.Values{}
+	}
+	params.Set("mode", mode)
+	params.Set("output", "json")
+	params.Set("apikey", s.apiKey)
+	return s.baseUrl + "/api?" + params.Encode()
+}
+
+// doQuery sends the request and decodes the response into v.
+// SABnzbd returns errors with a 200 status code, e.g. {"status": false, "error": "API Key Incorrect"}
+func (s *SABnzbd) doQuery(req *http.Request, v interface{}) error {
+	if s.apiKey == "" {
+		return usenet.ErrNotAuthenticated
+	}
+
+	req.Header.Set("User-Agent", "Seanime/"+constants.Version)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("sabnzbd: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("sabnzbd: failed to read response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("sabnzbd: request failed with status %d", resp.StatusCode)
+	}
+
+	var errResp errorResponse
+	if err := json.Unmarshal(data, &errResp); err == nil && errResp.Status != nil && !*errResp.Status {
+		if strings.Contains(strings.ToLower(errResp.Error), "api key") {
+			return fmt.Errorf("sabnzbd: %w: %s", usenet.ErrNotAuthenticated, errResp.Error)
+		}
+		return fmt.Errorf("sabnzbd: %s", errResp.Error)
+	}
+
+	if v == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("sabnzbd: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (s *SABnzbd) get(mode string, params url.Values, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, s.apiUrl(mode, params), nil)
+	if err != nil {
+		return err
+	}
+	return s.doQuery(req, v)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func (s *SABnzbd) TestConnection() (string, error) {
+	// "version" doesn't require an API key, the queue is fetched to validate it
+	var ret struct {
+		Version string `json:"version"`
+	}
+	if err := s.get("version", nil, &ret); err != nil {
+		return "", err
+	}
+	if err := s.get("queue", url.Values{"limit": {"1"}}, nil); err != nil {
+		return "", err
+	}
+	return ret.Version, nil
+}
+
+func (s *SABnzbd) AddNzb(opts usenet.AddNzbOptions) (string, error) {
+	params := url.Values{}
+	if opts.Name != "" {
+		params.Set("nzbname", opts.Name)
+	}
+	if category := cmp.Or(opts.Category, s.category); category != "" {
+		params.Set("cat", category)
+	}
+
+	var ret addResponse
+	if len(opts.Content) > 0 {
+		body := &bytes.Buffer{}
+		writer := multipart.NewWriter(body)
+		filename := cmp.Or(opts.Name, "seanime") + ".nzb"
+		part, err := writer.CreateFormFile("name", filename)
+		if err != nil {
+			return "", err
+		}
+		if _, err := part.Write(opts.Content); err != nil {
+			return "", err
+		}
+		if err := writer.Close(); err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequest(http.MethodPost, s.apiUrl("addfile", params), body)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		if err := s.doQuery(req, &ret); err != nil {
+			return "", err
+		}
+	} else {
+		if opts.URL == "" {
+			return "", fmt.Errorf("sabnzbd: no NZB to add")
+		}
+		params.Set("name", opts.URL)
+		if err := s.get("addurl", params, &ret); err != nil {
+			return "", err
+		}
+	}
+
+	if !ret.Status || len(ret.NzoIds) == 0 {
+		return "", fmt.Errorf("sabnzbd: NZB was not added")
+	}
+
+	s.logger.Debug().Str("id", ret.NzoIds[0]).Str("name", opts.Name).Msg("sabnzbd: NZB added")
+
+	return ret.NzoIds[0], nil
+}
+
+func (s *SABnzbd) GetJobs() ([]*usenet.Job, error) {
+	var queue queueResponse
+	if err := s.get("queue", nil, &queue); err != nil {
+		return nil, err
+	}
+
+	var history historyResponse
+	if err := s.get("history", url.Values{"limit": {"100"}}, &history); err != nil {
+		return nil, err
+	}
+
+	ret := make([]*usenet.Job, 0, len(queue.Queue.Slots)+len(history.History.Slots))
+	for _, slot := range queue.Queue.Slots {
+		ret = append(ret, toQueueJob(slot))
+	}
+	for _, slot := range history.History.Slots {
+		ret = append(ret, toHistoryJob(slot))
+	}
+
+	return ret, nil
+}
+
+func (s *SABnzbd) DeleteJob(id string, deleteFiles bool) error {
+	params := url.Values{
+		"name":  {"delete"},
+		"value": {id},
+	}
+	if deleteFiles {
+		params.Set("del_files", "1")
+	}
+
+	var queue queueResponse
+	if err := s.get("queue", nil, &queue); err != nil {
+		return err
+	}
+	for _, slot := range queue.Queue.Slots {
+		if slot.NzoID == id {
+			return s.get("queue", params, nil)
+		}
+	}
+
+	// Jobs that are post-processing or done are in the history
+	var history historyResponse
+	if err := s.get("history", url.Values{"limit": {"100"}}, &history); err != nil {
+		return err
+	}
+	for _, slot := range history.History.Slots {
+		if slot.NzoID == id {
+			return s.get("history", params, nil)
+		}
+	}
+
+	return usenet.ErrJobNotFound
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+func toQueueJob(slot *queueSlot) *usenet.Job {
+	size := mbToBytes(slot.MB)
+	percentage, _ := strconv.Atoi(slot.Percentage)
+
+	status := usenet.JobStatusQueued
+	switch strings.ToLower(slot.Status) {
+	case "downloading", "fetching", "grabbing", "propagating":
+		status = usenet.JobStatusDownloading
+	case "paused":
+		status = usenet.JobStatusPaused
+	case "checking":
+		status = usenet.JobStatusPostProcessing
+	}
+
+	eta := ""
+	if status == usenet.JobStatusDownloading && slot.TimeLeft != "0:00:00" {
+		eta = slot.TimeLeft
+	}
+
+	return &usenet.Job{
+		ID:                   slot.NzoID,
+		Name:                 slot.Filename,
+		Category:             slot.Cat,
+		Status:               status,
+		Size:                 size,
+		FormattedSize:        util.Bytes(uint64(size)),
+		CompletionPercentage: percentage,
+		ETA:                  eta,
+	}
+}
+
+func toHistoryJob(slot *historySlot) *usenet.Job {
+	status := usenet.JobStatusPostProcessing
+	percentage := 100
+	switch strings.ToLower(slot.Status) {
+	case "completed":
+		status = usenet.JobStatusCompleted
+	case "failed":
+		status = usenet.JobStatusFailed
+		percentage = 0
+	}
+
+	return &usenet.Job{
+		ID:                   slot.NzoID,
+		Name:                 slot.Name,
+		Category:             slot.Category,
+		Status:               status,
+		Size:                 slot.Bytes,
+		FormattedSize:        util.Bytes(uint64(slot.Bytes)),
+		CompletionPercentage: percentage,
+		StoragePath:          slot.Storage,
+		Error:                slot.FailMessage,
+	}
+}
+
+func mbToBytes(mb string) int64 {
+	v, err := strconv.ParseFloat(mb, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(v * 1024 * 1024)
+}
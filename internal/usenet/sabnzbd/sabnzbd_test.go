@@ -0,0 +1,174 @@
+package sabnzbd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"seanime/internal/usenet/usenet"
+	"seanime/internal/util"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type fakeSABnzbd struct {
+	server  *httptest.Server
+	mu      sync.Mutex
+	calls   []url.Values
+	uploads []string
+}
+
+func newFakeSABnzbd(t *testing.T) *fakeSABnzbd {
+	t.Helper()
+
+	f := &fakeSABnzbd{}
+	f.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		f.mu.Lock()
+		f.calls = append(f.calls, query)
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+
+		if query.Get("mode") == "version" {
+			_, _ = fmt.Fprint(w, `{"version": "4.2.1"}`)
+			return
+		}
+		if query.Get("apikey") != "secret" {
+			_, _ = fmt.Fprint(w, `{"status": false, "error": "API Key Incorrect"}`)
+			return
+		}
+
+		switch query.Get("mode") {
+		case "addurl":
+			_, _ = fmt.Fprint(w, `{"status": true, "nzo_ids": ["SABnzbd_nzo_url"]}`)
+		case "addfile":
+			file, _, err := r.FormFile("name")
+			require.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			f.mu.Lock()
+			f.uploads = append(f.uploads, string(data))
+			f.mu.Unlock()
+			_, _ = fmt.Fprint(w, `{"status": true, "nzo_ids": ["SABnzbd_nzo_file"]}`)
+		case "queue":
+			if query.Get("name") == "delete" {
+				_, _ = fmt.Fprint(w, `{"status": true, "nzo_ids": []}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"queue": {"slots": [
+				{"nzo_id": "SABnzbd_nzo_1", "filename": "[SubsPlease] Frieren - 01 (1080p)", "cat": "anime", "status": "Downloading", "mb": "1024.00", "mbleft": "512.00", "percentage": "50", "timeleft": "0:05:00"},
+				{"nzo_id": "SABnzbd_nzo_2", "filename": "[SubsPlease] Frieren - 02 (1080p)", "cat": "anime", "status": "Queued", "mb": "1024.00", "mbleft": "1024.00", "percentage": "0", "timeleft": "0:00:00"}
+			]}}`)
+		case "history":
+			if query.Get("name") == "delete" {
+				_, _ = fmt.Fprint(w, `{"status": true}`)
+				return
+			}
+			_, _ = fmt.Fprint(w, `{"history": {"slots": [
+				{"nzo_id": "SABnzbd_nzo_3", "name": "[SubsPlease] Frieren - 03 (1080p)", "category": "anime", "status": "Completed", "bytes": 1073741824, "storage": "/downloads/complete/Frieren - 03", "fail_message": ""},
+				{"nzo_id": "SABnzbd_nzo_4", "name": "[SubsPlease] Frieren - 04 (1080p)", "category": "anime", "status": "Failed", "bytes": 0, "storage": "", "fail_message": "Aborted, cannot be completed"},
+				{"nzo_id": "SABnzbd_nzo_5", "name": "[SubsPlease] Frieren - 05 (1080p)", "category": "anime", "status": "Extracting", "bytes": 1073741824, "storage": ""}
+			]}}`)
+		default:
+			_, _ = fmt.Fprint(w, `{"status": false, "error": "not implemented"}`)
+		}
+	}))
+	t.Cleanup(f.server.Close)
+
+	return f
+}
+
+func (f *fakeSABnzbd) lastCall() url.Values {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.calls[len(f.calls)-1]
+}
+
+func TestTestConnection(t *testing.T) {
+	f := newFakeSABnzbd(t)
+
+	client := NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL + "/", ApiKey: "secret"})
+	version, err := client.TestConnection()
+	require.NoError(t, err)
+	require.Equal(t, "4.2.1", version)
+
+	client = NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL, ApiKey: "wrong"})
+	_, err = client.TestConnection()
+	require.ErrorIs(t, err, usenet.ErrNotAuthenticated)
+
+	client = NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL})
+	_, err = client.TestConnection()
+	require.ErrorIs(t, err, usenet.ErrNotAuthenticated)
+}
+
+func TestAddNzb(t *testing.T) {
+	f := newFakeSABnzbd(t)
+	client := NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL, ApiKey: "secret", Category: "anime"})
+
+	id, err := client.AddNzb(usenet.AddNzbOptions{URL: "https://indexer.example/getnzb/1", Name: "Frieren - 01"})
+	require.NoError(t, err)
+	require.Equal(t, "SABnzbd_nzo_url", id)
+
+	call := f.lastCall()
+	require.Equal(t, "addurl", call.Get("mode"))
+	require.Equal(t, "https://indexer.example/getnzb/1", call.Get("name"))
+	require.Equal(t, "Frieren - 01", call.Get("nzbname"))
+	require.Equal(t, "anime", call.Get("cat"))
+
+	id, err = client.AddNzb(usenet.AddNzbOptions{Content: []byte("<nzb/>"), Name: "Frieren - 02", Category: "tv"})
+	require.NoError(t, err)
+	require.Equal(t, "SABnzbd_nzo_file", id)
+	require.Equal(t, "tv", f.lastCall().Get("cat"))
+	require.Equal(t, []string{"<nzb/>"}, f.uploads)
+
+	_, err = client.AddNzb(usenet.AddNzbOptions{})
+	require.Error(t, err)
+}
+
+func TestGetJobs(t *testing.T) {
+	f := newFakeSABnzbd(t)
+	client := NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL, ApiKey: "secret"})
+
+	jobs, err := client.GetJobs()
+	require.NoError(t, err)
+	require.Len(t, jobs, 5)
+
+	require.Equal(t, usenet.JobStatusDownloading, jobs[0].Status)
+	require.Equal(t, 50, jobs[0].CompletionPercentage)
+	require.Equal(t, int64(1024*1024*1024), jobs[0].Size)
+	require.Equal(t, "0:05:00", jobs[0].ETA)
+
+	require.Equal(t, usenet.JobStatusQueued, jobs[1].Status)
+	require.Empty(t, jobs[1].ETA)
+
+	require.Equal(t, usenet.JobStatusCompleted, jobs[2].Status)
+	require.Equal(t, "/downloads/complete/Frieren - 03", jobs[2].StoragePath)
+
+	require.Equal(t, usenet.JobStatusFailed, jobs[3].Status)
+	require.Equal(t, "Aborted, cannot be completed", jobs[3].Error)
+
+	require.Equal(t, usenet.JobStatusPostProcessing, jobs[4].Status)
+}
+
+func TestDeleteJob(t *testing.T) {
+	f := newFakeSABnzbd(t)
+	client := NewSABnzbd(util.NewLogger(), NewSABnzbdOptions{Host: f.server.URL, ApiKey: "secret"})
+
+	require.NoError(t, client.DeleteJob("SABnzbd_nzo_1", true))
+	call := f.lastCall()
+	require.Equal(t, "queue", call.Get("mode"))
+	require.Equal(t, "SABnzbd_nzo_1", call.Get("value"))
+	require.Equal(t, "1", call.Get("del_files"))
+
+	require.NoError(t, client.DeleteJob("SABnzbd_nzo_3", false))
+	call = f.lastCall()
+	require.Equal(t, "history", call.Get("mode"))
+	require.Equal(t, "delete", call.Get("name"))
+	require.Empty(t, call.Get("del_files"))
+
+	require.ErrorIs(t, client.DeleteJob("unknown", false), usenet.ErrJobNotFound)
+}
@@ -0,0 +1,81 @@
+package usenet
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrNotAuthenticated = fmt.Errorf("not authenticated")
+	ErrJobNotFound      = fmt.Errorf("job not found")
+)
+
+type (
+	// Client is a Usenet download client (e.g. SABnzbd, NZBGet).
+	Client interface {
+		GetSettings() Settings
+		// TestConnection checks that the client is reachable and that the credentials are valid.
+		// It returns the version of the client.
+		TestConnection() (string, error)
+		// AddNzb sends an NZB to the client and returns the ID of the job.
+		AddNzb(opts AddNzbOptions) (string, error)
+		// GetJobs returns the jobs in the queue and in the history.
+		GetJobs() ([]*Job, error)
+		// DeleteJob removes the job from the queue or history.
+		DeleteJob(id string, deleteFiles bool) error
+	}
+
+	AddNzbOptions struct {
+		// URL of the NZB file, the client will fetch it.
+		URL string `json:"url"`
+		// Content of the NZB file. Used instead of the URL when set.
+		Content []byte `json:"-"`
+		// Name of the job.
+		Name     string `json:"name"`
+		Category string `json:"category"`
+	}
+
+	// Job represents an NZB added to the download client
+	Job struct {
+		ID                   string    `json:"id"`
+		Name                 string    `json:"name"`
+		Category             string    `json:"category"`
+		Status               JobStatus `json:"status"`
+		Size                 int64     `json:"size"`                  // Total size in bytes
+		FormattedSize        string    `json:"formattedSize"`         // Formatted total size
+		CompletionPercentage int       `json:"completionPercentage"`  // Progress percentage (0 to 100)
+		ETA                  string    `json:"eta"`                   // Formatted estimated time remaining
+		StoragePath          string    `json:"storagePath,omitempty"` // Path to the downloaded files once the job is completed
+		Error                string    `json:"error,omitempty"`       // Reason of the failure
+	}
+
+	JobStatus string
+
+	Settings struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+)
+
+const (
+	JobStatusQueued         JobStatus = "queued"
+	JobStatusDownloading    JobStatus = "downloading"
+	JobStatusPaused         JobStatus = "paused"
+	JobStatusPostProcessing JobStatus = "post_processing" // Verifying, repairing, extracting or moving
+	JobStatusCompleted      JobStatus = "completed"
+	JobStatusFailed         JobStatus = "failed"
+)
+
+// IsDone returns true if the job will not change anymore.
+func (s JobStatus) IsDone() bool {
+	return s == JobStatusCompleted || s == JobStatusFailed
+}
+
+// ReleaseHash returns a stable identifier for an NZB release.
+// NZB releases don't have an info hash, this is used in its place to deduplicate releases.
+func ReleaseHash(guidOrLink string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(guidOrLink)))
+	return hex.EncodeToString(sum[:])
+}
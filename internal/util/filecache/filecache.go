@@ -1,22 +1,49 @@
 package filecache
 
 import (
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/goccy/go-json"
+	bolt "go.etcd.io/bbolt"
 )
 
-// CacheStore represents a single-process, file-based, key/value cache store.
-type CacheStore struct {
-	filePath string
-	mu       sync.Mutex
-	data     map[string]*cacheItem
-}
+// The cache is stored in a single embedded key/value store (bbolt) in the cache directory.
+// Each bucket of the cache is a bucket of the store, and entries are written one key at a time.
+//
+// An index of the entries is kept in memory to expire entries in the background,
+// keep the size of the store within its budget and report stats.
+
+const (
+	storeFilename = "filecache.db"
+	// legacyExt is the extension of the JSON files used before the store, they are imported when the cache is opened
+	legacyExt = ".cache"
+	// entryHeaderSize is the size of the expiration and update time stored before the value
+	entryHeaderSize = 16
+	// DefaultMaxSize is the default size budget of a cache
+	DefaultMaxSize int64 = 1 << 30
+	// evictionTarget is the fraction of the budget the cache is brought back to when it exceeds it
+	evictionTarget  = 0.9
+	janitorInterval = 5 * time.Minute
+	// compactionThreshold is the amount of unused space in the store file that triggers a compaction on open
+	compactionThreshold int64 = 64 << 20
+)
+
+var ErrClosed = errors.New("filecache: cache is closed")
+
+var (
+	// cachers holds the open caches, a store can only be opened once per process
+	cachers   = make(map[string]*Cacher)
+	cachersMu sync.Mutex
+)
 
 // Bucket represents a cache bucket with a name and TTL.
 type Bucket struct {
@@ -46,148 +73,404 @@ func (b *PermanentBucket) Name() string {
 
 // Cacher represents a single-process, file-based, key/value cache.
 type Cacher struct {
-	dir    string
-	stores map[string]*CacheStore
-	mu     sync.Mutex
-}
+	dir string
+	db  *bolt.DB
+
+	// mu guards the index
+	mu      sync.Mutex
+	buckets map[string]*bucketIndex
+	size    int64
+	maxSize int64
+	// Number of entries removed by the janitor
+	expirations int64
+	evictions   int64
+
+	evictCh   chan struct{}
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+type (
+	bucketIndex struct {
+		class   *BucketClass
+		entries map[string]*entryMeta
+		size    int64
+		hits    int64
+		misses  int64
+	}
+
+	entryMeta struct {
+		size       int64
+		expiration int64 // Unix nanoseconds, 0 if the entry does not expire
+		updatedAt  int64
+		accessedAt int64
+	}
+)
 
+// cacheItem is the JSON format of an entry used by Export and the legacy files.
 type cacheItem struct {
-	Value      interface{} `json:"value"`
-	Expiration *time.Time  `json:"expiration,omitempty"`
-	UpdatedAt  *time.Time  `json:"updated_at,omitempty"`
+	Value      json.RawMessage `json:"value"`
+	Expiration *time.Time      `json:"expiration,omitempty"`
+	UpdatedAt  *time.Time      `json:"updated_at,omitempty"`
 }
 
-// NewCacher creates a new instance of Cacher.
+// NewCacher opens the cache of the given directory.
+// Caches are shared, opening the same directory twice returns the same instance.
 func NewCacher(dir string) (*Cacher, error) {
-	// Check if the directory exists
-	_, err := os.Stat(dir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return nil, err
+	}
+
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		if os.IsNotExist(err) {
-			if err := os.MkdirAll(dir, os.ModePerm); err != nil {
-				return nil, err
-			}
-		} else {
-			return nil, err
-		}
+		return nil, err
 	}
-	return &Cacher{
-		stores: make(map[string]*CacheStore),
-		dir:    dir,
-	}, nil
+
+	cachersMu.Lock()
+	defer cachersMu.Unlock()
+
+	if c, ok := cachers[absDir]; ok {
+		return c, nil
+	}
+
+	c := &Cacher{
+		dir:     dir,
+		buckets: make(map[string]*bucketIndex),
+		maxSize: DefaultMaxSize,
+		evictCh: make(chan struct{}, 1),
+		closeCh: make(chan struct{}),
+	}
+
+	if err := c.open(); err != nil {
+		return nil, err
+	}
+
+	if err := c.importLegacyFiles(); err != nil {
+		_ = c.db.Close()
+		return nil, err
+	}
+
+	if err := c.loadIndex(); err != nil {
+		_ = c.db.Close()
+		return nil, err
+	}
+
+	if err := c.compactIfNeeded(); err != nil {
+		_ = c.db.Close()
+		return nil, err
+	}
+
+	cachers[absDir] = c
+
+	c.wg.Add(1)
+	go c.runJanitor()
+
+	return c, nil
 }
 
-// Close closes all the cache stores.
-func (c *Cacher) Close() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	for _, store := range c.stores {
-		if err := store.saveToFile(); err != nil {
-			return err
-		}
+func (c *Cacher) open() error {
+	db, err := bolt.Open(filepath.Join(c.dir, storeFilename), 0600, &bolt.Options{
+		Timeout:        5 * time.Second,
+		NoFreelistSync: true,
+		FreelistType:   bolt.FreelistMapType,
+	})
+	if err != nil {
+		return fmt.Errorf("filecache: failed to open the store: %w", err)
 	}
+	c.db = db
 	return nil
 }
 
-func (c *Cacher) Clear() error {
+// SetMaxSize sets the size budget of the cache in bytes, 0 disables eviction.
+func (c *Cacher) SetMaxSize(size int64) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.stores = make(map[string]*CacheStore)
+	c.maxSize = size
+	c.mu.Unlock()
+	c.requestEviction()
+}
+
+// Close closes the store, the cache cannot be used afterward.
+func (c *Cacher) Close() error {
+	var err error
+	c.closeOnce.Do(func() {
+		close(c.closeCh)
+		c.wg.Wait()
+
+		cachersMu.Lock()
+		for dir, cacher := range cachers {
+			if cacher == c {
+				delete(cachers, dir)
+			}
+		}
+		cachersMu.Unlock()
+
+		err = c.db.Close()
+	})
+	return err
+}
+
+// Clear is a no-op, entries are not kept in memory.
+func (c *Cacher) Clear() error {
 	return nil
 }
 
-// getStore returns a cache store for the given bucket name and TTL.
-func (c *Cacher) getStore(name string) (*CacheStore, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-	store, ok := c.stores[name]
-	if !ok {
-		store = &CacheStore{
-			filePath: filepath.Join(c.dir, name+".cache"),
-			data:     make(map[string]*cacheItem),
-		}
-		if err := store.loadFromFile(); err != nil {
-			return nil, err
-		}
-		c.stores[name] = store
+func encodeEntry(value interface{}, expiration time.Time, updatedAt time.Time) ([]byte, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
 	}
-	return store, nil
+	return encodeRawEntry(data, unixNano(expiration), unixNano(updatedAt)), nil
 }
 
-// Set sets the value for the given key in the given bucket.
-func (c *Cacher) Set(bucket Bucket, key string, value interface{}) error {
-	store, err := c.getStore(bucket.name)
+func encodeRawEntry(data []byte, expiration int64, updatedAt int64) []byte {
+	ret := make([]byte, entryHeaderSize+len(data))
+	binary.BigEndian.PutUint64(ret[0:8], uint64(expiration))
+	binary.BigEndian.PutUint64(ret[8:16], uint64(updatedAt))
+	copy(ret[entryHeaderSize:], data)
+	return ret
+}
+
+// decodeEntryHeader returns the expiration and update time of an entry.
+func decodeEntryHeader(v []byte) (expiration int64, updatedAt int64, ok bool) {
+	if len(v) < entryHeaderSize {
+		return 0, 0, false
+	}
+	return int64(binary.BigEndian.Uint64(v[0:8])), int64(binary.BigEndian.Uint64(v[8:16])), true
+}
+
+// maxUnixNano is the latest time that can be represented in unix nanoseconds, later times (e.g. very long TTLs) are clamped to it.
+var maxUnixNano = time.Unix(0, math.MaxInt64)
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	if t.After(maxUnixNano) {
+		return math.MaxInt64
+	}
+	return t.UnixNano()
+}
+
+func isExpired(expiration int64, now int64) bool {
+	return expiration != 0 && now > expiration
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// put writes an entry and updates the index.
+func (c *Cacher) put(bucketName string, key string, value interface{}, expiration time.Time) error {
+	now := time.Now()
+	data, err := encodeEntry(value, expiration, now)
 	if err != nil {
 		return err
 	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	store.data[key] = &cacheItem{Value: value, Expiration: new(time.Now().Add(bucket.ttl))}
-	return store.saveToFile()
+
+	err = c.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+		if err != nil {
+			return err
+		}
+		if err := b.Put([]byte(key), data); err != nil {
+			return err
+		}
+
+		// Write transactions are serialized, so the index follows the order of the writes
+		c.mu.Lock()
+		c.setIndexEntry(bucketName, key, &entryMeta{
+			size:       int64(len(key) + len(data)),
+			expiration: unixNano(expiration),
+			updatedAt:  now.UnixNano(),
+			accessedAt: now.UnixNano(),
+		})
+		c.mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return c.wrapError(err)
+	}
+
+	c.requestEviction()
+	return nil
 }
 
-func Range[T any](c *Cacher, bucket Bucket, f func(key string, value T) bool) error {
-	store, err := c.getStore(bucket.name)
+// get reads an entry, expired entries are ignored unless ignoreExpiration is true.
+func (c *Cacher) get(bucketName string, key string, out interface{}, ignoreExpiration bool) (bool, error) {
+	var data []byte
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		v := b.Get([]byte(key))
+		expiration, _, ok := decodeEntryHeader(v)
+		if !ok {
+			return nil
+		}
+		if !ignoreExpiration && isExpired(expiration, time.Now().UnixNano()) {
+			return nil
+		}
+		data = slices.Clone(v[entryHeaderSize:])
+		return nil
+	})
 	if err != nil {
-		return err
+		return false, c.wrapError(err)
 	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
 
-	for key, item := range store.data {
-		if item.Expiration != nil && time.Now().After(*item.Expiration) {
-			delete(store.data, key)
+	c.mu.Lock()
+	if idx, ok := c.buckets[bucketName]; ok {
+		if data == nil {
+			idx.misses++
 		} else {
-			itemVal, err := json.Marshal(item.Value)
-			if err != nil {
-				return err
-			}
-			var out T
-			err = json.Unmarshal(itemVal, &out)
-			if err != nil {
-				return err
-			}
-			if !f(key, out) {
-				break
+			idx.hits++
+			if meta, ok := idx.entries[key]; ok {
+				meta.accessedAt = time.Now().UnixNano()
 			}
 		}
 	}
+	c.mu.Unlock()
+
+	if data == nil {
+		return false, nil
+	}
+	return true, json.Unmarshal(data, out)
+}
 
-	return store.saveToFile()
+type rawEntry struct {
+	key  string
+	data []byte
 }
 
-// Get retrieves the value for the given key from the given bucket.
-func (c *Cacher) Get(bucket Bucket, key string, out interface{}) (bool, error) {
-	store, err := c.getStore(bucket.name)
+// readAll returns the entries of a bucket that have not expired.
+// The values are copied so that they can be used outside the transaction.
+func (c *Cacher) readAll(bucketName string, ignoreExpiration bool) ([]rawEntry, error) {
+	var ret []rawEntry
+	now := time.Now().UnixNano()
+	err := c.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			expiration, _, ok := decodeEntryHeader(v)
+			if !ok || (!ignoreExpiration && isExpired(expiration, now)) {
+				return nil
+			}
+			ret = append(ret, rawEntry{key: string(k), data: slices.Clone(v[entryHeaderSize:])})
+			return nil
+		})
+	})
 	if err != nil {
-		return false, err
+		return nil, c.wrapError(err)
 	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	item, ok := store.data[key]
-	if !ok {
-		return false, nil
+
+	c.mu.Lock()
+	if idx, ok := c.buckets[bucketName]; ok {
+		for _, e := range ret {
+			if meta, ok := idx.entries[e.key]; ok {
+				meta.accessedAt = now
+			}
+		}
 	}
-	if item.Expiration != nil && time.Now().After(*item.Expiration) {
-		delete(store.data, key)
-		_ = store.saveToFile() // Ignore errors here
-		return false, nil
+	c.mu.Unlock()
+
+	return ret, nil
+}
+
+// deleteKeys deletes entries of a bucket.
+func (c *Cacher) deleteKeys(bucketName string, keys []string) error {
+	if len(keys) == 0 {
+		return nil
 	}
-	data, err := json.Marshal(item.Value)
-	if err != nil {
-		return false, err
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketName))
+		if b == nil {
+			return nil
+		}
+		for _, key := range keys {
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+
+		c.mu.Lock()
+		for _, key := range keys {
+			c.deleteIndexEntry(bucketName, key)
+		}
+		c.mu.Unlock()
+		return nil
+	})
+	return c.wrapError(err)
+}
+
+// deleteBuckets deletes whole buckets.
+func (c *Cacher) deleteBuckets(bucketNames []string) error {
+	if len(bucketNames) == 0 {
+		return nil
 	}
-	return true, json.Unmarshal(data, out)
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range bucketNames {
+			if err := tx.DeleteBucket([]byte(name)); err != nil && !errors.Is(err, bolt.ErrBucketNotFound) {
+				return err
+			}
+		}
+
+		c.mu.Lock()
+		for _, name := range bucketNames {
+			if idx, ok := c.buckets[name]; ok {
+				c.size -= idx.size
+				delete(c.buckets, name)
+			}
+		}
+		c.mu.Unlock()
+		return nil
+	})
+	return c.wrapError(err)
 }
 
-func GetAll[T any](c *Cacher, bucket Bucket) (map[string]T, error) {
-	store, err := c.getStore(bucket.name)
+func (c *Cacher) wrapError(err error) error {
+	if errors.Is(err, bolt.ErrDatabaseNotOpen) {
+		return ErrClosed
+	}
+	return err
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// Set sets the value for the given key in the given bucket.
+func (c *Cacher) Set(bucket Bucket, key string, value interface{}) error {
+	return c.put(bucket.name, key, value, time.Now().Add(bucket.ttl))
+}
+
+func Range[T any](c *Cacher, bucket Bucket, f func(key string, value T) bool) error {
+	entries, err := c.readAll(bucket.name, false)
 	if err != nil {
-		return nil, err
+		return err
+	}
+
+	for _, e := range entries {
+		var out T
+		if err := json.Unmarshal(e.data, &out); err != nil {
+			return err
+		}
+		if !f(e.key, out) {
+			break
+		}
 	}
 
+	return nil
+}
+
+// Get retrieves the value for the given key from the given bucket.
+func (c *Cacher) Get(bucket Bucket, key string, out interface{}) (bool, error) {
+	return c.get(bucket.name, key, out, false)
+}
+
+func GetAll[T any](c *Cacher, bucket Bucket) (map[string]T, error) {
 	data := make(map[string]T)
-	err = Range(c, bucket, func(key string, value T) bool {
+	err := Range(c, bucket, func(key string, value T) bool {
 		data[key] = value
 		return true
 	})
@@ -195,155 +478,85 @@ func GetAll[T any](c *Cacher, bucket Bucket) (map[string]T, error) {
 		return nil, err
 	}
 
-	store.mu.Lock()
-	defer store.mu.Unlock()
-
-	return data, store.saveToFile()
+	return data, nil
 }
 
 // Delete deletes the value for the given key from the given bucket.
 func (c *Cacher) Delete(bucket Bucket, key string) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	delete(store.data, key)
-	return store.saveToFile()
+	return c.deleteKeys(bucket.name, []string{key})
 }
 
 func DeleteIf[T any](c *Cacher, bucket Bucket, cond func(key string, value T) bool) error {
-	store, err := c.getStore(bucket.name)
+	entries, err := c.readAll(bucket.name, true)
 	if err != nil {
 		return err
 	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
 
-	for key, item := range store.data {
-		itemVal, err := json.Marshal(item.Value)
-		if err != nil {
-			return err
-		}
+	var keys []string
+	for _, e := range entries {
 		var out T
-		err = json.Unmarshal(itemVal, &out)
-		if err != nil {
+		if err := json.Unmarshal(e.data, &out); err != nil {
 			return err
 		}
-		if cond(key, out) {
-			delete(store.data, key)
+		if cond(e.key, out) {
+			keys = append(keys, e.key)
 		}
 	}
 
-	return store.saveToFile()
+	return c.deleteKeys(bucket.name, keys)
 }
 
 // Empty empties the given bucket.
 func (c *Cacher) Empty(bucket Bucket) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	store.data = make(map[string]*cacheItem)
-	return store.saveToFile()
+	return c.deleteBuckets([]string{bucket.name})
 }
 
 // Remove removes the given bucket.
 func (c *Cacher) Remove(bucketName string) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	if _, ok := c.stores[bucketName]; ok {
-		delete(c.stores, bucketName)
-	}
-
-	_ = os.Remove(filepath.Join(c.dir, bucketName+".cache"))
-
-	return nil
+	return c.deleteBuckets([]string{bucketName})
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // SetPerm sets the value for the given key in the permanent bucket (no expiration).
 func (c *Cacher) SetPerm(bucket PermanentBucket, key string, value interface{}) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	store.data[key] = &cacheItem{Value: value, Expiration: nil, UpdatedAt: new(time.Now())} // No expiration
-	return store.saveToFile()
+	return c.put(bucket.name, key, value, time.Time{})
 }
 
 // GetPerm retrieves the value for the given key from the permanent bucket (ignores expiration).
 func (c *Cacher) GetPerm(bucket PermanentBucket, key string, out interface{}) (bool, error) {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return false, err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	item, ok := store.data[key]
-	if !ok {
-		return false, nil
-	}
-	data, err := json.Marshal(item.Value)
-	if err != nil {
-		return false, err
-	}
-	return true, json.Unmarshal(data, out)
+	return c.get(bucket.name, key, out, true)
 }
 
 // DeletePerm deletes the value for the given key from the permanent bucket.
 func (c *Cacher) DeletePerm(bucket PermanentBucket, key string) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	delete(store.data, key)
-	return store.saveToFile()
+	return c.deleteKeys(bucket.name, []string{key})
 }
 
 // DeletePermOldest deletes the oldest value from the permanent bucket.
 func (c *Cacher) DeletePermOldest(bucket PermanentBucket) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
+	c.mu.Lock()
 	oldestKey := ""
-	oldestTime := time.Now()
-	for key, item := range store.data {
-		updatedAt := time.Time{} // Default to 0 time
-		if item.UpdatedAt != nil {
-			updatedAt = *item.UpdatedAt
-		}
-		if updatedAt.Before(oldestTime) {
-			oldestKey = key
-			oldestTime = updatedAt
+	oldestTime := time.Now().UnixNano()
+	if idx, ok := c.buckets[bucket.name]; ok {
+		for key, meta := range idx.entries {
+			if meta.updatedAt < oldestTime {
+				oldestKey = key
+				oldestTime = meta.updatedAt
+			}
 		}
 	}
-	delete(store.data, oldestKey)
-	return store.saveToFile()
+	c.mu.Unlock()
+
+	if oldestKey == "" {
+		return nil
+	}
+	return c.deleteKeys(bucket.name, []string{oldestKey})
 }
 
 // EmptyPerm empties the permanent bucket.
 func (c *Cacher) EmptyPerm(bucket PermanentBucket) error {
-	store, err := c.getStore(bucket.name)
-	if err != nil {
-		return err
-	}
-	store.mu.Lock()
-	defer store.mu.Unlock()
-	store.data = make(map[string]*cacheItem)
-	return store.saveToFile()
+	return c.deleteBuckets([]string{bucket.name})
 }
 
 // RemovePerm calls Remove.
@@ -353,119 +566,228 @@ func (c *Cacher) RemovePerm(bucketName string) error {
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-func (cs *CacheStore) loadFromFile() error {
-	file, err := os.Open(cs.filePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return nil // File does not exist, so nothing to load
+// GetBucketNames returns the names of the buckets of the cache.
+func (c *Cacher) GetBucketNames() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ret := make([]string, 0, len(c.buckets))
+	for name := range c.buckets {
+		ret = append(ret, name)
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// RemoveAllBy removes all buckets that match the given filter.
+// The filter receives the name of the bucket followed by ".cache", the name of the files used by earlier versions.
+func (c *Cacher) RemoveAllBy(filter func(filename string) bool) error {
+	var names []string
+	for _, name := range c.GetBucketNames() {
+		if filter(name + legacyExt) {
+			names = append(names, name)
 		}
-		return fmt.Errorf("filecache: failed to open cache file: %w", err)
 	}
-	defer file.Close()
+	return c.deleteBuckets(names)
+}
 
-	if err := json.NewDecoder(file).Decode(&cs.data); err != nil {
-		// If decode fails (empty or corrupted file), initialize with empty data
-		cs.data = make(map[string]*cacheItem)
-		return nil
+// Export calls fn with the content of each bucket whose name passes the filter.
+// The content is a JSON object of the entries, the format of the files used by earlier versions.
+// All buckets are read in the same transaction, so that they are consistent with each other.
+func (c *Cacher) Export(filter func(bucketName string) bool, fn func(bucketName string, data []byte) error) error {
+	type export struct {
+		name string
+		data []byte
 	}
+	var exports []export
 
-	return nil
-}
+	err := c.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if !filter(string(name)) {
+				return nil
+			}
+
+			items := make(map[string]*cacheItem)
+			err := b.ForEach(func(k, v []byte) error {
+				expiration, updatedAt, ok := decodeEntryHeader(v)
+				if !ok {
+					return nil
+				}
+				item := &cacheItem{Value: slices.Clone(v[entryHeaderSize:])}
+				if expiration != 0 {
+					item.Expiration = new(time.Unix(0, expiration))
+				}
+				if updatedAt != 0 {
+					item.UpdatedAt = new(time.Unix(0, updatedAt))
+				}
+				items[string(k)] = item
+				return nil
+			})
+			if err != nil {
+				return err
+			}
 
-func (cs *CacheStore) saveToFile() error {
-	file, err := os.Create(cs.filePath)
+			data, err := json.Marshal(items)
+			if err != nil {
+				return err
+			}
+			exports = append(exports, export{name: string(name), data: data})
+			return nil
+		})
+	})
 	if err != nil {
-		return fmt.Errorf("filecache: failed to create cache file: %w", err)
+		return c.wrapError(err)
 	}
-	defer file.Close()
 
-	if err := json.NewEncoder(file).Encode(cs.data); err != nil {
-		return fmt.Errorf("filecache: failed to encode cache data: %w", err)
+	for _, e := range exports {
+		if err := fn(e.name, e.data); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
 //////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
-// RemoveAllBy removes all files in the cache directory that match the given filter.
-func (c *Cacher) RemoveAllBy(filter func(filename string) bool) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
+// importLegacyFiles imports the JSON files of earlier versions into the store and deletes them.
+// Backups are also restored as JSON files.
+func (c *Cacher) importLegacyFiles() error {
 	entries, err := os.ReadDir(c.dir)
 	if err != nil {
 		return err
 	}
 
+	now := time.Now().UnixNano()
 	for _, e := range entries {
-		if !e.IsDir() {
-			if !strings.HasSuffix(e.Name(), ".cache") {
-				continue
+		if e.IsDir() || !strings.HasSuffix(e.Name(), legacyExt) {
+			continue
+		}
+		p := filepath.Join(c.dir, e.Name())
+		bucketName := strings.TrimSuffix(e.Name(), legacyExt)
+
+		items := make(map[string]*cacheItem)
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		// Corrupted files are dropped
+		_ = json.Unmarshal(data, &items)
+
+		err = c.db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte(bucketName))
+			if err != nil {
+				return err
 			}
-			if filter(e.Name()) {
-				if err := os.Remove(filepath.Join(c.dir, e.Name())); err != nil {
+			for key, item := range items {
+				if item == nil || len(item.Value) == 0 {
+					continue
+				}
+				var expiration, updatedAt int64
+				if item.Expiration != nil {
+					expiration = unixNano(*item.Expiration)
+				}
+				if item.UpdatedAt != nil {
+					updatedAt = unixNano(*item.UpdatedAt)
+				}
+				if isExpired(expiration, now) {
+					continue
+				}
+				if err := b.Put([]byte(key), encodeRawEntry(item.Value, expiration, updatedAt)); err != nil {
 					return err
 				}
-				delete(c.stores, strings.TrimSuffix(e.Name(), ".cache"))
 			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("filecache: failed to import %s: %w", e.Name(), err)
+		}
+
+		if err := os.Remove(p); err != nil {
+			return err
 		}
 	}
+
 	return nil
 }
 
-//func (c *Cacher) RemoveAllBy(filter func(filename string) bool) error {
-//	c.mu.Lock()
-//	defer c.mu.Unlock()
-//
-//	err := filepath.WalkDir(c.dir, func(_ string, e os.DirEntry, err error) error {
-//		if err != nil {
-//			return err
-//		}
-//		if !e.IsDir() {
-//			if !strings.HasSuffix(e.Name(), ".cache") {
-//				return nil
-//			}
-//			if filter(e.Name()) {
-//				_ = os.Remove(filepath.Join(c.dir, e.Name()))
-//			}
-//		}
-//		return nil
-//	})
-//
-//	c.stores = make(map[string]*CacheStore)
-//	return err
-//}
+// loadIndex reads the size and times of every entry of the store.
+func (c *Cacher) loadIndex() error {
+	return c.db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			bucketName := string(name)
+			return b.ForEach(func(k, v []byte) error {
+				expiration, updatedAt, ok := decodeEntryHeader(v)
+				if !ok {
+					return nil
+				}
+				// The access times are not persisted
+				c.setIndexEntry(bucketName, string(k), &entryMeta{
+					size:       int64(len(k) + len(v)),
+					expiration: expiration,
+					updatedAt:  updatedAt,
+					accessedAt: updatedAt,
+				})
+				return nil
+			})
+		})
+	})
+}
+
+// compactIfNeeded rewrites the store file when most of it is unused, e.g. after a lot of entries were evicted.
+func (c *Cacher) compactIfNeeded() error {
+	p := filepath.Join(c.dir, storeFilename)
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+
+	if info.Size()-2*c.size < compactionThreshold {
+		return nil
+	}
+
+	tmpPath := p + ".compact"
+	_ = os.Remove(tmpPath)
+	dst, err := bolt.Open(tmpPath, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return err
+	}
+	if err := bolt.Compact(dst, c.db, 64<<20); err != nil {
+		_ = dst.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("filecache: failed to compact the store: %w", err)
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	if err := c.db.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, p); err != nil {
+		return err
+	}
+	return c.open()
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
 
 // ClearMediastreamVideoFiles clears all mediastream video file caches.
 func (c *Cacher) ClearMediastreamVideoFiles() error {
-	c.mu.Lock()
-
 	// Remove the contents of the directory
 	files, err := os.ReadDir(filepath.Join(c.dir, "videofiles"))
 	if err != nil {
-		c.mu.Unlock()
 		return nil
 	}
 	for _, file := range files {
 		_ = os.RemoveAll(filepath.Join(c.dir, "videofiles", file.Name()))
 	}
-	c.mu.Unlock()
 
-	err = c.RemoveAllBy(func(filename string) bool {
+	return c.RemoveAllBy(func(filename string) bool {
 		return strings.HasPrefix(filename, "mediastream")
 	})
-
-	c.mu.Lock()
-	c.stores = make(map[string]*CacheStore)
-	c.mu.Unlock()
-	return err
 }
 
 // TrimMediastreamVideoFiles clears all mediastream video file caches if the number of files exceeds the given limit.
 func (c *Cacher) TrimMediastreamVideoFiles() error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	// Remove the contents of the "videofiles" cache directory
 	files, err := os.ReadDir(filepath.Join(c.dir, "videofiles"))
 	if err != nil {
@@ -479,14 +801,10 @@ func (c *Cacher) TrimMediastreamVideoFiles() error {
 		}
 	}
 
-	c.stores = make(map[string]*CacheStore)
-	return err
+	return nil
 }
 
 func (c *Cacher) GetMediastreamVideoFilesTotalSize() (int64, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	_, err := os.Stat(filepath.Join(c.dir, "videofiles"))
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -512,12 +830,9 @@ func (c *Cacher) GetMediastreamVideoFilesTotalSize() (int64, error) {
 	return totalSize, nil
 }
 
-// GetTotalSize returns the total size of all files in the cache directory that match the given filter.
+// GetTotalSize returns the total size of all files in the cache directory, including the store.
 // The size is in bytes.
 func (c *Cacher) GetTotalSize() (int64, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
 	var totalSize int64
 	err := filepath.Walk(c.dir, func(_ string, info os.FileInfo, err error) error {
 		if err != nil {
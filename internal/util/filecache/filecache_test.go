@@ -1,7 +1,9 @@
 package filecache
 
 import (
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
 	"testing"
@@ -191,3 +193,216 @@ func TestCacherSetAndGet(t *testing.T) {
 	wg.Wait()
 
 }
+
+func TestCacherImportsLegacyFiles(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "cache")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	expired := time.Now().Add(-time.Hour)
+	legacy := `{"key":{"value":{"Name":"value"}},"expired":{"value":"old","expiration":"` + expired.Format(time.RFC3339Nano) + `"}}`
+	if err := os.WriteFile(filepath.Join(dir, "watch_history.cache"), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cacher, err := NewCacher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out struct {
+		Name string
+	}
+	found, err := cacher.GetPerm(NewPermanentBucket("watch_history"), "key", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, found)
+	assert.Equal(t, "value", out.Name)
+
+	all, err := GetAll[any](cacher, NewBucket("watch_history", time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.NotContains(t, all, "expired")
+	assert.NoFileExists(t, filepath.Join(dir, "watch_history.cache"))
+
+	// Opening the same directory returns the same cache
+	other, err := NewCacher(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Same(t, cacher, other)
+
+	// Export uses the legacy format
+	exported := make(map[string]string)
+	err = cacher.Export(func(bucketName string) bool { return true }, func(bucketName string, data []byte) error {
+		exported[bucketName] = string(data)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, exported["watch_history"], `"key":{"value":{"Name":"value"}`)
+}
+
+func TestCacherEviction(t *testing.T) {
+	cacher, err := NewCacher(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := strings.Repeat("a", 1000)
+	userBucket := NewPermanentBucket("watch_history")
+	mangaBucket := NewBucket("manga_provider_chapters_1", time.Hour)
+	streamingBucket := NewBucket("onlinestream_provider_episode-data_1", time.Hour)
+
+	for i := 0; i < 10; i++ {
+		key := strconv.Itoa(i)
+		if err := cacher.SetPerm(userBucket, key, value); err != nil {
+			t.Fatal(err)
+		}
+		if err := cacher.Set(mangaBucket, key, value); err != nil {
+			t.Fatal(err)
+		}
+		if err := cacher.Set(streamingBucket, key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first entry of the manga bucket is the most recently used
+	var out string
+	if found, _ := cacher.Get(mangaBucket, "0", &out); !found {
+		t.Fatal("entry not found")
+	}
+
+	stats := cacher.GetStats()
+	assert.Equal(t, 30, stats.Entries)
+	assert.Len(t, stats.Buckets, 3)
+
+	cacher.mu.Lock()
+	cacher.maxSize = stats.Size / 2
+	cacher.mu.Unlock()
+	if err := cacher.evict(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats = cacher.GetStats()
+	assert.LessOrEqual(t, stats.Size, stats.MaxSize)
+	assert.Positive(t, stats.Evictions)
+
+	// Entries of the user class are never evicted
+	userEntries, err := GetAll[string](cacher, NewBucket(userBucket.Name(), 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, userEntries, 10)
+
+	// The least recently used entries are evicted first
+	found, err := cacher.Get(mangaBucket, "0", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, found)
+	found, _ = cacher.Get(mangaBucket, "1", &out)
+	assert.False(t, found)
+}
+
+func TestCacherEvictionKeepsUserData(t *testing.T) {
+	cacher, err := NewCacher(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	value := strings.Repeat("a", 1000)
+	userBuckets := []PermanentBucket{
+		NewPermanentBucket("extension-settings"),
+		NewPermanentBucket("plugin-settings"),
+		NewPermanentBucket("ext_user_config_komga"),
+		NewPermanentBucket("customer-source-identifier"),
+	}
+	// Permanent buckets outside the user class are not evicted either
+	otherPermBucket := NewPermanentBucket("unknown-permanent")
+	otherBucket := NewBucket("unknown", time.Hour)
+
+	for _, bucket := range userBuckets {
+		assert.Equal(t, ClassUser, GetBucketClass(bucket.Name()))
+		if err := cacher.SetPerm(bucket, "key", value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := cacher.SetPerm(otherPermBucket, "key", value); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		if err := cacher.Set(otherBucket, strconv.Itoa(i), value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The budget is smaller than the data that cannot be evicted
+	cacher.SetMaxSize(2000)
+	if err := cacher.evict(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, bucket := range append(userBuckets, otherPermBucket) {
+		var out string
+		found, err := cacher.GetPerm(bucket, "key", &out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.True(t, found, bucket.Name())
+	}
+	entries, err := GetAll[string](cacher, otherBucket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Empty(t, entries)
+}
+
+func TestCacherRemoveExpired(t *testing.T) {
+	cacher, err := NewCacher(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bucket := NewBucket("test", time.Millisecond)
+	if err := cacher.Set(bucket, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, 1, cacher.GetStats().Buckets[0].Expired)
+
+	if err := cacher.removeExpired(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := cacher.GetStats()
+	assert.Zero(t, stats.Entries)
+	assert.Zero(t, stats.Size)
+	assert.EqualValues(t, 1, stats.Expirations)
+}
+
+func TestCacherLongTTL(t *testing.T) {
+	cacher, err := NewCacher(filepath.Join(t.TempDir(), "cache"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Expiration beyond the range of unix nanoseconds
+	bucket := NewBucket("test", time.Hour*24*99999)
+	if err := cacher.Set(bucket, "key", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out string
+	found, err := cacher.Get(bucket, "key", &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, found)
+	assert.Equal(t, "value", out)
+}
@@ -0,0 +1,342 @@
+package filecache
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// BucketClass groups buckets that share a part of the size budget.
+// When the cache exceeds its budget, the least recently used entries of the classes that use more than their share are evicted first.
+// Entries of permanent buckets are never evicted, whatever their class.
+type BucketClass struct {
+	Name string
+	// Share is the relative part of the budget of the class, entries of classes without a share are never evicted
+	Share    float64
+	prefixes []string
+}
+
+var (
+	// ClassUser contains data that cannot be fetched again
+	ClassUser = &BucketClass{Name: "user", Share: 0, prefixes: []string{
+		"watch_history", "reading_history", "manga_downloaded_", "manga-preferences", "pending-media-list-updates",
+		"extension-settings", "plugin-settings", "ext_user_config_", "customer-source-identifier",
+	}}
+	// ClassMetadata contains the AniList and metadata responses
+	ClassMetadata = &BucketClass{Name: "metadata", Share: 4, prefixes: []string{"anime-", "manga-", "base-", "complete-", "viewer", "studio-", "list-", "search-", "custom-query"}}
+	ClassManga    = &BucketClass{Name: "manga", Share: 3, prefixes: []string{"manga_"}}
+	// ClassStreaming contains the episode sources of online streaming and the media info of transcoded files
+	ClassStreaming = &BucketClass{Name: "streaming", Share: 2, prefixes: []string{"onlinestream_", "mediastream"}}
+	ClassOther     = &BucketClass{Name: "other", Share: 1}
+
+	bucketClasses = []*BucketClass{ClassUser, ClassMetadata, ClassManga, ClassStreaming}
+)
+
+// GetBucketClass returns the class of a bucket.
+func GetBucketClass(bucketName string) *BucketClass {
+	for _, class := range bucketClasses {
+		for _, prefix := range class.prefixes {
+			if strings.HasPrefix(bucketName, prefix) {
+				return class
+			}
+		}
+	}
+	return ClassOther
+}
+
+// setIndexEntry adds or replaces an entry of the index, c.mu must be held.
+func (c *Cacher) setIndexEntry(bucketName string, key string, meta *entryMeta) {
+	idx, ok := c.buckets[bucketName]
+	if !ok {
+		idx = &bucketIndex{
+			class:   GetBucketClass(bucketName),
+			entries: make(map[string]*entryMeta),
+		}
+		c.buckets[bucketName] = idx
+	}
+	if prev, ok := idx.entries[key]; ok {
+		idx.size -= prev.size
+		c.size -= prev.size
+	}
+	idx.entries[key] = meta
+	idx.size += meta.size
+	c.size += meta.size
+}
+
+// deleteIndexEntry removes an entry from the index, c.mu must be held.
+func (c *Cacher) deleteIndexEntry(bucketName string, key string) {
+	idx, ok := c.buckets[bucketName]
+	if !ok {
+		return
+	}
+	if prev, ok := idx.entries[key]; ok {
+		idx.size -= prev.size
+		c.size -= prev.size
+		delete(idx.entries, key)
+	}
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+// runJanitor deletes expired entries periodically and evicts entries when the cache exceeds its budget.
+func (c *Cacher) runJanitor() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(janitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-ticker.C:
+			_ = c.removeExpired()
+			_ = c.evict()
+		case <-c.evictCh:
+			_ = c.evict()
+		}
+	}
+}
+
+func (c *Cacher) requestEviction() {
+	select {
+	case c.evictCh <- struct{}{}:
+	default:
+	}
+}
+
+type indexKey struct {
+	bucket string
+	key    string
+}
+
+// removeExpired deletes the expired entries of all buckets.
+func (c *Cacher) removeExpired() error {
+	now := time.Now().UnixNano()
+
+	c.mu.Lock()
+	var expired []indexKey
+	for bucketName, idx := range c.buckets {
+		for key, meta := range idx.entries {
+			if isExpired(meta.expiration, now) {
+				expired = append(expired, indexKey{bucket: bucketName, key: key})
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	if err := c.deleteIndexKeys(expired); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.expirations += int64(len(expired))
+	c.mu.Unlock()
+	return nil
+}
+
+// evict deletes the least recently used entries until the cache is back under its budget.
+// Entries are taken from the class that exceeds its share of the budget the most.
+func (c *Cacher) evict() error {
+	c.mu.Lock()
+	if c.maxSize <= 0 || c.size <= c.maxSize {
+		c.mu.Unlock()
+		return nil
+	}
+
+	type candidate struct {
+		indexKey
+		size       int64
+		accessedAt int64
+	}
+
+	target := int64(float64(c.maxSize) * evictionTarget)
+	classSizes := make(map[*BucketClass]int64)
+	candidates := make(map[*BucketClass][]candidate)
+	totalShare := 0.0
+	for bucketName, idx := range c.buckets {
+		classSizes[idx.class] += idx.size
+		if idx.class.Share <= 0 {
+			continue
+		}
+		if _, ok := candidates[idx.class]; !ok {
+			totalShare += idx.class.Share
+		}
+		for key, meta := range idx.entries {
+			// Entries without expiration were set in a permanent bucket
+			if meta.expiration == 0 {
+				continue
+			}
+			candidates[idx.class] = append(candidates[idx.class], candidate{
+				indexKey:   indexKey{bucket: bucketName, key: key},
+				size:       meta.size,
+				accessedAt: meta.accessedAt,
+			})
+		}
+	}
+	for class := range candidates {
+		slices.SortFunc(candidates[class], func(a, b candidate) int {
+			return cmp.Compare(a.accessedAt, b.accessedAt)
+		})
+	}
+
+	size := c.size
+	var evicted []indexKey
+	for size > target {
+		// Find the class that exceeds its share the most
+		var class *BucketClass
+		var excess int64
+		for cl, list := range candidates {
+			if len(list) == 0 {
+				continue
+			}
+			allowance := int64(float64(target) * cl.Share / totalShare)
+			if e := classSizes[cl] - allowance; class == nil || e > excess {
+				class = cl
+				excess = e
+			}
+		}
+		if class == nil {
+			// Only the user class is left
+			break
+		}
+
+		oldest := candidates[class][0]
+		candidates[class] = candidates[class][1:]
+		classSizes[class] -= oldest.size
+		size -= oldest.size
+		evicted = append(evicted, oldest.indexKey)
+	}
+	c.mu.Unlock()
+
+	if err := c.deleteIndexKeys(evicted); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.evictions += int64(len(evicted))
+	c.mu.Unlock()
+	return nil
+}
+
+// deleteIndexKeys deletes entries of multiple buckets in one transaction.
+func (c *Cacher) deleteIndexKeys(keys []indexKey) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	err := c.db.Update(func(tx *bolt.Tx) error {
+		for _, k := range keys {
+			b := tx.Bucket([]byte(k.bucket))
+			if b == nil {
+				continue
+			}
+			if err := b.Delete([]byte(k.key)); err != nil {
+				return err
+			}
+		}
+
+		c.mu.Lock()
+		for _, k := range keys {
+			c.deleteIndexEntry(k.bucket, k.key)
+		}
+		c.mu.Unlock()
+		return nil
+	})
+	return c.wrapError(err)
+}
+
+//////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////////
+
+type (
+	Stats struct {
+		// Size is the size of the entries in bytes
+		Size    int64 `json:"size"`
+		MaxSize int64 `json:"maxSize"`
+		Entries int   `json:"entries"`
+		// Expirations and Evictions are the number of entries removed in the background since the cache was opened
+		Expirations int64          `json:"expirations"`
+		Evictions   int64          `json:"evictions"`
+		Classes     []*ClassStats  `json:"classes"`
+		Buckets     []*BucketStats `json:"buckets"`
+	}
+
+	ClassStats struct {
+		Name    string  `json:"name"`
+		Share   float64 `json:"share"`
+		Size    int64   `json:"size"`
+		Entries int     `json:"entries"`
+	}
+
+	BucketStats struct {
+		Name    string `json:"name"`
+		Class   string `json:"class"`
+		Size    int64  `json:"size"`
+		Entries int    `json:"entries"`
+		Expired int    `json:"expired"`
+		// Hits and Misses are counted since the cache was opened
+		Hits           int64      `json:"hits"`
+		Misses         int64      `json:"misses"`
+		LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty"`
+	}
+)
+
+// GetStats returns the size and usage of the buckets, largest first.
+func (c *Cacher) GetStats() *Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now().UnixNano()
+	ret := &Stats{
+		Size:        c.size,
+		MaxSize:     c.maxSize,
+		Expirations: c.expirations,
+		Evictions:   c.evictions,
+		Classes:     make([]*ClassStats, 0),
+		Buckets:     make([]*BucketStats, 0, len(c.buckets)),
+	}
+
+	classes := make(map[*BucketClass]*ClassStats)
+	for name, idx := range c.buckets {
+		bs := &BucketStats{
+			Name:    name,
+			Class:   idx.class.Name,
+			Size:    idx.size,
+			Entries: len(idx.entries),
+			Hits:    idx.hits,
+			Misses:  idx.misses,
+		}
+		var lastAccessedAt int64
+		for _, meta := range idx.entries {
+			if isExpired(meta.expiration, now) {
+				bs.Expired++
+			}
+			lastAccessedAt = max(lastAccessedAt, meta.accessedAt)
+		}
+		if lastAccessedAt > 0 {
+			bs.LastAccessedAt = new(time.Unix(0, lastAccessedAt))
+		}
+		ret.Buckets = append(ret.Buckets, bs)
+		ret.Entries += bs.Entries
+
+		cs, ok := classes[idx.class]
+		if !ok {
+			cs = &ClassStats{Name: idx.class.Name, Share: idx.class.Share}
+			classes[idx.class] = cs
+			ret.Classes = append(ret.Classes, cs)
+		}
+		cs.Size += bs.Size
+		cs.Entries += bs.Entries
+	}
+
+	slices.SortFunc(ret.Buckets, func(a, b *BucketStats) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Name, b.Name))
+	})
+	slices.SortFunc(ret.Classes, func(a, b *ClassStats) int {
+		return cmp.Or(cmp.Compare(b.Size, a.Size), cmp.Compare(a.Name, b.Name))
+	})
+
+	return ret
+}
@@ -11,7 +11,16 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-type ImageProxy struct{}
+type ImageProxy struct {
+	// Cache, if set, is checked before fetching the image
+	Cache ImageCache
+}
+
+// ImageCache serves the images it knows about from a cache, e.g. the pages of manga chapters.
+type ImageCache interface {
+	// GetCachedImage returns the image at the URL. ok is false if the URL is not handled by the cache.
+	GetCachedImage(url string, headers map[string]string) (buf []byte, contentType string, ok bool, err error)
+}
 
 func (ip *ImageProxy) GetImage(url string, headers map[string]string) ([]byte, string, error) {
 	request := req.C().DisableAutoReadResponse().NewRequest()
@@ -69,7 +78,15 @@ func (ip *ImageProxy) ProxyImage(c echo.Context) (err error) {
 		}
 	}
 
-	imageBuffer, contentType, err := ip.GetImage(url, headers)
+	var imageBuffer []byte
+	var contentType string
+	handled := false
+	if ip.Cache != nil {
+		imageBuffer, contentType, handled, err = ip.Cache.GetCachedImage(url, headers)
+	}
+	if !handled {
+		imageBuffer, contentType, err = ip.GetImage(url, headers)
+	}
 	if err != nil {
 		return c.String(echo.ErrInternalServerError.Code, "Error fetching image")
 	}
@@ -0,0 +1,192 @@
+// Package watchjournal records every viewing session in an append-only journal and computes viewing statistics from it.
+package watchjournal
+
+import (
+	"seanime/internal/database/db"
+	"seanime/internal/database/models"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+const (
+	// Sessions shorter than this are not recorded unless the episode was completed, e.g. when skimming through episodes
+	minWatchedSeconds = 30
+	// Largest playback rate counted as watching, position jumps beyond it are seeks
+	maxPlaybackRate = 2
+	// Position jumps that are not counted as seeks to absorb the irregularity of the updates
+	positionSlack = 2 * time.Second
+)
+
+// PlayerExternal is the player of the sessions played with an external media player, the built-in players use their target.
+const PlayerExternal = "external"
+
+type (
+	// Journal tracks the playback of the players and records a models.WatchSession when a playback ends.
+	// Each player is identified by a key so that concurrent playbacks are tracked separately.
+	Journal struct {
+		db     *db.Database
+		logger *zerolog.Logger
+		now    func() time.Time
+		active map[string]*activeSession
+		mu     sync.Mutex
+	}
+
+	// Playback identifies what is being watched.
+	Playback struct {
+		MediaId       int
+		EpisodeNumber int
+		Kind          string
+		Player        string
+	}
+
+	activeSession struct {
+		session     *models.WatchSession
+		position    float64
+		updatedAt   time.Time
+		playing     bool
+		hasPosition bool
+	}
+
+	NewJournalOptions struct {
+		Database *db.Database
+		Logger   *zerolog.Logger
+	}
+)
+
+func NewJournal(opts *NewJournalOptions) *Journal {
+	return &Journal{
+		db:     opts.Database,
+		logger: opts.Logger,
+		now:    time.Now,
+		active: make(map[string]*activeSession),
+	}
+}
+
+// Start starts a session for the player.
+// The current session of the player is ended first unless it is for the same episode.
+func (j *Journal) Start(key string, playback Playback) {
+	if playback.MediaId == 0 {
+		return
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if s, ok := j.active[key]; ok {
+		if s.session.MediaId == playback.MediaId && s.session.EpisodeNumber == playback.EpisodeNumber && s.session.Kind == playback.Kind {
+			return
+		}
+		j.end(key)
+	}
+
+	now := j.now()
+	j.active[key] = &activeSession{
+		session: &models.WatchSession{
+			MediaId:       playback.MediaId,
+			EpisodeNumber: playback.EpisodeNumber,
+			Kind:          playback.Kind,
+			Player:        playback.Player,
+			StartedAt:     now,
+			ProfileID:     j.db.ProfileScope(),
+		},
+		updatedAt: now,
+	}
+}
+
+// Update records the position of the player.
+// The time between two updates is counted as watched if the player was playing and the position moved accordingly.
+func (j *Journal) Update(key string, position float64, duration float64, playing bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s, ok := j.active[key]
+	if !ok {
+		return
+	}
+
+	now := j.now()
+	if s.playing && s.hasPosition {
+		elapsed := now.Sub(s.updatedAt)
+		delta := position - s.position
+		if delta > 0 && delta <= (elapsed*maxPlaybackRate+positionSlack).Seconds() {
+			s.session.WatchedSeconds += delta
+		}
+	}
+
+	s.position = position
+	s.hasPosition = true
+	s.updatedAt = now
+	s.playing = playing
+	if duration > 0 {
+		s.session.Duration = duration
+	}
+}
+
+// Seek records a position change that is not counted as watched.
+func (j *Journal) Seek(key string, position float64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	s, ok := j.active[key]
+	if !ok {
+		return
+	}
+	s.position = position
+	s.hasPosition = true
+	s.updatedAt = j.now()
+}
+
+// Complete marks the episode of the current session of the player as completed.
+func (j *Journal) Complete(key string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if s, ok := j.active[key]; ok {
+		s.session.Completed = true
+	}
+}
+
+// End ends the current session of the player and records it.
+func (j *Journal) End(key string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.end(key)
+}
+
+// Close ends and records all the current sessions.
+func (j *Journal) Close() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for key := range j.active {
+		j.end(key)
+	}
+}
+
+func (j *Journal) end(key string) {
+	s, ok := j.active[key]
+	if !ok {
+		return
+	}
+	delete(j.active, key)
+
+	s.session.EndedAt = j.now()
+	if s.session.WatchedSeconds < minWatchedSeconds && !s.session.Completed {
+		return
+	}
+
+	if err := j.db.InsertWatchSession(s.session); err != nil {
+		j.logger.Error().Err(err).Int("mediaId", s.session.MediaId).Msg("watch journal: Failed to record session")
+		return
+	}
+
+	j.logger.Debug().
+		Int("mediaId", s.session.MediaId).
+		Int("episode", s.session.EpisodeNumber).
+		Float64("watchedSeconds", s.session.WatchedSeconds).
+		Bool("completed", s.session.Completed).
+		Msg("watch journal: Recorded session")
+}
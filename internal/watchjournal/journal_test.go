@@ -0,0 +1,128 @@
+package watchjournal
+
+import (
+	"seanime/internal/database/db"
+	"seanime/internal/util"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time { return c.t }
+func (c *testClock) advance(seconds float64) {
+	c.t = c.t.Add(time.Duration(seconds * float64(time.Second)))
+}
+
+func newTestJournal(t *testing.T) (*Journal, *db.Database, *testClock) {
+	database, err := db.NewDatabase(t.TempDir(), "test", util.NewLogger())
+	require.NoError(t, err)
+
+	clock := &testClock{t: time.Date(2026, time.March, 2, 20, 0, 0, 0, time.UTC)}
+	journal := NewJournal(&NewJournalOptions{
+		Database: database,
+		Logger:   util.NewLogger(),
+	})
+	journal.now = clock.now
+	return journal, database, clock
+}
+
+func TestJournalCountsPlayingTimeOnly(t *testing.T) {
+	journal, database, clock := newTestJournal(t)
+
+	journal.Start("videocore:a", Playback{MediaId: 1, EpisodeNumber: 3, Kind: "localfile", Player: "videocore"})
+	journal.Update("videocore:a", 0, 1440, true)
+
+	// Playing for 5 minutes
+	for i := 1; i <= 60; i++ {
+		clock.advance(5)
+		journal.Update("videocore:a", float64(i*5), 1440, true)
+	}
+
+	// Paused for 10 minutes, the position does not move
+	journal.Update("videocore:a", 300, 1440, false)
+	clock.advance(600)
+	journal.Update("videocore:a", 300, 1440, true)
+
+	// Skipping the opening
+	clock.advance(1)
+	journal.Seek("videocore:a", 390)
+
+	// Playing for 1 minute at 1.5x
+	clock.advance(60)
+	journal.Update("videocore:a", 480, 1440, true)
+
+	// A seek reported as a status update is not counted either
+	clock.advance(5)
+	journal.Update("videocore:a", 1300, 1440, true)
+
+	clock.advance(140)
+	journal.Update("videocore:a", 1440, 1440, true)
+	journal.Complete("videocore:a")
+	journal.End("videocore:a")
+
+	sessions, err := database.GetWatchSessions(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+
+	s := sessions[0]
+	require.Equal(t, 1, s.MediaId)
+	require.Equal(t, 3, s.EpisodeNumber)
+	require.Equal(t, "localfile", s.Kind)
+	require.True(t, s.Completed)
+	require.Equal(t, 1440.0, s.Duration)
+	require.InDelta(t, 300+90+140, s.WatchedSeconds, 0.001)
+	require.True(t, s.StartedAt.Equal(time.Date(2026, time.March, 2, 20, 0, 0, 0, time.UTC)))
+	require.True(t, s.EndedAt.Equal(clock.t))
+}
+
+func TestJournalSessionBoundaries(t *testing.T) {
+	journal, database, clock := newTestJournal(t)
+
+	play := func(key string, seconds int) {
+		for i := 0; i < seconds; i += 10 {
+			clock.advance(10)
+			journal.Update(key, float64(i+10), 1440, true)
+		}
+	}
+
+	journal.Start(PlayerExternal, Playback{MediaId: 1, EpisodeNumber: 1, Kind: "stream", Player: PlayerExternal})
+	journal.Update(PlayerExternal, 0, 1440, true)
+	play(PlayerExternal, 120)
+
+	// Starting the same episode again continues the session
+	journal.Start(PlayerExternal, Playback{MediaId: 1, EpisodeNumber: 1, Kind: "stream", Player: PlayerExternal})
+	play(PlayerExternal, 0)
+
+	// Another episode ends the previous session
+	journal.Start(PlayerExternal, Playback{MediaId: 1, EpisodeNumber: 2, Kind: "stream", Player: PlayerExternal})
+	journal.Update(PlayerExternal, 0, 1440, true)
+	play(PlayerExternal, 20)
+
+	// Sessions that are too short are not recorded
+	journal.End(PlayerExternal)
+
+	// Unknown media are ignored
+	journal.Start(PlayerExternal, Playback{EpisodeNumber: 1, Kind: "stream", Player: PlayerExternal})
+	play(PlayerExternal, 120)
+	journal.End(PlayerExternal)
+
+	// Concurrent players are tracked separately
+	journal.Start("mpvcore:b", Playback{MediaId: 2, EpisodeNumber: 5, Kind: "torrent", Player: "mpvcore"})
+	journal.Update("mpvcore:b", 0, 1440, true)
+	play("mpvcore:b", 60)
+	journal.Close()
+
+	sessions, err := database.GetWatchSessions(time.Time{}, time.Time{})
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	require.Equal(t, 1, sessions[0].EpisodeNumber)
+	require.InDelta(t, 120, sessions[0].WatchedSeconds, 0.001)
+	require.False(t, sessions[0].Completed)
+	require.Equal(t, 2, sessions[1].MediaId)
+	require.InDelta(t, 60, sessions[1].WatchedSeconds, 0.001)
+}
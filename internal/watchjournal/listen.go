@@ -0,0 +1,71 @@
+package watchjournal
+
+import (
+	"seanime/internal/library/playbackmanager"
+	"seanime/internal/mediacore"
+	"seanime/internal/mediaplayers/mediaplayer"
+	"seanime/internal/player"
+)
+
+// ListenToMediacore records the sessions of the built-in players.
+func (j *Journal) ListenToMediacore(coordinator *mediacore.Coordinator) (cancel func()) {
+	return coordinator.RegisterEventCallback(func(event player.Event) bool {
+		session := event.GetSessionKey()
+		key := string(session.Target) + ":" + session.ClientID
+
+		switch e := event.(type) {
+		case *player.PlaybackLoadedEvent:
+			info := e.State.PlaybackInfo
+			if info == nil {
+				return true
+			}
+			j.Start(key, Playback{
+				MediaId:       info.Media.GetID(),
+				EpisodeNumber: info.Episode.GetEpisodeNumber(),
+				Kind:          string(info.PlaybackType),
+				Player:        string(session.Target),
+			})
+		case *player.LoadedMetadataEvent:
+			j.Update(key, e.CurrentTime, e.Duration, !e.Paused)
+		case *player.StatusEvent:
+			j.Update(key, e.CurrentTime, e.Duration, !e.Paused)
+		case *player.PausedEvent:
+			j.Update(key, e.CurrentTime, e.Duration, false)
+		case *player.ResumedEvent:
+			j.Update(key, e.CurrentTime, e.Duration, true)
+		case *player.SeekedEvent:
+			j.Seek(key, e.CurrentTime)
+		case *player.CompletedEvent:
+			j.Update(key, e.CurrentTime, e.Duration, true)
+			j.Complete(key)
+		case *player.EndedEvent, *player.ErrorEvent, *player.TerminatedEvent:
+			j.End(key)
+		}
+		return true
+	})
+}
+
+// ListenToPlaybackManager records the sessions of the external players.
+func (j *Journal) ListenToPlaybackManager(pm *playbackmanager.PlaybackManager) (cancel func()) {
+	return pm.RegisterMediaPlayerCallback(func(event playbackmanager.PlaybackEvent) bool {
+		switch e := event.(type) {
+		case playbackmanager.PlaybackStatusChangedEvent:
+			kind := string(player.PlaybackTypeLocalFile)
+			if e.Status.PlaybackType == mediaplayer.PlaybackTypeStream {
+				kind = "stream"
+			}
+			j.Start(PlayerExternal, Playback{
+				MediaId:       e.State.MediaId,
+				EpisodeNumber: e.State.EpisodeNumber,
+				Kind:          kind,
+				Player:        PlayerExternal,
+			})
+			j.Update(PlayerExternal, e.Status.CurrentTimeInSeconds, e.Status.DurationInSeconds, e.Status.Playing)
+		case playbackmanager.VideoCompletedEvent, playbackmanager.StreamCompletedEvent:
+			j.Complete(PlayerExternal)
+		case playbackmanager.VideoStoppedEvent, playbackmanager.StreamStoppedEvent, playbackmanager.PlaybackErrorEvent:
+			j.End(PlayerExternal)
+		}
+		return true
+	})
+}
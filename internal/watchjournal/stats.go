@@ -0,0 +1,375 @@
+package watchjournal
+
+import (
+	"cmp"
+	"fmt"
+	"seanime/internal/database/models"
+	"slices"
+	"time"
+)
+
+const (
+	DayPeriod   Period = "day"
+	WeekPeriod  Period = "week" // Weeks start on Monday
+	MonthPeriod Period = "month"
+)
+
+const dateFormat = "2006-01-02"
+
+// Number of media and genres listed in the yearly summary
+const summaryTopLimit = 10
+
+type (
+	Period string
+
+	PeriodStat struct {
+		// Start is the first day of the period, e.g. "2026-03-02"
+		Start             string  `json:"start"`
+		WatchedSeconds    float64 `json:"watchedSeconds"`
+		Sessions          int     `json:"sessions"`
+		CompletedEpisodes int     `json:"completedEpisodes"`
+	}
+
+	// MediaInfo is used to break down the sessions by genre and studio.
+	MediaInfo struct {
+		Genres  []string
+		Studios []string
+	}
+
+	BreakdownItem struct {
+		Name           string  `json:"name"`
+		WatchedSeconds float64 `json:"watchedSeconds"`
+		Sessions       int     `json:"sessions"`
+		MediaCount     int     `json:"mediaCount"`
+	}
+
+	MediaStat struct {
+		MediaId           int     `json:"mediaId"`
+		WatchedSeconds    float64 `json:"watchedSeconds"`
+		Sessions          int     `json:"sessions"`
+		CompletedEpisodes int     `json:"completedEpisodes"`
+	}
+
+	MediaRewatch struct {
+		MediaId int `json:"mediaId"`
+		// Rewatches is the number of times episodes were completed again after the first time
+		Rewatches         int       `json:"rewatches"`
+		RewatchedEpisodes int       `json:"rewatchedEpisodes"`
+		LastRewatchedAt   time.Time `json:"lastRewatchedAt"`
+	}
+
+	Streaks struct {
+		// Current is the number of consecutive days with a session up to today, or yesterday if nothing was watched today yet
+		Current      int    `json:"current"`
+		Longest      int    `json:"longest"`
+		LongestStart string `json:"longestStart,omitempty"`
+		LongestEnd   string `json:"longestEnd,omitempty"`
+		LastWatched  string `json:"lastWatched,omitempty"`
+	}
+
+	YearSummary struct {
+		Year              int                `json:"year"`
+		WatchedSeconds    float64            `json:"watchedSeconds"`
+		Sessions          int                `json:"sessions"`
+		CompletedEpisodes int                `json:"completedEpisodes"`
+		MediaCount        int                `json:"mediaCount"`
+		DaysWatched       int                `json:"daysWatched"`
+		LongestStreak     int                `json:"longestStreak"`
+		BusiestDay        *PeriodStat        `json:"busiestDay,omitempty"`
+		Months            []*PeriodStat      `json:"months"`
+		Kinds             map[string]float64 `json:"kinds"` // Watched seconds by playback kind
+		TopMedia          []*MediaStat       `json:"topMedia"`
+		TopGenres         []*BreakdownItem   `json:"topGenres"`
+	}
+)
+
+func ParsePeriod(s string) (Period, error) {
+	switch p := Period(s); p {
+	case DayPeriod, WeekPeriod, MonthPeriod:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid period %q", s)
+}
+
+// periodStart returns the start of the period containing t, in the location of t.
+func periodStart(t time.Time, period Period) time.Time {
+	y, m, d := t.Date()
+	switch period {
+	case WeekPeriod:
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, t.Location())
+	case MonthPeriod:
+		return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func nextPeriod(t time.Time, period Period) time.Time {
+	switch period {
+	case WeekPeriod:
+		return t.AddDate(0, 0, 7)
+	case MonthPeriod:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 1)
+}
+
+// TimeByPeriod returns the time watched in each period between from and to, including the periods without sessions.
+// Sessions are attributed to the period they started in, in the location of from.
+func TimeByPeriod(sessions []*models.WatchSession, period Period, from, to time.Time) []*PeriodStat {
+	ret := make([]*PeriodStat, 0)
+	if !from.Before(to) {
+		return ret
+	}
+
+	loc := from.Location()
+	stats := make(map[string]*PeriodStat)
+	for start := periodStart(from, period); start.Before(to); start = nextPeriod(start, period) {
+		stat := &PeriodStat{Start: start.Format(dateFormat)}
+		stats[stat.Start] = stat
+		ret = append(ret, stat)
+	}
+
+	for _, s := range sessions {
+		startedAt := s.StartedAt.In(loc)
+		if startedAt.Before(from) || !startedAt.Before(to) {
+			continue
+		}
+		stat, ok := stats[periodStart(startedAt, period).Format(dateFormat)]
+		if !ok {
+			continue
+		}
+		addSession(stat, s)
+	}
+
+	return ret
+}
+
+func addSession(stat *PeriodStat, s *models.WatchSession) {
+	stat.WatchedSeconds += s.WatchedSeconds
+	stat.Sessions++
+	if s.Completed {
+		stat.CompletedEpisodes++
+	}
+}
+
+// ByGenre returns the time watched by genre, most watched first.
+// The time of a session counts towards each genre of the media.
+func ByGenre(sessions []*models.WatchSession, media map[int]*MediaInfo) []*BreakdownItem {
+	return breakdown(sessions, func(mediaId int) []string {
+		if info, ok := media[mediaId]; ok {
+			return info.Genres
+		}
+		return nil
+	})
+}
+
+// ByStudio returns the time watched by studio, most watched first.
+func ByStudio(sessions []*models.WatchSession, media map[int]*MediaInfo) []*BreakdownItem {
+	return breakdown(sessions, func(mediaId int) []string {
+		if info, ok := media[mediaId]; ok {
+			return info.Studios
+		}
+		return nil
+	})
+}
+
+func breakdown(sessions []*models.WatchSession, names func(mediaId int) []string) []*BreakdownItem {
+	items := make(map[string]*BreakdownItem)
+	mediaIds := make(map[string]map[int]struct{})
+	for _, s := range sessions {
+		for _, name := range names(s.MediaId) {
+			item, ok := items[name]
+			if !ok {
+				item = &BreakdownItem{Name: name}
+				items[name] = item
+				mediaIds[name] = make(map[int]struct{})
+			}
+			item.WatchedSeconds += s.WatchedSeconds
+			item.Sessions++
+			mediaIds[name][s.MediaId] = struct{}{}
+		}
+	}
+
+	ret := make([]*BreakdownItem, 0, len(items))
+	for name, item := range items {
+		item.MediaCount = len(mediaIds[name])
+		ret = append(ret, item)
+	}
+	slices.SortFunc(ret, func(a, b *BreakdownItem) int {
+		return cmp.Or(cmp.Compare(b.WatchedSeconds, a.WatchedSeconds), cmp.Compare(a.Name, b.Name))
+	})
+	return ret
+}
+
+// ByMedia returns the time watched by media, most watched first.
+func ByMedia(sessions []*models.WatchSession) []*MediaStat {
+	stats := make(map[int]*MediaStat)
+	for _, s := range sessions {
+		stat, ok := stats[s.MediaId]
+		if !ok {
+			stat = &MediaStat{MediaId: s.MediaId}
+			stats[s.MediaId] = stat
+		}
+		stat.WatchedSeconds += s.WatchedSeconds
+		stat.Sessions++
+		if s.Completed {
+			stat.CompletedEpisodes++
+		}
+	}
+
+	ret := make([]*MediaStat, 0, len(stats))
+	for _, stat := range stats {
+		ret = append(ret, stat)
+	}
+	slices.SortFunc(ret, func(a, b *MediaStat) int {
+		return cmp.Or(cmp.Compare(b.WatchedSeconds, a.WatchedSeconds), cmp.Compare(a.MediaId, b.MediaId))
+	})
+	return ret
+}
+
+// Rewatches returns the media whose episodes were completed more than once, most rewatched first.
+// Sessions must be sorted by start time.
+func Rewatches(sessions []*models.WatchSession) []*MediaRewatch {
+	type episodeKey struct {
+		mediaId int
+		episode int
+	}
+	completions := make(map[episodeKey]int)
+	rewatches := make(map[int]*MediaRewatch)
+	rewatchedEpisodes := make(map[episodeKey]struct{})
+
+	for _, s := range sessions {
+		if !s.Completed {
+			continue
+		}
+		key := episodeKey{mediaId: s.MediaId, episode: s.EpisodeNumber}
+		completions[key]++
+		if completions[key] < 2 {
+			continue
+		}
+
+		r, ok := rewatches[s.MediaId]
+		if !ok {
+			r = &MediaRewatch{MediaId: s.MediaId}
+			rewatches[s.MediaId] = r
+		}
+		r.Rewatches++
+		r.LastRewatchedAt = s.StartedAt
+		if _, ok := rewatchedEpisodes[key]; !ok {
+			rewatchedEpisodes[key] = struct{}{}
+			r.RewatchedEpisodes++
+		}
+	}
+
+	ret := make([]*MediaRewatch, 0, len(rewatches))
+	for _, r := range rewatches {
+		ret = append(ret, r)
+	}
+	slices.SortFunc(ret, func(a, b *MediaRewatch) int {
+		return cmp.Or(cmp.Compare(b.Rewatches, a.Rewatches), b.LastRewatchedAt.Compare(a.LastRewatchedAt), cmp.Compare(a.MediaId, b.MediaId))
+	})
+	return ret
+}
+
+// GetStreaks returns the streaks of consecutive days with at least one session, in the location of today.
+func GetStreaks(sessions []*models.WatchSession, today time.Time) *Streaks {
+	ret := &Streaks{}
+
+	days := watchedDays(sessions, today.Location())
+	if len(days) == 0 {
+		return ret
+	}
+
+	var start time.Time
+	length := 0
+	for i, day := range days {
+		if i > 0 && days[i-1].AddDate(0, 0, 1).Equal(day) {
+			length++
+		} else {
+			start = day
+			length = 1
+		}
+		if length > ret.Longest {
+			ret.Longest = length
+			ret.LongestStart = start.Format(dateFormat)
+			ret.LongestEnd = day.Format(dateFormat)
+		}
+	}
+
+	last := days[len(days)-1]
+	ret.LastWatched = last.Format(dateFormat)
+
+	todayStart := periodStart(today, DayPeriod)
+	if last.Equal(todayStart) || last.Equal(todayStart.AddDate(0, 0, -1)) {
+		ret.Current = length
+	}
+
+	return ret
+}
+
+// watchedDays returns the days with at least one session in ascending order.
+func watchedDays(sessions []*models.WatchSession, loc *time.Location) []time.Time {
+	seen := make(map[time.Time]struct{})
+	ret := make([]time.Time, 0)
+	for _, s := range sessions {
+		day := periodStart(s.StartedAt.In(loc), DayPeriod)
+		if _, ok := seen[day]; ok {
+			continue
+		}
+		seen[day] = struct{}{}
+		ret = append(ret, day)
+	}
+	slices.SortFunc(ret, func(a, b time.Time) int { return a.Compare(b) })
+	return ret
+}
+
+// NewYearSummary returns the summary of the sessions started during the year, in the location loc.
+func NewYearSummary(year int, sessions []*models.WatchSession, media map[int]*MediaInfo, loc *time.Location) *YearSummary {
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+	to := from.AddDate(1, 0, 0)
+
+	inYear := make([]*models.WatchSession, 0, len(sessions))
+	for _, s := range sessions {
+		startedAt := s.StartedAt.In(loc)
+		if !startedAt.Before(from) && startedAt.Before(to) {
+			inYear = append(inYear, s)
+		}
+	}
+
+	ret := &YearSummary{
+		Year:   year,
+		Months: TimeByPeriod(inYear, MonthPeriod, from, to),
+		Kinds:  make(map[string]float64),
+	}
+
+	for _, s := range inYear {
+		ret.WatchedSeconds += s.WatchedSeconds
+		ret.Sessions++
+		if s.Completed {
+			ret.CompletedEpisodes++
+		}
+		ret.Kinds[s.Kind] += s.WatchedSeconds
+	}
+
+	for _, day := range TimeByPeriod(inYear, DayPeriod, from, to) {
+		if day.Sessions == 0 {
+			continue
+		}
+		ret.DaysWatched++
+		if ret.BusiestDay == nil || day.WatchedSeconds > ret.BusiestDay.WatchedSeconds {
+			ret.BusiestDay = day
+		}
+	}
+
+	ret.LongestStreak = GetStreaks(inYear, to.AddDate(0, 0, -1)).Longest
+
+	topMedia := ByMedia(inYear)
+	ret.MediaCount = len(topMedia)
+	ret.TopMedia = topMedia[:min(len(topMedia), summaryTopLimit)]
+
+	topGenres := ByGenre(inYear, media)
+	ret.TopGenres = topGenres[:min(len(topGenres), summaryTopLimit)]
+
+	return ret
+}
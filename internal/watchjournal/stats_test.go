@@ -0,0 +1,163 @@
+package watchjournal
+
+import (
+	"seanime/internal/database/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func testSession(mediaId int, episode int, startedAt string, minutes float64, completed bool) *models.WatchSession {
+	t, err := time.Parse("2006-01-02 15:04", startedAt)
+	if err != nil {
+		panic(err)
+	}
+	return &models.WatchSession{
+		MediaId:        mediaId,
+		EpisodeNumber:  episode,
+		Kind:           "localfile",
+		StartedAt:      t,
+		WatchedSeconds: minutes * 60,
+		Completed:      completed,
+	}
+}
+
+func TestTimeByPeriod(t *testing.T) {
+	sessions := []*models.WatchSession{
+		testSession(1, 1, "2026-03-01 22:00", 24, true), // Sunday
+		testSession(1, 2, "2026-03-02 20:00", 24, true), // Monday
+		testSession(2, 1, "2026-03-02 23:30", 10, false),
+		testSession(2, 1, "2026-03-04 21:00", 24, true),
+	}
+	from := time.Date(2026, time.March, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, time.March, 5, 0, 0, 0, 0, time.UTC)
+
+	days := TimeByPeriod(sessions, DayPeriod, from, to)
+	require.Len(t, days, 4)
+	require.Equal(t, "2026-03-01", days[0].Start)
+	require.Equal(t, "2026-03-02", days[1].Start)
+	require.Equal(t, 2, days[1].Sessions)
+	require.Equal(t, 1, days[1].CompletedEpisodes)
+	require.InDelta(t, 34*60, days[1].WatchedSeconds, 0.001)
+	require.Equal(t, 0, days[2].Sessions)
+	require.Equal(t, 1, days[3].Sessions)
+
+	// Weeks start on Monday
+	weeks := TimeByPeriod(sessions, WeekPeriod, from, to)
+	require.Len(t, weeks, 2)
+	require.Equal(t, "2026-02-23", weeks[0].Start)
+	require.Equal(t, 1, weeks[0].Sessions)
+	require.Equal(t, "2026-03-02", weeks[1].Start)
+	require.Equal(t, 3, weeks[1].Sessions)
+
+	// Days follow the location of the range
+	loc := time.FixedZone("UTC+2", 2*60*60)
+	days = TimeByPeriod(sessions, DayPeriod, from.In(loc), to.In(loc))
+	require.Equal(t, "2026-03-03", days[2].Start)
+	require.Equal(t, 1, days[2].Sessions)
+}
+
+func TestBreakdowns(t *testing.T) {
+	sessions := []*models.WatchSession{
+		testSession(1, 1, "2026-03-01 22:00", 24, true),
+		testSession(1, 2, "2026-03-02 20:00", 24, true),
+		testSession(2, 1, "2026-03-03 20:00", 30, true),
+		testSession(3, 1, "2026-03-03 21:00", 5, false),
+	}
+	media := map[int]*MediaInfo{
+		1: {Genres: []string{"Action", "Drama"}, Studios: []string{"MAPPA"}},
+		2: {Genres: []string{"Drama"}, Studios: []string{"Kyoto Animation"}},
+	}
+
+	genres := ByGenre(sessions, media)
+	require.Len(t, genres, 2)
+	require.Equal(t, "Drama", genres[0].Name)
+	require.InDelta(t, 78*60, genres[0].WatchedSeconds, 0.001)
+	require.Equal(t, 3, genres[0].Sessions)
+	require.Equal(t, 2, genres[0].MediaCount)
+	require.Equal(t, "Action", genres[1].Name)
+
+	studios := ByStudio(sessions, media)
+	require.Len(t, studios, 2)
+	require.Equal(t, "MAPPA", studios[0].Name)
+	require.Equal(t, 1, studios[0].MediaCount)
+}
+
+func TestRewatches(t *testing.T) {
+	sessions := []*models.WatchSession{
+		testSession(1, 1, "2025-01-01 20:00", 24, true),
+		testSession(1, 2, "2025-01-02 20:00", 24, true),
+		testSession(1, 1, "2026-01-01 20:00", 24, true),
+		testSession(1, 2, "2026-01-02 20:00", 10, false),
+		testSession(1, 1, "2026-02-01 20:00", 24, true),
+		testSession(2, 1, "2026-01-01 20:00", 24, true),
+		testSession(2, 1, "2026-01-05 20:00", 24, true),
+		testSession(3, 1, "2026-01-05 20:00", 24, true),
+	}
+
+	rewatches := Rewatches(sessions)
+	require.Len(t, rewatches, 2)
+	require.Equal(t, 1, rewatches[0].MediaId)
+	require.Equal(t, 2, rewatches[0].Rewatches)
+	require.Equal(t, 1, rewatches[0].RewatchedEpisodes)
+	require.Equal(t, "2026-02-01", rewatches[0].LastRewatchedAt.Format(dateFormat))
+	require.Equal(t, 2, rewatches[1].MediaId)
+	require.Equal(t, 1, rewatches[1].Rewatches)
+}
+
+func TestGetStreaks(t *testing.T) {
+	sessions := []*models.WatchSession{
+		testSession(1, 1, "2026-02-27 20:00", 24, true),
+		testSession(1, 2, "2026-02-28 20:00", 24, true),
+		testSession(1, 3, "2026-03-01 20:00", 24, true),
+		testSession(1, 4, "2026-03-01 21:00", 24, true),
+		testSession(1, 5, "2026-03-04 20:00", 24, true),
+		testSession(1, 6, "2026-03-05 20:00", 24, true),
+	}
+
+	streaks := GetStreaks(sessions, time.Date(2026, time.March, 6, 12, 0, 0, 0, time.UTC))
+	require.Equal(t, 2, streaks.Current)
+	require.Equal(t, 3, streaks.Longest)
+	require.Equal(t, "2026-02-27", streaks.LongestStart)
+	require.Equal(t, "2026-03-01", streaks.LongestEnd)
+	require.Equal(t, "2026-03-05", streaks.LastWatched)
+
+	// The streak is broken after a day without sessions
+	streaks = GetStreaks(sessions, time.Date(2026, time.March, 7, 12, 0, 0, 0, time.UTC))
+	require.Equal(t, 0, streaks.Current)
+	require.Equal(t, 3, streaks.Longest)
+
+	require.Equal(t, &Streaks{}, GetStreaks(nil, time.Now()))
+}
+
+func TestNewYearSummary(t *testing.T) {
+	sessions := []*models.WatchSession{
+		testSession(1, 1, "2025-12-31 20:00", 24, true),
+		testSession(1, 2, "2026-01-01 20:00", 24, true),
+		testSession(1, 3, "2026-01-02 20:00", 24, true),
+		testSession(2, 1, "2026-03-10 20:00", 100, true),
+		testSession(2, 1, "2026-03-10 23:00", 20, false),
+	}
+	sessions[4].Kind = "torrent"
+	media := map[int]*MediaInfo{
+		1: {Genres: []string{"Comedy"}},
+		2: {Genres: []string{"Drama"}},
+	}
+
+	summary := NewYearSummary(2026, sessions, media, time.UTC)
+	require.Equal(t, 2026, summary.Year)
+	require.Equal(t, 4, summary.Sessions)
+	require.Equal(t, 3, summary.CompletedEpisodes)
+	require.InDelta(t, 168*60, summary.WatchedSeconds, 0.001)
+	require.Equal(t, 2, summary.MediaCount)
+	require.Equal(t, 3, summary.DaysWatched)
+	require.Equal(t, 2, summary.LongestStreak)
+	require.Equal(t, "2026-03-10", summary.BusiestDay.Start)
+	require.Len(t, summary.Months, 12)
+	require.InDelta(t, 48*60, summary.Months[0].WatchedSeconds, 0.001)
+	require.InDelta(t, 120*60, summary.Months[2].WatchedSeconds, 0.001)
+	require.InDelta(t, 20*60, summary.Kinds["torrent"], 0.001)
+	require.Equal(t, 2, summary.TopMedia[0].MediaId)
+	require.Equal(t, "Drama", summary.TopGenres[0].Name)
+}